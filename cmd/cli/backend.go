@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/config"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+	"exchange-rate-service/internal/store"
+	"exchange-rate-service/internal/utils"
+	"exchange-rate-service/pkg/client"
+)
+
+// backend answers the commands, either the running service or the providers
+// queried in process. It is the subset of *client.Client the CLI uses.
+type backend interface {
+	Convert(ctx context.Context, req client.ConversionRequest) (*client.ConversionResponse, error)
+	LatestRateFrom(ctx context.Context, provider, from, to string) (*client.LatestRate, error)
+	HistoricalRates(ctx context.Context, req client.HistoricalRatesRequest) (*client.HistoricalRatesResponse, error)
+	CurrencyMetadata(ctx context.Context) ([]client.CurrencyInfo, error)
+}
+
+func newServerBackend(server string) backend {
+	return client.New(server)
+}
+
+// offlineBackend runs the service's exchange logic in process, configured
+// from the same environment variables as the server, so rates come straight
+// from the providers without a running server
+type offlineBackend struct {
+	service  *services.ExchangeService
+	archived bool // SNAPSHOT_DIR is set, so the archive is kept on disk
+}
+
+func newOfflineBackend() (*offlineBackend, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	utils.SetReferenceLocation(cfg.Timezone)
+	utils.SetDateLimits(cfg.Dates.LookbackDays, cfg.Dates.MaxRangeDays)
+	models.SetRequestLimits(cfg.Limits)
+	models.SetSupportedCurrencies(cfg.Currencies)
+	utils.SetHolidays(cfg.Holidays)
+	models.SetAmountLimits(cfg.Amounts)
+	models.SetCurrencyAliases(cfg.Aliases)
+
+	cacheService := cache.NewMemoryCache(cfg.Cache.TTL)
+	apiClient := external.NewExchangeRateClientWithConfig(cfg.Provider)
+	rateFetcher := services.NewRateFetcher(apiClient, cacheService)
+	rateFetcher.SetMetalsProvider(cfg.Fetch.Metals)
+	rateFetcher.SetPivot(cfg.Fetch.Pivot, cfg.Fetch.PerBaseProviders)
+	rateFetcher.SetFetchPool(cfg.Fetch.Pool)
+
+	service := services.NewExchangeService(cacheService, rateFetcher, apiClient)
+	service.SetMarkup(services.NewMarkup(cfg.Markup.GlobalPercent, cfg.Markup.Pairs))
+
+	archive, err := store.NewArchive(cfg.Snapshot.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rate archive: %w", err)
+	}
+	service.SetArchive(archive)
+	return &offlineBackend{service: service, archived: cfg.Snapshot.Dir != ""}, nil
+}
+
+func (b *offlineBackend) Convert(ctx context.Context, req client.ConversionRequest) (*client.ConversionResponse, error) {
+	resp, err := b.service.ConvertCurrency(ctx, &models.ConversionRequest{
+		From:      req.From,
+		To:        req.To,
+		Amount:    req.Amount,
+		Date:      req.Date,
+		Timestamp: req.Timestamp,
+		Locale:    req.Locale,
+		Provider:  req.Provider,
+		Precision: req.Precision,
+		Rounding:  req.Rounding,
+		Strict:    req.Strict,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out client.ConversionResponse
+	return &out, recode(resp, &out)
+}
+
+func (b *offlineBackend) LatestRateFrom(ctx context.Context, provider, from, to string) (*client.LatestRate, error) {
+	resp, err := b.service.GetLatestRate(ctx, from, to, provider)
+	if err != nil {
+		return nil, err
+	}
+	var out client.LatestRate
+	return &out, recode(resp, &out)
+}
+
+func (b *offlineBackend) HistoricalRates(ctx context.Context, req client.HistoricalRatesRequest) (*client.HistoricalRatesResponse, error) {
+	resp, err := b.service.GetHistoricalRates(ctx, &models.HistoricalRateRequest{
+		From:      req.From,
+		To:        req.To,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Provider:  req.Provider,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out client.HistoricalRatesResponse
+	return &out, recode(resp, &out)
+}
+
+func (b *offlineBackend) CurrencyMetadata(_ context.Context) ([]client.CurrencyInfo, error) {
+	var out []client.CurrencyInfo
+	return out, recode(b.service.GetCurrencyMetadata(), &out)
+}
+
+// recode copies a service response into its client type through JSON, the
+// same way it reaches the client from the server
+func recode(in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
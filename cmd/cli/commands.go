@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/pkg/client"
+)
+
+const dateFormat = "2006-01-02"
+
+// execute runs the command named by positional[0]
+func execute(ctx context.Context, b backend, opts options, positional []string) (*result, error) {
+	command, args := positional[0], positional[1:]
+	switch command {
+	case "convert":
+		return convert(ctx, b, opts, args)
+	case "rate":
+		return rate(ctx, b, opts, args)
+	case "history":
+		return history(ctx, b, opts, args)
+	case "currencies":
+		return currencies(ctx, b, args)
+	case "import":
+		return importRates(b, opts, args)
+	}
+	return nil, fmt.Errorf("%w: unknown command %q", errUsage, command)
+}
+
+func convert(ctx context.Context, b backend, opts options, args []string) (*result, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("%w: convert takes AMOUNT FROM TO", errUsage)
+	}
+	amount, err := strconv.ParseFloat(args[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: amount must be a number, got %q", errUsage, args[0])
+	}
+
+	req := client.ConversionRequest{
+		From:     strings.ToUpper(args[1]),
+		To:       strings.ToUpper(args[2]),
+		Amount:   amount,
+		Date:     opts.date,
+		Provider: opts.provider,
+		Rounding: opts.rounding,
+		Strict:   opts.strict,
+	}
+	if opts.precision >= 0 {
+		req.Precision = &opts.precision
+	}
+	resp, err := b.Convert(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result{
+		raw:    resp,
+		header: []string{"from", "to", "amount", "converted_amount", "rate", "rate_date", "provider"},
+		rows: [][]string{{
+			resp.From,
+			resp.To,
+			formatNumber(resp.Amount),
+			formatNumber(resp.ConvertedAmount),
+			formatNumber(resp.Rate),
+			resp.RateDate,
+			resp.Provider,
+		}},
+	}, nil
+}
+
+func rate(ctx context.Context, b backend, opts options, args []string) (*result, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("%w: rate takes FROM TO", errUsage)
+	}
+
+	resp, err := b.LatestRateFrom(ctx, opts.provider, strings.ToUpper(args[0]), strings.ToUpper(args[1]))
+	if err != nil {
+		return nil, err
+	}
+
+	published := ""
+	if resp.PublishedAt != nil {
+		published = resp.PublishedAt.Format(time.RFC3339)
+	}
+	return &result{
+		raw:    resp,
+		header: []string{"from", "to", "rate", "derived", "provider", "published_at"},
+		rows:   [][]string{{resp.From, resp.To, formatNumber(resp.Rate), resp.Derived, resp.Provider, published}},
+	}, nil
+}
+
+func history(ctx context.Context, b backend, opts options, args []string) (*result, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("%w: history takes FROM TO", errUsage)
+	}
+	start, end, err := historyRange(opts, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.HistoricalRates(ctx, client.HistoricalRatesRequest{
+		From:      strings.ToUpper(args[0]),
+		To:        strings.ToUpper(args[1]),
+		StartDate: start,
+		EndDate:   end,
+		Provider:  opts.provider,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// One row per day, the days without a rate saying why
+	notes := make(map[string]string)
+	dates := make([]string, 0, len(resp.Rates)+len(resp.MissingDates))
+	for date, rate := range resp.Rates {
+		dates = append(dates, date)
+		notes[date] = rate.Derived
+		if rate.ObservedDate != "" {
+			notes[date] = "observed " + rate.ObservedDate
+		}
+	}
+	for _, missing := range resp.MissingDates {
+		dates = append(dates, missing.Date)
+		notes[missing.Date] = missing.Reason
+	}
+	sort.Strings(dates)
+
+	rows := make([][]string, 0, len(dates))
+	for _, date := range dates {
+		value := ""
+		if rate, ok := resp.Rates[date]; ok {
+			value = formatNumber(rate.Rate)
+		}
+		rows = append(rows, []string{date, resp.From, resp.To, value, notes[date]})
+	}
+	return &result{raw: resp, header: []string{"date", "from", "to", "rate", "note"}, rows: rows}, nil
+}
+
+// historyRange resolves the history flags into start and end dates: --last
+// counts back from today, otherwise --start is required and --end defaults
+// to today
+func historyRange(opts options, now time.Time) (string, string, error) {
+	today := now.Format(dateFormat)
+	if opts.last != "" {
+		if opts.start != "" || opts.end != "" {
+			return "", "", fmt.Errorf("%w: --last cannot be combined with --start or --end", errUsage)
+		}
+		days, err := parseSpan(opts.last)
+		if err != nil {
+			return "", "", fmt.Errorf("%w: %v", errUsage, err)
+		}
+		return now.AddDate(0, 0, -(days - 1)).Format(dateFormat), today, nil
+	}
+
+	if opts.start == "" {
+		return "", "", fmt.Errorf("%w: history needs --last or --start", errUsage)
+	}
+	end := opts.end
+	if end == "" {
+		end = today
+	}
+	return opts.start, end, nil
+}
+
+// parseSpan parses a number of days ("30d") or weeks ("4w") into days
+func parseSpan(span string) (int, error) {
+	unit := 1
+	number := span
+	switch {
+	case strings.HasSuffix(span, "d"):
+		number = strings.TrimSuffix(span, "d")
+	case strings.HasSuffix(span, "w"):
+		number, unit = strings.TrimSuffix(span, "w"), 7
+	}
+
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("expected a span such as 30d or 4w, got %q", span)
+	}
+	return n * unit, nil
+}
+
+func currencies(ctx context.Context, b backend, args []string) (*result, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("%w: currencies takes no arguments", errUsage)
+	}
+
+	metadata, err := b.CurrencyMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(metadata))
+	for _, info := range metadata {
+		rows = append(rows, []string{info.Code, info.Name, info.Symbol, info.Type, info.Unit})
+	}
+	return &result{raw: metadata, header: []string{"code", "name", "symbol", "type", "unit"}, rows: rows}, nil
+}
+
+// importRates archives a historical rate file in the snapshot directory of
+// the environment, which a server loads on its next start
+func importRates(b backend, opts options, args []string) (*result, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%w: import takes FILE", errUsage)
+	}
+	offline, ok := b.(*offlineBackend)
+	if !ok {
+		return nil, fmt.Errorf("%w: import writes to SNAPSHOT_DIR and needs --offline; a running server imports with POST /api/v1/admin/rates/import", errUsage)
+	}
+	if !offline.archived {
+		return nil, errors.New("SNAPSHOT_DIR is not set, there is no archive to import into")
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	resp, err := offline.service.ImportHistoricalRates(file, &models.RateImportRequest{
+		Base:      opts.base,
+		Provider:  opts.provider,
+		Overwrite: opts.overwrite,
+	}, "xrate")
+	if err != nil {
+		return nil, err
+	}
+
+	return &result{
+		raw:    resp,
+		header: []string{"format", "provider", "rows", "imported_days", "skipped_days", "first_date", "last_date"},
+		rows: [][]string{{
+			resp.Format,
+			resp.Provider,
+			strconv.Itoa(resp.Rows),
+			strconv.Itoa(resp.ImportedDays),
+			strconv.Itoa(resp.SkippedDays),
+			resp.FirstDate,
+			resp.LastDate,
+		}},
+	}, nil
+}
+
+// describeError renders an error of the service, remote or in process, with
+// its error code, which scripts can match on
+func describeError(err error) string {
+	if code := client.ErrorCode(err); code != "" {
+		return fmt.Sprintf("%v [%s]", err, code)
+	}
+	var coded *models.CodedError
+	if errors.As(err, &coded) {
+		return fmt.Sprintf("%v [%s]", err, coded.Code)
+	}
+	return err.Error()
+}
+
+// formatNumber prints a number without float noise such as 8350.000000001
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
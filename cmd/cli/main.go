@@ -0,0 +1,156 @@
+// Command xrate queries the exchange rate service from the command line, or
+// the rate providers directly in offline mode:
+//
+//	xrate convert 100 USD INR
+//	xrate history USD INR --last 30d --format csv
+//	xrate --offline --provider frankfurter rate EUR USD
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+const usage = `Usage: xrate [flags] <command> [arguments]
+
+Commands:
+  convert AMOUNT FROM TO   Convert an amount, at a past day's rate with --date
+  rate FROM TO             Show the latest rate of a pair
+  history FROM TO          Show daily rates over --last 30d, or --start to --end
+  currencies               List the supported currencies
+  import FILE              Archive the historical rates of a CSV file, such as
+                           the ECB's eurofxref-hist.csv, in SNAPSHOT_DIR (--offline)
+
+Flags may also follow the command.
+
+Flags:
+`
+
+// errUsage is returned for malformed commands; the usage is printed with it
+var errUsage = errors.New("invalid usage")
+
+// options holds the parsed flags
+type options struct {
+	server   string
+	offline  bool
+	provider string
+	format   string
+	timeout  time.Duration
+	verbose  bool
+
+	date      string // convert
+	precision int    // convert, negative for the target currency's minor units
+	rounding  string // convert
+	strict    bool   // convert
+	last      string // history
+	start     string // history
+	end       string // history
+	base      string // import
+	overwrite bool   // import
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes one command and returns the process exit code: 0 on success,
+// 1 when the command failed and 2 on invalid usage
+func run(args []string, stdout, stderr io.Writer) int {
+	var opts options
+	fs := flag.NewFlagSet("xrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.server, "server", envOr("XRATE_SERVER", "http://localhost:8080"), "URL of the exchange rate service (env XRATE_SERVER)")
+	fs.BoolVar(&opts.offline, "offline", false, "query the providers directly, configured from the environment like the server")
+	fs.StringVar(&opts.provider, "provider", "", "provider to take rates from, e.g. frankfurter")
+	fs.StringVar(&opts.format, "format", formatTable, "output format: table, json or csv")
+	fs.DurationVar(&opts.timeout, "timeout", 30*time.Second, "give up after this long")
+	fs.BoolVar(&opts.verbose, "verbose", false, "log upstream requests in offline mode")
+	fs.StringVar(&opts.date, "date", "", "convert at the rate of this past day, YYYY-MM-DD")
+	fs.IntVar(&opts.precision, "precision", -1, "decimals of the converted amount (default the target currency's minor units)")
+	fs.StringVar(&opts.rounding, "rounding", "", "rounding of the converted amount: half_up, bankers or truncate")
+	fs.BoolVar(&opts.strict, "strict", false, "fail rather than convert at another day's or a stale rate")
+	fs.StringVar(&opts.last, "last", "", "history span ending today, e.g. 30d or 4w")
+	fs.StringVar(&opts.start, "start", "", "first day of the history, YYYY-MM-DD")
+	fs.StringVar(&opts.end, "end", "", "last day of the history, YYYY-MM-DD (default today)")
+	fs.StringVar(&opts.base, "base", "", "currency the imported file quotes against (default EUR)")
+	fs.BoolVar(&opts.overwrite, "overwrite", false, "replace days already archived on import")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+	if len(positional) == 0 || !validFormat(opts.format) {
+		fs.Usage()
+		return 2
+	}
+
+	var b backend
+	if opts.offline {
+		if !opts.verbose {
+			log.SetOutput(io.Discard)
+		}
+		if b, err = newOfflineBackend(); err != nil {
+			fmt.Fprintf(stderr, "xrate: %v\n", err)
+			return 1
+		}
+	} else {
+		b = newServerBackend(opts.server)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+
+	result, err := execute(ctx, b, opts, positional)
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(stderr, "xrate: %v\n\n", err)
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "xrate: %s\n", describeError(err))
+		return 1
+	}
+
+	if err := result.write(stdout, opts.format); err != nil {
+		fmt.Fprintf(stderr, "xrate: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// parseInterspersed parses flags appearing before, between and after the
+// positional arguments, which the flag package alone stops at, and returns
+// the positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSpan(t *testing.T) {
+	tests := []struct {
+		span    string
+		days    int
+		wantErr bool
+	}{
+		{"30d", 30, false},
+		{"4w", 28, false},
+		{"7", 7, false},
+		{"0d", 0, true},
+		{"-3d", 0, true},
+		{"1m", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.span, func(t *testing.T) {
+			days, err := parseSpan(tt.span)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.days, days)
+		})
+	}
+}
+
+func TestHistoryRange(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	start, end, err := historyRange(options{last: "30d"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-02", start)
+	assert.Equal(t, "2024-03-31", end)
+
+	start, end, err = historyRange(options{start: "2024-03-01"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-01", start)
+	assert.Equal(t, "2024-03-31", end)
+
+	_, _, err = historyRange(options{}, now)
+	assert.ErrorIs(t, err, errUsage)
+
+	_, _, err = historyRange(options{last: "7d", end: "2024-03-10"}, now)
+	assert.ErrorIs(t, err, errUsage)
+}
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/convert":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"from": "USD", "to": "INR", "amount": 100, "converted_amount": 8350, "rate": 83.5,
+				"rate_date": "2024-03-01", "provider": "frankfurter",
+			})
+		case "/api/v1/rates/historical":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"from": "USD", "to": "INR",
+				"rates": map[string]interface{}{
+					"2024-03-01": map[string]interface{}{"rate": 83.5},
+					"2024-03-04": map[string]interface{}{"rate": 83.25},
+				},
+				"missing_dates": []map[string]string{
+					{"date": "2024-03-02", "reason": "no_data"},
+					{"date": "2024-03-03", "reason": "no_data"},
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{
+				"error": "Invalid currency pair", "message": "unsupported currency: XXX", "error_code": "CURRENCY_UNSUPPORTED",
+			})
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{
+			name:   "convert as table",
+			args:   []string{"--server", server.URL, "convert", "100", "usd", "inr"},
+			stdout: "FROM  TO   AMOUNT  CONVERTED_AMOUNT  RATE  RATE_DATE   PROVIDER\nUSD   INR  100     8350              83.5  2024-03-01  frankfurter\n",
+		},
+		{
+			name: "history as csv with flags after the command",
+			args: []string{"history", "USD", "INR", "--start", "2024-03-01", "--end", "2024-03-04", "--format", "csv", "--server", server.URL},
+			stdout: "date,from,to,rate,note\n" +
+				"2024-03-01,USD,INR,83.5,\n" +
+				"2024-03-02,USD,INR,,no_data\n" +
+				"2024-03-03,USD,INR,,no_data\n" +
+				"2024-03-04,USD,INR,83.25,\n",
+		},
+		{
+			name:   "service error",
+			args:   []string{"--server", server.URL, "rate", "USD", "XXX"},
+			code:   1,
+			stderr: "xrate: exchange rate service returned 422: Invalid currency pair: unsupported currency: XXX [CURRENCY_UNSUPPORTED]\n",
+		},
+		{
+			name: "unknown command",
+			args: []string{"--server", server.URL, "forecast"},
+			code: 2,
+		},
+		{
+			name: "bad amount",
+			args: []string{"--server", server.URL, "convert", "ten", "USD", "INR"},
+			code: 2,
+		},
+		{
+			name: "unknown format",
+			args: []string{"--format", "xml", "currencies"},
+			code: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, &stdout, &stderr)
+
+			assert.Equal(t, tt.code, code, stderr.String())
+			if tt.code == 0 {
+				assert.Equal(t, tt.stdout, stdout.String())
+			}
+			if tt.stderr != "" {
+				assert.Equal(t, tt.stderr, stderr.String())
+			}
+		})
+	}
+}
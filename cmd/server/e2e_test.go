@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/config"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/external/fakeprovider"
+	"exchange-rate-service/internal/handlers"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+	"exchange-rate-service/internal/store"
+	"exchange-rate-service/internal/utils"
+)
+
+// newTestRouter wires the router, handlers, services and client like main
+// does, against a fake provider
+func newTestRouter(t *testing.T) (*gin.Engine, *fakeprovider.Server) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+
+	provider := fakeprovider.New()
+	t.Cleanup(provider.Close)
+
+	cfg := provider.Config()
+	cfg.Retry.MaxAttempts = 1
+	cfg.Throttle = external.ThrottleConfig{} // Long historical ranges fetch day by day
+	client := external.NewExchangeRateClientWithConfig(cfg)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	rateFetcher := services.NewRateFetcher(client, memoryCache)
+	exchangeService := services.NewExchangeService(memoryCache, rateFetcher, client)
+	auditLog, err := store.NewAuditLog("", 0)
+	require.NoError(t, err)
+	exchangeService.SetAuditLog(auditLog)
+
+	router := setupRouter(routeHandlers{
+		exchange:     handlers.NewExchangeHandler(exchangeService),
+		admin:        handlers.NewAdminHandler(exchangeService),
+		audit:        handlers.NewAuditHandler(exchangeService),
+		job:          handlers.NewJobHandler(services.NewConversionJobs(exchangeService, services.DefaultJobConfig())),
+		subscription: handlers.NewSubscriptionHandler(services.NewWebhookDispatcher(services.DefaultWebhookConfig())),
+		quote:        handlers.NewQuoteHandler(services.NewQuotes(exchangeService, services.DefaultQuoteConfig())),
+		usage:        handlers.NewUsageHandler(services.NewUsageTracker()),
+		replication:  handlers.NewReplicationHandler(services.NewReplicator(services.DefaultReplicationConfig(), rateFetcher)),
+		watchlist:    handlers.NewWatchlistHandler(services.NewWatchlists(exchangeService, services.DefaultWatchlistConfig())),
+	}, routerOptions{
+		keyStore:    auth.NewKeyStore(nil),
+		tenants:     services.NewTenantRegistry(nil),
+		timeout:     10 * time.Second,
+		cors:        middleware.DefaultCORSConfig(),
+		compression: middleware.DefaultCompressionConfig(),
+	})
+	return router, provider
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// lastWeekday returns the most recent weekday at least a week ago, within
+// the historical lookback
+func lastWeekday() string {
+	day := utils.Today().AddDate(0, 0, -7)
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day.Format(utils.DateFormat)
+}
+
+func TestEndToEnd_LatestRates(t *testing.T) {
+	router, provider := newTestRouter(t)
+
+	w := serve(router, http.MethodGet, "/api/v1/rates/latest?from=EUR&to=INR", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var latest models.LatestRateResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &latest))
+	assert.InDelta(t, 83.125/0.92, latest.Rate, 1e-9)
+	assert.Equal(t, external.ProviderExchangeRateAPI, latest.Provider)
+	assert.Equal(t, models.OriginLive, latest.Origin)
+
+	w = serve(router, http.MethodGet, "/api/v1/rates/latest?from=EUR&to=INR", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &latest))
+	assert.Equal(t, models.OriginCache, latest.Origin)
+	assert.Len(t, provider.Requests(), 1, "the second request is served from the cache")
+
+	w = serve(router, http.MethodPost, "/api/v1/convert", `{"from":"USD","to":"JPY","amount":10,"provider":"frankfurter"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var conversion models.ConversionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &conversion))
+	assert.Equal(t, 1564.0, conversion.ConvertedAmount)
+	assert.Equal(t, external.ProviderFrankfurter, conversion.Provider)
+}
+
+func TestEndToEnd_HistoricalRates(t *testing.T) {
+	router, provider := newTestRouter(t)
+	date := lastWeekday()
+	provider.SetHistory(date, map[string]float64{"INR": 84.5, "EUR": 0.95})
+
+	w := serve(router, http.MethodPost, "/api/v1/convert", `{"from":"USD","to":"INR","amount":2,"date":"`+date+`"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var conversion models.ConversionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &conversion))
+	assert.Equal(t, 84.5, conversion.Rate)
+	assert.Equal(t, 169.0, conversion.ConvertedAmount)
+	assert.Equal(t, date, conversion.RateDate)
+
+	w = serve(router, http.MethodGet, "/api/v1/rates/historical?from=EUR&to=INR&start_date="+date+"&end_date="+date, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var historical models.HistoricalRateResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &historical))
+	require.Contains(t, historical.Rates, date)
+	assert.InDelta(t, 84.5/0.95, historical.Rates[date].Rate, 1e-9)
+	assert.Empty(t, historical.MissingDates)
+}
+
+func TestEndToEnd_CompressedHistoricalRates(t *testing.T) {
+	router, _ := newTestRouter(t)
+	start := utils.Today().AddDate(0, 0, -(utils.DefaultMaxRangeDays - 1)).Format(utils.DateFormat)
+	path := "/api/v1/rates/historical?from=USD&to=INR&start_date=" + start + "&end_date=" + utils.Today().Format(utils.DateFormat)
+
+	plain := serve(router, http.MethodGet, path, "")
+	require.Equal(t, http.StatusOK, plain.Code, plain.Body.String())
+	assert.Empty(t, plain.Header().Get("Content-Encoding"))
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	compressed := httptest.NewRecorder()
+	router.ServeHTTP(compressed, req)
+	require.Equal(t, http.StatusOK, compressed.Code)
+	assert.Equal(t, "gzip", compressed.Header().Get("Content-Encoding"))
+	assert.Less(t, compressed.Body.Len(), plain.Body.Len()/4)
+
+	reader, err := gzip.NewReader(compressed.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+
+	var want, got models.HistoricalRateResponse
+	require.NoError(t, json.Unmarshal(plain.Body.Bytes(), &want))
+	require.NoError(t, json.Unmarshal(body, &got))
+	assert.Len(t, got.Rates, utils.DefaultMaxRangeDays)
+	assert.Equal(t, want.Rates, got.Rates)
+	assert.Equal(t, want.MissingDates, got.MissingDates)
+}
+
+func TestEndToEnd_ProviderFailures(t *testing.T) {
+	router, provider := newTestRouter(t)
+
+	provider.FailNext(1, http.StatusServiceUnavailable)
+	w := serve(router, http.MethodGet, "/api/v1/rates/latest?from=USD&to=GBP", "")
+	assert.Equal(t, http.StatusBadGateway, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), models.ErrCodeProviderUnavailable)
+
+	w = serve(router, http.MethodGet, "/api/v1/rates/latest?from=USD&to=GBP", "")
+	assert.Equal(t, http.StatusOK, w.Code, "the provider recovered")
+
+	w = serve(router, http.MethodGet, "/api/v1/rates/latest?from=USD&to=BRL", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, "BRL is quoted by the provider but not supported")
+	assert.Contains(t, w.Body.String(), models.ErrCodeCurrencyUnsupported)
+}
+
+func TestSandbox(t *testing.T) {
+	fixtures := t.TempDir() + "/fixtures.json"
+	require.NoError(t, os.WriteFile(fixtures, []byte(`{"base":"EUR","latest":{"USD":1.25,"INR":100}}`), 0o644))
+
+	cfg := &config.Config{Fixtures: fixtures}
+	cfg.Provider = external.DefaultConfig()
+	cfg.Provider.DefaultProvider = external.ProviderFixer
+	cfg.Discrepancy.Providers = []string{external.ProviderExchangeRateAPI, external.ProviderFixer, external.ProviderFrankfurter}
+	cfg.Shadow.Provider = external.ProviderFixer
+
+	provider, err := startSandbox(cfg)
+	require.NoError(t, err)
+	defer provider.Close()
+
+	assert.Equal(t, external.ProviderExchangeRateAPI, cfg.Provider.DefaultProvider, "fixer.io is not sandboxed")
+	assert.Equal(t, []string{external.ProviderExchangeRateAPI, external.ProviderFrankfurter}, cfg.Discrepancy.Providers)
+	assert.Empty(t, cfg.Shadow.Provider)
+
+	client := external.NewExchangeRateClientWithConfig(cfg.Provider)
+	rate, err := client.GetRateForPair(context.Background(), "USD", "INR")
+	require.NoError(t, err)
+	assert.InDelta(t, 80, rate, 1e-9)
+
+	cfg.Fixtures = t.TempDir() + "/missing.json"
+	_, err = startSandbox(cfg)
+	assert.Error(t, err)
+}
@@ -1,69 +1,431 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // REFERENCE_TIMEZONE must resolve in minimal images
 
 	"github.com/gin-gonic/gin"
 
+	"exchange-rate-service/internal/auth"
 	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/chaos"
+	"exchange-rate-service/internal/config"
 	"exchange-rate-service/internal/external"
 	"exchange-rate-service/internal/handlers"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
 	"exchange-rate-service/internal/services"
+	"exchange-rate-service/internal/signing"
+	"exchange-rate-service/internal/store"
+	"exchange-rate-service/internal/utils"
 )
 
 func main() {
 	log.Println("Starting Exchange Rate Service...")
 
-	cacheService := cache.NewMemoryCache(1 * time.Hour) // 1 hour TTL
-	apiClient := external.NewExchangeRateClient()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	utils.SetReferenceLocation(cfg.Timezone)
+	utils.SetDateLimits(cfg.Dates.LookbackDays, cfg.Dates.MaxRangeDays)
+	models.SetRequestLimits(cfg.Limits)
+	log.Printf("Using reference time zone %s", cfg.Timezone)
+	if cfg.Mode == config.ModeSandbox {
+		sandbox, err := startSandbox(cfg)
+		if err != nil {
+			log.Fatalf("Invalid configuration: %v", err)
+		}
+		log.Printf("Running in sandbox mode: serving fixture rates from %s, no provider is called", sandbox.URL)
+	} else if err := loadRateSources(cfg); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	cacheService := cache.NewMemoryCacheWithOptions(cache.Options{
+		TTL:           cfg.Cache.TTL,
+		HistoricalTTL: cfg.Cache.HistoricalTTL,
+		MaxEntries:    cfg.Cache.MaxEntries,
+		StaleGrace:    cfg.Cache.StaleGrace,
+		Shards:        cfg.Cache.Shards,
+	})
+	var cacheSnapshots *cache.SnapshotWriter
+	var cacheJournal *cache.Journal
+	if cfg.Cache.SnapshotFile != "" {
+		restored, err := cacheService.LoadSnapshot(cfg.Cache.SnapshotFile)
+		if err != nil {
+			log.Printf("Starting with an empty cache: %v", err)
+		} else {
+			log.Printf("Restored %d cache entries from %s", restored, cfg.Cache.SnapshotFile)
+		}
+		cacheSnapshots = cache.NewSnapshotWriter(cacheService, cfg.Cache.SnapshotFile, cfg.Cache.SnapshotInterval)
+		if cfg.Cache.WriteStrategy != cache.WriteSnapshot {
+			cacheJournal, err = cache.OpenJournal(cache.JournalPath(cfg.Cache.SnapshotFile), cfg.Cache.WriteStrategy, cfg.Cache.FlushInterval)
+			if err != nil {
+				log.Fatalf("Failed to open cache journal: %v", err)
+			}
+			cacheService.SetJournal(cacheJournal)
+			log.Printf("Persisting cache changes %s", cfg.Cache.WriteStrategy)
+		}
+	}
+	apiClient := external.NewExchangeRateClientWithConfig(cfg.Provider)
+	logChaos("provider", cfg.Provider.Chaos)
+	logChaos("API", cfg.Chaos)
+	for provider, maskedKey := range cfg.Provider.Credentials.Configured() {
+		log.Printf("Using API key %s for provider %s", maskedKey, provider)
+	}
 	rateFetcher := services.NewRateFetcher(apiClient, cacheService)
+	rateFetcher.SetNegativeTTL(cfg.Cache.NegativeTTL)
+	rateFetcher.SetPivot(cfg.Fetch.Pivot, cfg.Fetch.PerBaseProviders)
+	rateFetcher.SetFetchPool(cfg.Fetch.Pool)
+	rateFetcher.SetHotPairs(cfg.Fetch.Hot)
+	if cfg.Fetch.Hot.Enabled() {
+		log.Printf("Adapting refresh intervals to requests every %v (hot %v, cold %v)", cfg.Fetch.Hot.Window, cfg.Fetch.Hot.HotInterval, cfg.Fetch.Hot.ColdInterval)
+	}
 	exchangeService := services.NewExchangeService(cacheService, rateFetcher, apiClient)
+	shadowTraffic := services.NewShadowTraffic(apiClient, cfg.Shadow)
+	exchangeService.SetShadowTraffic(shadowTraffic)
+
+	configReloader := &reloader{exchangeService: exchangeService, rateFetcher: rateFetcher, client: apiClient, shadow: shadowTraffic}
+	configReloader.apply(cfg.Runtime())
+	for _, pair := range cfg.Fetch.Pairs {
+		log.Printf("Refreshing %s/%s every %v (priority %d)", pair.From, pair.To, pair.Interval, pair.Priority)
+	}
+
+	archive, err := store.NewArchive(cfg.Snapshot.Dir)
+	if err != nil {
+		log.Fatalf("Failed to open rate archive: %v", err)
+	}
+	exchangeService.SetArchive(archive)
+	exchangeService.SetBulkHistoricalConcurrency(cfg.BulkLookups)
+	if cfg.Snapshot.IntradayRetention > 0 {
+		history := store.NewRateHistory(cfg.Snapshot.IntradayRetention)
+		rateFetcher.SetHistory(history)
+		exchangeService.SetRateHistory(history)
+	}
+	auditLog, err := store.NewAuditLog(cfg.AuditLog, cfg.AuditMax)
+	if err != nil {
+		log.Fatalf("Failed to open audit log: %v", err)
+	}
+	exchangeService.SetAuditLog(auditLog)
+	if cfg.SigningKey != "" {
+		signer, err := signing.LoadSigner(cfg.SigningKey)
+		if err != nil {
+			log.Fatalf("Failed to load signing key: %v", err)
+		}
+		exchangeService.SetSigner(signer)
+		log.Printf("Signing rates with key %s", signer.PublicKey().KeyID)
+	}
+	snapshotScheduler := services.NewSnapshotScheduler(rateFetcher, archive, cfg.Snapshot.Hour, cfg.Snapshot.Minute)
+	discrepancyMonitor := services.NewDiscrepancyMonitor(apiClient, cfg.Discrepancy)
+	exchangeService.SetDiscrepancyMonitor(discrepancyMonitor)
+	if shadowTraffic.Enabled() {
+		log.Printf("Comparing %.1f%% of served rates with shadow provider %s", cfg.Shadow.Percent, cfg.Shadow.Provider)
+	}
+
+	sloTracker := services.NewSLOTracker(cfg.SLO)
 	handler := handlers.NewExchangeHandler(exchangeService)
+	handler.SetSLOTracker(sloTracker)
+	adminHandler := handlers.NewAdminHandler(exchangeService)
+	adminHandler.SetReloader(configReloader.reload)
+	auditHandler := handlers.NewAuditHandler(exchangeService)
+	conversionJobs := services.NewConversionJobs(exchangeService, cfg.Jobs)
+	jobHandler := handlers.NewJobHandler(conversionJobs)
+	webhooks := services.NewWebhookDispatcher(cfg.Webhooks)
+	replicator := services.NewReplicator(cfg.Replication, rateFetcher)
+	replicator.SetReceiveListener(webhooks.Publish)
+	rateFetcher.SetStandby(replicator.Standby)
+	rateFetcher.SetCycleListener(func(rates []services.FetchedRate) {
+		webhooks.Publish(rates)
+		replicator.Publish(rates)
+	})
+	replicationHandler := handlers.NewReplicationHandler(replicator)
+	subscriptionHandler := handlers.NewSubscriptionHandler(webhooks)
+	quoteHandler := handlers.NewQuoteHandler(services.NewQuotes(exchangeService, cfg.Quotes))
+	watchlistHandler := handlers.NewWatchlistHandler(services.NewWatchlists(exchangeService, cfg.Watchlists))
+	usageTracker := services.NewUsageTracker()
+	usageHandler := handlers.NewUsageHandler(usageTracker)
+
+	keyStore := auth.NewKeyStore(cfg.APIKeys)
+	tenants := services.NewTenantRegistry(cfg.Tenants)
+	if tenants.Len() > 0 {
+		log.Printf("Serving tenants %v", tenants.IDs())
+	}
+	var jwtVerifier *auth.JWTVerifier
+	if cfg.JWT.JWKSURL != "" {
+		log.Printf("Verifying JWT bearer tokens against %s", cfg.JWT.JWKSURL)
+		jwtVerifier = auth.NewJWTVerifier(cfg.JWT)
+	}
+	if keyStore.Len() == 0 && jwtVerifier == nil {
+		log.Println("No API keys or JWKS URL configured, admin endpoints are disabled")
+	}
+
+	// Checked before any background work starts, so a fail-fast exit leaves
+	// nothing half-running
+	startupChecker := services.NewStartupChecker(cfg.Startup, startupChecks(cfg, apiClient, cacheService, jwtVerifier)...)
+	if _, err := startupChecker.Run(); err != nil {
+		log.Fatalf("Startup checks failed: %v", err)
+	}
 
 	rateFetcher.Start()
+	snapshotScheduler.Start()
+	discrepancyMonitor.Start()
+	conversionJobs.Start()
+	webhooks.Start()
+	replicator.Start()
+	sloTracker.Start()
+	if cacheJournal != nil {
+		cacheJournal.Start()
+	}
+	if cacheSnapshots != nil {
+		cacheSnapshots.Start()
+	}
 
-	router := setupRouter(handler)
+	if cfg.File != "" && cfg.Watch > 0 {
+		log.Printf("Watching %s for configuration changes", cfg.File)
+		go config.WatchFile(context.Background(), cfg.File, cfg.Watch, func() {
+			configReloader.reload()
+		})
+	}
 
-	setupGracefulShutdown(rateFetcher)
+	accessLog, err := middleware.NewAccessLogger(cfg.AccessLog)
+	if err != nil {
+		log.Fatalf("Failed to open access log: %v", err)
+	}
+
+	router := setupRouter(routeHandlers{
+		exchange:     handler,
+		admin:        adminHandler,
+		audit:        auditHandler,
+		job:          jobHandler,
+		subscription: subscriptionHandler,
+		quote:        quoteHandler,
+		usage:        usageHandler,
+		replication:  replicationHandler,
+		watchlist:    watchlistHandler,
+	}, routerOptions{
+		keyStore:     keyStore,
+		jwtVerifier:  jwtVerifier,
+		tenants:      tenants,
+		sloTracker:   sloTracker,
+		usageTracker: usageTracker,
+		timeout:      cfg.Timeout,
+		rateLimit:    cfg.RateLimit,
+		cors:         cfg.CORS,
+		compression:  cfg.Compression,
+		adminAccess:  cfg.Admin,
+		accessLog:    accessLog,
+		faults:       cfg.Chaos,
+		proxies:      cfg.Proxies,
+		anonymous:    cfg.Anonymous,
+	})
+
+	server := &http.Server{Addr: net.JoinHostPort(cfg.Bind, cfg.Port), Handler: router, TLSConfig: cfg.TLS}
+	stopped := setupGracefulShutdown(server, cfg.Shutdown, runningServices{
+		exchangeService:    exchangeService,
+		startupChecker:     startupChecker,
+		rateFetcher:        rateFetcher,
+		snapshotScheduler:  snapshotScheduler,
+		discrepancyMonitor: discrepancyMonitor,
+		shadowTraffic:      shadowTraffic,
+		conversionJobs:     conversionJobs,
+		webhooks:           webhooks,
+		replicator:         replicator,
+		sloTracker:         sloTracker,
+		cacheSnapshots:     cacheSnapshots,
+		cacheJournal:       cacheJournal,
+		auditLog:           auditLog,
+		accessLog:          accessLog,
+	})
 
-	port := getPort()
-	log.Printf("Server starting on port %s", port)
-	if err := router.Run(":" + port); err != nil {
+	if cfg.TLS != nil {
+		log.Printf("Server listening on %s over HTTPS", server.Addr)
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		log.Printf("Server listening on %s", server.Addr)
+		err = server.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("Failed to start server: %v", err)
 	}
+	<-stopped
 }
 
-func setupRouter(handler *handlers.ExchangeHandler) *gin.Engine {
+// logChaos warns that faults are injected into target's responses
+func logChaos(target string, faults chaos.Config) {
+	if faults.Enabled() {
+		log.Printf("Chaos mode: injecting faults into %s responses (%.0f%% errors, %.0f%% malformed, %.0f%% delayed by %v)", target, faults.ErrorRate*100, faults.MalformedRate*100, faults.LatencyRate*100, faults.Latency)
+	}
+}
+
+// routeHandlers holds the handlers setupRouter mounts
+type routeHandlers struct {
+	exchange     *handlers.ExchangeHandler
+	admin        *handlers.AdminHandler
+	audit        *handlers.AuditHandler
+	job          *handlers.JobHandler
+	subscription *handlers.SubscriptionHandler
+	quote        *handlers.QuoteHandler
+	usage        *handlers.UsageHandler
+	replication  *handlers.ReplicationHandler
+	watchlist    *handlers.WatchlistHandler
+}
+
+// routerOptions configures the middleware setupRouter installs. A nil
+// sloTracker, usageTracker or accessLog turns its middleware off.
+type routerOptions struct {
+	keyStore     *auth.KeyStore
+	jwtVerifier  *auth.JWTVerifier
+	tenants      *services.TenantRegistry
+	sloTracker   *services.SLOTracker
+	usageTracker *services.UsageTracker
+	timeout      time.Duration
+	rateLimit    middleware.RateLimitConfig
+	cors         middleware.CORSConfig
+	compression  middleware.CompressionConfig
+	adminAccess  middleware.AdminAccessConfig
+	accessLog    *middleware.AccessLogger
+	faults       chaos.Config
+	proxies      []string    // Addresses X-Forwarded-For is believed from
+	anonymous    auth.Policy // What callers without a key or token may do
+}
+
+func setupRouter(h routeHandlers, opts routerOptions) *gin.Engine {
 	if os.Getenv("GIN_MODE") == "" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	router := gin.Default()
+	router := gin.New()
+	// Gin believes X-Forwarded-For from anyone unless told otherwise, which
+	// would let clients pick their rate limit bucket and caller ID
+	if err := router.SetTrustedProxies(opts.proxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
 
-	router.Use(corsMiddleware())
-	router.Use(gin.Logger())
+	if opts.accessLog != nil {
+		router.Use(opts.accessLog.Handler())
+	}
+	router.Use(middleware.TrackSLO(opts.sloTracker))
+	router.Use(middleware.CORS(opts.cors))
+	router.Use(middleware.Compress(opts.compression))
 	router.Use(gin.Recovery())
+	router.Use(middleware.RateLimit(opts.rateLimit))
+	router.Use(middleware.LimitBody())
+	router.Use(middleware.Deadline(opts.timeout))
+	router.Use(middleware.Chaos(opts.faults))
+	router.Use(middleware.Authenticate(opts.keyStore, opts.jwtVerifier))
+	router.Use(middleware.TrackUsage(opts.usageTracker))
+	router.Use(middleware.ResolveTenant(opts.tenants))
+	router.Use(middleware.EnforcePolicy(opts.anonymous))
+	router.Use(middleware.HTTPCache())
+
+	latest := middleware.RequireEntitlement(auth.EntitlementLatest)
+	historical := middleware.RequireEntitlement(auth.EntitlementHistorical)
+	analytics := middleware.RequireEntitlement(auth.EntitlementAnalytics)
+	conversion := middleware.RequireConversionEntitlement()
 
 	v1 := router.Group("/api/v1")
 	{
-		v1.POST("/convert", handler.ConvertCurrency)
-		v1.GET("/convert", handler.ConvertCurrencyQuery)
+		v1.POST("/convert", conversion, h.exchange.ConvertCurrency)
+		v1.POST("/convert/chain", latest, h.exchange.ConvertChain)
+		v1.POST("/convert/simulate", conversion, h.exchange.SimulateConversion)
+		v1.GET("/convert", conversion, h.exchange.ConvertCurrencyQuery)
+		v1.GET("/conversions/:id", h.audit.GetReceipt)
 
 		// Rate endpoints
-		v1.GET("/rates/latest", handler.GetLatestRate)
-		v1.POST("/rates/historical", handler.GetHistoricalRates)
-		v1.GET("/rates/historical", handler.GetHistoricalRatesQuery)
+		v1.GET("/rates/latest", latest, h.exchange.GetLatestRate)
+		v1.GET("/rates/table", latest, h.exchange.GetRateTable)
+		v1.GET("/rates/compare", latest, h.exchange.CompareRates)
+		v1.POST("/rates/historical", historical, h.exchange.GetHistoricalRates)
+		v1.POST("/rates/historical/bulk", historical, h.exchange.GetBulkHistoricalRates)
+		v1.GET("/rates/historical", historical, h.exchange.GetHistoricalRatesQuery)
+		v1.GET("/rates/intraday", historical, h.exchange.GetIntradayRates)
+		v1.GET("/rates/trend", analytics, h.exchange.GetRateTrend)
+		v1.GET("/rates/diff", historical, h.exchange.GetRateDiff)
+		v1.GET("/rates/recommendation", analytics, h.exchange.GetRateRecommendation)
+		v1.GET("/rates/correlation", analytics, h.exchange.GetCorrelation)
+		v1.GET("/rates/forecast", analytics, h.exchange.GetRateForecast)
+		v1.GET("/reports/historical", historical, h.exchange.GetHistoricalReport)
+
+		v1.GET("/currencies", h.exchange.GetSupportedCurrencies)
+		v1.GET("/keys", h.exchange.GetSigningKeys)
+		v1.GET("/health", h.exchange.GetHealth)
+		v1.GET("/stats/cache", h.exchange.GetCacheStats)
+		v1.GET("/stats/client", h.exchange.GetClientStats)
+		v1.GET("/stats/providers", h.exchange.GetProviderStats)
+		v1.GET("/stats/discrepancies", h.exchange.GetDiscrepancyStats)
+		v1.GET("/stats/shadow", h.exchange.GetShadowStats)
+		v1.GET("/stats/usage", middleware.RestrictAdmin(opts.adminAccess), middleware.RequireRole(auth.RoleAdmin), middleware.RequireEntitlement(auth.EntitlementAdmin), h.usage.GetUsage)
+		v1.GET("/me/usage", h.usage.GetOwnUsage)
+		v1.GET("/stats/replication", h.replication.GetStats)
+
+		// Peers sign their pushes with the replication secret instead of
+		// presenting an API key
+		v1.POST("/replication/rates", h.replication.ReceiveRates)
+
+		admin := v1.Group("/admin", middleware.RestrictAdmin(opts.adminAccess), middleware.RequireRole(auth.RoleAdmin), middleware.RequireEntitlement(auth.EntitlementAdmin))
+		{
+			admin.DELETE("/cache", h.admin.ClearCache)
+			admin.DELETE("/cache/:from/:to", h.admin.InvalidatePair)
+			admin.POST("/rates/refetch", h.admin.RefetchHistoricalRates)
+			admin.POST("/rates/import", h.admin.ImportHistoricalRates)
+			admin.POST("/cache/warm", h.admin.WarmCache)
+			admin.POST("/reload", h.admin.Reload)
+			admin.POST("/currencies", h.admin.AddCurrency)
+			admin.DELETE("/currencies/:code", h.admin.RemoveCurrency)
+		}
+
+		// Rows of a job may be at a date, which the file is only read for later
+		v1.POST("/jobs/convert", latest, historical, h.job.SubmitConversion)
+		v1.GET("/jobs/:id", h.job.GetJob)
+		v1.GET("/jobs/:id/result", h.job.GetResult)
+
+		// Subscriptions make the server push to their callback, so they
+		// are only taken from callers with a key
+		subscriptions := v1.Group("/subscriptions", middleware.RequireAPIKey())
+		{
+			subscriptions.POST("", latest, h.subscription.Subscribe)
+			subscriptions.GET("", h.subscription.List)
+			subscriptions.GET("/:id", h.subscription.Get)
+			subscriptions.DELETE("/:id", h.subscription.Unsubscribe)
+			subscriptions.GET("/:id/dead_letters", h.subscription.GetDeadLetters)
+			subscriptions.POST("/:id/dead_letters/redeliver", h.subscription.Redeliver)
+		}
 
-		v1.GET("/currencies", handler.GetSupportedCurrencies)
-		v1.GET("/health", handler.GetHealth)
-		v1.GET("/stats/cache", handler.GetCacheStats)
+		v1.POST("/quotes", latest, h.quote.CreateQuote)
+		v1.GET("/quotes/:id", h.quote.GetQuote)
+		v1.POST("/quotes/:id/execute", latest, h.quote.ExecuteQuote)
+
+		// Watchlists belong to their caller, which must not be an IP anyone
+		// can claim
+		watchlists := v1.Group("/watchlists", middleware.RequireAPIKey())
+		{
+			watchlists.POST("", h.watchlist.CreateWatchlist)
+			watchlists.GET("", h.watchlist.ListWatchlists)
+			watchlists.GET("/:id", h.watchlist.GetWatchlist)
+			watchlists.PUT("/:id", h.watchlist.UpdateWatchlist)
+			watchlists.DELETE("/:id", h.watchlist.DeleteWatchlist)
+			watchlists.GET("/:id/rates", latest, h.watchlist.GetWatchlistRates)
+		}
+
+		audit := v1.Group("/audit", middleware.RequireRole(auth.RoleAuditor))
+		{
+			audit.GET("/conversions", h.audit.GetConversions)
+			audit.GET("/conversions/:id", h.audit.GetConversion)
+		}
 	}
 
-	router.GET("/health", handler.GetHealth)
+	router.GET("/health", h.exchange.GetHealth)
+	router.GET("/healthz", h.exchange.Liveness)
+	router.GET("/readyz", h.exchange.Readiness)
+	router.GET("/metrics", h.exchange.GetMetrics)
 	router.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"service": "Exchange Rate Service",
@@ -75,37 +437,71 @@ func setupRouter(handler *handlers.ExchangeHandler) *gin.Engine {
 	return router
 }
 
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-
-		c.Next()
-	}
+// runningServices holds what setupGracefulShutdown stops. cacheSnapshots and
+// cacheJournal are nil when the cache isn't persisted.
+type runningServices struct {
+	exchangeService    *services.ExchangeService
+	startupChecker     *services.StartupChecker
+	rateFetcher        *services.RateFetcher
+	snapshotScheduler  *services.SnapshotScheduler
+	discrepancyMonitor *services.DiscrepancyMonitor
+	shadowTraffic      *services.ShadowTraffic
+	conversionJobs     *services.ConversionJobs
+	webhooks           *services.WebhookDispatcher
+	replicator         *services.Replicator
+	sloTracker         *services.SLOTracker
+	cacheSnapshots     *cache.SnapshotWriter
+	cacheJournal       *cache.Journal
+	auditLog           *store.AuditLog
+	accessLog          *middleware.AccessLogger
 }
 
-func setupGracefulShutdown(rateFetcher *services.RateFetcher) {
+// setupGracefulShutdown drains the server on SIGTERM or an interrupt: /readyz
+// fails for the drain delay, so load balancers stop routing to the pod, then
+// the listener closes and requests in flight get the shutdown timeout to
+// complete before the background services stop. The returned channel is
+// closed once everything has stopped.
+func setupGracefulShutdown(server *http.Server, shutdown config.ShutdownConfig, running runningServices) <-chan struct{} {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	stopped := make(chan struct{})
 
 	go func() {
 		<-c
-		log.Println("Shutting down gracefully...")
-		rateFetcher.Stop()
-		os.Exit(0)
-	}()
-}
+		log.Printf("Shutting down gracefully, draining for %v...", shutdown.DrainDelay)
+		running.exchangeService.Drain()
+		time.Sleep(shutdown.DrainDelay)
 
-func getPort() string {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-	return port
+		ctx, cancel := context.WithTimeout(context.Background(), shutdown.Timeout)
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("Requests still in flight after %v were cut off: %v", shutdown.Timeout, err)
+		}
+		cancel()
+
+		running.startupChecker.Stop()
+		running.snapshotScheduler.Stop()
+		running.discrepancyMonitor.Stop()
+		running.shadowTraffic.Stop()
+		running.conversionJobs.Stop()
+		running.rateFetcher.Stop()
+		running.webhooks.Stop()
+		running.replicator.Stop()
+		running.sloTracker.Stop()
+		if running.cacheSnapshots != nil {
+			// Saved last, so the snapshot holds the final fetched rates
+			running.cacheSnapshots.Stop()
+		}
+		if running.cacheJournal != nil {
+			// Closed after the last snapshot, which compacts it
+			running.cacheJournal.Stop()
+		}
+		if err := running.auditLog.Close(); err != nil {
+			log.Printf("Failed to close audit log: %v", err)
+		}
+		if err := running.accessLog.Close(); err != nil {
+			log.Printf("Failed to close access log: %v", err)
+		}
+		close(stopped)
+	}()
+	return stopped
 }
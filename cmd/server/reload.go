@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"sync"
+
+	"exchange-rate-service/internal/config"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+	"exchange-rate-service/internal/utils"
+)
+
+// reloader re-reads the configuration and applies its runtime settings to the
+// running services. Reloads are serialised.
+type reloader struct {
+	mu              sync.Mutex
+	exchangeService *services.ExchangeService
+	rateFetcher     *services.RateFetcher
+	client          *external.ExchangeRateClient
+	shadow          *services.ShadowTraffic
+}
+
+func (r *reloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Printf("Configuration reload rejected: %v", err)
+		return err
+	}
+
+	r.apply(cfg.Runtime())
+	log.Printf("Configuration reloaded: %d currencies, default fetch interval %v, %d pair schedules, default provider %s",
+		len(cfg.Currencies), cfg.Fetch.Interval, len(cfg.Fetch.Pairs), cfg.Provider.DefaultProvider)
+	return nil
+}
+
+func (r *reloader) apply(runtime config.Runtime) {
+	previous := models.SupportedCurrencyCodes()
+	models.SetSupportedCurrencies(runtime.Currencies)
+	utils.SetHolidays(runtime.Holidays)
+	models.SetAmountLimits(runtime.Amounts)
+	models.SetCurrencyAliases(runtime.Aliases)
+
+	r.exchangeService.SetMarkup(services.NewMarkup(runtime.Markup.GlobalPercent, runtime.Markup.Pairs))
+	r.rateFetcher.SetSchedule(runtime.Fetch.Interval, runtime.Fetch.Pairs)
+
+	previousProvider := r.client.DefaultProvider()
+	if err := r.client.SetDefaultProvider(runtime.Providers.Default); err != nil {
+		log.Printf("Keeping default provider %s: %v", previousProvider, err)
+	}
+	r.rateFetcher.SetMetalsProvider(runtime.Providers.Metals)
+	r.shadow.SetProvider(runtime.Providers.Shadow)
+
+	// Newly supported currencies, and every currency of a new default
+	// provider, have no cached rates until their first refresh
+	changed := !reflect.DeepEqual(previous, models.SupportedCurrencyCodes()) || r.client.DefaultProvider() != previousProvider
+	if changed && r.rateFetcher.IsRunning() {
+		go r.rateFetcher.FetchNow(context.Background())
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"exchange-rate-service/internal/config"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/external/fakeprovider"
+)
+
+// startSandbox serves the fixtures from a local fake provider and points
+// every provider setting at it, so no external provider is called. Settings
+// naming fixer.io, which the fake provider doesn't emulate, fall back to the
+// default provider or are dropped.
+func startSandbox(cfg *config.Config) (*fakeprovider.Server, error) {
+	fixtures := fakeprovider.DefaultFixtures()
+	if cfg.Fixtures != "" {
+		var err error
+		if fixtures, err = fakeprovider.LoadFixtures(cfg.Fixtures); err != nil {
+			return nil, fmt.Errorf("failed to load sandbox fixtures: %w", err)
+		}
+	}
+	provider := fakeprovider.New()
+	provider.SetFixtures(fixtures)
+
+	sandboxed := provider.Config()
+	sandboxed.DefaultProvider = cfg.Provider.DefaultProvider
+	if !servedBySandbox(sandboxed.DefaultProvider) {
+		sandboxed.DefaultProvider = external.ProviderExchangeRateAPI
+	}
+	sandboxed.Timeout = cfg.Provider.Timeout
+	sandboxed.DecodeMode = cfg.Provider.DecodeMode
+	cfg.Provider = sandboxed
+
+	cfg.Fetch.Metals = sandboxed.DefaultProvider
+	cfg.Fetch.PerBaseProviders = sandboxProviders(cfg.Fetch.PerBaseProviders)
+	cfg.Discrepancy.Providers = sandboxProviders(cfg.Discrepancy.Providers)
+	if !servedBySandbox(cfg.Shadow.Provider) || cfg.Shadow.Provider == sandboxed.DefaultProvider {
+		cfg.Shadow.Provider = ""
+	}
+	return provider, nil
+}
+
+func servedBySandbox(provider string) bool {
+	return provider == external.ProviderExchangeRateAPI || provider == external.ProviderFrankfurter
+}
+
+// sandboxProviders keeps the providers the sandbox serves
+func sandboxProviders(providers []string) []string {
+	var kept []string
+	for _, provider := range providers {
+		if servedBySandbox(provider) {
+			kept = append(kept, provider)
+		}
+	}
+	return kept
+}
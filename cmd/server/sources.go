@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"plugin"
+	"time"
+
+	"exchange-rate-service/internal/config"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+	"exchange-rate-service/pkg/exchange"
+)
+
+// loadRateSources opens the source plugins, whose init functions register
+// their kinds of source with exchange.RegisterSource, then builds the
+// declared sources and adds them to the client's providers
+func loadRateSources(cfg *config.Config) error {
+	for _, path := range cfg.Plugins {
+		if _, err := plugin.Open(path); err != nil {
+			return fmt.Errorf("failed to load rate source plugin %s: %w", path, err)
+		}
+		log.Printf("Loaded rate source plugin %s", path)
+	}
+
+	for _, declared := range cfg.Sources {
+		source, err := exchange.NewSource(declared.Kind, declared.Name, declared.Settings)
+		if err != nil {
+			return err
+		}
+		cfg.Provider.Providers = append(cfg.Provider.Providers, &sourceProvider{source: source})
+		log.Printf("Registered %s rate source %s", declared.Kind, declared.Name)
+	}
+	return nil
+}
+
+// sourceProvider adapts a declared source to the client's providers
+type sourceProvider struct {
+	source exchange.RateSource
+}
+
+func (p *sourceProvider) Name() string {
+	return p.source.Name()
+}
+
+func (p *sourceProvider) LatestRates(ctx context.Context, base string) (*models.ExternalAPIResponse, error) {
+	rates, err := p.source.LatestRates(ctx, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest rates: %w", err)
+	}
+	return p.response(rates, ""), nil
+}
+
+func (p *sourceProvider) HistoricalRates(ctx context.Context, base, date string) (*models.ExternalAPIResponse, error) {
+	day, err := time.Parse(utils.DateFormat, date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid historical date %q: %w", date, err)
+	}
+	rates, err := p.source.HistoricalRates(ctx, base, day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch historical rates: %w", err)
+	}
+	return p.response(rates, date), nil
+}
+
+// HasHistoricalData is true: a source without history says so per request
+func (p *sourceProvider) HasHistoricalData() bool {
+	return true
+}
+
+func (p *sourceProvider) ProbeURL() string {
+	return ""
+}
+
+func (p *sourceProvider) response(rates *exchange.Rates, date string) *models.ExternalAPIResponse {
+	response := &models.ExternalAPIResponse{
+		Provider: p.source.Name(),
+		Base:     rates.Base,
+		Date:     date,
+		Rates:    rates.Rates,
+	}
+	if !rates.PublishedAt.IsZero() {
+		response.TimeLastUpdated = rates.PublishedAt.Unix()
+	}
+	return response
+}
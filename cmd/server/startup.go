@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/config"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/services"
+)
+
+// startupChecks lists the dependencies verified before the port is bound:
+// the default provider and the cache always, and the cache snapshot file,
+// snapshot directory and JWKS URL when they are configured
+func startupChecks(cfg *config.Config, client *external.ExchangeRateClient, cacheService *cache.MemoryCache, jwtVerifier *auth.JWTVerifier) []services.StartupCheck {
+	checks := []services.StartupCheck{
+		{Name: "provider " + external.CanonicalProvider(cfg.Provider.DefaultProvider), Check: func(ctx context.Context) error {
+			return client.Probe()
+		}},
+		{Name: "cache", Check: func(ctx context.Context) error {
+			return cacheService.Ping()
+		}},
+	}
+	if cfg.Cache.SnapshotFile != "" {
+		checks = append(checks, services.StartupCheck{Name: "cache snapshot", Check: func(ctx context.Context) error {
+			return checkWritable(filepath.Dir(cfg.Cache.SnapshotFile))
+		}})
+	}
+	if cfg.Snapshot.Dir != "" {
+		checks = append(checks, services.StartupCheck{Name: "rate archive", Check: func(ctx context.Context) error {
+			return checkWritable(cfg.Snapshot.Dir)
+		}})
+	}
+	if jwtVerifier != nil {
+		checks = append(checks, services.StartupCheck{Name: "jwks", Check: func(ctx context.Context) error {
+			return jwtVerifier.Refresh()
+		}})
+	}
+	return checks
+}
+
+// checkWritable creates and removes a file in dir
+func checkWritable(dir string) error {
+	file, err := os.CreateTemp(dir, ".startup-check-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
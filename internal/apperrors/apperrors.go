@@ -0,0 +1,126 @@
+// Package apperrors defines the kinds of failure the service reports, so
+// callers can tell them apart with errors.Is instead of matching messages:
+//
+//	if errors.Is(err, apperrors.ErrUnsupportedCurrency) { ... }
+//
+// Every kind has the stable error code the API answers it with. Coded
+// errors of package models match the kind of their code, and errors of
+// package external wrap the kind of the failure, so the same checks work on
+// errors of the HTTP handlers, the services and the embedded library.
+package apperrors
+
+import (
+	"errors"
+
+	"exchange-rate-service/internal/models"
+)
+
+// Kind is a sentinel error for a class of failure. A Kind made with Variant
+// is also its parent: errors.Is(external.ErrCircuitOpen, ErrProviderBusy)
+// holds.
+type Kind struct {
+	code    string
+	message string
+	parent  *Kind
+}
+
+// New returns a kind answered with code
+func New(code, message string) *Kind {
+	return &Kind{code: code, message: message}
+}
+
+func (k *Kind) Error() string {
+	return k.message
+}
+
+// ErrorCode returns the API error code of the kind, e.g. RATE_NOT_FOUND
+func (k *Kind) ErrorCode() string {
+	return k.code
+}
+
+// Unwrap returns the kind k is a variant of, nil for the kinds below
+func (k *Kind) Unwrap() error {
+	if k.parent == nil {
+		return nil
+	}
+	return k.parent
+}
+
+// Variant returns a more specific kind of k, with the same code and its own
+// message
+func (k *Kind) Variant(message string) *Kind {
+	return &Kind{code: k.code, message: message, parent: k}
+}
+
+// Kinds of failure, by the code they are answered with
+var (
+	ErrInvalidRequest      = New(models.ErrCodeInvalidRequest, "invalid request")
+	ErrMissingParameter    = New(models.ErrCodeMissingParameter, "missing parameter")
+	ErrUnsupportedCurrency = New(models.ErrCodeCurrencyUnsupported, "unsupported currency")
+	ErrInvalidAmount       = New(models.ErrCodeAmountInvalid, "invalid amount")
+	ErrAmountBelowMinimum  = New(models.ErrCodeAmountBelowMinimum, "amount below minimum")
+	ErrAmountAboveMaximum  = New(models.ErrCodeAmountAboveMaximum, "amount above maximum")
+	ErrInvalidDate         = New(models.ErrCodeDateInvalid, "invalid date")
+	ErrDateOutOfRange      = New(models.ErrCodeDateOutOfRange, "date out of range")
+	ErrInvalidValue        = New(models.ErrCodeValueInvalid, "invalid value")
+	ErrLimitExceeded       = New(models.ErrCodeLimitExceeded, "limit exceeded")
+	ErrPayloadTooLarge     = New(models.ErrCodePayloadTooLarge, "payload too large")
+	ErrUnknownProvider     = New(models.ErrCodeProviderUnknown, "unknown provider")
+	ErrRateNotFound        = New(models.ErrCodeRateNotFound, "rate not found")
+	ErrRateUnavailable     = New(models.ErrCodeProviderUnavailable, "rate unavailable from the provider")
+	ErrProviderBusy        = New(models.ErrCodeProviderBusy, "provider is not called for now")
+	ErrProviderTimeout     = New(models.ErrCodeTimeout, "provider did not answer in time")
+	ErrCanceled            = New(models.ErrCodeCanceled, "request canceled")
+	ErrNotFound            = New(models.ErrCodeNotFound, "not found")
+	ErrJobPending          = New(models.ErrCodeJobPending, "job pending")
+	ErrQuoteExpired        = New(models.ErrCodeQuoteExpired, "quote expired")
+	ErrQuoteExecuted       = New(models.ErrCodeQuoteExecuted, "quote already executed")
+	ErrNotImplemented      = New(models.ErrCodeNotImplemented, "not implemented")
+)
+
+// ErrNoHistoricalData is returned when a provider serves no historical rates
+var ErrNoHistoricalData = ErrNotImplemented.Variant("historical data not available with current API")
+
+// Wrap returns err, unchanged in message and unwrapping, as a failure of
+// kind. It is nil when err is.
+func Wrap(kind *Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &wrapped{kind: kind, err: err}
+}
+
+type wrapped struct {
+	kind *Kind
+	err  error
+}
+
+func (w *wrapped) Error() string {
+	return w.err.Error()
+}
+
+func (w *wrapped) Unwrap() error {
+	return w.err
+}
+
+func (w *wrapped) Is(target error) bool {
+	return errors.Is(w.kind, target)
+}
+
+func (w *wrapped) ErrorCode() string {
+	return w.kind.code
+}
+
+// CodeOf returns the error code of the first kind or models.CodedError in
+// err's chain, and false when there is neither
+func CodeOf(err error) (string, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch e := err.(type) {
+		case *models.CodedError:
+			return e.Code, true
+		case interface{ ErrorCode() string }:
+			return e.ErrorCode(), true
+		}
+	}
+	return "", false
+}
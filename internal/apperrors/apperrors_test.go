@@ -0,0 +1,54 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"exchange-rate-service/internal/models"
+)
+
+func TestVariant_IsItsParent(t *testing.T) {
+	throttled := ErrProviderBusy.Variant("upstream request throttled")
+
+	assert.ErrorIs(t, throttled, ErrProviderBusy)
+	assert.NotErrorIs(t, ErrProviderBusy, throttled)
+	assert.Equal(t, models.ErrCodeProviderBusy, throttled.ErrorCode())
+	assert.Equal(t, "upstream request throttled", throttled.Error())
+}
+
+func TestWrap_KeepsMessageAndCause(t *testing.T) {
+	cause := errors.New("connection reset")
+	err := fmt.Errorf("giving up: %w", Wrap(ErrRateUnavailable, cause))
+
+	assert.Equal(t, "giving up: connection reset", err.Error())
+	assert.ErrorIs(t, err, ErrRateUnavailable)
+	assert.ErrorIs(t, err, cause)
+	assert.NotErrorIs(t, err, ErrProviderTimeout)
+	assert.Nil(t, Wrap(ErrRateUnavailable, nil))
+}
+
+func TestCodedError_IsKindOfItsCode(t *testing.T) {
+	err := fmt.Errorf("invalid target currency: %w",
+		models.NewFieldError(models.ErrCodeCurrencyUnsupported, "to", "unsupported currency: XXX"))
+
+	assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+	assert.NotErrorIs(t, err, ErrInvalidAmount)
+	// A coded error says nothing about which variant of its code it is
+	assert.NotErrorIs(t, models.NewError(models.ErrCodeNotImplemented, "no history"), ErrNoHistoricalData)
+}
+
+func TestCodeOf(t *testing.T) {
+	code, ok := CodeOf(fmt.Errorf("historical %w for USD/INR", ErrRateNotFound))
+	assert.True(t, ok)
+	assert.Equal(t, models.ErrCodeRateNotFound, code)
+
+	code, ok = CodeOf(fmt.Errorf("wrapped: %w", models.NewError(models.ErrCodeTimeout, "late")))
+	assert.True(t, ok)
+	assert.Equal(t, models.ErrCodeTimeout, code)
+
+	_, ok = CodeOf(errors.New("plain"))
+	assert.False(t, ok)
+}
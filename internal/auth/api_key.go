@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"sync"
+)
+
+const (
+	RoleReader  = "reader"
+	RoleAuditor = "auditor" // May read the conversion audit log
+	RoleAdmin   = "admin"
+
+	// RoleTenantProxy may name the tenant of a request in X-Tenant-ID, for
+	// gateways that serve several tenants with one key
+	RoleTenantProxy = "tenant_proxy"
+)
+
+// APIKey identifies a caller. ID is safe to log, Key is the secret itself.
+type APIKey struct {
+	ID     string
+	Key    string
+	Roles  []string
+	Policy Policy
+	Tenant string // ID of the tenant the caller belongs to, none when empty
+}
+
+// HasRole reports whether the key was granted role. Admins implicitly hold
+// every role.
+func (k *APIKey) HasRole(role string) bool {
+	for _, r := range k.Roles {
+		if r == role || r == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyStore holds the API keys accepted by the service
+type KeyStore struct {
+	mu   sync.RWMutex
+	keys []APIKey
+}
+
+func NewKeyStore(keys []APIKey) *KeyStore {
+	store := &KeyStore{}
+	store.Replace(keys)
+	return store
+}
+
+// Replace swaps the set of accepted keys
+func (s *KeyStore) Replace(keys []APIKey) {
+	copied := make([]APIKey, len(keys))
+	copy(copied, keys)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = copied
+}
+
+// Lookup returns the key matching secret. Secrets are compared in constant
+// time so response timing doesn't leak how much of a key matched.
+func (s *KeyStore) Lookup(secret string) (*APIKey, bool) {
+	if secret == "" {
+		return nil, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := range s.keys {
+		if subtle.ConstantTimeCompare([]byte(s.keys[i].Key), []byte(secret)) == 1 {
+			key := s.keys[i]
+			return &key, true
+		}
+	}
+	return nil, false
+}
+
+// Len returns the number of configured keys
+func (s *KeyStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
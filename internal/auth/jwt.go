@@ -0,0 +1,408 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidToken is returned for bearer tokens that fail verification
+var ErrInvalidToken = errors.New("invalid token")
+
+// JWTConfig configures verification of JWT bearer tokens against the signing
+// keys published at a JWKS URL
+type JWTConfig struct {
+	JWKSURL         string
+	Issuer          string        // Required "iss"
+	Audience        string        // Required entry of "aud"
+	RolesClaim      string        // Claim holding the roles; dots address nested claims, e.g. "realm_access.roles"
+	RefreshInterval time.Duration // How long fetched keys are trusted before they are re-fetched
+	Leeway          time.Duration // Clock skew tolerated on exp and nbf
+}
+
+// DefaultJWTConfig returns the settings used for the fields Load leaves unset
+func DefaultJWTConfig() JWTConfig {
+	return JWTConfig{
+		RolesClaim:      "roles",
+		RefreshInterval: time.Hour,
+		Leeway:          time.Minute,
+	}
+}
+
+// minKeyRefetch bounds how often the key set is fetched, so neither forged key
+// IDs nor an unreachable identity provider cause a fetch per request
+const minKeyRefetch = time.Minute
+
+const jwksFetchTimeout = 5 * time.Second
+
+// minRSABits is the shortest RSA modulus a signing key may have
+const minRSABits = 2048
+
+// algorithms maps the accepted JWS algorithms to their hash. Symmetric and
+// "none" algorithms are deliberately absent.
+var algorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// JWTVerifier validates RS* and ES* signed JWTs and maps their claims to a
+// caller identity. Keys are fetched lazily and cached for RefreshInterval.
+type JWTVerifier struct {
+	cfg    JWTConfig
+	client *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey // Key ID -> key
+	fetchedAt time.Time
+
+	fetchMu   sync.Mutex
+	lastFetch time.Time
+	fetchErr  error
+}
+
+func NewJWTVerifier(cfg JWTConfig) *JWTVerifier {
+	return &JWTVerifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: jwksFetchTimeout},
+	}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks token's signature and registered claims and returns the
+// caller it identifies. The returned key has no secret; its ID is
+// "jwt:<sub>" so it never collides with a configured API key ID.
+func (v *JWTVerifier) Verify(token string) (*APIKey, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: bad header: %v", ErrInvalidToken, err)
+	}
+	hash, ok := algorithms[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad signature encoding", ErrInvalidToken)
+	}
+
+	keys, err := v.signingKeys(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := hash.New()
+	digest.Write([]byte(parts[0] + "." + parts[1]))
+	if !verifyAny(keys, header.Alg, hash, digest.Sum(nil), signature) {
+		return nil, fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: bad claims: %v", ErrInvalidToken, err)
+	}
+	if err := v.validateClaims(claims, time.Now()); err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidToken)
+	}
+	return &APIKey{
+		ID:    "jwt:" + subject,
+		Roles: stringList(claimPath(claims, v.cfg.RolesClaim)),
+	}, nil
+}
+
+func (v *JWTVerifier) validateClaims(claims map[string]interface{}, now time.Time) error {
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(v.cfg.Leeway)) {
+		return fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.cfg.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+
+	// Both are always checked: without them any token the identity provider
+	// issued for another application would be accepted here
+	if issuer, _ := claims["iss"].(string); issuer == "" || issuer != v.cfg.Issuer {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, issuer)
+	}
+	for _, audience := range stringList(claims["aud"]) {
+		if audience != "" && audience == v.cfg.Audience {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: audience mismatch", ErrInvalidToken)
+}
+
+// signingKeys returns the candidate keys for kid, fetching the key set when it
+// is stale or doesn't know kid. Without a kid every key is a candidate.
+func (v *JWTVerifier) signingKeys(kid string) ([]crypto.PublicKey, error) {
+	keys, fresh := v.lookup(kid)
+	if len(keys) > 0 && fresh {
+		return keys, nil
+	}
+
+	if err := v.refresh(); err != nil {
+		if len(keys) > 0 {
+			// Keep accepting known keys while the identity provider is down
+			return keys, nil
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	keys, _ = v.lookup(kid)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+	}
+	return keys, nil
+}
+
+func (v *JWTVerifier) lookup(kid string) ([]crypto.PublicKey, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	fresh := !v.fetchedAt.IsZero() && time.Since(v.fetchedAt) < v.cfg.RefreshInterval
+	if kid != "" {
+		if key, ok := v.keys[kid]; ok {
+			return []crypto.PublicKey{key}, fresh
+		}
+		return nil, fresh
+	}
+
+	keys := make([]crypto.PublicKey, 0, len(v.keys))
+	for _, key := range v.keys {
+		keys = append(keys, key)
+	}
+	return keys, fresh
+}
+
+// Refresh fetches the key set now, so an unreachable JWKS URL shows up before
+// the first token does
+func (v *JWTVerifier) Refresh() error {
+	return v.refresh()
+}
+
+// refresh re-fetches the key set, at most once per minKeyRefetch. Callers
+// within that window get the outcome of the last fetch.
+func (v *JWTVerifier) refresh() error {
+	v.fetchMu.Lock()
+	defer v.fetchMu.Unlock()
+
+	if !v.lastFetch.IsZero() && time.Since(v.lastFetch) < minKeyRefetch {
+		return v.fetchErr
+	}
+	v.lastFetch = time.Now()
+
+	keys, err := v.fetchKeys()
+	v.fetchErr = err
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+	return nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (v *JWTVerifier) fetchKeys() (map[string]crypto.PublicKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.cfg.JWKSURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build JWKS request: %w", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			// Skip keys of unsupported types instead of rejecting the whole set
+			continue
+		}
+		kid := k.Kid
+		if kid == "" {
+			kid = fmt.Sprintf("#%d", i)
+		}
+		keys[kid] = key
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA exponent too large")
+		}
+		if n.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key of %d bits is shorter than %d", n.BitLen(), minRSABits)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// verifyAny reports whether signature was made by one of keys. Keys whose
+// type doesn't match alg are skipped, so an RSA key can't verify an ES token.
+func verifyAny(keys []crypto.PublicKey, alg string, hash crypto.Hash, digest, signature []byte) bool {
+	for _, key := range keys {
+		switch key := key.(type) {
+		case *rsa.PublicKey:
+			if strings.HasPrefix(alg, "RS") && rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil {
+				return true
+			}
+		case *ecdsa.PublicKey:
+			// JWS encodes ES signatures as the fixed-size concatenation r || s
+			size := (key.Curve.Params().BitSize + 7) / 8
+			if !strings.HasPrefix(alg, "ES") || len(signature) != 2*size {
+				continue
+			}
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			if ecdsa.Verify(key, digest, r, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("bad key parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// claimPath resolves a dotted path such as "realm_access.roles" in claims
+func claimPath(claims map[string]interface{}, path string) interface{} {
+	var value interface{} = claims
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
+
+// stringList reads a claim that is either a list of strings or a single
+// space-separated string, as OAuth "scope" claims are
+func stringList(value interface{}) []string {
+	switch value := value.(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
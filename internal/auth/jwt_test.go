@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return testKeys{rsa: rsaKey, ec: ecKey}
+}
+
+func encodeInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+// serveJWKS publishes keys and counts the fetches
+func serveJWKS(t *testing.T, keys testKeys, fetches *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(fetches, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": encodeInt(keys.rsa.N), "e": encodeInt(big.NewInt(int64(keys.rsa.E)))},
+				{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": encodeInt(keys.ec.X), "y": encodeInt(keys.ec.Y)},
+				{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func signToken(t *testing.T, keys testKeys, alg, kid string, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := crypto.SHA256.New()
+	digest.Write([]byte(signingInput))
+	var signature []byte
+	switch alg {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, keys.rsa, crypto.SHA256, digest.Sum(nil))
+		require.NoError(t, err)
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, keys.ec, digest.Sum(nil))
+		require.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	default:
+		signature = []byte("unsigned")
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// testJWTConfig verifies against the JWKS at url, expecting the issuer and
+// audience of validClaims
+func testJWTConfig(url string) JWTConfig {
+	cfg := DefaultJWTConfig()
+	cfg.JWKSURL = url
+	cfg.Issuer = "https://issuer.example"
+	cfg.Audience = "rates-api"
+	return cfg
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":   "ops-user",
+		"iss":   "https://issuer.example",
+		"aud":   []string{"rates-api", "other"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{RoleAdmin},
+	}
+}
+
+func TestJWTVerifier_Verify(t *testing.T) {
+	keys := newTestKeys(t)
+	var fetches int32
+	server := serveJWKS(t, keys, &fetches)
+
+	cfg := testJWTConfig(server.URL)
+	verifier := NewJWTVerifier(cfg)
+
+	with := func(change func(claims map[string]interface{})) map[string]interface{} {
+		claims := validClaims()
+		change(claims)
+		return claims
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"RS256", signToken(t, keys, "RS256", "rsa-1", validClaims()), true},
+		{"ES256", signToken(t, keys, "ES256", "ec-1", validClaims()), true},
+		{"No key ID", signToken(t, keys, "ES256", "", validClaims()), true},
+		{"Expired", signToken(t, keys, "RS256", "rsa-1", with(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-2 * time.Minute).Unix() })), false},
+		{"Expired within leeway", signToken(t, keys, "RS256", "rsa-1", with(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-30 * time.Second).Unix() })), true},
+		{"Not yet valid", signToken(t, keys, "RS256", "rsa-1", with(func(c map[string]interface{}) { c["nbf"] = time.Now().Add(time.Hour).Unix() })), false},
+		{"Missing exp", signToken(t, keys, "RS256", "rsa-1", with(func(c map[string]interface{}) { delete(c, "exp") })), false},
+		{"Wrong issuer", signToken(t, keys, "RS256", "rsa-1", with(func(c map[string]interface{}) { c["iss"] = "https://evil.example" })), false},
+		{"Wrong audience", signToken(t, keys, "RS256", "rsa-1", with(func(c map[string]interface{}) { c["aud"] = "other" })), false},
+		{"Missing issuer", signToken(t, keys, "RS256", "rsa-1", with(func(c map[string]interface{}) { delete(c, "iss") })), false},
+		{"Missing audience", signToken(t, keys, "RS256", "rsa-1", with(func(c map[string]interface{}) { delete(c, "aud") })), false},
+		{"Missing subject", signToken(t, keys, "RS256", "rsa-1", with(func(c map[string]interface{}) { delete(c, "sub") })), false},
+		{"Key type mismatch", signToken(t, keys, "ES256", "rsa-1", validClaims()), false},
+		{"Unknown key", signToken(t, keys, "RS256", "rsa-2", validClaims()), false},
+		{"Algorithm none", signToken(t, keys, "none", "rsa-1", validClaims()), false},
+		{"Symmetric algorithm", signToken(t, keys, "HS256", "hmac", validClaims()), false},
+		{"Malformed", "not-a-token", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := verifier.Verify(tt.token)
+			if !tt.valid {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "jwt:ops-user", key.ID)
+			assert.Empty(t, key.Key)
+			assert.True(t, key.HasRole(RoleAdmin))
+		})
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "keys are cached and unknown key IDs don't refetch within a minute")
+}
+
+func TestJWTVerifier_TamperedToken(t *testing.T) {
+	keys := newTestKeys(t)
+	var fetches int32
+	cfg := testJWTConfig(serveJWKS(t, keys, &fetches).URL)
+	verifier := NewJWTVerifier(cfg)
+
+	readerClaims := validClaims()
+	readerClaims["roles"] = []string{RoleReader}
+	reader := signToken(t, keys, "RS256", "rsa-1", readerClaims)
+	admin := signToken(t, keys, "RS256", "rsa-1", validClaims())
+
+	// Reader's header and signature with the admin's claims
+	forged := reader[:strings.Index(reader, ".")] + admin[strings.Index(admin, "."):strings.LastIndex(admin, ".")] + reader[strings.LastIndex(reader, "."):]
+	_, err := verifier.Verify(forged)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestJWTVerifier_NestedRolesClaim(t *testing.T) {
+	keys := newTestKeys(t)
+	var fetches int32
+	cfg := testJWTConfig(serveJWKS(t, keys, &fetches).URL)
+	cfg.RolesClaim = "realm_access.roles"
+	verifier := NewJWTVerifier(cfg)
+
+	claims := validClaims()
+	delete(claims, "roles")
+	claims["realm_access"] = map[string]interface{}{"roles": []string{RoleReader, RoleAuditor}}
+
+	key, err := verifier.Verify(signToken(t, keys, "RS256", "rsa-1", claims))
+	require.NoError(t, err)
+	assert.Equal(t, []string{RoleReader, RoleAuditor}, key.Roles)
+	assert.False(t, key.HasRole(RoleAdmin))
+}
+
+func TestJWTVerifier_KeepsKeysWhileJWKSIsDown(t *testing.T) {
+	keys := newTestKeys(t)
+	var fetches int32
+	server := serveJWKS(t, keys, &fetches)
+	cfg := testJWTConfig(server.URL)
+	verifier := NewJWTVerifier(cfg)
+
+	token := signToken(t, keys, "RS256", "rsa-1", validClaims())
+	_, err := verifier.Verify(token)
+	require.NoError(t, err)
+
+	server.Close()
+	verifier.mu.Lock()
+	verifier.fetchedAt = time.Now().Add(-2 * cfg.RefreshInterval)
+	verifier.mu.Unlock()
+	verifier.fetchMu.Lock()
+	verifier.lastFetch = time.Now().Add(-2 * minKeyRefetch)
+	verifier.fetchMu.Unlock()
+
+	_, err = verifier.Verify(token)
+	assert.NoError(t, err)
+}
+
+func TestJWTVerifier_RejectsShortRSAKeys(t *testing.T) {
+	keys := newTestKeys(t)
+	short, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	keys.rsa = short
+	var fetches int32
+	verifier := NewJWTVerifier(testJWTConfig(serveJWKS(t, keys, &fetches).URL))
+
+	_, err = verifier.Verify(signToken(t, keys, "RS256", "rsa-1", validClaims()))
+	assert.ErrorIs(t, err, ErrInvalidToken, "a 1024-bit key is ignored")
+	_, err = verifier.Verify(signToken(t, keys, "ES256", "ec-1", validClaims()))
+	assert.NoError(t, err, "the other keys of the set still verify")
+}
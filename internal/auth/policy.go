@@ -0,0 +1,97 @@
+package auth
+
+import "strings"
+
+// Entitlements grant a key groups of endpoints, so a plan can be limited to,
+// say, latest rates
+const (
+	EntitlementLatest     = "latest"     // Latest rates and conversions at them
+	EntitlementHistorical = "historical" // Historical and intraday rates, and dated conversions
+	EntitlementAnalytics  = "analytics"  // Trends, forecasts, correlations and recommendations
+	EntitlementAdmin      = "admin"      // Admin endpoints, on top of the admin role
+)
+
+// Entitlements lists every entitlement
+var Entitlements = []string{EntitlementLatest, EntitlementHistorical, EntitlementAnalytics, EntitlementAdmin}
+
+// Policy restricts the currency pairs and endpoints a key may use. The zero
+// Policy allows everything.
+type Policy struct {
+	// Pairs a key may use as FROM_TO, in either direction; "*" stands for
+	// any currency, e.g. "USD_*". Every pair is allowed when empty.
+	Pairs []string
+	// DeniedPairs are refused even if Pairs allows them
+	DeniedPairs []string
+	// Endpoints are the route paths a key may call, e.g. "/api/v1/convert";
+	// a trailing "*" matches a prefix. Every endpoint is allowed when empty.
+	Endpoints []string
+	// Entitlements granted to the key; it holds every one when empty
+	Entitlements []string
+}
+
+// RestrictsPairs reports whether the policy limits pairs at all
+func (p *Policy) RestrictsPairs() bool {
+	return len(p.Pairs) > 0 || len(p.DeniedPairs) > 0
+}
+
+// AllowsPair reports whether the pair may be used
+func (p *Policy) AllowsPair(from, to string) bool {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	for _, pair := range p.DeniedPairs {
+		if pairMatches(pair, from, to) {
+			return false
+		}
+	}
+	if len(p.Pairs) == 0 {
+		return true
+	}
+	for _, pair := range p.Pairs {
+		if pairMatches(pair, from, to) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsEndpoint reports whether the route path may be called
+func (p *Policy) AllowsEndpoint(path string) bool {
+	if len(p.Endpoints) == 0 {
+		return true
+	}
+	for _, endpoint := range p.Endpoints {
+		if prefix, found := strings.CutSuffix(endpoint, "*"); found {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// Entitled reports whether the policy grants entitlement
+func (p *Policy) Entitled(entitlement string) bool {
+	if len(p.Entitlements) == 0 {
+		return true
+	}
+	for _, granted := range p.Entitlements {
+		if granted == entitlement {
+			return true
+		}
+	}
+	return false
+}
+
+func pairMatches(pattern, from, to string) bool {
+	base, quote, found := strings.Cut(pattern, "_")
+	if !found {
+		return false
+	}
+	return currencyMatches(base, from) && currencyMatches(quote, to) ||
+		currencyMatches(base, to) && currencyMatches(quote, from)
+}
+
+func currencyMatches(pattern, currency string) bool {
+	return pattern == "*" || pattern == currency
+}
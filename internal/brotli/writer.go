@@ -0,0 +1,619 @@
+// Package brotli compresses data in the Brotli format of RFC 7932, for the
+// br content coding.
+//
+// The encoder favours speed over ratio, like the low quality levels of the
+// reference encoder: it finds repeats with a single-entry hash table, codes
+// each meta-block with one Huffman code per alphabet, and neither uses the
+// static dictionary nor context modelling. A meta-block that doesn't shrink
+// is stored uncompressed. JSON bodies still compress to about the size gzip
+// gives them.
+package brotli
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+	"sort"
+)
+
+const (
+	// windowBits is the log2 of the sliding window the stream declares. It
+	// covers historySize and blockSize, the farthest a match reaches back.
+	windowBits = 18
+
+	// blockSize is the largest meta-block the Writer holds before coding it
+	blockSize = 1 << 16
+
+	// historySize is how much of the data already coded matches may repeat
+	historySize = 1 << 16
+
+	hashBits = 15
+	minMatch = 4
+)
+
+// ErrClosed is returned when writing to or flushing a closed Writer
+var ErrClosed = errors.New("brotli: writer is closed")
+
+// Writer compresses what is written to it into the underlying writer. Data
+// is coded a meta-block at a time; Flush and Close code what is pending.
+type Writer struct {
+	w      io.Writer
+	bits   bitWriter
+	window []byte  // Recent data coded already, then that of the next meta-block
+	start  int     // Where the next meta-block starts in window
+	table  []int32 // Positions in window, plus one, of recent data by hash
+	closed bool
+	err    error
+}
+
+// NewWriter returns a Writer compressing into w. The stream header is sent
+// with the first meta-block.
+func NewWriter(w io.Writer) *Writer {
+	z := &Writer{w: w, table: make([]int32, 1<<hashBits)}
+	// WBITS: 1, then 3 bits of windowBits-17
+	z.bits.write(1, 1)
+	z.bits.write(windowBits-17, 3)
+	return z
+}
+
+// Write buffers p and codes every full meta-block
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.closed {
+		return 0, ErrClosed
+	}
+	if z.err != nil {
+		return 0, z.err
+	}
+	written := len(p)
+	for len(p) > 0 {
+		n := blockSize - (len(z.window) - z.start)
+		if n > len(p) {
+			n = len(p)
+		}
+		z.window = append(z.window, p[:n]...)
+		p = p[n:]
+		if len(z.window)-z.start == blockSize {
+			z.writeMetaBlock()
+			if err := z.send(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Flush codes the pending data and pads the stream to a byte boundary with
+// an empty metadata block, so a reader can decode everything written so far
+func (z *Writer) Flush() error {
+	if z.closed {
+		return ErrClosed
+	}
+	if z.err != nil {
+		return z.err
+	}
+	z.writeMetaBlock()
+	// ISLAST 0, MNIBBLES 0 (metadata), reserved bit, MSKIPBYTES 0
+	z.bits.write(0, 1)
+	z.bits.write(3, 2)
+	z.bits.write(0, 1)
+	z.bits.write(0, 2)
+	z.bits.align()
+	return z.send()
+}
+
+// Close codes the pending data and ends the stream. It doesn't close the
+// underlying writer.
+func (z *Writer) Close() error {
+	if z.closed {
+		return z.err
+	}
+	z.closed = true
+	if z.err != nil {
+		return z.err
+	}
+	z.writeMetaBlock()
+	// ISLAST 1, ISLASTEMPTY 1
+	z.bits.write(1, 1)
+	z.bits.write(1, 1)
+	z.bits.align()
+	return z.send()
+}
+
+// send passes the complete bytes coded so far to the underlying writer
+func (z *Writer) send() error {
+	if len(z.bits.out) == 0 {
+		return nil
+	}
+	_, z.err = z.w.Write(z.bits.out)
+	z.bits.out = z.bits.out[:0]
+	return z.err
+}
+
+// writeMetaBlock codes the pending data as one meta-block, compressed or
+// stored, whichever is smaller, and keeps the last historySize bytes for the
+// next one to repeat
+func (z *Writer) writeMetaBlock() {
+	data := z.window[z.start:]
+	if len(data) == 0 {
+		return
+	}
+	compressed := bitWriter{acc: z.bits.acc, n: z.bits.n}
+	writeCompressed(&compressed, data, findCommands(z.window, z.start, z.table))
+	// A stored meta-block takes its data, a header of at most 7 bytes and
+	// the padding to a byte boundary
+	if len(compressed.out) <= len(data)+7 {
+		z.bits.out = append(z.bits.out, compressed.out...)
+		z.bits.acc, z.bits.n = compressed.acc, compressed.n
+	} else {
+		writeStored(&z.bits, data)
+	}
+
+	z.start = len(z.window)
+	if shift := z.start - historySize; shift > 0 {
+		z.window = z.window[:copy(z.window, z.window[shift:])]
+		z.start -= shift
+		for i, pos := range z.table {
+			if pos := int(pos) - shift; pos > 0 {
+				z.table[i] = int32(pos)
+			} else {
+				z.table[i] = 0
+			}
+		}
+	}
+}
+
+// command inserts the next insert bytes as literals, then copies copy bytes
+// from distance bytes back. The last command of a meta-block may copy
+// nothing.
+type command struct {
+	insert   int
+	copy     int
+	distance int
+}
+
+// findCommands splits data from start on into literals and repeats. Like
+// deflate, it holds a repeat back by a byte while the next one is longer.
+func findCommands(data []byte, start int, table []int32) []command {
+	var commands []command
+	literals := start // Start of the literals of the next command
+	for i := start; i+minMatch <= len(data); {
+		candidate, length := match(data, table, i)
+		if length == 0 {
+			i++
+			continue
+		}
+		for i+1+minMatch <= len(data) {
+			next, nextLength := match(data, table, i+1)
+			if nextLength <= length {
+				break
+			}
+			i, candidate, length = i+1, next, nextLength
+		}
+		commands = append(commands, command{insert: i - literals, copy: length, distance: i - candidate})
+		// The positions the repeat covers are candidates for later ones
+		end := i + length
+		for i++; i < end && i+minMatch <= len(data); i++ {
+			table[hash(binary.LittleEndian.Uint32(data[i:]))] = int32(i + 1)
+		}
+		i = end
+		literals = i
+	}
+	if literals < len(data) {
+		commands = append(commands, command{insert: len(data) - literals})
+	}
+	return commands
+}
+
+// match returns the repeat of data[i:] the hash table knows of, of at least
+// minMatch bytes or of length 0, and records i in the table
+func match(data []byte, table []int32, i int) (candidate, length int) {
+	word := binary.LittleEndian.Uint32(data[i:])
+	h := hash(word)
+	candidate = int(table[h]) - 1
+	table[h] = int32(i + 1)
+	if candidate < 0 || binary.LittleEndian.Uint32(data[candidate:]) != word {
+		return 0, 0
+	}
+	length = minMatch
+	for i+length < len(data) && data[candidate+length] == data[i+length] {
+		length++
+	}
+	return candidate, length
+}
+
+func hash(word uint32) uint32 {
+	return (word * 0x1e35a7bd) >> (32 - hashBits)
+}
+
+// writeStored codes data as an uncompressed meta-block
+func writeStored(b *bitWriter, data []byte) {
+	b.write(0, 1) // ISLAST
+	writeLength(b, len(data))
+	b.write(1, 1) // ISUNCOMPRESSED
+	b.align()
+	b.out = append(b.out, data...)
+}
+
+// writeLength writes MNIBBLES and MLEN-1 of a meta-block of n bytes
+func writeLength(b *bitWriter, n int) {
+	nibbles := 4
+	for n-1 >= 1<<(4*nibbles) {
+		nibbles++
+	}
+	b.write(uint64(nibbles-4), 2)
+	b.write(uint64(n-1), uint(4*nibbles))
+}
+
+// Lengths of the insert and copy length codes, as (base, extra bits) pairs
+var (
+	insertLengthCodes = [24][2]int{
+		{0, 0}, {1, 0}, {2, 0}, {3, 0}, {4, 0}, {5, 0}, {6, 1}, {8, 1},
+		{10, 2}, {14, 2}, {18, 3}, {26, 3}, {34, 4}, {50, 4}, {66, 5}, {98, 5},
+		{130, 6}, {194, 7}, {322, 8}, {578, 9}, {1090, 10}, {2114, 12}, {6210, 14}, {22594, 24},
+	}
+	copyLengthCodes = [24][2]int{
+		{2, 0}, {3, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0}, {8, 0}, {9, 0},
+		{10, 1}, {12, 1}, {14, 2}, {18, 2}, {22, 3}, {30, 3}, {38, 4}, {54, 4},
+		{70, 5}, {102, 5}, {134, 6}, {198, 7}, {326, 8}, {582, 9}, {1094, 10}, {2118, 24},
+	}
+)
+
+// lengthCode returns the code of n in codes
+func lengthCode(codes *[24][2]int, n int) int {
+	code := len(codes) - 1
+	for codes[code][0] > n {
+		code--
+	}
+	return code
+}
+
+// commandSymbol returns the insert-and-copy symbol of an insert and a copy
+// length code. The symbols of the first two cells reuse the last distance,
+// so no distance follows them.
+func commandSymbol(insertCode, copyCode int, lastDistance bool) int {
+	low := (insertCode&7)<<3 | copyCode&7
+	if lastDistance && insertCode < 8 && copyCode < 16 {
+		return (copyCode>>3)<<6 | low
+	}
+	cells := [3][3]int{{128, 192, 384}, {256, 320, 512}, {448, 576, 640}}
+	return cells[insertCode>>3][copyCode>>3] | low
+}
+
+// distanceSymbol returns the distance code of distance, with neither direct
+// codes nor postfix bits, and its extra bits
+func distanceSymbol(distance int) (symbol, extraBits, extra int) {
+	d := distance + 3
+	extraBits = bits.Len(uint(d)) - 2
+	high := (d >> extraBits) & 1
+	return 16 + 2*(extraBits-1) + high, extraBits, d - (2+high)<<extraBits
+}
+
+const (
+	literalAlphabet  = 256
+	commandAlphabet  = 704
+	distanceAlphabet = 64
+)
+
+// writeCompressed codes data as a compressed meta-block of commands
+func writeCompressed(b *bitWriter, data []byte, commands []command) {
+	literalCounts := make([]int, literalAlphabet)
+	commandCounts := make([]int, commandAlphabet)
+	distanceCounts := make([]int, distanceAlphabet)
+	pos := 0
+	for _, cmd := range commands {
+		for _, c := range data[pos : pos+cmd.insert] {
+			literalCounts[c]++
+		}
+		commandCounts[cmd.symbol()]++
+		if cmd.copy > 0 {
+			symbol, _, _ := distanceSymbol(cmd.distance)
+			distanceCounts[symbol]++
+		}
+		pos += cmd.insert + cmd.copy
+	}
+
+	b.write(0, 1) // ISLAST
+	writeLength(b, len(data))
+	b.write(0, 1) // ISUNCOMPRESSED
+	b.write(0, 1) // NBLTYPESL 1
+	b.write(0, 1) // NBLTYPESI 1
+	b.write(0, 1) // NBLTYPESD 1
+	b.write(0, 2) // NPOSTFIX
+	b.write(0, 4) // NDIRECT
+	b.write(0, 2) // Context mode of the literals
+	b.write(0, 1) // NTREESL 1
+	b.write(0, 1) // NTREESD 1
+	literals := writePrefixCode(b, literalCounts, 8)
+	cmds := writePrefixCode(b, commandCounts, 10)
+	distances := writePrefixCode(b, distanceCounts, 6)
+
+	pos = 0
+	for _, cmd := range commands {
+		cmds.write(b, cmd.symbol())
+		insertCode := lengthCode(&insertLengthCodes, cmd.insert)
+		b.write(uint64(cmd.insert-insertLengthCodes[insertCode][0]), uint(insertLengthCodes[insertCode][1]))
+		copyCode := lengthCode(&copyLengthCodes, cmd.copyLength())
+		b.write(uint64(cmd.copyLength()-copyLengthCodes[copyCode][0]), uint(copyLengthCodes[copyCode][1]))
+		for _, c := range data[pos : pos+cmd.insert] {
+			literals.write(b, int(c))
+		}
+		if cmd.copy > 0 {
+			symbol, extraBits, extra := distanceSymbol(cmd.distance)
+			distances.write(b, symbol)
+			b.write(uint64(extra), uint(extraBits))
+		}
+		pos += cmd.insert + cmd.copy
+	}
+}
+
+// copyLength is the copy length the command codes. A command that copies
+// nothing ends the meta-block, so its copy length is never used.
+func (cmd command) copyLength() int {
+	if cmd.copy == 0 {
+		return copyLengthCodes[0][0]
+	}
+	return cmd.copy
+}
+
+func (cmd command) symbol() int {
+	return commandSymbol(lengthCode(&insertLengthCodes, cmd.insert), lengthCode(&copyLengthCodes, cmd.copyLength()), cmd.copy == 0)
+}
+
+// prefixCode is a canonical prefix code, its codes bit-reversed as the
+// stream carries them
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint16
+}
+
+func (p *prefixCode) write(b *bitWriter, symbol int) {
+	b.write(uint64(p.codes[symbol]), uint(p.lengths[symbol]))
+}
+
+// codeLengthOrder is the order the code lengths of the code length code are
+// written in
+var codeLengthOrder = [18]int{1, 2, 3, 4, 0, 5, 17, 6, 16, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// writePrefixCode writes the prefix code of the symbols counted in counts,
+// whose alphabet needs alphabetBits, and returns it
+func writePrefixCode(b *bitWriter, counts []int, alphabetBits uint) *prefixCode {
+	var used []int
+	for symbol, count := range counts {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+	if len(used) == 0 {
+		used = []int{0}
+	}
+	lengths := make([]uint8, len(counts))
+	if len(used) > 4 {
+		huffmanLengths(counts, 15, lengths)
+		writeComplexCode(b, lengths)
+		return newPrefixCode(lengths)
+	}
+
+	// A simple prefix code lists its symbols. The first of three gets the
+	// shortest code, so the most frequent symbol goes first.
+	sort.SliceStable(used, func(i, j int) bool { return counts[used[i]] > counts[used[j]] })
+	b.write(1, 2) // HSKIP 1
+	b.write(uint64(len(used)-1), 2)
+	for _, symbol := range used {
+		b.write(uint64(symbol), alphabetBits)
+	}
+	switch len(used) {
+	case 2:
+		lengths[used[0]], lengths[used[1]] = 1, 1
+	case 3:
+		lengths[used[0]], lengths[used[1]], lengths[used[2]] = 1, 2, 2
+	case 4:
+		b.write(0, 1) // Tree-select: all codes of 2 bits
+		for _, symbol := range used {
+			lengths[symbol] = 2
+		}
+	}
+	// A single symbol takes no bits at all
+	return newPrefixCode(lengths)
+}
+
+// writeComplexCode writes the code lengths of a prefix code of at least two
+// symbols, run-length coded with the code length code
+func writeComplexCode(b *bitWriter, lengths []uint8) {
+	last := len(lengths) - 1
+	for lengths[last] == 0 {
+		last--
+	}
+	// Runs of 3 to 10 zeros take symbol 17 and 3 extra bits. Consecutive
+	// 17s would multiply their counts, so a single zero separates them.
+	var symbols, extras []int
+	for i := 0; i <= last; {
+		if lengths[i] != 0 {
+			symbols, extras = append(symbols, int(lengths[i])), append(extras, 0)
+			i++
+			continue
+		}
+		run := 0
+		for lengths[i+run] == 0 {
+			run++
+		}
+		i += run
+		for run > 0 {
+			if run < 3 {
+				symbols, extras = append(symbols, 0), append(extras, 0)
+				run--
+				continue
+			}
+			repeat := run
+			if repeat > 10 {
+				repeat = 10
+			}
+			symbols, extras = append(symbols, 17), append(extras, repeat-3)
+			run -= repeat
+			if run > 0 {
+				symbols, extras = append(symbols, 0), append(extras, 0)
+				run--
+			}
+		}
+	}
+
+	counts := make([]int, len(codeLengthOrder))
+	for _, symbol := range symbols {
+		counts[symbol]++
+	}
+	codeLengths := make([]uint8, len(codeLengthOrder))
+	huffmanLengths(counts, 5, codeLengths)
+	code := newPrefixCode(append([]uint8(nil), codeLengths...))
+	for symbol, count := range counts {
+		if count > 0 && count == len(symbols) {
+			// A code length code of one symbol takes no bits, however long
+			// its code is declared
+			code.lengths[symbol] = 0
+		}
+	}
+
+	b.write(0, 2) // HSKIP 0
+	space := 32
+	for _, symbol := range codeLengthOrder {
+		length := codeLengths[symbol]
+		// Static code of the code lengths 0 to 5, as (bits, length) pairs
+		static := [6][2]uint64{{0, 2}, {7, 4}, {3, 3}, {2, 2}, {1, 2}, {15, 4}}
+		b.write(static[length][0], uint(static[length][1]))
+		if length != 0 {
+			space -= 32 >> length
+			if space <= 0 {
+				break
+			}
+		}
+	}
+	for i, symbol := range symbols {
+		code.write(b, symbol)
+		if symbol == 17 {
+			b.write(uint64(extras[i]), 3)
+		}
+	}
+}
+
+// huffmanLengths sets lengths to the code lengths of a Huffman code of the
+// symbols counted in counts, halving the counts until no code is longer than
+// maxLength bits
+func huffmanLengths(counts []int, maxLength int, lengths []uint8) {
+	weights := append([]int(nil), counts...)
+	for huffmanTree(weights, lengths) > maxLength {
+		for i, w := range weights {
+			if w > 0 {
+				weights[i] = (w + 1) / 2
+			}
+		}
+	}
+}
+
+// huffmanTree sets lengths to the depths of the symbols in a Huffman tree of
+// weights and returns the largest
+func huffmanTree(weights []int, lengths []uint8) int {
+	type node struct {
+		weight      int
+		left, right int // Children, or -1 and the symbol of a leaf
+	}
+	var nodes []node
+	for symbol, w := range weights {
+		lengths[symbol] = 0
+		if w > 0 {
+			nodes = append(nodes, node{w, -1, symbol})
+		}
+	}
+	if len(nodes) == 1 {
+		lengths[nodes[0].right] = 1
+		return 1
+	}
+	leaves := make([]int, len(nodes))
+	for i := range leaves {
+		leaves[i] = i
+	}
+	sort.SliceStable(leaves, func(i, j int) bool { return nodes[leaves[i]].weight < nodes[leaves[j]].weight })
+
+	// Merged nodes are made in order of weight, so the lightest node is at
+	// the front of one of the two queues
+	var merged []int
+	lightest := func() int {
+		var n int
+		if len(merged) == 0 || len(leaves) > 0 && nodes[leaves[0]].weight <= nodes[merged[0]].weight {
+			n, leaves = leaves[0], leaves[1:]
+		} else {
+			n, merged = merged[0], merged[1:]
+		}
+		return n
+	}
+	for len(leaves)+len(merged) > 1 {
+		a, b := lightest(), lightest()
+		nodes = append(nodes, node{nodes[a].weight + nodes[b].weight, a, b})
+		merged = append(merged, len(nodes)-1)
+	}
+
+	deepest := 0
+	type entry struct{ node, depth int }
+	stack := []entry{{merged[0], 0}}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		n := nodes[e.node]
+		if n.left < 0 {
+			lengths[n.right] = uint8(e.depth)
+			if e.depth > deepest {
+				deepest = e.depth
+			}
+			continue
+		}
+		stack = append(stack, entry{n.left, e.depth + 1}, entry{n.right, e.depth + 1})
+	}
+	return deepest
+}
+
+// newPrefixCode assigns the canonical codes of lengths: shorter codes first,
+// and codes of the same length in the order of their symbols
+func newPrefixCode(lengths []uint8) *prefixCode {
+	var counts [16]int
+	for _, length := range lengths {
+		counts[length]++
+	}
+	counts[0] = 0
+	var next [16]int
+	code := 0
+	for length := 1; length < len(next); length++ {
+		code = (code + counts[length-1]) << 1
+		next[length] = code
+	}
+	p := &prefixCode{lengths: lengths, codes: make([]uint16, len(lengths))}
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+		p.codes[symbol] = uint16(bits.Reverse16(uint16(next[length])) >> (16 - length))
+		next[length]++
+	}
+	return p
+}
+
+// bitWriter packs values into bytes, least significant bit first
+type bitWriter struct {
+	out []byte
+	acc uint64 // Bits not yet in out
+	n   uint   // Number of bits in acc
+}
+
+func (b *bitWriter) write(value uint64, n uint) {
+	b.acc |= value << b.n
+	b.n += n
+	for b.n >= 8 {
+		b.out = append(b.out, byte(b.acc))
+		b.acc >>= 8
+		b.n -= 8
+	}
+}
+
+// align pads the stream with zeros to a byte boundary
+func (b *bitWriter) align() {
+	if b.n > 0 {
+		b.write(0, 8-b.n)
+	}
+}
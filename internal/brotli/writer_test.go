@@ -0,0 +1,414 @@
+package brotli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	z := NewWriter(&buf)
+	_, err := z.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, z.Close())
+	return buf.Bytes()
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	random := make([]byte, 3*blockSize+17)
+	rand.New(rand.NewSource(1)).Read(random)
+	json := strings.Repeat(`{"date":"2025-01-02","from":"USD","to":"INR","rate":83.5},`, 2000)
+	allBytes := make([]byte, 3*256)
+	for i := range allBytes {
+		allBytes[i] = byte(i)
+	}
+
+	tests := map[string][]byte{
+		"empty":           nil,
+		"one byte":        []byte("a"),
+		"short text":      []byte("hello hello hello hello"),
+		"one symbol":      bytes.Repeat([]byte("a"), 1000),
+		"all byte values": allBytes,
+		"json":            []byte(json),
+		"random":          random,
+		"mixed blocks":    append([]byte(json), random...),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			compressed := compress(t, data)
+			decoded, err := decode(compressed)
+			require.NoError(t, err)
+			assert.Equal(t, data, decoded)
+		})
+	}
+
+	// Repeats shrink the data, including those reaching into an earlier
+	// meta-block; random data grows by little more than its headers
+	assert.Less(t, len(compress(t, []byte(json))), len(json)/20)
+	assert.Less(t, len(compress(t, random)), len(random)+64)
+}
+
+func TestWriter_Flush(t *testing.T) {
+	var buf bytes.Buffer
+	z := NewWriter(&buf)
+	var written []byte
+	for i := 0; i < 5; i++ {
+		chunk := []byte(fmt.Sprintf(`{"chunk":%d,"rates":{"EUR":0.92,"GBP":0.79,"INR":83.5}}`, i))
+		_, err := z.Write(chunk)
+		require.NoError(t, err)
+		written = append(written, chunk...)
+		require.NoError(t, z.Flush())
+
+		// Everything written so far decodes before the stream ends
+		decoded, err := decode(buf.Bytes())
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, written, decoded)
+	}
+	require.NoError(t, z.Close())
+	decoded, err := decode(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, written, decoded)
+
+	_, err = z.Write([]byte("late"))
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.ErrorIs(t, z.Flush(), ErrClosed)
+	assert.NoError(t, z.Close())
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestWriter_KeepsWriteError(t *testing.T) {
+	z := NewWriter(failingWriter{})
+	_, err := z.Write([]byte("rates"))
+	require.NoError(t, err, "nothing is sent before a meta-block is full")
+	assert.EqualError(t, z.Flush(), "connection reset")
+	_, err = z.Write([]byte("more rates"))
+	assert.EqualError(t, err, "connection reset")
+	assert.EqualError(t, z.Close(), "connection reset")
+}
+
+func TestPrefixCodes(t *testing.T) {
+	// Skewed counts are limited to 15 bits and stay a complete code
+	counts := make([]int, commandAlphabet)
+	for i := range counts {
+		counts[i] = 1 << (i % 30)
+	}
+	lengths := make([]uint8, len(counts))
+	huffmanLengths(counts, 15, lengths)
+	kraft := 0
+	for _, length := range lengths {
+		require.NotZero(t, length)
+		assert.LessOrEqual(t, length, uint8(15))
+		kraft += 1 << (15 - length)
+	}
+	assert.Equal(t, 1<<15, kraft)
+
+	// Canonical codes are bit-reversed: 0, 10, 110 and 111 read from the
+	// first bit sent
+	code := newPrefixCode([]uint8{1, 2, 3, 3})
+	assert.Equal(t, []uint16{0b0, 0b01, 0b011, 0b111}, code.codes)
+
+	for distance, want := range map[int][3]int{1: {16, 1, 0}, 2: {16, 1, 1}, 3: {17, 1, 0}, 5: {18, 2, 0}, 12: {19, 2, 3}} {
+		symbol, extraBits, extra := distanceSymbol(distance)
+		assert.Equal(t, want, [3]int{symbol, extraBits, extra}, "distance %d", distance)
+	}
+}
+
+// decode decompresses the streams Writer produces: it supports one block
+// type and one prefix code per alphabet, neither postfix bits nor direct
+// distance codes, and no static dictionary. A stream that stops before its
+// last meta-block returns the data decoded so far and io.ErrUnexpectedEOF.
+func decode(data []byte) ([]byte, error) {
+	r := &bitReader{data: data}
+	var out []byte
+	if r.read(1) == 1 && r.read(3) == 0 && r.read(3) != 0 {
+		return nil, errors.New("window smaller than 128 KiB")
+	}
+	for r.err == nil {
+		if r.pos == 8*len(data) {
+			return out, io.ErrUnexpectedEOF
+		}
+		if r.read(1) == 1 { // ISLAST
+			if r.read(1) != 1 {
+				return out, errors.New("last meta-block is not empty")
+			}
+			if r.align(); r.err == nil && r.pos/8 != len(data) {
+				return out, errors.New("data after the last meta-block")
+			}
+			return out, r.err
+		}
+		nibbles := int(r.read(2))
+		if nibbles == 3 {
+			if r.read(1) != 0 || r.read(2) != 0 {
+				return out, errors.New("metadata is not empty")
+			}
+			r.align()
+			continue
+		}
+		length := int(r.read(uint(4*(nibbles+4)))) + 1
+		if r.read(1) == 1 { // ISUNCOMPRESSED
+			r.align()
+			out = append(out, r.bytes(length)...)
+			continue
+		}
+		var err error
+		if out, err = decodeCompressed(r, out, length); err != nil {
+			return out, err
+		}
+	}
+	return out, r.err
+}
+
+func decodeCompressed(r *bitReader, out []byte, length int) ([]byte, error) {
+	// NBLTYPESL, NBLTYPESI, NBLTYPESD, NPOSTFIX, NDIRECT, the context mode,
+	// NTREESL and NTREESD
+	if r.read(1) != 0 || r.read(1) != 0 || r.read(1) != 0 || r.read(2) != 0 || r.read(4) != 0 {
+		return out, errors.New("unsupported block types or distance parameters")
+	}
+	r.read(2)
+	if r.read(1) != 0 || r.read(1) != 0 {
+		return out, errors.New("unsupported context map")
+	}
+	literals, err := readPrefixCode(r, literalAlphabet, 8)
+	if err != nil {
+		return out, err
+	}
+	commands, err := readPrefixCode(r, commandAlphabet, 10)
+	if err != nil {
+		return out, err
+	}
+	distances, err := readPrefixCode(r, distanceAlphabet, 6)
+	if err != nil {
+		return out, err
+	}
+
+	insertCells := [11]int{0, 0, 0, 0, 8, 8, 0, 16, 8, 16, 16}
+	copyCells := [11]int{0, 8, 0, 8, 0, 8, 16, 0, 16, 8, 16}
+	end := len(out) + length
+	for r.err == nil {
+		symbol := commands.decode(r)
+		cell := symbol >> 6
+		insertCode := insertCells[cell] + symbol>>3&7
+		copyCode := copyCells[cell] + symbol&7
+		insert := insertLengthCodes[insertCode][0] + int(r.read(uint(insertLengthCodes[insertCode][1])))
+		copyLength := copyLengthCodes[copyCode][0] + int(r.read(uint(copyLengthCodes[copyCode][1])))
+		if len(out)+insert > end {
+			return out, errors.New("insert past the end of the meta-block")
+		}
+		for i := 0; i < insert; i++ {
+			out = append(out, byte(literals.decode(r)))
+		}
+		if len(out) == end {
+			break
+		}
+		if cell < 2 {
+			return out, errors.New("unsupported reuse of the last distance")
+		}
+		d := distances.decode(r)
+		if d < 16 {
+			return out, errors.New("unsupported short distance code")
+		}
+		extraBits := 1 + (d-16)>>1
+		distance := (2+(d-16)&1)<<extraBits - 4 + int(r.read(uint(extraBits))) + 1
+		if distance > len(out) || len(out)+copyLength > end {
+			return out, fmt.Errorf("copy of %d bytes from %d back is out of bounds", copyLength, distance)
+		}
+		for i := 0; i < copyLength; i++ {
+			out = append(out, out[len(out)-distance])
+		}
+		if len(out) == end {
+			break
+		}
+	}
+	return out, r.err
+}
+
+// huffmanDecoder decodes a canonical prefix code a bit at a time
+type huffmanDecoder struct {
+	counts  [16]int // Number of codes of each length
+	symbols []int   // Symbols by code length, then value
+}
+
+func newHuffmanDecoder(lengths []int) *huffmanDecoder {
+	h := &huffmanDecoder{}
+	for length := 1; length < len(h.counts); length++ {
+		for symbol, l := range lengths {
+			if l == length {
+				h.counts[length]++
+				h.symbols = append(h.symbols, symbol)
+			}
+		}
+	}
+	return h
+}
+
+func (h *huffmanDecoder) decode(r *bitReader) int {
+	if len(h.symbols) == 1 {
+		// A code of one symbol takes no bits
+		return h.symbols[0]
+	}
+	code, first, index := 0, 0, 0
+	for length := 1; length < len(h.counts); length++ {
+		code |= int(r.read(1))
+		if count := h.counts[length]; code-first < count {
+			return h.symbols[index+code-first]
+		}
+		index += h.counts[length]
+		first = (first + h.counts[length]) << 1
+		code <<= 1
+	}
+	r.fail(errors.New("invalid prefix code"))
+	return 0
+}
+
+func readPrefixCode(r *bitReader, alphabetSize int, alphabetBits uint) (*huffmanDecoder, error) {
+	lengths := make([]int, alphabetSize)
+	skip := int(r.read(2))
+	if skip == 1 {
+		symbols := make([]int, r.read(2)+1)
+		for i := range symbols {
+			symbols[i] = int(r.read(alphabetBits))
+		}
+		if len(symbols) == 1 {
+			return &huffmanDecoder{symbols: symbols}, r.err
+		}
+		shapes := [][]int{nil, {1, 1}, {1, 2, 2}, {2, 2, 2, 2}}
+		shape := shapes[len(symbols)-1]
+		if len(symbols) == 4 && r.read(1) == 1 {
+			shape = []int{1, 2, 3, 3}
+		}
+		for i, symbol := range symbols {
+			lengths[symbol] = shape[i]
+		}
+		return newHuffmanDecoder(lengths), r.err
+	}
+
+	codeLengths := make([]int, len(codeLengthOrder))
+	space, used := 32, 0
+	for _, symbol := range codeLengthOrder[skip:] {
+		var length int
+		switch r.read(2) {
+		case 1:
+			length = 4
+		case 2:
+			length = 3
+		case 3:
+			switch {
+			case r.read(1) == 0:
+				length = 2
+			case r.read(1) == 0:
+				length = 1
+			default:
+				length = 5
+			}
+		}
+		codeLengths[symbol] = length
+		if length != 0 {
+			used++
+			if space -= 32 >> length; space <= 0 {
+				break
+			}
+		}
+	}
+	if used != 1 && space != 0 {
+		return nil, errors.New("incomplete code length code")
+	}
+	code := newHuffmanDecoder(codeLengths)
+
+	space = 32768
+	previous, repeatCode, repeat := 8, 0, 0
+	for symbol := 0; symbol < alphabetSize && space > 0 && r.err == nil; {
+		s := code.decode(r)
+		if s < 16 {
+			lengths[symbol] = s
+			if s != 0 {
+				previous = s
+				space -= 32768 >> s
+			}
+			symbol++
+			repeatCode, repeat = 0, 0
+			continue
+		}
+		extraBits, length := uint(2), previous
+		if s == 17 {
+			extraBits, length = 3, 0
+		}
+		if s != repeatCode {
+			repeatCode, repeat = s, 0
+		}
+		old := repeat
+		if repeat > 0 {
+			repeat = (repeat - 2) << extraBits
+		}
+		repeat += int(r.read(extraBits)) + 3
+		for i := old; i < repeat; i++ {
+			if symbol >= alphabetSize {
+				return nil, errors.New("code length repeat past the alphabet")
+			}
+			lengths[symbol] = length
+			if length != 0 {
+				space -= 32768 >> length
+			}
+			symbol++
+		}
+	}
+	if r.err == nil && space != 0 {
+		return nil, errors.New("incomplete prefix code")
+	}
+	return newHuffmanDecoder(lengths), r.err
+}
+
+// bitReader reads values least significant bit first
+type bitReader struct {
+	data []byte
+	pos  int // In bits
+	err  error
+}
+
+func (r *bitReader) read(n uint) uint64 {
+	var value uint64
+	for i := uint(0); i < n; i++ {
+		if r.pos/8 >= len(r.data) {
+			r.fail(io.ErrUnexpectedEOF)
+			return 0
+		}
+		value |= uint64(r.data[r.pos/8]>>(r.pos%8)&1) << i
+		r.pos++
+	}
+	return value
+}
+
+func (r *bitReader) align() {
+	if r.pos%8 != 0 && r.read(uint(8-r.pos%8)) != 0 {
+		r.fail(errors.New("padding is not zero"))
+	}
+}
+
+func (r *bitReader) bytes(n int) []byte {
+	start := r.pos / 8
+	if start+n > len(r.data) {
+		r.fail(io.ErrUnexpectedEOF)
+		return nil
+	}
+	r.pos += 8 * n
+	return r.data[start : start+n]
+}
+
+func (r *bitReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
@@ -0,0 +1,336 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// How changes to the cache reach the snapshot file
+const (
+	WriteSnapshot = "snapshot"      // Only with the periodic snapshot
+	WriteThrough  = "write-through" // Journaled and synced before Set returns
+	WriteBack     = "write-back"    // Journaled in batches every flush interval
+)
+
+// Journal record operations
+const (
+	journalSet    = "set"
+	journalDelete = "delete"
+	journalClear  = "clear"
+)
+
+// JournalPath returns where the journal of a snapshot file is kept
+func JournalPath(snapshotPath string) string {
+	return snapshotPath + ".journal"
+}
+
+// journalRecord is one line of the journal
+type journalRecord struct {
+	Op string `json:"op"`
+	snapshotEntry
+}
+
+// Journal records the changes made to a MemoryCache since its last snapshot,
+// one JSON line per change, so that a crash loses no more than the changes
+// not yet flushed. LoadSnapshot replays it over the snapshot, and
+// SaveSnapshot empties it. Write-through appends and syncs every change as it
+// is made; write-back keeps the latest change of each key and appends them
+// every flush interval.
+type Journal struct {
+	path          string
+	strategy      string
+	flushInterval time.Duration
+
+	mu           sync.Mutex // Serialises appends, flushes and compaction
+	file         *os.File
+	pending      map[string]journalRecord // Write-back changes not yet flushed
+	pendingOrder []string
+	pendingSince time.Time
+
+	writes      int64 // Changes recorded
+	persisted   int64 // Records written and synced
+	flushes     int64
+	writeErrors int64
+	compactions int64
+	lastFlush   time.Time
+	lastError   string
+
+	running bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// OpenJournal opens the journal at path for appending with strategy,
+// WriteThrough or WriteBack. Write-back changes are flushed every
+// flushInterval once Start is called.
+func OpenJournal(path, strategy string, flushInterval time.Duration) (*Journal, error) {
+	if strategy != WriteThrough && strategy != WriteBack {
+		return nil, fmt.Errorf("unknown cache write strategy %q", strategy)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache journal: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Journal{
+		path:          path,
+		strategy:      strategy,
+		flushInterval: flushInterval,
+		file:          file,
+		pending:       make(map[string]journalRecord),
+		ctx:           ctx,
+		cancel:        cancel,
+		done:          make(chan struct{}),
+	}, nil
+}
+
+// Start flushes write-back changes every flush interval until Stop
+func (j *Journal) Start() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.running || j.strategy != WriteBack {
+		return
+	}
+	j.running = true
+
+	log.Printf("Flushing cache changes to %s every %v", j.path, j.flushInterval)
+	go j.run()
+}
+
+// Stop flushes the pending changes and closes the journal
+func (j *Journal) Stop() {
+	j.mu.Lock()
+	running := j.running
+	j.running = false
+	j.mu.Unlock()
+	if running {
+		j.cancel()
+		<-j.done
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.flushLocked()
+	if err := j.file.Close(); err != nil {
+		log.Printf("Failed to close cache journal: %v", err)
+	}
+}
+
+func (j *Journal) run() {
+	defer close(j.done)
+
+	ticker := time.NewTicker(j.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-j.ctx.Done():
+			return
+		case <-ticker.C:
+			j.Flush()
+		}
+	}
+}
+
+// Flush writes the pending write-back changes
+func (j *Journal) Flush() {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.flushLocked()
+}
+
+// record journals one change, at once or with the next flush
+func (j *Journal) record(record journalRecord) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.writes++
+	if j.strategy == WriteThrough {
+		j.appendLocked([]journalRecord{record})
+		return
+	}
+
+	if len(j.pending) == 0 {
+		j.pendingSince = time.Now()
+	}
+	if record.Op == journalClear {
+		// Nothing recorded before a clear survives it
+		j.pending = make(map[string]journalRecord)
+		j.pendingOrder = j.pendingOrder[:0]
+	}
+	if _, exists := j.pending[record.Key]; !exists {
+		j.pendingOrder = append(j.pendingOrder, record.Key)
+	}
+	j.pending[record.Key] = record
+}
+
+func (j *Journal) flushLocked() {
+	if len(j.pending) == 0 {
+		return
+	}
+	records := make([]journalRecord, 0, len(j.pending))
+	for _, key := range j.pendingOrder {
+		records = append(records, j.pending[key])
+	}
+	if j.appendLocked(records) {
+		j.pending = make(map[string]journalRecord)
+		j.pendingOrder = j.pendingOrder[:0]
+	}
+	j.flushes++
+}
+
+// appendLocked writes and syncs records, reporting whether they were
+// persisted. Failed write-back records stay pending for the next flush.
+func (j *Journal) appendLocked(records []journalRecord) bool {
+	var buf []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			continue
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+
+	_, err := j.file.Write(buf)
+	if err == nil {
+		err = j.file.Sync()
+	}
+	if err != nil {
+		j.writeErrors++
+		j.lastError = err.Error()
+		log.Printf("Failed to write cache journal: %v", err)
+		return false
+	}
+	j.persisted += int64(len(records))
+	j.lastFlush = time.Now()
+	return true
+}
+
+// lock holds back appends while a snapshot is taken, so that no change is
+// both missed by the snapshot and truncated from the journal. It returns the
+// function releasing the lock.
+func (j *Journal) lock() func() {
+	if j == nil {
+		return func() {}
+	}
+	j.mu.Lock()
+	return j.mu.Unlock
+}
+
+// truncateLocked empties the journal once a snapshot holds its changes. The
+// caller must hold the lock.
+func (j *Journal) truncateLocked() {
+	if j == nil {
+		return
+	}
+	if err := j.file.Truncate(0); err != nil {
+		j.writeErrors++
+		j.lastError = err.Error()
+		log.Printf("Failed to compact cache journal: %v", err)
+		return
+	}
+	j.compactions++
+}
+
+// Stats returns the journal's durability counters. pending is the number of
+// changes a crash would lose, oldest_pending_seconds how old the oldest is.
+func (j *Journal) Stats() map[string]interface{} {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	oldest := 0.0
+	if len(j.pending) > 0 {
+		oldest = time.Since(j.pendingSince).Seconds()
+	}
+	var size int64
+	if info, err := j.file.Stat(); err == nil {
+		size = info.Size()
+	}
+	stats := map[string]interface{}{
+		"strategy":               j.strategy,
+		"writes":                 j.writes,
+		"persisted":              j.persisted,
+		"pending":                len(j.pending),
+		"oldest_pending_seconds": oldest,
+		"write_errors":           j.writeErrors,
+		"compactions":            j.compactions,
+		"journal_bytes":          size,
+	}
+	if j.strategy == WriteBack {
+		stats["flush_interval_seconds"] = j.flushInterval.Seconds()
+		stats["flushes"] = j.flushes
+	}
+	if !j.lastFlush.IsZero() {
+		stats["last_write"] = j.lastFlush
+	}
+	if j.lastError != "" {
+		stats["last_error"] = j.lastError
+	}
+	return stats
+}
+
+// readJournal returns the entries left by replaying the journal at path over
+// entries, keyed by cache key, most recently changed first. A missing journal
+// changes nothing; a torn last line, as a crash mid-append leaves, ends the
+// replay.
+func readJournal(path string, entries []snapshotEntry) ([]snapshotEntry, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache journal: %w", err)
+	}
+	defer file.Close()
+
+	byKey := make(map[string]snapshotEntry, len(entries))
+	order := make(map[string]int, len(entries)) // Lower is more recent
+	for i, e := range entries {
+		byKey[e.Key] = e
+		order[e.Key] = i
+	}
+
+	changed := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			break
+		}
+		changed++
+		switch record.Op {
+		case journalSet:
+			byKey[record.Key] = record.snapshotEntry
+			order[record.Key] = -changed
+		case journalDelete:
+			delete(byKey, record.Key)
+		case journalClear:
+			byKey = make(map[string]snapshotEntry)
+		}
+	}
+
+	replayed := make([]snapshotEntry, 0, len(byKey))
+	for _, e := range byKey {
+		replayed = append(replayed, e)
+	}
+	sort.Slice(replayed, func(a, b int) bool { return order[replayed[a].Key] < order[replayed[b].Key] })
+	return replayed, nil
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal_WriteThrough(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	journal, err := OpenJournal(JournalPath(path), WriteThrough, time.Second)
+	require.NoError(t, err)
+
+	original := NewMemoryCache(time.Hour)
+	original.SetJournal(journal)
+	original.Set(testProvider, "USD", "INR", "", 83.5)
+	original.Set(testProvider, "USD", "EUR", "", 0.92)
+	saved, err := original.SaveSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, saved)
+
+	// Changes after the snapshot are only in the journal, persisted before
+	// Set returns: a crash now loses nothing
+	original.Set(testProvider, "USD", "INR", "", 84.0)
+	original.Set(testProvider, "USD", "GBP", "", 0.79)
+	original.Delete(testProvider, "USD", "EUR", "")
+	original.Set(testProvider, "USD", "JPY", "", 150)
+	original.DeletePair("USD", "JPY")
+
+	stats := original.GetStats()["journal"].(map[string]interface{})
+	assert.Equal(t, WriteThrough, stats["strategy"])
+	assert.Equal(t, int64(7), stats["writes"])
+	assert.Equal(t, int64(7), stats["persisted"])
+	assert.Equal(t, 0, stats["pending"])
+	assert.Equal(t, int64(1), stats["compactions"])
+
+	restored := NewMemoryCache(time.Hour)
+	count, err := restored.LoadSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	rate, found := restored.Get(testProvider, "USD", "INR", "")
+	assert.True(t, found)
+	assert.Equal(t, 84.0, rate, "the journal is replayed over the snapshot")
+	_, found = restored.Get(testProvider, "USD", "GBP", "")
+	assert.True(t, found)
+	_, found = restored.Get(testProvider, "USD", "EUR", "")
+	assert.False(t, found, "deletions are replayed")
+	_, found = restored.Get(testProvider, "USD", "JPY", "")
+	assert.False(t, found)
+
+	// A new snapshot compacts the journal
+	_, err = original.SaveSnapshot(path)
+	require.NoError(t, err)
+	info, err := os.Stat(JournalPath(path))
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+	journal.Stop()
+}
+
+func TestJournal_WriteBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	journal, err := OpenJournal(JournalPath(path), WriteBack, time.Hour)
+	require.NoError(t, err)
+
+	original := NewMemoryCache(time.Hour)
+	original.SetJournal(journal)
+	original.Set(testProvider, "USD", "INR", "", 83.5)
+	original.Set(testProvider, "USD", "INR", "", 84.0)
+	original.Set(testProvider, "USD", "EUR", "", 0.92)
+
+	stats := journal.Stats()
+	assert.Equal(t, int64(3), stats["writes"])
+	assert.Equal(t, 2, stats["pending"], "only the latest change of a key is kept")
+	assert.Equal(t, int64(0), stats["persisted"])
+	assert.Greater(t, stats["oldest_pending_seconds"], 0.0)
+
+	restored := NewMemoryCache(time.Hour)
+	count, err := restored.LoadSnapshot(path)
+	require.NoError(t, err)
+	assert.Zero(t, count, "nothing is on disk before the flush")
+
+	journal.Flush()
+	stats = journal.Stats()
+	assert.Equal(t, 0, stats["pending"])
+	assert.Equal(t, int64(2), stats["persisted"])
+	assert.Equal(t, int64(1), stats["flushes"])
+
+	original.Clear()
+	original.Set(testProvider, "USD", "GBP", "", 0.79)
+	journal.Stop() // Flushes
+
+	restored = NewMemoryCache(time.Hour)
+	count, err = restored.LoadSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "a clear drops everything journaled before it")
+	rate, found := restored.Get(testProvider, "USD", "GBP", "")
+	assert.True(t, found)
+	assert.Equal(t, 0.79, rate)
+}
+
+func TestReadJournal_TornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json.journal")
+	expires := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	lines := `{"op":"set","key":"erapi|USD_INR_latest","rate":83.5,"expires_at":"` + expires + `"}` + "\n" +
+		`{"op":"set","key":"erapi|USD_EUR_latest","rate":0.9`
+	require.NoError(t, os.WriteFile(path, []byte(lines), 0o644))
+
+	entries, err := readJournal(path, []snapshotEntry{{Key: "erapi|USD_GBP_latest", Rate: 0.79}})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "erapi|USD_INR_latest", entries[0].Key, "journaled entries are the most recent")
+	assert.Equal(t, "erapi|USD_GBP_latest", entries[1].Key)
+
+	_, err = OpenJournal(path, WriteSnapshot, time.Second)
+	assert.Error(t, err)
+}
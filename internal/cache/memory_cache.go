@@ -1,26 +1,121 @@
 package cache
 
 import (
+	"container/list"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 )
 
+// Source names the provider a rate came from and when the provider
+// published it
+type Source struct {
+	Provider    string
+	PublishedAt time.Time
+	Derived     string // How the rate was computed when not quoted, e.g. models.DerivedCross
+}
+
 type CacheItem struct {
 	Rate      float64
+	StoredAt  time.Time
 	ExpiresAt time.Time
+	Source
+
+	// Negative marks an entry recording that the provider has no rate for
+	// the pair, so repeated lookups don't go upstream
+	Negative bool
+}
+
+// Options configures a MemoryCache
+type Options struct {
+	TTL           time.Duration // TTL of latest rates
+	HistoricalTTL time.Duration // TTL of dated entries, defaults to TTL
+	MaxEntries    int           // 0 means unbounded
+
+	// StaleGrace is how long after being stored an expired rate is kept
+	// and served by GetStale, for when the provider can't refresh it. 0
+	// drops entries as soon as they expire.
+	StaleGrace time.Duration
+
+	// Shards is how many independently locked parts the entries are spread
+	// over by key hash, DefaultShards when 0. Bounded caches use fewer, so
+	// every shard has room for at least minShardEntries.
+	Shards int
+}
+
+// DefaultShards is how many shards a cache uses unless Options say otherwise
+const DefaultShards = 32
+
+// minShardEntries is the fewest entries a shard of a bounded cache holds.
+// Each shard evicts its own least recently used entries, which stays close
+// to evicting the cache's only while shards are not tiny.
+const minShardEntries = 64
+
+// entry is the value stored in the LRU list
+type entry struct {
+	key  string
+	item CacheItem
+	used int64 // When the entry was last stored or hit, in nanoseconds since the cache was created
+}
+
+// shard holds the entries whose keys hash to it, under its own lock, so
+// lookups of different pairs don't wait on each other. It tracks the calls
+// it serves in stats of its own for the same reason.
+type shard struct {
+	mu          sync.RWMutex
+	stats       *cacheStats
+	data        map[string]*list.Element
+	lru         *list.List // front is most recently used
+	maxEntries  int        // 0 means unbounded
+	evictions   int64
+	expirations int64
 }
 
 type MemoryCache struct {
-	data map[string]CacheItem
-	mu   sync.RWMutex
-	ttl  time.Duration
+	shards        []*shard
+	ttl           time.Duration
+	historicalTTL time.Duration
+	maxEntries    int
+	staleGrace    time.Duration
+	created       time.Time  // Entries' uses are ordered by the time since, across shards, for snapshots
+	restored      int64      // Uses given to restored entries, counting down from 0 under every shard's lock
+	snapshotMu    sync.Mutex // Serialises SaveSnapshot
+	journal       *Journal   // Changes since the last snapshot, nil when only snapshots persist the cache
 }
 
 func NewMemoryCache(ttl time.Duration) *MemoryCache {
+	return NewMemoryCacheWithOptions(Options{TTL: ttl})
+}
+
+func NewMemoryCacheWithOptions(opts Options) *MemoryCache {
+	if opts.HistoricalTTL == 0 {
+		opts.HistoricalTTL = opts.TTL
+	}
+
+	shards := opts.Shards
+	if shards <= 0 {
+		shards = DefaultShards
+	}
+	if opts.MaxEntries > 0 && shards > opts.MaxEntries/minShardEntries {
+		shards = max(opts.MaxEntries/minShardEntries, 1)
+	}
+
 	cache := &MemoryCache{
-		data: make(map[string]CacheItem),
-		ttl:  ttl,
+		shards:        make([]*shard, shards),
+		ttl:           opts.TTL,
+		historicalTTL: opts.HistoricalTTL,
+		maxEntries:    opts.MaxEntries,
+		staleGrace:    opts.StaleGrace,
+		created:       time.Now(),
+	}
+	for i := range cache.shards {
+		// MaxEntries is split evenly, the first shards taking the remainder
+		maxEntries := opts.MaxEntries / shards
+		if i < opts.MaxEntries%shards {
+			maxEntries++
+		}
+		cache.shards[i] = &shard{data: make(map[string]*list.Element), lru: list.New(), maxEntries: maxEntries, stats: newCacheStats()}
 	}
 
 	go cache.cleanupExpired()
@@ -28,113 +123,487 @@ func NewMemoryCache(ttl time.Duration) *MemoryCache {
 	return cache
 }
 
-func (c *MemoryCache) generateKey(from, to, date string) string {
+// use returns the time of an entry's use, ordering it after every earlier
+// use in any shard
+func (c *MemoryCache) use() int64 {
+	return int64(time.Since(c.created))
+}
+
+// shardFor returns the shard of a key, picked by its FNV-1a hash
+func (c *MemoryCache) shardFor(key string) *shard {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return c.shards[hash%uint32(len(c.shards))]
+}
+
+// lockAll write-locks every shard, in order, and returns the function that
+// unlocks them
+func (c *MemoryCache) lockAll() func() {
+	for _, s := range c.shards {
+		s.mu.Lock()
+	}
+	return func() {
+		for _, s := range c.shards {
+			s.mu.Unlock()
+		}
+	}
+}
+
+// SetJournal records every later change to the cache in journal, to be
+// replayed by LoadSnapshot. Call it before the cache is shared.
+func (c *MemoryCache) SetJournal(journal *Journal) {
+	c.journal = journal
+}
+
+// Namespace prefixes currency with namespace for use as the from currency of
+// an entry, so the entry is kept apart from other namespaces' entries of the
+// same pair. DeletePair removes a pair from every namespace.
+func Namespace(namespace, currency string) string {
+	return namespace + ":" + currency
+}
+
+// generateKey returns the key of an entry: the provider the rate comes from,
+// then the pair and date, e.g. "erapi|USD_INR_latest". Rates of different
+// providers are kept apart, so a lookup never returns another source's rate.
+func (c *MemoryCache) generateKey(provider, from, to, date string) string {
 	if date == "" {
-		return fmt.Sprintf("%s_%s_latest", from, to)
+		return fmt.Sprintf("%s|%s_%s_latest", provider, from, to)
+	}
+	return fmt.Sprintf("%s|%s_%s_%s", provider, from, to, date)
+}
+
+// splitKey splits a key into its provider and the rest, which names the
+// namespaced pair and date
+func splitKey(key string) (provider, rest string) {
+	provider, rest, found := strings.Cut(key, "|")
+	if !found {
+		return "", key
+	}
+	return provider, rest
+}
+
+// pairKey returns the part of a key naming the pair and date, without its
+// provider and namespace
+func pairKey(key string) string {
+	_, key = splitKey(key)
+	if _, unscoped, found := strings.Cut(key, ":"); found {
+		return unscoped
+	}
+	return key
+}
+
+func (c *MemoryCache) Get(provider, from, to, date string) (float64, bool) {
+	item, found := c.GetItem(provider, from, to, date)
+	return item.Rate, found
+}
+
+// GetItem returns a fresh entry of provider together with when it was stored
+// and when it expires
+func (c *MemoryCache) GetItem(provider, from, to, date string) (item CacheItem, found bool) {
+	key := c.generateKey(provider, from, to, date)
+	s := c.shardFor(key)
+	defer func(start time.Time) { s.stats.recordLookup(OpGet, provider, start, found) }(time.Now())
+	// Full lock: a hit moves the entry to the front of the LRU list
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, exists := s.data[key]
+
+	if !exists {
+		return CacheItem{}, false
+	}
+
+	item = element.Value.(*entry).item
+	if item.Negative || time.Now().After(item.ExpiresAt) {
+		return CacheItem{}, false
 	}
-	return fmt.Sprintf("%s_%s_%s", from, to, date)
+
+	s.lru.MoveToFront(element)
+	element.Value.(*entry).used = c.use()
+	return item, true
 }
 
-func (c *MemoryCache) Get(from, to, date string) (float64, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// GetStale returns an entry whether or not it has expired, as long as it was
+// stored within StaleGrace. It backs conversions up when the provider is down
+// and the fresh entry is gone.
+func (c *MemoryCache) GetStale(provider, from, to, date string) (item CacheItem, found bool) {
+	key := c.generateKey(provider, from, to, date)
+	s := c.shardFor(key)
+	defer func(start time.Time) { s.stats.recordLookup(OpGetStale, provider, start, found) }(time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, exists := s.data[key]
+	if !exists {
+		return CacheItem{}, false
+	}
+
+	item = element.Value.(*entry).item
+	if item.Negative || !c.retained(item, time.Now()) {
+		return CacheItem{}, false
+	}
+
+	s.lru.MoveToFront(element)
+	element.Value.(*entry).used = c.use()
+	return item, true
+}
 
-	key := c.generateKey(from, to, date)
-	item, exists := c.data[key]
+// GetNegative returns a fresh negative entry of a pair at provider
+func (c *MemoryCache) GetNegative(provider, from, to, date string) (item CacheItem, found bool) {
+	key := c.generateKey(provider, from, to, date)
+	s := c.shardFor(key)
+	defer func(start time.Time) { s.stats.recordLookup(OpGetNegative, provider, start, found) }(time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	element, exists := s.data[key]
 	if !exists {
-		return 0, false
+		return CacheItem{}, false
 	}
 
-	if time.Now().After(item.ExpiresAt) {
-		return 0, false
+	item = element.Value.(*entry).item
+	if !item.Negative || time.Now().After(item.ExpiresAt) {
+		return CacheItem{}, false
 	}
 
-	return item.Rate, true
+	s.lru.MoveToFront(element)
+	element.Value.(*entry).used = c.use()
+	return item, true
+}
+
+// Set stores a rate of provider using the default TTL for its kind: latest
+// rates use TTL, dated entries use HistoricalTTL
+func (c *MemoryCache) Set(provider, from, to, date string, rate float64) {
+	c.SetWithSource(from, to, date, rate, Source{Provider: provider})
+}
+
+// SetWithSource stores a rate like Set under the provider of source,
+// recording when it was published
+func (c *MemoryCache) SetWithSource(from, to, date string, rate float64, source Source) {
+	ttl := c.ttl
+	if date != "" {
+		ttl = c.historicalTTL
+	}
+	c.setItem(from, to, date, rate, source, ttl)
 }
 
-func (c *MemoryCache) Set(from, to, date string, rate float64) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// SetWithTTL stores a rate of provider that expires after ttl instead of the
+// default
+func (c *MemoryCache) SetWithTTL(provider, from, to, date string, rate float64, ttl time.Duration) {
+	c.setItem(from, to, date, rate, Source{Provider: provider}, ttl)
+}
 
-	key := c.generateKey(from, to, date)
-	c.data[key] = CacheItem{
+func (c *MemoryCache) setItem(from, to, date string, rate float64, source Source, ttl time.Duration) {
+	now := time.Now()
+	c.store(c.generateKey(source.Provider, from, to, date), CacheItem{
 		Rate:      rate,
-		ExpiresAt: time.Now().Add(c.ttl),
+		StoredAt:  now,
+		ExpiresAt: now.Add(ttl),
+		Source:    source,
+	})
+}
+
+// SetNegative records that provider has no rate for a pair for ttl. It
+// shares the pair's key, so a rate stored later replaces it.
+func (c *MemoryCache) SetNegative(provider, from, to, date string, ttl time.Duration) {
+	now := time.Now()
+	c.store(c.generateKey(provider, from, to, date), CacheItem{
+		StoredAt:  now,
+		ExpiresAt: now.Add(ttl),
+		Source:    Source{Provider: provider},
+		Negative:  true,
+	})
+}
+
+// store inserts or replaces an entry, evicting the least recently used ones
+// of its shard beyond the shard's share of MaxEntries
+func (c *MemoryCache) store(key string, item CacheItem) {
+	s := c.shardFor(key)
+	defer s.stats.record(OpSet, time.Now())
+	// Journaled once the lock is released, so lookups don't wait on the disk
+	defer c.journal.record(journalRecord{Op: journalSet, snapshotEntry: newSnapshotEntry(key, item)})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, exists := s.data[key]; exists {
+		element.Value.(*entry).item = item
+		element.Value.(*entry).used = c.use()
+		s.lru.MoveToFront(element)
+		return
+	}
+
+	s.data[key] = s.lru.PushFront(&entry{key: key, item: item, used: c.use()})
+	s.evict()
+}
+
+func (c *MemoryCache) Delete(provider, from, to, date string) {
+	key := c.generateKey(provider, from, to, date)
+	s := c.shardFor(key)
+	defer s.stats.record(OpDelete, time.Now())
+	var removed []string
+	defer func() { c.journalDeletes(removed) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, exists := s.data[key]; exists {
+		s.remove(element)
+		removed = append(removed, key)
 	}
 }
 
-func (c *MemoryCache) Delete(from, to, date string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// DeletePair removes the latest and every dated entry of a currency pair, of
+// every provider and in every namespace, and returns how many entries were
+// removed
+func (c *MemoryCache) DeletePair(from, to string) int {
+	var removed []string
+	defer func() { c.journalDeletes(removed) }()
 
-	key := c.generateKey(from, to, date)
-	delete(c.data, key)
+	prefix := fmt.Sprintf("%s_%s_", from, to)
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for key, element := range s.data {
+			if strings.HasPrefix(pairKey(key), prefix) {
+				removed = append(removed, key)
+				s.remove(element)
+			}
+		}
+		s.mu.Unlock()
+	}
+	return len(removed)
+}
+
+// DeleteDate removes the entry of a currency pair on date, negative ones
+// included, of every provider and in every namespace, and returns how many
+// entries were removed
+func (c *MemoryCache) DeleteDate(from, to, date string) int {
+	var removed []string
+	defer func() { c.journalDeletes(removed) }()
+
+	target := pairKey(c.generateKey("", from, to, date))
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for key, element := range s.data {
+			if pairKey(key) == target {
+				removed = append(removed, key)
+				s.remove(element)
+			}
+		}
+		s.mu.Unlock()
+	}
+	return len(removed)
+}
+
+// journalDeletes journals the removal of keys. It is deferred before the
+// lock is taken, so it runs once the lock is released.
+func (c *MemoryCache) journalDeletes(keys []string) {
+	for _, key := range keys {
+		c.journal.record(journalRecord{Op: journalDelete, snapshotEntry: snapshotEntry{Key: key}})
+	}
 }
 
 func (c *MemoryCache) Clear() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.journal.record(journalRecord{Op: journalClear})
 
-	c.data = make(map[string]CacheItem)
+	defer c.lockAll()()
+
+	for _, s := range c.shards {
+		s.data = make(map[string]*list.Element)
+		s.lru.Init()
+	}
 }
 
 func (c *MemoryCache) Size() int {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	size := 0
+	for _, s := range c.shards {
+		s.mu.RLock()
+		size += len(s.data)
+		s.mu.RUnlock()
+	}
+	return size
+}
 
-	return len(c.data)
+// itemCounts counts entries by state
+type itemCounts struct {
+	total, valid, expired, negative int
 }
 
-func (c *MemoryCache) GetStats() map[string]interface{} {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+func (n *itemCounts) add(item CacheItem, now time.Time) {
+	n.total++
+	switch {
+	case now.After(item.ExpiresAt):
+		n.expired++
+	case item.Negative:
+		n.negative++
+	default:
+		n.valid++
+	}
+}
 
-	validItems := 0
-	expiredItems := 0
+func (c *MemoryCache) GetStats() map[string]interface{} {
+	var counts itemCounts
+	var evictions, expirations int64
+	providers := make(map[string]int)
 	now := time.Now()
+	for _, s := range c.shards {
+		s.mu.RLock()
+		for key, element := range s.data {
+			counts.add(element.Value.(*entry).item, now)
+			provider, _ := splitKey(key)
+			providers[provider]++
+		}
+		evictions += s.evictions
+		expirations += s.expirations
+		s.mu.RUnlock()
+	}
+
+	total := newCacheStats()
+	for _, s := range c.shards {
+		s.stats.addTo(total)
+	}
+	hits, misses, sets, deletes, operations := total.snapshot()
 
-	for _, item := range c.data {
-		if now.After(item.ExpiresAt) {
-			expiredItems++
-		} else {
-			validItems++
+	stats := map[string]interface{}{
+		"total_items":            counts.total,
+		"valid_items":            counts.valid,
+		"expired_items":          counts.expired,
+		"negative_items":         counts.negative,
+		"ttl_seconds":            c.ttl.Seconds(),
+		"historical_ttl_seconds": c.historicalTTL.Seconds(),
+		"max_entries":            c.maxEntries,
+		"shards":                 len(c.shards),
+		"stale_grace_seconds":    c.staleGrace.Seconds(),
+		"evictions":              evictions,
+		"expirations":            expirations,
+		"hits":                   hits,
+		"misses":                 misses,
+		"hit_ratio":              hitRatio(hits, misses),
+		"sets":                   sets,
+		"deletes":                deletes,
+		"operations":             operations,
+		"providers":              providers, // Entries per provider
+	}
+	if c.journal != nil {
+		stats["journal"] = c.journal.Stats()
+	}
+	return stats
+}
+
+// GetProviderStats returns the entries and lookups of one provider's rates
+func (c *MemoryCache) GetProviderStats(provider string) map[string]interface{} {
+	var counts itemCounts
+	now := time.Now()
+	for _, s := range c.shards {
+		s.mu.RLock()
+		for key, element := range s.data {
+			if keyProvider, _ := splitKey(key); keyProvider == provider {
+				counts.add(element.Value.(*entry).item, now)
+			}
 		}
+		s.mu.RUnlock()
 	}
 
+	var hits, misses int64
+	for _, s := range c.shards {
+		shardHits, shardMisses := s.stats.providerLookups(provider)
+		hits += shardHits
+		misses += shardMisses
+	}
 	return map[string]interface{}{
-		"total_items":   len(c.data),
-		"valid_items":   validItems,
-		"expired_items": expiredItems,
-		"ttl_seconds":   c.ttl.Seconds(),
+		"provider":       provider,
+		"total_items":    counts.total,
+		"valid_items":    counts.valid,
+		"expired_items":  counts.expired,
+		"negative_items": counts.negative,
+		"hits":           hits,
+		"misses":         misses,
+		"hit_ratio":      hitRatio(hits, misses),
+	}
+}
+
+func hitRatio(hits, misses int64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// Ping reports whether the cache backend is reachable. The in-memory cache
+// always is.
+func (c *MemoryCache) Ping() error {
+	return nil
+}
+
+// retained reports whether an entry is still kept at now: every entry until
+// it expires, and rates until StaleGrace after they were stored
+func (c *MemoryCache) retained(item CacheItem, now time.Time) bool {
+	if !now.After(item.ExpiresAt) {
+		return true
+	}
+	return !item.Negative && c.staleGrace > 0 && !now.After(item.StoredAt.Add(c.staleGrace))
+}
+
+// remove drops an entry from both the map and the LRU list. The caller must
+// hold the write lock.
+func (s *shard) remove(element *list.Element) {
+	s.lru.Remove(element)
+	delete(s.data, element.Value.(*entry).key)
+}
+
+// evict drops the least recently used entries beyond the shard's share of
+// MaxEntries. The caller must hold the write lock.
+func (s *shard) evict() {
+	for s.maxEntries > 0 && s.lru.Len() > s.maxEntries {
+		s.remove(s.lru.Back())
+		s.evictions++
 	}
 }
 
 func (c *MemoryCache) cleanupExpired() {
-	ticker := time.NewTicker(5 * time.Minute) 
+	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			c.mu.Lock()
-			now := time.Now()
+			c.removeExpired()
+		}
+	}
+}
 
-			for key, item := range c.data {
-				if now.After(item.ExpiresAt) {
-					delete(c.data, key)
-				}
+func (c *MemoryCache) removeExpired() {
+	now := time.Now()
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for _, element := range s.data {
+			if !c.retained(element.Value.(*entry).item, now) {
+				s.remove(element)
+				s.expirations++
 			}
-			c.mu.Unlock()
 		}
+		s.mu.Unlock()
 	}
 }
 
 type CacheInterface interface {
-	Get(from, to, date string) (float64, bool)
-	Set(from, to, date string, rate float64)
-	Delete(from, to, date string)
+	Get(provider, from, to, date string) (float64, bool)
+	GetItem(provider, from, to, date string) (CacheItem, bool)
+	Set(provider, from, to, date string, rate float64)
+	SetWithSource(from, to, date string, rate float64, source Source)
+	SetWithTTL(provider, from, to, date string, rate float64, ttl time.Duration)
+	GetStale(provider, from, to, date string) (CacheItem, bool)
+	GetNegative(provider, from, to, date string) (CacheItem, bool)
+	SetNegative(provider, from, to, date string, ttl time.Duration)
+	Delete(provider, from, to, date string)
+	DeletePair(from, to string) int
+	DeleteDate(from, to, date string) int
 	Clear()
 	Size() int
 	GetStats() map[string]interface{}
+	GetProviderStats(provider string) map[string]interface{}
+	Ping() error
 }
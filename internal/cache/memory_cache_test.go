@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"math/rand"
 	"sync"
 	"testing"
 	"time"
@@ -8,16 +9,19 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// testProvider is the provider the tests' rates are stored under
+const testProvider = "erapi"
+
 func TestMemoryCache_BasicOperations(t *testing.T) {
 	cache := NewMemoryCache(1 * time.Hour)
 	defer cache.Clear()
 
-	cache.Set("USD", "INR", "", 83.5)
-	rate, found := cache.Get("USD", "INR", "")
+	cache.Set(testProvider, "USD", "INR", "", 83.5)
+	rate, found := cache.Get(testProvider, "USD", "INR", "")
 	assert.True(t, found)
 	assert.Equal(t, 83.5, rate)
 
-	_, found = cache.Get("EUR", "JPY", "")
+	_, found = cache.Get(testProvider, "EUR", "JPY", "")
 	assert.False(t, found)
 }
 
@@ -25,12 +29,12 @@ func TestMemoryCache_HistoricalRates(t *testing.T) {
 	cache := NewMemoryCache(1 * time.Hour)
 	defer cache.Clear()
 
-	cache.Set("USD", "INR", "2023-01-01", 82.0)
-	rate, found := cache.Get("USD", "INR", "2023-01-01")
+	cache.Set(testProvider, "USD", "INR", "2023-01-01", 82.0)
+	rate, found := cache.Get(testProvider, "USD", "INR", "2023-01-01")
 	assert.True(t, found)
 	assert.Equal(t, 82.0, rate)
 
-	_, found = cache.Get("USD", "INR", "2023-01-02")
+	_, found = cache.Get(testProvider, "USD", "INR", "2023-01-02")
 	assert.False(t, found)
 }
 
@@ -38,17 +42,17 @@ func TestMemoryCache_Expiration(t *testing.T) {
 	cache := NewMemoryCache(100 * time.Millisecond)
 	defer cache.Clear()
 
-	cache.Set("USD", "INR", "", 83.5)
+	cache.Set(testProvider, "USD", "INR", "", 83.5)
 
 
-	_, found := cache.Get("USD", "INR", "")
+	_, found := cache.Get(testProvider, "USD", "INR", "")
 	assert.True(t, found)
 
 
 	time.Sleep(150 * time.Millisecond)
 
 
-	_, found = cache.Get("USD", "INR", "")
+	_, found = cache.Get(testProvider, "USD", "INR", "")
 	assert.False(t, found)
 }
 
@@ -56,13 +60,13 @@ func TestMemoryCache_KeyGeneration(t *testing.T) {
 	cache := NewMemoryCache(1 * time.Hour)
 	defer cache.Clear()
 
-	cache.Set("USD", "INR", "", 83.5)
-	cache.Set("USD", "INR", "2023-01-01", 82.0)
-	cache.Set("INR", "USD", "", 0.012)
+	cache.Set(testProvider, "USD", "INR", "", 83.5)
+	cache.Set(testProvider, "USD", "INR", "2023-01-01", 82.0)
+	cache.Set(testProvider, "INR", "USD", "", 0.012)
 
-	rate1, found1 := cache.Get("USD", "INR", "")
-	rate2, found2 := cache.Get("USD", "INR", "2023-01-01")
-	rate3, found3 := cache.Get("INR", "USD", "")
+	rate1, found1 := cache.Get(testProvider, "USD", "INR", "")
+	rate2, found2 := cache.Get(testProvider, "USD", "INR", "2023-01-01")
+	rate3, found3 := cache.Get(testProvider, "INR", "USD", "")
 
 	assert.True(t, found1)
 	assert.True(t, found2)
@@ -77,31 +81,61 @@ func TestMemoryCache_Delete(t *testing.T) {
 	defer cache.Clear()
 
 
-	cache.Set("USD", "INR", "", 83.5)
-	_, found := cache.Get("USD", "INR", "")
+	cache.Set(testProvider, "USD", "INR", "", 83.5)
+	_, found := cache.Get(testProvider, "USD", "INR", "")
 	assert.True(t, found)
 
 
-	cache.Delete("USD", "INR", "")
-	_, found = cache.Get("USD", "INR", "")
+	cache.Delete(testProvider, "USD", "INR", "")
+	_, found = cache.Get(testProvider, "USD", "INR", "")
 	assert.False(t, found)
 }
 
+func TestMemoryCache_DeletePair(t *testing.T) {
+	cache := NewMemoryCache(1 * time.Hour)
+	defer cache.Clear()
+
+	cache.Set(testProvider, "USD", "INR", "", 83.5)
+	cache.Set(testProvider, "USD", "INR", "2023-01-01", 82.0)
+	cache.Set(testProvider, "INR", "USD", "", 0.012)
+
+	removed := cache.DeletePair("USD", "INR")
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, 1, cache.Size())
+
+	_, found := cache.Get(testProvider, "INR", "USD", "")
+	assert.True(t, found)
+}
+
+func TestMemoryCache_DeleteDate(t *testing.T) {
+	cache := NewMemoryCache(1 * time.Hour)
+	defer cache.Clear()
+
+	cache.Set(testProvider, "USD", "INR", "2023-01-01", 82.0)
+	cache.Set(testProvider, "USD", "INR", "2023-01-02", 82.5)
+	cache.SetNegative(testProvider, Namespace("acme", "USD"), "INR", "2023-01-01", time.Minute)
+
+	assert.Equal(t, 2, cache.DeleteDate("USD", "INR", "2023-01-01"))
+	assert.Equal(t, 1, cache.Size())
+	_, found := cache.Get(testProvider, "USD", "INR", "2023-01-02")
+	assert.True(t, found)
+}
+
 func TestMemoryCache_Clear(t *testing.T) {
 	cache := NewMemoryCache(1 * time.Hour)
 
-	cache.Set("USD", "INR", "", 83.5)
-	cache.Set("EUR", "USD", "", 1.1)
-	cache.Set("GBP", "JPY", "", 150.0)
+	cache.Set(testProvider, "USD", "INR", "", 83.5)
+	cache.Set(testProvider, "EUR", "USD", "", 1.1)
+	cache.Set(testProvider, "GBP", "JPY", "", 150.0)
 
 	assert.Equal(t, 3, cache.Size())
 
 	cache.Clear()
 	assert.Equal(t, 0, cache.Size())
 
-	_, found1 := cache.Get("USD", "INR", "")
-	_, found2 := cache.Get("EUR", "USD", "")
-	_, found3 := cache.Get("GBP", "JPY", "")
+	_, found1 := cache.Get(testProvider, "USD", "INR", "")
+	_, found2 := cache.Get(testProvider, "EUR", "USD", "")
+	_, found3 := cache.Get(testProvider, "GBP", "JPY", "")
 
 	assert.False(t, found1)
 	assert.False(t, found2)
@@ -114,13 +148,13 @@ func TestMemoryCache_Size(t *testing.T) {
 
 	assert.Equal(t, 0, cache.Size())
 
-	cache.Set("USD", "INR", "", 83.5)
+	cache.Set(testProvider, "USD", "INR", "", 83.5)
 	assert.Equal(t, 1, cache.Size())
 
-	cache.Set("EUR", "USD", "", 1.1)
+	cache.Set(testProvider, "EUR", "USD", "", 1.1)
 	assert.Equal(t, 2, cache.Size())
 
-	cache.Delete("USD", "INR", "")
+	cache.Delete(testProvider, "USD", "INR", "")
 	assert.Equal(t, 1, cache.Size())
 }
 
@@ -129,8 +163,8 @@ func TestMemoryCache_Stats(t *testing.T) {
 	defer cache.Clear()
 
 
-	cache.Set("USD", "INR", "", 83.5)
-	cache.Set("EUR", "USD", "", 1.1)
+	cache.Set(testProvider, "USD", "INR", "", 83.5)
+	cache.Set(testProvider, "EUR", "USD", "", 1.1)
 
 	stats := cache.GetStats()
 	assert.Equal(t, 2, stats["total_items"])
@@ -146,6 +180,99 @@ func TestMemoryCache_Stats(t *testing.T) {
 	assert.Equal(t, 2, stats["expired_items"])
 }
 
+func TestMemoryCache_ProviderKeys(t *testing.T) {
+	cache := NewMemoryCache(1 * time.Hour)
+	defer cache.Clear()
+
+	cache.Set("erapi", "USD", "INR", "", 83.5)
+	cache.Set("frankfurter", "USD", "INR", "", 83.4)
+	cache.SetNegative("frankfurter", "USD", "XAU", "", time.Minute)
+
+	rate, _ := cache.Get("erapi", "USD", "INR", "")
+	assert.Equal(t, 83.5, rate)
+	item, _ := cache.GetItem("frankfurter", "USD", "INR", "")
+	assert.Equal(t, 83.4, item.Rate)
+	assert.Equal(t, "frankfurter", item.Source.Provider)
+	_, found := cache.Get("fixer", "USD", "INR", "")
+	assert.False(t, found, "a provider never sees another provider's rates")
+	_, found = cache.GetNegative("erapi", "USD", "XAU", "")
+	assert.False(t, found)
+
+	stats := cache.GetStats()
+	assert.Equal(t, map[string]int{"erapi": 1, "frankfurter": 2}, stats["providers"])
+
+	frankfurter := cache.GetProviderStats("frankfurter")
+	assert.Equal(t, "frankfurter", frankfurter["provider"])
+	assert.Equal(t, 2, frankfurter["total_items"])
+	assert.Equal(t, 1, frankfurter["valid_items"])
+	assert.Equal(t, 1, frankfurter["negative_items"])
+	assert.Equal(t, int64(1), frankfurter["hits"])
+	assert.Equal(t, int64(0), frankfurter["misses"])
+	fixer := cache.GetProviderStats("fixer")
+	assert.Equal(t, 0, fixer["total_items"])
+	assert.Equal(t, int64(1), fixer["misses"])
+
+	assert.Equal(t, 2, cache.DeletePair("USD", "INR"), "invalidating a pair drops it from every provider")
+}
+
+func TestMemoryCache_SetWithTTL(t *testing.T) {
+	cache := NewMemoryCache(1 * time.Hour)
+	defer cache.Clear()
+
+	cache.SetWithTTL(testProvider, "USD", "INR", "", 83.5, 50*time.Millisecond)
+	cache.Set(testProvider, "EUR", "USD", "", 1.1)
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, found := cache.Get(testProvider, "USD", "INR", "")
+	assert.False(t, found)
+	_, found = cache.Get(testProvider, "EUR", "USD", "")
+	assert.True(t, found)
+}
+
+func TestMemoryCache_HistoricalTTL(t *testing.T) {
+	cache := NewMemoryCacheWithOptions(Options{
+		TTL:           50 * time.Millisecond,
+		HistoricalTTL: 1 * time.Hour,
+	})
+	defer cache.Clear()
+
+	cache.Set(testProvider, "USD", "INR", "", 83.5)
+	cache.Set(testProvider, "USD", "INR", "2023-01-01", 82.0)
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, found := cache.Get(testProvider, "USD", "INR", "")
+	assert.False(t, found)
+	rate, found := cache.Get(testProvider, "USD", "INR", "2023-01-01")
+	assert.True(t, found)
+	assert.Equal(t, 82.0, rate)
+}
+
+func TestMemoryCache_LRUEviction(t *testing.T) {
+	cache := NewMemoryCacheWithOptions(Options{TTL: 1 * time.Hour, MaxEntries: 2})
+	defer cache.Clear()
+
+	cache.Set(testProvider, "USD", "INR", "", 83.5)
+	cache.Set(testProvider, "EUR", "USD", "", 1.1)
+
+	// Touch USD/INR so EUR/USD becomes the least recently used entry
+	cache.Get(testProvider, "USD", "INR", "")
+	cache.Set(testProvider, "GBP", "JPY", "", 150.0)
+
+	assert.Equal(t, 2, cache.Size())
+	_, found := cache.Get(testProvider, "EUR", "USD", "")
+	assert.False(t, found)
+	_, found = cache.Get(testProvider, "USD", "INR", "")
+	assert.True(t, found)
+	_, found = cache.Get(testProvider, "GBP", "JPY", "")
+	assert.True(t, found)
+
+	stats := cache.GetStats()
+	assert.Equal(t, int64(1), stats["evictions"])
+	assert.Equal(t, 2, stats["max_entries"])
+}
+
 func TestMemoryCache_ConcurrentAccess(t *testing.T) {
 	cache := NewMemoryCache(1 * time.Hour)
 	defer cache.Clear()
@@ -161,7 +288,7 @@ func TestMemoryCache_ConcurrentAccess(t *testing.T) {
 		go func(id int) {
 			defer wg.Done()
 			for j := 0; j < numOperations; j++ {
-				cache.Set("USD", "INR", "", float64(id*numOperations+j))
+				cache.Set(testProvider, "USD", "INR", "", float64(id*numOperations+j))
 			}
 		}(i)
 	}
@@ -172,15 +299,15 @@ func TestMemoryCache_ConcurrentAccess(t *testing.T) {
 		go func() {
 			defer wg.Done()
 			for j := 0; j < numOperations; j++ {
-				cache.Get("USD", "INR", "")
+				cache.Get(testProvider, "USD", "INR", "")
 			}
 		}()
 	}
 
 	wg.Wait()
 
-	cache.Set("TEST", "PAIR", "", 123.45)
-	rate, found := cache.Get("TEST", "PAIR", "")
+	cache.Set(testProvider, "TEST", "PAIR", "", 123.45)
+	rate, found := cache.Get(testProvider, "TEST", "PAIR", "")
 	assert.True(t, found)
 	assert.Equal(t, 123.45, rate)
 }
@@ -199,14 +326,14 @@ func TestMemoryCache_MixedOperations(t *testing.T) {
 			defer wg.Done()
 
 			// Set
-			cache.Set("CURR1", "CURR2", "", float64(id))
+			cache.Set(testProvider, "CURR1", "CURR2", "", float64(id))
 
 			// Get
-			cache.Get("CURR1", "CURR2", "")
+			cache.Get(testProvider, "CURR1", "CURR2", "")
 
 			// Delete
 			if id%2 == 0 {
-				cache.Delete("CURR1", "CURR2", "")
+				cache.Delete(testProvider, "CURR1", "CURR2", "")
 			}
 
 			// Size
@@ -221,3 +348,203 @@ func TestMemoryCache_MixedOperations(t *testing.T) {
 
 	assert.NotNil(t, cache)
 }
+
+func TestMemoryCache_NegativeEntries(t *testing.T) {
+	cache := NewMemoryCache(1 * time.Hour)
+
+	cache.SetNegative(testProvider, "USD", "XYZ", "", time.Hour)
+
+	_, found := cache.Get(testProvider, "USD", "XYZ", "")
+	assert.False(t, found, "a negative entry is not a rate")
+
+	item, found := cache.GetNegative(testProvider, "USD", "XYZ", "")
+	assert.True(t, found)
+	assert.True(t, item.Negative)
+	assert.Equal(t, 1, cache.GetStats()["negative_items"])
+
+	// A rate stored later replaces the negative entry
+	cache.Set(testProvider, "USD", "XYZ", "", 2.5)
+	_, found = cache.GetNegative(testProvider, "USD", "XYZ", "")
+	assert.False(t, found)
+	rate, found := cache.Get(testProvider, "USD", "XYZ", "")
+	assert.True(t, found)
+	assert.Equal(t, 2.5, rate)
+
+	cache.SetNegative(testProvider, "USD", "ABC", "2025-01-02", -time.Second)
+	_, found = cache.GetNegative(testProvider, "USD", "ABC", "2025-01-02")
+	assert.False(t, found, "expired negative entries are ignored")
+}
+
+func TestMemoryCache_StaleEntries(t *testing.T) {
+	cache := NewMemoryCacheWithOptions(Options{TTL: time.Hour, StaleGrace: 24 * time.Hour})
+	now := time.Now()
+
+	cache.SetWithTTL(testProvider, "USD", "INR", "", 83.0, -time.Second)
+	cache.store(cache.generateKey(testProvider, "USD", "EUR", ""), CacheItem{Rate: 0.9, StoredAt: now.Add(-25 * time.Hour), ExpiresAt: now.Add(-24 * time.Hour)})
+	cache.SetNegative(testProvider, "USD", "XYZ", "", -time.Second)
+
+	_, found := cache.GetItem(testProvider, "USD", "INR", "")
+	assert.False(t, found, "an expired entry is not fresh")
+	item, found := cache.GetStale(testProvider, "USD", "INR", "")
+	assert.True(t, found, "an expired entry within the grace window is kept")
+	assert.Equal(t, 83.0, item.Rate)
+
+	cache.Set(testProvider, "USD", "GBP", "", 0.79)
+	_, found = cache.GetStale(testProvider, "USD", "GBP", "")
+	assert.True(t, found, "fresh entries are served too")
+
+	_, found = cache.GetStale(testProvider, "USD", "EUR", "")
+	assert.False(t, found, "entries stored before the grace window are dropped")
+	_, found = cache.GetStale(testProvider, "USD", "XYZ", "")
+	assert.False(t, found, "negative entries are never served stale")
+
+	cache.removeExpired()
+	assert.Equal(t, 2, cache.Size(), "cleanup keeps stale entries within the grace window")
+
+	noGrace := NewMemoryCache(time.Hour)
+	noGrace.SetWithTTL(testProvider, "USD", "INR", "", 83.0, -time.Second)
+	_, found = noGrace.GetStale(testProvider, "USD", "INR", "")
+	assert.False(t, found, "without a grace window expired entries are never served")
+}
+
+func TestMemoryCache_OperationStats(t *testing.T) {
+	cache := NewMemoryCache(time.Hour)
+
+	cache.Set(testProvider, "USD", "INR", "", 83.5)
+	cache.Set(testProvider, "EUR", "USD", "", 1.1)
+	cache.Get(testProvider, "USD", "INR", "")
+	cache.Get(testProvider, "USD", "INR", "")
+	cache.Get(testProvider, "GBP", "INR", "")
+	cache.GetStale(testProvider, "GBP", "INR", "")
+	cache.Delete(testProvider, "EUR", "USD", "")
+
+	stats := cache.GetStats()
+	assert.Equal(t, int64(2), stats["hits"])
+	assert.Equal(t, int64(1), stats["misses"])
+	assert.InDelta(t, 2.0/3, stats["hit_ratio"], 1e-9)
+	assert.Equal(t, int64(2), stats["sets"])
+	assert.Equal(t, int64(1), stats["deletes"])
+
+	operations := stats["operations"].(map[string]interface{})
+	get := operations[OpGet].(map[string]interface{})
+	assert.Equal(t, int64(3), get["calls"])
+	assert.LessOrEqual(t, get["p50_ms"], get["p99_ms"])
+	assert.LessOrEqual(t, get["p99_ms"], get["max_ms"])
+
+	stale := operations[OpGetStale].(map[string]interface{})
+	assert.Equal(t, int64(1), stale["misses"])
+	assert.NotContains(t, operations[OpSet], "hits", "only lookups count hits")
+}
+
+func TestMemoryCache_OperationStatsAcrossShards(t *testing.T) {
+	cache := NewMemoryCacheWithOptions(Options{TTL: time.Hour, Shards: 8})
+	currencies := []string{"USD", "EUR", "GBP", "JPY", "INR", "CHF", "AUD", "CAD"}
+
+	var wg sync.WaitGroup
+	for _, from := range currencies {
+		wg.Add(1)
+		go func(from string) {
+			defer wg.Done()
+			for _, to := range currencies {
+				cache.Set(testProvider, from, to, "", 1)
+				cache.Get(testProvider, from, to, "")
+				cache.Get("other", from, to, "")
+			}
+		}(from)
+	}
+	wg.Wait()
+
+	stats := cache.GetStats()
+	total := int64(len(currencies) * len(currencies))
+	assert.Equal(t, total, stats["hits"], "every shard's lookups are counted")
+	assert.Equal(t, total, stats["misses"])
+	assert.Equal(t, total, stats["sets"])
+	get := stats["operations"].(map[string]interface{})[OpGet].(map[string]interface{})
+	assert.Equal(t, 2*total, get["calls"])
+
+	provider := cache.GetProviderStats(testProvider)
+	assert.Equal(t, total, provider["hits"])
+	assert.Equal(t, int64(0), provider["misses"])
+}
+
+func TestOperationStats_RollingWindow(t *testing.T) {
+	var op operationStats
+	for i := 0; i < latencyWindow; i++ {
+		op.observe(time.Second)
+	}
+	for i := 0; i < latencyWindow; i++ {
+		op.observe(time.Millisecond)
+	}
+
+	summary := op.summary(false)
+	assert.Equal(t, int64(2*latencyWindow), summary["calls"])
+	assert.Equal(t, 1.0, summary["max_ms"], "older samples rolled out of the window")
+	assert.Equal(t, 1.0, summary["p50_ms"])
+}
+
+func TestMemoryCache_Shards(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		shards int
+	}{
+		{"unbounded", Options{TTL: time.Hour}, DefaultShards},
+		{"configured", Options{TTL: time.Hour, Shards: 8}, 8},
+		{"large bound", Options{TTL: time.Hour, MaxEntries: 10000}, DefaultShards},
+		{"small bound", Options{TTL: time.Hour, MaxEntries: 640}, 10},
+		{"tiny bound", Options{TTL: time.Hour, MaxEntries: 2}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewMemoryCacheWithOptions(tt.opts)
+			assert.Equal(t, tt.shards, cache.GetStats()["shards"])
+
+			capacity := 0
+			for _, s := range cache.shards {
+				capacity += s.maxEntries
+			}
+			assert.Equal(t, tt.opts.MaxEntries, capacity, "MaxEntries is split across the shards")
+		})
+	}
+
+	cache := NewMemoryCacheWithOptions(Options{TTL: time.Hour, MaxEntries: 640})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i*100+j).Format("2006-01-02")
+				cache.Set(testProvider, "USD", "INR", date, float64(j))
+				cache.Get(testProvider, "USD", "INR", date)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stats := cache.GetStats()
+	assert.LessOrEqual(t, cache.Size(), 640)
+	assert.Equal(t, int64(1000-cache.Size()), stats["evictions"], "every entry beyond a shard's share is evicted")
+}
+
+// BenchmarkMemoryCache_ParallelGet measures lookups of different pairs from
+// many goroutines, which only contend when their keys share a shard
+func BenchmarkMemoryCache_ParallelGet(b *testing.B) {
+	cache := NewMemoryCache(time.Hour)
+	currencies := []string{"USD", "EUR", "GBP", "JPY", "INR", "CHF", "AUD", "CAD", "SGD", "HKD"}
+	var pairs [][2]string
+	for _, from := range currencies {
+		for _, to := range currencies {
+			cache.Set(testProvider, from, to, "", 1)
+			pairs = append(pairs, [2]string{from, to})
+		}
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := rand.Intn(len(pairs)); pb.Next(); i++ {
+			pair := pairs[i%len(pairs)]
+			cache.Get(testProvider, pair[0], pair[1], "")
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// snapshotVersion is bumped when the snapshot format changes; snapshots of
+// another version are rejected rather than misread
+const snapshotVersion = 2
+
+// snapshot is the file a MemoryCache is saved to
+type snapshot struct {
+	Version int             `json:"version"`
+	SavedAt time.Time       `json:"saved_at"`
+	Entries []snapshotEntry `json:"entries"` // Most recently used first
+}
+
+type snapshotEntry struct {
+	Key         string    `json:"key"`
+	Rate        float64   `json:"rate"`
+	StoredAt    time.Time `json:"stored_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Provider    string    `json:"provider,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+	Derived     string    `json:"derived,omitempty"`
+	Negative    bool      `json:"negative,omitempty"`
+}
+
+func newSnapshotEntry(key string, item CacheItem) snapshotEntry {
+	return snapshotEntry{
+		Key:         key,
+		Rate:        item.Rate,
+		StoredAt:    item.StoredAt,
+		ExpiresAt:   item.ExpiresAt,
+		Provider:    item.Provider,
+		PublishedAt: item.PublishedAt,
+		Derived:     item.Derived,
+		Negative:    item.Negative,
+	}
+}
+
+// SaveSnapshot writes the cache's unexpired entries, and stale rates still
+// within StaleGrace, to path and returns how
+// many were written. The file is replaced atomically, so a crash mid-write
+// leaves the previous snapshot intact. The journal, if any, is emptied once
+// the snapshot holds its changes.
+func (c *MemoryCache) SaveSnapshot(path string) (int, error) {
+	// Concurrent saves would share the temporary file and could finish out
+	// of order, leaving the older copy on disk
+	c.snapshotMu.Lock()
+	defer c.snapshotMu.Unlock()
+	// Changes made while the snapshot is taken are journaled after it
+	defer c.journal.lock()()
+
+	// Entries are copied under each shard's read lock in turn; encoding and
+	// writing happen without them so lookups aren't held up by disk I/O
+	now := time.Now()
+	kept := make([]entry, 0, c.Size())
+	for _, s := range c.shards {
+		s.mu.RLock()
+		for _, element := range s.data {
+			if e := element.Value.(*entry); c.retained(e.item, now) {
+				kept = append(kept, *e)
+			}
+		}
+		s.mu.RUnlock()
+	}
+	// Most recently used first, as if the shards were one LRU list
+	sort.Slice(kept, func(i, j int) bool { return kept[i].used > kept[j].used })
+	entries := make([]snapshotEntry, len(kept))
+	for i, e := range kept {
+		entries[i] = newSnapshotEntry(e.key, e.item)
+	}
+
+	data, err := json.Marshal(snapshot{Version: snapshotVersion, SavedAt: now, Entries: entries})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return 0, fmt.Errorf("failed to write cache snapshot: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return 0, fmt.Errorf("failed to write cache snapshot: %w", err)
+	}
+	c.journal.truncateLocked()
+	return len(entries), nil
+}
+
+// LoadSnapshot restores the entries saved to path that are still kept, as
+// SaveSnapshot chooses them, with their original expiry and returns how many were restored. The changes of its
+// journal are replayed on top. Entries already in the cache are newer and
+// kept. A missing file is not an error: there is nothing to restore on the
+// first start.
+func (c *MemoryCache) LoadSnapshot(path string) (int, error) {
+	var saved snapshot
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		saved.Version = snapshotVersion
+	case err != nil:
+		return 0, fmt.Errorf("failed to read cache snapshot: %w", err)
+	default:
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return 0, fmt.Errorf("failed to read cache snapshot %s: %w", path, err)
+		}
+	}
+	if saved.Version != snapshotVersion {
+		return 0, fmt.Errorf("cache snapshot %s has version %d, expected %d", path, saved.Version, snapshotVersion)
+	}
+	if saved.Entries, err = readJournal(JournalPath(path), saved.Entries); err != nil {
+		return 0, err
+	}
+
+	defer c.lockAll()()
+
+	now := time.Now()
+	restored := 0
+	// Restored entries queue behind the current ones of their shard in their
+	// saved order, so the LRU order survives the restart and evictions drop
+	// old entries
+	for _, saved := range saved.Entries {
+		if !c.retained(CacheItem{StoredAt: saved.StoredAt, ExpiresAt: saved.ExpiresAt, Negative: saved.Negative}, now) {
+			continue
+		}
+		s := c.shardFor(saved.Key)
+		if _, exists := s.data[saved.Key]; exists {
+			continue
+		}
+		if provider, _ := splitKey(saved.Key); provider == "" {
+			continue // Journaled before keys named their provider
+		}
+
+		c.restored--
+		s.data[saved.Key] = s.lru.PushBack(&entry{key: saved.Key, used: c.restored, item: CacheItem{
+			Rate:      saved.Rate,
+			StoredAt:  saved.StoredAt,
+			ExpiresAt: saved.ExpiresAt,
+			Source:    Source{Provider: saved.Provider, PublishedAt: saved.PublishedAt, Derived: saved.Derived},
+			Negative:  saved.Negative,
+		}})
+		restored++
+	}
+
+	for _, s := range c.shards {
+		s.evict()
+	}
+	return restored, nil
+}
+
+// SnapshotWriter saves a MemoryCache to a snapshot file at an interval and
+// once more when stopped, so a restart can warm the cache with LoadSnapshot
+type SnapshotWriter struct {
+	cache    *MemoryCache
+	path     string
+	interval time.Duration
+
+	mu        sync.Mutex
+	isRunning bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// NewSnapshotWriter creates a writer saving cache to path every interval
+func NewSnapshotWriter(cache *MemoryCache, path string, interval time.Duration) *SnapshotWriter {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &SnapshotWriter{
+		cache:    cache,
+		path:     path,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+func (w *SnapshotWriter) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.isRunning {
+		return
+	}
+	w.isRunning = true
+
+	log.Printf("Saving cache snapshots to %s every %v", w.path, w.interval)
+	go w.run()
+}
+
+// Stop stops the periodic saves and saves a last snapshot
+func (w *SnapshotWriter) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.isRunning {
+		return
+	}
+	w.cancel()
+	<-w.done
+	w.isRunning = false
+
+	w.save()
+}
+
+func (w *SnapshotWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.save()
+		}
+	}
+}
+
+func (w *SnapshotWriter) save() {
+	saved, err := w.cache.SaveSnapshot(w.path)
+	if err != nil {
+		log.Printf("Failed to save cache snapshot: %v", err)
+		return
+	}
+	log.Printf("Saved %d cache entries to %s", saved, w.path)
+}
@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache_SnapshotRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	published := time.Date(2024, 3, 1, 16, 0, 0, 0, time.UTC)
+
+	original := NewMemoryCache(time.Hour)
+	original.SetWithSource("USD", "INR", "", 83.5, Source{Provider: "frankfurter", PublishedAt: published})
+	original.Set(testProvider, "EUR", "USD", "2024-03-01", 1.08)
+	original.SetNegative(testProvider, "USD", "XYZ", "", time.Minute)
+	original.SetWithTTL(testProvider, "GBP", "USD", "", 1.27, -time.Second) // Already expired
+
+	saved, err := original.SaveSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, saved)
+
+	restored := NewMemoryCache(time.Hour)
+	count, err := restored.LoadSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	item, found := restored.GetItem("frankfurter", "USD", "INR", "")
+	require.True(t, found)
+	assert.Equal(t, 83.5, item.Rate)
+	assert.Equal(t, "frankfurter", item.Provider)
+	assert.True(t, published.Equal(item.PublishedAt))
+	s := original.shardFor("frankfurter|USD_INR_latest")
+	s.mu.RLock()
+	expiresAt := s.data["frankfurter|USD_INR_latest"].Value.(*entry).item.ExpiresAt
+	s.mu.RUnlock()
+	assert.True(t, expiresAt.Equal(item.ExpiresAt), "entries keep their original expiry")
+
+	rate, found := restored.Get(testProvider, "EUR", "USD", "2024-03-01")
+	assert.True(t, found)
+	assert.Equal(t, 1.08, rate)
+
+	_, found = restored.GetNegative(testProvider, "USD", "XYZ", "")
+	assert.True(t, found)
+
+	_, found = restored.Get(testProvider, "GBP", "USD", "")
+	assert.False(t, found)
+}
+
+func TestMemoryCache_LoadSnapshotKeepsNewerEntriesAndOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	original := NewMemoryCache(time.Hour)
+	original.Set(testProvider, "USD", "INR", "", 83.5)
+	original.Set(testProvider, "USD", "EUR", "", 0.92)
+	original.Set(testProvider, "USD", "GBP", "", 0.79)
+	original.Get(testProvider, "USD", "INR", "") // Most recently used
+	_, err := original.SaveSnapshot(path)
+	require.NoError(t, err)
+
+	restored := NewMemoryCacheWithOptions(Options{TTL: time.Hour, MaxEntries: 2})
+	restored.Set(testProvider, "USD", "INR", "", 84.0)
+	count, err := restored.LoadSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	rate, found := restored.Get(testProvider, "USD", "INR", "")
+	assert.True(t, found)
+	assert.Equal(t, 84.0, rate, "an entry stored since startup is newer than the snapshot")
+
+	// With room for two entries the least recently used one is evicted
+	_, found = restored.Get(testProvider, "USD", "EUR", "")
+	assert.False(t, found)
+	_, found = restored.Get(testProvider, "USD", "GBP", "")
+	assert.True(t, found)
+}
+
+func TestMemoryCache_LoadSnapshotErrors(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewMemoryCache(time.Hour)
+
+	count, err := cache.LoadSnapshot(filepath.Join(dir, "missing.json"))
+	assert.NoError(t, err, "a missing snapshot is a cold start")
+	assert.Zero(t, count)
+
+	corrupt := filepath.Join(dir, "corrupt.json")
+	require.NoError(t, os.WriteFile(corrupt, []byte("{"), 0o644))
+	_, err = cache.LoadSnapshot(corrupt)
+	assert.Error(t, err)
+
+	future := filepath.Join(dir, "future.json")
+	require.NoError(t, os.WriteFile(future, []byte(`{"version":99,"entries":[]}`), 0o644))
+	_, err = cache.LoadSnapshot(future)
+	assert.ErrorContains(t, err, "version 99")
+}
+
+func TestSnapshotWriter_SavesOnStop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	cache := NewMemoryCache(time.Hour)
+	cache.Set(testProvider, "USD", "INR", "", 83.5)
+
+	writer := NewSnapshotWriter(cache, path, time.Hour)
+	writer.Start()
+	writer.Stop()
+
+	restored := NewMemoryCache(time.Hour)
+	count, err := restored.LoadSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
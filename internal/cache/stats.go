@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Cache operations whose calls and latencies are tracked
+const (
+	OpGet         = "get"
+	OpGetStale    = "get_stale"
+	OpGetNegative = "get_negative"
+	OpSet         = "set"
+	OpDelete      = "delete"
+)
+
+// latencyWindow is how many of an operation's most recent calls its
+// percentiles are computed from
+const latencyWindow = 1024
+
+// operationStats counts the calls of one operation and keeps the latencies
+// of the last latencyWindow of them
+type operationStats struct {
+	calls   int64
+	hits    int64
+	misses  int64
+	total   time.Duration
+	samples []time.Duration
+	next    int
+}
+
+// add adds the calls and recent latencies of other to s
+func (s *operationStats) add(other *operationStats) {
+	s.calls += other.calls
+	s.hits += other.hits
+	s.misses += other.misses
+	s.total += other.total
+	s.samples = append(s.samples, other.samples...)
+}
+
+func (s *operationStats) observe(latency time.Duration) {
+	s.calls++
+	s.total += latency
+	if len(s.samples) < latencyWindow {
+		s.samples = append(s.samples, latency)
+		return
+	}
+	s.samples[s.next] = latency
+	s.next = (s.next + 1) % latencyWindow
+}
+
+// summary returns the operation's counters and the 50th, 90th and 99th
+// percentile and maximum of its recent latencies in milliseconds
+func (s *operationStats) summary(lookup bool) map[string]interface{} {
+	sorted := append([]time.Duration(nil), s.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p float64) float64 {
+		if len(sorted) == 0 {
+			return 0
+		}
+		return milliseconds(sorted[int(p*float64(len(sorted)-1))])
+	}
+
+	summary := map[string]interface{}{
+		"calls":    s.calls,
+		"total_ms": milliseconds(s.total),
+		"p50_ms":   percentile(0.5),
+		"p90_ms":   percentile(0.9),
+		"p99_ms":   percentile(0.99),
+		"max_ms":   percentile(1),
+	}
+	if lookup {
+		summary["hits"] = s.hits
+		summary["misses"] = s.misses
+	}
+	return summary
+}
+
+// cacheStats tracks the calls of every cache operation on one shard. It has
+// its own lock so latencies include the wait for the shard's.
+type cacheStats struct {
+	mu         sync.Mutex
+	operations map[string]*operationStats
+	providers  map[string]*lookupCounts // Gets of each provider's rates
+}
+
+type lookupCounts struct {
+	hits, misses int64
+}
+
+func newCacheStats() *cacheStats {
+	operations := make(map[string]*operationStats)
+	for _, name := range []string{OpGet, OpGetStale, OpGetNegative, OpSet, OpDelete} {
+		operations[name] = &operationStats{}
+	}
+	return &cacheStats{operations: operations, providers: make(map[string]*lookupCounts)}
+}
+
+// record counts a call of name that started at start
+func (s *cacheStats) record(name string, start time.Time) {
+	latency := time.Since(start)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.operations[name].observe(latency)
+}
+
+// recordLookup counts a lookup of name of a provider's rate that started at
+// start and whether it found an entry
+func (s *cacheStats) recordLookup(name, provider string, start time.Time, found bool) {
+	latency := time.Since(start)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	op := s.operations[name]
+	op.observe(latency)
+	if found {
+		op.hits++
+	} else {
+		op.misses++
+	}
+
+	if name != OpGet {
+		return
+	}
+	counts, ok := s.providers[provider]
+	if !ok {
+		counts = &lookupCounts{}
+		s.providers[provider] = counts
+	}
+	if found {
+		counts.hits++
+	} else {
+		counts.misses++
+	}
+}
+
+// providerLookups returns the hits and misses of gets of provider's rates
+func (s *cacheStats) providerLookups(provider string) (hits, misses int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if counts, ok := s.providers[provider]; ok {
+		return counts.hits, counts.misses
+	}
+	return 0, 0
+}
+
+// addTo adds the calls of every operation and the lookups of every provider
+// to total, whose samples then hold the recent latencies of every shard
+func (s *cacheStats) addTo(total *cacheStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, op := range s.operations {
+		total.operations[name].add(op)
+	}
+	for provider, counts := range s.providers {
+		sum, ok := total.providers[provider]
+		if !ok {
+			sum = &lookupCounts{}
+			total.providers[provider] = sum
+		}
+		sum.hits += counts.hits
+		sum.misses += counts.misses
+	}
+}
+
+// snapshot returns the lookup counters of get, how many entries were set
+// and deleted, and the summary of every operation
+func (s *cacheStats) snapshot() (hits, misses, sets, deletes int64, operations map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	operations = make(map[string]interface{}, len(s.operations))
+	for name, op := range s.operations {
+		lookup := name == OpGet || name == OpGetStale || name == OpGetNegative
+		operations[name] = op.summary(lookup)
+	}
+	get, set, del := s.operations[OpGet], s.operations[OpSet], s.operations[OpDelete]
+	return get.hits, get.misses, set.calls, del.calls, operations
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,145 @@
+// Package chaos injects faults into requests for resilience testing: added
+// latency, 5xx errors and malformed JSON bodies, each at a configured rate.
+// The provider client wraps its transport with an Injector so retries,
+// circuit breakers and stale serving can be exercised against real traffic,
+// and middleware.Chaos does the same to API responses for callers.
+//
+// It is meant for test and staging environments only; the configuration
+// refuses to enable it in production.
+package chaos
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FaultHeader names the faults injected into a response, e.g.
+// "latency,error", so they can be told apart from real failures
+const FaultHeader = "X-Chaos-Fault"
+
+// Config holds the share of requests, between 0 and 1, each fault is
+// injected into. ErrorRate and MalformedRate are exclusive, a request gets
+// at most one of them; latency is drawn independently and comes first.
+type Config struct {
+	Latency       time.Duration // Delay added to a request
+	LatencyRate   float64       // Share of requests delayed by Latency
+	ErrorRate     float64       // Share of requests answered with a 5xx error
+	MalformedRate float64       // Share of requests answered with a truncated JSON body
+	Seed          int64         // Seeds the fault sequence for reproducible runs, random when 0
+}
+
+// Enabled reports whether any fault is injected
+func (c Config) Enabled() bool {
+	return (c.LatencyRate > 0 && c.Latency > 0) || c.ErrorRate > 0 || c.MalformedRate > 0
+}
+
+// Fault is what is injected into one request
+type Fault struct {
+	Delay     time.Duration
+	Error     bool
+	Malformed bool
+}
+
+// None reports whether the request is left alone
+func (f Fault) None() bool {
+	return f.Delay == 0 && !f.Error && !f.Malformed
+}
+
+// String returns the FaultHeader value of f
+func (f Fault) String() string {
+	var names []string
+	if f.Delay > 0 {
+		names = append(names, "latency")
+	}
+	if f.Error {
+		names = append(names, "error")
+	}
+	if f.Malformed {
+		names = append(names, "malformed")
+	}
+	return strings.Join(names, ",")
+}
+
+// Injector draws the fault of each request
+type Injector struct {
+	cfg Config
+
+	mu   sync.Mutex
+	rand *rand.Rand
+
+	requests  int64
+	delayed   int64
+	errors    int64
+	malformed int64
+}
+
+// NewInjector returns an injector drawing faults at the rates of cfg
+func NewInjector(cfg Config) *Injector {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Injector{cfg: cfg, rand: rand.New(rand.NewSource(seed))}
+}
+
+// Next draws the fault of the next request
+func (i *Injector) Next() Fault {
+	i.mu.Lock()
+	delay, outcome := i.rand.Float64(), i.rand.Float64()
+	i.mu.Unlock()
+
+	atomic.AddInt64(&i.requests, 1)
+	var fault Fault
+	if delay < i.cfg.LatencyRate && i.cfg.Latency > 0 {
+		fault.Delay = i.cfg.Latency
+		atomic.AddInt64(&i.delayed, 1)
+	}
+	switch {
+	case outcome < i.cfg.ErrorRate:
+		fault.Error = true
+		atomic.AddInt64(&i.errors, 1)
+	case outcome < i.cfg.ErrorRate+i.cfg.MalformedRate:
+		fault.Malformed = true
+		atomic.AddInt64(&i.malformed, 1)
+	}
+	return fault
+}
+
+// Stats returns how many requests were seen and how many got each fault
+func (i *Injector) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"requests":  atomic.LoadInt64(&i.requests),
+		"delayed":   atomic.LoadInt64(&i.delayed),
+		"errors":    atomic.LoadInt64(&i.errors),
+		"malformed": atomic.LoadInt64(&i.malformed),
+	}
+}
+
+// Sleep waits for d, or returns ctx's error once ctx is done
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Truncate cuts body in half, so a JSON document no longer parses. Bodies
+// too short to halve become a lone opening brace.
+func Truncate(body []byte) []byte {
+	if len(body) < 4 {
+		return []byte("{")
+	}
+	return body[:len(body)/2]
+}
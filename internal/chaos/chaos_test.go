@@ -0,0 +1,82 @@
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInjector_Rates(t *testing.T) {
+	injector := NewInjector(Config{
+		Latency:       time.Second,
+		LatencyRate:   0.25,
+		ErrorRate:     0.1,
+		MalformedRate: 0.2,
+		Seed:          7,
+	})
+
+	const requests = 10000
+	var delayed, errors, malformed int
+	for i := 0; i < requests; i++ {
+		fault := injector.Next()
+		assert.False(t, fault.Error && fault.Malformed, "errors and malformed bodies are exclusive")
+		if fault.Delay > 0 {
+			assert.Equal(t, time.Second, fault.Delay)
+			delayed++
+		}
+		if fault.Error {
+			errors++
+		}
+		if fault.Malformed {
+			malformed++
+		}
+	}
+	assert.InDelta(t, 0.25, float64(delayed)/requests, 0.02)
+	assert.InDelta(t, 0.1, float64(errors)/requests, 0.02)
+	assert.InDelta(t, 0.2, float64(malformed)/requests, 0.02)
+
+	stats := injector.Stats()
+	assert.Equal(t, int64(requests), stats["requests"])
+	assert.Equal(t, int64(delayed), stats["delayed"])
+	assert.Equal(t, int64(errors), stats["errors"])
+	assert.Equal(t, int64(malformed), stats["malformed"])
+}
+
+func TestInjector_SeedIsReproducible(t *testing.T) {
+	cfg := Config{ErrorRate: 0.5, MalformedRate: 0.3, Seed: 99}
+	first, second := NewInjector(cfg), NewInjector(cfg)
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, first.Next(), second.Next())
+	}
+}
+
+func TestConfig_Enabled(t *testing.T) {
+	assert.False(t, Config{}.Enabled())
+	assert.False(t, Config{LatencyRate: 1}.Enabled(), "a rate without latency delays nothing")
+	assert.True(t, Config{Latency: time.Second, LatencyRate: 1}.Enabled())
+	assert.True(t, Config{ErrorRate: 0.01}.Enabled())
+	assert.True(t, Config{MalformedRate: 0.01}.Enabled())
+}
+
+func TestFault_String(t *testing.T) {
+	assert.Equal(t, "", Fault{}.String())
+	assert.Equal(t, "latency,error", Fault{Delay: time.Second, Error: true}.String())
+	assert.Equal(t, "malformed", Fault{Malformed: true}.String())
+}
+
+func TestTruncate(t *testing.T) {
+	for _, body := range []string{`{"base":"USD","rates":{"EUR":0.92}}`, `{}`, ``} {
+		var out map[string]interface{}
+		assert.Error(t, json.Unmarshal(Truncate([]byte(body)), &out), body)
+	}
+}
+
+func TestSleep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, Sleep(ctx, time.Hour), context.Canceled)
+	assert.NoError(t, Sleep(context.Background(), 0))
+}
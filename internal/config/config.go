@@ -0,0 +1,2023 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/chaos"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+	"exchange-rate-service/internal/utils"
+)
+
+// Run modes
+const (
+	ModeLive    = "live"    // Rates come from the configured providers
+	ModeSandbox = "sandbox" // Rates come from fixtures, no provider is called
+)
+
+// Config holds the service settings read from the environment and, for the
+// reloadable ones, an optional JSON config file
+type Config struct {
+	Port        string
+	Bind        string         // Address the server listens on, every interface when empty
+	Proxies     []string       // IPs and CIDRs of proxies whose X-Forwarded-For is believed, none when empty
+	Mode        string         // ModeLive or ModeSandbox
+	Fixtures    string         // JSON rate fixtures served in sandbox mode, the built-in ones when empty
+	Timeout     time.Duration  // Deadline of each API request, 0 leaves only client cancellation
+	Timezone    *time.Location // Reference time zone for "today" and market days
+	Provider    external.Config
+	Sources     []RateSourceConfig // Custom rate sources, registered after the builtin providers
+	Plugins     []string           // Go plugins opened before Sources are built, to register their kinds
+	Cache       CacheConfig
+	Markup      MarkupConfig
+	Snapshot    SnapshotConfig
+	Dates       DateConfig
+	Limits      models.RequestLimits // Size of request bodies, pairs per bulk request and conversion precision
+	BulkLookups int                  // Pairs of a bulk historical request looked up at once
+	Fetch       FetchConfig
+	Currencies  []string
+	Holidays    map[string][]string            // Currency -> YYYY-MM-DD market holidays
+	Amounts     map[string]models.AmountLimits // Currency -> amounts allowed in conversions, over its metadata
+	Aliases     map[string]string              // Alias such as "rupee" -> currency code accepted in its place
+	File        string                         // JSON file overriding the reloadable settings
+	Watch       time.Duration                  // How often File is checked for changes, 0 disables
+	AuditLog    string                         // File conversions are audited to, in-memory only when empty
+	AuditMax    int                            // Newest audit records held in memory
+	SigningKey  string                         // Ed25519 PEM key file rates are signed with, unsigned when empty
+	APIKeys     []auth.APIKey
+	Anonymous   auth.Policy                  // Pairs, endpoints and entitlements of callers without a key or token
+	Tenants     []services.Tenant            // Customers with their own markup and currencies, keyed by API key or X-Tenant-ID
+	JWT         auth.JWTConfig               // Bearer token verification, disabled when JWKSURL is empty
+	RateLimit   middleware.RateLimitConfig   // Requests allowed per client IP, unlimited when PerMinute is 0
+	CORS        middleware.CORSConfig        // Browser origins allowed to call the API
+	Admin       middleware.AdminAccessConfig // Networks and client certificates admin routes are restricted to
+	TLS         *tls.Config                  // Certificate and client CAs of the HTTPS listener, plain HTTP when nil
+	AccessLog   middleware.AccessLogConfig   // Structured, sampled request logging
+	Compression middleware.CompressionConfig // Response compression negotiated from Accept-Encoding
+	Startup     services.StartupConfig       // Dependency checks before the port is bound
+	Shutdown    ShutdownConfig               // How requests are drained on SIGTERM
+
+	Environment string                     // Deployment environment, e.g. production, selecting per-environment settings
+	Discrepancy services.DiscrepancyConfig // Cross-provider quote comparison, off with fewer than two providers
+	Shadow      services.ShadowConfig      // Shadow traffic to a provider being evaluated, off without a provider
+	Jobs        services.JobConfig         // Asynchronous batch conversions
+	Webhooks    services.WebhookConfig     // Delivery of rate pushes to subscribers
+	Quotes      services.QuoteConfig       // How long quotes lock their rate for checkouts
+	Watchlists  services.WatchlistConfig   // How many pairs and watchlists callers keep
+	SLO         services.SLOConfig         // Per-endpoint latency and error rate targets, off without targets
+	Replication services.ReplicationConfig // Fetched rates shared with peer instances, off without a secret
+	Chaos       chaos.Config               // Faults injected into API responses for resilience testing, off by default
+}
+
+// ShutdownConfig holds how the server drains on SIGTERM. Together they
+// should stay below the pod's terminationGracePeriodSeconds.
+type ShutdownConfig struct {
+	DrainDelay time.Duration // How long readiness fails before the listener closes, for load balancers to notice
+	Timeout    time.Duration // How long requests in flight may take to complete once it is closed
+}
+
+// CacheConfig holds the in-memory cache settings
+type CacheConfig struct {
+	TTL           time.Duration
+	HistoricalTTL time.Duration
+	NegativeTTL   time.Duration // How long pairs the provider has no rate for are remembered, 0 disables
+	MaxEntries    int
+	Shards        int           // Independently locked parts of the cache, fewer when MaxEntries is small
+	StaleGrace    time.Duration // How old a rate may be served when the provider is down, 0 disables
+
+	// SnapshotFile is where the cache is saved every SnapshotInterval and
+	// restored from on startup, disabled when empty
+	SnapshotFile     string
+	SnapshotInterval time.Duration
+
+	// WriteStrategy is how changes between snapshots are persisted:
+	// cache.WriteSnapshot, cache.WriteThrough or cache.WriteBack, which
+	// journals them every FlushInterval
+	WriteStrategy string
+	FlushInterval time.Duration
+}
+
+// MarkupConfig holds the spread applied on top of mid-market rates, in percent
+type MarkupConfig struct {
+	GlobalPercent float64
+	Pairs         map[string]float64 // "USD_INR" -> percent
+}
+
+// SnapshotConfig holds the end-of-day rate archival settings
+type SnapshotConfig struct {
+	Hour   int // Time of the daily snapshot in the reference time zone
+	Minute int
+	Dir    string // Directory snapshots are persisted to, in-memory only when empty
+
+	// IntradayRetention is how long every fetched rate is kept for
+	// conversions at a timestamp, 0 disables intraday history
+	IntradayRetention time.Duration
+}
+
+// DateConfig limits which dates historical queries may ask for
+type DateConfig struct {
+	LookbackDays int // 0 means unbounded
+	MaxRangeDays int // Longest range a single request may span
+}
+
+// RateSourceConfig declares a custom rate source of a kind registered with
+// exchange.RegisterSource, e.g. the builtin command kind
+type RateSourceConfig struct {
+	Name     string // Provider name requests pin the source with
+	Kind     string
+	Settings map[string]string // Passed to the kind's factory
+}
+
+// FetchConfig holds how often rates are refreshed from the provider
+type FetchConfig struct {
+	Interval time.Duration // Default refresh interval of every pair
+	Pairs    []services.PairSchedule
+	Metals   string // Provider pairs involving XAU or XAG are fetched from
+	Pivot    string // Currency every base is derived from
+
+	PerBaseProviders []string // Providers always fetched per base
+	Pool             services.FetchPoolConfig
+	Hot              services.HotPairConfig
+}
+
+// Load reads the configuration from environment variables
+func Load() (*Config, error) {
+	if err := applyConfigDirs(parseList(os.Getenv("CONFIG_DIR"))); err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		Port:        getEnv("PORT", "8080"),
+		Bind:        os.Getenv("BIND_ADDRESS"),
+		Environment: strings.ToLower(strings.TrimSpace(os.Getenv("ENVIRONMENT"))),
+	}
+
+	cfg.Mode = strings.ToLower(strings.TrimSpace(getEnv("MODE", ModeLive)))
+	if cfg.Mode != ModeLive && cfg.Mode != ModeSandbox {
+		return nil, fmt.Errorf("invalid MODE: must be %s or %s", ModeLive, ModeSandbox)
+	}
+	cfg.Fixtures = os.Getenv("SANDBOX_FIXTURES")
+
+	timezone := getEnv("REFERENCE_TIMEZONE", "UTC")
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REFERENCE_TIMEZONE %q: %w", timezone, err)
+	}
+	cfg.Timezone = location
+
+	cfg.Timeout, err = getDuration("REQUEST_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Timeout < 0 {
+		return nil, fmt.Errorf("invalid REQUEST_TIMEOUT: must not be negative")
+	}
+
+	cfg.Sources, err = parseRateSources(os.Getenv("RATE_SOURCES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_SOURCES: %w", err)
+	}
+	for _, path := range strings.Split(os.Getenv("RATE_SOURCE_PLUGINS"), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			cfg.Plugins = append(cfg.Plugins, path)
+		}
+	}
+
+	cfg.Provider, err = loadProviderConfig(cfg.Sources)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Cache.TTL, err = getDuration("CACHE_TTL", 1*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	// Historical rates never change, so they can be kept much longer
+	cfg.Cache.HistoricalTTL, err = getDuration("HISTORICAL_CACHE_TTL", 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Cache.NegativeTTL, err = getDuration("NEGATIVE_CACHE_TTL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Cache.MaxEntries, err = getInt("CACHE_MAX_ENTRIES", 10000)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Cache.Shards, err = getInt("CACHE_SHARDS", cache.DefaultShards)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Cache.Shards < 1 {
+		return nil, fmt.Errorf("invalid CACHE_SHARDS: must be positive")
+	}
+	cfg.Cache.StaleGrace, err = getDuration("CACHE_STALE_GRACE", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Cache.StaleGrace < 0 {
+		return nil, fmt.Errorf("invalid CACHE_STALE_GRACE: must not be negative")
+	}
+	cfg.Cache.SnapshotFile = os.Getenv("CACHE_SNAPSHOT_FILE")
+	cfg.Cache.SnapshotInterval, err = getDuration("CACHE_SNAPSHOT_INTERVAL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Cache.SnapshotInterval <= 0 {
+		return nil, fmt.Errorf("invalid CACHE_SNAPSHOT_INTERVAL: must be positive")
+	}
+	cfg.Cache.WriteStrategy = strings.ToLower(getEnv("CACHE_WRITE_STRATEGY", cache.WriteSnapshot))
+	switch cfg.Cache.WriteStrategy {
+	case cache.WriteSnapshot:
+	case cache.WriteThrough, cache.WriteBack:
+		if cfg.Cache.SnapshotFile == "" {
+			return nil, fmt.Errorf("invalid CACHE_WRITE_STRATEGY: %s requires CACHE_SNAPSHOT_FILE", cfg.Cache.WriteStrategy)
+		}
+	default:
+		return nil, fmt.Errorf("invalid CACHE_WRITE_STRATEGY: expected %s, %s or %s, got %q",
+			cache.WriteSnapshot, cache.WriteThrough, cache.WriteBack, cfg.Cache.WriteStrategy)
+	}
+	cfg.Cache.FlushInterval, err = getDuration("CACHE_FLUSH_INTERVAL", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Cache.FlushInterval <= 0 {
+		return nil, fmt.Errorf("invalid CACHE_FLUSH_INTERVAL: must be positive")
+	}
+
+	cfg.Markup.GlobalPercent, err = getFloat("MARKUP_PERCENT", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Markup.Pairs, err = parsePairValues(os.Getenv("MARKUP_PAIRS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MARKUP_PAIRS: %w", err)
+	}
+
+	snapshotTime := getEnv("SNAPSHOT_TIME", "23:59")
+	at, err := time.Parse("15:04", snapshotTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNAPSHOT_TIME: %q is not HH:MM", snapshotTime)
+	}
+	cfg.Snapshot.Hour, cfg.Snapshot.Minute = at.Hour(), at.Minute()
+	cfg.Snapshot.Dir = os.Getenv("SNAPSHOT_DIR")
+	cfg.Snapshot.IntradayRetention, err = getDuration("INTRADAY_RETENTION", 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	// A persistent archive can answer arbitrarily old dates, so only bound
+	// the lookback by default when there is none
+	defaultLookback := utils.DefaultLookbackDays
+	if cfg.Snapshot.Dir != "" {
+		defaultLookback = 0
+	}
+	cfg.Dates.LookbackDays, err = getInt("LOOKBACK_DAYS", defaultLookback)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Dates.LookbackDays < 0 {
+		return nil, fmt.Errorf("invalid LOOKBACK_DAYS: must not be negative")
+	}
+	cfg.Dates.MaxRangeDays, err = getInt("MAX_RANGE_DAYS", utils.DefaultMaxRangeDays)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Dates.MaxRangeDays < 1 {
+		return nil, fmt.Errorf("invalid MAX_RANGE_DAYS: must be at least 1")
+	}
+	cfg.BulkLookups, err = getInt("HISTORICAL_BULK_CONCURRENCY", services.DefaultBulkHistoricalConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.BulkLookups < 1 {
+		return nil, fmt.Errorf("invalid HISTORICAL_BULK_CONCURRENCY: must be at least 1")
+	}
+
+	cfg.Limits, err = loadLimitsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Currencies = models.DefaultCurrencies
+	if value := os.Getenv("SUPPORTED_CURRENCIES"); value != "" {
+		cfg.Currencies = parseCurrencies(value)
+	}
+
+	cfg.Fetch.Interval, err = getDuration("FETCH_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Fetch.Pairs, err = parsePairSchedules(os.Getenv("FETCH_PAIR_SCHEDULES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid FETCH_PAIR_SCHEDULES: %w", err)
+	}
+	cfg.Fetch.Metals = external.CanonicalProvider(getEnv("METALS_PROVIDER", external.ProviderFixer))
+	if !external.IsBuiltinProvider(cfg.Fetch.Metals) {
+		return nil, fmt.Errorf("invalid METALS_PROVIDER: expected one of %s", strings.Join(external.BuiltinProviders, ", "))
+	}
+	cfg.Fetch.Pivot = strings.ToUpper(strings.TrimSpace(getEnv("FETCH_PIVOT_CURRENCY", "USD")))
+	if !containsCode(cfg.Currencies, cfg.Fetch.Pivot) {
+		return nil, fmt.Errorf("invalid FETCH_PIVOT_CURRENCY: %q is not a supported currency", cfg.Fetch.Pivot)
+	}
+	for _, provider := range strings.Split(os.Getenv("FETCH_PER_BASE_PROVIDERS"), ",") {
+		if provider = strings.TrimSpace(provider); provider == "" {
+			continue
+		}
+		if !external.IsBuiltinProvider(provider) {
+			return nil, fmt.Errorf("invalid FETCH_PER_BASE_PROVIDERS: unknown provider %q", provider)
+		}
+		cfg.Fetch.PerBaseProviders = append(cfg.Fetch.PerBaseProviders, external.CanonicalProvider(provider))
+	}
+	cfg.Fetch.Pool.Concurrency, err = getInt("FETCH_CONCURRENCY", services.DefaultFetchPoolConfig().Concurrency)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Fetch.Pool.Concurrency < 1 {
+		return nil, fmt.Errorf("invalid FETCH_CONCURRENCY: must be at least 1")
+	}
+	cfg.Fetch.Pool.CycleBudget, err = getDuration("FETCH_CYCLE_BUDGET", 0)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Fetch.Pool.CycleBudget < 0 {
+		return nil, fmt.Errorf("invalid FETCH_CYCLE_BUDGET: must be >= 0")
+	}
+	if cfg.Fetch.Hot, err = loadHotPairConfig(); err != nil {
+		return nil, err
+	}
+
+	cfg.Holidays = parseHolidays(os.Getenv("HOLIDAYS"))
+	if cfg.Amounts, err = parseAmountLimits(os.Getenv("AMOUNT_LIMITS")); err != nil {
+		return nil, fmt.Errorf("invalid AMOUNT_LIMITS: %w", err)
+	}
+	if cfg.Aliases, err = parseAliases(os.Getenv("CURRENCY_ALIASES")); err != nil {
+		return nil, fmt.Errorf("invalid CURRENCY_ALIASES: %w", err)
+	}
+
+	cfg.AuditLog = os.Getenv("AUDIT_LOG_FILE")
+	if cfg.AuditMax, err = getInt("AUDIT_MAX_RECORDS", 10000); err != nil {
+		return nil, err
+	}
+	if cfg.AuditMax <= 0 {
+		return nil, fmt.Errorf("invalid AUDIT_MAX_RECORDS: must be positive")
+	}
+	cfg.SigningKey = os.Getenv("SIGNING_KEY_FILE")
+
+	cfg.File = os.Getenv("CONFIG_FILE")
+	cfg.Watch, err = getDuration("CONFIG_WATCH_INTERVAL", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	// Read ahead of the file, which may override its provider
+	cfg.Shadow, err = loadShadowConfig(cfg.Provider.DefaultProvider)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.File != "" {
+		if err := cfg.applyFile(cfg.File); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.Runtime().validate(); err != nil {
+		return nil, err
+	}
+
+	cfg.APIKeys, err = parseAPIKeys(os.Getenv("API_KEYS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid API_KEYS: %w", err)
+	}
+	if adminKey := os.Getenv("ADMIN_API_KEY"); adminKey != "" {
+		cfg.APIKeys = append(cfg.APIKeys, auth.APIKey{
+			ID:    "admin",
+			Key:   adminKey,
+			Roles: []string{auth.RoleAdmin},
+		})
+	}
+
+	if err := applyKeyPolicies(cfg.APIKeys, os.Getenv("API_KEY_POLICIES")); err != nil {
+		return nil, fmt.Errorf("invalid API_KEY_POLICIES: %w", err)
+	}
+	cfg.Tenants, err = parseTenants(os.Getenv("TENANTS"), cfg.Currencies, cfg.APIKeys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TENANTS: %w", err)
+	}
+
+	cfg.JWT, err = loadJWTConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Anonymous, err = loadAnonymousPolicy(cfg.APIKeys, cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Discrepancy, err = loadDiscrepancyConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.RateLimit, err = loadRateLimitConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.CORS, err = loadCORSConfig(cfg.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.TLS, err = loadServerTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Proxies, err = loadTrustedProxies()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Admin, err = loadAdminAccessConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.AccessLog, err = loadAccessLogConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Compression, err = loadCompressionConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Shutdown, err = loadShutdownConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Startup, err = loadStartupConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Jobs, err = loadJobConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Webhooks, err = loadWebhookConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Quotes, err = loadQuoteConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Watchlists, err = loadWatchlistConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.SLO, err = loadSLOConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Replication, err = loadReplicationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Chaos, cfg.Provider.Chaos, err = loadChaosConfig(cfg.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func loadJWTConfig() (auth.JWTConfig, error) {
+	cfg := auth.DefaultJWTConfig()
+	cfg.JWKSURL = os.Getenv("JWT_JWKS_URL")
+	cfg.Issuer = os.Getenv("JWT_ISSUER")
+	cfg.Audience = os.Getenv("JWT_AUDIENCE")
+	cfg.RolesClaim = getEnv("JWT_ROLES_CLAIM", cfg.RolesClaim)
+
+	var err error
+	if cfg.RefreshInterval, err = getDuration("JWT_JWKS_REFRESH", cfg.RefreshInterval); err != nil {
+		return cfg, err
+	}
+	if cfg.Leeway, err = getDuration("JWT_LEEWAY", cfg.Leeway); err != nil {
+		return cfg, err
+	}
+	// Tokens the identity provider issued for other applications must not
+	// be accepted, so neither check is optional
+	if cfg.JWKSURL != "" && (cfg.Issuer == "" || cfg.Audience == "") {
+		return cfg, fmt.Errorf("invalid JWT_JWKS_URL: JWT_ISSUER and JWT_AUDIENCE must be set too")
+	}
+	return cfg, nil
+}
+
+func loadRateLimitConfig() (middleware.RateLimitConfig, error) {
+	cfg := middleware.DefaultRateLimitConfig()
+
+	var err error
+	if cfg.PerMinute, err = getInt("RATE_LIMIT_PER_MINUTE", cfg.PerMinute); err != nil {
+		return cfg, err
+	}
+	if cfg.PerMinute < 0 {
+		return cfg, fmt.Errorf("invalid RATE_LIMIT_PER_MINUTE: must not be negative")
+	}
+	if cfg.Burst, err = getInt("RATE_LIMIT_BURST", cfg.Burst); err != nil {
+		return cfg, err
+	}
+	if cfg.Burst < 1 {
+		return cfg, fmt.Errorf("invalid RATE_LIMIT_BURST: must be positive")
+	}
+	return cfg, nil
+}
+
+// loadServerTLSConfig reads the certificate the server is served over HTTPS
+// with, and the CAs client certificates are verified against. Clients may
+// present a certificate on any route; RestrictAdmin can require one on the
+// admin routes. Without TLS_CERT_FILE it returns nil, for plain HTTP.
+func loadServerTLSConfig() (*tls.Config, error) {
+	certFile, keyFile, caFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE"), os.Getenv("TLS_CLIENT_CA_FILE")
+	if certFile == "" {
+		if keyFile != "" || caFile != "" {
+			return nil, fmt.Errorf("invalid TLS settings: TLS_KEY_FILE and TLS_CLIENT_CA_FILE need TLS_CERT_FILE")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS_CERT_FILE or TLS_KEY_FILE: %w", err)
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS_CLIENT_CA_FILE: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("invalid TLS_CLIENT_CA_FILE: no PEM certificates in %s", caFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg, nil
+}
+
+// loadTrustedProxies reads the IPs and CIDRs of the proxies in front of the
+// service. Only requests from them have the client IP taken from
+// X-Forwarded-For, which anyone else could forge to dodge the rate limit.
+func loadTrustedProxies() ([]string, error) {
+	proxies := parseList(os.Getenv("TRUSTED_PROXIES"))
+	for _, entry := range proxies {
+		if net.ParseIP(entry) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(entry); err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %q is not an IP or CIDR", entry)
+		}
+	}
+	return proxies, nil
+}
+
+// loadAdminAccessConfig reads the networks, as IPs or CIDRs, admin requests
+// may come from and whether they need a client certificate, which takes the
+// client CAs of serverTLS
+func loadAdminAccessConfig(serverTLS *tls.Config) (middleware.AdminAccessConfig, error) {
+	var cfg middleware.AdminAccessConfig
+	for _, entry := range parseList(os.Getenv("ADMIN_ALLOWED_IPS")) {
+		cidr := entry
+		if ip := net.ParseIP(entry); ip != nil {
+			cidr += "/128"
+			if ip.To4() != nil {
+				cidr = ip.String() + "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid ADMIN_ALLOWED_IPS: %q is not an IP or CIDR", entry)
+		}
+		cfg.AllowedNets = append(cfg.AllowedNets, network)
+	}
+
+	var err error
+	if cfg.RequireClientCert, err = getBool("ADMIN_REQUIRE_CLIENT_CERT", false); err != nil {
+		return cfg, err
+	}
+	if cfg.RequireClientCert && (serverTLS == nil || serverTLS.ClientCAs == nil) {
+		return cfg, fmt.Errorf("invalid ADMIN_REQUIRE_CLIENT_CERT: needs TLS_CERT_FILE and TLS_CLIENT_CA_FILE")
+	}
+	return cfg, nil
+}
+
+// loadCORSConfig reads the CORS policy. The allowed origins of an
+// environment, e.g. CORS_ALLOWED_ORIGINS_PRODUCTION, replace
+// CORS_ALLOWED_ORIGINS when ENVIRONMENT names it.
+func loadCORSConfig(environment string) (middleware.CORSConfig, error) {
+	cfg := middleware.DefaultCORSConfig()
+
+	originsKey := "CORS_ALLOWED_ORIGINS"
+	if environment != "" {
+		if key := originsKey + "_" + strings.ToUpper(environment); os.Getenv(key) != "" {
+			originsKey = key
+		}
+	}
+	if value := os.Getenv(originsKey); value != "" {
+		cfg.AllowedOrigins = nil
+		for _, origin := range parseList(value) {
+			if err := validateOrigin(origin); err != nil {
+				return cfg, fmt.Errorf("invalid %s: %w", originsKey, err)
+			}
+			cfg.AllowedOrigins = append(cfg.AllowedOrigins, strings.TrimSuffix(origin, "/"))
+		}
+	}
+	if value := os.Getenv("CORS_ALLOWED_METHODS"); value != "" {
+		cfg.AllowedMethods = parseList(strings.ToUpper(value))
+	}
+	if value := os.Getenv("CORS_ALLOWED_HEADERS"); value != "" {
+		cfg.AllowedHeaders = parseList(value)
+	}
+	if value, found := os.LookupEnv("CORS_EXPOSED_HEADERS"); found {
+		cfg.ExposedHeaders = parseList(value)
+	}
+
+	var err error
+	if cfg.AllowCredentials, err = getBool("CORS_ALLOW_CREDENTIALS", cfg.AllowCredentials); err != nil {
+		return cfg, err
+	}
+	if cfg.AllowCredentials && cfg.AllowsAnyOrigin() {
+		return cfg, fmt.Errorf("invalid %s: credentials need explicit origins, not *", originsKey)
+	}
+	if cfg.MaxAge, err = getDuration("CORS_MAX_AGE", cfg.MaxAge); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxAge < 0 {
+		return cfg, fmt.Errorf("invalid CORS_MAX_AGE: must not be negative")
+	}
+	return cfg, nil
+}
+
+// validateOrigin checks that origin is "*" or a scheme and host, with an
+// optional port and a leading "*." wildcard label, and no path
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	parsed, err := url.Parse(strings.Replace(strings.TrimSuffix(origin, "/"), "://*.", "://wildcard.", 1))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Path != "" || parsed.RawQuery != "" || strings.Contains(parsed.Host, "*") {
+		return fmt.Errorf("expected an origin such as https://app.example.com or https://*.example.com, got %q", origin)
+	}
+	return nil
+}
+
+func loadAccessLogConfig() (middleware.AccessLogConfig, error) {
+	cfg := middleware.DefaultAccessLogConfig()
+	cfg.Sink = getEnv("ACCESS_LOG_SINK", cfg.Sink)
+	if value, found := os.LookupEnv("ACCESS_LOG_SKIP_PATHS"); found {
+		cfg.SkipPaths = parseList(value)
+	}
+
+	var err error
+	if cfg.Enabled, err = getBool("ACCESS_LOG", cfg.Enabled); err != nil {
+		return cfg, err
+	}
+	if cfg.SamplePercent, err = getFloat("ACCESS_LOG_SAMPLE_PERCENT", cfg.SamplePercent); err != nil {
+		return cfg, err
+	}
+	if cfg.SamplePercent < 0 || cfg.SamplePercent > 100 {
+		return cfg, fmt.Errorf("invalid ACCESS_LOG_SAMPLE_PERCENT: must be between 0 and 100")
+	}
+	if cfg.LogErrors, err = getBool("ACCESS_LOG_ERRORS", cfg.LogErrors); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func loadCompressionConfig() (middleware.CompressionConfig, error) {
+	cfg := middleware.DefaultCompressionConfig()
+	if value := os.Getenv("COMPRESSION_ENCODINGS"); value != "" {
+		cfg.Encodings = nil
+		for _, encoding := range parseList(strings.ToLower(value)) {
+			if !middleware.IsSupportedEncoding(encoding) {
+				return cfg, fmt.Errorf("invalid COMPRESSION_ENCODINGS: unsupported encoding %q", encoding)
+			}
+			cfg.Encodings = append(cfg.Encodings, encoding)
+		}
+	}
+
+	var err error
+	if cfg.Enabled, err = getBool("COMPRESSION", cfg.Enabled); err != nil {
+		return cfg, err
+	}
+	if cfg.MinSize, err = getInt("COMPRESSION_MIN_SIZE", cfg.MinSize); err != nil {
+		return cfg, err
+	}
+	if cfg.MinSize < 0 {
+		return cfg, fmt.Errorf("invalid COMPRESSION_MIN_SIZE: must be >= 0")
+	}
+	if cfg.Level, err = getInt("COMPRESSION_LEVEL", cfg.Level); err != nil {
+		return cfg, err
+	}
+	if cfg.Level != -1 && (cfg.Level < 1 || cfg.Level > 9) {
+		return cfg, fmt.Errorf("invalid COMPRESSION_LEVEL: must be between 1 and 9, or -1 for the default")
+	}
+	return cfg, nil
+}
+
+func loadHotPairConfig() (services.HotPairConfig, error) {
+	cfg := services.DefaultHotPairConfig()
+	var err error
+	if cfg.HotInterval, err = getDuration("HOT_PAIR_INTERVAL", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.ColdInterval, err = getDuration("COLD_PAIR_INTERVAL", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.HotInterval < 0 || cfg.ColdInterval < 0 {
+		return cfg, fmt.Errorf("invalid HOT_PAIR_INTERVAL or COLD_PAIR_INTERVAL: must be >= 0")
+	}
+	if cfg.Window, err = getDuration("HOT_PAIR_WINDOW", cfg.Window); err != nil {
+		return cfg, err
+	}
+	if cfg.Window <= 0 {
+		return cfg, fmt.Errorf("invalid HOT_PAIR_WINDOW: must be positive")
+	}
+	if cfg.MinRequests, err = getInt("HOT_PAIR_MIN_REQUESTS", cfg.MinRequests); err != nil {
+		return cfg, err
+	}
+	if cfg.MinRequests < 1 {
+		return cfg, fmt.Errorf("invalid HOT_PAIR_MIN_REQUESTS: must be positive")
+	}
+	if cfg.MaxCallsPerHour, err = getInt("HOT_PAIR_MAX_CALLS_PER_HOUR", cfg.MaxCallsPerHour); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxCallsPerHour < 0 {
+		return cfg, fmt.Errorf("invalid HOT_PAIR_MAX_CALLS_PER_HOUR: must be >= 0")
+	}
+	return cfg, nil
+}
+
+func loadShutdownConfig() (ShutdownConfig, error) {
+	cfg := ShutdownConfig{DrainDelay: 5 * time.Second, Timeout: 20 * time.Second}
+
+	var err error
+	if cfg.DrainDelay, err = getDuration("SHUTDOWN_DRAIN_DELAY", cfg.DrainDelay); err != nil {
+		return cfg, err
+	}
+	if cfg.DrainDelay < 0 {
+		return cfg, fmt.Errorf("invalid SHUTDOWN_DRAIN_DELAY: must not be negative")
+	}
+	if cfg.Timeout, err = getDuration("SHUTDOWN_TIMEOUT", cfg.Timeout); err != nil {
+		return cfg, err
+	}
+	if cfg.Timeout <= 0 {
+		return cfg, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: must be positive")
+	}
+	return cfg, nil
+}
+
+func loadStartupConfig() (services.StartupConfig, error) {
+	cfg := services.DefaultStartupConfig()
+	cfg.Policy = strings.ToLower(getEnv("STARTUP_CHECK_POLICY", cfg.Policy))
+	if cfg.Policy != services.StartupFailFast && cfg.Policy != services.StartupDegrade {
+		return cfg, fmt.Errorf("invalid STARTUP_CHECK_POLICY: must be %s or %s", services.StartupFailFast, services.StartupDegrade)
+	}
+
+	var err error
+	if cfg.Timeout, err = getDuration("STARTUP_CHECK_TIMEOUT", cfg.Timeout); err != nil {
+		return cfg, err
+	}
+	if cfg.Timeout <= 0 {
+		return cfg, fmt.Errorf("invalid STARTUP_CHECK_TIMEOUT: must be positive")
+	}
+	if cfg.RetryInterval, err = getDuration("STARTUP_RETRY_INTERVAL", cfg.RetryInterval); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxRetryInterval, err = getDuration("STARTUP_RETRY_MAX_INTERVAL", cfg.MaxRetryInterval); err != nil {
+		return cfg, err
+	}
+	if cfg.RetryInterval <= 0 || cfg.MaxRetryInterval < cfg.RetryInterval {
+		return cfg, fmt.Errorf("invalid STARTUP_RETRY_INTERVAL: must be positive and at most STARTUP_RETRY_MAX_INTERVAL")
+	}
+	return cfg, nil
+}
+
+func loadJobConfig() (services.JobConfig, error) {
+	cfg := services.DefaultJobConfig()
+
+	var err error
+	if cfg.Workers, err = getInt("JOB_WORKERS", cfg.Workers); err != nil {
+		return cfg, err
+	}
+	if cfg.Workers < 1 {
+		return cfg, fmt.Errorf("invalid JOB_WORKERS: must be positive")
+	}
+	if cfg.MaxRows, err = getInt("JOB_MAX_ROWS", cfg.MaxRows); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxRows < 1 {
+		return cfg, fmt.Errorf("invalid JOB_MAX_ROWS: must be positive")
+	}
+	if cfg.Retention, err = getDuration("JOB_RETENTION", cfg.Retention); err != nil {
+		return cfg, err
+	}
+	if cfg.Retention <= 0 {
+		return cfg, fmt.Errorf("invalid JOB_RETENTION: must be positive")
+	}
+	return cfg, nil
+}
+
+func loadLimitsConfig() (models.RequestLimits, error) {
+	cfg := models.DefaultRequestLimits()
+
+	maxBody, err := getInt("MAX_BODY_BYTES", int(cfg.MaxBodyBytes))
+	if err != nil {
+		return cfg, err
+	}
+	if maxBody < 1 {
+		return cfg, fmt.Errorf("invalid MAX_BODY_BYTES: must be positive")
+	}
+	cfg.MaxBodyBytes = int64(maxBody)
+	if cfg.MaxPairs, err = getInt("MAX_BULK_PAIRS", cfg.MaxPairs); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxPairs < 1 {
+		return cfg, fmt.Errorf("invalid MAX_BULK_PAIRS: must be positive")
+	}
+	if cfg.MaxPrecision, err = getInt("MAX_PRECISION", cfg.MaxPrecision); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxPrecision < 0 {
+		return cfg, fmt.Errorf("invalid MAX_PRECISION: must not be negative")
+	}
+	return cfg, nil
+}
+
+func loadQuoteConfig() (services.QuoteConfig, error) {
+	cfg := services.DefaultQuoteConfig()
+
+	var err error
+	if cfg.DefaultTTL, err = getDuration("QUOTE_TTL", cfg.DefaultTTL); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxTTL, err = getDuration("QUOTE_MAX_TTL", cfg.MaxTTL); err != nil {
+		return cfg, err
+	}
+	if cfg.DefaultTTL <= 0 || cfg.DefaultTTL > cfg.MaxTTL {
+		return cfg, fmt.Errorf("invalid QUOTE_TTL: must be positive and at most QUOTE_MAX_TTL")
+	}
+	if cfg.Retention, err = getDuration("QUOTE_RETENTION", cfg.Retention); err != nil {
+		return cfg, err
+	}
+	if cfg.Retention <= 0 {
+		return cfg, fmt.Errorf("invalid QUOTE_RETENTION: must be positive")
+	}
+	return cfg, nil
+}
+
+func loadWatchlistConfig() (services.WatchlistConfig, error) {
+	cfg := services.DefaultWatchlistConfig()
+
+	var err error
+	if cfg.MaxPairs, err = getInt("WATCHLIST_MAX_PAIRS", cfg.MaxPairs); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxPairs < 1 {
+		return cfg, fmt.Errorf("invalid WATCHLIST_MAX_PAIRS: must be positive")
+	}
+	if cfg.MaxPerCaller, err = getInt("WATCHLIST_MAX_PER_CALLER", cfg.MaxPerCaller); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxPerCaller < 1 {
+		return cfg, fmt.Errorf("invalid WATCHLIST_MAX_PER_CALLER: must be positive")
+	}
+	return cfg, nil
+}
+
+func loadWebhookConfig() (services.WebhookConfig, error) {
+	cfg := services.DefaultWebhookConfig()
+
+	var err error
+	if cfg.MaxAttempts, err = getInt("WEBHOOK_MAX_ATTEMPTS", cfg.MaxAttempts); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxAttempts < 1 {
+		return cfg, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS: must be positive")
+	}
+	if cfg.RetryBackoff, err = getDuration("WEBHOOK_RETRY_BACKOFF", cfg.RetryBackoff); err != nil {
+		return cfg, err
+	}
+	if cfg.RetryBackoff < 0 {
+		return cfg, fmt.Errorf("invalid WEBHOOK_RETRY_BACKOFF: must not be negative")
+	}
+	if cfg.Timeout, err = getDuration("WEBHOOK_TIMEOUT", cfg.Timeout); err != nil {
+		return cfg, err
+	}
+	if cfg.Timeout <= 0 {
+		return cfg, fmt.Errorf("invalid WEBHOOK_TIMEOUT: must be positive")
+	}
+	if cfg.MaxDeadLetters, err = getInt("WEBHOOK_MAX_DEAD_LETTERS", cfg.MaxDeadLetters); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxDeadLetters < 0 {
+		return cfg, fmt.Errorf("invalid WEBHOOK_MAX_DEAD_LETTERS: must not be negative")
+	}
+	if cfg.MaxPerCaller, err = getInt("WEBHOOK_MAX_PER_CALLER", cfg.MaxPerCaller); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxPerCaller < 1 {
+		return cfg, fmt.Errorf("invalid WEBHOOK_MAX_PER_CALLER: must be positive")
+	}
+	if cfg.AllowPrivate, err = getBool("WEBHOOK_ALLOW_PRIVATE", cfg.AllowPrivate); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func loadReplicationConfig() (services.ReplicationConfig, error) {
+	cfg := services.DefaultReplicationConfig()
+	cfg.Secret = os.Getenv("REPLICATION_SECRET")
+	cfg.InstanceID = os.Getenv("REPLICATION_INSTANCE_ID")
+	if cfg.InstanceID == "" {
+		cfg.InstanceID, _ = os.Hostname()
+	}
+
+	for _, peer := range strings.Split(os.Getenv("REPLICATION_PEERS"), ",") {
+		if peer = strings.TrimSpace(peer); peer == "" {
+			continue
+		}
+		parsed, err := url.Parse(peer)
+		if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return cfg, fmt.Errorf("invalid REPLICATION_PEERS: %q is not an absolute http or https URL", peer)
+		}
+		cfg.Peers = append(cfg.Peers, peer)
+	}
+	if len(cfg.Peers) > 0 && cfg.Secret == "" {
+		return cfg, fmt.Errorf("invalid REPLICATION_PEERS: REPLICATION_SECRET is required to sign pushes")
+	}
+	if cfg.Enabled() && cfg.InstanceID == "" {
+		return cfg, fmt.Errorf("invalid REPLICATION_INSTANCE_ID: required when the host name is unknown")
+	}
+
+	var err error
+	if cfg.StandbyFor, err = getDuration("REPLICATION_STANDBY_FOR", cfg.StandbyFor); err != nil {
+		return cfg, err
+	}
+	if cfg.StandbyFor < 0 {
+		return cfg, fmt.Errorf("invalid REPLICATION_STANDBY_FOR: must not be negative")
+	}
+	if cfg.Timeout, err = getDuration("REPLICATION_TIMEOUT", cfg.Timeout); err != nil {
+		return cfg, err
+	}
+	if cfg.Timeout <= 0 {
+		return cfg, fmt.Errorf("invalid REPLICATION_TIMEOUT: must be positive")
+	}
+	return cfg, nil
+}
+
+// Targets of CHAOS_TARGETS
+const (
+	chaosTargetAPI       = "api"
+	chaosTargetProviders = "providers"
+)
+
+// loadChaosConfig returns the faults injected into API responses and into
+// provider responses, in that order. Fault injection is for test and staging
+// environments and refused in production.
+func loadChaosConfig(environment string) (chaos.Config, chaos.Config, error) {
+	var cfg chaos.Config
+
+	var err error
+	if cfg.Latency, err = getDuration("CHAOS_LATENCY", 0); err != nil {
+		return chaos.Config{}, chaos.Config{}, err
+	}
+	if cfg.Latency < 0 {
+		return chaos.Config{}, chaos.Config{}, fmt.Errorf("invalid CHAOS_LATENCY: must not be negative")
+	}
+	for _, rate := range []struct {
+		key   string
+		value *float64
+	}{
+		{"CHAOS_LATENCY_RATE", &cfg.LatencyRate},
+		{"CHAOS_ERROR_RATE", &cfg.ErrorRate},
+		{"CHAOS_MALFORMED_RATE", &cfg.MalformedRate},
+	} {
+		if *rate.value, err = getFloat(rate.key, 0); err != nil {
+			return chaos.Config{}, chaos.Config{}, err
+		}
+		if *rate.value < 0 || *rate.value > 1 {
+			return chaos.Config{}, chaos.Config{}, fmt.Errorf("invalid %s: must be between 0 and 1", rate.key)
+		}
+	}
+	if cfg.ErrorRate+cfg.MalformedRate > 1 {
+		return chaos.Config{}, chaos.Config{}, fmt.Errorf("invalid CHAOS_MALFORMED_RATE: CHAOS_ERROR_RATE and CHAOS_MALFORMED_RATE must not add up to more than 1")
+	}
+	seed, err := getInt("CHAOS_SEED", 0)
+	if err != nil {
+		return chaos.Config{}, chaos.Config{}, err
+	}
+	cfg.Seed = int64(seed)
+
+	if !cfg.Enabled() {
+		return chaos.Config{}, chaos.Config{}, nil
+	}
+	if environment == "production" {
+		return chaos.Config{}, chaos.Config{}, fmt.Errorf("invalid CHAOS_*: fault injection is refused when ENVIRONMENT is production")
+	}
+
+	var api, providers chaos.Config
+	for _, target := range parseList(strings.ToLower(getEnv("CHAOS_TARGETS", chaosTargetProviders))) {
+		switch target {
+		case chaosTargetAPI:
+			api = cfg
+		case chaosTargetProviders:
+			providers = cfg
+		default:
+			return chaos.Config{}, chaos.Config{}, fmt.Errorf("invalid CHAOS_TARGETS: %q is not %s or %s", target, chaosTargetAPI, chaosTargetProviders)
+		}
+	}
+	return api, providers, nil
+}
+
+func loadSLOConfig() (services.SLOConfig, error) {
+	cfg := services.DefaultSLOConfig()
+	cfg.WebhookURL = os.Getenv("SLO_WEBHOOK_URL")
+
+	var err error
+	if cfg.Targets, err = parseSLOTargets(os.Getenv("SLO_TARGETS")); err != nil {
+		return cfg, fmt.Errorf("invalid SLO_TARGETS: %w", err)
+	}
+	if cfg.Window, err = getDuration("SLO_WINDOW", cfg.Window); err != nil {
+		return cfg, err
+	}
+	if cfg.Window <= 0 {
+		return cfg, fmt.Errorf("invalid SLO_WINDOW: must be positive")
+	}
+	if cfg.Interval, err = getDuration("SLO_INTERVAL", cfg.Interval); err != nil {
+		return cfg, err
+	}
+	if cfg.Interval < 0 {
+		return cfg, fmt.Errorf("invalid SLO_INTERVAL: must not be negative")
+	}
+	if cfg.MinRequests, err = getInt("SLO_MIN_REQUESTS", cfg.MinRequests); err != nil {
+		return cfg, err
+	}
+	if cfg.MinRequests < 1 {
+		return cfg, fmt.Errorf("invalid SLO_MIN_REQUESTS: must be positive")
+	}
+	if cfg.Alerts, err = loadAlertConfig("SLO"); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func loadDiscrepancyConfig() (services.DiscrepancyConfig, error) {
+	cfg := services.DefaultDiscrepancyConfig()
+	cfg.WebhookURL = os.Getenv("DISCREPANCY_WEBHOOK_URL")
+
+	for _, provider := range strings.Split(os.Getenv("DISCREPANCY_PROVIDERS"), ",") {
+		if provider = strings.TrimSpace(provider); provider == "" {
+			continue
+		}
+		if !external.IsBuiltinProvider(provider) {
+			return cfg, fmt.Errorf("invalid DISCREPANCY_PROVIDERS: unknown provider %q", provider)
+		}
+		cfg.Providers = append(cfg.Providers, external.CanonicalProvider(provider))
+	}
+	if value := os.Getenv("DISCREPANCY_PAIRS"); value != "" {
+		cfg.Pairs = nil
+		for _, pair := range strings.Split(value, ",") {
+			if pair = strings.ToUpper(strings.TrimSpace(pair)); pair == "" {
+				continue
+			}
+			if len(strings.Split(pair, "_")) != 2 {
+				return cfg, fmt.Errorf("invalid DISCREPANCY_PAIRS: expected pair in FROM_TO form, got %q", pair)
+			}
+			cfg.Pairs = append(cfg.Pairs, pair)
+		}
+	}
+
+	var err error
+	if cfg.ThresholdPercent, err = getFloat("DISCREPANCY_THRESHOLD_PERCENT", cfg.ThresholdPercent); err != nil {
+		return cfg, err
+	}
+	if cfg.Interval, err = getDuration("DISCREPANCY_INTERVAL", cfg.Interval); err != nil {
+		return cfg, err
+	}
+	if cfg.Alerts, err = loadAlertConfig("DISCREPANCY"); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// loadAlertConfig reads the channels and template of the alerts of prefix,
+// e.g. DISCREPANCY_ALERT_CHANNELS, and the SMTP server and Twilio account
+// every alert shares
+func loadAlertConfig(prefix string) (services.AlertConfig, error) {
+	cfg := services.AlertConfig{
+		Template: os.Getenv(prefix + "_ALERT_TEMPLATE"),
+		SMTP: services.SMTPConfig{
+			Addr:     os.Getenv("ALERT_SMTP_ADDR"),
+			Username: os.Getenv("ALERT_SMTP_USERNAME"),
+			Password: os.Getenv("ALERT_SMTP_PASSWORD"),
+			From:     os.Getenv("ALERT_SMTP_FROM"),
+		},
+		Twilio: services.TwilioConfig{
+			AccountSID: os.Getenv("ALERT_TWILIO_ACCOUNT_SID"),
+			AuthToken:  os.Getenv("ALERT_TWILIO_AUTH_TOKEN"),
+			From:       os.Getenv("ALERT_TWILIO_FROM"),
+		},
+	}
+	for _, entry := range parseList(os.Getenv(prefix + "_ALERT_CHANNELS")) {
+		kind, target, ok := strings.Cut(entry, ":")
+		if !ok || target == "" {
+			return cfg, fmt.Errorf("invalid %s_ALERT_CHANNELS: expected kind:target, got %q", prefix, entry)
+		}
+		cfg.Channels = append(cfg.Channels, services.AlertChannel{Kind: strings.ToLower(kind), Target: target})
+	}
+	if err := services.ValidateAlertConfig(cfg); err != nil {
+		return cfg, fmt.Errorf("invalid %s alerts: %w", prefix, err)
+	}
+	return cfg, nil
+}
+
+// loadShadowConfig reads the shadow provider, which must be another one than
+// the default provider rates are served from
+func loadShadowConfig(defaultProvider string) (services.ShadowConfig, error) {
+	cfg := services.DefaultShadowConfig()
+	if provider := strings.TrimSpace(os.Getenv("SHADOW_PROVIDER")); provider != "" {
+		if !external.IsBuiltinProvider(provider) {
+			return cfg, fmt.Errorf("invalid SHADOW_PROVIDER: expected one of %s", strings.Join(external.BuiltinProviders, ", "))
+		}
+		if cfg.Provider = external.CanonicalProvider(provider); cfg.Provider == defaultProvider {
+			return cfg, fmt.Errorf("invalid SHADOW_PROVIDER: %s already serves the rates", provider)
+		}
+	}
+
+	var err error
+	if cfg.Percent, err = getFloat("SHADOW_PERCENT", cfg.Percent); err != nil {
+		return cfg, err
+	}
+	if cfg.Percent < 0 || cfg.Percent > 100 {
+		return cfg, fmt.Errorf("invalid SHADOW_PERCENT: must be between 0 and 100")
+	}
+	if cfg.ThresholdPercent, err = getFloat("SHADOW_THRESHOLD_PERCENT", cfg.ThresholdPercent); err != nil {
+		return cfg, err
+	}
+	if cfg.Concurrency, err = getInt("SHADOW_CONCURRENCY", cfg.Concurrency); err != nil {
+		return cfg, err
+	}
+	if cfg.Concurrency < 1 {
+		return cfg, fmt.Errorf("invalid SHADOW_CONCURRENCY: must be at least 1")
+	}
+	if cfg.Timeout, err = getDuration("SHADOW_TIMEOUT", cfg.Timeout); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// loadProviderConfig reads the provider settings. DEFAULT_PROVIDER may name
+// one of sources as well as a builtin provider.
+func loadProviderConfig(sources []RateSourceConfig) (external.Config, error) {
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = getEnv("PROVIDER_BASE_URL", cfg.BaseURL)
+
+	providers := append([]string(nil), external.BuiltinProviders...)
+	for _, source := range sources {
+		providers = append(providers, source.Name)
+	}
+	cfg.DefaultProvider = external.CanonicalProvider(getEnv("DEFAULT_PROVIDER", cfg.DefaultProvider))
+	if !containsCode(providers, cfg.DefaultProvider) {
+		return cfg, fmt.Errorf("invalid DEFAULT_PROVIDER: expected one of %s", strings.Join(providers, ", "))
+	}
+
+	var err error
+	if cfg.Timeout, err = getDuration("PROVIDER_TIMEOUT", cfg.Timeout); err != nil {
+		return cfg, err
+	}
+	if cfg.Retry.MaxAttempts, err = getInt("PROVIDER_MAX_ATTEMPTS", cfg.Retry.MaxAttempts); err != nil {
+		return cfg, err
+	}
+	if cfg.Retry.InitialBackoff, err = getDuration("PROVIDER_RETRY_BACKOFF", cfg.Retry.InitialBackoff); err != nil {
+		return cfg, err
+	}
+	if cfg.Retry.MaxBackoff, err = getDuration("PROVIDER_RETRY_MAX_BACKOFF", cfg.Retry.MaxBackoff); err != nil {
+		return cfg, err
+	}
+	if cfg.Retry.Jitter, err = getFloat("PROVIDER_RETRY_JITTER", cfg.Retry.Jitter); err != nil {
+		return cfg, err
+	}
+	if cfg.Throttle.GlobalPerMinute, err = getInt("THROTTLE_GLOBAL_RPM", cfg.Throttle.GlobalPerMinute); err != nil {
+		return cfg, err
+	}
+	if cfg.Throttle.Burst, err = getInt("THROTTLE_BURST", cfg.Throttle.Burst); err != nil {
+		return cfg, err
+	}
+	if cfg.Throttle.MaxWait, err = getDuration("THROTTLE_MAX_WAIT", cfg.Throttle.MaxWait); err != nil {
+		return cfg, err
+	}
+	if cfg.CircuitBreaker.FailureThreshold, err = getInt("CIRCUIT_FAILURE_THRESHOLD", cfg.CircuitBreaker.FailureThreshold); err != nil {
+		return cfg, err
+	}
+	if cfg.CircuitBreaker.OpenTimeout, err = getDuration("CIRCUIT_OPEN_TIMEOUT", cfg.CircuitBreaker.OpenTimeout); err != nil {
+		return cfg, err
+	}
+	if value := os.Getenv("THROTTLE_PROVIDER_RPM"); value != "" {
+		if cfg.Throttle.ProviderPerMinute, err = parseProviderLimits(value); err != nil {
+			return cfg, fmt.Errorf("invalid THROTTLE_PROVIDER_RPM: %w", err)
+		}
+	}
+	if value := os.Getenv("PROVIDER_FIXED_BASES"); value != "" {
+		if cfg.FixedBases, err = parseFixedBases(value, providers); err != nil {
+			return cfg, fmt.Errorf("invalid PROVIDER_FIXED_BASES: %w", err)
+		}
+	}
+	if cfg.ConditionalRequests, err = getBool("PROVIDER_CONDITIONAL_REQUESTS", cfg.ConditionalRequests); err != nil {
+		return cfg, err
+	}
+	cfg.DecodeMode = getEnv("PROVIDER_DECODE_MODE", cfg.DecodeMode)
+	if cfg.DecodeMode != external.DecodeLenient && cfg.DecodeMode != external.DecodeStrict {
+		return cfg, fmt.Errorf("invalid PROVIDER_DECODE_MODE: expected %s or %s", external.DecodeLenient, external.DecodeStrict)
+	}
+	if cfg.Credentials, err = external.LoadCredentialsFromEnv(); err != nil {
+		return cfg, err
+	}
+	if cfg.Transport, err = loadTransportConfig(cfg.Transport); err != nil {
+		return cfg, err
+	}
+	if value := os.Getenv("PROVIDER_RETRY_STATUS"); value != "" {
+		cfg.Retry.RetryOnStatus = nil
+		for _, code := range strings.Split(value, ",") {
+			status, err := strconv.Atoi(strings.TrimSpace(code))
+			if err != nil {
+				return cfg, fmt.Errorf("invalid PROVIDER_RETRY_STATUS: %q is not a status code", code)
+			}
+			cfg.Retry.RetryOnStatus = append(cfg.Retry.RetryOnStatus, status)
+		}
+	}
+
+	return cfg, nil
+}
+
+// loadTransportConfig reads the proxy, TLS, header, connection pool, HTTP/2
+// and DNS cache settings of provider requests on top of cfg
+func loadTransportConfig(cfg external.TransportConfig) (external.TransportConfig, error) {
+	var err error
+	if value := os.Getenv("PROVIDER_PROXY_URL"); value != "" {
+		proxy, err := url.Parse(value)
+		if err != nil || proxy.Host == "" {
+			return cfg, fmt.Errorf("invalid PROVIDER_PROXY_URL: %q is not an absolute URL", value)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return cfg, fmt.Errorf("invalid PROVIDER_PROXY_URL: scheme must be http, https or socks5, got %q", proxy.Scheme)
+		}
+		cfg.Proxy = proxy
+	}
+
+	cfg.TLS, err = external.LoadTLSConfig(os.Getenv("PROVIDER_CA_FILE"), os.Getenv("PROVIDER_CLIENT_CERT_FILE"), os.Getenv("PROVIDER_CLIENT_KEY_FILE"))
+	if err != nil {
+		return cfg, fmt.Errorf("invalid provider TLS settings: %w", err)
+	}
+
+	cfg.UserAgent = getEnv("PROVIDER_USER_AGENT", cfg.UserAgent)
+	if value := os.Getenv("PROVIDER_HEADERS"); value != "" {
+		if cfg.Headers, err = parseHeaders(value); err != nil {
+			return cfg, fmt.Errorf("invalid PROVIDER_HEADERS: %w", err)
+		}
+	}
+
+	if cfg.MaxIdleConns, err = getInt("PROVIDER_MAX_IDLE_CONNS", cfg.MaxIdleConns); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxIdleConnsPerHost, err = getInt("PROVIDER_MAX_IDLE_CONNS_PER_HOST", cfg.MaxIdleConnsPerHost); err != nil {
+		return cfg, err
+	}
+	if cfg.IdleConnTimeout, err = getDuration("PROVIDER_IDLE_CONN_TIMEOUT", cfg.IdleConnTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxIdleConns < 0 || cfg.MaxIdleConnsPerHost < 0 || cfg.IdleConnTimeout < 0 {
+		return cfg, fmt.Errorf("invalid provider connection pool: sizes and timeout must not be negative")
+	}
+
+	http2, err := getBool("PROVIDER_HTTP2", !cfg.DisableHTTP2)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.DisableHTTP2 = !http2
+	if cfg.KeepAlive, err = getDuration("PROVIDER_KEEP_ALIVE", cfg.KeepAlive); err != nil {
+		return cfg, err
+	}
+	if cfg.DNSCacheTTL, err = getDuration("PROVIDER_DNS_CACHE_TTL", cfg.DNSCacheTTL); err != nil {
+		return cfg, err
+	}
+	if cfg.KeepAlive < 0 || cfg.DNSCacheTTL < 0 {
+		return cfg, fmt.Errorf("invalid PROVIDER_KEEP_ALIVE or PROVIDER_DNS_CACHE_TTL: must not be negative")
+	}
+	return cfg, nil
+}
+
+// parseHeaders parses a comma-separated list of Name=Value headers
+func parseHeaders(value string) (http.Header, error) {
+	headers := make(http.Header)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, headerValue, found := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" || strings.ContainsAny(name, " \t:") {
+			return nil, fmt.Errorf("expected Name=Value, got %q", entry)
+		}
+		headers.Add(name, strings.TrimSpace(headerValue))
+	}
+	return headers, nil
+}
+
+// parseList splits a comma-separated value, dropping empty entries
+func parseList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// dirVars holds the variables last set from CONFIG_DIR. Every load replaces
+// them, so a reload picks up changed files, while variables set in the
+// environment itself are never overwritten.
+var (
+	dirVarsMu sync.Mutex
+	dirVars   = make(map[string]bool)
+)
+
+var envName = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+// applyConfigDirs sets every variable that is not in the environment from
+// the file named after it in dirs, such as a mounted ConfigMap or Secret
+// holding FETCH_INTERVAL or PROVIDER_API_KEY. Values are trimmed and earlier
+// dirs win. Files not named like a variable, which includes the hidden
+// entries of mounted volumes, are ignored.
+func applyConfigDirs(dirs []string) error {
+	dirVarsMu.Lock()
+	defer dirVarsMu.Unlock()
+
+	values := make(map[string]string)
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("invalid CONFIG_DIR: %w", err)
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if _, seen := values[name]; seen || !envName.MatchString(name) {
+				continue
+			}
+			// Stat follows the symlinks mounted volumes consist of
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("invalid CONFIG_DIR: %w", err)
+			}
+			values[name] = strings.TrimSpace(string(data))
+		}
+	}
+
+	for name := range dirVars {
+		if _, ok := values[name]; !ok {
+			os.Unsetenv(name)
+			delete(dirVars, name)
+		}
+	}
+	for name, value := range values {
+		if _, set := os.LookupEnv(name); set && !dirVars[name] {
+			continue
+		}
+		os.Setenv(name, value)
+		dirVars[name] = true
+	}
+	return nil
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getFloat(key string, fallback float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %q is not a number", key, value)
+	}
+	return parsed, nil
+}
+
+func getInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %q is not an integer", key, value)
+	}
+	return parsed, nil
+}
+
+func getBool(key string, fallback bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %q is not a boolean", key, value)
+	}
+	return parsed, nil
+}
+
+func getDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %q is not a duration", key, value)
+	}
+	return parsed, nil
+}
+
+// parsePairValues parses "USD_INR=0.5,EUR_GBP=0.25" into a map keyed by pair
+func parsePairValues(value string) (map[string]float64, error) {
+	pairs := make(map[string]float64)
+	if value == "" {
+		return pairs, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		pair, number, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("expected PAIR=value, got %q", entry)
+		}
+
+		pair = strings.ToUpper(strings.TrimSpace(pair))
+		if len(strings.Split(pair, "_")) != 2 {
+			return nil, fmt.Errorf("expected pair in FROM_TO form, got %q", pair)
+		}
+
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %q", pair, number)
+		}
+		pairs[pair] = parsed
+	}
+
+	return pairs, nil
+}
+
+// parseHolidays parses "USD=2025-07-04|2025-12-25,INR=2025-01-26" into
+// each currency's holidays; dates are checked by Runtime.validate
+func parseHolidays(value string) map[string][]string {
+	holidays := make(map[string][]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		currency, dates, _ := strings.Cut(entry, "=")
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		for _, date := range strings.Split(dates, "|") {
+			holidays[currency] = append(holidays[currency], strings.TrimSpace(date))
+		}
+	}
+	return holidays
+}
+
+// parseAmountLimits parses "USD=0.01:1e12,JPY=1:" into the minimum and
+// maximum amount of each currency; either bound may be left empty
+func parseAmountLimits(value string) (map[string]models.AmountLimits, error) {
+	limits := make(map[string]models.AmountLimits)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		currency, bounds, found := strings.Cut(entry, "=")
+		minimum, maximum, hasMax := strings.Cut(bounds, ":")
+		if !found || !hasMax {
+			return nil, fmt.Errorf("expected CODE=min:max, got %q", entry)
+		}
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+
+		var l models.AmountLimits
+		for _, bound := range []struct {
+			value  string
+			target *float64
+		}{{minimum, &l.Min}, {maximum, &l.Max}} {
+			if bound.value = strings.TrimSpace(bound.value); bound.value == "" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(bound.value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid limit for %s: %q", currency, bound.value)
+			}
+			*bound.target = parsed
+		}
+		limits[currency] = l
+	}
+	return limits, nil
+}
+
+// parseAliases parses "rupee=INR,₹=INR" into lower-case aliases of
+// upper-case currency codes; codes are checked by Runtime.validate
+func parseAliases(value string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		alias, code, found := strings.Cut(entry, "=")
+		if alias = strings.TrimSpace(alias); !found || alias == "" {
+			return nil, fmt.Errorf("expected alias=CODE, got %q", entry)
+		}
+		aliases[strings.ToLower(alias)] = strings.ToUpper(strings.TrimSpace(code))
+	}
+	return aliases, nil
+}
+
+// parseCurrencies parses "USD,INR,EUR" into upper-case currency codes
+// containsCode reports whether codes contains code
+func containsCode(codes []string, code string) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCurrencies(value string) []string {
+	var codes []string
+	for _, code := range strings.Split(value, ",") {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// parseProviderLimits parses "exchangerate-api=60,fixer=30" into requests per
+// minute keyed by provider
+func parseProviderLimits(value string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		provider, number, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("expected provider=limit, got %q", entry)
+		}
+
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		known := false
+		for _, p := range external.KnownProviders {
+			known = known || p == provider
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown provider %q", provider)
+		}
+
+		limit, err := strconv.Atoi(strings.TrimSpace(number))
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid limit for %s: %q", provider, number)
+		}
+		limits[provider] = limit
+	}
+	return limits, nil
+}
+
+// parseFixedBases parses "fixer=EUR,frankfurter=EUR", the only base
+// currency each of providers quotes
+func parseFixedBases(value string, providers []string) (map[string]string, error) {
+	bases := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		provider, base, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("expected provider=currency, got %q", entry)
+		}
+		provider = external.CanonicalProvider(provider)
+		if !containsCode(providers, provider) {
+			return nil, fmt.Errorf("unknown provider %q", provider)
+		}
+		base = strings.ToUpper(strings.TrimSpace(base))
+		if len(base) != 3 {
+			return nil, fmt.Errorf("expected a three-letter currency code for %s, got %q", provider, base)
+		}
+		bases[provider] = base
+	}
+	return bases, nil
+}
+
+// parsePairSchedules parses "USD_INR=5m,EUR_GBP=15m:10", where the optional
+// number after the interval is the pair's priority
+func parsePairSchedules(value string) ([]services.PairSchedule, error) {
+	var schedules []services.PairSchedule
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		pair, spec, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("expected PAIR=interval, got %q", entry)
+		}
+
+		from, to, found := strings.Cut(strings.ToUpper(strings.TrimSpace(pair)), "_")
+		if !found {
+			return nil, fmt.Errorf("expected pair in FROM_TO form, got %q", pair)
+		}
+
+		interval, priority, hasPriority := strings.Cut(strings.TrimSpace(spec), ":")
+		schedule := services.PairSchedule{From: from, To: to}
+
+		var err error
+		schedule.Interval, err = time.ParseDuration(interval)
+		if err != nil || schedule.Interval <= 0 {
+			return nil, fmt.Errorf("invalid interval for %s_%s: %q", from, to, interval)
+		}
+		if hasPriority {
+			schedule.Priority, err = strconv.Atoi(priority)
+			if err != nil {
+				return nil, fmt.Errorf("invalid priority for %s_%s: %q", from, to, priority)
+			}
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, nil
+}
+
+// parseSLOTargets parses "/api/v1/rates/latest=p95:200ms|error_rate:1,*=p99:1s"
+// into the target of each route; * applies to routes without their own
+func parseSLOTargets(value string) (map[string]services.SLOTarget, error) {
+	var targets map[string]services.SLOTarget
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, objectives, found := strings.Cut(entry, "=")
+		route = strings.TrimSpace(route)
+		if !found || (route != services.SLOAnyEndpoint && !strings.HasPrefix(route, "/")) {
+			return nil, fmt.Errorf("expected route=objective:target, got %q", entry)
+		}
+
+		var target services.SLOTarget
+		for _, objective := range strings.Split(objectives, "|") {
+			name, limit, found := strings.Cut(strings.TrimSpace(objective), ":")
+			if !found {
+				return nil, fmt.Errorf("expected objective:target for %s, got %q", route, objective)
+			}
+			limit = strings.TrimSpace(limit)
+
+			var latency *time.Duration
+			switch strings.ToLower(name) {
+			case models.SLOObjectiveP50:
+				latency = &target.P50
+			case models.SLOObjectiveP95:
+				latency = &target.P95
+			case models.SLOObjectiveP99:
+				latency = &target.P99
+			case models.SLOObjectiveErrorRate:
+				percent, err := strconv.ParseFloat(limit, 64)
+				if err != nil || percent <= 0 || percent > 100 {
+					return nil, fmt.Errorf("invalid error rate for %s: %q", route, limit)
+				}
+				target.ErrorRatePercent = percent
+				continue
+			default:
+				return nil, fmt.Errorf("unknown objective %q for %s: expected p50, p95, p99 or error_rate", name, route)
+			}
+			parsed, err := time.ParseDuration(limit)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("invalid %s latency for %s: %q", name, route, limit)
+			}
+			*latency = parsed
+		}
+
+		if targets == nil {
+			targets = make(map[string]services.SLOTarget)
+		}
+		targets[route] = target
+	}
+	return targets, nil
+}
+
+// parseAPIKeys parses "id:key:role1|role2,id2:key2:reader" into API keys
+func parseAPIKeys(value string) ([]auth.APIKey, error) {
+	var keys []auth.APIKey
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("expected id:key:roles, got entry for %q", parts[0])
+		}
+
+		keys = append(keys, auth.APIKey{
+			ID:    parts[0],
+			Key:   parts[1],
+			Roles: strings.Split(parts[2], "|"),
+		})
+	}
+	return keys, nil
+}
+
+// parseTenants parses
+// "acme:currencies=USD|EUR;markup=0.5;markup_pairs=USD_INR:1|EUR_INR:0.8;keys=partner|ops"
+// into tenants and assigns the listed API keys to them. Tenant currencies must
+// be supported.
+func parseTenants(value string, currencies []string, keys []auth.APIKey) ([]services.Tenant, error) {
+	var tenants []services.Tenant
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, clauses, found := strings.Cut(entry, ":")
+		if id = strings.TrimSpace(id); !found || id == "" {
+			return nil, fmt.Errorf("expected id:settings, got %q", entry)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("tenant %s is defined twice", id)
+		}
+		seen[id] = true
+
+		tenant := services.Tenant{ID: id}
+		var markupPercent float64
+		var markupPairs map[string]float64
+		hasMarkup := false
+		for _, clause := range strings.Split(clauses, ";") {
+			name, list, found := strings.Cut(strings.TrimSpace(clause), "=")
+			if !found {
+				return nil, fmt.Errorf("expected name=values in tenant %s, got %q", id, clause)
+			}
+			values := strings.Split(list, "|")
+			switch name {
+			case "currencies":
+				for _, code := range values {
+					code = strings.ToUpper(strings.TrimSpace(code))
+					if !containsCode(currencies, code) {
+						return nil, fmt.Errorf("tenant %s uses unsupported currency %q", id, code)
+					}
+					tenant.Currencies = append(tenant.Currencies, code)
+				}
+			case "markup":
+				percent, err := strconv.ParseFloat(strings.TrimSpace(list), 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid markup of tenant %s: %q", id, list)
+				}
+				markupPercent, hasMarkup = percent, true
+			case "markup_pairs":
+				pairs, err := parsePairValues(strings.ReplaceAll(strings.ReplaceAll(list, ":", "="), "|", ","))
+				if err != nil {
+					return nil, fmt.Errorf("invalid markup_pairs of tenant %s: %w", id, err)
+				}
+				markupPairs, hasMarkup = pairs, true
+			case "keys":
+				for _, keyID := range values {
+					keyID = strings.TrimSpace(keyID)
+					matched := false
+					for i := range keys {
+						if keys[i].ID == keyID {
+							keys[i].Tenant = id
+							matched = true
+						}
+					}
+					if !matched {
+						return nil, fmt.Errorf("tenant %s lists unknown key %q", id, keyID)
+					}
+				}
+			default:
+				return nil, fmt.Errorf("unknown setting %q of tenant %s: expected currencies, markup, markup_pairs or keys", name, id)
+			}
+		}
+		if hasMarkup {
+			tenant.Markup = services.NewMarkup(markupPercent, markupPairs)
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, nil
+}
+
+// parseRateSources parses
+// "treasury:kind=command;command=/opt/bin/treasury-rates --feed main;timeout=5s,other:..."
+// into source declarations. Settings other than kind are left to the kind to
+// check once its plugin is loaded.
+func parseRateSources(value string) ([]RateSourceConfig, error) {
+	var sources []RateSourceConfig
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, clauses, found := strings.Cut(entry, ":")
+		name = external.CanonicalProvider(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("expected name:settings, got %q", entry)
+		}
+		if external.IsBuiltinProvider(name) {
+			return nil, fmt.Errorf("source %s has the name of a builtin provider", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("source %s is defined twice", name)
+		}
+		seen[name] = true
+
+		source := RateSourceConfig{Name: name, Settings: make(map[string]string)}
+		for _, clause := range strings.Split(clauses, ";") {
+			key, setting, found := strings.Cut(strings.TrimSpace(clause), "=")
+			if !found || key == "" {
+				return nil, fmt.Errorf("expected name=value in source %s, got %q", name, clause)
+			}
+			if key == "kind" {
+				source.Kind = strings.TrimSpace(setting)
+				continue
+			}
+			source.Settings[key] = strings.TrimSpace(setting)
+		}
+		if source.Kind == "" {
+			return nil, fmt.Errorf("source %s has no kind", name)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// applyKeyPolicies parses
+// "partner:pairs=USD_INR|EUR_*;deny=EUR_RUB;endpoints=/api/v1/convert|/api/v1/rates/*;entitlements=latest,other:..."
+// and sets the policy of each named key
+func applyKeyPolicies(keys []auth.APIKey, value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, clauses, found := strings.Cut(entry, ":")
+		if !found || id == "" {
+			return fmt.Errorf("expected id:rules, got %q", entry)
+		}
+		policy, err := parsePolicy("the policy of "+id, clauses)
+		if err != nil {
+			return err
+		}
+
+		matched := false
+		for i := range keys {
+			if keys[i].ID == id {
+				keys[i].Policy = policy
+				matched = true
+			}
+		}
+		if !matched {
+			return fmt.Errorf("policy for unknown key %q", id)
+		}
+	}
+	return nil
+}
+
+// parsePolicy parses "pairs=USD_INR|EUR_*;deny=EUR_RUB;endpoints=/api/v1/convert;entitlements=latest",
+// naming what the rules are for in errors
+func parsePolicy(of, clauses string) (auth.Policy, error) {
+	var policy auth.Policy
+	for _, clause := range strings.Split(clauses, ";") {
+		name, list, found := strings.Cut(strings.TrimSpace(clause), "=")
+		if !found {
+			return policy, fmt.Errorf("expected name=values in %s, got %q", of, clause)
+		}
+		values := strings.Split(list, "|")
+		switch name {
+		case "pairs", "deny":
+			for i, pair := range values {
+				pair = strings.ToUpper(strings.TrimSpace(pair))
+				if from, to, found := strings.Cut(pair, "_"); !found || from == "" || to == "" || strings.Contains(to, "_") {
+					return policy, fmt.Errorf("expected pair in FROM_TO form in %s, got %q", of, pair)
+				}
+				values[i] = pair
+			}
+			if name == "pairs" {
+				policy.Pairs = append(policy.Pairs, values...)
+			} else {
+				policy.DeniedPairs = append(policy.DeniedPairs, values...)
+			}
+		case "endpoints":
+			for _, endpoint := range values {
+				if !strings.HasPrefix(endpoint, "/") {
+					return policy, fmt.Errorf("expected endpoint path starting with / in %s, got %q", of, endpoint)
+				}
+			}
+			policy.Endpoints = append(policy.Endpoints, values...)
+		case "entitlements":
+			for _, entitlement := range values {
+				entitlement = strings.ToLower(strings.TrimSpace(entitlement))
+				if !containsCode(auth.Entitlements, entitlement) {
+					return policy, fmt.Errorf("unknown entitlement %q in %s: expected one of %s", entitlement, of, strings.Join(auth.Entitlements, ", "))
+				}
+				policy.Entitlements = append(policy.Entitlements, entitlement)
+			}
+		default:
+			return policy, fmt.Errorf("unknown rule %q in %s: expected pairs, deny, endpoints or entitlements", name, of)
+		}
+	}
+	return policy, nil
+}
+
+// loadAnonymousPolicy reads ANONYMOUS_POLICY, the rules of API_KEY_POLICIES
+// for callers without an API key or token. Unset, anonymous callers may do
+// everything when no keys or JWKS URL are configured, and only get latest
+// rates otherwise, so a key on a plan gains nothing by being left out.
+func loadAnonymousPolicy(keys []auth.APIKey, jwt auth.JWTConfig) (auth.Policy, error) {
+	value := strings.TrimSpace(os.Getenv("ANONYMOUS_POLICY"))
+	if value == "" {
+		if len(keys) == 0 && jwt.JWKSURL == "" {
+			return auth.Policy{}, nil
+		}
+		return auth.Policy{Entitlements: []string{auth.EntitlementLatest}}, nil
+	}
+	policy, err := parsePolicy("ANONYMOUS_POLICY", value)
+	if err != nil {
+		return policy, fmt.Errorf("invalid ANONYMOUS_POLICY: %w", err)
+	}
+	return policy, nil
+}
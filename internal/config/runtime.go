@@ -0,0 +1,265 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+	"exchange-rate-service/internal/utils"
+)
+
+// Runtime holds the settings that can change without a restart
+type Runtime struct {
+	Currencies []string
+	Fetch      FetchConfig
+	Markup     MarkupConfig
+	Holidays   map[string][]string
+	Amounts    map[string]models.AmountLimits
+	Aliases    map[string]string
+	Providers  ProviderPriorities
+}
+
+// ProviderPriorities picks which provider serves which rates
+type ProviderPriorities struct {
+	Default string // Serves the rates of requests that don't pin a provider
+	Metals  string // Serves pairs involving a precious metal
+	Shadow  string // Is compared with the served rates, off when empty
+}
+
+// Runtime returns the reloadable part of the configuration
+func (c *Config) Runtime() Runtime {
+	return Runtime{
+		Currencies: c.Currencies,
+		Fetch:      c.Fetch,
+		Markup:     c.Markup,
+		Holidays:   c.Holidays,
+		Amounts:    c.Amounts,
+		Aliases:    c.Aliases,
+		Providers: ProviderPriorities{
+			Default: c.Provider.DefaultProvider,
+			Metals:  c.Fetch.Metals,
+			Shadow:  c.Shadow.Provider,
+		},
+	}
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// validate checks that the currencies are well formed, every configured pair
+// uses supported currencies, holidays are dates, amount limits are ordered
+// non-negative numbers, aliases stand for supported currencies and the shadow
+// provider is not the one serving the rates
+func (r Runtime) validate() error {
+	if len(r.Currencies) == 0 {
+		return fmt.Errorf("at least one supported currency is required")
+	}
+
+	supported := make(map[string]bool, len(r.Currencies))
+	for _, code := range r.Currencies {
+		if !currencyCode.MatchString(code) {
+			return fmt.Errorf("invalid currency code %q", code)
+		}
+		supported[code] = true
+	}
+
+	for _, pair := range r.Fetch.Pairs {
+		if !supported[pair.From] || !supported[pair.To] {
+			return fmt.Errorf("fetch schedule for unsupported pair %s_%s", pair.From, pair.To)
+		}
+	}
+	for pair := range r.Markup.Pairs {
+		from, to, _ := strings.Cut(pair, "_")
+		if !supported[from] || !supported[to] {
+			return fmt.Errorf("markup for unsupported pair %s", pair)
+		}
+	}
+	for currency, dates := range r.Holidays {
+		if !currencyCode.MatchString(currency) {
+			return fmt.Errorf("holidays for invalid currency code %q", currency)
+		}
+		for _, date := range dates {
+			if _, err := time.Parse(utils.DateFormat, date); err != nil {
+				return fmt.Errorf("invalid holiday of %s: expected YYYY-MM-DD, got %q", currency, date)
+			}
+		}
+	}
+	for currency, limits := range r.Amounts {
+		if !currencyCode.MatchString(currency) {
+			return fmt.Errorf("amount limits for invalid currency code %q", currency)
+		}
+		if limits.Min < 0 || limits.Max < 0 {
+			return fmt.Errorf("amount limits of %s must be >= 0", currency)
+		}
+		if limits.Max > 0 && limits.Min > limits.Max {
+			return fmt.Errorf("minimum amount of %s exceeds its maximum", currency)
+		}
+	}
+	for alias, code := range r.Aliases {
+		if supported[strings.ToUpper(alias)] {
+			return fmt.Errorf("alias %q is itself a supported currency", alias)
+		}
+		if !supported[code] {
+			return fmt.Errorf("alias %q for unsupported currency %q", alias, code)
+		}
+	}
+	if r.Providers.Shadow != "" && r.Providers.Shadow == r.Providers.Default {
+		return fmt.Errorf("shadow provider %s already serves the rates", r.Providers.Shadow)
+	}
+	return nil
+}
+
+// fileConfig is the JSON layout of CONFIG_FILE. Settings present in the file
+// override the environment.
+type fileConfig struct {
+	SupportedCurrencies []string                       `json:"supported_currencies"`
+	FetchInterval       string                         `json:"fetch_interval"`
+	FetchPairs          map[string]filePairSchedule    `json:"fetch_pairs"`
+	MarkupPercent       *float64                       `json:"markup_percent"`
+	MarkupPairs         map[string]float64             `json:"markup_pairs"`
+	Holidays            map[string][]string            `json:"holidays"`
+	AmountLimits        map[string]models.AmountLimits `json:"amount_limits"`
+	CurrencyAliases     map[string]string              `json:"currency_aliases"`
+	DefaultProvider     string                         `json:"default_provider"`
+	MetalsProvider      string                         `json:"metals_provider"`
+	ShadowProvider      *string                        `json:"shadow_provider"` // "" turns the shadow traffic off
+}
+
+type filePairSchedule struct {
+	Interval string `json:"interval"`
+	Priority int    `json:"priority"`
+}
+
+// applyFile overlays the settings of a JSON config file
+func (c *Config) applyFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read CONFIG_FILE: %w", err)
+	}
+
+	var file fileConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return fmt.Errorf("invalid CONFIG_FILE %s: %w", path, err)
+	}
+
+	if file.SupportedCurrencies != nil {
+		c.Currencies = parseCurrencies(strings.Join(file.SupportedCurrencies, ","))
+	}
+	if file.FetchInterval != "" {
+		if c.Fetch.Interval, err = time.ParseDuration(file.FetchInterval); err != nil {
+			return fmt.Errorf("invalid fetch_interval in %s: %q", path, file.FetchInterval)
+		}
+	}
+	if file.FetchPairs != nil {
+		c.Fetch.Pairs = make([]services.PairSchedule, 0, len(file.FetchPairs))
+		for pair, schedule := range file.FetchPairs {
+			from, to, found := strings.Cut(strings.ToUpper(pair), "_")
+			if !found {
+				return fmt.Errorf("invalid fetch_pairs in %s: expected pair in FROM_TO form, got %q", path, pair)
+			}
+			interval, err := time.ParseDuration(schedule.Interval)
+			if err != nil || interval <= 0 {
+				return fmt.Errorf("invalid fetch_pairs in %s: bad interval for %s: %q", path, pair, schedule.Interval)
+			}
+			c.Fetch.Pairs = append(c.Fetch.Pairs, services.PairSchedule{
+				From: from, To: to, Interval: interval, Priority: schedule.Priority,
+			})
+		}
+	}
+	if file.MarkupPercent != nil {
+		c.Markup.GlobalPercent = *file.MarkupPercent
+	}
+	if file.MarkupPairs != nil {
+		c.Markup.Pairs = make(map[string]float64, len(file.MarkupPairs))
+		for pair, percent := range file.MarkupPairs {
+			c.Markup.Pairs[strings.ToUpper(pair)] = percent
+		}
+	}
+	if file.Holidays != nil {
+		c.Holidays = make(map[string][]string, len(file.Holidays))
+		for currency, dates := range file.Holidays {
+			c.Holidays[strings.ToUpper(currency)] = dates
+		}
+	}
+	if file.AmountLimits != nil {
+		c.Amounts = make(map[string]models.AmountLimits, len(file.AmountLimits))
+		for currency, limits := range file.AmountLimits {
+			c.Amounts[strings.ToUpper(currency)] = limits
+		}
+	}
+	if file.CurrencyAliases != nil {
+		c.Aliases = make(map[string]string, len(file.CurrencyAliases))
+		for alias, code := range file.CurrencyAliases {
+			c.Aliases[strings.ToLower(strings.TrimSpace(alias))] = strings.ToUpper(code)
+		}
+	}
+	return c.applyFileProviders(path, file)
+}
+
+// applyFileProviders overlays the provider priorities of a config file. The
+// default provider may name a rate source as well as a builtin provider.
+func (c *Config) applyFileProviders(path string, file fileConfig) error {
+	if file.DefaultProvider != "" {
+		provider := external.CanonicalProvider(file.DefaultProvider)
+		known := external.IsBuiltinProvider(provider)
+		for _, source := range c.Sources {
+			known = known || source.Name == provider
+		}
+		if !known {
+			return fmt.Errorf("invalid default_provider in %s: unknown provider %q", path, file.DefaultProvider)
+		}
+		c.Provider.DefaultProvider = provider
+	}
+	if file.MetalsProvider != "" {
+		if !external.IsBuiltinProvider(file.MetalsProvider) {
+			return fmt.Errorf("invalid metals_provider in %s: expected one of %s", path, strings.Join(external.BuiltinProviders, ", "))
+		}
+		c.Fetch.Metals = external.CanonicalProvider(file.MetalsProvider)
+	}
+	if file.ShadowProvider != nil {
+		if *file.ShadowProvider != "" && !external.IsBuiltinProvider(*file.ShadowProvider) {
+			return fmt.Errorf("invalid shadow_provider in %s: expected one of %s", path, strings.Join(external.BuiltinProviders, ", "))
+		}
+		c.Shadow.Provider = external.CanonicalProvider(*file.ShadowProvider)
+	}
+	return nil
+}
+
+// WatchFile calls onChange whenever the modification time of path changes,
+// checking every interval until ctx is done
+func WatchFile(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	var lastModified time.Time
+	if info, err := os.Stat(path); err == nil {
+		lastModified = info.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil {
+				log.Printf("Failed to check config file %s: %v", path, err)
+				continue
+			}
+			if !info.ModTime().Equal(lastModified) {
+				lastModified = info.ModTime()
+				onChange()
+			}
+		}
+	}
+}
@@ -0,0 +1,794 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_ConfigFileOverridesEnvironment(t *testing.T) {
+	t.Setenv("MARKUP_PERCENT", "1")
+	t.Setenv("FETCH_INTERVAL", "2h")
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, `{
+		"supported_currencies": ["usd", "EUR", "CHF"],
+		"fetch_pairs": {"USD_CHF": {"interval": "5m", "priority": 3}},
+		"markup_pairs": {"usd_eur": 0.25}
+	}`))
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"USD", "EUR", "CHF"}, cfg.Currencies)
+	assert.Equal(t, 2*time.Hour, cfg.Fetch.Interval, "unset file settings keep the environment value")
+	require.Len(t, cfg.Fetch.Pairs, 1)
+	assert.Equal(t, 5*time.Minute, cfg.Fetch.Pairs[0].Interval)
+	assert.Equal(t, 3, cfg.Fetch.Pairs[0].Priority)
+	assert.Equal(t, 1.0, cfg.Markup.GlobalPercent)
+	assert.Equal(t, map[string]float64{"USD_EUR": 0.25}, cfg.Markup.Pairs)
+}
+
+func TestLoad_InvalidConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"Unknown field", `{"currencies": ["USD"]}`},
+		{"Bad interval", `{"fetch_interval": "soon"}`},
+		{"Pair outside currencies", `{"supported_currencies": ["USD", "EUR"], "markup_pairs": {"USD_INR": 1}}`},
+		{"Bad currency code", `{"supported_currencies": ["DOLLAR"]}`},
+		{"No currencies", `{"supported_currencies": []}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", writeConfigFile(t, tt.content))
+			_, err := Load()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoad_ConfigFileProviders(t *testing.T) {
+	t.Setenv("SHADOW_PROVIDER", "frankfurter")
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, `{
+		"default_provider": "Frankfurter",
+		"metals_provider": "exchangerate-api",
+		"shadow_provider": "erapi"
+	}`))
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, ProviderPriorities{
+		Default: external.ProviderFrankfurter,
+		Metals:  external.ProviderExchangeRateAPI,
+		Shadow:  external.ProviderExchangeRateAPI,
+	}, cfg.Runtime().Providers, "the file overrides the environment")
+
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, `{"default_provider": "frankfurter", "shadow_provider": ""}`))
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Runtime().Providers.Shadow, "an empty shadow provider turns the shadow traffic off")
+
+	for _, content := range []string{
+		`{"default_provider": "frankfurter"}`, // The environment's shadow provider would serve the rates
+		`{"default_provider": "unknown"}`,
+		`{"metals_provider": "unknown"}`,
+		`{"shadow_provider": "unknown"}`,
+	} {
+		t.Setenv("CONFIG_FILE", writeConfigFile(t, content))
+		_, err := Load()
+		assert.Error(t, err, content)
+	}
+}
+
+func TestLoad_ConfigDir(t *testing.T) {
+	configMap, secret := t.TempDir(), t.TempDir()
+	write := func(dir, name, value string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(value), 0o600))
+	}
+	write(configMap, "FETCH_INTERVAL", "15m\n")
+	write(configMap, "MARKUP_PERCENT", "0.5")
+	write(configMap, "CACHE_TTL", "10m")
+	write(configMap, "README.md", "not a variable")
+	write(secret, "CACHE_TTL", "20m")
+	write(secret, "ADMIN_API_KEY", "s3cret\n")
+	t.Cleanup(func() { applyConfigDirs(nil) })
+
+	t.Setenv("MARKUP_PERCENT", "1")
+	t.Setenv("CONFIG_DIR", configMap+","+secret)
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, cfg.Fetch.Interval)
+	assert.Equal(t, 1.0, cfg.Markup.GlobalPercent, "the environment wins over files")
+	assert.Equal(t, 10*time.Minute, cfg.Cache.TTL, "earlier directories win")
+	require.NotEmpty(t, cfg.APIKeys)
+	assert.Equal(t, "s3cret", cfg.APIKeys[len(cfg.APIKeys)-1].Key)
+
+	// A reload picks up changed and removed files
+	write(configMap, "FETCH_INTERVAL", "30m")
+	require.NoError(t, os.Remove(filepath.Join(configMap, "CACHE_TTL")))
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, cfg.Fetch.Interval)
+	assert.Equal(t, 20*time.Minute, cfg.Cache.TTL)
+
+	t.Setenv("CONFIG_DIR", filepath.Join(configMap, "missing"))
+	_, err = Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_BindAndShutdown(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Bind)
+	assert.Equal(t, ShutdownConfig{DrainDelay: 5 * time.Second, Timeout: 20 * time.Second}, cfg.Shutdown)
+
+	t.Setenv("BIND_ADDRESS", "127.0.0.1")
+	t.Setenv("SHUTDOWN_DRAIN_DELAY", "0s")
+	t.Setenv("SHUTDOWN_TIMEOUT", "45s")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", cfg.Bind)
+	assert.Equal(t, ShutdownConfig{Timeout: 45 * time.Second}, cfg.Shutdown)
+
+	t.Setenv("SHUTDOWN_TIMEOUT", "0s")
+	_, err = Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_TrustedProxies(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Proxies, "no proxy is trusted by default")
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.10")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.0.2.10"}, cfg.Proxies)
+
+	t.Setenv("TRUSTED_PROXIES", "balancer")
+	_, err = Load()
+	assert.ErrorContains(t, err, "invalid TRUSTED_PROXIES")
+}
+
+func TestLoad_DefaultCurrencies(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultCurrencies, cfg.Currencies)
+}
+
+func TestLoad_APIKeyPolicies(t *testing.T) {
+	t.Setenv("API_KEYS", "partner:secret:reader,ops:other:admin")
+	t.Setenv("API_KEY_POLICIES", "partner:pairs=usd_inr|EUR_*;deny=EUR_RUB;endpoints=/api/v1/convert|/api/v1/rates/*")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Len(t, cfg.APIKeys, 2)
+	assert.Equal(t, []string{"USD_INR", "EUR_*"}, cfg.APIKeys[0].Policy.Pairs)
+	assert.Equal(t, []string{"EUR_RUB"}, cfg.APIKeys[0].Policy.DeniedPairs)
+	assert.Equal(t, []string{"/api/v1/convert", "/api/v1/rates/*"}, cfg.APIKeys[0].Policy.Endpoints)
+	assert.False(t, cfg.APIKeys[1].Policy.RestrictsPairs())
+
+	for _, value := range []string{
+		"nobody:pairs=USD_INR",
+		"partner:pairs=USDINR",
+		"partner:currencies=USD",
+		"partner:endpoints=convert",
+	} {
+		t.Setenv("API_KEY_POLICIES", value)
+		_, err := Load()
+		assert.Error(t, err, value)
+	}
+}
+
+func TestLoad_Holidays(t *testing.T) {
+	t.Setenv("HOLIDAYS", "usd=2025-07-04|2025-12-25")
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"USD": {"2025-07-04", "2025-12-25"}}, cfg.Holidays)
+
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, `{"holidays": {"inr": ["2025-01-26"]}}`))
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"INR": {"2025-01-26"}}, cfg.Holidays)
+
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("HOLIDAYS", "USD=07/04/2025")
+	_, err = Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_AmountLimits(t *testing.T) {
+	t.Setenv("AMOUNT_LIMITS", "usd=0.01:1e12, JPY=1:")
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]models.AmountLimits{"USD": {Min: 0.01, Max: 1e12}, "JPY": {Min: 1}}, cfg.Amounts)
+	assert.Equal(t, cfg.Amounts, cfg.Runtime().Amounts)
+
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, `{"amount_limits": {"gbp": {"max_amount": 1e9}}}`))
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]models.AmountLimits{"GBP": {Max: 1e9}}, cfg.Amounts)
+
+	t.Setenv("CONFIG_FILE", "")
+	for _, value := range []string{"USD=1", "USD=x:", "USD=-1:", "USD=10:5", "DOLLAR=1:"} {
+		t.Setenv("AMOUNT_LIMITS", value)
+		_, err = Load()
+		assert.Error(t, err, value)
+	}
+}
+
+func TestLoad_RequestLimits(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultRequestLimits(), cfg.Limits)
+
+	t.Setenv("MAX_BODY_BYTES", "65536")
+	t.Setenv("MAX_BULK_PAIRS", "50")
+	t.Setenv("MAX_PRECISION", "4")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, models.RequestLimits{MaxBodyBytes: 65536, MaxPairs: 50, MaxPrecision: 4}, cfg.Limits)
+
+	for _, env := range [][2]string{{"MAX_BODY_BYTES", "0"}, {"MAX_BULK_PAIRS", "0"}, {"MAX_PRECISION", "-1"}, {"MAX_PRECISION", "x"}} {
+		t.Run(env[0]+"="+env[1], func(t *testing.T) {
+			t.Setenv(env[0], env[1])
+			_, err := Load()
+			assert.ErrorContains(t, err, "invalid "+env[0])
+		})
+	}
+}
+
+func TestLoad_CurrencyAliases(t *testing.T) {
+	t.Setenv("CURRENCY_ALIASES", "Rupiya=inr, ₹ = INR,quid=GBP")
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"rupiya": "INR", "₹": "INR", "quid": "GBP"}, cfg.Aliases)
+	assert.Equal(t, cfg.Aliases, cfg.Runtime().Aliases)
+
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, `{"currency_aliases": {"Greenback": "usd"}}`))
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"greenback": "USD"}, cfg.Aliases)
+
+	t.Setenv("CONFIG_FILE", "")
+	for _, value := range []string{"rupee", "=INR", "rupee=XYZ", "usd=EUR"} {
+		t.Setenv("CURRENCY_ALIASES", value)
+		_, err = Load()
+		assert.Error(t, err, value)
+	}
+}
+
+func TestLoad_ProviderTransport(t *testing.T) {
+	t.Setenv("PROVIDER_PROXY_URL", "http://proxy.corp:3128")
+	t.Setenv("PROVIDER_USER_AGENT", "treasury-rates/2.1")
+	t.Setenv("PROVIDER_HEADERS", "X-Route=treasury, X-Team = fx")
+	t.Setenv("PROVIDER_MAX_IDLE_CONNS_PER_HOST", "4")
+	t.Setenv("PROVIDER_IDLE_CONN_TIMEOUT", "30s")
+	t.Setenv("PROVIDER_HTTP2", "false")
+	t.Setenv("PROVIDER_DNS_CACHE_TTL", "0")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	transport := cfg.Provider.Transport
+	require.NotNil(t, transport.Proxy)
+	assert.Equal(t, "proxy.corp:3128", transport.Proxy.Host)
+	assert.Nil(t, transport.TLS)
+	assert.Equal(t, "treasury-rates/2.1", transport.UserAgent)
+	assert.Equal(t, "treasury", transport.Headers.Get("X-Route"))
+	assert.Equal(t, "fx", transport.Headers.Get("X-Team"))
+	assert.Equal(t, 100, transport.MaxIdleConns, "unset settings keep their default")
+	assert.Equal(t, 4, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 30*time.Second, transport.IdleConnTimeout)
+	assert.True(t, transport.DisableHTTP2)
+	assert.Equal(t, 30*time.Second, transport.KeepAlive)
+	assert.Zero(t, transport.DNSCacheTTL, "0 turns the DNS cache off")
+}
+
+func TestLoad_InvalidProviderTransport(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value string
+	}{
+		{"Relative proxy", "PROVIDER_PROXY_URL", "proxy.corp:3128"},
+		{"Unsupported proxy scheme", "PROVIDER_PROXY_URL", "ftp://proxy.corp"},
+		{"Missing CA bundle", "PROVIDER_CA_FILE", filepath.Join(t.TempDir(), "missing.pem")},
+		{"Certificate without key", "PROVIDER_CLIENT_CERT_FILE", "client.pem"},
+		{"Header without value", "PROVIDER_HEADERS", "X-Route"},
+		{"Negative pool size", "PROVIDER_MAX_IDLE_CONNS", "-1"},
+		{"HTTP/2 not a boolean", "PROVIDER_HTTP2", "h2"},
+		{"Negative DNS cache TTL", "PROVIDER_DNS_CACHE_TTL", "-1m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.key, tt.value)
+			_, err := Load()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoad_FetchPool(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, 4, cfg.Fetch.Pool.Concurrency)
+	assert.Zero(t, cfg.Fetch.Pool.CycleBudget)
+
+	t.Setenv("FETCH_CONCURRENCY", "16")
+	t.Setenv("FETCH_CYCLE_BUDGET", "45s")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, 16, cfg.Fetch.Pool.Concurrency)
+	assert.Equal(t, 45*time.Second, cfg.Fetch.Pool.CycleBudget)
+
+	t.Setenv("FETCH_CONCURRENCY", "0")
+	_, err = Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_HotPairs(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.False(t, cfg.Fetch.Hot.Enabled())
+	assert.Equal(t, 10*time.Minute, cfg.Fetch.Hot.Window)
+
+	t.Setenv("HOT_PAIR_INTERVAL", "5m")
+	t.Setenv("COLD_PAIR_INTERVAL", "6h")
+	t.Setenv("HOT_PAIR_MIN_REQUESTS", "50")
+	t.Setenv("HOT_PAIR_MAX_CALLS_PER_HOUR", "60")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.True(t, cfg.Fetch.Hot.Enabled())
+	assert.Equal(t, 5*time.Minute, cfg.Fetch.Hot.HotInterval)
+	assert.Equal(t, 6*time.Hour, cfg.Fetch.Hot.ColdInterval)
+	assert.Equal(t, 50, cfg.Fetch.Hot.MinRequests)
+	assert.Equal(t, 60, cfg.Fetch.Hot.MaxCallsPerHour)
+
+	t.Setenv("HOT_PAIR_WINDOW", "0s")
+	_, err = Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_AlertChannels(t *testing.T) {
+	t.Setenv("DISCREPANCY_ALERT_CHANNELS", "slack:https://hooks.slack.com/services/T0/B0/x, Email:ops@example.com")
+	t.Setenv("DISCREPANCY_ALERT_TEMPLATE", "{{.Pair}} at {{.Rates}}")
+	t.Setenv("SLO_ALERT_CHANNELS", "sms:+15550100")
+	t.Setenv("ALERT_SMTP_ADDR", "smtp.example.com:587")
+	t.Setenv("ALERT_SMTP_FROM", "alerts@example.com")
+	t.Setenv("ALERT_TWILIO_ACCOUNT_SID", "AC123")
+	t.Setenv("ALERT_TWILIO_AUTH_TOKEN", "token")
+	t.Setenv("ALERT_TWILIO_FROM", "+15550199")
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, []services.AlertChannel{
+		{Kind: services.AlertChannelSlack, Target: "https://hooks.slack.com/services/T0/B0/x"},
+		{Kind: services.AlertChannelEmail, Target: "ops@example.com"},
+	}, cfg.Discrepancy.Alerts.Channels)
+	assert.Equal(t, "{{.Pair}} at {{.Rates}}", cfg.Discrepancy.Alerts.Template)
+	assert.Equal(t, "smtp.example.com:587", cfg.Discrepancy.Alerts.SMTP.Addr)
+	assert.Equal(t, []services.AlertChannel{{Kind: services.AlertChannelSMS, Target: "+15550100"}}, cfg.SLO.Alerts.Channels)
+	assert.Equal(t, "AC123", cfg.SLO.Alerts.Twilio.AccountSID)
+
+	for name, env := range map[string][2]string{
+		"no target":       {"SLO_ALERT_CHANNELS", "slack"},
+		"unknown kind":    {"SLO_ALERT_CHANNELS", "pager:ops"},
+		"relative URL":    {"SLO_ALERT_CHANNELS", "webhook:/alerts"},
+		"bad template":    {"SLO_ALERT_TEMPLATE", "{{.Pair"},
+		"no SMTP server":  {"ALERT_SMTP_ADDR", ""},
+		"no Twilio token": {"ALERT_TWILIO_AUTH_TOKEN", ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(env[0], env[1])
+			_, err := Load()
+			assert.ErrorContains(t, err, "invalid")
+		})
+	}
+}
+
+func TestLoad_Shadow(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Shadow.Provider)
+
+	t.Setenv("SHADOW_PROVIDER", "Frankfurter")
+	t.Setenv("SHADOW_PERCENT", "25")
+	t.Setenv("SHADOW_CONCURRENCY", "2")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "frankfurter", cfg.Shadow.Provider)
+	assert.Equal(t, 25.0, cfg.Shadow.Percent)
+	assert.Equal(t, 0.5, cfg.Shadow.ThresholdPercent)
+	assert.Equal(t, 2, cfg.Shadow.Concurrency)
+
+	for name, env := range map[string][2]string{
+		"serving provider": {"SHADOW_PROVIDER", "exchangerate-api"},
+		"unknown provider": {"SHADOW_PROVIDER", "ledger"},
+		"over 100 percent": {"SHADOW_PERCENT", "120"},
+		"no concurrency":   {"SHADOW_CONCURRENCY", "0"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(env[0], env[1])
+			_, err := Load()
+			assert.ErrorContains(t, err, "invalid "+env[0])
+		})
+	}
+}
+
+func TestLoad_CORS(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*"}, cfg.CORS.AllowedOrigins)
+	assert.False(t, cfg.CORS.AllowCredentials)
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "http://localhost:3000")
+	t.Setenv("CORS_ALLOWED_ORIGINS_PRODUCTION", "https://app.example.com/, https://*.example.com")
+	t.Setenv("CORS_ALLOWED_METHODS", "get,post")
+	t.Setenv("CORS_EXPOSED_HEADERS", "")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	t.Setenv("CORS_MAX_AGE", "1h")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://localhost:3000"}, cfg.CORS.AllowedOrigins, "without ENVIRONMENT the default list applies")
+	assert.Equal(t, []string{"GET", "POST"}, cfg.CORS.AllowedMethods)
+	assert.Empty(t, cfg.CORS.ExposedHeaders)
+	assert.True(t, cfg.CORS.AllowCredentials)
+	assert.Equal(t, time.Hour, cfg.CORS.MaxAge)
+
+	t.Setenv("ENVIRONMENT", "Production")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "production", cfg.Environment)
+	assert.Equal(t, []string{"https://app.example.com", "https://*.example.com"}, cfg.CORS.AllowedOrigins)
+
+	t.Setenv("ENVIRONMENT", "staging")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://localhost:3000"}, cfg.CORS.AllowedOrigins, "an environment without its own list uses the default one")
+
+	for name, origins := range map[string]string{
+		"wildcard with credentials": "*",
+		"path":                      "https://app.example.com/login",
+		"no scheme":                 "app.example.com",
+		"wildcard inside the host":  "https://app.*.example.com",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("CORS_ALLOWED_ORIGINS", origins)
+			_, err := Load()
+			assert.ErrorContains(t, err, "invalid CORS_ALLOWED_ORIGINS")
+		})
+	}
+}
+
+func TestLoad_Tenants(t *testing.T) {
+	t.Setenv("API_KEYS", "acme-app:secret:reader,ops:other:admin")
+	t.Setenv("TENANTS", "acme:currencies=usd|INR;markup=0.5;markup_pairs=USD_INR:1.25;keys=acme-app,globex:markup=0")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Len(t, cfg.Tenants, 2)
+
+	acme := cfg.Tenants[0]
+	assert.Equal(t, "acme", acme.ID)
+	assert.Equal(t, []string{"USD", "INR"}, acme.Currencies)
+	assert.Equal(t, 1.25, acme.Markup.PercentFor("USD", "INR"))
+	assert.Equal(t, 0.5, acme.Markup.PercentFor("INR", "USD"))
+	assert.Equal(t, "acme", cfg.APIKeys[0].Tenant)
+	assert.Empty(t, cfg.APIKeys[1].Tenant)
+
+	assert.Empty(t, cfg.Tenants[1].Currencies)
+}
+
+func TestLoad_InvalidTenants(t *testing.T) {
+	t.Setenv("API_KEYS", "acme-app:secret:reader")
+
+	for _, value := range []string{
+		"acme",
+		"acme:currencies=USD|XYZ",
+		"acme:markup=lots",
+		"acme:markup_pairs=USDINR:1",
+		"acme:keys=missing",
+		"acme:region=eu",
+		"acme:markup=1,acme:markup=2",
+	} {
+		t.Run(value, func(t *testing.T) {
+			t.Setenv("TENANTS", value)
+			_, err := Load()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoad_AccessLog(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.True(t, cfg.AccessLog.Enabled)
+	assert.Equal(t, 100.0, cfg.AccessLog.SamplePercent)
+	assert.Equal(t, "stdout", cfg.AccessLog.Sink)
+	assert.Contains(t, cfg.AccessLog.SkipPaths, "/healthz")
+
+	t.Setenv("ACCESS_LOG_SAMPLE_PERCENT", "5")
+	t.Setenv("ACCESS_LOG_ERRORS", "false")
+	t.Setenv("ACCESS_LOG_SKIP_PATHS", "")
+	t.Setenv("ACCESS_LOG_SINK", "/var/log/exchange/access.log")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, cfg.AccessLog.SamplePercent)
+	assert.False(t, cfg.AccessLog.LogErrors)
+	assert.Empty(t, cfg.AccessLog.SkipPaths)
+	assert.Equal(t, "/var/log/exchange/access.log", cfg.AccessLog.Sink)
+
+	t.Setenv("ACCESS_LOG_SAMPLE_PERCENT", "150")
+	_, err = Load()
+	assert.ErrorContains(t, err, "invalid ACCESS_LOG_SAMPLE_PERCENT")
+}
+
+func TestLoad_Startup(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, services.DefaultStartupConfig(), cfg.Startup)
+
+	t.Setenv("STARTUP_CHECK_POLICY", "Fail-Fast")
+	t.Setenv("STARTUP_CHECK_TIMEOUT", "2s")
+	t.Setenv("STARTUP_RETRY_INTERVAL", "1s")
+	t.Setenv("STARTUP_RETRY_MAX_INTERVAL", "30s")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, services.StartupConfig{Policy: services.StartupFailFast, Timeout: 2 * time.Second, RetryInterval: time.Second, MaxRetryInterval: 30 * time.Second}, cfg.Startup)
+
+	for env, value := range map[string]string{
+		"STARTUP_CHECK_POLICY":   "ignore",
+		"STARTUP_CHECK_TIMEOUT":  "0s",
+		"STARTUP_RETRY_INTERVAL": "1m",
+	} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, value)
+			_, err := Load()
+			assert.ErrorContains(t, err, "invalid "+env)
+		})
+	}
+}
+
+func TestLoad_Compression(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, middleware.DefaultCompressionConfig(), cfg.Compression)
+
+	t.Setenv("COMPRESSION_MIN_SIZE", "4096")
+	t.Setenv("COMPRESSION_LEVEL", "9")
+	t.Setenv("COMPRESSION_ENCODINGS", "Deflate, gzip, BR")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, 4096, cfg.Compression.MinSize)
+	assert.Equal(t, 9, cfg.Compression.Level)
+	assert.Equal(t, []string{"deflate", "gzip", "br"}, cfg.Compression.Encodings)
+
+	for env, value := range map[string]string{
+		"COMPRESSION_ENCODINGS": "zstd",
+		"COMPRESSION_LEVEL":     "12",
+		"COMPRESSION_MIN_SIZE":  "-1",
+	} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, value)
+			_, err := Load()
+			assert.ErrorContains(t, err, "invalid "+env)
+		})
+	}
+}
+
+func TestLoad_CacheWriteStrategy(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, cache.WriteSnapshot, cfg.Cache.WriteStrategy)
+	assert.Equal(t, time.Second, cfg.Cache.FlushInterval)
+
+	t.Setenv("CACHE_SNAPSHOT_FILE", filepath.Join(t.TempDir(), "cache.json"))
+	t.Setenv("CACHE_WRITE_STRATEGY", "Write-Back")
+	t.Setenv("CACHE_FLUSH_INTERVAL", "250ms")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, cache.WriteBack, cfg.Cache.WriteStrategy)
+	assert.Equal(t, 250*time.Millisecond, cfg.Cache.FlushInterval)
+
+	t.Setenv("CACHE_WRITE_STRATEGY", "write-around")
+	_, err = Load()
+	assert.ErrorContains(t, err, "invalid CACHE_WRITE_STRATEGY")
+
+	t.Setenv("CACHE_WRITE_STRATEGY", cache.WriteThrough)
+	t.Setenv("CACHE_SNAPSHOT_FILE", "")
+	_, err = Load()
+	assert.ErrorContains(t, err, "requires CACHE_SNAPSHOT_FILE")
+}
+
+func TestLoad_AdminAccess(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key")
+	key, err := x509.MarshalPKCS8PrivateKey(server.TLS.Certificates[0].PrivateKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0o600))
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Nil(t, cfg.TLS, "plain HTTP by default")
+	assert.Empty(t, cfg.Admin.AllowedNets)
+
+	t.Setenv("TLS_CERT_FILE", certFile)
+	t.Setenv("TLS_KEY_FILE", keyFile)
+	t.Setenv("TLS_CLIENT_CA_FILE", certFile)
+	t.Setenv("ADMIN_ALLOWED_IPS", "10.1.0.0/16, 192.0.2.10, 2001:db8::1")
+	t.Setenv("ADMIN_REQUIRE_CLIENT_CERT", "true")
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.NotNil(t, cfg.TLS)
+	assert.Len(t, cfg.TLS.Certificates, 1)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, cfg.TLS.ClientAuth, "only admin routes require a certificate")
+	require.Len(t, cfg.Admin.AllowedNets, 3)
+	assert.True(t, cfg.Admin.AllowedNets[1].Contains(net.ParseIP("192.0.2.10")))
+	assert.False(t, cfg.Admin.AllowedNets[1].Contains(net.ParseIP("192.0.2.11")), "a single IP allows only itself")
+	assert.True(t, cfg.Admin.RequireClientCert)
+
+	tests := []struct {
+		name string
+		key  string
+		val  string
+	}{
+		{"Bad network", "ADMIN_ALLOWED_IPS", "10.1.0.0/33"},
+		{"Missing key", "TLS_KEY_FILE", filepath.Join(dir, "missing.key")},
+		{"CA without certificates", "TLS_CLIENT_CA_FILE", keyFile},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.key, tt.val)
+			_, err := Load()
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("Client certificate without CA", func(t *testing.T) {
+		t.Setenv("TLS_CLIENT_CA_FILE", "")
+		_, err := Load()
+		assert.ErrorContains(t, err, "ADMIN_REQUIRE_CLIENT_CERT")
+	})
+}
+
+func TestLoad_Chaos(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.False(t, cfg.Chaos.Enabled())
+	assert.False(t, cfg.Provider.Chaos.Enabled())
+
+	t.Setenv("CHAOS_LATENCY", "2s")
+	t.Setenv("CHAOS_LATENCY_RATE", "0.1")
+	t.Setenv("CHAOS_ERROR_RATE", "0.2")
+	t.Setenv("CHAOS_MALFORMED_RATE", "0.05")
+	t.Setenv("CHAOS_SEED", "42")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.False(t, cfg.Chaos.Enabled(), "only providers are targeted by default")
+	assert.Equal(t, 2*time.Second, cfg.Provider.Chaos.Latency)
+	assert.Equal(t, 0.1, cfg.Provider.Chaos.LatencyRate)
+	assert.Equal(t, 0.2, cfg.Provider.Chaos.ErrorRate)
+	assert.Equal(t, 0.05, cfg.Provider.Chaos.MalformedRate)
+	assert.Equal(t, int64(42), cfg.Provider.Chaos.Seed)
+
+	t.Setenv("CHAOS_TARGETS", "API, providers")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, cfg.Provider.Chaos, cfg.Chaos)
+
+	t.Setenv("CHAOS_TARGETS", "api")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.True(t, cfg.Chaos.Enabled())
+	assert.False(t, cfg.Provider.Chaos.Enabled())
+
+	tests := []struct {
+		name string
+		key  string
+		val  string
+		want string
+	}{
+		{"Rate over 1", "CHAOS_ERROR_RATE", "1.5", "invalid CHAOS_ERROR_RATE"},
+		{"Negative latency", "CHAOS_LATENCY", "-1s", "invalid CHAOS_LATENCY"},
+		{"Exclusive rates over 1", "CHAOS_MALFORMED_RATE", "0.9", "invalid CHAOS_MALFORMED_RATE"},
+		{"Unknown target", "CHAOS_TARGETS", "cache", "invalid CHAOS_TARGETS"},
+		{"Production", "ENVIRONMENT", "production", "ENVIRONMENT is production"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.key, tt.val)
+			_, err := Load()
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestLoad_Watchlists(t *testing.T) {
+	t.Setenv("WATCHLIST_MAX_PAIRS", "10")
+	t.Setenv("WATCHLIST_MAX_PER_CALLER", "3")
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, 10, cfg.Watchlists.MaxPairs)
+	assert.Equal(t, 3, cfg.Watchlists.MaxPerCaller)
+
+	t.Setenv("WATCHLIST_MAX_PER_CALLER", "0")
+	_, err = Load()
+	assert.ErrorContains(t, err, "invalid WATCHLIST_MAX_PER_CALLER")
+}
+
+func TestLoad_AuditMaxRecords(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, 10000, cfg.AuditMax)
+
+	t.Setenv("AUDIT_MAX_RECORDS", "500")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, 500, cfg.AuditMax)
+
+	t.Setenv("AUDIT_MAX_RECORDS", "0")
+	_, err = Load()
+	assert.ErrorContains(t, err, "invalid AUDIT_MAX_RECORDS")
+}
+
+func TestLoad_AnonymousPolicy(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, auth.Policy{}, cfg.Anonymous, "an open deployment lets anonymous callers do everything")
+
+	t.Setenv("API_KEYS", "partner:partner-secret:reader")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{auth.EntitlementLatest}, cfg.Anonymous.Entitlements, "with keys, anonymous callers only get latest rates")
+
+	t.Setenv("ANONYMOUS_POLICY", "pairs=USD_*;entitlements=latest|historical")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, auth.Policy{Pairs: []string{"USD_*"}, Entitlements: []string{auth.EntitlementLatest, auth.EntitlementHistorical}}, cfg.Anonymous)
+
+	t.Setenv("ANONYMOUS_POLICY", "entitlements=premium")
+	_, err = Load()
+	assert.ErrorContains(t, err, "invalid ANONYMOUS_POLICY")
+}
+
+func TestLoad_JWTRequiresIssuerAndAudience(t *testing.T) {
+	t.Setenv("JWT_JWKS_URL", "https://idp.example/jwks")
+	_, err := Load()
+	assert.ErrorContains(t, err, "JWT_ISSUER and JWT_AUDIENCE")
+
+	t.Setenv("JWT_ISSUER", "https://idp.example")
+	_, err = Load()
+	assert.ErrorContains(t, err, "JWT_ISSUER and JWT_AUDIENCE")
+
+	t.Setenv("JWT_AUDIENCE", "rates-api")
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "https://idp.example", cfg.JWT.Issuer)
+	assert.Equal(t, "rates-api", cfg.JWT.Audience)
+}
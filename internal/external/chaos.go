@@ -0,0 +1,68 @@
+package external
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"exchange-rate-service/internal/chaos"
+)
+
+// chaosBody is the body of injected provider errors
+const chaosBody = `{"error":"fault injected by chaos mode"}`
+
+// chaosTransport injects faults into provider requests below the retry and
+// circuit breaker logic, which see them as real provider failures: errors are
+// answered with a 503 without calling the provider, malformed bodies are the
+// provider's own, cut in half.
+type chaosTransport struct {
+	next     http.RoundTripper
+	injector *chaos.Injector
+}
+
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fault := t.injector.Next()
+	if err := chaos.Sleep(req.Context(), fault.Delay); err != nil {
+		return nil, err
+	}
+
+	if fault.Error {
+		header := http.Header{"Content-Type": {"application/json"}}
+		header.Set(chaos.FaultHeader, fault.String())
+		return &http.Response{
+			Status:        strconv.Itoa(http.StatusServiceUnavailable) + " " + http.StatusText(http.StatusServiceUnavailable),
+			StatusCode:    http.StatusServiceUnavailable,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader([]byte(chaosBody))),
+			ContentLength: int64(len(chaosBody)),
+			Request:       req,
+		}, nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || !fault.Malformed || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	body = chaos.Truncate(body)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Del("Content-Length")
+	resp.Header.Set(chaos.FaultHeader, fault.String())
+	return resp, nil
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (t *chaosTransport) CloseIdleConnections() {
+	if closer, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,90 @@
+package external
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/chaos"
+)
+
+func newChaosClient(t *testing.T, faults chaos.Config) (*ExchangeRateClient, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"base":"USD","rates":{"INR":83.5,"EUR":0.92}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := DefaultConfig()
+	cfg.BaseURL = server.URL
+	cfg.ConditionalRequests = false
+	cfg.Retry.InitialBackoff = time.Millisecond
+	cfg.Retry.MaxBackoff = 5 * time.Millisecond
+	cfg.Throttle = ThrottleConfig{}
+	cfg.Chaos = faults
+	return NewExchangeRateClientWithConfig(cfg), &calls
+}
+
+func TestChaos_ErrorsAreRetried(t *testing.T) {
+	client, calls := newChaosClient(t, chaos.Config{ErrorRate: 0.5, Seed: 3})
+
+	for i := 0; i < 20; i++ {
+		client.GetLatestRates(context.Background(), "USD")
+	}
+	stats := client.GetStats()
+	injected := stats["chaos"].(map[string]interface{})
+	assert.Positive(t, injected["errors"])
+	assert.Equal(t, injected["requests"], stats["attempts"], "every attempt passes the injector")
+	assert.Positive(t, stats["retries"], "injected errors are retried like real 503s")
+	assert.Equal(t, int64(atomic.LoadInt32(calls)), stats["attempts"].(int64)-injected["errors"].(int64), "errors never reach the provider")
+}
+
+func TestChaos_ErrorsOpenTheCircuit(t *testing.T) {
+	client, calls := newChaosClient(t, chaos.Config{ErrorRate: 1})
+	client.monitor = NewProviderMonitor(CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute})
+	client.retry.MaxAttempts = 1
+
+	for i := 0; i < 2; i++ {
+		_, err := client.GetLatestRates(context.Background(), "USD")
+		assert.ErrorContains(t, err, "status code: 503")
+	}
+	_, err := client.GetLatestRates(context.Background(), "USD")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Zero(t, atomic.LoadInt32(calls))
+}
+
+func TestChaos_MalformedBodies(t *testing.T) {
+	client, calls := newChaosClient(t, chaos.Config{MalformedRate: 1})
+
+	_, err := client.GetLatestRates(context.Background(), "USD")
+	assert.ErrorContains(t, err, "failed to decode response")
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls), "malformed bodies are the provider's own, truncated")
+}
+
+func TestChaos_Latency(t *testing.T) {
+	client, calls := newChaosClient(t, chaos.Config{Latency: time.Second, LatencyRate: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err := client.GetLatestRates(ctx, "USD")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(started), time.Second, "delays end with the request's deadline")
+	assert.Zero(t, atomic.LoadInt32(calls))
+}
+
+func TestChaos_Disabled(t *testing.T) {
+	client, _ := newChaosClient(t, chaos.Config{})
+
+	rates, err := client.GetLatestRates(context.Background(), "USD")
+	require.NoError(t, err)
+	assert.Equal(t, 83.5, rates.Rates["INR"])
+	assert.NotContains(t, client.GetStats(), "chaos")
+}
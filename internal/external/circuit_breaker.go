@@ -0,0 +1,125 @@
+package external
+
+import (
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/apperrors"
+)
+
+// ErrCircuitOpen is returned without calling the provider while its circuit
+// breaker is open
+var ErrCircuitOpen = apperrors.ErrProviderBusy.Variant("provider circuit breaker is open")
+
+// halfOpenRetryAfter is suggested to requests refused while the trial
+// request of a half-open circuit is in flight
+const halfOpenRetryAfter = time.Second
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitBreakerConfig stops calling a provider that keeps failing so
+// requests fail fast instead of waiting out timeouts and retries
+type CircuitBreakerConfig struct {
+	FailureThreshold int           // Consecutive failures that open the circuit, 0 disables the breaker
+	OpenTimeout      time.Duration // How long the circuit stays open before a trial request is let through
+}
+
+// DefaultCircuitBreakerConfig opens after 5 consecutive failures for 30 seconds
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+	}
+}
+
+// CircuitBreaker tracks consecutive failures of one provider. After
+// FailureThreshold of them it opens and rejects requests for OpenTimeout;
+// then it lets a single trial request through (half-open) and closes again if
+// that succeeds.
+type CircuitBreaker struct {
+	cfg CircuitBreakerConfig
+	now func() time.Time
+
+	mu         sync.Mutex
+	state      string
+	failures   int
+	openedAt   time.Time
+	trialTaken bool
+}
+
+func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{cfg: cfg, now: time.Now, state: CircuitClosed}
+}
+
+// Allow reports whether a request may be sent, returning ErrCircuitOpen,
+// wrapped in a RetryAfterError, when it may not. A nil or disabled breaker
+// always allows.
+func (b *CircuitBreaker) Allow() error {
+	if b == nil || b.cfg.FailureThreshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if open := b.now().Sub(b.openedAt); open < b.cfg.OpenTimeout {
+			return &RetryAfterError{Err: ErrCircuitOpen, After: b.cfg.OpenTimeout - open}
+		}
+		b.state = CircuitHalfOpen
+		b.trialTaken = true
+		return nil
+	case CircuitHalfOpen:
+		if b.trialTaken {
+			return &RetryAfterError{Err: ErrCircuitOpen, After: halfOpenRetryAfter}
+		}
+		b.trialTaken = true
+	}
+	return nil
+}
+
+// Record reports the outcome of a request let through by Allow
+func (b *CircuitBreaker) Record(success bool) {
+	if b == nil || b.cfg.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.state = CircuitClosed
+		b.failures = 0
+		b.trialTaken = false
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.cfg.FailureThreshold {
+		b.state = CircuitOpen
+		b.openedAt = b.now()
+		b.trialTaken = false
+	}
+}
+
+// State returns the breaker's state, the consecutive failure count and, while
+// open, when the next trial request is allowed
+func (b *CircuitBreaker) State() (state string, failures int, retryAt time.Time) {
+	if b == nil || b.cfg.FailureThreshold <= 0 {
+		return CircuitClosed, 0, time.Time{}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen {
+		retryAt = b.openedAt.Add(b.cfg.OpenTimeout)
+	}
+	return b.state, b.failures, retryAt
+}
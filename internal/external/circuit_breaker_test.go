@@ -0,0 +1,61 @@
+package external
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker_OpensAndRecovers(t *testing.T) {
+	now := time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: 30 * time.Second})
+	breaker.now = func() time.Time { return now }
+
+	assert.NoError(t, breaker.Allow())
+	breaker.Record(false)
+	assert.NoError(t, breaker.Allow(), "one failure is below the threshold")
+	breaker.Record(false)
+
+	state, failures, retryAt := breaker.State()
+	assert.Equal(t, CircuitOpen, state)
+	assert.Equal(t, 2, failures)
+	assert.Equal(t, now.Add(30*time.Second), retryAt)
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+
+	now = now.Add(10 * time.Second)
+	after, ok := RetryAfter(breaker.Allow())
+	assert.True(t, ok)
+	assert.Equal(t, 20*time.Second, after, "rejected requests learn when the circuit may close")
+
+	// After the timeout a single trial goes through
+	now = now.Add(31 * time.Second)
+	assert.NoError(t, breaker.Allow())
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+	state, _, _ = breaker.State()
+	assert.Equal(t, CircuitHalfOpen, state)
+
+	// A failed trial reopens the circuit straight away
+	breaker.Record(false)
+	state, _, _ = breaker.State()
+	assert.Equal(t, CircuitOpen, state)
+
+	now = now.Add(31 * time.Second)
+	assert.NoError(t, breaker.Allow())
+	breaker.Record(true)
+	state, failures, _ = breaker.State()
+	assert.Equal(t, CircuitClosed, state)
+	assert.Equal(t, 0, failures)
+	assert.NoError(t, breaker.Allow())
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{})
+	for i := 0; i < 10; i++ {
+		breaker.Record(false)
+	}
+	assert.NoError(t, breaker.Allow())
+
+	var nilBreaker *CircuitBreaker
+	assert.NoError(t, nilBreaker.Allow())
+}
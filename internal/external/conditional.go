@@ -0,0 +1,112 @@
+package external
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// storedBody is the last body a latest-rates endpoint answered, and what
+// identifies its version for a conditional request
+type storedBody struct {
+	body         []byte
+	etag         string
+	lastModified string
+	nextUpdate   time.Time // When the provider publishes new rates, zero if it doesn't say
+}
+
+// publication holds the publication times some providers put in the body,
+// used when they send no validator headers
+type publication struct {
+	TimeLastUpdated    int64 `json:"time_last_updated"`     // exchangerate-api v4
+	TimeLastUpdateUnix int64 `json:"time_last_update_unix"` // exchangerate-api v6
+	TimeNextUpdateUnix int64 `json:"time_next_update_unix"` // exchangerate-api v6
+}
+
+// conditionalStore remembers the latest-rates bodies by URL. Providers such
+// as exchangerate-api publish once a day, so most hourly refreshes can be
+// answered 304 Not Modified, or skipped until the announced next update.
+type conditionalStore struct {
+	mu      sync.Mutex
+	entries map[string]*storedBody
+}
+
+func newConditionalStore() *conditionalStore {
+	return &conditionalStore{entries: make(map[string]*storedBody)}
+}
+
+// fresh returns the stored body of rawURL when the provider announced it
+// won't change before now
+func (s *conditionalStore) fresh(rawURL string, now time.Time) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[rawURL]
+	if !ok || entry.nextUpdate.IsZero() || !now.Before(entry.nextUpdate) {
+		return nil, false
+	}
+	return entry.body, true
+}
+
+// prepare makes req conditional on the version of the stored body
+func (s *conditionalStore) prepare(req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[req.URL.String()]
+	if !ok {
+		return
+	}
+	if entry.etag != "" {
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+	if entry.lastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.lastModified)
+	}
+}
+
+// stored returns the body a 304 answer for rawURL refers to
+func (s *conditionalStore) stored(rawURL string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[rawURL]
+	if !ok {
+		return nil, false
+	}
+	return entry.body, true
+}
+
+// store remembers a 200 answer. Its ETag and Last-Modified headers identify
+// its version; without either, the publication time in the body stands in
+// for Last-Modified.
+func (s *conditionalStore) store(rawURL string, header http.Header, body []byte) {
+	entry := &storedBody{
+		body:         body,
+		etag:         header.Get("ETag"),
+		lastModified: header.Get("Last-Modified"),
+	}
+
+	var published publication
+	if err := json.Unmarshal(body, &published); err == nil {
+		updated := published.TimeLastUpdated
+		if updated == 0 {
+			updated = published.TimeLastUpdateUnix
+		}
+		if entry.etag == "" && entry.lastModified == "" && updated > 0 {
+			entry.lastModified = time.Unix(updated, 0).UTC().Format(http.TimeFormat)
+		}
+		if published.TimeNextUpdateUnix > 0 {
+			entry.nextUpdate = time.Unix(published.TimeNextUpdateUnix, 0)
+		}
+	}
+
+	if entry.etag == "" && entry.lastModified == "" && entry.nextUpdate.IsZero() {
+		return // Nothing to make the next request conditional on
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[rawURL] = entry
+}
@@ -0,0 +1,140 @@
+package external
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"exchange-rate-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProviderContracts replays payloads recorded from the providers, and
+// drifted versions of them, through the builtin providers in both decode
+// modes
+func TestProviderContracts(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   string
+		fixture    string
+		keyed      bool   // exchangerate-api.com's v6 API rather than v4
+		date       string // Historical date; latest when empty
+		want       map[string]float64
+		lenientErr error // nil when lenient mode serves want
+		strictErr  error // nil when strict mode serves want
+	}{
+		{
+			name: "erapi v4", provider: ProviderExchangeRateAPI, fixture: "erapi_v4_latest.json",
+			want: map[string]float64{"USD": 1, "EUR": 0.971, "GBP": 0.806, "INR": 85.79, "JPY": 157.32},
+		},
+		{
+			name: "erapi v6", provider: ProviderExchangeRateAPI, fixture: "erapi_v6_latest.json", keyed: true,
+			want: map[string]float64{"USD": 1, "EUR": 0.971, "GBP": 0.806, "INR": 85.79, "JPY": 157.32},
+		},
+		{
+			name: "erapi v6 history", provider: ProviderExchangeRateAPI, fixture: "erapi_v6_history.json", keyed: true,
+			date: "2025-01-02",
+			want: map[string]float64{"USD": 1, "EUR": 0.966, "INR": 85.65},
+		},
+		{
+			name: "erapi v6 unsupported code", provider: ProviderExchangeRateAPI, fixture: "erapi_v6_unsupported_code.json",
+			keyed: true, lenientErr: ErrRateNotFound, strictErr: ErrRateNotFound,
+		},
+		{
+			name: "frankfurter", provider: ProviderFrankfurter, fixture: "frankfurter_latest.json",
+			want: map[string]float64{"EUR": 0.97125, "GBP": 0.80621, "INR": 85.79, "JPY": 157.31},
+		},
+		{
+			name: "fixer", provider: ProviderFixer, fixture: "fixer_latest.json",
+			want: map[string]float64{"EUR": 0.971, "GBP": 0.806, "INR": 85.79},
+		},
+		{
+			name: "fixer invalid base", provider: ProviderFixer, fixture: "fixer_invalid_base.json",
+			lenientErr: ErrRateNotFound, strictErr: ErrRateNotFound,
+		},
+		{
+			name: "added field", provider: ProviderExchangeRateAPI, fixture: "erapi_v4_added_field.json",
+			want:      map[string]float64{"USD": 1, "EUR": 0.971, "INR": 85.79},
+			strictErr: ErrUnexpectedPayload,
+		},
+		{
+			name: "rates that aren't numbers", provider: ProviderExchangeRateAPI, fixture: "erapi_v4_string_rates.json",
+			want:      map[string]float64{"USD": 1, "EUR": 0.971},
+			strictErr: ErrUnexpectedPayload,
+		},
+		{
+			name: "renamed rates", provider: ProviderExchangeRateAPI, fixture: "erapi_v4_renamed_rates.json",
+			lenientErr: ErrUnexpectedPayload, strictErr: ErrUnexpectedPayload,
+		},
+		{
+			name: "not an object", provider: ProviderExchangeRateAPI, fixture: "erapi_v4_not_object.json",
+			lenientErr: ErrUnexpectedPayload, strictErr: ErrUnexpectedPayload,
+		},
+		{
+			name: "rates turned into a list", provider: ProviderFrankfurter, fixture: "frankfurter_rates_list.json",
+			lenientErr: ErrUnexpectedPayload, strictErr: ErrUnexpectedPayload,
+		},
+		{
+			name: "timestamp turned into a string", provider: ProviderFixer, fixture: "fixer_string_timestamp.json",
+			lenientErr: ErrUnexpectedPayload, strictErr: ErrUnexpectedPayload,
+		},
+	}
+
+	for _, tt := range tests {
+		body, err := os.ReadFile(filepath.Join("testdata", "contracts", tt.fixture))
+		require.NoError(t, err)
+
+		for _, mode := range []string{DecodeLenient, DecodeStrict} {
+			t.Run(tt.name+"/"+mode, func(t *testing.T) {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					w.Write(body)
+				}))
+				defer server.Close()
+
+				creds := NewCredentials()
+				if tt.keyed {
+					creds.SetKey(ProviderExchangeRateAPI, "contract-test-key")
+				}
+				creds.SetKey(ProviderFixer, "contract-test-key")
+				cfg := DefaultConfig()
+				cfg.BaseURL = server.URL
+				cfg.AuthenticatedBaseURL = server.URL
+				cfg.FrankfurterBaseURL = server.URL
+				cfg.FixerBaseURL = server.URL
+				cfg.Credentials = creds
+				cfg.Retry.MaxAttempts = 1
+				cfg.ConditionalRequests = false
+				cfg.DecodeMode = mode
+				client := NewExchangeRateClientWithConfig(cfg)
+
+				var (
+					rates *models.ExternalAPIResponse
+					err   error
+				)
+				if tt.date == "" {
+					rates, err = client.GetLatestRatesFrom(context.Background(), tt.provider, "USD")
+				} else {
+					rates, err = client.GetHistoricalRatesFrom(context.Background(), tt.provider, "USD", tt.date)
+				}
+
+				wantErr := tt.lenientErr
+				if mode == DecodeStrict {
+					wantErr = tt.strictErr
+				}
+				if wantErr != nil {
+					assert.ErrorIs(t, err, wantErr)
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, rates.Rates)
+				assert.Equal(t, tt.provider, rates.Provider)
+			})
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package external
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider names, also used to look up credentials
+const (
+	ProviderExchangeRateAPI = "exchangerate-api"
+	ProviderFixer           = "fixer"
+	ProviderFrankfurter     = "frankfurter" // Needs no key
+)
+
+// KnownProviders lists the providers whose keys are read from the environment
+var KnownProviders = []string{
+	ProviderExchangeRateAPI,
+	ProviderFixer,
+}
+
+// Credentials holds per-provider API keys. A key is either set directly or
+// read from a secret file; file-backed keys are re-read whenever the file
+// changes, so keys can be rotated without a restart.
+type Credentials struct {
+	mu    sync.RWMutex
+	keys  map[string]string
+	files map[string]*secretFile
+}
+
+type secretFile struct {
+	path    string
+	modTime time.Time
+}
+
+func NewCredentials() *Credentials {
+	return &Credentials{
+		keys:  make(map[string]string),
+		files: make(map[string]*secretFile),
+	}
+}
+
+// LoadCredentialsFromEnv reads <PROVIDER>_API_KEY and <PROVIDER>_API_KEY_FILE
+// for every known provider, e.g. FIXER_API_KEY or EXCHANGERATE_API_KEY_FILE.
+// The file variant wins when both are set.
+func LoadCredentialsFromEnv() (*Credentials, error) {
+	creds := NewCredentials()
+
+	for _, provider := range KnownProviders {
+		prefix := envPrefix(provider)
+		if key := os.Getenv(prefix + "_API_KEY"); key != "" {
+			creds.SetKey(provider, key)
+		}
+		if path := os.Getenv(prefix + "_API_KEY_FILE"); path != "" {
+			if err := creds.SetKeyFile(provider, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return creds, nil
+}
+
+// envPrefix turns "exchangerate-api" into "EXCHANGERATE"
+func envPrefix(provider string) string {
+	name := strings.TrimSuffix(provider, "-api")
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// SetKey sets a static key for a provider
+func (c *Credentials) SetKey(provider, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.keys[provider] = key
+	delete(c.files, provider)
+}
+
+// SetKeyFile loads a provider key from path and keeps watching it for changes
+func (c *Credentials) SetKeyFile(provider, path string) error {
+	key, modTime, err := readSecretFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read API key for %s: %w", provider, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.keys[provider] = key
+	c.files[provider] = &secretFile{path: path, modTime: modTime}
+	return nil
+}
+
+// Key returns the current key of a provider, or "" when none is configured.
+// A changed secret file is re-read by one caller only: the others find the
+// new key in place once they get the lock.
+func (c *Credentials) Key(provider string) string {
+	if c == nil {
+		return ""
+	}
+
+	c.mu.RLock()
+	key := c.keys[provider]
+	file := c.files[provider]
+	var loaded time.Time
+	if file != nil {
+		loaded = file.modTime
+	}
+	c.mu.RUnlock()
+
+	if file == nil {
+		return key
+	}
+
+	info, err := os.Stat(file.path)
+	if err != nil || !info.ModTime().After(loaded) {
+		return key
+	}
+
+	c.mu.Lock()
+	key = c.keys[provider]
+	if c.files[provider] != file || !info.ModTime().After(file.modTime) {
+		// Reloaded, or replaced by SetKey or SetKeyFile, while we waited
+		c.mu.Unlock()
+		return key
+	}
+	rotated, modTime, err := readSecretFile(file.path)
+	if err != nil {
+		c.mu.Unlock()
+		log.Printf("Failed to reload API key for %s, keeping %s: %v", provider, MaskKey(key), err)
+		return key
+	}
+	c.keys[provider] = rotated
+	file.modTime = modTime
+	c.mu.Unlock()
+
+	log.Printf("Rotated API key for %s: %s -> %s", provider, MaskKey(key), MaskKey(rotated))
+	return rotated
+}
+
+// Configured returns the providers that have a key, with masked keys, for
+// logging and diagnostics
+func (c *Credentials) Configured() map[string]string {
+	configured := make(map[string]string)
+	if c == nil {
+		return configured
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for provider, key := range c.keys {
+		configured[provider] = MaskKey(key)
+	}
+	return configured
+}
+
+// MaskKey hides all but the first and last four characters of a key
+func MaskKey(key string) string {
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:4] + strings.Repeat("*", len(key)-8) + key[len(key)-4:]
+}
+
+func readSecretFile(path string) (string, time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	key := strings.TrimSpace(string(content))
+	if key == "" {
+		return "", time.Time{}, fmt.Errorf("secret file %s is empty", path)
+	}
+	return key, info.ModTime(), nil
+}
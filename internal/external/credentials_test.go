@@ -0,0 +1,87 @@
+package external
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskKey(t *testing.T) {
+	assert.Equal(t, "abcd********mnop", MaskKey("abcdefghijklmnop"))
+	assert.Equal(t, "*****", MaskKey("short"))
+	assert.Equal(t, "", MaskKey(""))
+}
+
+func TestCredentials_StaticKey(t *testing.T) {
+	creds := NewCredentials()
+	creds.SetKey(ProviderFixer, "fixer-secret-key")
+
+	assert.Equal(t, "fixer-secret-key", creds.Key(ProviderFixer))
+	assert.Equal(t, "", creds.Key(ProviderExchangeRateAPI))
+	assert.Equal(t, map[string]string{ProviderFixer: "fixe********-key"}, creds.Configured())
+}
+
+func TestCredentials_NilIsEmpty(t *testing.T) {
+	var creds *Credentials
+	assert.Equal(t, "", creds.Key(ProviderExchangeRateAPI))
+	assert.Empty(t, creds.Configured())
+}
+
+func TestCredentials_KeyFileRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "erapi.key")
+	require.NoError(t, os.WriteFile(path, []byte("first-key-value\n"), 0o600))
+
+	creds := NewCredentials()
+	require.NoError(t, creds.SetKeyFile(ProviderExchangeRateAPI, path))
+	assert.Equal(t, "first-key-value", creds.Key(ProviderExchangeRateAPI))
+
+	require.NoError(t, os.WriteFile(path, []byte("second-key-value"), 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	assert.Equal(t, "second-key-value", creds.Key(ProviderExchangeRateAPI))
+}
+
+func TestCredentials_KeyFileRotationConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "erapi.key")
+	require.NoError(t, os.WriteFile(path, []byte("first-key-value"), 0o600))
+	creds := NewCredentials()
+	require.NoError(t, creds.SetKeyFile(ProviderExchangeRateAPI, path))
+
+	require.NoError(t, os.WriteFile(path, []byte("second-key-value"), 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, "second-key-value", creds.Key(ProviderExchangeRateAPI))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, strings.Count(logs.String(), "Rotated API key"), "one caller reloads the file")
+}
+
+func TestCredentials_LoadFromEnv(t *testing.T) {
+	t.Setenv("EXCHANGERATE_API_KEY", "erapi-key-from-env")
+	t.Setenv("FIXER_API_KEY", "fixer-key-from-env")
+
+	creds, err := LoadCredentialsFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, "erapi-key-from-env", creds.Key(ProviderExchangeRateAPI))
+	assert.Equal(t, "fixer-key-from-env", creds.Key(ProviderFixer))
+}
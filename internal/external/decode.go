@@ -0,0 +1,157 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"exchange-rate-service/internal/apperrors"
+)
+
+// Decode modes of provider payloads
+const (
+	// DecodeLenient logs fields a payload's schema doesn't know and drops
+	// rates that aren't positive numbers, serving the rest
+	DecodeLenient = "lenient"
+	// DecodeStrict fails a request whose payload has unknown fields or
+	// unusable rates
+	DecodeStrict = "strict"
+)
+
+// ErrUnexpectedPayload is returned when a provider answers with a body its
+// schema doesn't describe, e.g. after an upstream change to its JSON
+var ErrUnexpectedPayload = apperrors.ErrRateUnavailable.Variant("unexpected provider payload")
+
+// payloadSchema is one version of a provider's response body
+type payloadSchema struct {
+	provider string
+	version  string   // e.g. v6 for exchangerate-api.com's keyed API
+	fields   []string // Top-level fields of the version
+	rates    string   // Field holding the quote -> rate object
+}
+
+func (s payloadSchema) String() string {
+	return s.provider + " " + s.version
+}
+
+func (s payloadSchema) knows(field string) bool {
+	for _, known := range s.fields {
+		if known == field {
+			return true
+		}
+	}
+	return false
+}
+
+// payloadDecoder decodes provider payloads against their schema. Every
+// deviation is logged once per schema, so a changed upstream doesn't flood
+// the log.
+type payloadDecoder struct {
+	mode          string
+	unknownFields int64 // Payload fields no schema knows
+	droppedRates  int64 // Rates that weren't positive numbers
+
+	mu       sync.Mutex
+	reported map[string]bool // schema + deviation -> logged
+}
+
+func newPayloadDecoder(mode string) *payloadDecoder {
+	if mode != DecodeStrict {
+		mode = DecodeLenient
+	}
+	return &payloadDecoder{mode: mode, reported: make(map[string]bool)}
+}
+
+// decode decodes body, a payload of schema, into out. A body that isn't a
+// JSON object, or whose fields don't have the types out expects, fails with
+// ErrUnexpectedPayload whatever the mode.
+func (d *payloadDecoder) decode(schema payloadSchema, body []byte, out interface{}) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return fmt.Errorf("%w: %s answered with something other than a JSON object", ErrUnexpectedPayload, schema)
+	}
+
+	var unknown []string
+	for field := range fields {
+		if !schema.knows(field) {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		if d.mode == DecodeStrict {
+			return fmt.Errorf("%w: %s payload has unknown fields %s", ErrUnexpectedPayload, schema, strings.Join(unknown, ", "))
+		}
+		atomic.AddInt64(&d.unknownFields, int64(len(unknown)))
+		for _, field := range unknown {
+			d.report(schema, "field "+field, "Provider %s payload has unknown field %q, ignoring it", schema, field)
+		}
+	}
+
+	if raw, ok := fields[schema.rates]; ok && string(raw) != "null" {
+		rates, err := d.decodeRates(schema, raw)
+		if err != nil {
+			return err
+		}
+		if fields[schema.rates], err = json.Marshal(rates); err != nil {
+			return err
+		}
+		if body, err = json.Marshal(fields); err != nil {
+			return err
+		}
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("%w: %s payload: %v", ErrUnexpectedPayload, schema, err)
+	}
+	return nil
+}
+
+// decodeRates decodes a quote -> rate object, keeping only positive numbers
+// in lenient mode
+func (d *payloadDecoder) decodeRates(schema payloadSchema, raw json.RawMessage) (map[string]float64, error) {
+	var quoted map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &quoted); err != nil {
+		return nil, fmt.Errorf("%w: %s payload's %s is not an object", ErrUnexpectedPayload, schema, schema.rates)
+	}
+
+	rates := make(map[string]float64, len(quoted))
+	for code, value := range quoted {
+		var rate float64
+		if err := json.Unmarshal(value, &rate); err == nil && rate > 0 {
+			rates[code] = rate
+			continue
+		}
+		if d.mode == DecodeStrict {
+			return nil, fmt.Errorf("%w: %s payload has rate %s for %s", ErrUnexpectedPayload, schema, value, code)
+		}
+		atomic.AddInt64(&d.droppedRates, 1)
+		d.report(schema, "rate "+code, "Provider %s payload has rate %s for %s, dropping it", schema, value, code)
+	}
+	return rates, nil
+}
+
+// report logs a deviation from schema the first time it is seen
+func (d *payloadDecoder) report(schema payloadSchema, deviation, format string, args ...interface{}) {
+	key := schema.String() + " " + deviation
+	d.mu.Lock()
+	seen := d.reported[key]
+	d.reported[key] = true
+	d.mu.Unlock()
+
+	if !seen {
+		log.Printf(format, args...)
+	}
+}
+
+func (d *payloadDecoder) stats() map[string]interface{} {
+	return map[string]interface{}{
+		"mode":           d.mode,
+		"unknown_fields": atomic.LoadInt64(&d.unknownFields),
+		"dropped_rates":  atomic.LoadInt64(&d.droppedRates),
+	}
+}
@@ -0,0 +1,40 @@
+package external
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadDecoder_Lenient(t *testing.T) {
+	decoder := newPayloadDecoder("")
+	body := []byte(`{"base":"USD","source":"ecb","rates":{"EUR":0.97,"INR":"85.7","XDR":-1}}`)
+
+	for i := 0; i < 2; i++ {
+		var payload frankfurterResponse
+		require.NoError(t, decoder.decode(frankfurterV1Schema, body, &payload))
+		assert.Equal(t, map[string]float64{"EUR": 0.97}, payload.Rates)
+	}
+
+	stats := decoder.stats()
+	assert.Equal(t, DecodeLenient, stats["mode"])
+	assert.Equal(t, int64(2), stats["unknown_fields"])
+	assert.Equal(t, int64(4), stats["dropped_rates"])
+	assert.Len(t, decoder.reported, 3, "each deviation is logged once")
+}
+
+func TestPayloadDecoder_Strict(t *testing.T) {
+	decoder := newPayloadDecoder(DecodeStrict)
+
+	var payload frankfurterResponse
+	err := decoder.decode(frankfurterV1Schema, []byte(`{"base":"USD","source":"ecb","rates":{"EUR":0.97}}`), &payload)
+	assert.ErrorIs(t, err, ErrUnexpectedPayload)
+	assert.Contains(t, err.Error(), "source")
+
+	err = decoder.decode(frankfurterV1Schema, []byte(`{"base":"USD","rates":{"EUR":0}}`), &payload)
+	assert.ErrorIs(t, err, ErrUnexpectedPayload)
+
+	require.NoError(t, decoder.decode(frankfurterV1Schema, []byte(`{"base":"USD","rates":null}`), &payload))
+	assert.Nil(t, payload.Rates)
+}
@@ -0,0 +1,103 @@
+package external
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dnsCache remembers the addresses of provider hosts for a while, so that new
+// connections do not wait on a lookup. The few providers called resolve to
+// stable addresses, and an address that stops answering is forgotten.
+type dnsCache struct {
+	ttl    time.Duration
+	lookup func(ctx context.Context, host string) ([]string, error)
+
+	mu    sync.Mutex
+	hosts map[string]dnsEntry
+
+	hits   int64
+	misses int64
+}
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+func newDNSCache(ttl time.Duration) *dnsCache {
+	return &dnsCache{ttl: ttl, lookup: net.DefaultResolver.LookupHost, hosts: make(map[string]dnsEntry)}
+}
+
+// resolve returns the addresses of host, looking it up when it is not cached
+// or has expired
+func (d *dnsCache) resolve(ctx context.Context, host string) ([]string, error) {
+	d.mu.Lock()
+	entry, ok := d.hosts[host]
+	d.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		atomic.AddInt64(&d.hits, 1)
+		return entry.addrs, nil
+	}
+
+	atomic.AddInt64(&d.misses, 1)
+	addrs, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	d.hosts[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(d.ttl)}
+	d.mu.Unlock()
+	return addrs, nil
+}
+
+// forget drops the addresses of host, so the next dial looks it up again
+func (d *dnsCache) forget(host string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.hosts, host)
+}
+
+// dialContext dials addr through dialer, trying each cached address of its
+// host in turn. Addresses given as IPs are dialed as they are.
+func (d *dnsCache) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		addrs, err := d.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var lastErr error
+		for _, ip := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		// The host may have moved; look it up again next time
+		d.forget(host)
+		if lastErr == nil {
+			lastErr = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+		}
+		return nil, lastErr
+	}
+}
+
+func (d *dnsCache) stats() map[string]interface{} {
+	d.mu.Lock()
+	hosts := len(d.hosts)
+	d.mu.Unlock()
+	return map[string]interface{}{
+		"ttl_seconds": d.ttl.Seconds(),
+		"hosts":       hosts,
+		"hits":        atomic.LoadInt64(&d.hits),
+		"misses":      atomic.LoadInt64(&d.misses),
+	}
+}
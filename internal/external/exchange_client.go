@@ -1,11 +1,21 @@
 package external
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"exchange-rate-service/internal/apperrors"
+	"exchange-rate-service/internal/chaos"
 	"exchange-rate-service/internal/models"
 )
 
@@ -14,85 +24,541 @@ const (
 	LatestEndpoint  = "/latest"
 	HistoryEndpoint = "/history"
 	RequestTimeout  = 10 * time.Second
+	ProbeTimeout    = 3 * time.Second
+
+	// AuthenticatedBaseURL is the keyed API, which also serves historical data
+	AuthenticatedBaseURL = "https://v6.exchangerate-api.com/v6"
 )
 
+// ErrRateNotFound is returned when the provider answers but has no rate for
+// the requested currency
+var ErrRateNotFound = apperrors.ErrRateNotFound
+
+// ErrNoHistoricalData is returned when the provider's plan has no
+// historical rates
+var ErrNoHistoricalData = apperrors.ErrNoHistoricalData
+
+// Config configures an ExchangeRateClient
+type Config struct {
+	BaseURL              string
+	AuthenticatedBaseURL string
+	FrankfurterBaseURL   string
+	FixerBaseURL         string
+	DefaultProvider      string // Provider used when a request does not pick one
+	Timeout              time.Duration
+	Retry                RetryPolicy
+	Throttle             ThrottleConfig
+	CircuitBreaker       CircuitBreakerConfig
+	Credentials          *Credentials
+	Transport            TransportConfig // Proxy, TLS, headers and connection pool of provider requests
+	Providers            []Provider      // Registered after the builtin providers; DefaultProvider may name one
+
+	// FixedBases names the providers that quote a single base currency,
+	// e.g. fixer's free plan only EUR. Their table against that base is
+	// fetched for every base and rebased to it.
+	FixedBases map[string]string
+
+	// ConditionalRequests revalidates latest rates with If-None-Match and
+	// If-Modified-Since instead of downloading unchanged tables again
+	ConditionalRequests bool
+
+	// DecodeMode is how payloads that deviate from the builtin providers'
+	// schemas are handled: DecodeLenient or DecodeStrict
+	DecodeMode string
+
+	// Chaos injects latency, errors and malformed bodies into provider
+	// responses for resilience testing, off when no rate is set
+	Chaos chaos.Config
+}
+
+// DefaultConfig returns the configuration used by NewExchangeRateClient
+func DefaultConfig() Config {
+	return Config{
+		BaseURL:              BaseURL,
+		AuthenticatedBaseURL: AuthenticatedBaseURL,
+		FrankfurterBaseURL:   FrankfurterBaseURL,
+		FixerBaseURL:         FixerBaseURL,
+		DefaultProvider:      ProviderExchangeRateAPI,
+		Timeout:              RequestTimeout,
+		Retry:                DefaultRetryPolicy(),
+		Throttle:             DefaultThrottleConfig(),
+		CircuitBreaker:       DefaultCircuitBreakerConfig(),
+		Transport:            DefaultTransportConfig(),
+		ConditionalRequests:  true,
+		DecodeMode:           DecodeLenient,
+	}
+}
+
 type ExchangeRateClient struct {
-	httpClient *http.Client
-	baseURL    string
+	httpClient      *http.Client
+	headers         http.Header // Sent with every provider request
+	providers       map[string]Provider
+	providerOrder   []string
+	defaultMu       sync.RWMutex
+	defaultProvider string            // Guarded by defaultMu, as reloads change it
+	fixedBases      map[string]string // Provider -> the only base it quotes
+	retry           RetryPolicy
+	throttler       *Throttler
+	monitor         *ProviderMonitor
+	credentials     *Credentials
+	conditional     *conditionalStore // nil when conditional requests are off
+	decoder         *payloadDecoder
+	dns             *dnsCache       // nil when provider addresses are not cached
+	chaos           *chaos.Injector // nil when no faults are injected
+	conns           connStats
+	stats           clientStats
+}
+
+type clientStats struct {
+	requests       int64
+	attempts       int64
+	retries        int64
+	retryExhausted int64
+	failures       int64
+	notModified    int64 // Latest rates answered 304 Not Modified
+	skipped        int64 // Latest rates served without a request until the provider's next update
 }
 
 func NewExchangeRateClient() *ExchangeRateClient {
-	return &ExchangeRateClient{
-		httpClient: &http.Client{
-			Timeout: RequestTimeout,
-		},
-		baseURL: BaseURL,
-	}
+	return NewExchangeRateClientWithConfig(DefaultConfig())
 }
 
-func (c *ExchangeRateClient) GetLatestRates(baseCurrency string) (*models.ExternalAPIResponse, error) {
-	url := fmt.Sprintf("%s%s/%s", c.baseURL, LatestEndpoint, baseCurrency)
-	
-	resp, err := c.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch latest rates: %w", err)
+func NewExchangeRateClientWithConfig(cfg Config) *ExchangeRateClient {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = BaseURL
+	}
+	if cfg.AuthenticatedBaseURL == "" {
+		cfg.AuthenticatedBaseURL = AuthenticatedBaseURL
+	}
+	if cfg.FrankfurterBaseURL == "" {
+		cfg.FrankfurterBaseURL = FrankfurterBaseURL
+	}
+	if cfg.FixerBaseURL == "" {
+		cfg.FixerBaseURL = FixerBaseURL
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = RequestTimeout
+	}
+	if cfg.Retry.MaxAttempts < 1 {
+		cfg.Retry.MaxAttempts = 1
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status code: %d", resp.StatusCode)
+	client := &ExchangeRateClient{
+		headers:     cfg.Transport.requestHeaders(),
+		providers:   make(map[string]Provider),
+		retry:       cfg.Retry,
+		throttler:   NewThrottler(cfg.Throttle),
+		monitor:     NewProviderMonitor(cfg.CircuitBreaker),
+		credentials: cfg.Credentials,
+		decoder:     newPayloadDecoder(cfg.DecodeMode),
+		fixedBases:  make(map[string]string, len(cfg.FixedBases)),
+	}
+	for provider, base := range cfg.FixedBases {
+		client.fixedBases[CanonicalProvider(provider)] = base
+	}
+	if cfg.ConditionalRequests {
+		client.conditional = newConditionalStore()
+	}
+	if cfg.Transport.DNSCacheTTL > 0 {
+		client.dns = newDNSCache(cfg.Transport.DNSCacheTTL)
+	}
+	var transport http.RoundTripper = &trackingTransport{next: cfg.Transport.transport(client.dns), stats: &client.conns}
+	if cfg.Chaos.Enabled() {
+		client.chaos = chaos.NewInjector(cfg.Chaos)
+		transport = &chaosTransport{next: transport, injector: client.chaos}
+	}
+	client.httpClient = &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: transport,
 	}
 
-	var apiResponse models.ExternalAPIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	client.register(&exchangeRateAPI{client: client, baseURL: cfg.BaseURL, authBaseURL: cfg.AuthenticatedBaseURL})
+	client.register(&frankfurter{client: client, baseURL: cfg.FrankfurterBaseURL})
+	client.register(&fixer{client: client, baseURL: cfg.FixerBaseURL})
+	for _, provider := range cfg.Providers {
+		client.register(provider)
 	}
 
-	if apiResponse.Rates == nil {
-		return nil, fmt.Errorf("API request was not successful - no rates received")
+	client.defaultProvider = CanonicalProvider(cfg.DefaultProvider)
+	if _, ok := client.providers[client.defaultProvider]; !ok {
+		client.defaultProvider = ProviderExchangeRateAPI
 	}
 
-	return &apiResponse, nil
+	return client
 }
 
-func (c *ExchangeRateClient) GetHistoricalRates(baseCurrency, date string) (*models.ExternalAPIResponse, error) {
+// register makes a provider available to requests and starts monitoring it
+func (c *ExchangeRateClient) register(provider Provider) {
+	c.providers[provider.Name()] = provider
+	c.providerOrder = append(c.providerOrder, provider.Name())
+	c.monitor.Register(provider.Name())
+}
+
+// HasHistoricalData reports whether the default provider can serve
+// historical rates; exchangerate-api requires an API key for the paid tier
+func (c *ExchangeRateClient) HasHistoricalData() bool {
+	return c.providers[c.DefaultProvider()].HasHistoricalData()
+}
 
-	return nil, fmt.Errorf("historical data not available with current API - upgrade to paid tier for historical data")
+// GetLatestRates fetches the latest rates against baseCurrency from the
+// default provider
+func (c *ExchangeRateClient) GetLatestRates(ctx context.Context, baseCurrency string) (*models.ExternalAPIResponse, error) {
+	return c.GetLatestRatesFrom(ctx, "", baseCurrency)
 }
 
-func (c *ExchangeRateClient) GetRateForPair(from, to string) (float64, error) {
+// GetLatestRatesFrom fetches the latest rates from the named provider, or
+// the default one when provider is empty
+func (c *ExchangeRateClient) GetLatestRatesFrom(ctx context.Context, provider, baseCurrency string) (*models.ExternalAPIResponse, error) {
+	p, err := c.Provider(provider)
+	if err != nil {
+		return nil, err
+	}
+	fixedBase, rebase := c.fixedBase(p, baseCurrency)
+	response, err := p.LatestRates(ctx, fixedBase)
+	if err != nil {
+		return nil, err
+	}
+	if rebase {
+		return Rebase(stamped(p, response), baseCurrency)
+	}
+	return stamped(p, response), nil
+}
+
+// GetHistoricalRates fetches the rates against baseCurrency published for
+// date from the default provider
+func (c *ExchangeRateClient) GetHistoricalRates(ctx context.Context, baseCurrency, date string) (*models.ExternalAPIResponse, error) {
+	return c.GetHistoricalRatesFrom(ctx, "", baseCurrency, date)
+}
+
+// GetHistoricalRatesFrom fetches historical rates from the named provider,
+// or the default one when provider is empty
+func (c *ExchangeRateClient) GetHistoricalRatesFrom(ctx context.Context, provider, baseCurrency, date string) (*models.ExternalAPIResponse, error) {
+	p, err := c.Provider(provider)
+	if err != nil {
+		return nil, err
+	}
+	fixedBase, rebase := c.fixedBase(p, baseCurrency)
+	response, err := p.HistoricalRates(ctx, fixedBase, date)
+	if err != nil {
+		return nil, err
+	}
+	if rebase {
+		return Rebase(stamped(p, response), baseCurrency)
+	}
+	return stamped(p, response), nil
+}
+
+// fixedBase returns the base to fetch p's rates against for rates against
+// base, and whether the table must then be rebased to base
+func (c *ExchangeRateClient) fixedBase(p Provider, base string) (string, bool) {
+	if fixed, ok := c.fixedBases[p.Name()]; ok && fixed != base {
+		return fixed, true
+	}
+	return base, false
+}
+
+// stamped names p as the provider of a response that doesn't name one, as
+// rates are cached under their provider
+func stamped(p Provider, response *models.ExternalAPIResponse) *models.ExternalAPIResponse {
+	if response != nil && response.Provider == "" {
+		response.Provider = p.Name()
+	}
+	return response
+}
+
+func (c *ExchangeRateClient) GetRateForPair(ctx context.Context, from, to string) (float64, error) {
 	if from == to {
 		return 1.0, nil
 	}
 
 	// Get latest rates with 'from' currency as base
-	apiResponse, err := c.GetLatestRates(from)
+	apiResponse, err := c.GetLatestRates(ctx, from)
 	if err != nil {
 		return 0, err
 	}
 
 	rate, exists := apiResponse.Rates[to]
 	if !exists {
-		return 0, fmt.Errorf("rate not found for currency pair %s/%s", from, to)
+		return 0, fmt.Errorf("%w for currency pair %s/%s", ErrRateNotFound, from, to)
 	}
 
 	return rate, nil
 }
 
-func (c *ExchangeRateClient) GetHistoricalRateForPair(from, to, date string) (float64, error) {
+func (c *ExchangeRateClient) GetHistoricalRateForPair(ctx context.Context, from, to, date string) (float64, error) {
 	if from == to {
 		return 1.0, nil
 	}
 
-	apiResponse, err := c.GetHistoricalRates(from, date)
+	apiResponse, err := c.GetHistoricalRates(ctx, from, date)
 	if err != nil {
 		return 0, err
 	}
 
 	rate, exists := apiResponse.Rates[to]
 	if !exists {
-		return 0, fmt.Errorf("historical rate not found for currency pair %s/%s on %s", from, to, date)
+		return 0, fmt.Errorf("historical %w for currency pair %s/%s on %s", ErrRateNotFound, from, to, date)
 	}
 
 	return rate, nil
 }
+
+// Probe checks that the default provider is reachable with a single HEAD
+// request, without retries. Any response below 500 counts as reachable.
+// Providers without a probe URL are assumed reachable.
+func (c *ExchangeRateClient) Probe() error {
+	probeURL := c.providers[c.DefaultProvider()].ProbeURL()
+	if probeURL == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, probeURL, nil)
+	if err != nil {
+		return err
+	}
+	c.setHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("provider unreachable: %w", urlErr.Err)
+		}
+		return fmt.Errorf("provider unreachable: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("provider returned status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ProviderStatus returns the health of every provider the client calls
+func (c *ExchangeRateClient) ProviderStatus() []models.ProviderStatus {
+	return c.monitor.Status()
+}
+
+// GetStats returns request and retry counters, and under connections how
+// often provider connections were reused
+func (c *ExchangeRateClient) GetStats() map[string]interface{} {
+	connections := c.conns.snapshot()
+	if c.dns != nil {
+		connections["dns_cache"] = c.dns.stats()
+	}
+	stats := map[string]interface{}{
+		"requests":        atomic.LoadInt64(&c.stats.requests),
+		"attempts":        atomic.LoadInt64(&c.stats.attempts),
+		"retries":         atomic.LoadInt64(&c.stats.retries),
+		"retry_exhausted": atomic.LoadInt64(&c.stats.retryExhausted),
+		"failures":        atomic.LoadInt64(&c.stats.failures),
+		"not_modified":    atomic.LoadInt64(&c.stats.notModified),
+		"skipped":         atomic.LoadInt64(&c.stats.skipped),
+		"max_attempts":    c.retry.MaxAttempts,
+		"throttle":        c.throttler.Stats(),
+		"connections":     connections,
+		"decoding":        c.decoder.stats(),
+	}
+	if c.chaos != nil {
+		stats["chaos"] = c.chaos.Stats()
+	}
+	return stats
+}
+
+// getJSON fetches endpoint of provider and decodes the body into out,
+// retrying transient failures according to the client's retry policy. It
+// gives up as soon as ctx is done, between attempts as well as during one.
+// key is the API key endpoint carries, if any, masked in errors.
+func (c *ExchangeRateClient) getJSON(ctx context.Context, provider, endpoint, key string, out interface{}) error {
+	return c.fetchJSON(ctx, provider, endpoint, key, out, false)
+}
+
+// getLatestJSON is getJSON for a latest-rates endpoint, whose body changes
+// only when the provider publishes. With conditional requests on, an
+// unchanged body is revalidated rather than downloaded again, and not
+// requested at all before the update time the provider announced.
+func (c *ExchangeRateClient) getLatestJSON(ctx context.Context, provider, endpoint, key string, out interface{}) error {
+	return c.fetchJSON(ctx, provider, endpoint, key, out, c.conditional != nil)
+}
+
+// getPayload fetches endpoint like getJSON, or getLatestJSON when latest is
+// set, and decodes the body into out against schema
+func (c *ExchangeRateClient) getPayload(ctx context.Context, schema payloadSchema, endpoint, key string, latest bool, out interface{}) error {
+	get := c.getJSON
+	if latest {
+		get = c.getLatestJSON
+	}
+	var body json.RawMessage
+	if err := get(ctx, schema.provider, endpoint, key, &body); err != nil {
+		return err
+	}
+	return c.decoder.decode(schema, body, out)
+}
+
+func (c *ExchangeRateClient) fetchJSON(ctx context.Context, provider, endpoint, key string, out interface{}, conditional bool) error {
+	atomic.AddInt64(&c.stats.requests, 1)
+
+	if conditional {
+		if body, ok := c.conditional.fresh(endpoint, time.Now()); ok {
+			if err := json.Unmarshal(body, out); err == nil {
+				atomic.AddInt64(&c.stats.skipped, 1)
+				return nil
+			}
+		}
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= c.retry.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			wait := c.retry.backoff(attempt - 1)
+			log.Printf("Retrying upstream request (attempt %d/%d) in %v: %v", attempt, c.retry.MaxAttempts, wait, lastErr)
+			atomic.AddInt64(&c.stats.retries, 1)
+			if err := sleep(ctx, wait); err != nil {
+				atomic.AddInt64(&c.stats.failures, 1)
+				return classify(ctx, fmt.Errorf("gave up retrying: %w", err))
+			}
+		}
+
+		// Every attempt, retries included, counts against the provider's limit
+		if err := c.throttler.Wait(ctx, provider); err != nil {
+			atomic.AddInt64(&c.stats.failures, 1)
+			return classify(ctx, err)
+		}
+		if err := c.monitor.Allow(provider); err != nil {
+			atomic.AddInt64(&c.stats.failures, 1)
+			return err
+		}
+
+		atomic.AddInt64(&c.stats.attempts, 1)
+		start := time.Now()
+		retryable, err := c.doGet(ctx, endpoint, key, out, conditional)
+		if err != nil && ctx.Err() != nil {
+			// The caller gave up, which says nothing about the provider's health
+			atomic.AddInt64(&c.stats.failures, 1)
+			return classify(ctx, err)
+		}
+		c.monitor.Record(provider, time.Since(start), err, retryable)
+		if err == nil {
+			return nil
+		}
+
+		lastErr = err
+		if !retryable {
+			atomic.AddInt64(&c.stats.failures, 1)
+			return classify(ctx, err)
+		}
+	}
+
+	atomic.AddInt64(&c.stats.failures, 1)
+	if c.retry.MaxAttempts > 1 {
+		atomic.AddInt64(&c.stats.retryExhausted, 1)
+		return classify(ctx, fmt.Errorf("giving up after %d attempts: %w", c.retry.MaxAttempts, lastErr))
+	}
+	return classify(ctx, lastErr)
+}
+
+// classify returns err, a failed call made on behalf of ctx, as the kind of
+// failure it is unless it already has one: a timeout or cancellation when
+// the caller stopped waiting, the provider failing otherwise
+func classify(ctx context.Context, err error) error {
+	if _, ok := apperrors.CodeOf(err); ok {
+		return err
+	}
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return apperrors.Wrap(apperrors.ErrProviderTimeout, err)
+	case ctx.Err() != nil:
+		return apperrors.Wrap(apperrors.ErrCanceled, err)
+	}
+	return apperrors.Wrap(apperrors.ErrRateUnavailable, err)
+}
+
+// setHeaders adds the configured User-Agent and extra headers to a provider
+// request
+func (c *ExchangeRateClient) setHeaders(req *http.Request) {
+	for name, values := range c.headers {
+		req.Header[name] = append([]string(nil), values...)
+	}
+}
+
+// doGet performs a single request, conditional on the stored body of rawURL
+// when conditional is set. The returned bool reports whether the failure is
+// transient.
+func (c *ExchangeRateClient) doGet(ctx context.Context, rawURL, key string, out interface{}, conditional bool) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("request to %s failed: %w", redact(rawURL, key), err)
+	}
+	c.setHeaders(req)
+	if conditional {
+		c.conditional.prepare(req)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// url.Error embeds the full URL, which carries the API key on keyed endpoints
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return true, fmt.Errorf("request to %s failed: %w", redact(rawURL, key), urlErr.Err)
+		}
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if conditional && resp.StatusCode == http.StatusNotModified {
+		if body, ok := c.conditional.stored(rawURL); ok {
+			atomic.AddInt64(&c.stats.notModified, 1)
+			return false, json.Unmarshal(body, out)
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return c.retry.shouldRetryStatus(resp.StatusCode), fmt.Errorf("API returned status code: %d", resp.StatusCode)
+	}
+
+	if !conditional {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return false, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return false, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return true, fmt.Errorf("failed to read response: %w", err)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return false, fmt.Errorf("failed to decode response: %w", err)
+	}
+	c.conditional.store(rawURL, resp.Header, body)
+	return false, nil
+}
+
+// sleep waits for d, or returns ctx's error once ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// redact masks key, the API key a request was sent with, wherever it
+// appears in s. The key is the one sent rather than the provider's current
+// one, which may have been rotated since.
+func redact(s, key string) string {
+	if key == "" {
+		return s
+	}
+	s = strings.ReplaceAll(s, key, MaskKey(key))
+	if escaped := url.QueryEscape(key); escaped != key {
+		s = strings.ReplaceAll(s, escaped, MaskKey(escaped))
+	}
+	return s
+}
@@ -0,0 +1,259 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(url string, maxAttempts int) *ExchangeRateClient {
+	cfg := DefaultConfig()
+	cfg.BaseURL = url
+	cfg.Retry.MaxAttempts = maxAttempts
+	cfg.Retry.InitialBackoff = time.Millisecond
+	cfg.Retry.MaxBackoff = 5 * time.Millisecond
+	return NewExchangeRateClientWithConfig(cfg)
+}
+
+func TestExchangeRateClient_RetriesTransientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"base":"USD","rates":{"INR":83.5}}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, 3)
+	rate, err := client.GetRateForPair(context.Background(), "USD", "INR")
+	require.NoError(t, err)
+	assert.Equal(t, 83.5, rate)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	stats := client.GetStats()
+	assert.Equal(t, int64(1), stats["requests"])
+	assert.Equal(t, int64(2), stats["attempts"])
+	assert.Equal(t, int64(1), stats["retries"])
+}
+
+func TestExchangeRateClient_DoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, 3)
+	_, err := client.GetLatestRates(context.Background(), "USD")
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestExchangeRateClient_RetryExhausted(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, 3)
+	_, err := client.GetLatestRates(context.Background(), "USD")
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Equal(t, int64(1), client.GetStats()["retry_exhausted"])
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     300 * time.Millisecond,
+		Multiplier:     2,
+	}
+
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, policy.backoff(2))
+	assert.Equal(t, 300*time.Millisecond, policy.backoff(3))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 20; i++ {
+		wait := policy.backoff(1)
+		assert.GreaterOrEqual(t, wait, 50*time.Millisecond)
+		assert.LessOrEqual(t, wait, 100*time.Millisecond)
+	}
+}
+
+func TestExchangeRateClient_AuthenticatedHistorical(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/secret-test-key/history/USD/2025/1/2", r.URL.Path)
+		w.Write([]byte(`{"result":"success","base_code":"USD","conversion_rates":{"INR":85.1}}`))
+	}))
+	defer server.Close()
+
+	creds := NewCredentials()
+	creds.SetKey(ProviderExchangeRateAPI, "secret-test-key")
+
+	cfg := DefaultConfig()
+	cfg.AuthenticatedBaseURL = server.URL
+	cfg.Credentials = creds
+	client := NewExchangeRateClientWithConfig(cfg)
+
+	assert.True(t, client.HasHistoricalData())
+	rate, err := client.GetHistoricalRateForPair(context.Background(), "USD", "INR", "2025-01-02")
+	require.NoError(t, err)
+	assert.Equal(t, 85.1, rate)
+}
+
+func TestExchangeRateClient_RedactsKeyInErrors(t *testing.T) {
+	creds := NewCredentials()
+	creds.SetKey(ProviderExchangeRateAPI, "secret-test-key")
+
+	cfg := DefaultConfig()
+	cfg.AuthenticatedBaseURL = "http://127.0.0.1:1"
+	cfg.Credentials = creds
+	cfg.Retry.MaxAttempts = 1
+	client := NewExchangeRateClientWithConfig(cfg)
+
+	_, err := client.GetLatestRates(context.Background(), "USD")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret-test-key")
+	assert.Contains(t, err.Error(), MaskKey("secret-test-key"))
+}
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, "https://example.com/v6/"+MaskKey("old-secret-key")+"/latest/USD",
+		redact("https://example.com/v6/old-secret-key/latest/USD", "old-secret-key"))
+	assert.Equal(t, "https://example.com/latest?access_key="+MaskKey("a%2Bsecret%2Fkey"),
+		redact("https://example.com/latest?access_key=a%2Bsecret%2Fkey", "a+secret/key"))
+	assert.Equal(t, "https://example.com/latest", redact("https://example.com/latest", ""))
+}
+
+func TestExchangeRateClient_HistoricalRequiresKey(t *testing.T) {
+	client := NewExchangeRateClient()
+	assert.False(t, client.HasHistoricalData())
+
+	_, err := client.GetHistoricalRates(context.Background(), "USD", "2025-01-02")
+	assert.Error(t, err)
+}
+
+func TestExchangeRateClient_Probe(t *testing.T) {
+	var status int32 = http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, 3)
+	assert.NoError(t, client.Probe())
+
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	assert.Error(t, client.Probe())
+	assert.Equal(t, int64(0), client.GetStats()["attempts"], "probes bypass request stats")
+
+	server.Close()
+	assert.Error(t, client.Probe())
+}
+
+func TestExchangeRateClient_ConditionalRequests(t *testing.T) {
+	published := time.Date(2025, 1, 6, 0, 0, 1, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		conditional bool
+		etag        string
+		wantHeader  string // Conditional header expected on the second request
+		wantValue   string
+	}{
+		{"etag", true, `"v1"`, "If-None-Match", `"v1"`},
+		{"publication time", true, "", "If-Modified-Since", published.Format(http.TimeFormat)},
+		{"disabled", false, `"v1"`, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			var header, value string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) == 2 {
+					for _, name := range []string{"If-None-Match", "If-Modified-Since"} {
+						if v := r.Header.Get(name); v != "" {
+							header, value = name, v
+						}
+					}
+					if header != "" {
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+				}
+				if tt.etag != "" {
+					w.Header().Set("ETag", tt.etag)
+				}
+				fmt.Fprintf(w, `{"base":"USD","time_last_updated":%d,"rates":{"INR":83.5}}`, published.Unix())
+			}))
+			defer server.Close()
+
+			cfg := DefaultConfig()
+			cfg.BaseURL = server.URL
+			cfg.ConditionalRequests = tt.conditional
+			client := NewExchangeRateClientWithConfig(cfg)
+
+			for i := 0; i < 2; i++ {
+				response, err := client.GetLatestRates(context.Background(), "USD")
+				require.NoError(t, err)
+				assert.Equal(t, 83.5, response.Rates["INR"])
+				assert.Equal(t, published.Unix(), response.TimeLastUpdated)
+			}
+			assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+			assert.Equal(t, tt.wantHeader, header)
+			assert.Equal(t, tt.wantValue, value)
+
+			notModified := int64(0)
+			if tt.conditional {
+				notModified = 1
+			}
+			assert.Equal(t, notModified, client.GetStats()["not_modified"])
+		})
+	}
+}
+
+func TestExchangeRateClient_SkipsUntilNextUpdate(t *testing.T) {
+	var calls int32
+	next := time.Now().Add(time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		fmt.Fprintf(w, `{"result":"success","base_code":"USD","time_last_update_unix":%d,"time_next_update_unix":%d,"conversion_rates":{"INR":83.5}}`,
+			next.Add(-24*time.Hour).Unix(), next.Unix())
+	}))
+	defer server.Close()
+
+	creds := NewCredentials()
+	creds.SetKey(ProviderExchangeRateAPI, "erapi-test-key")
+	cfg := DefaultConfig()
+	cfg.AuthenticatedBaseURL = server.URL
+	cfg.Credentials = creds
+	client := NewExchangeRateClientWithConfig(cfg)
+
+	for i := 0; i < 3; i++ {
+		response, err := client.GetLatestRates(context.Background(), "USD")
+		require.NoError(t, err)
+		assert.Equal(t, 83.5, response.Rates["INR"])
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "nothing is requested before the announced update")
+	assert.Equal(t, int64(2), client.GetStats()["skipped"])
+
+	// Historical tables are never served from the latest-rates store
+	_, err := client.GetHistoricalRates(context.Background(), "USD", "2025-01-06")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
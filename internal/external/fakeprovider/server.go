@@ -0,0 +1,397 @@
+// Package fakeprovider is an httptest server emulating the exchangerate-api.com
+// (free v4 and keyed v6) and frankfurter.app endpoints the client calls, for
+// tests that run the handlers, services and client end to end without the
+// network, and for the server's sandbox mode:
+//
+//	provider := fakeprovider.New()
+//	defer provider.Close()
+//	client := external.NewExchangeRateClientWithConfig(provider.Config())
+//
+// Rates come from fixtures quoted against one base currency; any other base
+// is answered with cross rates. Latency and failures can be injected, and
+// every request is recorded.
+package fakeprovider
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/external"
+)
+
+// APIKey is the exchangerate-api.com key Config sets, enabling historical
+// rates
+const APIKey = "fake-key"
+
+//go:embed fixtures.json
+var defaultFixtures []byte
+
+// Fixtures are the rates the server quotes, every table against Base. A
+// date without a History table is answered with the Latest one.
+type Fixtures struct {
+	Base    string                        `json:"base"`
+	Latest  map[string]float64            `json:"latest"`
+	History map[string]map[string]float64 `json:"history"` // date -> quote -> rate
+}
+
+// DefaultFixtures returns the fixtures a new Server starts with: USD rates
+// of the default currencies plus CHF and BRL
+func DefaultFixtures() Fixtures {
+	var fixtures Fixtures
+	if err := json.Unmarshal(defaultFixtures, &fixtures); err != nil {
+		panic(fmt.Sprintf("fakeprovider: invalid default fixtures: %v", err))
+	}
+	return fixtures
+}
+
+// LoadFixtures reads fixtures from a JSON file in the format of
+// DefaultFixtures. The base must be quoted, directly or as 1, and at least
+// one other currency.
+func LoadFixtures(path string) (Fixtures, error) {
+	var fixtures Fixtures
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fixtures, err
+	}
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return fixtures, fmt.Errorf("invalid fixtures %s: %w", path, err)
+	}
+
+	fixtures.Base = strings.ToUpper(strings.TrimSpace(fixtures.Base))
+	if fixtures.Base == "" {
+		return fixtures, fmt.Errorf("invalid fixtures %s: missing base", path)
+	}
+	if len(fixtures.Latest) == 0 {
+		return fixtures, fmt.Errorf("invalid fixtures %s: no latest rates", path)
+	}
+	for date, rates := range fixtures.History {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return fixtures, fmt.Errorf("invalid fixtures %s: history date %q is not YYYY-MM-DD", path, date)
+		}
+		for code, rate := range rates {
+			if rate <= 0 {
+				return fixtures, fmt.Errorf("invalid fixtures %s: rate of %s on %s must be positive", path, code, date)
+			}
+		}
+	}
+	for code, rate := range fixtures.Latest {
+		if rate <= 0 {
+			return fixtures, fmt.Errorf("invalid fixtures %s: rate of %s must be positive", path, code)
+		}
+	}
+	return fixtures, nil
+}
+
+// Server is a running fake provider. Its methods are safe to call while it
+// serves requests.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	fixtures  Fixtures
+	published time.Time     // When the latest rates were published
+	latency   time.Duration // Delay before every answer
+	failures  []int         // Statuses the next requests fail with, in order
+	requests  []string
+}
+
+// New starts a fake provider serving DefaultFixtures
+func New() *Server {
+	s := &Server{
+		fixtures:  DefaultFixtures(),
+		published: time.Now().UTC().Truncate(time.Second),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Config returns a client configuration with every builtin provider
+// pointing at the server: exchangerate-api.com, keyed with APIKey, and
+// frankfurter.app. Retries back off briefly so failure tests stay fast.
+func (s *Server) Config() external.Config {
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = s.URL + "/erapi/v4"
+	cfg.AuthenticatedBaseURL = s.URL + "/erapi/v6"
+	cfg.FrankfurterBaseURL = s.URL + "/frankfurter"
+	cfg.Credentials = external.NewCredentials()
+	cfg.Credentials.SetKey(external.ProviderExchangeRateAPI, APIKey)
+	cfg.Retry.InitialBackoff = time.Millisecond
+	cfg.Retry.MaxBackoff = 5 * time.Millisecond
+	return cfg
+}
+
+// SetFixtures replaces every rate the server quotes
+func (s *Server) SetFixtures(fixtures Fixtures) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures = fixtures
+}
+
+// SetLatest replaces the latest rates, quoted against the fixtures' base,
+// and marks them published now
+func (s *Server) SetLatest(rates map[string]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures.Latest = rates
+	s.published = time.Now().UTC().Truncate(time.Second)
+}
+
+// SetHistory sets the rates of date, quoted against the fixtures' base
+func (s *Server) SetHistory(date string, rates map[string]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fixtures.History == nil {
+		s.fixtures.History = make(map[string]map[string]float64)
+	}
+	s.fixtures.History[date] = rates
+}
+
+// SetLatency delays every answer by latency, or until the client gives up
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = latency
+}
+
+// FailNext makes the next n requests fail with status
+func (s *Server) FailNext(n, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, status)
+	}
+}
+
+// Requests returns the path and query of every request served, in order
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+// ResetRequests forgets the requests served so far
+func (s *Server) ResetRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.RequestURI())
+	latency := s.latency
+	failure := 0
+	if len(s.failures) > 0 {
+		failure, s.failures = s.failures[0], s.failures[1:]
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if failure != 0 {
+		writeJSON(w, failure, map[string]string{"error": http.StatusText(failure)})
+		return
+	}
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(segments) == 4 && segments[0] == "erapi" && segments[1] == "v4" && segments[2] == "latest":
+		s.serveFreeLatest(w, r, segments[3])
+	case len(segments) >= 5 && segments[0] == "erapi" && segments[1] == "v6":
+		s.serveKeyed(w, r, segments[2], segments[3], segments[4:])
+	case len(segments) == 2 && segments[0] == "frankfurter":
+		s.serveFrankfurter(w, r, segments[1], r.URL.Query().Get("from"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveFreeLatest answers GET /erapi/v4/latest/{base} like the free v4 API,
+// honouring If-Modified-Since
+func (s *Server) serveFreeLatest(w http.ResponseWriter, r *http.Request, base string) {
+	rates, published, ok := s.table(base, "")
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"result": "error", "error-type": "unsupported-code"})
+		return
+	}
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !published.After(since) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Last-Modified", published.Format(http.TimeFormat))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"base":              base,
+		"date":              published.Format("2006-01-02"),
+		"time_last_updated": published.Unix(),
+		"rates":             rates,
+	})
+}
+
+// serveKeyed answers the keyed v6 API: /erapi/v6/{key}/latest/{base} and
+// /erapi/v6/{key}/history/{base}/{year}/{month}/{day}
+func (s *Server) serveKeyed(w http.ResponseWriter, r *http.Request, key, endpoint string, args []string) {
+	if key != APIKey {
+		writeJSON(w, http.StatusForbidden, map[string]string{"result": "error", "error-type": "invalid-key"})
+		return
+	}
+
+	var date string
+	switch {
+	case endpoint == "latest" && len(args) == 1:
+	case endpoint == "history" && len(args) == 4:
+		year, errY := strconv.Atoi(args[1])
+		month, errM := strconv.Atoi(args[2])
+		day, errD := strconv.Atoi(args[3])
+		if errY != nil || errM != nil || errD != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"result": "error", "error-type": "malformed-request"})
+			return
+		}
+		date = fmt.Sprintf("%04d-%02d-%02d", year, month, day)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"result": "error", "error-type": "malformed-request"})
+		return
+	}
+
+	rates, published, ok := s.table(args[0], date)
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]string{"result": "error", "error-type": "unsupported-code"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"result":                "success",
+		"base_code":             args[0],
+		"time_last_update_unix": published.Unix(),
+		"conversion_rates":      rates,
+	})
+}
+
+// serveFrankfurter answers /frankfurter/latest, /frankfurter/{date} and the
+// time series /frankfurter/{start}..{end}, all with ?from={base}. Like
+// frankfurter.app, weekends are answered with the previous Friday's rates.
+func (s *Server) serveFrankfurter(w http.ResponseWriter, r *http.Request, path, base string) {
+	if base == "" {
+		base = "EUR"
+	}
+	notFound := map[string]string{"message": "not found"}
+
+	if start, end, found := strings.Cut(path, ".."); found {
+		from, errStart := time.Parse("2006-01-02", start)
+		to, errEnd := time.Parse("2006-01-02", end)
+		if errStart != nil || errEnd != nil || to.Before(from) {
+			writeJSON(w, http.StatusNotFound, notFound)
+			return
+		}
+		series := make(map[string]map[string]float64)
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			if weekday := day.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+				continue
+			}
+			date := day.Format("2006-01-02")
+			rates, _, ok := s.table(base, date)
+			if !ok {
+				writeJSON(w, http.StatusNotFound, notFound)
+				return
+			}
+			series[date] = withoutBase(rates, base)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"amount": 1, "base": base, "start_date": start, "end_date": end, "rates": series,
+		})
+		return
+	}
+
+	var date string
+	if path != "latest" {
+		day, err := time.Parse("2006-01-02", path)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, notFound)
+			return
+		}
+		for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			day = day.AddDate(0, 0, -1)
+		}
+		date = day.Format("2006-01-02")
+	}
+
+	rates, published, ok := s.table(base, date)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, notFound)
+		return
+	}
+	if date == "" {
+		date = published.Format("2006-01-02")
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"amount": 1, "base": base, "date": date, "rates": withoutBase(rates, base),
+	})
+}
+
+// table returns the rates of base on date, the latest ones when date is
+// empty, and when they were published
+func (s *Server) table(base, date string) (map[string]float64, time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quoted, published := s.fixtures.Latest, s.published
+	if date != "" {
+		day, err := time.Parse("2006-01-02", date)
+		if err != nil || day.After(time.Now()) {
+			return nil, time.Time{}, false
+		}
+		published = day
+		if history, ok := s.fixtures.History[date]; ok {
+			quoted = history
+		}
+	}
+
+	against := func(code string) (float64, bool) {
+		if code == s.fixtures.Base {
+			return 1, true
+		}
+		rate, ok := quoted[code]
+		return rate, ok && rate != 0
+	}
+	baseRate, ok := against(base)
+	if !ok {
+		return nil, time.Time{}, false
+	}
+
+	rates := map[string]float64{s.fixtures.Base: 1 / baseRate}
+	for code := range quoted {
+		if rate, ok := against(code); ok {
+			rates[code] = rate / baseRate
+		}
+	}
+	rates[base] = 1
+	return rates, published, true
+}
+
+// withoutBase drops the base from rates, which frankfurter.app doesn't quote
+func withoutBase(rates map[string]float64, base string) map[string]float64 {
+	quotes := make(map[string]float64, len(rates))
+	for code, rate := range rates {
+		if code != base {
+			quotes[code] = rate
+		}
+	}
+	return quotes
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
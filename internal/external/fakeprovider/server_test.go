@@ -0,0 +1,132 @@
+package fakeprovider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/external"
+)
+
+func TestServer_QuotesEveryBase(t *testing.T) {
+	provider := New()
+	defer provider.Close()
+	client := external.NewExchangeRateClientWithConfig(provider.Config())
+
+	tests := []struct {
+		name     string
+		provider string
+		base     string
+		quote    string
+		rate     float64
+	}{
+		{"fixture base", external.ProviderExchangeRateAPI, "USD", "INR", 83.125},
+		{"cross rate", external.ProviderExchangeRateAPI, "EUR", "INR", 83.125 / 0.92},
+		{"inverse", external.ProviderExchangeRateAPI, "INR", "USD", 1 / 83.125},
+		{"frankfurter", external.ProviderFrankfurter, "GBP", "EUR", 0.92 / 0.79},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.GetLatestRatesFrom(context.Background(), tt.provider, tt.base)
+			require.NoError(t, err)
+			assert.Equal(t, tt.provider, resp.Provider)
+			assert.InDelta(t, tt.rate, resp.Rates[tt.quote], 1e-9)
+		})
+	}
+
+	_, err := client.GetLatestRatesFrom(context.Background(), external.ProviderExchangeRateAPI, "XYZ")
+	assert.ErrorIs(t, err, external.ErrRateNotFound)
+}
+
+func TestServer_History(t *testing.T) {
+	provider := New()
+	defer provider.Close()
+	provider.SetHistory("2025-01-03", map[string]float64{"INR": 85.5, "EUR": 0.97})
+	client := external.NewExchangeRateClientWithConfig(provider.Config())
+
+	resp, err := client.GetHistoricalRatesFrom(context.Background(), external.ProviderExchangeRateAPI, "USD", "2025-01-03")
+	require.NoError(t, err)
+	assert.Equal(t, 85.5, resp.Rates["INR"])
+
+	// frankfurter.app answers a Saturday with Friday's rates
+	resp, err = client.GetHistoricalRatesFrom(context.Background(), external.ProviderFrankfurter, "USD", "2025-01-04")
+	require.NoError(t, err)
+	assert.Equal(t, "2025-01-03", resp.Date)
+	assert.Equal(t, 85.5, resp.Rates["INR"])
+
+	resp, err = client.GetHistoricalRatesFrom(context.Background(), external.ProviderExchangeRateAPI, "USD", "2025-01-06")
+	require.NoError(t, err)
+	assert.Equal(t, 83.125, resp.Rates["INR"], "dates without fixtures get the latest rates")
+
+	httpResp, err := http.Get(provider.URL + "/frankfurter/2025-01-02..2025-01-06?from=USD")
+	require.NoError(t, err)
+	defer httpResp.Body.Close()
+	var series struct {
+		Rates map[string]map[string]float64 `json:"rates"`
+	}
+	require.NoError(t, json.NewDecoder(httpResp.Body).Decode(&series))
+	assert.Len(t, series.Rates, 3, "the time series skips the weekend")
+	assert.Equal(t, 85.5, series.Rates["2025-01-03"]["INR"])
+}
+
+func TestServer_FailuresAndLatency(t *testing.T) {
+	provider := New()
+	defer provider.Close()
+	cfg := provider.Config()
+	cfg.Timeout = 50 * time.Millisecond
+	cfg.Retry.MaxAttempts = 2
+	client := external.NewExchangeRateClientWithConfig(cfg)
+
+	provider.FailNext(1, http.StatusServiceUnavailable)
+	_, err := client.GetLatestRates(context.Background(), "USD")
+	require.NoError(t, err, "a single failure is retried")
+	assert.Len(t, provider.Requests(), 2)
+
+	provider.ResetRequests()
+	provider.FailNext(2, http.StatusBadGateway)
+	_, err = client.GetLatestRates(context.Background(), "EUR")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"/erapi/v6/fake-key/latest/EUR", "/erapi/v6/fake-key/latest/EUR"}, provider.Requests())
+
+	provider.SetLatency(time.Second)
+	start := time.Now()
+	_, err = client.GetLatestRates(context.Background(), "GBP")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second, "the client's timeout cuts the latency short")
+}
+
+func TestLoadFixtures(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	fixtures, err := LoadFixtures(write("valid.json", `{"base":"eur","latest":{"USD":1.1},"history":{"2025-01-03":{"USD":1.02}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", fixtures.Base)
+	assert.Equal(t, 1.02, fixtures.History["2025-01-03"]["USD"])
+
+	for name, content := range map[string]string{
+		"malformed.json":   `{"base":`,
+		"no-base.json":     `{"latest":{"USD":1.1}}`,
+		"no-latest.json":   `{"base":"EUR"}`,
+		"bad-rate.json":    `{"base":"EUR","latest":{"USD":0}}`,
+		"bad-date.json":    `{"base":"EUR","latest":{"USD":1.1},"history":{"03/01/2025":{"USD":1.02}}}`,
+		"bad-history.json": `{"base":"EUR","latest":{"USD":1.1},"history":{"2025-01-03":{"USD":-1}}}`,
+	} {
+		_, err := LoadFixtures(write(name, content))
+		assert.Error(t, err, name)
+	}
+	_, err = LoadFixtures(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
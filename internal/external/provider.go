@@ -0,0 +1,114 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"exchange-rate-service/internal/apperrors"
+	"exchange-rate-service/internal/models"
+)
+
+// ErrUnknownProvider is returned when a request names a provider the client
+// does not know
+var ErrUnknownProvider = apperrors.ErrUnknownProvider
+
+// Provider is an upstream source of exchange rates. Implementations fetch
+// through the client, so every provider is throttled, retried and watched by
+// its own circuit breaker.
+type Provider interface {
+	// Name identifies the provider in requests, responses and stats
+	Name() string
+	// LatestRates returns the current rates of every currency against base
+	LatestRates(ctx context.Context, base string) (*models.ExternalAPIResponse, error)
+	// HistoricalRates returns the rates against base published for date
+	HistoricalRates(ctx context.Context, base, date string) (*models.ExternalAPIResponse, error)
+	// HasHistoricalData reports whether HistoricalRates can succeed
+	HasHistoricalData() bool
+	// ProbeURL is requested to check that the provider is reachable; empty
+	// skips the check
+	ProbeURL() string
+}
+
+// BuiltinProviders lists the providers every client can fetch from, in the
+// order they are registered
+var BuiltinProviders = []string{ProviderExchangeRateAPI, ProviderFrankfurter, ProviderFixer}
+
+// IsBuiltinProvider reports whether name, once canonical, is a builtin
+// provider
+func IsBuiltinProvider(name string) bool {
+	name = CanonicalProvider(name)
+	for _, provider := range BuiltinProviders {
+		if provider == name {
+			return true
+		}
+	}
+	return false
+}
+
+// providerAliases maps short names accepted in requests to provider names
+var providerAliases = map[string]string{
+	"erapi": ProviderExchangeRateAPI,
+}
+
+// CanonicalProvider lower-cases a provider name and resolves its aliases,
+// e.g. "ERAPI" becomes "exchangerate-api"
+func CanonicalProvider(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if canonical, ok := providerAliases[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+// Provider returns the provider called name, or the default provider when
+// name is empty
+func (c *ExchangeRateClient) Provider(name string) (Provider, error) {
+	if name == "" {
+		return c.providers[c.DefaultProvider()], nil
+	}
+	provider, ok := c.providers[CanonicalProvider(name)]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, expected one of %s", ErrUnknownProvider, name, strings.Join(c.Providers(), ", "))
+	}
+	return provider, nil
+}
+
+// Providers returns the names of the providers requests can be pinned to
+func (c *ExchangeRateClient) Providers() []string {
+	return append([]string(nil), c.providerOrder...)
+}
+
+// ConfiguredProviders returns the names of the providers that have the
+// credentials they need, in the order of Providers
+func (c *ExchangeRateClient) ConfiguredProviders() []string {
+	var names []string
+	for _, name := range c.providerOrder {
+		if p, ok := c.providers[name].(interface{ Configured() bool }); ok && !p.Configured() {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// DefaultProvider returns the name of the provider used when a request does
+// not pick one
+func (c *ExchangeRateClient) DefaultProvider() string {
+	c.defaultMu.RLock()
+	defer c.defaultMu.RUnlock()
+	return c.defaultProvider
+}
+
+// SetDefaultProvider makes the provider called name serve the requests that
+// don't pick one
+func (c *ExchangeRateClient) SetDefaultProvider(name string) error {
+	name = CanonicalProvider(name)
+	if _, ok := c.providers[name]; !ok {
+		return fmt.Errorf("%w %q, expected one of %s", ErrUnknownProvider, name, strings.Join(c.Providers(), ", "))
+	}
+	c.defaultMu.Lock()
+	defer c.defaultMu.Unlock()
+	c.defaultProvider = name
+	return nil
+}
@@ -0,0 +1,153 @@
+package external
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/models"
+)
+
+// monitorBuckets is the number of one-minute buckets, so statistics cover the
+// last hour
+const monitorBuckets = 60
+
+type minuteBucket struct {
+	minute   int64 // Unix minute the counters belong to
+	requests int64
+	errors   int64
+	latency  time.Duration
+}
+
+type providerHealth struct {
+	breaker     *CircuitBreaker
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastError   string
+	buckets     [monitorBuckets]minuteBucket
+}
+
+// ProviderMonitor records the outcome and latency of every upstream request
+// per provider and guards each provider with a circuit breaker
+type ProviderMonitor struct {
+	cfg CircuitBreakerConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	providers map[string]*providerHealth
+}
+
+func NewProviderMonitor(cfg CircuitBreakerConfig) *ProviderMonitor {
+	return &ProviderMonitor{
+		cfg:       cfg,
+		now:       time.Now,
+		providers: make(map[string]*providerHealth),
+	}
+}
+
+// provider returns the health record of name, creating it on first use. The
+// caller must hold the lock.
+func (m *ProviderMonitor) provider(name string) *providerHealth {
+	health, ok := m.providers[name]
+	if !ok {
+		breaker := NewCircuitBreaker(m.cfg)
+		breaker.now = m.now
+		health = &providerHealth{breaker: breaker}
+		m.providers[name] = health
+	}
+	return health
+}
+
+// Register makes provider show up in Status before its first request
+func (m *ProviderMonitor) Register(provider string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.provider(provider)
+}
+
+// Allow returns ErrCircuitOpen while provider's circuit breaker is open
+func (m *ProviderMonitor) Allow(provider string) error {
+	m.mu.Lock()
+	breaker := m.provider(provider).breaker
+	m.mu.Unlock()
+	return breaker.Allow()
+}
+
+// Record stores the outcome of a request. Only transient failures count
+// against the circuit breaker: a provider that answers a bad request with a
+// client error is still healthy.
+func (m *ProviderMonitor) Record(provider string, latency time.Duration, err error, transient bool) {
+	now := m.now()
+	minute := now.Unix() / 60
+
+	m.mu.Lock()
+	health := m.provider(provider)
+	bucket := &health.buckets[minute%monitorBuckets]
+	if bucket.minute != minute {
+		*bucket = minuteBucket{minute: minute}
+	}
+	bucket.requests++
+	bucket.latency += latency
+	if err != nil {
+		bucket.errors++
+		health.lastFailure = now
+		health.lastError = err.Error()
+	} else {
+		health.lastSuccess = now
+	}
+	breaker := health.breaker
+	m.mu.Unlock()
+
+	breaker.Record(err == nil || !transient)
+}
+
+// Status returns the status of every provider, sorted by name
+func (m *ProviderMonitor) Status() []models.ProviderStatus {
+	now := m.now()
+	minute := now.Unix() / 60
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]models.ProviderStatus, 0, len(m.providers))
+	for name, health := range m.providers {
+		status := models.ProviderStatus{
+			Provider:  name,
+			LastError: health.lastError,
+		}
+
+		var latency time.Duration
+		for _, bucket := range health.buckets {
+			if minute-bucket.minute < monitorBuckets {
+				status.Requests += bucket.requests
+				status.Errors += bucket.errors
+				latency += bucket.latency
+			}
+		}
+		if status.Requests > 0 {
+			status.ErrorRate = float64(status.Errors) / float64(status.Requests)
+			status.AvgLatencyMs = float64(latency.Microseconds()) / 1000 / float64(status.Requests)
+		}
+
+		if !health.lastSuccess.IsZero() {
+			lastSuccess := health.lastSuccess
+			status.LastSuccess = &lastSuccess
+		}
+		if !health.lastFailure.IsZero() {
+			lastFailure := health.lastFailure
+			status.LastFailure = &lastFailure
+		}
+
+		state, failures, retryAt := health.breaker.State()
+		status.CircuitState = state
+		status.ConsecutiveFailures = failures
+		if !retryAt.IsZero() {
+			status.CircuitRetryAt = &retryAt
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Provider < statuses[j].Provider })
+	return statuses
+}
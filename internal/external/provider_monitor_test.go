@@ -0,0 +1,85 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderMonitor_Status(t *testing.T) {
+	now := time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)
+	monitor := NewProviderMonitor(DefaultCircuitBreakerConfig())
+	monitor.now = func() time.Time { return now }
+	monitor.Register("fixer")
+
+	monitor.Record(ProviderExchangeRateAPI, 100*time.Millisecond, nil, false)
+	now = now.Add(30 * time.Minute)
+	monitor.Record(ProviderExchangeRateAPI, 300*time.Millisecond, errors.New("API returned status code: 503"), true)
+
+	statuses := monitor.Status()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, ProviderExchangeRateAPI, statuses[0].Provider)
+	assert.Equal(t, "fixer", statuses[1].Provider)
+	assert.Nil(t, statuses[1].LastSuccess, "registered providers are listed before their first request")
+
+	status := statuses[0]
+	assert.Equal(t, int64(2), status.Requests)
+	assert.Equal(t, int64(1), status.Errors)
+	assert.Equal(t, 0.5, status.ErrorRate)
+	assert.Equal(t, 200.0, status.AvgLatencyMs)
+	assert.Equal(t, "API returned status code: 503", status.LastError)
+	assert.Equal(t, CircuitClosed, status.CircuitState)
+	assert.Equal(t, 1, status.ConsecutiveFailures)
+
+	// The first request falls out of the one hour window
+	now = now.Add(45 * time.Minute)
+	status = monitor.Status()[0]
+	assert.Equal(t, int64(1), status.Requests)
+	assert.Equal(t, 1.0, status.ErrorRate)
+	require.NotNil(t, status.LastSuccess)
+}
+
+func TestProviderMonitor_ClientErrorsKeepCircuitClosed(t *testing.T) {
+	monitor := NewProviderMonitor(CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})
+
+	monitor.Record(ProviderExchangeRateAPI, time.Millisecond, errors.New("API returned status code: 404"), false)
+	assert.NoError(t, monitor.Allow(ProviderExchangeRateAPI))
+
+	monitor.Record(ProviderExchangeRateAPI, time.Millisecond, errors.New("API returned status code: 503"), true)
+	assert.ErrorIs(t, monitor.Allow(ProviderExchangeRateAPI), ErrCircuitOpen)
+}
+
+func TestExchangeRateClient_CircuitBreakerFailsFast(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.BaseURL = server.URL
+	cfg.Retry.MaxAttempts = 1
+	cfg.CircuitBreaker = CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute}
+	client := NewExchangeRateClientWithConfig(cfg)
+
+	for i := 0; i < 2; i++ {
+		_, err := client.GetRateForPair(context.Background(), "USD", "INR")
+		require.Error(t, err)
+	}
+	_, err := client.GetRateForPair(context.Background(), "USD", "INR")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls), "the provider is not called while the circuit is open")
+
+	status := client.ProviderStatus()[0]
+	assert.Equal(t, CircuitOpen, status.CircuitState)
+	assert.NotNil(t, status.CircuitRetryAt)
+	assert.Equal(t, int64(2), status.Errors)
+}
@@ -0,0 +1,136 @@
+package external
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExchangeRateClient_Provider(t *testing.T) {
+	client := NewExchangeRateClient()
+
+	tests := []struct {
+		name     string
+		expected string
+		wantErr  bool
+	}{
+		{"", ProviderExchangeRateAPI, false},
+		{"erapi", ProviderExchangeRateAPI, false},
+		{"Frankfurter", ProviderFrankfurter, false},
+		{"fixer", ProviderFixer, false},
+		{"bank-of-nowhere", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := client.Provider(tt.name)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrUnknownProvider)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, provider.Name())
+		})
+	}
+
+	assert.Equal(t, []string{ProviderExchangeRateAPI, ProviderFrankfurter, ProviderFixer}, client.Providers())
+}
+
+func TestExchangeRateClient_DefaultProvider(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DefaultProvider = "frankfurter"
+	client := NewExchangeRateClientWithConfig(cfg)
+	assert.Equal(t, ProviderFrankfurter, client.DefaultProvider())
+	assert.True(t, client.HasHistoricalData(), "frankfurter serves historical rates without a key")
+
+	cfg.DefaultProvider = "unknown"
+	assert.Equal(t, ProviderExchangeRateAPI, NewExchangeRateClientWithConfig(cfg).DefaultProvider())
+
+	require.NoError(t, client.SetDefaultProvider("ERAPI"))
+	assert.Equal(t, ProviderExchangeRateAPI, client.DefaultProvider())
+	err := client.SetDefaultProvider("unknown")
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+	assert.Equal(t, ProviderExchangeRateAPI, client.DefaultProvider(), "an unknown provider keeps the previous one")
+}
+
+func TestFrankfurter_Rates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "USD", r.URL.Query().Get("from"))
+		switch r.URL.Path {
+		case "/latest":
+			w.Write([]byte(`{"amount":1.0,"base":"USD","date":"2025-01-03","rates":{"INR":85.7}}`))
+		case "/2025-01-02":
+			w.Write([]byte(`{"amount":1.0,"base":"USD","date":"2025-01-02","rates":{"INR":85.5}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.FrankfurterBaseURL = server.URL
+	client := NewExchangeRateClientWithConfig(cfg)
+
+	latest, err := client.GetLatestRatesFrom(context.Background(), "frankfurter", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, ProviderFrankfurter, latest.Provider)
+	assert.Equal(t, 85.7, latest.Rates["INR"])
+	assert.Equal(t, time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC).Unix(), latest.TimeLastUpdated)
+
+	historical, err := client.GetHistoricalRatesFrom(context.Background(), "frankfurter", "USD", "2025-01-02")
+	require.NoError(t, err)
+	assert.Equal(t, 85.5, historical.Rates["INR"])
+}
+
+func TestFixer_Rates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "fixer-test-key", r.URL.Query().Get("access_key"))
+		if r.URL.Query().Get("base") == "XYZ" {
+			w.Write([]byte(`{"success":false,"error":{"code":201,"type":"invalid_base_currency"}}`))
+			return
+		}
+		w.Write([]byte(`{"success":true,"timestamp":1735819200,"base":"USD","date":"2025-01-02","rates":{"INR":85.4}}`))
+	}))
+	defer server.Close()
+
+	creds := NewCredentials()
+	creds.SetKey(ProviderFixer, "fixer-test-key")
+	cfg := DefaultConfig()
+	cfg.FixerBaseURL = server.URL
+	cfg.Credentials = creds
+	client := NewExchangeRateClientWithConfig(cfg)
+
+	latest, err := client.GetLatestRatesFrom(context.Background(), "fixer", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, ProviderFixer, latest.Provider)
+	assert.Equal(t, int64(1735819200), latest.TimeLastUpdated)
+	assert.Equal(t, 85.4, latest.Rates["INR"])
+
+	_, err = client.GetLatestRatesFrom(context.Background(), "fixer", "XYZ")
+	assert.ErrorIs(t, err, ErrRateNotFound)
+}
+
+func TestFixer_RequiresKey(t *testing.T) {
+	_, err := NewExchangeRateClient().GetLatestRatesFrom(context.Background(), "fixer", "USD")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "FIXER_API_KEY")
+	assert.Equal(t, []string{ProviderExchangeRateAPI, ProviderFrankfurter}, NewExchangeRateClient().ConfiguredProviders())
+}
+
+func TestFixer_RedactsKeyInErrors(t *testing.T) {
+	creds := NewCredentials()
+	creds.SetKey(ProviderFixer, "fixer-test-key")
+	cfg := DefaultConfig()
+	cfg.FixerBaseURL = "http://127.0.0.1:1"
+	cfg.Credentials = creds
+	cfg.Retry.MaxAttempts = 1
+
+	_, err := NewExchangeRateClientWithConfig(cfg).GetLatestRatesFrom(context.Background(), "fixer", "USD")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "fixer-test-key")
+}
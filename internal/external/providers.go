@@ -0,0 +1,308 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"exchange-rate-service/internal/models"
+)
+
+const (
+	// FrankfurterBaseURL serves the European Central Bank reference rates
+	FrankfurterBaseURL = "https://api.frankfurter.app"
+	FixerBaseURL       = "https://data.fixer.io/api"
+)
+
+// Payload schemas of the builtin providers. A field missing from a schema
+// is logged, or rejected in strict mode, when a provider starts sending it.
+var (
+	erapiV4Schema = payloadSchema{
+		provider: ProviderExchangeRateAPI,
+		version:  "v4",
+		fields:   []string{"provider", "WARNING_UPGRADE_TO_V6", "terms", "base", "date", "time_last_updated", "rates"},
+		rates:    "rates",
+	}
+	erapiV6Schema = payloadSchema{
+		provider: ProviderExchangeRateAPI,
+		version:  "v6",
+		fields: []string{"result", "documentation", "terms_of_use", "terms-of-use", "error-type", "base_code",
+			"time_last_update_unix", "time_last_update_utc", "time_next_update_unix", "time_next_update_utc",
+			"year", "month", "day", "conversion_rates"},
+		rates: "conversion_rates",
+	}
+	frankfurterV1Schema = payloadSchema{
+		provider: ProviderFrankfurter,
+		version:  "v1",
+		fields:   []string{"amount", "base", "date", "start_date", "end_date", "rates"},
+		rates:    "rates",
+	}
+	fixerV1Schema = payloadSchema{
+		provider: ProviderFixer,
+		version:  "v1",
+		fields:   []string{"success", "timestamp", "historical", "base", "date", "rates", "error"},
+		rates:    "rates",
+	}
+)
+
+// exchangeRateAPI is exchangerate-api.com. Without a key it uses the free v4
+// API, which has no historical data; with one it uses the keyed v6 API.
+type exchangeRateAPI struct {
+	client      *ExchangeRateClient
+	baseURL     string
+	authBaseURL string
+}
+
+// authenticatedResponse is the payload of the keyed v6 API
+type authenticatedResponse struct {
+	Result             string             `json:"result"`
+	ErrorType          string             `json:"error-type"`
+	BaseCode           string             `json:"base_code"`
+	TimeLastUpdateUnix int64              `json:"time_last_update_unix"`
+	ConversionRates    map[string]float64 `json:"conversion_rates"`
+}
+
+func (p *exchangeRateAPI) Name() string {
+	return ProviderExchangeRateAPI
+}
+
+func (p *exchangeRateAPI) HasHistoricalData() bool {
+	return p.client.credentials.Key(ProviderExchangeRateAPI) != ""
+}
+
+func (p *exchangeRateAPI) ProbeURL() string {
+	return p.baseURL + LatestEndpoint + "/USD"
+}
+
+func (p *exchangeRateAPI) LatestRates(ctx context.Context, baseCurrency string) (*models.ExternalAPIResponse, error) {
+	if key := p.client.credentials.Key(ProviderExchangeRateAPI); key != "" {
+		endpoint := fmt.Sprintf("%s/%s%s/%s", p.authBaseURL, key, LatestEndpoint, baseCurrency)
+		apiResponse, err := p.getAuthenticated(ctx, endpoint, key, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch latest rates: %w", err)
+		}
+		return apiResponse, nil
+	}
+
+	endpoint := fmt.Sprintf("%s%s/%s", p.baseURL, LatestEndpoint, baseCurrency)
+
+	var apiResponse models.ExternalAPIResponse
+	if err := p.client.getPayload(ctx, erapiV4Schema, endpoint, "", true, &apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to fetch latest rates: %w", err)
+	}
+
+	if apiResponse.Rates == nil {
+		return nil, fmt.Errorf("%w: no rates received", ErrUnexpectedPayload)
+	}
+
+	apiResponse.Provider = ProviderExchangeRateAPI
+	return &apiResponse, nil
+}
+
+func (p *exchangeRateAPI) HistoricalRates(ctx context.Context, baseCurrency, date string) (*models.ExternalAPIResponse, error) {
+	key := p.client.credentials.Key(ProviderExchangeRateAPI)
+	if key == "" {
+		return nil, fmt.Errorf("%w - upgrade to paid tier for historical data", ErrNoHistoricalData)
+	}
+
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid historical date %q: %w", date, err)
+	}
+
+	endpoint := fmt.Sprintf("%s/%s%s/%s/%d/%d/%d", p.authBaseURL, key, HistoryEndpoint, baseCurrency,
+		day.Year(), int(day.Month()), day.Day())
+	apiResponse, err := p.getAuthenticated(ctx, endpoint, key, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch historical rates: %w", err)
+	}
+
+	apiResponse.Date = date
+	return apiResponse, nil
+}
+
+// getAuthenticated fetches a keyed v6 endpoint, the latest rates when
+// latest is set, and normalises the payload
+func (p *exchangeRateAPI) getAuthenticated(ctx context.Context, endpoint, key string, latest bool) (*models.ExternalAPIResponse, error) {
+	var payload authenticatedResponse
+	if err := p.client.getPayload(ctx, erapiV6Schema, endpoint, key, latest, &payload); err != nil {
+		return nil, err
+	}
+
+	if payload.ErrorType == "unsupported-code" {
+		return nil, fmt.Errorf("%w: provider does not support the currency code", ErrRateNotFound)
+	}
+	if payload.Result != "success" {
+		return nil, fmt.Errorf("API request was not successful: %s (key %s)", payload.ErrorType, MaskKey(key))
+	}
+	if payload.ConversionRates == nil {
+		return nil, fmt.Errorf("%w: no rates received", ErrUnexpectedPayload)
+	}
+
+	return &models.ExternalAPIResponse{
+		Provider:        ProviderExchangeRateAPI,
+		Base:            payload.BaseCode,
+		Date:            time.Unix(payload.TimeLastUpdateUnix, 0).UTC().Format("2006-01-02"),
+		TimeLastUpdated: payload.TimeLastUpdateUnix,
+		Rates:           payload.ConversionRates,
+	}, nil
+}
+
+// frankfurter serves the daily ECB reference rates. It needs no key and has
+// historical data, but publishes only a date, so rates are stamped with the
+// start of that day in UTC.
+type frankfurter struct {
+	client  *ExchangeRateClient
+	baseURL string
+}
+
+type frankfurterResponse struct {
+	Base  string             `json:"base"`
+	Date  string             `json:"date"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+func (p *frankfurter) Name() string {
+	return ProviderFrankfurter
+}
+
+func (p *frankfurter) HasHistoricalData() bool {
+	return true
+}
+
+func (p *frankfurter) ProbeURL() string {
+	return p.baseURL + "/latest?from=USD"
+}
+
+func (p *frankfurter) LatestRates(ctx context.Context, baseCurrency string) (*models.ExternalAPIResponse, error) {
+	apiResponse, err := p.get(ctx, "latest", baseCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest rates: %w", err)
+	}
+	return apiResponse, nil
+}
+
+func (p *frankfurter) HistoricalRates(ctx context.Context, baseCurrency, date string) (*models.ExternalAPIResponse, error) {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, fmt.Errorf("invalid historical date %q: %w", date, err)
+	}
+
+	apiResponse, err := p.get(ctx, date, baseCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch historical rates: %w", err)
+	}
+	return apiResponse, nil
+}
+
+func (p *frankfurter) get(ctx context.Context, path, baseCurrency string) (*models.ExternalAPIResponse, error) {
+	endpoint := fmt.Sprintf("%s/%s?from=%s", p.baseURL, path, url.QueryEscape(baseCurrency))
+
+	var payload frankfurterResponse
+	if err := p.client.getPayload(ctx, frankfurterV1Schema, endpoint, "", path == "latest", &payload); err != nil {
+		return nil, err
+	}
+	if payload.Rates == nil {
+		return nil, fmt.Errorf("%w: no rates received", ErrUnexpectedPayload)
+	}
+
+	var published int64
+	if day, err := time.Parse("2006-01-02", payload.Date); err == nil {
+		published = day.Unix()
+	}
+	return &models.ExternalAPIResponse{
+		Provider:        ProviderFrankfurter,
+		Base:            payload.Base,
+		Date:            payload.Date,
+		TimeLastUpdated: published,
+		Rates:           payload.Rates,
+	}, nil
+}
+
+// fixer is fixer.io, which requires FIXER_API_KEY
+type fixer struct {
+	client  *ExchangeRateClient
+	baseURL string
+}
+
+type fixerResponse struct {
+	Success   bool               `json:"success"`
+	Timestamp int64              `json:"timestamp"`
+	Base      string             `json:"base"`
+	Date      string             `json:"date"`
+	Rates     map[string]float64 `json:"rates"`
+	Error     struct {
+		Code int    `json:"code"`
+		Type string `json:"type"`
+	} `json:"error"`
+}
+
+func (p *fixer) Name() string {
+	return ProviderFixer
+}
+
+func (p *fixer) HasHistoricalData() bool {
+	return p.Configured()
+}
+
+// Configured reports whether FIXER_API_KEY is set
+func (p *fixer) Configured() bool {
+	return p.client.credentials.Key(ProviderFixer) != ""
+}
+
+func (p *fixer) ProbeURL() string {
+	return p.baseURL + "/latest"
+}
+
+func (p *fixer) LatestRates(ctx context.Context, baseCurrency string) (*models.ExternalAPIResponse, error) {
+	apiResponse, err := p.get(ctx, "latest", baseCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest rates: %w", err)
+	}
+	return apiResponse, nil
+}
+
+func (p *fixer) HistoricalRates(ctx context.Context, baseCurrency, date string) (*models.ExternalAPIResponse, error) {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, fmt.Errorf("invalid historical date %q: %w", date, err)
+	}
+
+	apiResponse, err := p.get(ctx, date, baseCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch historical rates: %w", err)
+	}
+	return apiResponse, nil
+}
+
+func (p *fixer) get(ctx context.Context, path, baseCurrency string) (*models.ExternalAPIResponse, error) {
+	key := p.client.credentials.Key(ProviderFixer)
+	if key == "" {
+		return nil, fmt.Errorf("provider %s requires FIXER_API_KEY", ProviderFixer)
+	}
+
+	endpoint := fmt.Sprintf("%s/%s?access_key=%s&base=%s", p.baseURL, path, url.QueryEscape(key), url.QueryEscape(baseCurrency))
+
+	var payload fixerResponse
+	if err := p.client.getPayload(ctx, fixerV1Schema, endpoint, key, path == "latest", &payload); err != nil {
+		return nil, err
+	}
+
+	if !payload.Success {
+		switch payload.Error.Type {
+		case "invalid_base_currency", "invalid_currency_codes":
+			return nil, fmt.Errorf("%w: provider does not support the currency code", ErrRateNotFound)
+		}
+		return nil, fmt.Errorf("API request was not successful: %s (key %s)", payload.Error.Type, MaskKey(key))
+	}
+	if payload.Rates == nil {
+		return nil, fmt.Errorf("%w: no rates received", ErrUnexpectedPayload)
+	}
+
+	return &models.ExternalAPIResponse{
+		Provider:        ProviderFixer,
+		Base:            payload.Base,
+		Date:            payload.Date,
+		TimeLastUpdated: payload.Timestamp,
+		Rates:           payload.Rates,
+	}, nil
+}
@@ -0,0 +1,49 @@
+package external
+
+import (
+	"fmt"
+	"strconv"
+
+	"exchange-rate-service/internal/models"
+)
+
+// rebaseDigits are the significant digits rebased rates are rounded to.
+// Dividing two quotes leaves float noise in the last bits, e.g. 0.88/1.1 is
+// 0.7999999999999999; providers quote at most 6 or 7 digits, so 12 drop the
+// noise without losing anything they published.
+const rebaseDigits = 12
+
+// Rebase converts a table of rates against table.Base into rates against
+// base: a currency's rate is its rate against the table's base divided by
+// base's, so table.Base itself gets 1/rate. Rates are always rebased from
+// the provider's own table, never from another rebased one, so errors don't
+// compound. The table is not modified. A base the table has no rate for is
+// ErrRateNotFound.
+func Rebase(table *models.ExternalAPIResponse, base string) (*models.ExternalAPIResponse, error) {
+	if base == table.Base {
+		return table, nil
+	}
+	pivot, ok := table.Rates[base]
+	if !ok || pivot <= 0 {
+		return nil, fmt.Errorf("%w: %s quotes no %s rate to rebase its %s table on", ErrRateNotFound, table.Provider, base, table.Base)
+	}
+
+	rebased := *table
+	rebased.Base = base
+	rebased.RebasedFrom = table.Base
+	rebased.Rates = make(map[string]float64, len(table.Rates)+1)
+	for currency, rate := range table.Rates {
+		rebased.Rates[currency] = roundSignificant(rate / pivot)
+	}
+	rebased.Rates[table.Base] = roundSignificant(1 / pivot)
+	rebased.Rates[base] = 1
+	return &rebased, nil
+}
+
+func roundSignificant(rate float64) float64 {
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(rate, 'g', rebaseDigits, 64), 64)
+	if err != nil {
+		return rate
+	}
+	return rounded
+}
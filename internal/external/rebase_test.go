@@ -0,0 +1,137 @@
+package external
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/models"
+)
+
+// ecbTable is a EUR table as the ECB publishes it, five significant digits
+func ecbTable() *models.ExternalAPIResponse {
+	return &models.ExternalAPIResponse{
+		Provider: ProviderFrankfurter,
+		Base:     "EUR",
+		Date:     "2025-01-02",
+		Rates: map[string]float64{
+			"USD": 1.1,
+			"GBP": 0.88,
+			"JPY": 162.34,
+			"INR": 89.1235,
+			"CHF": 0.9412,
+			"IDR": 16815.52,
+			"KWD": 0.31934,
+		},
+	}
+}
+
+func TestRebase(t *testing.T) {
+	table := ecbTable()
+
+	usd, err := Rebase(table, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", usd.Base)
+	assert.Equal(t, "EUR", usd.RebasedFrom)
+	assert.Equal(t, ProviderFrankfurter, usd.Provider)
+	assert.Equal(t, "2025-01-02", usd.Date)
+	assert.Equal(t, 1.0, usd.Rates["USD"])
+	assert.Equal(t, 0.909090909091, usd.Rates["EUR"])
+	assert.Equal(t, 0.8, usd.Rates["GBP"], "0.88/1.1 is 0.7999999999999999 before rounding")
+	assert.Equal(t, 147.581818182, usd.Rates["JPY"])
+
+	assert.Equal(t, 1.1, table.Rates["USD"], "the provider's table is left as it was")
+	assert.NotContains(t, table.Rates, "EUR")
+	assert.Empty(t, table.RebasedFrom)
+
+	same, err := Rebase(table, "EUR")
+	require.NoError(t, err)
+	assert.Same(t, table, same)
+
+	_, err = Rebase(table, "XAU")
+	assert.ErrorIs(t, err, ErrRateNotFound)
+}
+
+func TestRebase_PrecisionDrift(t *testing.T) {
+	table := ecbTable()
+
+	for base := range table.Rates {
+		rebased, err := Rebase(table, base)
+		require.NoError(t, err)
+
+		for quote, rate := range rebased.Rates {
+			// Each rebased rate is within rounding to 12 digits of the
+			// exact quotient of the provider's rates
+			exact := new(big.Float).SetPrec(256).Quo(big.NewFloat(rateAgainstEUR(table, quote)), big.NewFloat(table.Rates[base]))
+			want, _ := exact.Float64()
+			assert.InEpsilon(t, want, rate, 5e-12, "%s/%s", base, quote)
+
+			// Rebasing on both sides of a pair gives reciprocal rates
+			if quote == base {
+				continue
+			}
+			reverse, err := Rebase(table, quote)
+			require.NoError(t, err)
+			assert.InEpsilon(t, 1.0, rate*reverse.Rates[base], 1e-11, "%s/%s", base, quote)
+		}
+
+		// Rebasing back recovers the provider's quotes
+		back, err := Rebase(rebased, "EUR")
+		require.NoError(t, err)
+		assert.Equal(t, 1.0, back.Rates["EUR"])
+		for quote, rate := range table.Rates {
+			assert.InEpsilon(t, rate, back.Rates[quote], 1e-11, "EUR/%s via %s", quote, base)
+		}
+	}
+}
+
+func rateAgainstEUR(table *models.ExternalAPIResponse, currency string) float64 {
+	if currency == table.Base {
+		return 1
+	}
+	return table.Rates[currency]
+}
+
+func TestExchangeRateClient_FixedBase(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path+"?from="+r.URL.Query().Get("from"))
+		if r.URL.Query().Get("from") != "EUR" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		w.Write([]byte(`{"amount":1.0,"base":"EUR","date":"2025-01-02","rates":{"USD":1.1,"GBP":0.88}}`))
+	}))
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.FrankfurterBaseURL = server.URL
+	cfg.ConditionalRequests = false
+	cfg.FixedBases = map[string]string{"Frankfurter": "EUR"}
+	client := NewExchangeRateClientWithConfig(cfg)
+
+	latest, err := client.GetLatestRatesFrom(context.Background(), ProviderFrankfurter, "GBP")
+	require.NoError(t, err)
+	assert.Equal(t, "GBP", latest.Base)
+	assert.Equal(t, "EUR", latest.RebasedFrom)
+	assert.Equal(t, 1.25, latest.Rates["USD"])
+	assert.Equal(t, 1.13636363636, latest.Rates["EUR"])
+
+	historical, err := client.GetHistoricalRatesFrom(context.Background(), ProviderFrankfurter, "USD", "2025-01-02")
+	require.NoError(t, err)
+	assert.Equal(t, 0.8, historical.Rates["GBP"])
+
+	eur, err := client.GetLatestRatesFrom(context.Background(), ProviderFrankfurter, "EUR")
+	require.NoError(t, err)
+	assert.Empty(t, eur.RebasedFrom, "the fixed base itself is not rebased")
+
+	_, err = client.GetLatestRatesFrom(context.Background(), ProviderFrankfurter, "JPY")
+	assert.ErrorIs(t, err, ErrRateNotFound)
+
+	assert.Equal(t, []string{"/latest?from=EUR", "/2025-01-02?from=EUR", "/latest?from=EUR", "/latest?from=EUR"}, requested)
+}
@@ -0,0 +1,83 @@
+package external
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how failed upstream requests are retried
+type RetryPolicy struct {
+	MaxAttempts    int           // total attempts including the first one
+	InitialBackoff time.Duration // wait before the first retry
+	MaxBackoff     time.Duration // upper bound for a single wait
+	Multiplier     float64       // backoff growth factor per attempt
+	Jitter         float64       // fraction of the backoff randomised away, 0..1
+	RetryOnStatus  []int         // HTTP status codes considered transient
+}
+
+// DefaultRetryPolicy retries transient failures twice with exponential backoff
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryOnStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// RetryAfterError is returned when a request is refused before reaching the
+// provider, by its open circuit breaker or the throttle, and says how long
+// until it is worth sending again
+type RetryAfterError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter returns how long to wait before retrying a request that failed
+// with err, and false when err doesn't say
+func RetryAfter(err error) (time.Duration, bool) {
+	var retryErr *RetryAfterError
+	if errors.As(err, &retryErr) {
+		return retryErr.After, true
+	}
+	return 0, false
+}
+
+func (p RetryPolicy) shouldRetryStatus(status int) bool {
+	for _, s := range p.RetryOnStatus {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns how long to wait before the given retry (1-based)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	wait := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && wait > float64(p.MaxBackoff) {
+		wait = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		wait -= wait * p.Jitter * rand.Float64()
+	}
+	return time.Duration(wait)
+}
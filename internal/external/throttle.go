@@ -0,0 +1,231 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/apperrors"
+)
+
+// ErrThrottled is returned when an upstream request would have to wait longer
+// than the throttler's MaxWait for its turn
+var ErrThrottled = apperrors.ErrProviderBusy.Variant("upstream request throttled")
+
+// ThrottleConfig limits upstream requests so bursts of on-demand fetches do not
+// exceed the providers' rate limits. Zero limits are unlimited.
+type ThrottleConfig struct {
+	GlobalPerMinute   int            // Across all providers
+	ProviderPerMinute map[string]int // Provider name -> limit
+	Burst             int            // Requests allowed back to back before pacing starts
+	MaxWait           time.Duration  // Longest a request may queue before it is rejected
+}
+
+// DefaultThrottleConfig stays well inside the free exchangerate-api.com quota
+func DefaultThrottleConfig() ThrottleConfig {
+	return ThrottleConfig{
+		ProviderPerMinute: map[string]int{ProviderExchangeRateAPI: 60},
+		Burst:             10,
+		MaxWait:           10 * time.Second,
+	}
+}
+
+// bucket is a token bucket refilled continuously at perMinute tokens a minute.
+// Tokens may go negative: a negative balance is the queue of requests that
+// already reserved a future slot.
+type bucket struct {
+	perSecond float64
+	capacity  float64
+	tokens    float64
+	last      time.Time
+}
+
+func newBucket(perMinute, burst int, now time.Time) *bucket {
+	capacity := float64(burst)
+	if capacity < 1 {
+		capacity = 1
+	}
+	if capacity > float64(perMinute) {
+		capacity = float64(perMinute)
+	}
+	return &bucket{
+		perSecond: float64(perMinute) / 60,
+		capacity:  capacity,
+		tokens:    capacity,
+		last:      now,
+	}
+}
+
+// reserve takes a token and returns how long the caller must wait for it
+func (b *bucket) reserve(now time.Time) time.Duration {
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.perSecond)
+	b.last = now
+	b.tokens--
+
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.perSecond * float64(time.Second))
+}
+
+// perMinute returns the bucket's refill rate; a nil bucket is unlimited
+func (b *bucket) perMinute() int {
+	if b == nil {
+		return 0
+	}
+	return int(math.Round(b.perSecond * 60))
+}
+
+// release returns a token taken by reserve
+func (b *bucket) release() {
+	b.tokens++
+}
+
+// Throttler paces upstream requests with a global and a per-provider token
+// bucket. Requests queue until both buckets grant them a slot.
+type Throttler struct {
+	mu        sync.Mutex
+	global    *bucket
+	providers map[string]*bucket
+	maxWait   time.Duration
+
+	allowed   int64
+	throttled int64 // Requests that had to queue
+	rejected  int64
+	canceled  int64 // Requests whose context ended while they queued
+	waiting   int64
+	waited    time.Duration
+}
+
+func NewThrottler(cfg ThrottleConfig) *Throttler {
+	now := time.Now()
+	throttler := &Throttler{
+		providers: make(map[string]*bucket),
+		maxWait:   cfg.MaxWait,
+	}
+	if cfg.GlobalPerMinute > 0 {
+		throttler.global = newBucket(cfg.GlobalPerMinute, cfg.Burst, now)
+	}
+	for provider, perMinute := range cfg.ProviderPerMinute {
+		if perMinute > 0 {
+			throttler.providers[provider] = newBucket(perMinute, cfg.Burst, now)
+		}
+	}
+	return throttler
+}
+
+// Wait blocks until a request to provider may be sent. It fails with
+// ErrThrottled when the queue is longer than MaxWait, wrapped in a
+// RetryAfterError saying when the queue will be short enough, or with the
+// context's error when ctx ends first, giving its slot back to the queue. A
+// nil Throttler never waits.
+func (t *Throttler) Wait(ctx context.Context, provider string) error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	now := time.Now()
+	var buckets []*bucket
+	if t.global != nil {
+		buckets = append(buckets, t.global)
+	}
+	if b, ok := t.providers[provider]; ok {
+		buckets = append(buckets, b)
+	}
+
+	var wait time.Duration
+	for _, b := range buckets {
+		if d := b.reserve(now); d > wait {
+			wait = d
+		}
+	}
+
+	if t.maxWait > 0 && wait > t.maxWait {
+		for _, b := range buckets {
+			b.release()
+		}
+		t.rejected++
+		t.mu.Unlock()
+		return &RetryAfterError{
+			Err:   fmt.Errorf("%w: %s queue is %v long", ErrThrottled, provider, wait.Round(time.Millisecond)),
+			After: wait - t.maxWait,
+		}
+	}
+
+	t.allowed++
+	if wait == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	t.throttled++
+	t.waiting++
+	t.waited += wait
+	t.mu.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		t.done()
+		return nil
+	case <-ctx.Done():
+		t.cancel(buckets)
+		return ctx.Err()
+	}
+}
+
+// cancel gives back the slots a request reserved in buckets, so the requests
+// queued behind it needn't wait for a request that is never sent
+func (t *Throttler) cancel(buckets []*bucket) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, b := range buckets {
+		b.release()
+	}
+	t.waiting--
+	t.allowed--
+	t.canceled++
+}
+
+func (t *Throttler) done() {
+	t.mu.Lock()
+	t.waiting--
+	t.mu.Unlock()
+}
+
+// Stats returns throttling counters
+func (t *Throttler) Stats() map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return map[string]interface{}{
+		"enabled":             t.global != nil || len(t.providers) > 0,
+		"allowed":             t.allowed,
+		"throttled":           t.throttled,
+		"rejected":            t.rejected,
+		"canceled":            t.canceled,
+		"queued":              t.waiting,
+		"total_wait_ms":       t.waited.Milliseconds(),
+		"max_wait_ms":         t.maxWait.Milliseconds(),
+		"global_per_minute":   t.global.perMinute(),
+		"provider_per_minute": t.providerLimits(),
+	}
+}
+
+// providerLimits returns the configured requests per minute of each provider.
+// The caller must hold the lock.
+func (t *Throttler) providerLimits() map[string]int {
+	limits := make(map[string]int, len(t.providers))
+	for provider, b := range t.providers {
+		limits[provider] = b.perMinute()
+	}
+	return limits
+}
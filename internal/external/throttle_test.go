@@ -0,0 +1,93 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket_PacesAfterBurst(t *testing.T) {
+	now := time.Now()
+	b := newBucket(60, 2, now)
+
+	assert.Equal(t, time.Duration(0), b.reserve(now))
+	assert.Equal(t, time.Duration(0), b.reserve(now))
+	assert.Equal(t, time.Second, b.reserve(now))
+	assert.Equal(t, 2*time.Second, b.reserve(now))
+
+	// Two seconds later the queue has drained
+	assert.Equal(t, time.Second, b.reserve(now.Add(2*time.Second)))
+}
+
+func TestThrottler_QueuesAndRejects(t *testing.T) {
+	throttler := NewThrottler(ThrottleConfig{
+		ProviderPerMinute: map[string]int{ProviderExchangeRateAPI: 600}, // one every 100ms
+		Burst:             1,
+		MaxWait:           150 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	start := time.Now()
+	require.NoError(t, throttler.Wait(ctx, ProviderExchangeRateAPI))
+	require.NoError(t, throttler.Wait(ctx, ProviderExchangeRateAPI))
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	// Queue a request, then one more would exceed MaxWait
+	go throttler.Wait(ctx, ProviderExchangeRateAPI)
+	time.Sleep(10 * time.Millisecond)
+	err := throttler.Wait(ctx, ProviderExchangeRateAPI)
+	assert.True(t, errors.Is(err, ErrThrottled), "got %v", err)
+
+	// Unthrottled providers never wait
+	assert.NoError(t, throttler.Wait(ctx, ProviderFixer))
+
+	stats := throttler.Stats()
+	assert.Equal(t, int64(1), stats["rejected"])
+	assert.Equal(t, int64(2), stats["throttled"])
+	assert.Equal(t, 600, stats["provider_per_minute"].(map[string]int)[ProviderExchangeRateAPI])
+}
+
+func TestThrottler_GlobalLimitAndCancellation(t *testing.T) {
+	throttler := NewThrottler(ThrottleConfig{GlobalPerMinute: 1, Burst: 1})
+	require.NoError(t, throttler.Wait(context.Background(), ProviderFixer))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := throttler.Wait(ctx, ProviderExchangeRateAPI)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int64(0), throttler.Stats()["queued"])
+}
+
+func TestThrottler_CancellationReleasesSlot(t *testing.T) {
+	throttler := NewThrottler(ThrottleConfig{
+		ProviderPerMinute: map[string]int{ProviderExchangeRateAPI: 60}, // one a second
+		Burst:             1,
+		MaxWait:           1500 * time.Millisecond,
+	})
+	require.NoError(t, throttler.Wait(context.Background(), ProviderExchangeRateAPI))
+
+	// Each canceled request gives its slot back, so the queue never grows
+	// past MaxWait however many requests give up
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		err := throttler.Wait(ctx, ProviderExchangeRateAPI)
+		cancel()
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "request %d", i)
+	}
+
+	stats := throttler.Stats()
+	assert.Equal(t, int64(3), stats["canceled"])
+	assert.Equal(t, int64(0), stats["rejected"])
+	assert.Equal(t, int64(1), stats["allowed"], "canceled requests were never sent")
+	assert.Equal(t, int64(0), stats["queued"])
+}
+
+func TestThrottler_NilIsUnlimited(t *testing.T) {
+	var throttler *Throttler
+	assert.NoError(t, throttler.Wait(context.Background(), ProviderExchangeRateAPI))
+	assert.Equal(t, false, throttler.Stats()["enabled"])
+}
@@ -0,0 +1,188 @@
+package external
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultUserAgent identifies the service to providers unless
+// TransportConfig.UserAgent replaces it
+const DefaultUserAgent = "exchange-rate-service"
+
+// TransportConfig tunes how providers are reached: through an outbound proxy,
+// trusting a custom CA bundle, with the User-Agent and extra headers every
+// request carries, how many idle connections are kept for reuse and how long
+// provider addresses are cached. Zero values keep Go's defaults.
+type TransportConfig struct {
+	Proxy     *url.URL    // Outbound proxy; nil honours HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	TLS       *tls.Config // Custom roots or client certificate, see LoadTLSConfig
+	UserAgent string
+	Headers   http.Header // Added to every provider request
+
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+
+	DisableHTTP2 bool          // Stay on HTTP/1.1 even with providers offering HTTP/2
+	KeepAlive    time.Duration // TCP keep-alive period of provider connections
+	DNSCacheTTL  time.Duration // How long resolved provider addresses are reused, 0 resolves on every dial
+}
+
+// DefaultTransportConfig returns the transport used by DefaultConfig. A few
+// providers serve every request, so more idle connections are kept per host
+// than Go's default of 2, and their addresses are cached for a minute: a cold
+// lookup and TLS handshake otherwise dominate on-demand fetches.
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		UserAgent:           DefaultUserAgent,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		KeepAlive:           30 * time.Second,
+		DNSCacheTTL:         time.Minute,
+	}
+}
+
+// LoadTLSConfig builds the TLS settings of provider requests. caFile is a PEM
+// bundle trusted on top of the system roots, e.g. a corporate proxy's CA;
+// certFile and keyFile are a client certificate for providers requiring
+// mutual TLS. Empty paths are skipped, and nil is returned when all are.
+func LoadTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s holds no PEM certificates", caFile)
+		}
+		cfg.RootCAs = roots
+	}
+
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("a client certificate needs both a certificate and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// transport returns the round tripper of provider requests, Go's default
+// transport with cfg's proxy, TLS settings, pool sizes and HTTP/2 setting,
+// dialing through dns when it is not nil
+func (cfg TransportConfig) transport(dns *dnsCache) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: cfg.KeepAlive}
+	if dns != nil {
+		transport.DialContext = dns.dialContext(dialer)
+	} else {
+		transport.DialContext = dialer.DialContext
+	}
+	// A custom dialer or TLS config turns HTTP/2 off unless it is forced
+	transport.ForceAttemptHTTP2 = !cfg.DisableHTTP2
+	if cfg.DisableHTTP2 {
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	if cfg.Proxy != nil {
+		transport.Proxy = http.ProxyURL(cfg.Proxy)
+	}
+	if cfg.TLS != nil {
+		transport.TLSClientConfig = cfg.TLS.Clone()
+	}
+	if cfg.MaxIdleConns > 0 {
+		transport.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	return transport
+}
+
+// requestHeaders returns the headers every provider request carries
+func (cfg TransportConfig) requestHeaders() http.Header {
+	headers := cfg.Headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	if cfg.UserAgent != "" {
+		headers.Set("User-Agent", cfg.UserAgent)
+	}
+	return headers
+}
+
+// connStats counts how provider requests got their connection
+type connStats struct {
+	created int64 // Requests that dialed a new connection
+	reused  int64 // Requests that reused an idle or multiplexed connection
+	http2   int64 // Responses received over HTTP/2
+}
+
+// snapshot returns the counters with the share of requests that reused a
+// connection, 0 before the first request
+func (s *connStats) snapshot() map[string]interface{} {
+	created, reused := atomic.LoadInt64(&s.created), atomic.LoadInt64(&s.reused)
+	ratio := 0.0
+	if total := created + reused; total > 0 {
+		ratio = float64(reused) / float64(total)
+	}
+	return map[string]interface{}{
+		"new":         created,
+		"reused":      reused,
+		"reuse_ratio": ratio,
+		"http2":       atomic.LoadInt64(&s.http2),
+	}
+}
+
+// trackingTransport records in stats whether each request reused a
+// connection and which protocol answered it
+type trackingTransport struct {
+	next  http.RoundTripper
+	stats *connStats
+}
+
+func (t *trackingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) {
+		if info.Reused {
+			atomic.AddInt64(&t.stats.reused, 1)
+		} else {
+			atomic.AddInt64(&t.stats.created, 1)
+		}
+	}}
+	resp, err := t.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err == nil && resp.ProtoMajor == 2 {
+		atomic.AddInt64(&t.stats.http2, 1)
+	}
+	return resp, err
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (t *trackingTransport) CloseIdleConnections() {
+	if closer, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,202 @@
+package external
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExchangeRateClient_Proxy(t *testing.T) {
+	var requested *http.Request
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.Clone(context.Background())
+		w.Write([]byte(`{"base":"USD","rates":{"INR":83.5}}`))
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	cfg := DefaultConfig()
+	cfg.BaseURL = "http://provider.invalid/v4"
+	cfg.Retry.MaxAttempts = 1
+	cfg.Transport.Proxy = proxyURL
+	cfg.Transport.UserAgent = "rates/1.0"
+	cfg.Transport.Headers = http.Header{"X-Route": []string{"treasury"}}
+	client := NewExchangeRateClientWithConfig(cfg)
+
+	rate, err := client.GetRateForPair(context.Background(), "USD", "INR")
+	require.NoError(t, err)
+	assert.Equal(t, 83.5, rate)
+
+	require.NotNil(t, requested, "the request went through the proxy")
+	assert.Equal(t, "provider.invalid", requested.Host)
+	assert.Equal(t, "http://provider.invalid/v4/latest/USD", requested.RequestURI)
+	assert.Equal(t, "rates/1.0", requested.Header.Get("User-Agent"))
+	assert.Equal(t, "treasury", requested.Header.Get("X-Route"))
+}
+
+func TestExchangeRateClient_DefaultUserAgent(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		w.Write([]byte(`{"base":"USD","rates":{"INR":83.5}}`))
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(server.URL, 1).GetLatestRates(context.Background(), "USD")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultUserAgent, userAgent)
+}
+
+func TestExchangeRateClient_CustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"base":"USD","rates":{"INR":83.5}}`))
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, certificate, 0o600))
+
+	_, err := newTestClient(server.URL, 1).GetLatestRates(context.Background(), "USD")
+	assert.Error(t, err, "the test server's certificate is not trusted by default")
+
+	tlsConfig, err := LoadTLSConfig(caFile, "", "")
+	require.NoError(t, err)
+	cfg := DefaultConfig()
+	cfg.BaseURL = server.URL
+	cfg.Retry.MaxAttempts = 1
+	cfg.Transport.TLS = tlsConfig
+	rates, err := NewExchangeRateClientWithConfig(cfg).GetLatestRates(context.Background(), "USD")
+	require.NoError(t, err)
+	assert.Equal(t, 83.5, rates.Rates["INR"])
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	notPEM := filepath.Join(t.TempDir(), "ca.txt")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+
+	tests := []struct {
+		name     string
+		caFile   string
+		certFile string
+		keyFile  string
+		wantNil  bool
+		wantErr  bool
+	}{
+		{"nothing configured", "", "", "", true, false},
+		{"missing CA bundle", filepath.Join(t.TempDir(), "missing.pem"), "", "", false, true},
+		{"CA bundle without certificates", notPEM, "", "", false, true},
+		{"certificate without key", "", "client.pem", "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadTLSConfig(tt.caFile, tt.certFile, tt.keyFile)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNil, cfg == nil)
+		})
+	}
+}
+
+func TestExchangeRateClient_ConnectionReuse(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"base":"USD","rates":{"INR":83.5}}`))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	newClient := func(disableHTTP2 bool) *ExchangeRateClient {
+		cfg := DefaultConfig()
+		cfg.BaseURL = server.URL
+		cfg.Retry.MaxAttempts = 1
+		cfg.Transport.TLS = &tls.Config{RootCAs: roots}
+		cfg.Transport.DisableHTTP2 = disableHTTP2
+		return NewExchangeRateClientWithConfig(cfg)
+	}
+
+	tests := []struct {
+		name         string
+		disableHTTP2 bool
+		http2        int64
+	}{
+		{"HTTP/2", false, 2},
+		{"HTTP/1.1", true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClient(tt.disableHTTP2)
+			for _, base := range []string{"USD", "EUR"} {
+				_, err := client.GetLatestRates(context.Background(), base)
+				require.NoError(t, err)
+			}
+
+			connections := client.GetStats()["connections"].(map[string]interface{})
+			assert.Equal(t, int64(1), connections["new"])
+			assert.Equal(t, int64(1), connections["reused"], "the second request reuses the connection")
+			assert.Equal(t, 0.5, connections["reuse_ratio"])
+			assert.Equal(t, tt.http2, connections["http2"])
+		})
+	}
+}
+
+func TestDNSCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"base":"USD","rates":{"INR":83.5}}`))
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	cfg := DefaultConfig()
+	cfg.BaseURL = "http://provider.test:" + port
+	cfg.Retry.MaxAttempts = 1
+	client := NewExchangeRateClientWithConfig(cfg)
+	require.NotNil(t, client.dns)
+
+	var lookups int
+	addrs := []string{"127.0.0.1"}
+	client.dns.lookup = func(ctx context.Context, host string) ([]string, error) {
+		lookups++
+		assert.Equal(t, "provider.test", host)
+		return addrs, nil
+	}
+
+	for _, base := range []string{"USD", "EUR", "GBP"} {
+		_, err := client.GetLatestRates(context.Background(), base)
+		require.NoError(t, err)
+		client.httpClient.CloseIdleConnections() // Every request dials
+	}
+	assert.Equal(t, 1, lookups, "the address is looked up once")
+	stats := client.dns.stats()
+	assert.Equal(t, int64(2), stats["hits"])
+	assert.Equal(t, int64(1), stats["misses"])
+	assert.Equal(t, 1, stats["hosts"])
+
+	// An address that stops answering is looked up again on the next dial
+	server.Close()
+	_, err = client.GetLatestRates(context.Background(), "JPY")
+	assert.Error(t, err)
+	assert.Equal(t, 0, client.dns.stats()["hosts"])
+
+	cfg.Transport.DNSCacheTTL = 0
+	assert.Nil(t, NewExchangeRateClientWithConfig(cfg).dns)
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+type AdminHandler struct {
+	exchangeService services.ExchangeServiceInterface
+	reload          func() error
+}
+
+func NewAdminHandler(exchangeService services.ExchangeServiceInterface) *AdminHandler {
+	return &AdminHandler{
+		exchangeService: exchangeService,
+	}
+}
+
+// SetReloader sets the function POST /admin/reload uses to re-read and apply
+// the configuration
+func (h *AdminHandler) SetReloader(reload func() error) {
+	h.reload = reload
+}
+
+// DELETE /admin/cache
+func (h *AdminHandler) ClearCache(c *gin.Context) {
+	h.exchangeService.ClearCache()
+	log.Printf("Cache cleared by %s", callerID(c))
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "cleared",
+	})
+}
+
+// DELETE /admin/cache/:from/:to
+func (h *AdminHandler) InvalidatePair(c *gin.Context) {
+	from := strings.ToUpper(c.Param("from"))
+	to := strings.ToUpper(c.Param("to"))
+
+	removed, err := h.exchangeService.InvalidatePair(from, to)
+	if err != nil {
+		writeError(c, "Invalid currency pair", err)
+		return
+	}
+	log.Printf("Cache entries for %s/%s invalidated by %s", from, to, callerID(c))
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":            from,
+		"to":              to,
+		"removed_entries": removed,
+	})
+}
+
+// POST /admin/rates/refetch
+// Fetches the historical rates of a pair on the given dates again, replacing
+// the cached and archived ones, e.g. after a provider glitch
+func (h *AdminHandler) RefetchHistoricalRates(c *gin.Context) {
+	var req models.RefetchRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, "Invalid request body", err)
+		return
+	}
+	req.From = strings.ToUpper(req.From)
+	req.To = strings.ToUpper(req.To)
+
+	result, err := h.exchangeService.RefetchHistoricalRates(c.Request.Context(), &req, callerID(c))
+	if err != nil {
+		writeError(c, "Failed to refetch historical rates", err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// POST /admin/rates/import with a historical rate CSV file, or the zip the
+// ECB publishes it in, as the "file" field of a multipart form or as the raw
+// body. The base, provider and overwrite query parameters describe it.
+func (h *AdminHandler) ImportHistoricalRates(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxJobFileBytes)
+
+	req := models.RateImportRequest{
+		Base:     c.Query("base"),
+		Provider: c.Query("provider"),
+	}
+	if value := c.Query("overwrite"); value != "" {
+		overwrite, err := strconv.ParseBool(value)
+		if err != nil {
+			writeError(c, "Invalid import", models.NewFieldError(models.ErrCodeInvalidRequest, "overwrite", "overwrite must be true or false, got %q", value))
+			return
+		}
+		req.Overwrite = overwrite
+	}
+
+	var file io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		upload, err := c.FormFile("file")
+		if tooLarge := tooLargeError(err); tooLarge != nil {
+			writeError(c, "Invalid upload", tooLarge)
+			return
+		}
+		if err != nil {
+			writeError(c, "Invalid upload", models.NewFieldError(models.ErrCodeMissingParameter, "file", "a CSV file is required in the file field"))
+			return
+		}
+		opened, err := upload.Open()
+		if err != nil {
+			writeError(c, "Invalid upload", err)
+			return
+		}
+		defer opened.Close()
+		file = opened
+	}
+
+	result, err := h.exchangeService.ImportHistoricalRates(file, &req, callerID(c))
+	if tooLarge := tooLargeError(err); tooLarge != nil {
+		err = tooLarge
+	}
+	if err != nil {
+		writeError(c, "Failed to import historical rates", err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// POST /admin/cache/warm
+func (h *AdminHandler) WarmCache(c *gin.Context) {
+	log.Printf("Cache warm-up requested by %s", callerID(c))
+	result := h.exchangeService.WarmCache(c.Request.Context())
+	c.JSON(http.StatusOK, result)
+}
+
+// POST /admin/currencies
+func (h *AdminHandler) AddCurrency(c *gin.Context) {
+	var req models.CurrencyRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, "Invalid request body", err)
+		return
+	}
+
+	added, err := h.exchangeService.AddCurrency(req.Code)
+	if err != nil {
+		writeError(c, "Invalid currency", err)
+		return
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	status, result := http.StatusOK, "unchanged"
+	if added {
+		log.Printf("Currency %s added by %s", code, callerID(c))
+		status, result = http.StatusCreated, "added"
+	}
+	c.JSON(status, gin.H{
+		"code":                 code,
+		"status":               result,
+		"supported_currencies": h.exchangeService.GetSupportedCurrencies(),
+	})
+}
+
+// DELETE /admin/currencies/:code
+func (h *AdminHandler) RemoveCurrency(c *gin.Context) {
+	code := strings.ToUpper(c.Param("code"))
+
+	removed, err := h.exchangeService.RemoveCurrency(code)
+	if err != nil {
+		writeError(c, "Currency not removed", err)
+		return
+	}
+	log.Printf("Currency %s removed by %s", code, callerID(c))
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":                 code,
+		"status":               "removed",
+		"removed_entries":      removed,
+		"supported_currencies": h.exchangeService.GetSupportedCurrencies(),
+	})
+}
+
+// POST /admin/reload
+func (h *AdminHandler) Reload(c *gin.Context) {
+	if h.reload == nil {
+		writeError(c, "Reload unavailable", models.NewError(models.ErrCodeNotImplemented, "configuration reload is not configured"))
+		return
+	}
+
+	log.Printf("Configuration reload requested by %s", callerID(c))
+	if err := h.reload(); err != nil {
+		writeError(c, "Reload failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":               "reloaded",
+		"supported_currencies": h.exchangeService.GetSupportedCurrencies(),
+	})
+}
+
+func callerID(c *gin.Context) string {
+	if key, ok := middleware.APIKeyFromContext(c); ok {
+		return key.ID
+	}
+	return c.ClientIP()
+}
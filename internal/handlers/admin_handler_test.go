@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+	"exchange-rate-service/internal/services/mocks"
+)
+
+func TestAdminHandler_Reload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := services.NewExchangeService(cache.NewMemoryCache(time.Hour), nil, nil)
+
+	tests := []struct {
+		name         string
+		reload       func() error
+		expectedCode int
+	}{
+		{"Not configured", nil, http.StatusNotImplemented},
+		{"Reloaded", func() error { return nil }, http.StatusOK},
+		{"Invalid configuration", func() error { return errors.New("unsupported currency") }, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAdminHandler(service)
+			if tt.reload != nil {
+				handler.SetReloader(tt.reload)
+			}
+			router := gin.New()
+			router.POST("/admin/reload", handler.Reload)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"supported_currencies"`)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_Currencies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer models.SetSupportedCurrencies(models.DefaultCurrencies)
+	models.SetSupportedCurrencies([]string{"USD", "INR", "EUR"})
+
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "EUR", "", 0.9)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "EUR", "INR", "", 90)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 83)
+	handler := NewAdminHandler(services.NewExchangeService(memoryCache, nil, nil))
+	router := gin.New()
+	router.POST("/admin/currencies", handler.AddCurrency)
+	router.DELETE("/admin/currencies/:code", handler.RemoveCurrency)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{"add", http.MethodPost, "/admin/currencies", `{"code":"chf"}`, http.StatusCreated, `"supported_currencies":["CHF","EUR","INR","USD"]`},
+		{"add again", http.MethodPost, "/admin/currencies", `{"code":"CHF"}`, http.StatusOK, `"status":"unchanged"`},
+		{"add invalid code", http.MethodPost, "/admin/currencies", `{"code":"SWISS"}`, http.StatusUnprocessableEntity, models.ErrCodeValueInvalid},
+		{"add without code", http.MethodPost, "/admin/currencies", `{}`, http.StatusBadRequest, "Invalid request body"},
+		{"remove", http.MethodDelete, "/admin/currencies/eur", "", http.StatusOK, `"removed_entries":2`},
+		{"remove unsupported", http.MethodDelete, "/admin/currencies/EUR", "", http.StatusNotFound, models.ErrCodeNotFound},
+		{"remove another", http.MethodDelete, "/admin/currencies/CHF", "", http.StatusOK, `"supported_currencies":["INR","USD"]`},
+		{"keep two", http.MethodDelete, "/admin/currencies/INR", "", http.StatusUnprocessableEntity, models.ErrCodeValueInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.want)
+		})
+	}
+
+	_, found := memoryCache.Get(external.ProviderExchangeRateAPI, "USD", "INR", "")
+	assert.True(t, found, "rates of the remaining currencies are kept")
+	assert.False(t, models.IsSupportedCurrency("EUR"))
+}
+
+func TestAdminHandler_RefetchHistoricalRates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &mocks.ExchangeService{
+		RefetchHistoricalRatesFunc: func(ctx context.Context, req *models.RefetchRequest, caller string) (*models.RefetchResponse, error) {
+			if len(req.Dates) > 1 {
+				return nil, models.NewFieldError(models.ErrCodeValueInvalid, "dates", "too many dates")
+			}
+			return &models.RefetchResponse{From: req.From, To: req.To, RequestedBy: caller, Refetched: len(req.Dates)}, nil
+		},
+	}
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(middleware.ContextKeyAPIKey, &auth.APIKey{ID: "ops", Roles: []string{auth.RoleAdmin}})
+	})
+	router.POST("/admin/rates/refetch", NewAdminHandler(service).RefetchHistoricalRates)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   string
+	}{
+		{"refetch", `{"from":"usd","to":"inr","dates":["2025-01-02"]}`, http.StatusOK, `"from":"USD","to":"INR","requested_by":"ops"`},
+		{"service error", `{"from":"USD","to":"INR","dates":["2025-01-02","2025-01-03"]}`, http.StatusUnprocessableEntity, `"field":"dates"`},
+		{"without dates", `{"from":"USD","to":"INR"}`, http.StatusBadRequest, "Invalid request body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/rates/refetch", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.want)
+		})
+	}
+	assert.Equal(t, 2, service.CallCount("RefetchHistoricalRates"))
+}
+
+func TestAdminHandler_ImportHistoricalRates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &mocks.ExchangeService{
+		ImportHistoricalRatesFunc: func(file io.Reader, req *models.RateImportRequest, caller string) (*models.RateImportResponse, error) {
+			content, err := io.ReadAll(file)
+			if err != nil {
+				return nil, err
+			}
+			if !strings.HasPrefix(string(content), "Date,") {
+				return nil, models.NewFieldError(models.ErrCodeInvalidRequest, "file", "invalid header")
+			}
+			skipped := 1
+			if req.Overwrite {
+				skipped = 0
+			}
+			return &models.RateImportResponse{Format: models.RateImportECB, Provider: req.Base, ImportedBy: caller, SkippedDays: skipped}, nil
+		},
+	}
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(middleware.ContextKeyAPIKey, &auth.APIKey{ID: "ops", Roles: []string{auth.RoleAdmin}})
+	})
+	router.POST("/admin/rates/import", NewAdminHandler(service).ImportHistoricalRates)
+
+	tests := []struct {
+		name   string
+		query  string
+		body   string
+		status int
+		want   string
+	}{
+		{"import", "?base=USD", "Date,INR,\n2025-01-02,85.5,\n", http.StatusOK, `"provider":"USD","rows":0,"imported_days":0,"skipped_days":1`},
+		{"overwrite", "?overwrite=true", "Date,INR,\n2025-01-02,85.5,\n", http.StatusOK, `"skipped_days":0`},
+		{"invalid overwrite", "?overwrite=maybe", "Date,INR,\n", http.StatusBadRequest, `"field":"overwrite"`},
+		{"service error", "", "when,what\n", http.StatusBadRequest, `"field":"file"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/rates/import"+tt.query, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "text/csv")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.want)
+		})
+	}
+	assert.Equal(t, 3, service.CallCount("ImportHistoricalRates"))
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+	"exchange-rate-service/internal/store"
+	"exchange-rate-service/internal/utils"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+)
+
+type AuditHandler struct {
+	exchangeService services.ExchangeServiceInterface
+}
+
+func NewAuditHandler(exchangeService services.ExchangeServiceInterface) *AuditHandler {
+	return &AuditHandler{
+		exchangeService: exchangeService,
+	}
+}
+
+// GET /audit/conversions?caller=&from=&to=&start_date=&end_date=&page=1&page_size=50
+func (h *AuditHandler) GetConversions(c *gin.Context) {
+	filter, page, pageSize, err := parseAuditFilter(c)
+	if err != nil {
+		writeError(c, "Invalid audit query", err)
+		return
+	}
+
+	records, total, err := h.exchangeService.QueryConversions(filter)
+	if err != nil {
+		writeError(c, "Audit log unavailable", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"records":   records,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GET /audit/conversions/:id
+func (h *AuditHandler) GetConversion(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		writeError(c, "Conversion not found", models.NewError(models.ErrCodeNotFound, "conversion %s not found", c.Param("id")))
+		return
+	}
+
+	record, err := h.exchangeService.GetConversion(id)
+	if err != nil {
+		writeError(c, "Conversion not found", err)
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+// GET /conversions/:id
+// Returns a conversion exactly as it was returned, by the conversion_id of
+// its response. Only the caller it was returned to and auditors may read it.
+func (h *AuditHandler) GetReceipt(c *gin.Context) {
+	id := c.Param("id")
+	record, err := h.exchangeService.GetConversionReceipt(id)
+	if err == nil && record.Caller != callerID(c) {
+		key, authenticated := middleware.APIKeyFromContext(c)
+		if !authenticated || !key.HasRole(auth.RoleAuditor) {
+			err = models.NewError(models.ErrCodeNotFound, "conversion %s not found", id)
+		}
+	}
+	if err != nil {
+		writeError(c, "Conversion not found", err)
+		return
+	}
+
+	if len(record.Legs) > 0 {
+		var chain models.ChainConversionResponse
+		if err := json.Unmarshal(record.Result, &chain); err != nil {
+			writeError(c, "Conversion unavailable", models.NewError(models.ErrCodeInternal, "conversion %s could not be decoded", id))
+			return
+		}
+		c.JSON(http.StatusOK, chain)
+		return
+	}
+
+	var result models.ConversionResponse
+	if err := json.Unmarshal(record.Result, &result); err != nil {
+		writeError(c, "Conversion unavailable", models.NewError(models.ErrCodeInternal, "conversion %s could not be decoded", id))
+		return
+	}
+	renderConversion(c, &result)
+}
+
+// parseAuditFilter reads the filter and pagination parameters. Dates are
+// calendar days in the reference time zone and end_date is inclusive.
+func parseAuditFilter(c *gin.Context) (store.AuditFilter, int, int, error) {
+	filter := store.AuditFilter{
+		Caller: c.Query("caller"),
+		From:   strings.ToUpper(c.Query("from")),
+		To:     strings.ToUpper(c.Query("to")),
+	}
+
+	if value := c.Query("start_date"); value != "" {
+		start, err := time.ParseInLocation(utils.DateFormat, value, utils.ReferenceLocation())
+		if err != nil {
+			return filter, 0, 0, fmt.Errorf("invalid start_date %q, expected YYYY-MM-DD", value)
+		}
+		filter.Since = start
+	}
+	if value := c.Query("end_date"); value != "" {
+		end, err := time.ParseInLocation(utils.DateFormat, value, utils.ReferenceLocation())
+		if err != nil {
+			return filter, 0, 0, fmt.Errorf("invalid end_date %q, expected YYYY-MM-DD", value)
+		}
+		filter.Until = end.AddDate(0, 0, 1)
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		return filter, 0, 0, fmt.Errorf("invalid page %q, expected a positive integer", c.Query("page"))
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultAuditPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxAuditPageSize {
+		return filter, 0, 0, fmt.Errorf("invalid page_size %q, expected 1 to %d", c.Query("page_size"), maxAuditPageSize)
+	}
+
+	filter.Offset = (page - 1) * pageSize
+	filter.Limit = pageSize
+	return filter, page, pageSize, nil
+}
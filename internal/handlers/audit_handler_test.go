@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+	"exchange-rate-service/internal/store"
+	"exchange-rate-service/internal/utils"
+)
+
+func TestAuditHandler_ConversionsAreAudited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 80.0)
+	service := services.NewExchangeService(memoryCache, nil, nil)
+	auditLog, err := store.NewAuditLog("", 0)
+	require.NoError(t, err)
+	service.SetAuditLog(auditLog)
+
+	router := gin.New()
+	router.GET("/convert", NewExchangeHandler(service).ConvertCurrencyQuery)
+	router.GET("/audit/conversions", NewAuditHandler(service).GetConversions)
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/convert?from=USD&to=INR&amount=2", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/audit/conversions?from=usd&page=2&page_size=2", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Records []store.ConversionRecord `json:"records"`
+		Total   int                      `json:"total"`
+		Page    int                      `json:"page"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 3, resp.Total)
+	assert.Equal(t, 2, resp.Page)
+	require.Len(t, resp.Records, 1)
+	assert.Equal(t, int64(1), resp.Records[0].ID)
+	assert.Equal(t, "203.0.113.7", resp.Records[0].Caller)
+	assert.Equal(t, 80.0, resp.Records[0].Rate)
+	assert.Equal(t, 160.0, resp.Records[0].ConvertedAmount)
+}
+
+func TestAuditHandler_ConversionRoundingTrail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 83.123456789)
+	service := services.NewExchangeService(memoryCache, nil, nil)
+	auditLog, err := store.NewAuditLog("", 0)
+	require.NoError(t, err)
+	service.SetAuditLog(auditLog)
+
+	router := gin.New()
+	router.GET("/convert", NewExchangeHandler(service).ConvertCurrencyQuery)
+	router.GET("/audit/conversions/:id", NewAuditHandler(service).GetConversion)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/convert?from=USD&to=INR&amount=10.5&rounding=bankers&fee_percent=1.5", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/audit/conversions/1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var record store.ConversionRecord
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &record))
+	trail := record.Trail
+	require.NotNil(t, trail)
+	assert.Equal(t, 83.123456789, trail.RawRate)
+	assert.Equal(t, models.RoundingBankers, trail.Rounding)
+	assert.Equal(t, 2, trail.Precision)
+	assert.Equal(t, 10.5*83.123456789, trail.UnroundedAmount)
+
+	// The rounding is reproducible from the trail alone
+	assert.Equal(t, record.ConvertedAmount, utils.Round(trail.UnroundedAmount, trail.Precision, trail.Rounding))
+	assert.Equal(t, record.Rate, utils.Round(trail.UnroundedRate, trail.RatePrecision, trail.Rounding))
+	assert.Equal(t, trail.FeeAmount, utils.Round(trail.UnroundedFee, trail.Precision, trail.Rounding))
+	assert.Equal(t, 1.5, trail.FeePercent)
+
+	for _, id := range []string{"2", "abc"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/audit/conversions/"+id, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, id)
+	}
+}
+
+func TestAuditHandler_InvalidQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/audit/conversions", NewAuditHandler(services.NewExchangeService(cache.NewMemoryCache(time.Hour), nil, nil)).GetConversions)
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{"Bad start date", "start_date=01-01-2025", http.StatusBadRequest},
+		{"Page size too large", "page_size=1000", http.StatusBadRequest},
+		{"Page zero", "page=0", http.StatusBadRequest},
+		{"Auditing disabled", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/audit/conversions?"+tt.query, nil))
+			assert.Equal(t, tt.status, w.Code)
+
+			var errResp models.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+			assert.NotEmpty(t, errResp.Message)
+		})
+	}
+}
+
+func TestAuditHandler_ConversionReceipts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 83.123456789)
+	service := services.NewExchangeService(memoryCache, nil, nil)
+	auditLog, err := store.NewAuditLog("", 0)
+	require.NoError(t, err)
+	service.SetAuditLog(auditLog)
+
+	keys := auth.NewKeyStore([]auth.APIKey{
+		{ID: "partner", Key: "partner-secret", Roles: []string{auth.RoleReader}},
+		{ID: "other", Key: "other-secret", Roles: []string{auth.RoleReader}},
+		{ID: "support", Key: "support-secret", Roles: []string{auth.RoleAuditor}},
+	})
+	router := gin.New()
+	router.Use(middleware.Authenticate(keys, nil), middleware.HTTPCache())
+	router.POST("/convert", NewExchangeHandler(service).ConvertCurrency)
+	router.GET("/convert", NewExchangeHandler(service).ConvertCurrencyQuery)
+	router.GET("/conversions/:id", NewAuditHandler(service).GetReceipt)
+
+	request := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("X-API-Key", key)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/convert", "partner-secret", `{"from":"USD","to":"INR","amount":10.5,"fee_percent":1.5}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	quoted := w.Body.String()
+	var conversion models.ConversionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &conversion))
+	assert.Regexp(t, `^[0-9a-f]{32}$`, conversion.ConversionID)
+
+	for _, key := range []string{"partner-secret", "support-secret"} {
+		w = request(http.MethodGet, "/conversions/"+conversion.ConversionID, key, "")
+		assert.Equal(t, http.StatusOK, w.Code, key)
+		assert.JSONEq(t, quoted, w.Body.String(), "the receipt holds the numbers exactly as quoted")
+	}
+
+	w = request(http.MethodGet, "/conversions/"+conversion.ConversionID, "other-secret", "")
+	assert.Equal(t, http.StatusNotFound, w.Code, "other callers can't read the receipt")
+	w = request(http.MethodGet, "/conversions/0123456789abcdef0123456789abcdef", "partner-secret", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"error_code":"NOT_FOUND"`)
+
+	// Every GET conversion is a receipt of its own, never served from a cache
+	var ids []string
+	for i := 0; i < 2; i++ {
+		w = request(http.MethodGet, "/convert?from=USD&to=INR&amount=10", "partner-secret", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		assert.Empty(t, w.Header().Get("ETag"))
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &conversion))
+		ids = append(ids, conversion.ConversionID)
+	}
+	assert.NotEqual(t, ids[0], ids[1])
+}
+
+func TestAuditHandler_ChainConversionsAreAudited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "GBP", "USD", "", 1.25)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 80.0)
+	service := services.NewExchangeService(memoryCache, nil, nil)
+	auditLog, err := store.NewAuditLog("", 0)
+	require.NoError(t, err)
+	service.SetAuditLog(auditLog)
+
+	router := gin.New()
+	router.POST("/convert/chain", NewExchangeHandler(service).ConvertChain)
+	router.GET("/conversions/:id", NewAuditHandler(service).GetReceipt)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/convert/chain", bytes.NewBufferString(`{"path":["GBP","USD","INR"],"amount":16}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	quoted := w.Body.String()
+	var chain models.ChainConversionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &chain))
+	require.Regexp(t, `^[0-9a-f]{32}$`, chain.ConversionID)
+
+	records, total, err := auditLog.Query(store.AuditFilter{})
+	require.NoError(t, err)
+	require.Equal(t, 1, total, "a chain is recorded once")
+	record := records[0]
+	assert.Equal(t, "GBP", record.From)
+	assert.Equal(t, "INR", record.To)
+	assert.Equal(t, 1600.0, record.ConvertedAmount)
+	assert.Equal(t, 100.0, record.MidMarketRate)
+	require.Len(t, record.Legs, 2)
+	assert.Equal(t, store.ChainLegRecord{From: "USD", To: "INR", Amount: 20, ConvertedAmount: 1600, Rate: 80, MidMarketRate: 80}, record.Legs[1])
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/conversions/"+chain.ConversionID, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, quoted, w.Body.String(), "the receipt holds the chain exactly as quoted")
+}
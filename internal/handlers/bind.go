@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"exchange-rate-service/internal/models"
+)
+
+// bindJSON decodes the JSON body into obj like ShouldBindJSON, turning its
+// failures into coded errors about the field at fault
+func bindJSON(c *gin.Context, obj interface{}) error {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		return bindError(err, obj)
+	}
+	return nil
+}
+
+// bindError codes an error of binding a request body into obj: a body over
+// the size limit is PAYLOAD_TOO_LARGE, a missing required field
+// MISSING_PARAMETER, a value failing its binding rule VALUE_INVALID and
+// anything that is not the expected JSON INVALID_REQUEST
+func bindError(err error, obj interface{}) error {
+	if tooLarge := tooLargeError(err); tooLarge != nil {
+		return tooLarge
+	}
+
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) && len(invalid) > 0 {
+		fieldErr := invalid[0]
+		field := jsonName(obj, fieldErr.StructField())
+		if fieldErr.Tag() == "required" {
+			return &models.CodedError{Code: models.ErrCodeMissingParameter, Field: field, Message: field + " is required", Err: err}
+		}
+		message := fmt.Sprintf("%s must satisfy %s", field, fieldErr.Tag())
+		if fieldErr.Param() != "" {
+			message += "=" + fieldErr.Param()
+		}
+		return &models.CodedError{Code: models.ErrCodeValueInvalid, Field: field, Message: message, Err: err}
+	}
+
+	var wrongType *json.UnmarshalTypeError
+	if errors.As(err, &wrongType) {
+		return &models.CodedError{
+			Code:    models.ErrCodeInvalidRequest,
+			Field:   wrongType.Field,
+			Message: fmt.Sprintf("%s must be a %s, got %s", wrongType.Field, wrongType.Type, wrongType.Value),
+			Err:     err,
+		}
+	}
+
+	if errors.Is(err, io.EOF) {
+		return &models.CodedError{Code: models.ErrCodeInvalidRequest, Message: "request body is empty", Err: err}
+	}
+	return &models.CodedError{Code: models.ErrCodeInvalidRequest, Message: "request body is not valid JSON: " + err.Error(), Err: err}
+}
+
+// jsonName returns the JSON name of the field of obj, a pointer to a
+// struct, named structField in Go; the Go name when it has none
+func jsonName(obj interface{}, structField string) string {
+	t := reflect.TypeOf(obj)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return structField
+	}
+	field, ok := t.FieldByName(structField)
+	if !ok {
+		return structField
+	}
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return structField
+}
+
+// tooLargeError returns err as PAYLOAD_TOO_LARGE when reading the body went
+// over its size limit, else nil
+func tooLargeError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return nil
+	}
+	return &models.CodedError{
+		Code:    models.ErrCodePayloadTooLarge,
+		Message: fmt.Sprintf("request body is larger than %d bytes", tooLarge.Limit),
+		Err:     err,
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/models"
+)
+
+// writeError answers with err's error code and the HTTP status of that code.
+// Errors about a single field are detailed for clients that highlight it, and
+// errors that know when a retry is worth it set Retry-After.
+func writeError(c *gin.Context, title string, err error) {
+	code, field := models.ErrorCodeOf(err)
+	status := models.ErrorStatus(code)
+
+	var coded *models.CodedError
+	if errors.As(err, &coded) && coded.Retry > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(coded.Retry.Seconds()))))
+	}
+
+	response := models.ErrorResponse{
+		Error:     title,
+		Message:   err.Error(),
+		Code:      status,
+		ErrorCode: code,
+	}
+	if field != "" {
+		response.Details = []models.FieldError{{Field: field, Code: code, Message: err.Error()}}
+	}
+	c.JSON(status, response)
+}
+
+// requireQuery reports whether every named query parameter is set. When
+// some are not it answers 400 MISSING_PARAMETER with message, detailing each
+// missing parameter.
+func requireQuery(c *gin.Context, message string, names ...string) bool {
+	var details []models.FieldError
+	for _, name := range names {
+		if c.Query(name) == "" {
+			details = append(details, models.FieldError{
+				Field:   name,
+				Code:    models.ErrCodeMissingParameter,
+				Message: fmt.Sprintf("%s is required", name),
+			})
+		}
+	}
+	if len(details) == 0 {
+		return true
+	}
+
+	c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		Error:     "Missing required parameters",
+		Message:   message,
+		Code:      http.StatusBadRequest,
+		ErrorCode: models.ErrCodeMissingParameter,
+		Details:   details,
+	})
+	return false
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+func TestExchangeHandler_ErrorCodes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewExchangeHandler(services.NewExchangeService(cache.NewMemoryCache(time.Hour), nil, nil))
+	router := gin.New()
+	router.GET("/convert", handler.ConvertCurrencyQuery)
+
+	future := time.Now().AddDate(0, 0, 7).Format("2006-01-02")
+	tests := []struct {
+		name      string
+		query     string
+		status    int
+		errorCode string
+		field     string
+	}{
+		{"Missing amount", "from=USD&to=INR", http.StatusBadRequest, models.ErrCodeMissingParameter, "amount"},
+		{"Malformed amount", "from=USD&to=INR&amount=ten", http.StatusBadRequest, models.ErrCodeInvalidRequest, "amount"},
+		{"Unsupported currency", "from=XYZ&to=INR&amount=10", http.StatusUnprocessableEntity, models.ErrCodeCurrencyUnsupported, "from"},
+		{"Negative amount", "from=USD&to=INR&amount=-5", http.StatusUnprocessableEntity, models.ErrCodeAmountInvalid, "amount"},
+		{"Malformed date", "from=USD&to=INR&amount=10&date=01-02-2025", http.StatusBadRequest, models.ErrCodeDateInvalid, "date"},
+		{"Future date", "from=USD&to=INR&amount=10&date=" + future, http.StatusUnprocessableEntity, models.ErrCodeDateOutOfRange, "date"},
+		{"Bad locale", "from=USD&to=INR&amount=10&locale=!!", http.StatusBadRequest, models.ErrCodeLocaleInvalid, "locale"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/convert?"+tt.query, nil))
+			assert.Equal(t, tt.status, w.Code)
+
+			var errResp models.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+			assert.Equal(t, tt.status, errResp.Code)
+			assert.Equal(t, tt.errorCode, errResp.ErrorCode)
+			require.Len(t, errResp.Details, 1)
+			assert.Equal(t, tt.field, errResp.Details[0].Field)
+			assert.Equal(t, tt.errorCode, errResp.Details[0].Code)
+		})
+	}
+}
+
+func TestWriteError_UncodedErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	writeError(c, "Failed", assert.AnError)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var errResp models.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+	assert.Equal(t, models.ErrCodeInvalidRequest, errResp.ErrorCode)
+	assert.Empty(t, errResp.Details)
+}
+
+func TestWriteError_RetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	writeError(c, "Failed to get exchange rate", &models.CodedError{
+		Code:    models.ErrCodeProviderBusy,
+		Message: "provider circuit breaker is open",
+		Retry:   1500 * time.Millisecond,
+	})
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+}
+
+func TestExchangeHandler_BodyErrorCodes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	handler := NewExchangeHandler(services.NewExchangeService(memoryCache, nil, nil))
+	router := gin.New()
+	router.POST("/convert", handler.ConvertCurrency)
+	router.POST("/rates/historical/bulk", handler.GetBulkHistoricalRates)
+
+	models.SetRequestLimits(models.RequestLimits{MaxBodyBytes: 1 << 20, MaxPairs: 2, MaxPrecision: 4})
+	defer models.SetRequestLimits(models.DefaultRequestLimits())
+
+	tests := []struct {
+		name      string
+		path      string
+		body      string
+		status    int
+		errorCode string
+		field     string
+	}{
+		{"Missing field", "/convert", `{"from":"USD","amount":10}`, http.StatusBadRequest, models.ErrCodeMissingParameter, "to"},
+		{"Binding rule", "/convert", `{"from":"USD","to":"INR","amount":-1}`, http.StatusUnprocessableEntity, models.ErrCodeValueInvalid, "amount"},
+		{"Wrong type", "/convert", `{"from":"USD","to":"INR","amount":"ten"}`, http.StatusBadRequest, models.ErrCodeInvalidRequest, "amount"},
+		{"Precision over limit", "/convert", `{"from":"USD","to":"INR","amount":10,"precision":5}`, http.StatusUnprocessableEntity, models.ErrCodeLimitExceeded, "precision"},
+		{"Pairs over limit", "/rates/historical/bulk", `{"pairs":["USD_INR","EUR_INR","GBP_INR"],"start_date":"2025-01-02","end_date":"2025-01-02"}`, http.StatusUnprocessableEntity, models.ErrCodeLimitExceeded, "pairs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.status, w.Code)
+
+			var errResp models.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+			assert.Equal(t, tt.errorCode, errResp.ErrorCode)
+			require.Len(t, errResp.Details, 1)
+			assert.Equal(t, tt.field, errResp.Details[0].Field)
+		})
+	}
+
+	for _, body := range []string{"", `{"from":`} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/convert", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var errResp models.ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+		assert.Equal(t, models.ErrCodeInvalidRequest, errResp.ErrorCode)
+	}
+}
@@ -1,205 +1,663 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"exchange-rate-service/internal/middleware"
 	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/reports"
 	"exchange-rate-service/internal/services"
+	"exchange-rate-service/internal/utils"
 )
 
 type ExchangeHandler struct {
-	exchangeService *services.ExchangeService
+	exchangeService services.ExchangeServiceInterface
+	slo             *services.SLOTracker
 }
 
-func NewExchangeHandler(exchangeService *services.ExchangeService) *ExchangeHandler {
+func NewExchangeHandler(exchangeService services.ExchangeServiceInterface) *ExchangeHandler {
 	return &ExchangeHandler{
 		exchangeService: exchangeService,
 	}
 }
 
+// SetSLOTracker sets the tracker whose endpoint latencies and violations
+// /metrics exposes
+func (h *ExchangeHandler) SetSLOTracker(tracker *services.SLOTracker) {
+	h.slo = tracker
+}
+
 func (h *ExchangeHandler) ConvertCurrency(c *gin.Context) {
 	var req models.ConversionRequest
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid request body",
-			Message: err.Error(),
-			Code:    http.StatusBadRequest,
-		})
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, "Invalid request body", err)
+		return
+	}
+	if !selectFields(c, models.ConversionResponse{}) {
+		return
+	}
+
+	result, err := h.exchangeService.ConvertCurrency(c.Request.Context(), &req)
+	if err != nil {
+		writeError(c, "Conversion failed", err)
+		return
+	}
+
+	if err := h.exchangeService.RecordConversion(callerID(c), result); err != nil {
+		log.Printf("Failed to audit conversion %s/%s: %v", result.From, result.To, errors.Unwrap(err))
+		writeError(c, "Conversion failed", err)
+		return
+	}
+	renderConversion(c, result)
+}
+
+// POST /convert/simulate converts at the actual rate and a what-if one
+func (h *ExchangeHandler) SimulateConversion(c *gin.Context) {
+	var req models.SimulationRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, "Invalid request body", err)
 		return
 	}
 
-	result, err := h.exchangeService.ConvertCurrency(&req)
+	result, err := h.exchangeService.SimulateConversion(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Conversion failed",
-			Message: err.Error(),
-			Code:    http.StatusBadRequest,
-		})
+		writeError(c, "Simulation failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// POST /convert/chain
+func (h *ExchangeHandler) ConvertChain(c *gin.Context) {
+	var req models.ChainConversionRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, "Invalid request body", err)
 		return
 	}
 
+	result, err := h.exchangeService.ConvertChain(c.Request.Context(), &req, allowedPairs(c))
+	if err != nil {
+		writeError(c, "Conversion failed", err)
+		return
+	}
+
+	if err := h.exchangeService.RecordChainConversion(callerID(c), result); err != nil {
+		log.Printf("Failed to audit conversion chain %s: %v", strings.Join(result.Path, "/"), errors.Unwrap(err))
+		writeError(c, "Conversion failed", err)
+		return
+	}
 	c.JSON(http.StatusOK, result)
 }
 
-// GET /convert?from=USD&to=INR&amount=100&date=2025-01-01
+// GET /convert?from=USD&to=INR&amount=100&date=2025-01-01&locale=en-IN&provider=frankfurter&precision=2&rounding=bankers&fee_percent=1.5&fee_fixed=50
+// or with timestamp=2025-01-01T14:30:00Z instead of date
 func (h *ExchangeHandler) ConvertCurrencyQuery(c *gin.Context) {
 	from := c.Query("from")
 	to := c.Query("to")
 	amountStr := c.Query("amount")
 	date := c.Query("date")
+	timestamp := c.Query("timestamp")
+	locale := c.Query("locale")
+	provider := c.Query("provider")
 
-	if from == "" || to == "" || amountStr == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Missing required parameters",
-			Message: "from, to, and amount parameters are required",
-			Code:    http.StatusBadRequest,
-		})
+	if !requireQuery(c, "from, to, and amount parameters are required", "from", "to", "amount") {
+		return
+	}
+	if !selectFields(c, models.ConversionResponse{}) {
 		return
 	}
 
 	amount, err := strconv.ParseFloat(amountStr, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid amount",
-			Message: "amount must be a valid number",
-			Code:    http.StatusBadRequest,
-		})
+		writeError(c, "Invalid amount", models.NewFieldError(models.ErrCodeInvalidRequest, "amount", "amount must be a valid number"))
 		return
 	}
 
 	req := models.ConversionRequest{
-		From:   from,
-		To:     to,
-		Amount: amount,
-		Date:   date,
+		From:      from,
+		To:        to,
+		Amount:    amount,
+		Date:      date,
+		Timestamp: timestamp,
+		Locale:    locale,
+		Provider:  provider,
+		Rounding:  c.Query("rounding"),
+	}
+	if value := c.Query("strict"); value != "" {
+		if req.Strict, err = strconv.ParseBool(value); err != nil {
+			writeError(c, "Invalid strict", models.NewFieldError(models.ErrCodeInvalidRequest, "strict", "strict must be true or false, got %q", value))
+			return
+		}
+	}
+	if precisionStr := c.Query("precision"); precisionStr != "" {
+		precision, err := strconv.Atoi(precisionStr)
+		if err != nil {
+			writeError(c, "Invalid precision", models.NewFieldError(models.ErrCodeInvalidRequest, "precision", "precision must be a whole number"))
+			return
+		}
+		req.Precision = &precision
+	}
+	for _, fee := range []struct {
+		name  string
+		value *float64
+	}{{"fee_percent", &req.FeePercent}, {"fee_fixed", &req.FeeFixed}} {
+		if raw := c.Query(fee.name); raw != "" {
+			if *fee.value, err = strconv.ParseFloat(raw, 64); err != nil {
+				writeError(c, "Invalid fee", models.NewFieldError(models.ErrCodeInvalidRequest, fee.name, fee.name+" must be a valid number"))
+				return
+			}
+		}
 	}
 
-	result, err := h.exchangeService.ConvertCurrency(&req)
+	result, err := h.exchangeService.ConvertCurrency(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Conversion failed",
-			Message: err.Error(),
-			Code:    http.StatusBadRequest,
-		})
+		writeError(c, "Conversion failed", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	if err := h.exchangeService.RecordConversion(callerID(c), result); err != nil {
+		log.Printf("Failed to audit conversion %s/%s: %v", result.From, result.To, errors.Unwrap(err))
+		writeError(c, "Conversion failed", err)
+		return
+	}
+	if result.ConversionID != "" {
+		// Each call records its own receipt, so a cached answer would
+		// show the ID of another one
+		c.Header("Cache-Control", "no-store")
+	} else {
+		middleware.SetFreshness(c, result.Freshness)
+		middleware.SetMarkup(c, result.MarkupPercent)
+	}
+	renderConversion(c, result)
 }
 
-// GET /rates/latest?from=USD&to=INR
+// GET /rates/latest?from=USD&to=INR&provider=frankfurter
 func (h *ExchangeHandler) GetLatestRate(c *gin.Context) {
 	from := c.Query("from")
 	to := c.Query("to")
 
-	if from == "" || to == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Missing required parameters",
-			Message: "from and to parameters are required",
-			Code:    http.StatusBadRequest,
-		})
+	if !requireQuery(c, "from and to parameters are required", "from", "to") {
 		return
 	}
 
-	rate, err := h.exchangeService.GetLatestRate(from, to)
+	result, err := h.exchangeService.GetLatestRate(c.Request.Context(), from, to, c.Query("provider"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Failed to get exchange rate",
-			Message: err.Error(),
-			Code:    http.StatusBadRequest,
-		})
+		writeError(c, "Failed to get exchange rate", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"from": from,
-		"to":   to,
-		"rate": rate,
-	})
+	middleware.SetFreshness(c, result.Freshness)
+	c.JSON(http.StatusOK, result)
+}
+
+// GET /rates/compare?from=USD&to=INR
+func (h *ExchangeHandler) CompareRates(c *gin.Context) {
+	if !requireQuery(c, "from and to parameters are required", "from", "to") {
+		return
+	}
+
+	result, err := h.exchangeService.CompareRates(c.Request.Context(), c.Query("from"), c.Query("to"))
+	if err != nil {
+		writeError(c, "Failed to compare rates", err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GET /rates/table?base=USD or /rates/table?matrix=true
+func (h *ExchangeHandler) GetRateTable(c *gin.Context) {
+	base := strings.ToUpper(c.Query("base"))
+	matrix := c.Query("matrix") == "true"
+
+	if !matrix && !requireQuery(c, "base parameter is required unless matrix=true", "base") {
+		return
+	}
+	if matrix {
+		base = ""
+	}
+
+	result, err := h.exchangeService.GetRateTable(c.Request.Context(), base)
+	if err != nil {
+		writeError(c, "Failed to get rate table", err)
+		return
+	}
+
+	middleware.SetFreshness(c, result.Freshness)
+	renderTable(c, result)
 }
 
 // POST /rates/historical
 func (h *ExchangeHandler) GetHistoricalRates(c *gin.Context) {
 	var req models.HistoricalRateRequest
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid request body",
-			Message: err.Error(),
-			Code:    http.StatusBadRequest,
-		})
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, "Invalid request body", err)
+		return
+	}
+	if !validGranularity(c, req.Granularity, models.HistoricalRateResponse{}, models.HistoricalBucketsResponse{}) {
 		return
 	}
 
-	result, err := h.exchangeService.GetHistoricalRates(&req)
+	result, err := h.exchangeService.GetHistoricalRates(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Failed to get historical rates",
-			Message: err.Error(),
-			Code:    http.StatusBadRequest,
-		})
+		writeError(c, "Failed to get historical rates", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	if aggregated(req.Granularity) {
+		renderBuckets(c, services.AggregateHistoricalRates(result, req.Granularity))
+		return
+	}
+	renderHistorical(c, result)
+}
+
+// POST /rates/historical/bulk
+// Returns the historical rates of several pairs over one date range
+func (h *ExchangeHandler) GetBulkHistoricalRates(c *gin.Context) {
+	var req models.BulkHistoricalRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, "Invalid request body", err)
+		return
+	}
+	if !validGranularity(c, req.Granularity, models.BulkHistoricalResponse{}, models.BulkHistoricalBucketsResponse{}) {
+		return
+	}
+
+	result, err := h.exchangeService.GetBulkHistoricalRates(c.Request.Context(), &req, allowedPairs(c))
+	if err != nil {
+		writeError(c, "Failed to get historical rates", err)
+		return
+	}
+
+	middleware.SetFreshness(c, result.Freshness)
+	if aggregated(req.Granularity) {
+		writeJSON(c, http.StatusOK, services.AggregateBulkHistoricalRates(result, req.Granularity))
+		return
+	}
+	writeJSON(c, http.StatusOK, result)
 }
 
-// GET /rates/historical?from=USD&to=INR&start_date=2025-01-01&end_date=2025-01-07
+// validGranularity checks the requested granularity of historical rates and
+// the fields selected of the response it answers with: daily, or aggregated
+// into buckets
+func validGranularity(c *gin.Context, granularity string, daily, buckets interface{}) bool {
+	if err := utils.ValidateGranularity(granularity); err != nil {
+		writeError(c, "Invalid granularity", err)
+		return false
+	}
+	if aggregated(granularity) {
+		return selectFields(c, buckets)
+	}
+	return selectFields(c, daily)
+}
+
+// aggregated reports whether rates of granularity are aggregated into
+// buckets rather than listed per day
+func aggregated(granularity string) bool {
+	return granularity == models.GranularityWeekly || granularity == models.GranularityMonthly
+}
+
+// GET /rates/historical?from=USD&to=INR&start_date=2025-01-01&end_date=2025-01-07&provider=frankfurter&granularity=weekly
 func (h *ExchangeHandler) GetHistoricalRatesQuery(c *gin.Context) {
 	from := c.Query("from")
 	to := c.Query("to")
 	startDate := c.Query("start_date")
 	endDate := c.Query("end_date")
 
-	if from == "" || to == "" || startDate == "" || endDate == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Missing required parameters",
-			Message: "from, to, start_date, and end_date parameters are required",
-			Code:    http.StatusBadRequest,
-		})
+	if !requireQuery(c, "from, to, start_date, and end_date parameters are required", "from", "to", "start_date", "end_date") {
 		return
 	}
+	granularity := c.Query("granularity")
+	if !validGranularity(c, granularity, models.HistoricalRateResponse{}, models.HistoricalBucketsResponse{}) {
+		return
+	}
+	var maxWait int
+	if maxWaitStr := c.Query("max_wait_ms"); maxWaitStr != "" {
+		var err error
+		if maxWait, err = strconv.Atoi(maxWaitStr); err != nil {
+			writeError(c, "Invalid max_wait_ms", models.NewFieldError(models.ErrCodeInvalidRequest, "max_wait_ms", "max_wait_ms must be a whole number of milliseconds"))
+			return
+		}
+	}
 
 	req := models.HistoricalRateRequest{
+		From:        from,
+		To:          to,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		Provider:    c.Query("provider"),
+		Granularity: granularity,
+		MaxWaitMs:   maxWait,
+	}
+
+	result, err := h.exchangeService.GetHistoricalRates(c.Request.Context(), &req)
+	if err != nil {
+		writeError(c, "Failed to get historical rates", err)
+		return
+	}
+
+	middleware.SetFreshness(c, result.Freshness)
+	if aggregated(granularity) {
+		renderBuckets(c, services.AggregateHistoricalRates(result, granularity))
+		return
+	}
+	renderHistorical(c, result)
+}
+
+// GET /rates/intraday?from=USD&to=INR&date=2025-01-02
+func (h *ExchangeHandler) GetIntradayRates(c *gin.Context) {
+	if !requireQuery(c, "from and to parameters are required", "from", "to") {
+		return
+	}
+
+	result, err := h.exchangeService.GetIntradayRates(c.Request.Context(), c.Query("from"), c.Query("to"), c.Query("date"))
+	if err != nil {
+		writeError(c, "Failed to get intraday rates", err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GET /rates/trend?from=USD&to=INR&window=7&start_date=2025-01-01&end_date=2025-01-31
+func (h *ExchangeHandler) GetRateTrend(c *gin.Context) {
+	from := c.Query("from")
+	to := c.Query("to")
+
+	if !requireQuery(c, "from and to parameters are required", "from", "to") {
+		return
+	}
+
+	window := services.DefaultTrendWindow
+	if windowStr := c.Query("window"); windowStr != "" {
+		var err error
+		if window, err = strconv.Atoi(windowStr); err != nil {
+			writeError(c, "Invalid window", models.NewFieldError(models.ErrCodeInvalidRequest, "window", "window must be a whole number of days"))
+			return
+		}
+	}
+
+	result, err := h.exchangeService.GetRateTrend(c.Request.Context(), &models.TrendRequest{
 		From:      from,
 		To:        to,
-		StartDate: startDate,
-		EndDate:   endDate,
+		Window:    window,
+		StartDate: c.Query("start_date"),
+		EndDate:   c.Query("end_date"),
+		Provider:  c.Query("provider"),
+	})
+	if err != nil {
+		writeError(c, "Failed to get rate trend", err)
+		return
+	}
+
+	middleware.SetFreshness(c, result.Freshness)
+	c.JSON(http.StatusOK, result)
+}
+
+// GET /rates/diff?base=USD&since=2025-01-01&until=2025-02-01&symbols=INR,EUR
+func (h *ExchangeHandler) GetRateDiff(c *gin.Context) {
+	if !requireQuery(c, "base and since parameters are required", "base", "since") {
+		return
+	}
+
+	var symbols []string
+	for _, symbol := range strings.Split(c.Query("symbols"), ",") {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	result, err := h.exchangeService.GetRateDiff(c.Request.Context(), &models.RateDiffRequest{
+		Base:    c.Query("base"),
+		Since:   c.Query("since"),
+		Until:   c.Query("until"),
+		Symbols: symbols,
+	})
+	if err != nil {
+		writeError(c, "Failed to get rate diff", err)
+		return
+	}
+
+	middleware.SetFreshness(c, result.Freshness)
+	c.JSON(http.StatusOK, result)
+}
+
+// GET /rates/recommendation?from=USD&to=INR&windows=30,90
+func (h *ExchangeHandler) GetRateRecommendation(c *gin.Context) {
+	if !requireQuery(c, "from and to parameters are required", "from", "to") {
+		return
+	}
+
+	var windows []int
+	if windowsStr := c.Query("windows"); windowsStr != "" {
+		for _, value := range strings.Split(windowsStr, ",") {
+			days, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				writeError(c, "Invalid windows", models.NewFieldError(models.ErrCodeInvalidRequest, "windows", "windows must be whole numbers of days, got %q", value))
+				return
+			}
+			windows = append(windows, days)
+		}
+	}
+
+	result, err := h.exchangeService.GetRateRecommendation(c.Request.Context(), &models.RecommendationRequest{
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+		Windows:  windows,
+		Provider: c.Query("provider"),
+	})
+	if err != nil {
+		writeError(c, "Failed to get rate recommendation", err)
+		return
+	}
+
+	middleware.SetFreshness(c, result.Freshness)
+	c.JSON(http.StatusOK, result)
+}
+
+// GET /rates/correlation?pairs=USD_INR,EUR_INR&window=30
+// Returns the volatility of each pair and the correlation matrix of their
+// day-over-day changes, for hedging analysis
+func (h *ExchangeHandler) GetCorrelation(c *gin.Context) {
+	if !requireQuery(c, "pairs parameter is required", "pairs") {
+		return
+	}
+
+	var window int
+	if windowStr := c.Query("window"); windowStr != "" {
+		var err error
+		if window, err = strconv.Atoi(windowStr); err != nil {
+			writeError(c, "Invalid window", models.NewFieldError(models.ErrCodeInvalidRequest, "window", "window must be a whole number of days"))
+			return
+		}
+	}
+
+	result, err := h.exchangeService.GetCorrelation(c.Request.Context(), &models.CorrelationRequest{
+		Pairs:    strings.Split(c.Query("pairs"), ","),
+		Window:   window,
+		Provider: c.Query("provider"),
+	})
+	if err != nil {
+		writeError(c, "Failed to get rate correlation", err)
+		return
 	}
 
-	result, err := h.exchangeService.GetHistoricalRates(&req)
+	middleware.SetFreshness(c, result.Freshness)
+	c.JSON(http.StatusOK, result)
+}
+
+// GET /rates/forecast?from=USD&to=INR&horizon=7&history=90&model=drift
+// Projects the rate over the next trading days with a confidence band; the
+// result is indicative, not a quote
+func (h *ExchangeHandler) GetRateForecast(c *gin.Context) {
+	if !requireQuery(c, "from and to parameters are required", "from", "to") {
+		return
+	}
+
+	req := &models.ForecastRequest{
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+		Model:    c.Query("model"),
+		Provider: c.Query("provider"),
+	}
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{{"horizon", &req.Horizon}, {"history", &req.History}} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		var err error
+		if *param.target, err = strconv.Atoi(value); err != nil {
+			writeError(c, "Invalid "+param.name, models.NewFieldError(models.ErrCodeInvalidRequest, param.name, "%s must be a whole number of days", param.name))
+			return
+		}
+	}
+
+	result, err := h.exchangeService.GetRateForecast(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Failed to get historical rates",
-			Message: err.Error(),
-			Code:    http.StatusBadRequest,
-		})
+		writeError(c, "Failed to get rate forecast", err)
 		return
 	}
 
+	middleware.SetFreshness(c, result.Freshness)
 	c.JSON(http.StatusOK, result)
 }
 
+// GET /reports/historical?from=USD&to=INR&start_date=2025-01-01&end_date=2025-01-31&format=xlsx
+// Downloads the historical rates of a pair with a min/max/avg summary as a
+// CSV (default), XLSX or PDF file
+func (h *ExchangeHandler) GetHistoricalReport(c *gin.Context) {
+	if !requireQuery(c, "from, to, start_date, and end_date parameters are required", "from", "to", "start_date", "end_date") {
+		return
+	}
+	format := strings.ToLower(c.DefaultQuery("format", reports.FormatCSV))
+	contentType, ok := reports.ContentTypes[format]
+	if !ok {
+		writeError(c, "Invalid report format", models.NewFieldError(models.ErrCodeValueInvalid, "format", "format must be csv, xlsx or pdf, got %q", format))
+		return
+	}
+
+	req := models.HistoricalRateRequest{
+		From:      c.Query("from"),
+		To:        c.Query("to"),
+		StartDate: c.Query("start_date"),
+		EndDate:   c.Query("end_date"),
+		Provider:  c.Query("provider"),
+	}
+	result, err := h.exchangeService.GetHistoricalRates(c.Request.Context(), &req)
+	if err != nil {
+		writeError(c, "Failed to get historical rates", err)
+		return
+	}
+
+	var buf bytes.Buffer
+	report := reports.NewHistoricalReport(result, req.StartDate, req.EndDate, time.Now())
+	if err := report.Write(&buf, format); err != nil {
+		writeError(c, "Failed to render report", &models.CodedError{Code: models.ErrCodeInternal, Message: err.Error(), Err: err})
+		return
+	}
+
+	middleware.SetFreshness(c, result.Freshness)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="historical_%s_%s_%s_%s.%s"`,
+		result.From, result.To, req.StartDate, req.EndDate, format))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// GET /keys lists the public keys rates and conversions are signed with
+func (h *ExchangeHandler) GetSigningKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"keys": h.exchangeService.GetSigningKeys()})
+}
+
 // GET /currencies
+// Lists the currencies enabled for the caller's tenant
 func (h *ExchangeHandler) GetSupportedCurrencies(c *gin.Context) {
-	currencies := h.exchangeService.GetSupportedCurrencies()
+	tenant := services.TenantFromContext(c.Request.Context())
+	currencies := tenant.FilterCurrencies(h.exchangeService.GetSupportedCurrencies())
+	metadata := make([]models.CurrencyInfo, 0, len(currencies))
+	for _, info := range h.exchangeService.GetCurrencyMetadata() {
+		if tenant.SupportsCurrency(info.Code) {
+			metadata = append(metadata, info)
+		}
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"currencies": currencies,
+		"metadata":   metadata,
 	})
 }
 
 // GET /health
 func (h *ExchangeHandler) GetHealth(c *gin.Context) {
 	health := h.exchangeService.GetServiceHealth()
-	c.JSON(http.StatusOK, health)
+
+	status := http.StatusOK
+	if health["status"] == models.HealthStatusUnhealthy {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, health)
 }
 
-// GET /stats/cache
+// GET /healthz
+// Liveness: the process is up and serving requests
+func (h *ExchangeHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+// GET /readyz
+// Readiness: 503 until the first rate fetch has succeeded
+func (h *ExchangeHandler) Readiness(c *gin.Context) {
+	if ready, reason := h.exchangeService.IsReady(); !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "reason": reason})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
+
+// GET /stats/cache?provider=frankfurter
+// Without a provider, returns the stats of the whole cache
 func (h *ExchangeHandler) GetCacheStats(c *gin.Context) {
+	if provider := c.Query("provider"); provider != "" {
+		stats, err := h.exchangeService.GetProviderCacheStats(provider)
+		if err != nil {
+			writeError(c, "Invalid provider", err)
+			return
+		}
+		c.JSON(http.StatusOK, stats)
+		return
+	}
 	stats := h.exchangeService.GetCacheStats()
 	c.JSON(http.StatusOK, stats)
 }
+
+// GET /stats/client
+func (h *ExchangeHandler) GetClientStats(c *gin.Context) {
+	stats := h.exchangeService.GetClientStats()
+	c.JSON(http.StatusOK, stats)
+}
+
+// GET /stats/providers
+func (h *ExchangeHandler) GetProviderStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"providers": h.exchangeService.GetProviderStatus(),
+	})
+}
+
+// GET /stats/discrepancies
+func (h *ExchangeHandler) GetDiscrepancyStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.exchangeService.GetDiscrepancyStats())
+}
+
+// GET /stats/shadow
+func (h *ExchangeHandler) GetShadowStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.exchangeService.GetShadowStats())
+}
@@ -0,0 +1,517 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/reports"
+	"exchange-rate-service/internal/services"
+	"exchange-rate-service/internal/services/mocks"
+)
+
+func TestExchangeHandler_WithMockService(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	service := &mocks.ExchangeService{
+		GetLatestRateFunc: func(ctx context.Context, from, to, provider string) (*models.LatestRateResponse, error) {
+			if to == "XYZ" {
+				return nil, models.NewError(models.ErrCodeCurrencyUnsupported, "unsupported currency: XYZ")
+			}
+			return &models.LatestRateResponse{From: from, To: to, Rate: 83.5}, nil
+		},
+		CompareRatesFunc: func(ctx context.Context, from, to string) (*models.RateComparison, error) {
+			return &models.RateComparison{From: from, To: to, Quotes: []models.ProviderQuote{
+				{Rate: 83.5, Served: true, Source: models.Source{Provider: "exchangerate-api"}},
+				{Error: "timeout", ErrorCode: models.ErrCodeTimeout, Source: models.Source{Provider: "frankfurter"}},
+			}}, nil
+		},
+		ConvertCurrencyFunc: func(ctx context.Context, req *models.ConversionRequest) (*models.ConversionResponse, error) {
+			resp := &models.ConversionResponse{From: req.From, To: req.To, Amount: req.Amount, ConvertedAmount: req.Amount * 2, Rate: 2}
+			if req.FeePercent != 0 || req.FeeFixed != 0 {
+				resp.Fees = &models.ConversionFees{FeePercent: req.FeePercent, FeeFixed: req.FeeFixed}
+			}
+			return resp, nil
+		},
+		SimulateConversionFunc: func(ctx context.Context, req *models.SimulationRequest) (*models.SimulationResponse, error) {
+			actual := &models.ConversionResponse{From: req.From, To: req.To, Amount: req.Amount, ConvertedAmount: req.Amount * 2, Rate: 2}
+			simulated := req.Amount * 2 * (1 + *req.RateOffsetPercent/100)
+			return &models.SimulationResponse{Actual: actual, Simulated: models.SimulatedConversion{ConvertedAmount: simulated}, Difference: simulated - actual.ConvertedAmount}, nil
+		},
+		ConvertChainFunc: func(ctx context.Context, req *models.ChainConversionRequest, allowed services.PairFilter) (*models.ChainConversionResponse, error) {
+			return &models.ChainConversionResponse{Path: req.Path, Amount: req.Amount, Legs: make([]models.ChainLeg, len(req.Path)-1)}, nil
+		},
+		GetRateRecommendationFunc: func(ctx context.Context, req *models.RecommendationRequest) (*models.RecommendationResponse, error) {
+			return &models.RecommendationResponse{From: req.From, To: req.To, Signal: models.SignalFavorable, Windows: make([]models.RecommendationWindow, len(req.Windows))}, nil
+		},
+		GetCorrelationFunc: func(ctx context.Context, req *models.CorrelationRequest) (*models.CorrelationResponse, error) {
+			return &models.CorrelationResponse{Window: req.Window, Pairs: make([]models.PairVolatility, len(req.Pairs))}, nil
+		},
+		GetRateForecastFunc: func(ctx context.Context, req *models.ForecastRequest) (*models.ForecastResponse, error) {
+			return &models.ForecastResponse{From: req.From, To: req.To, Horizon: req.Horizon, Indicative: true, Points: make([]models.ForecastPoint, req.Horizon)}, nil
+		},
+		GetRateDiffFunc: func(ctx context.Context, req *models.RateDiffRequest) (*models.RateDiffResponse, error) {
+			changes := make([]models.RateDiff, len(req.Symbols))
+			for i, symbol := range req.Symbols {
+				changes[i] = models.RateDiff{Quote: symbol}
+			}
+			return &models.RateDiffResponse{Base: req.Base, Since: req.Since, Changes: changes}, nil
+		},
+		GetIntradayRatesFunc: func(ctx context.Context, from, to, date string) (*models.IntradayRateResponse, error) {
+			return &models.IntradayRateResponse{From: from, To: to, Date: date, Rates: []models.IntradayRate{{Rate: 83.5}}}, nil
+		},
+		GetSigningKeysFunc: func() []models.PublicKey {
+			return []models.PublicKey{{KeyID: "3f2a9c", Algorithm: models.SignatureAlgorithm}}
+		},
+		RecordConversionFunc:      func(caller string, conversion *models.ConversionResponse) error { return nil },
+		RecordChainConversionFunc: func(caller string, chain *models.ChainConversionResponse) error { return nil },
+		IsReadyFunc:               func() (bool, string) { return false, "no successful rate fetch yet" },
+	}
+	handler := NewExchangeHandler(service)
+	router := gin.New()
+	router.GET("/api/v1/rates/latest", handler.GetLatestRate)
+	router.GET("/api/v1/rates/compare", handler.CompareRates)
+	router.POST("/api/v1/convert", handler.ConvertCurrency)
+	router.GET("/api/v1/convert", handler.ConvertCurrencyQuery)
+	router.POST("/api/v1/convert/chain", handler.ConvertChain)
+	router.POST("/api/v1/convert/simulate", handler.SimulateConversion)
+	router.GET("/api/v1/rates/recommendation", handler.GetRateRecommendation)
+	router.GET("/api/v1/rates/intraday", handler.GetIntradayRates)
+	router.GET("/api/v1/rates/correlation", handler.GetCorrelation)
+	router.GET("/api/v1/rates/forecast", handler.GetRateForecast)
+	router.GET("/api/v1/rates/diff", handler.GetRateDiff)
+	router.GET("/api/v1/keys", handler.GetSigningKeys)
+	router.GET("/readyz", handler.Readiness)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{"latest rate", http.MethodGet, "/api/v1/rates/latest?from=USD&to=INR", "", http.StatusOK, `"rate":83.5`},
+		{"service error", http.MethodGet, "/api/v1/rates/latest?from=USD&to=XYZ", "", http.StatusUnprocessableEntity, models.ErrCodeCurrencyUnsupported},
+		{"missing parameter", http.MethodGet, "/api/v1/rates/latest?from=USD", "", http.StatusBadRequest, models.ErrCodeMissingParameter},
+		{"rate comparison", http.MethodGet, "/api/v1/rates/compare?from=USD&to=INR", "", http.StatusOK, `"quotes":[{"rate":83.5,"served":true,"provider":"exchangerate-api"},{"error":"timeout","error_code":"TIMEOUT","provider":"frankfurter"}]`},
+		{"rate comparison without pair", http.MethodGet, "/api/v1/rates/compare?to=INR", "", http.StatusBadRequest, models.ErrCodeMissingParameter},
+		{"conversion", http.MethodPost, "/api/v1/convert", `{"from":"USD","to":"EUR","amount":10}`, http.StatusOK, `"converted_amount":20`},
+		{"conversion with fees", http.MethodGet, "/api/v1/convert?from=USD&to=EUR&amount=10&fee_percent=1.5&fee_fixed=0.25", "", http.StatusOK, `"fees":{"fee_percent":1.5,"fee_fixed":0.25`},
+		{"conversion with bad strict", http.MethodGet, "/api/v1/convert?from=USD&to=EUR&amount=10&strict=maybe", "", http.StatusBadRequest, `"field":"strict"`},
+		{"conversion with bad fee", http.MethodGet, "/api/v1/convert?from=USD&to=EUR&amount=10&fee_fixed=free", "", http.StatusBadRequest, `"field":"fee_fixed"`},
+		{"conversion chain", http.MethodPost, "/api/v1/convert/chain", `{"path":["USD","EUR","INR"],"amount":10}`, http.StatusOK, `"legs":[{`},
+		{"simulation", http.MethodPost, "/api/v1/convert/simulate", `{"from":"USD","to":"EUR","amount":10,"rate_offset_percent":5}`, http.StatusOK, `"difference":1`},
+		{"simulation without amount", http.MethodPost, "/api/v1/convert/simulate", `{"from":"USD","to":"EUR","rate":2}`, http.StatusBadRequest, "Invalid request body"},
+		{"chain without path", http.MethodPost, "/api/v1/convert/chain", `{"amount":10}`, http.StatusBadRequest, "Invalid request body"},
+		{"recommendation", http.MethodGet, "/api/v1/rates/recommendation?from=USD&to=INR&windows=30,%2090", "", http.StatusOK, `"windows":[{`},
+		{"recommendation with bad windows", http.MethodGet, "/api/v1/rates/recommendation?from=USD&to=INR&windows=30,month", "", http.StatusBadRequest, models.ErrCodeInvalidRequest},
+		{"intraday rates", http.MethodGet, "/api/v1/rates/intraday?from=USD&to=INR&date=2025-01-02", "", http.StatusOK, `"date":"2025-01-02","rates":[{`},
+		{"intraday rates without pair", http.MethodGet, "/api/v1/rates/intraday?from=USD", "", http.StatusBadRequest, models.ErrCodeMissingParameter},
+		{"correlation", http.MethodGet, "/api/v1/rates/correlation?pairs=USD_INR,EUR_INR&window=30", "", http.StatusOK, `"window":30,"start_date":"","end_date":"","pairs":[{`},
+		{"correlation without pairs", http.MethodGet, "/api/v1/rates/correlation?window=30", "", http.StatusBadRequest, models.ErrCodeMissingParameter},
+		{"correlation with bad window", http.MethodGet, "/api/v1/rates/correlation?pairs=USD_INR,EUR_INR&window=month", "", http.StatusBadRequest, `"field":"window"`},
+		{"forecast", http.MethodGet, "/api/v1/rates/forecast?from=USD&to=INR&horizon=3", "", http.StatusOK, `"horizon":3`},
+		{"forecast without pair", http.MethodGet, "/api/v1/rates/forecast?from=USD", "", http.StatusBadRequest, models.ErrCodeMissingParameter},
+		{"forecast with bad history", http.MethodGet, "/api/v1/rates/forecast?from=USD&to=INR&history=year", "", http.StatusBadRequest, `"field":"history"`},
+		{"rate diff", http.MethodGet, "/api/v1/rates/diff?base=USD&since=2025-01-02&symbols=INR,%20EUR,", "", http.StatusOK, `"since":"2025-01-02","changes":[{"quote":"INR"`},
+		{"rate diff without since", http.MethodGet, "/api/v1/rates/diff?base=USD", "", http.StatusBadRequest, models.ErrCodeMissingParameter},
+		{"signing keys", http.MethodGet, "/api/v1/keys", "", http.StatusOK, `"keys":[{"key_id":"3f2a9c","algorithm":"Ed25519"`},
+		{"not ready", http.MethodGet, "/readyz", "", http.StatusServiceUnavailable, "no successful rate fetch yet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.want)
+			assert.True(t, json.Valid(w.Body.Bytes()))
+		})
+	}
+
+	assert.Equal(t, 2, service.CallCount("GetLatestRate"), "the missing parameter is rejected before the service")
+	assert.Equal(t, 1, service.CallCount("CompareRates"), "the missing parameter is rejected before the service")
+	assert.Equal(t, 2, service.CallCount("ConvertCurrency"), "the bad fee is rejected before the service")
+	assert.Equal(t, 2, service.CallCount("RecordConversion"), "conversions are audited")
+	assert.Equal(t, 1, service.CallCount("ConvertChain"))
+	assert.Equal(t, 1, service.CallCount("SimulateConversion"))
+	assert.Equal(t, 1, service.CallCount("GetRateRecommendation"), "bad windows are rejected before the service")
+	assert.Equal(t, 1, service.CallCount("GetIntradayRates"))
+	assert.Equal(t, 1, service.CallCount("GetCorrelation"), "bad requests are rejected before the service")
+	assert.Equal(t, 1, service.CallCount("GetRateForecast"), "bad requests are rejected before the service")
+	assert.Equal(t, 1, service.CallCount("GetRateDiff"), "the missing parameter is rejected before the service")
+}
+
+func TestMockExchangeService_PanicsWhenNotStubbed(t *testing.T) {
+	service := &mocks.ExchangeService{}
+	require.PanicsWithValue(t, "mocks.ExchangeService: ClearCache called but ClearCacheFunc is not set", service.ClearCache)
+	assert.Equal(t, []string{"ClearCache"}, service.Calls())
+}
+
+func TestExchangeHandler_BulkHistoricalRates(t *testing.T) {
+	var got *models.BulkHistoricalRequest
+	service := &mocks.ExchangeService{
+		GetBulkHistoricalRatesFunc: func(ctx context.Context, req *models.BulkHistoricalRequest, allowed services.PairFilter) (*models.BulkHistoricalResponse, error) {
+			got = req
+			return &models.BulkHistoricalResponse{
+				StartDate: req.StartDate,
+				EndDate:   req.EndDate,
+				Rates: map[string]map[string]models.HistoricalRate{
+					"USD_INR": {"2025-01-02": {Rate: 85.5}},
+				},
+				MissingDates: map[string][]models.MissingDate{},
+			}, nil
+		},
+	}
+	router := gin.New()
+	router.POST("/rates/historical/bulk", NewExchangeHandler(service).GetBulkHistoricalRates)
+
+	body := `{"pairs":["USD_INR","EUR_INR"],"start_date":"2025-01-02","end_date":"2025-01-03"}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/rates/historical/bulk", bytes.NewBufferString(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"USD_INR", "EUR_INR"}, got.Pairs)
+	var resp models.BulkHistoricalResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 85.5, resp.Rates["USD_INR"]["2025-01-02"].Rate)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/rates/historical/bulk", bytes.NewBufferString(`{"pairs":["USD_INR"]}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code, "the date range is required")
+	assert.Equal(t, 1, service.CallCount("GetBulkHistoricalRates"))
+}
+
+func TestExchangeHandler_CacheStatsByProvider(t *testing.T) {
+	service := &mocks.ExchangeService{
+		GetCacheStatsFunc: func() map[string]interface{} {
+			return map[string]interface{}{"total_items": 3}
+		},
+		GetProviderCacheStatsFunc: func(provider string) (map[string]interface{}, error) {
+			if provider != "frankfurter" {
+				return nil, &models.CodedError{Code: models.ErrCodeProviderUnknown, Field: "provider", Message: "unknown provider " + provider}
+			}
+			return map[string]interface{}{"provider": provider, "total_items": 1}, nil
+		},
+	}
+	router := gin.New()
+	router.GET("/stats/cache", NewExchangeHandler(service).GetCacheStats)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/stats/cache", http.StatusOK, `{"total_items":3}`},
+		{"/stats/cache?provider=frankfurter", http.StatusOK, `{"provider":"frankfurter","total_items":1}`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		require.Equal(t, tt.status, w.Code, tt.path)
+		assert.JSONEq(t, tt.body, w.Body.String(), tt.path)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stats/cache?provider=nope", nil))
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var resp models.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, models.ErrCodeProviderUnknown, resp.ErrorCode)
+}
+
+func TestExchangeHandler_Metrics(t *testing.T) {
+	service := &mocks.ExchangeService{
+		GetCacheStatsFunc: func() map[string]interface{} {
+			return map[string]interface{}{
+				"hits":        int64(12),
+				"misses":      int64(3),
+				"valid_items": 5,
+				"operations": map[string]interface{}{
+					"get": map[string]interface{}{"calls": int64(15), "p50_ms": 0.5, "p90_ms": 1.0, "p99_ms": 2.5, "total_ms": 9.0},
+				},
+			}
+		},
+	}
+	router := gin.New()
+	router.GET("/metrics", NewExchangeHandler(service).GetMetrics)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, prometheusContentType, w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.Contains(t, body, "# TYPE exchange_cache_hits_total counter\nexchange_cache_hits_total 12\n")
+	assert.Contains(t, body, "exchange_cache_misses_total 3\n")
+	assert.NotContains(t, body, "exchange_cache_evictions_total", "stats the backend doesn't report are left out")
+	assert.Contains(t, body, `exchange_cache_items{state="valid"} 5`)
+	assert.Contains(t, body, `exchange_cache_operation_duration_seconds{operation="get",quantile="0.99"} 0.0025`)
+	assert.Contains(t, body, `exchange_cache_operation_duration_seconds_count{operation="get"} 15`)
+}
+
+func TestExchangeHandler_SLOMetrics(t *testing.T) {
+	cfg := services.DefaultSLOConfig()
+	cfg.MinRequests = 1
+	cfg.Targets = map[string]services.SLOTarget{"/api/v1/rates/latest": {P99: 100 * time.Millisecond}}
+	tracker := services.NewSLOTracker(cfg)
+	tracker.Observe("/api/v1/rates/latest", 250*time.Millisecond, http.StatusOK)
+	tracker.Observe("/api/v1/currencies", time.Millisecond, http.StatusInternalServerError)
+	tracker.Evaluate(time.Now())
+
+	service := &mocks.ExchangeService{
+		GetCacheStatsFunc: func() map[string]interface{} { return map[string]interface{}{} },
+	}
+	handler := NewExchangeHandler(service)
+	handler.SetSLOTracker(tracker)
+	router := gin.New()
+	router.GET("/metrics", handler.GetMetrics)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `exchange_slo_latency_seconds{endpoint="/api/v1/rates/latest",quantile="0.99"} 0.25`)
+	assert.Contains(t, body, `exchange_slo_error_rate_percent{endpoint="/api/v1/currencies"} 100`)
+	assert.Contains(t, body, `exchange_slo_target{endpoint="/api/v1/rates/latest",objective="p99"} 0.1`)
+	assert.Contains(t, body, `exchange_slo_violated{endpoint="/api/v1/rates/latest",objective="p99"} 1`)
+	assert.Contains(t, body, `exchange_slo_violations_total{endpoint="/api/v1/rates/latest",objective="p99"} 1`)
+	assert.NotContains(t, body, `exchange_slo_target{endpoint="/api/v1/currencies"`, "endpoints without targets are only measured")
+}
+
+func TestExchangeHandler_TenantCurrencies(t *testing.T) {
+	service := &mocks.ExchangeService{
+		GetSupportedCurrenciesFunc: func() []string { return []string{"EUR", "INR", "USD"} },
+		GetCurrencyMetadataFunc: func() []models.CurrencyInfo {
+			return []models.CurrencyInfo{{Code: "EUR"}, {Code: "INR"}, {Code: "USD"}}
+		},
+	}
+	tenant := &services.Tenant{ID: "acme", Currencies: []string{"USD", "INR"}}
+	router := gin.New()
+	router.GET("/api/v1/currencies", func(c *gin.Context) {
+		c.Request = c.Request.WithContext(services.WithTenant(c.Request.Context(), tenant))
+	}, NewExchangeHandler(service).GetSupportedCurrencies)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/currencies", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Currencies []string              `json:"currencies"`
+		Metadata   []models.CurrencyInfo `json:"metadata"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"INR", "USD"}, resp.Currencies)
+	assert.Len(t, resp.Metadata, 2)
+}
+
+func TestExchangeHandler_HistoricalReport(t *testing.T) {
+	service := &mocks.ExchangeService{
+		GetHistoricalRatesFunc: func(ctx context.Context, req *models.HistoricalRateRequest) (*models.HistoricalRateResponse, error) {
+			return &models.HistoricalRateResponse{From: req.From, To: req.To, Rates: map[string]models.HistoricalRate{
+				"2025-01-02": {Rate: 80},
+				"2025-01-03": {Rate: 84},
+			}}, nil
+		},
+	}
+	router := gin.New()
+	router.GET("/api/v1/reports/historical", NewExchangeHandler(service).GetHistoricalReport)
+
+	tests := []struct {
+		name        string
+		query       string
+		status      int
+		contentType string
+		want        string
+	}{
+		{"CSV by default", "from=USD&to=INR&start_date=2025-01-02&end_date=2025-01-03", http.StatusOK, "text/csv; charset=utf-8", "Average,82,,over 2 trading days"},
+		{"XLSX", "from=USD&to=INR&start_date=2025-01-02&end_date=2025-01-03&format=xlsx", http.StatusOK, reports.ContentTypes[reports.FormatXLSX], "PK"},
+		{"PDF", "from=USD&to=INR&start_date=2025-01-02&end_date=2025-01-03&format=PDF", http.StatusOK, "application/pdf", "%PDF-1.4"},
+		{"Unknown format", "from=USD&to=INR&start_date=2025-01-02&end_date=2025-01-03&format=docx", http.StatusUnprocessableEntity, "application/json; charset=utf-8", models.ErrCodeValueInvalid},
+		{"Missing dates", "from=USD&to=INR", http.StatusBadRequest, "application/json; charset=utf-8", models.ErrCodeMissingParameter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/reports/historical?"+tt.query, nil))
+
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"))
+			assert.Contains(t, w.Body.String(), tt.want)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/reports/historical?from=USD&to=INR&start_date=2025-01-02&end_date=2025-01-03&format=xlsx", nil))
+	assert.Equal(t, `attachment; filename="historical_USD_INR_2025-01-02_2025-01-03.xlsx"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, 4, service.CallCount("GetHistoricalRates"), "invalid requests are rejected before the service")
+}
+
+func TestExchangeHandler_HistoricalGranularity(t *testing.T) {
+	service := &mocks.ExchangeService{
+		GetHistoricalRatesFunc: func(ctx context.Context, req *models.HistoricalRateRequest) (*models.HistoricalRateResponse, error) {
+			return &models.HistoricalRateResponse{From: req.From, To: req.To, MissingDates: []models.MissingDate{}, Rates: map[string]models.HistoricalRate{
+				"2025-01-02": {Rate: 85.5},
+				"2025-01-03": {Rate: 85.7},
+				"2025-01-06": {Rate: 85.1},
+			}}, nil
+		},
+	}
+	handler := NewExchangeHandler(service)
+	router := gin.New()
+	router.GET("/rates/historical", handler.GetHistoricalRatesQuery)
+	router.POST("/rates/historical", handler.GetHistoricalRates)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{"weekly", http.MethodGet, "/rates/historical?from=USD&to=INR&start_date=2025-01-02&end_date=2025-01-06&granularity=weekly&fields=granularity,buckets", "", http.StatusOK,
+			`{"granularity":"weekly","buckets":[{"start":"2025-01-02","end":"2025-01-03","open":85.5,"high":85.7,"low":85.5,"close":85.7,"average":85.6,"days":2},{"start":"2025-01-06","end":"2025-01-06","open":85.1,"high":85.1,"low":85.1,"close":85.1,"average":85.1,"days":1}]}`},
+		{"monthly body request", http.MethodPost, "/rates/historical?fields=buckets", `{"from":"USD","to":"INR","start_date":"2025-01-02","end_date":"2025-01-06","granularity":"monthly"}`, http.StatusOK,
+			`{"buckets":[{"start":"2025-01-02","end":"2025-01-06","open":85.5,"high":85.7,"low":85.1,"close":85.1,"average":85.433333,"days":3}]}`},
+		{"daily keeps rates", http.MethodGet, "/rates/historical?from=USD&to=INR&start_date=2025-01-02&end_date=2025-01-06&granularity=daily&fields=from", "", http.StatusOK, `{"from":"USD"}`},
+		{"rates are not a field of buckets", http.MethodGet, "/rates/historical?from=USD&to=INR&start_date=2025-01-02&end_date=2025-01-06&granularity=weekly&fields=rates", "", http.StatusUnprocessableEntity, `"field":"fields"`},
+		{"csv buckets", http.MethodGet, "/rates/historical?from=USD&to=INR&start_date=2025-01-02&end_date=2025-01-06&granularity=monthly&format=csv", "", http.StatusOK, "2025-01-02,2025-01-06,USD,INR,85.5,85.7,85.1,85.1,85.433333,3"},
+		{"unknown granularity", http.MethodGet, "/rates/historical?from=USD&to=INR&start_date=2025-01-02&end_date=2025-01-06&granularity=yearly", "", http.StatusUnprocessableEntity, `"field":"granularity"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body)))
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.status == http.StatusOK && tt.want[0] == '{' {
+				assert.JSONEq(t, tt.want, w.Body.String())
+				return
+			}
+			assert.Contains(t, w.Body.String(), tt.want)
+		})
+	}
+}
+
+func TestExchangeHandler_HistoricalMaxWait(t *testing.T) {
+	var got *models.HistoricalRateRequest
+	service := &mocks.ExchangeService{
+		GetHistoricalRatesFunc: func(ctx context.Context, req *models.HistoricalRateRequest) (*models.HistoricalRateResponse, error) {
+			got = req
+			return &models.HistoricalRateResponse{From: req.From, To: req.To, Rates: map[string]models.HistoricalRate{}, MissingDates: []models.MissingDate{
+				{Date: "2025-01-02", Reason: models.MissingPending},
+			}}, nil
+		},
+	}
+	router := gin.New()
+	router.GET("/rates/historical", NewExchangeHandler(service).GetHistoricalRatesQuery)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rates/historical?from=USD&to=INR&start_date=2025-01-02&end_date=2025-01-02&max_wait_ms=250", nil))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 250, got.MaxWaitMs)
+	assert.Contains(t, w.Body.String(), `{"date":"2025-01-02","reason":"pending"}`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rates/historical?from=USD&to=INR&start_date=2025-01-02&end_date=2025-01-02&max_wait_ms=soon", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"max_wait_ms"`)
+}
+
+func TestExchangeHandler_SparseFieldsets(t *testing.T) {
+	service := &mocks.ExchangeService{
+		ConvertCurrencyFunc: func(ctx context.Context, req *models.ConversionRequest) (*models.ConversionResponse, error) {
+			return &models.ConversionResponse{From: req.From, To: req.To, Amount: req.Amount, ConvertedAmount: req.Amount * 2, Rate: 2}, nil
+		},
+		GetHistoricalRatesFunc: func(ctx context.Context, req *models.HistoricalRateRequest) (*models.HistoricalRateResponse, error) {
+			resp := &models.HistoricalRateResponse{From: req.From, To: req.To, Rates: map[string]models.HistoricalRate{"2025-01-02": {Rate: 85.5}}}
+			resp.Provider = "frankfurter"
+			return resp, nil
+		},
+		RecordConversionFunc: func(caller string, conversion *models.ConversionResponse) error { return nil },
+	}
+	handler := NewExchangeHandler(service)
+	router := gin.New()
+	router.GET("/convert", handler.ConvertCurrencyQuery)
+	router.POST("/convert", handler.ConvertCurrency)
+	router.GET("/rates/historical", handler.GetHistoricalRatesQuery)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{"selected fields", http.MethodGet, "/convert?from=USD&to=EUR&amount=10&fields=converted_amount,%20from", "", http.StatusOK, `{"converted_amount":20,"from":"USD"}`},
+		{"selected fields of a body request", http.MethodPost, "/convert?fields=rate", `{"from":"USD","to":"EUR","amount":10}`, http.StatusOK, `{"rate":2}`},
+		{"omitted field stays omitted", http.MethodGet, "/convert?from=USD&to=EUR&amount=10&fields=derived", "", http.StatusOK, `{}`},
+		{"field of an embedded struct", http.MethodGet, "/rates/historical?from=USD&to=INR&start_date=2025-01-02&end_date=2025-01-02&fields=rates,provider", "", http.StatusOK, `{"provider":"frankfurter","rates":{"2025-01-02":{"rate":85.5,"date":"0001-01-01T00:00:00Z"}}}`},
+		{"unknown field", http.MethodGet, "/convert?from=USD&to=EUR&amount=10&fields=from,freshness", "", http.StatusUnprocessableEntity, `"field":"fields"`},
+		{"csv ignores fields", http.MethodGet, "/convert?from=USD&to=EUR&amount=10&fields=from&format=csv", "", http.StatusOK, "from,to,amount,converted_amount"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body)))
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.status == http.StatusOK && tt.want[0] == '{' {
+				assert.JSONEq(t, tt.want, w.Body.String())
+				return
+			}
+			assert.Contains(t, w.Body.String(), tt.want)
+		})
+	}
+	assert.Equal(t, 4, service.CallCount("ConvertCurrency"), "unknown fields are rejected before the service")
+}
+
+func TestExchangeHandler_UnauditedConversionsFail(t *testing.T) {
+	service := &mocks.ExchangeService{
+		ConvertCurrencyFunc: func(ctx context.Context, req *models.ConversionRequest) (*models.ConversionResponse, error) {
+			return &models.ConversionResponse{From: req.From, To: req.To, Amount: req.Amount, ConvertedAmount: req.Amount * 2, Rate: 2}, nil
+		},
+		ConvertChainFunc: func(ctx context.Context, req *models.ChainConversionRequest, allowed services.PairFilter) (*models.ChainConversionResponse, error) {
+			return &models.ChainConversionResponse{From: req.Path[0], To: req.Path[len(req.Path)-1], Path: req.Path, Amount: req.Amount}, nil
+		},
+		RecordConversionFunc: func(caller string, conversion *models.ConversionResponse) error {
+			return &models.CodedError{Code: models.ErrCodeInternal, Message: "the conversion could not be recorded", Err: errors.New("disk full")}
+		},
+		RecordChainConversionFunc: func(caller string, chain *models.ChainConversionResponse) error {
+			return &models.CodedError{Code: models.ErrCodeInternal, Message: "the conversion could not be recorded", Err: errors.New("disk full")}
+		},
+	}
+	handler := NewExchangeHandler(service)
+	router := gin.New()
+	router.GET("/convert", handler.ConvertCurrencyQuery)
+	router.POST("/convert", handler.ConvertCurrency)
+	router.POST("/convert/chain", handler.ConvertChain)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/convert?from=USD&to=EUR&amount=10", nil),
+		httptest.NewRequest(http.MethodPost, "/convert", bytes.NewBufferString(`{"from":"USD","to":"EUR","amount":10}`)),
+		httptest.NewRequest(http.MethodPost, "/convert/chain", bytes.NewBufferString(`{"path":["USD","EUR","INR"],"amount":10}`)),
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusInternalServerError, w.Code, req.URL.Path)
+		assert.Contains(t, w.Body.String(), `"error_code":"INTERNAL_ERROR"`)
+		assert.NotContains(t, w.Body.String(), "converted_amount", "a conversion is never quoted unaudited")
+		assert.NotContains(t, w.Body.String(), "disk full")
+	}
+}
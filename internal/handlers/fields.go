@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/models"
+)
+
+// fieldsKey is the context key of the response fields a request selected
+const fieldsKey = "fields"
+
+// selectFields reads the comma-separated fields of ?fields=, JSON:API's
+// sparse fieldsets, and keeps them for writeJSON. They must be top-level JSON
+// fields of response; an unknown one is rejected with VALUE_INVALID and
+// false is returned.
+func selectFields(c *gin.Context, response interface{}) bool {
+	value := c.Query("fields")
+	if value == "" {
+		return true
+	}
+
+	known := jsonFields(reflect.TypeOf(response))
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !known[field] {
+			writeError(c, "Invalid fields", models.NewFieldError(models.ErrCodeValueInvalid, "fields", "unknown field %q", field))
+			return false
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) > 0 {
+		c.Set(fieldsKey, fields)
+	}
+	return true
+}
+
+// writeJSON writes response as JSON, with only the fields the request
+// selected with selectFields. Selected fields the response omits, such as an
+// empty derived, stay omitted.
+func writeJSON(c *gin.Context, status int, response interface{}) {
+	fields := c.GetStringSlice(fieldsKey)
+	if len(fields) == 0 {
+		c.JSON(status, response)
+		return
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		c.JSON(status, response) // Fails the same way, through gin
+		return
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		c.JSON(status, response)
+		return
+	}
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		}
+	}
+	c.JSON(status, selected)
+}
+
+// jsonFields returns the names of the JSON fields of a struct type, with
+// those of its embedded structs
+func jsonFields(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	fields := make(map[string]bool)
+	if t.Kind() != reflect.Struct {
+		return fields
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" && field.Anonymous {
+			for embedded := range jsonFields(field.Type) {
+				fields[embedded] = true
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = true
+	}
+	return fields
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/models"
+)
+
+// Response formats selectable with ?format= or the Accept header
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+	formatXML  = "xml"
+)
+
+// negotiateFormat picks the response format. An explicit ?format= wins over
+// the Accept header; anything unrecognised falls back to JSON.
+func negotiateFormat(c *gin.Context) string {
+	switch strings.ToLower(c.Query("format")) {
+	case formatCSV:
+		return formatCSV
+	case formatXML:
+		return formatXML
+	case formatJSON:
+		return formatJSON
+	}
+
+	c.Writer.Header().Add("Vary", "Accept")
+	for _, accepted := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/json":
+			return formatJSON
+		case "text/csv":
+			return formatCSV
+		case "application/xml", "text/xml":
+			return formatXML
+		}
+	}
+	return formatJSON
+}
+
+type xmlConversion struct {
+	XMLName         xml.Name               `xml:"conversion"`
+	ConversionID    string                 `xml:"conversion_id,omitempty"`
+	From            string                 `xml:"from"`
+	To              string                 `xml:"to"`
+	Amount          float64                `xml:"amount"`
+	ConvertedAmount float64                `xml:"converted_amount"`
+	Rate            float64                `xml:"rate"`
+	MidMarketRate   float64                `xml:"mid_market_rate"`
+	MarkupPercent   float64                `xml:"markup_percent"`
+	Derived         string                 `xml:"derived,omitempty"`
+	Date            time.Time              `xml:"date"`
+	RateDate        string                 `xml:"rate_date,omitempty"`
+	MarketClosed    bool                   `xml:"market_closed,omitempty"`
+	RateTimestamp   *time.Time             `xml:"rate_timestamp,omitempty"`
+	Provider        string                 `xml:"provider,omitempty"`
+	PublishedAt     *time.Time             `xml:"published_at,omitempty"`
+	FetchedAt       *time.Time             `xml:"fetched_at,omitempty"`
+	Origin          string                 `xml:"origin,omitempty"`
+	Stale           bool                   `xml:"stale,omitempty"`
+	Formatted       *xmlFormatted          `xml:"formatted,omitempty"`
+	Fees            *models.ConversionFees `xml:"fees,omitempty"`
+	Signature       *models.Signature      `xml:"signature,omitempty"`
+}
+
+type xmlFormatted struct {
+	Locale          string `xml:"locale,attr"`
+	Amount          string `xml:"amount"`
+	ConvertedAmount string `xml:"converted_amount"`
+	Rate            string `xml:"rate"`
+}
+
+type xmlHistorical struct {
+	XMLName     xml.Name            `xml:"historical_rates"`
+	From        string              `xml:"from,attr"`
+	To          string              `xml:"to,attr"`
+	Provider    string              `xml:"provider,attr,omitempty"`
+	PublishedAt *time.Time          `xml:"published_at,attr,omitempty"`
+	FetchedAt   *time.Time          `xml:"fetched_at,attr,omitempty"`
+	Origin      string              `xml:"origin,attr,omitempty"`
+	Rates       []xmlHistoricalRate `xml:"rate"`
+	Missing     []xmlMissingDate    `xml:"missing>date,omitempty"`
+}
+
+type xmlMissingDate struct {
+	Date   string `xml:",chardata"`
+	Reason string `xml:"reason,attr"`
+}
+
+type xmlHistoricalRate struct {
+	Date         string  `xml:"date,attr"`
+	Derived      string  `xml:"derived,attr,omitempty"`
+	ObservedDate string  `xml:"observed_date,attr,omitempty"`
+	Value        float64 `xml:",chardata"`
+}
+
+func renderConversion(c *gin.Context, result *models.ConversionResponse) {
+	switch negotiateFormat(c) {
+	case formatCSV:
+		header := []string{"from", "to", "amount", "converted_amount", "rate", "mid_market_rate", "markup_percent", "derived", "date"}
+		record := []string{
+			result.From,
+			result.To,
+			formatFloat(result.Amount),
+			formatFloat(result.ConvertedAmount),
+			formatFloat(result.Rate),
+			formatFloat(result.MidMarketRate),
+			formatFloat(result.MarkupPercent),
+			result.Derived,
+			result.Date.Format(time.RFC3339),
+		}
+		if fees := result.Fees; fees != nil {
+			header = append(header, "mid_market_amount", "fee_amount", "net_amount")
+			record = append(record, formatFloat(fees.MidMarketAmount), formatFloat(fees.FeeAmount), formatFloat(fees.NetAmount))
+		}
+		if result.ConversionID != "" {
+			header = append(header, "conversion_id")
+			record = append(record, result.ConversionID)
+		}
+		writeCSV(c, fmt.Sprintf("conversion_%s_%s.csv", result.From, result.To), [][]string{header, record})
+	case formatXML:
+		var formatted *xmlFormatted
+		if result.Formatted != nil {
+			formatted = &xmlFormatted{
+				Locale:          result.Formatted.Locale,
+				Amount:          result.Formatted.Amount,
+				ConvertedAmount: result.Formatted.ConvertedAmount,
+				Rate:            result.Formatted.Rate,
+			}
+		}
+		c.XML(http.StatusOK, xmlConversion{
+			ConversionID:    result.ConversionID,
+			From:            result.From,
+			To:              result.To,
+			Amount:          result.Amount,
+			ConvertedAmount: result.ConvertedAmount,
+			Rate:            result.Rate,
+			MidMarketRate:   result.MidMarketRate,
+			MarkupPercent:   result.MarkupPercent,
+			Derived:         result.Derived,
+			Date:            result.Date,
+			RateDate:        result.RateDate,
+			MarketClosed:    result.MarketClosed,
+			RateTimestamp:   result.RateTimestamp,
+			Provider:        result.Provider,
+			PublishedAt:     result.PublishedAt,
+			FetchedAt:       result.Source.FetchedAt,
+			Origin:          result.Origin,
+			Stale:           result.Stale,
+			Formatted:       formatted,
+			Fees:            result.Fees,
+			Signature:       result.Signature,
+		})
+	default:
+		writeJSON(c, http.StatusOK, result)
+	}
+}
+
+func renderHistorical(c *gin.Context, result *models.HistoricalRateResponse) {
+	dates := make([]string, 0, len(result.Rates))
+	for date := range result.Rates {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	switch negotiateFormat(c) {
+	case formatCSV:
+		rows := [][]string{{"date", "from", "to", "rate", "derived", "observed_date"}}
+		for _, date := range dates {
+			rate := result.Rates[date]
+			rows = append(rows, []string{date, result.From, result.To, formatFloat(rate.Rate), rate.Derived, rate.ObservedDate})
+		}
+		writeCSV(c, fmt.Sprintf("historical_%s_%s.csv", result.From, result.To), rows)
+	case formatXML:
+		payload := xmlHistorical{From: result.From, To: result.To, Provider: result.Provider, PublishedAt: result.PublishedAt, FetchedAt: result.Source.FetchedAt, Origin: result.Origin}
+		for _, date := range dates {
+			rate := result.Rates[date]
+			payload.Rates = append(payload.Rates, xmlHistoricalRate{Date: date, Derived: rate.Derived, ObservedDate: rate.ObservedDate, Value: rate.Rate})
+		}
+		for _, missing := range result.MissingDates {
+			payload.Missing = append(payload.Missing, xmlMissingDate{Date: missing.Date, Reason: missing.Reason})
+		}
+		c.XML(http.StatusOK, payload)
+	default:
+		writeJSON(c, http.StatusOK, result)
+	}
+}
+
+type xmlHistoricalBuckets struct {
+	XMLName     xml.Name            `xml:"historical_buckets"`
+	From        string              `xml:"from,attr"`
+	To          string              `xml:"to,attr"`
+	Granularity string              `xml:"granularity,attr"`
+	Provider    string              `xml:"provider,attr,omitempty"`
+	PublishedAt *time.Time          `xml:"published_at,attr,omitempty"`
+	FetchedAt   *time.Time          `xml:"fetched_at,attr,omitempty"`
+	Origin      string              `xml:"origin,attr,omitempty"`
+	Buckets     []models.RateBucket `xml:"bucket"`
+	Missing     []xmlMissingDate    `xml:"missing>date,omitempty"`
+}
+
+func renderBuckets(c *gin.Context, result *models.HistoricalBucketsResponse) {
+	switch negotiateFormat(c) {
+	case formatCSV:
+		rows := [][]string{{"start", "end", "from", "to", "open", "high", "low", "close", "average", "days"}}
+		for _, bucket := range result.Buckets {
+			rows = append(rows, []string{
+				bucket.Start,
+				bucket.End,
+				result.From,
+				result.To,
+				formatFloat(bucket.Open),
+				formatFloat(bucket.High),
+				formatFloat(bucket.Low),
+				formatFloat(bucket.Close),
+				formatFloat(bucket.Average),
+				strconv.Itoa(bucket.Days),
+			})
+		}
+		writeCSV(c, fmt.Sprintf("historical_%s_%s_%s.csv", result.Granularity, result.From, result.To), rows)
+	case formatXML:
+		payload := xmlHistoricalBuckets{From: result.From, To: result.To, Granularity: result.Granularity, Provider: result.Provider, PublishedAt: result.PublishedAt, FetchedAt: result.Source.FetchedAt, Origin: result.Origin, Buckets: result.Buckets}
+		for _, missing := range result.MissingDates {
+			payload.Missing = append(payload.Missing, xmlMissingDate{Date: missing.Date, Reason: missing.Reason})
+		}
+		c.XML(http.StatusOK, payload)
+	default:
+		writeJSON(c, http.StatusOK, result)
+	}
+}
+
+type xmlTable struct {
+	XMLName     xml.Name       `xml:"rate_table"`
+	Base        string         `xml:"base,attr,omitempty"`
+	Provider    string         `xml:"provider,attr,omitempty"`
+	PublishedAt *time.Time     `xml:"published_at,attr,omitempty"`
+	FetchedAt   *time.Time     `xml:"fetched_at,attr,omitempty"`
+	Origin      string         `xml:"origin,attr,omitempty"`
+	Rates       []xmlTableRate `xml:"rate"`
+	Missing     []string       `xml:"missing>pair,omitempty"`
+}
+
+type xmlTableRate struct {
+	From  string  `xml:"from,attr"`
+	To    string  `xml:"to,attr"`
+	Value float64 `xml:",chardata"`
+}
+
+func renderTable(c *gin.Context, result *models.RateTableResponse) {
+	matrix := result.Matrix
+	if result.Base != "" {
+		matrix = map[string]map[string]float64{result.Base: result.Rates}
+	}
+
+	var rates []xmlTableRate
+	for from, row := range matrix {
+		for to, rate := range row {
+			rates = append(rates, xmlTableRate{From: from, To: to, Value: rate})
+		}
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].From != rates[j].From {
+			return rates[i].From < rates[j].From
+		}
+		return rates[i].To < rates[j].To
+	})
+
+	switch negotiateFormat(c) {
+	case formatCSV:
+		rows := [][]string{{"from", "to", "rate"}}
+		for _, rate := range rates {
+			rows = append(rows, []string{rate.From, rate.To, formatFloat(rate.Value)})
+		}
+		filename := "rate_matrix.csv"
+		if result.Base != "" {
+			filename = fmt.Sprintf("rate_table_%s.csv", result.Base)
+		}
+		writeCSV(c, filename, rows)
+	case formatXML:
+		c.XML(http.StatusOK, xmlTable{Base: result.Base, Provider: result.Provider, PublishedAt: result.PublishedAt, FetchedAt: result.Source.FetchedAt, Origin: result.Origin, Rates: rates, Missing: result.Missing})
+	default:
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func writeCSV(c *gin.Context, filename string, rows [][]string) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.WriteAll(rows); err != nil {
+		writeError(c, "Failed to render CSV", &models.CodedError{Code: models.ErrCodeInternal, Message: err.Error(), Err: err})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
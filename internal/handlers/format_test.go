@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+)
+
+func historicalFixture() *models.HistoricalRateResponse {
+	return &models.HistoricalRateResponse{
+		From: "USD",
+		To:   "INR",
+		Rates: map[string]models.HistoricalRate{
+			"2025-01-02": {Rate: 85.5, Date: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+			"2025-01-01": {Rate: 85.25, Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Derived: models.DerivedInverse},
+			"2025-01-04": {Rate: 85.5, Date: time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC), ObservedDate: "2025-01-02"},
+		},
+		MissingDates: []models.MissingDate{{Date: "2025-01-03", Reason: models.MissingProviderError, Error: "API returned status code: 503"}},
+	}
+}
+
+func renderHistoricalWith(target, accept string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+	if accept != "" {
+		c.Request.Header.Set("Accept", accept)
+	}
+	renderHistorical(c, historicalFixture())
+	return w
+}
+
+func TestRenderHistorical_CSV(t *testing.T) {
+	w := renderHistoricalWith("/rates/historical", "text/csv")
+
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "date,from,to,rate,derived,observed_date\n"+
+		"2025-01-01,USD,INR,85.25,inverse,\n"+
+		"2025-01-02,USD,INR,85.5,,\n"+
+		"2025-01-04,USD,INR,85.5,,2025-01-02\n", w.Body.String())
+}
+
+func TestRenderHistorical_XMLViaQuery(t *testing.T) {
+	w := renderHistoricalWith("/rates/historical?format=xml", "application/json")
+
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/xml")
+	assert.Equal(t, `<historical_rates from="USD" to="INR">`+
+		`<rate date="2025-01-01" derived="inverse">85.25</rate>`+
+		`<rate date="2025-01-02">85.5</rate>`+
+		`<rate date="2025-01-04" observed_date="2025-01-02">85.5</rate>`+
+		`<missing><date reason="provider_error">2025-01-03</date></missing>`+
+		`</historical_rates>`, w.Body.String())
+}
+
+func TestRenderHistorical_DefaultsToJSON(t *testing.T) {
+	w := renderHistoricalWith("/rates/historical", "*/*")
+
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	assert.Contains(t, w.Body.String(), `"rates"`)
+}
+
+func TestRenderConversion_CSV(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/convert?format=csv", nil)
+
+	renderConversion(c, &models.ConversionResponse{
+		From:            "USD",
+		To:              "INR",
+		Amount:          100,
+		ConvertedAmount: 8350,
+		Rate:            83.5,
+		MidMarketRate:   83.5,
+		Date:            time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC),
+	})
+
+	assert.Equal(t, "from,to,amount,converted_amount,rate,mid_market_rate,markup_percent,derived,date\n"+
+		"USD,INR,100,8350,83.5,83.5,0,,2025-01-02T10:00:00Z\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/convert?format=csv", nil)
+	renderConversion(c, &models.ConversionResponse{
+		From:            "USD",
+		To:              "INR",
+		Amount:          100,
+		ConvertedAmount: 8350,
+		Rate:            83.5,
+		MidMarketRate:   83.5,
+		Date:            time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC),
+		Fees:            &models.ConversionFees{FeePercent: 1, MidMarketAmount: 8350, FeeAmount: 83.5, NetAmount: 8266.5},
+	})
+
+	assert.Equal(t, "from,to,amount,converted_amount,rate,mid_market_rate,markup_percent,derived,date,mid_market_amount,fee_amount,net_amount\n"+
+		"USD,INR,100,8350,83.5,83.5,0,,2025-01-02T10:00:00Z,8350,83.5,8266.5\n", w.Body.String())
+}
+
+func TestRenderTable_CSVAndXML(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	table := &models.RateTableResponse{
+		Base:    "USD",
+		Rates:   map[string]float64{"USD": 1, "INR": 83.5},
+		Missing: []string{"USD_JPY"},
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/rates/table?base=USD&format=csv", nil)
+	renderTable(c, table)
+	assert.Equal(t, "from,to,rate\nUSD,INR,83.5\nUSD,USD,1\n", w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "rate_table_USD.csv")
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/rates/table?base=USD&format=xml", nil)
+	renderTable(c, table)
+	assert.Contains(t, w.Body.String(), `<rate_table base="USD"><rate from="USD" to="INR">83.5</rate>`)
+	assert.Contains(t, w.Body.String(), `<missing><pair>USD_JPY</pair></missing>`)
+}
+
+func TestRenderTable_VaryAccept(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fetchedAt := time.Now().Add(-time.Minute)
+	router := gin.New()
+	router.Use(middleware.HTTPCache())
+	router.GET("/rates/table", func(c *gin.Context) {
+		middleware.SetFreshness(c, models.Freshness{FetchedAt: fetchedAt, ExpiresAt: fetchedAt.Add(time.Hour)})
+		renderTable(c, &models.RateTableResponse{Base: "USD", Rates: map[string]float64{"INR": 83.5}})
+	})
+	get := func(target, accept, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept", accept)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	csv := get("/rates/table?base=USD", "text/csv", "")
+	assert.Contains(t, csv.Header().Values("Vary"), "Accept", "shared caches must keep the formats apart")
+	json := get("/rates/table?base=USD", "application/json", "")
+	assert.Contains(t, json.Header().Values("Vary"), "Accept")
+	assert.NotEqual(t, csv.Header().Get("ETag"), json.Header().Get("ETag"))
+
+	w := get("/rates/table?base=USD", "text/csv", csv.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Contains(t, w.Header().Values("Vary"), "Accept")
+
+	w = get("/rates/table?base=USD&format=csv", "application/json", "")
+	assert.NotContains(t, w.Header().Values("Vary"), "Accept", "an explicit format is part of the URL")
+}
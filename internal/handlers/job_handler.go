@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+// maxJobFileBytes bounds the size of an uploaded conversion file
+const maxJobFileBytes = 32 << 20
+
+type JobHandler struct {
+	jobs *services.ConversionJobs
+}
+
+func NewJobHandler(jobs *services.ConversionJobs) *JobHandler {
+	return &JobHandler{
+		jobs: jobs,
+	}
+}
+
+// POST /jobs/convert with a CSV file, as the "file" field of a multipart
+// form or as the raw text/csv body
+func (h *JobHandler) SubmitConversion(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxJobFileBytes)
+
+	var file io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		upload, err := c.FormFile("file")
+		if tooLarge := tooLargeError(err); tooLarge != nil {
+			writeError(c, "Invalid upload", tooLarge)
+			return
+		}
+		if err != nil {
+			writeError(c, "Invalid upload", models.NewFieldError(models.ErrCodeMissingParameter, "file", "a CSV file is required in the file field"))
+			return
+		}
+		opened, err := upload.Open()
+		if err != nil {
+			writeError(c, "Invalid upload", err)
+			return
+		}
+		defer opened.Close()
+		file = opened
+	}
+
+	job, err := h.jobs.Submit(c.Request.Context(), callerID(c), file, allowedPairs(c))
+	if tooLarge := tooLargeError(err); tooLarge != nil {
+		err = tooLarge
+	}
+	if err != nil {
+		writeError(c, "Invalid conversion file", err)
+		return
+	}
+
+	c.Header("Location", "/api/v1/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GET /jobs/:id
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, ok := h.ownJob(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// GET /jobs/:id/result
+func (h *JobHandler) GetResult(c *gin.Context) {
+	job, ok := h.ownJob(c)
+	if !ok {
+		return
+	}
+	if job.Status != models.JobCompleted {
+		writeError(c, "Result not ready", models.NewError(models.ErrCodeJobPending, "job %s is %s, its result is not ready", job.ID, job.Status))
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="conversions_`+job.ID+`.csv"`)
+	c.Status(http.StatusOK)
+	if err := h.jobs.WriteResult(job.ID, c.Writer); err != nil {
+		c.Error(err)
+	}
+}
+
+// ownJob looks up the job named in the path. Callers only see their own
+// jobs, except admins; others get the same 404 as for an unknown ID.
+func (h *JobHandler) ownJob(c *gin.Context) (*models.ConversionJob, bool) {
+	id := c.Param("id")
+	job, ok := h.jobs.Get(id)
+	if ok && job.Caller != callerID(c) {
+		key, authenticated := middleware.APIKeyFromContext(c)
+		ok = authenticated && key.HasRole(auth.RoleAdmin)
+	}
+	if !ok {
+		writeError(c, "Job not found", models.NewError(models.ErrCodeNotFound, "job %s not found", id))
+		return nil, false
+	}
+	return job, true
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+func TestJobHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 83.5)
+	jobs := services.NewConversionJobs(services.NewExchangeService(memoryCache, nil, nil), services.DefaultJobConfig())
+	jobs.Start()
+	defer jobs.Stop()
+
+	store := auth.NewKeyStore([]auth.APIKey{
+		{ID: "partner", Key: "partner-secret", Roles: []string{auth.RoleReader}},
+		{ID: "other", Key: "other-secret", Roles: []string{auth.RoleReader}},
+		{ID: "ops", Key: "admin-secret", Roles: []string{auth.RoleAdmin}},
+	})
+	handler := NewJobHandler(jobs)
+	router := gin.New()
+	router.Use(middleware.Authenticate(store, nil))
+	router.POST("/api/v1/jobs/convert", handler.SubmitConversion)
+	router.GET("/api/v1/jobs/:id", handler.GetJob)
+	router.GET("/api/v1/jobs/:id/result", handler.GetResult)
+
+	request := func(method, path, key string, body *bytes.Buffer, contentType string) *httptest.ResponseRecorder {
+		if body == nil {
+			body = &bytes.Buffer{}
+		}
+		req := httptest.NewRequest(method, path, body)
+		req.Header.Set("X-API-Key", key)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, err := writer.CreateFormFile("file", "payments.csv")
+	require.NoError(t, err)
+	part.Write([]byte("from,to,amount\nUSD,INR,2\nUSD,INR,4\n"))
+	require.NoError(t, writer.Close())
+
+	w := request(http.MethodPost, "/api/v1/jobs/convert", "partner-secret", &form, writer.FormDataContentType())
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var job models.ConversionJob
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, 2, job.Total)
+	assert.Equal(t, "/api/v1/jobs/"+job.ID, w.Header().Get("Location"))
+
+	require.Eventually(t, func() bool {
+		w := request(http.MethodGet, "/api/v1/jobs/"+job.ID, "partner-secret", nil, "")
+		json.Unmarshal(w.Body.Bytes(), &job)
+		return job.Status == models.JobCompleted
+	}, 5*time.Second, 5*time.Millisecond)
+
+	w = request(http.MethodGet, job.ResultURL, "partner-secret", nil, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "2,USD,INR,2,167,83.5,")
+	assert.Contains(t, w.Body.String(), "3,USD,INR,4,334,83.5,")
+
+	// Jobs are private to their caller, except to admins
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/api/v1/jobs/"+job.ID, "other-secret", nil, "").Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/v1/jobs/"+job.ID, "admin-secret", nil, "").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/api/v1/jobs/unknown", "partner-secret", nil, "").Code)
+
+	// A raw CSV body works too; a file without the required columns is refused
+	w = request(http.MethodPost, "/api/v1/jobs/convert", "partner-secret", bytes.NewBufferString("from,to,amount\nUSD,INR,1\n"), "text/csv")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	w = request(http.MethodPost, "/api/v1/jobs/convert", "partner-secret", bytes.NewBufferString("currency,value\nUSD,1\n"), "text/csv")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), models.ErrCodeInvalidRequest)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/models"
+)
+
+// prometheusContentType is the Prometheus text exposition format
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// cacheCounters maps the counters of the cache stats to their metric names
+var cacheCounters = []struct {
+	stat, name, help string
+}{
+	{"hits", "exchange_cache_hits_total", "Lookups of fresh rates answered by the cache"},
+	{"misses", "exchange_cache_misses_total", "Lookups of fresh rates the cache had no entry for"},
+	{"sets", "exchange_cache_sets_total", "Entries stored in the cache"},
+	{"deletes", "exchange_cache_deletes_total", "Entries deleted from the cache"},
+	{"evictions", "exchange_cache_evictions_total", "Entries evicted to stay within the cache's size"},
+	{"expirations", "exchange_cache_expirations_total", "Expired entries cleaned up"},
+}
+
+// GET /metrics
+// Cache counters and latency percentiles, and the endpoints' SLO state when
+// tracked, in the Prometheus text format
+func (h *ExchangeHandler) GetMetrics(c *gin.Context) {
+	var buf bytes.Buffer
+	writeCacheMetrics(&buf, h.exchangeService.GetCacheStats())
+	if h.slo != nil {
+		writeSLOMetrics(&buf, h.slo.Report(time.Now()))
+	}
+	c.Data(http.StatusOK, prometheusContentType, buf.Bytes())
+}
+
+// writeCacheMetrics writes the cache stats as Prometheus metrics. Stats a
+// cache backend doesn't report are left out.
+func writeCacheMetrics(buf *bytes.Buffer, stats map[string]interface{}) {
+	for _, counter := range cacheCounters {
+		if value, ok := metricValue(stats[counter.stat]); ok {
+			writeMetricHeader(buf, counter.name, "counter", counter.help)
+			fmt.Fprintf(buf, "%s %s\n", counter.name, formatMetric(value))
+		}
+	}
+
+	var items []string
+	for _, state := range []string{"valid", "expired", "negative"} {
+		if value, ok := metricValue(stats[state+"_items"]); ok {
+			items = append(items, fmt.Sprintf("exchange_cache_items{state=%q} %s\n", state, formatMetric(value)))
+		}
+	}
+	if len(items) > 0 {
+		writeMetricHeader(buf, "exchange_cache_items", "gauge", "Entries in the cache by state")
+		for _, item := range items {
+			buf.WriteString(item)
+		}
+	}
+
+	operations, _ := stats["operations"].(map[string]interface{})
+	if len(operations) == 0 {
+		return
+	}
+	names := make([]string, 0, len(operations))
+	for name := range operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const summary = "exchange_cache_operation_duration_seconds"
+	writeMetricHeader(buf, summary, "summary", "Latency of cache operations over their most recent calls")
+	for _, name := range names {
+		op, _ := operations[name].(map[string]interface{})
+		for _, quantile := range []struct{ label, stat string }{{"0.5", "p50_ms"}, {"0.9", "p90_ms"}, {"0.99", "p99_ms"}} {
+			if value, ok := metricValue(op[quantile.stat]); ok {
+				fmt.Fprintf(buf, "%s{operation=%q,quantile=%q} %s\n", summary, name, quantile.label, formatMetric(value/1000))
+			}
+		}
+		if value, ok := metricValue(op["total_ms"]); ok {
+			fmt.Fprintf(buf, "%s_sum{operation=%q} %s\n", summary, name, formatMetric(value/1000))
+		}
+		if value, ok := metricValue(op["calls"]); ok {
+			fmt.Fprintf(buf, "%s_count{operation=%q} %s\n", summary, name, formatMetric(value))
+		}
+	}
+}
+
+// writeSLOMetrics writes each endpoint's latency percentiles and error rate
+// over the SLO window, its targets and the objectives it misses
+func writeSLOMetrics(buf *bytes.Buffer, report models.SLOReport) {
+	if len(report.Endpoints) == 0 {
+		return
+	}
+
+	const latency = "exchange_slo_latency_seconds"
+	writeMetricHeader(buf, latency, "gauge", "Latency percentiles of each endpoint over the SLO window")
+	for _, e := range report.Endpoints {
+		for _, quantile := range []struct {
+			label string
+			value float64
+		}{{"0.5", e.P50Seconds}, {"0.95", e.P95Seconds}, {"0.99", e.P99Seconds}} {
+			fmt.Fprintf(buf, "%s{endpoint=%q,quantile=%q} %s\n", latency, e.Endpoint, quantile.label, formatMetric(quantile.value))
+		}
+	}
+	writeMetricHeader(buf, "exchange_slo_requests", "gauge", "Requests to each endpoint over the SLO window")
+	for _, e := range report.Endpoints {
+		fmt.Fprintf(buf, "exchange_slo_requests{endpoint=%q} %d\n", e.Endpoint, e.Requests)
+	}
+	writeMetricHeader(buf, "exchange_slo_error_rate_percent", "gauge", "Share of 5xx responses of each endpoint over the SLO window")
+	for _, e := range report.Endpoints {
+		fmt.Fprintf(buf, "exchange_slo_error_rate_percent{endpoint=%q} %s\n", e.Endpoint, formatMetric(e.ErrorRatePercent))
+	}
+
+	var targets, violated, totals []string
+	for _, e := range report.Endpoints {
+		objectives := make([]string, 0, len(e.Targets))
+		for objective := range e.Targets {
+			objectives = append(objectives, objective)
+		}
+		sort.Strings(objectives)
+
+		active := make(map[string]bool, len(e.Violations))
+		for _, violation := range e.Violations {
+			active[violation.Objective] = true
+		}
+		for _, objective := range objectives {
+			labels := fmt.Sprintf("{endpoint=%q,objective=%q}", e.Endpoint, objective)
+			targets = append(targets, fmt.Sprintf("exchange_slo_target%s %s\n", labels, formatMetric(e.Targets[objective])))
+			state := 0
+			if active[objective] {
+				state = 1
+			}
+			violated = append(violated, fmt.Sprintf("exchange_slo_violated%s %d\n", labels, state))
+			totals = append(totals, fmt.Sprintf("exchange_slo_violations_total%s %d\n", labels, e.ViolationsTotal[objective]))
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+	for _, family := range []struct {
+		name, kind, help string
+		lines            []string
+	}{
+		{"exchange_slo_target", "gauge", "Target of each objective, seconds for latencies and percent for the error rate", targets},
+		{"exchange_slo_violated", "gauge", "1 while an endpoint missed the objective at the last evaluation", violated},
+		{"exchange_slo_violations_total", "counter", "Times an endpoint started missing the objective", totals},
+	} {
+		writeMetricHeader(buf, family.name, family.kind, family.help)
+		for _, line := range family.lines {
+			buf.WriteString(line)
+		}
+	}
+}
+
+func writeMetricHeader(buf *bytes.Buffer, name, kind, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// metricValue converts a numeric stat to a float
+func metricValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func formatMetric(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
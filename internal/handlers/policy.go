@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/services"
+)
+
+// allowedPairs returns the pair filter of the caller's key policy, nil when
+// it permits every pair
+func allowedPairs(c *gin.Context) services.PairFilter {
+	if key, ok := middleware.APIKeyFromContext(c); ok && key.Policy.RestrictsPairs() {
+		policy := key.Policy
+		return policy.AllowsPair
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+type QuoteHandler struct {
+	quotes *services.Quotes
+}
+
+func NewQuoteHandler(quotes *services.Quotes) *QuoteHandler {
+	return &QuoteHandler{
+		quotes: quotes,
+	}
+}
+
+// POST /quotes
+func (h *QuoteHandler) CreateQuote(c *gin.Context) {
+	var req models.QuoteRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, "Invalid request body", err)
+		return
+	}
+
+	quote, err := h.quotes.Create(c.Request.Context(), callerID(c), &req, allowedPairs(c))
+	if err != nil {
+		writeError(c, "Quote failed", err)
+		return
+	}
+
+	middleware.SetFreshness(c, quote.Conversion.Freshness)
+	c.Header("Location", "/api/v1/quotes/"+quote.ID)
+	c.JSON(http.StatusCreated, quote)
+}
+
+// GET /quotes/:id
+func (h *QuoteHandler) GetQuote(c *gin.Context) {
+	quote, ok := h.ownQuote(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
+
+// POST /quotes/:id/execute
+func (h *QuoteHandler) ExecuteQuote(c *gin.Context) {
+	quote, ok := h.ownQuote(c)
+	if !ok {
+		return
+	}
+
+	executed, err := h.quotes.Execute(quote.ID, callerID(c))
+	if err != nil {
+		writeError(c, "Quote not executed", err)
+		return
+	}
+	c.JSON(http.StatusOK, executed)
+}
+
+// ownQuote looks up the quote named in the path. Callers only see their own
+// quotes, except admins; others get the same 404 as for an unknown ID.
+func (h *QuoteHandler) ownQuote(c *gin.Context) (*models.Quote, bool) {
+	id := c.Param("id")
+	quote, ok := h.quotes.Get(id)
+	if ok && quote.Caller != callerID(c) {
+		key, authenticated := middleware.APIKeyFromContext(c)
+		ok = authenticated && key.HasRole(auth.RoleAdmin)
+	}
+	if !ok {
+		writeError(c, "Quote not found", models.NewError(models.ErrCodeNotFound, "quote %s not found", id))
+		return nil, false
+	}
+	return quote, true
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+func TestQuoteHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 83.5)
+	quotes := services.NewQuotes(services.NewExchangeService(memoryCache, nil, nil), services.DefaultQuoteConfig())
+
+	store := auth.NewKeyStore([]auth.APIKey{
+		{ID: "shop", Key: "shop-secret", Roles: []string{auth.RoleReader}},
+		{ID: "other", Key: "other-secret", Roles: []string{auth.RoleReader}},
+	})
+	handler := NewQuoteHandler(quotes)
+	router := gin.New()
+	router.Use(middleware.Authenticate(store, nil))
+	router.POST("/api/v1/quotes", handler.CreateQuote)
+	router.GET("/api/v1/quotes/:id", handler.GetQuote)
+	router.POST("/api/v1/quotes/:id/execute", handler.ExecuteQuote)
+
+	request := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("X-API-Key", key)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/v1/quotes", "shop-secret", `{"from":"USD","to":"INR","amount":10,"valid_minutes":2}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var quote models.Quote
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &quote))
+	assert.Equal(t, "/api/v1/quotes/"+quote.ID, w.Header().Get("Location"))
+	assert.Equal(t, "shop", quote.Caller)
+	assert.Equal(t, 835.0, quote.Conversion.ConvertedAmount)
+
+	w = request(http.MethodPost, "/api/v1/quotes/"+quote.ID+"/execute", "other-secret", "")
+	assert.Equal(t, http.StatusNotFound, w.Code, "other callers can't execute the quote")
+
+	w = request(http.MethodPost, "/api/v1/quotes/"+quote.ID+"/execute", "shop-secret", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"status":"executed"`)
+
+	w = request(http.MethodPost, "/api/v1/quotes/"+quote.ID+"/execute", "shop-secret", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), models.ErrCodeQuoteExecuted)
+
+	w = request(http.MethodGet, "/api/v1/quotes/"+quote.ID, "shop-secret", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"executed_at"`)
+
+	w = request(http.MethodPost, "/api/v1/quotes", "shop-secret", `{"from":"USD","to":"INR","amount":10,"valid_minutes":600}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"valid_minutes"`)
+}
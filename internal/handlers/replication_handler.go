@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/services"
+)
+
+type ReplicationHandler struct {
+	replicator *services.Replicator
+}
+
+func NewReplicationHandler(replicator *services.Replicator) *ReplicationHandler {
+	return &ReplicationHandler{
+		replicator: replicator,
+	}
+}
+
+// POST /replication/rates
+// Caches the rates a peer fetched. Peers authenticate with the signature of
+// the shared replication secret rather than an API key.
+func (h *ReplicationHandler) ReceiveRates(c *gin.Context) {
+	body, err := c.GetRawData()
+	if tooLarge := tooLargeError(err); tooLarge != nil {
+		err = tooLarge
+	}
+	if err != nil {
+		writeError(c, "Invalid replication push", err)
+		return
+	}
+
+	applied, err := h.replicator.Receive(body, c.GetHeader(services.WebhookTimestampHeader), c.GetHeader(services.WebhookSignatureHeader))
+	if err != nil {
+		writeError(c, "Invalid replication push", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"applied": applied})
+}
+
+// GET /stats/replication
+func (h *ReplicationHandler) GetStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.replicator.Stats())
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/services"
+)
+
+func TestReplicationHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fetcher := services.NewRateFetcher(external.NewExchangeRateClientWithConfig(external.DefaultConfig()), cache.NewMemoryCache(time.Hour))
+	replicator := services.NewReplicator(services.ReplicationConfig{InstanceID: "b", Secret: "secret"}, fetcher)
+
+	handler := NewReplicationHandler(replicator)
+	router := gin.New()
+	router.POST(services.ReplicationPath, handler.ReceiveRates)
+	router.GET("/stats/replication", handler.GetStats)
+
+	body := []byte(`{"origin":"a","rates":[{"from":"USD","to":"INR","rate":83.1}]}`)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	tests := []struct {
+		name      string
+		signature string
+		status    int
+		want      string
+	}{
+		{"signed", "sha256=" + services.SignWebhook("secret", timestamp, body), http.StatusOK, `{"applied":1}`},
+		{"unsigned", "", http.StatusUnauthorized, `"error_code":"UNAUTHORIZED"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, services.ReplicationPath, bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(services.WebhookTimestampHeader, timestamp)
+			req.Header.Set(services.WebhookSignatureHeader, tt.signature)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.want)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stats/replication", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"standby":true,"leader_id":"a","received":1,"rejected":1,"applied":1`)
+}
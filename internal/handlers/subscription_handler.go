@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+type SubscriptionHandler struct {
+	webhooks *services.WebhookDispatcher
+}
+
+func NewSubscriptionHandler(webhooks *services.WebhookDispatcher) *SubscriptionHandler {
+	return &SubscriptionHandler{
+		webhooks: webhooks,
+	}
+}
+
+// POST /subscriptions
+func (h *SubscriptionHandler) Subscribe(c *gin.Context) {
+	var req models.SubscriptionRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, "Invalid request body", err)
+		return
+	}
+
+	subscription, err := h.webhooks.Subscribe(callerID(c), &req, allowedPairs(c))
+	if err != nil {
+		writeError(c, "Invalid subscription", err)
+		return
+	}
+
+	c.Header("Location", "/api/v1/subscriptions/"+subscription.ID)
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// GET /subscriptions lists the caller's subscriptions, or every one for admins
+func (h *SubscriptionHandler) List(c *gin.Context) {
+	caller := callerID(c)
+	if key, ok := middleware.APIKeyFromContext(c); ok && key.HasRole(auth.RoleAdmin) {
+		caller = ""
+	}
+	c.JSON(http.StatusOK, gin.H{"subscriptions": h.webhooks.List(caller)})
+}
+
+// GET /subscriptions/:id
+func (h *SubscriptionHandler) Get(c *gin.Context) {
+	subscription, ok := h.ownSubscription(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+// DELETE /subscriptions/:id
+func (h *SubscriptionHandler) Unsubscribe(c *gin.Context) {
+	subscription, ok := h.ownSubscription(c)
+	if !ok {
+		return
+	}
+	h.webhooks.Unsubscribe(subscription.ID)
+	c.Status(http.StatusNoContent)
+}
+
+// GET /subscriptions/:id/dead_letters
+func (h *SubscriptionHandler) GetDeadLetters(c *gin.Context) {
+	subscription, ok := h.ownSubscription(c)
+	if !ok {
+		return
+	}
+	letters, _ := h.webhooks.DeadLetters(subscription.ID)
+	c.JSON(http.StatusOK, gin.H{"dead_letters": letters})
+}
+
+// POST /subscriptions/:id/dead_letters/redeliver
+func (h *SubscriptionHandler) Redeliver(c *gin.Context) {
+	subscription, ok := h.ownSubscription(c)
+	if !ok {
+		return
+	}
+	queued, _ := h.webhooks.Redeliver(subscription.ID)
+	c.JSON(http.StatusAccepted, gin.H{"redelivered": queued})
+}
+
+// ownSubscription looks up the subscription named in the path. Callers only
+// see their own subscriptions, except admins; others get the same 404 as for
+// an unknown ID.
+func (h *SubscriptionHandler) ownSubscription(c *gin.Context) (*models.Subscription, bool) {
+	id := c.Param("id")
+	subscription, ok := h.webhooks.Get(id)
+	if ok && subscription.Caller != callerID(c) {
+		key, authenticated := middleware.APIKeyFromContext(c)
+		ok = authenticated && key.HasRole(auth.RoleAdmin)
+	}
+	if !ok {
+		writeError(c, "Subscription not found", models.NewError(models.ErrCodeNotFound, "subscription %s not found", id))
+		return nil, false
+	}
+	return subscription, true
+}
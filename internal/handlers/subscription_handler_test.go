@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+func TestSubscriptionHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	webhooks := services.NewWebhookDispatcher(services.DefaultWebhookConfig())
+	webhooks.Start()
+	defer webhooks.Stop()
+
+	store := auth.NewKeyStore([]auth.APIKey{
+		{ID: "partner", Key: "partner-secret", Roles: []string{auth.RoleReader}, Policy: auth.Policy{Pairs: []string{"USD_*"}}},
+		{ID: "other", Key: "other-secret", Roles: []string{auth.RoleReader}},
+		{ID: "ops", Key: "admin-secret", Roles: []string{auth.RoleAdmin}},
+	})
+	handler := NewSubscriptionHandler(webhooks)
+	router := gin.New()
+	router.Use(middleware.Authenticate(store, nil))
+	router.POST("/api/v1/subscriptions", handler.Subscribe)
+	router.GET("/api/v1/subscriptions", handler.List)
+	router.GET("/api/v1/subscriptions/:id", handler.Get)
+	router.DELETE("/api/v1/subscriptions/:id", handler.Unsubscribe)
+	router.GET("/api/v1/subscriptions/:id/dead_letters", handler.GetDeadLetters)
+
+	request := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("X-API-Key", key)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/v1/subscriptions", "partner-secret", `{"callback_url":"https://93.184.215.14/rates","pairs":["EUR_INR"]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code, "the key's pair policy applies")
+
+	w = request(http.MethodPost, "/api/v1/subscriptions", "partner-secret", `{"callback_url":"https://93.184.215.14/rates","pairs":["USD_INR"]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var sub models.Subscription
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sub))
+	assert.NotEmpty(t, sub.Secret)
+	assert.Equal(t, "/api/v1/subscriptions/"+sub.ID, w.Header().Get("Location"))
+
+	w = request(http.MethodGet, "/api/v1/subscriptions/"+sub.ID, "partner-secret", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "secret", "the secret is only returned on creation")
+
+	w = request(http.MethodGet, "/api/v1/subscriptions/"+sub.ID+"/dead_letters", "partner-secret", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"dead_letters":[]}`, w.Body.String())
+
+	w = request(http.MethodGet, "/api/v1/subscriptions/"+sub.ID, "other-secret", "")
+	assert.Equal(t, http.StatusNotFound, w.Code, "other callers can't see the subscription")
+	w = request(http.MethodGet, "/api/v1/subscriptions", "other-secret", "")
+	assert.JSONEq(t, `{"subscriptions":[]}`, w.Body.String())
+	w = request(http.MethodGet, "/api/v1/subscriptions", "admin-secret", "")
+	assert.Contains(t, w.Body.String(), sub.ID)
+
+	w = request(http.MethodDelete, "/api/v1/subscriptions/"+sub.ID, "other-secret", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = request(http.MethodDelete, "/api/v1/subscriptions/"+sub.ID, "partner-secret", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = request(http.MethodGet, "/api/v1/subscriptions/"+sub.ID, "partner-secret", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
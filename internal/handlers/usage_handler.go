@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+type UsageHandler struct {
+	tracker *services.UsageTracker
+}
+
+func NewUsageHandler(tracker *services.UsageTracker) *UsageHandler {
+	return &UsageHandler{
+		tracker: tracker,
+	}
+}
+
+// GET /stats/usage?key_id=
+// Reports the usage of one key, or of every key without key_id
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	keyID := c.Query("key_id")
+	if keyID == "" {
+		c.JSON(http.StatusOK, h.tracker.Report())
+		return
+	}
+
+	usage, ok := h.tracker.Usage(keyID)
+	if !ok {
+		writeError(c, "Usage not found", models.NewFieldError(models.ErrCodeNotFound, "key_id", "API key %s made no request since %s", keyID, h.tracker.Since().Format(time.RFC3339)))
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+// GET /me/usage
+// Reports the usage of the caller's own key
+func (h *UsageHandler) GetOwnUsage(c *gin.Context) {
+	key, ok := middleware.APIKeyFromContext(c)
+	if !ok {
+		writeError(c, "Unauthorized", models.NewError(models.ErrCodeUnauthorized, "an API key or bearer token is required"))
+		return
+	}
+
+	// The request asking is counted once it completes, so a key's first
+	// request finds no usage yet
+	usage, ok := h.tracker.Usage(key.ID)
+	if !ok {
+		usage = models.KeyUsage{KeyID: key.ID}
+	}
+	c.JSON(http.StatusOK, usage)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/services"
+)
+
+func TestUsageHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tracker := services.NewUsageTracker()
+	tracker.Record(services.UsageRequest{KeyID: "partner", Endpoint: "/api/v1/convert", From: "USD", To: "INR", Status: http.StatusOK, At: time.Now()})
+
+	handler := NewUsageHandler(tracker)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if id := c.GetHeader("X-Key-ID"); id != "" {
+			c.Set(middleware.ContextKeyAPIKey, &auth.APIKey{ID: id})
+		}
+	})
+	router.GET("/stats/usage", handler.GetUsage)
+	router.GET("/me/usage", handler.GetOwnUsage)
+
+	tests := []struct {
+		name   string
+		path   string
+		key    string
+		status int
+		want   string
+	}{
+		{"every key", "/stats/usage", "ops", http.StatusOK, `"keys":[{"key_id":"partner","requests":1`},
+		{"one key", "/stats/usage?key_id=partner", "ops", http.StatusOK, `"pairs":[{"from":"USD","to":"INR","requests":1}]`},
+		{"unknown key", "/stats/usage?key_id=nobody", "ops", http.StatusNotFound, `"field":"key_id"`},
+		{"own usage", "/me/usage", "partner", http.StatusOK, `"key_id":"partner","requests":1`},
+		{"own usage before any request", "/me/usage", "newcomer", http.StatusOK, `"key_id":"newcomer","requests":0`},
+		{"anonymous", "/me/usage", "", http.StatusUnauthorized, `"error_code":"UNAUTHORIZED"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set("X-Key-ID", tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+type WatchlistHandler struct {
+	watchlists *services.Watchlists
+}
+
+func NewWatchlistHandler(watchlists *services.Watchlists) *WatchlistHandler {
+	return &WatchlistHandler{
+		watchlists: watchlists,
+	}
+}
+
+// POST /watchlists
+func (h *WatchlistHandler) CreateWatchlist(c *gin.Context) {
+	var req models.WatchlistRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, "Invalid request body", err)
+		return
+	}
+
+	watchlist, err := h.watchlists.Create(c.Request.Context(), callerID(c), &req, allowedPairs(c))
+	if err != nil {
+		writeError(c, "Invalid watchlist", err)
+		return
+	}
+
+	c.Header("Location", "/api/v1/watchlists/"+watchlist.ID)
+	c.JSON(http.StatusCreated, watchlist)
+}
+
+// GET /watchlists lists the caller's watchlists, or every one for admins
+func (h *WatchlistHandler) ListWatchlists(c *gin.Context) {
+	caller := callerID(c)
+	if key, ok := middleware.APIKeyFromContext(c); ok && key.HasRole(auth.RoleAdmin) {
+		caller = ""
+	}
+	c.JSON(http.StatusOK, gin.H{"watchlists": h.watchlists.List(caller)})
+}
+
+// GET /watchlists/:id
+func (h *WatchlistHandler) GetWatchlist(c *gin.Context) {
+	watchlist, ok := h.ownWatchlist(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, watchlist)
+}
+
+// PUT /watchlists/:id
+func (h *WatchlistHandler) UpdateWatchlist(c *gin.Context) {
+	watchlist, ok := h.ownWatchlist(c)
+	if !ok {
+		return
+	}
+	var req models.WatchlistRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, "Invalid request body", err)
+		return
+	}
+
+	watchlist, err := h.watchlists.Update(c.Request.Context(), watchlist.ID, &req, allowedPairs(c))
+	if err != nil {
+		writeError(c, "Invalid watchlist", err)
+		return
+	}
+	c.JSON(http.StatusOK, watchlist)
+}
+
+// DELETE /watchlists/:id
+func (h *WatchlistHandler) DeleteWatchlist(c *gin.Context) {
+	watchlist, ok := h.ownWatchlist(c)
+	if !ok {
+		return
+	}
+	h.watchlists.Delete(watchlist.ID)
+	c.Status(http.StatusNoContent)
+}
+
+// GET /watchlists/:id/rates
+func (h *WatchlistHandler) GetWatchlistRates(c *gin.Context) {
+	watchlist, ok := h.ownWatchlist(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.watchlists.Rates(c.Request.Context(), watchlist.ID, allowedPairs(c))
+	if err != nil {
+		writeError(c, "Failed to get watchlist rates", err)
+		return
+	}
+	// Not given to SetFreshness: the response is the caller's own, so it
+	// must not be marked cacheable by shared caches
+	c.JSON(http.StatusOK, result)
+}
+
+// ownWatchlist looks up the watchlist named in the path. Callers only see
+// their own watchlists, except admins; others get the same 404 as for an
+// unknown ID.
+func (h *WatchlistHandler) ownWatchlist(c *gin.Context) (*models.Watchlist, bool) {
+	id := c.Param("id")
+	watchlist, ok := h.watchlists.Get(id)
+	if ok && watchlist.Caller != callerID(c) {
+		key, authenticated := middleware.APIKeyFromContext(c)
+		ok = authenticated && key.HasRole(auth.RoleAdmin)
+	}
+	if !ok {
+		writeError(c, "Watchlist not found", models.NewError(models.ErrCodeNotFound, "watchlist %s not found", id))
+		return nil, false
+	}
+	return watchlist, true
+}
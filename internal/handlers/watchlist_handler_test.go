@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/middleware"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+func TestWatchlistHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 83.5)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "EUR", "", 0.92)
+	watchlists := services.NewWatchlists(services.NewExchangeService(memoryCache, nil, nil), services.DefaultWatchlistConfig())
+
+	store := auth.NewKeyStore([]auth.APIKey{
+		{ID: "partner", Key: "partner-secret", Roles: []string{auth.RoleReader}, Policy: auth.Policy{Pairs: []string{"USD_*"}}},
+		{ID: "other", Key: "other-secret", Roles: []string{auth.RoleReader}},
+		{ID: "ops", Key: "admin-secret", Roles: []string{auth.RoleAdmin}},
+	})
+	handler := NewWatchlistHandler(watchlists)
+	router := gin.New()
+	router.Use(middleware.Authenticate(store, nil), middleware.HTTPCache())
+	router.POST("/api/v1/watchlists", handler.CreateWatchlist)
+	router.GET("/api/v1/watchlists", handler.ListWatchlists)
+	router.GET("/api/v1/watchlists/:id", handler.GetWatchlist)
+	router.PUT("/api/v1/watchlists/:id", handler.UpdateWatchlist)
+	router.DELETE("/api/v1/watchlists/:id", handler.DeleteWatchlist)
+	router.GET("/api/v1/watchlists/:id/rates", handler.GetWatchlistRates)
+
+	request := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("X-API-Key", key)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/v1/watchlists", "partner-secret", `{"name":"Home","pairs":["EUR_INR"]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code, "the key's pair policy applies")
+
+	w = request(http.MethodPost, "/api/v1/watchlists", "partner-secret", `{"name":"Home","pairs":["USD_INR","usd_eur"]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var watchlist models.Watchlist
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &watchlist))
+	assert.Equal(t, "/api/v1/watchlists/"+watchlist.ID, w.Header().Get("Location"))
+	assert.Equal(t, "partner", watchlist.Caller)
+	assert.Equal(t, []string{"USD_INR", "USD_EUR"}, watchlist.Pairs)
+
+	w = request(http.MethodGet, "/api/v1/watchlists/"+watchlist.ID+"/rates", "partner-secret", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, w.Header().Get("Cache-Control"), "rates of a caller's watchlist aren't marked cacheable")
+	var rates struct {
+		Name  string `json:"name"`
+		Rates []struct {
+			Pair string  `json:"pair"`
+			From string  `json:"from"`
+			To   string  `json:"to"`
+			Rate float64 `json:"rate"`
+		} `json:"rates"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rates))
+	assert.Equal(t, "Home", rates.Name)
+	require.Len(t, rates.Rates, 2)
+	assert.Equal(t, "USD_INR", rates.Rates[0].Pair)
+	assert.Equal(t, "INR", rates.Rates[0].To)
+	assert.Equal(t, 83.5, rates.Rates[0].Rate)
+	assert.Equal(t, 0.92, rates.Rates[1].Rate)
+
+	w = request(http.MethodGet, "/api/v1/watchlists/"+watchlist.ID, "other-secret", "")
+	assert.Equal(t, http.StatusNotFound, w.Code, "other callers don't see the watchlist")
+	w = request(http.MethodGet, "/api/v1/watchlists/"+watchlist.ID+"/rates", "other-secret", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = request(http.MethodGet, "/api/v1/watchlists/"+watchlist.ID, "admin-secret", "")
+	assert.Equal(t, http.StatusOK, w.Code, "admins see every watchlist")
+
+	w = request(http.MethodGet, "/api/v1/watchlists", "other-secret", "")
+	assert.JSONEq(t, `{"watchlists":[]}`, w.Body.String())
+	w = request(http.MethodGet, "/api/v1/watchlists", "partner-secret", "")
+	assert.Contains(t, w.Body.String(), watchlist.ID)
+
+	w = request(http.MethodPut, "/api/v1/watchlists/"+watchlist.ID, "other-secret", `{"name":"Mine","pairs":["EUR_INR"]}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = request(http.MethodPut, "/api/v1/watchlists/"+watchlist.ID, "partner-secret", `{"name":"Travel","pairs":["USD_EUR"]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &watchlist))
+	assert.Equal(t, "Travel", watchlist.Name)
+	assert.Equal(t, []string{"USD_EUR"}, watchlist.Pairs)
+
+	w = request(http.MethodDelete, "/api/v1/watchlists/"+watchlist.ID, "other-secret", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = request(http.MethodDelete, "/api/v1/watchlists/"+watchlist.ID, "partner-secret", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = request(http.MethodGet, "/api/v1/watchlists/"+watchlist.ID, "partner-secret", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/services"
+)
+
+// Sinks access logs can be written to besides a file path
+const (
+	AccessLogStdout = "stdout"
+	AccessLogStderr = "stderr"
+)
+
+// AccessLogConfig sets up structured access logging
+type AccessLogConfig struct {
+	Enabled       bool
+	SamplePercent float64  // Share of requests logged, 0 to 100
+	LogErrors     bool     // Log every 4xx and 5xx answer whatever the sampling
+	SkipPaths     []string // Paths never logged, e.g. probes
+	Sink          string   // stdout, stderr, or a file appended to
+}
+
+// DefaultAccessLogConfig logs every request but the probes and metrics
+// scrapes to stdout
+func DefaultAccessLogConfig() AccessLogConfig {
+	return AccessLogConfig{
+		Enabled:       true,
+		SamplePercent: 100,
+		LogErrors:     true,
+		SkipPaths:     []string{"/healthz", "/readyz", "/metrics"},
+		Sink:          AccessLogStdout,
+	}
+}
+
+// AccessLogEntry is one request, as a line of JSON. It holds no secrets or
+// personal data: the caller is named by its API key ID, not the key, the
+// amount only by its order of magnitude, and the client IP and query string
+// are left out.
+type AccessLogEntry struct {
+	Time          time.Time `json:"time"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"` // Route pattern, e.g. /api/v1/jobs/:id, or the raw path when no route matched
+	Status        int       `json:"status"`
+	LatencyMs     float64   `json:"latency_ms"`
+	Bytes         int       `json:"bytes"`
+	Pair          string    `json:"pair,omitempty"`          // FROM_TO
+	AmountBucket  string    `json:"amount_bucket,omitempty"` // E.g. 100-1K
+	APIKeyID      string    `json:"api_key_id,omitempty"`
+	Tenant        string    `json:"tenant,omitempty"`
+	SamplePercent float64   `json:"sample_percent"` // Share of such requests logged, to weight the entry by
+}
+
+// AccessLogger writes sampled access log entries to its sink
+type AccessLogger struct {
+	cfg  AccessLogConfig
+	skip map[string]bool
+	out  io.Writer
+	file *os.File
+
+	mu     sync.Mutex
+	credit float64 // Accumulated SamplePercent; a request is logged each time it reaches 100
+}
+
+// NewAccessLogger opens the sink of cfg
+func NewAccessLogger(cfg AccessLogConfig) (*AccessLogger, error) {
+	switch cfg.Sink {
+	case "", AccessLogStdout:
+		return newAccessLogger(cfg, os.Stdout), nil
+	case AccessLogStderr:
+		return newAccessLogger(cfg, os.Stderr), nil
+	}
+	file, err := os.OpenFile(cfg.Sink, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open access log: %w", err)
+	}
+	logger := newAccessLogger(cfg, file)
+	logger.file = file
+	return logger, nil
+}
+
+func newAccessLogger(cfg AccessLogConfig, out io.Writer) *AccessLogger {
+	skip := make(map[string]bool, len(cfg.SkipPaths))
+	for _, path := range cfg.SkipPaths {
+		skip[path] = true
+	}
+	return &AccessLogger{cfg: cfg, skip: skip, out: out}
+}
+
+// Handler logs each request once it has been answered. The sampling share
+// is spread evenly: at 10% every tenth request is logged. Errors are logged
+// regardless when LogErrors is set.
+func (l *AccessLogger) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !l.cfg.Enabled || l.skip[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		// Read before the handler consumes the body
+		from, to, amount := requestParams(c)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		samplePercent := l.cfg.SamplePercent
+		if l.cfg.LogErrors && status >= 400 {
+			samplePercent = 100
+		} else if !l.sample() {
+			return
+		}
+
+		entry := AccessLogEntry{
+			Time:          start.UTC(),
+			Method:        c.Request.Method,
+			Path:          c.FullPath(),
+			Status:        status,
+			LatencyMs:     float64(time.Since(start).Microseconds()) / 1000,
+			Bytes:         c.Writer.Size(),
+			AmountBucket:  amountBucket(amount),
+			SamplePercent: samplePercent,
+		}
+		if entry.Path == "" {
+			entry.Path = c.Request.URL.Path
+		}
+		if entry.Bytes < 0 {
+			entry.Bytes = 0
+		}
+		if from != "" && to != "" {
+			entry.Pair = from + "_" + to
+		}
+		if key, ok := APIKeyFromContext(c); ok {
+			entry.APIKeyID = key.ID
+		}
+		if tenant := services.TenantFromContext(c.Request.Context()); tenant != nil {
+			entry.Tenant = tenant.ID
+		}
+		l.write(entry)
+	}
+}
+
+func (l *AccessLogger) sample() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.credit += l.cfg.SamplePercent
+	if l.credit < 100 {
+		return false
+	}
+	l.credit -= 100
+	return true
+}
+
+func (l *AccessLogger) write(entry AccessLogEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Failed to encode access log entry: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.out.Write(line); err != nil {
+		log.Printf("Failed to write access log entry: %v", err)
+	}
+}
+
+// Close closes the sink when it is a file
+func (l *AccessLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// amountBucket returns the order of magnitude of an amount, e.g. "100-1K",
+// so amounts can be analysed without logging them. Amounts that are not
+// positive numbers have no bucket.
+func amountBucket(amount string) string {
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil || !(value > 0) {
+		return ""
+	}
+	bounds := []struct {
+		limit float64
+		label string
+	}{
+		{1, "<1"},
+		{10, "1-10"},
+		{100, "10-100"},
+		{1e3, "100-1K"},
+		{1e4, "1K-10K"},
+		{1e5, "10K-100K"},
+		{1e6, "100K-1M"},
+	}
+	for _, bound := range bounds {
+		if value < bound.limit {
+			return bound.label
+		}
+	}
+	return ">=1M"
+}
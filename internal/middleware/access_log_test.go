@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/auth"
+)
+
+func newAccessLogRouter(cfg AccessLogConfig, out *bytes.Buffer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	store := auth.NewKeyStore([]auth.APIKey{{ID: "partner", Key: "reader-secret", Roles: []string{auth.RoleReader}}})
+
+	router := gin.New()
+	router.Use(newAccessLogger(cfg, out).Handler())
+	router.Use(Authenticate(store, nil))
+	router.GET("/api/v1/convert", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.POST("/api/v1/convert", func(c *gin.Context) {
+		var req struct {
+			From   string  `json:"from"`
+			Amount float64 `json:"amount"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.From == "" {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	router.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func accessLogEntries(t *testing.T, out *bytes.Buffer) []AccessLogEntry {
+	var entries []AccessLogEntry
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		var entry AccessLogEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestAccessLogger(t *testing.T) {
+	var out bytes.Buffer
+	router := newAccessLogRouter(DefaultAccessLogConfig(), &out)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/convert?from=usd&to=inr&amount=2500", nil)
+	req.Header.Set("X-API-Key", "reader-secret")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/convert", strings.NewReader(`{"from":"EUR","to":"GBP","amount":0.5}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, "the body is left for the handler to bind")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/unknown", nil))
+
+	assert.NotContains(t, out.String(), "reader-secret")
+	assert.NotContains(t, out.String(), "2500")
+	entries := accessLogEntries(t, &out)
+	require.Len(t, entries, 3, "probes are skipped")
+
+	assert.Equal(t, http.MethodGet, entries[0].Method)
+	assert.Equal(t, "/api/v1/convert", entries[0].Path)
+	assert.Equal(t, http.StatusOK, entries[0].Status)
+	assert.Equal(t, 2, entries[0].Bytes)
+	assert.Equal(t, "USD_INR", entries[0].Pair)
+	assert.Equal(t, "1K-10K", entries[0].AmountBucket)
+	assert.Equal(t, "partner", entries[0].APIKeyID)
+	assert.Equal(t, 100.0, entries[0].SamplePercent)
+
+	assert.Equal(t, "EUR_GBP", entries[1].Pair)
+	assert.Equal(t, "<1", entries[1].AmountBucket)
+	assert.Empty(t, entries[1].APIKeyID)
+
+	assert.Equal(t, "/api/v1/unknown", entries[2].Path, "unmatched requests keep their raw path")
+	assert.Equal(t, http.StatusNotFound, entries[2].Status)
+}
+
+func TestAccessLogger_Sampling(t *testing.T) {
+	var out bytes.Buffer
+	cfg := DefaultAccessLogConfig()
+	cfg.SamplePercent = 25
+	router := newAccessLogRouter(cfg, &out)
+
+	for i := 0; i < 8; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/convert", nil))
+	}
+	failed := httptest.NewRequest(http.MethodPost, "/api/v1/convert", strings.NewReader(`{}`))
+	failed.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), failed)
+
+	entries := accessLogEntries(t, &out)
+	require.Len(t, entries, 3, "every fourth success and every error")
+	assert.Equal(t, 25.0, entries[0].SamplePercent)
+	assert.Equal(t, http.StatusBadRequest, entries[2].Status)
+	assert.Equal(t, 100.0, entries[2].SamplePercent)
+
+	out.Reset()
+	cfg.Enabled = false
+	router = newAccessLogRouter(cfg, &out)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/convert", nil))
+	assert.Zero(t, out.Len())
+}
+
+func TestAmountBucket(t *testing.T) {
+	tests := map[string]string{
+		"0.25":    "<1",
+		"1":       "1-10",
+		"99.99":   "10-100",
+		"100":     "100-1K",
+		"50000":   "10K-100K",
+		"1000000": ">=1M",
+		"0":       "",
+		"-5":      "",
+		"lots":    "",
+		"":        "",
+	}
+	for amount, want := range tests {
+		assert.Equal(t, want, amountBucket(amount), amount)
+	}
+}
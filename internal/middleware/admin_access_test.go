@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestrictAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, office, _ := net.ParseCIDR("10.1.0.0/16")
+	_, bastion, _ := net.ParseCIDR("2001:db8::1/128")
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+
+	tests := []struct {
+		name      string
+		cfg       AdminAccessConfig
+		remote    string
+		forwarded string
+		tls       *tls.ConnectionState
+		status    int
+	}{
+		{"unrestricted", AdminAccessConfig{}, "203.0.113.7:5000", "", nil, http.StatusOK},
+		{"allowed network", AdminAccessConfig{AllowedNets: []*net.IPNet{office, bastion}}, "10.1.4.2:5000", "", nil, http.StatusOK},
+		{"allowed IPv6 address", AdminAccessConfig{AllowedNets: []*net.IPNet{office, bastion}}, "[2001:db8::1]:5000", "", nil, http.StatusOK},
+		{"other network", AdminAccessConfig{AllowedNets: []*net.IPNet{office}}, "203.0.113.7:5000", "", nil, http.StatusForbidden},
+		{"forged forwarded address", AdminAccessConfig{AllowedNets: []*net.IPNet{office}}, "203.0.113.7:5000", "10.1.4.2", nil, http.StatusForbidden},
+		{"verified client certificate", AdminAccessConfig{RequireClientCert: true}, "203.0.113.7:5000", "", verified, http.StatusOK},
+		{"unverified TLS connection", AdminAccessConfig{RequireClientCert: true}, "203.0.113.7:5000", "", &tls.ConnectionState{}, http.StatusForbidden},
+		{"plain HTTP", AdminAccessConfig{RequireClientCert: true}, "203.0.113.7:5000", "", nil, http.StatusForbidden},
+		{"certificate from another network", AdminAccessConfig{AllowedNets: []*net.IPNet{office}, RequireClientCert: true}, "203.0.113.7:5000", "", verified, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.DELETE("/api/v1/admin/cache", RestrictAdmin(tt.cfg), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/admin/cache", nil)
+			req.RemoteAddr = tt.remote
+			req.TLS = tt.tls
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.status == http.StatusForbidden {
+				assert.Contains(t, w.Body.String(), `"error_code":"FORBIDDEN"`)
+			}
+		})
+	}
+}
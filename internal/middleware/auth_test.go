@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"exchange-rate-service/internal/auth"
+)
+
+func newAuthRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	store := auth.NewKeyStore([]auth.APIKey{
+		{ID: "ops", Key: "admin-secret", Roles: []string{auth.RoleAdmin}},
+		{ID: "partner", Key: "reader-secret", Roles: []string{auth.RoleReader}},
+	})
+
+	router := gin.New()
+	router.Use(Authenticate(store, nil))
+	router.GET("/public", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.DELETE("/admin", RequireRole(auth.RoleAdmin), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/owned", RequireAPIKey(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func TestAuth(t *testing.T) {
+	router := newAuthRouter()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header string
+		value  string
+		status int
+	}{
+		{"Anonymous public", http.MethodGet, "/public", "", "", http.StatusOK},
+		{"Invalid key on public", http.MethodGet, "/public", "X-API-Key", "wrong", http.StatusUnauthorized},
+		{"Anonymous admin", http.MethodDelete, "/admin", "", "", http.StatusUnauthorized},
+		{"Reader on admin", http.MethodDelete, "/admin", "X-API-Key", "reader-secret", http.StatusForbidden},
+		{"Admin key header", http.MethodDelete, "/admin", "X-API-Key", "admin-secret", http.StatusOK},
+		{"Admin bearer token", http.MethodDelete, "/admin", "Authorization", "Bearer admin-secret", http.StatusOK},
+		{"Anonymous owned", http.MethodGet, "/owned", "", "", http.StatusUnauthorized},
+		{"Reader on owned", http.MethodGet, "/owned", "X-API-Key", "reader-secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestAuth_BearerJWT(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := auth.NewKeyStore([]auth.APIKey{
+		{ID: "dotted", Key: "looks.like.jwt", Roles: []string{auth.RoleAdmin}},
+	})
+	cfg := auth.DefaultJWTConfig()
+	cfg.JWKSURL = "http://127.0.0.1:1/jwks"
+
+	router := gin.New()
+	router.Use(Authenticate(store, auth.NewJWTVerifier(cfg)))
+	router.DELETE("/admin", RequireRole(auth.RoleAdmin), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/owned", RequireAPIKey(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"Unverifiable token", "eyJhbGciOiJSUzI1NiJ9.e30.c2ln", http.StatusUnauthorized},
+		{"API key shaped like a JWT", "looks.like.jwt", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/admin", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/models"
+)
+
+func TestLimitBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	models.SetRequestLimits(models.RequestLimits{MaxBodyBytes: 16})
+	defer models.SetRequestLimits(models.DefaultRequestLimits())
+
+	router := gin.New()
+	router.Use(LimitBody())
+	router.POST("/", func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		chunked     bool // No Content-Length, so only reading finds the body too large
+		status      int
+	}{
+		{"within limit", `{"from":"USD"}`, "application/json", false, http.StatusOK},
+		{"declared too large", `{"from":"USD","to":"INR"}`, "application/json", false, http.StatusRequestEntityTooLarge},
+		{"read too large", `{"from":"USD","to":"INR"}`, "application/json", true, http.StatusRequestEntityTooLarge},
+		{"upload", "from,to,amount\nUSD,INR,1\n", "text/csv", false, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("x", 32)))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var errResp models.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+	assert.Equal(t, models.ErrCodePayloadTooLarge, errResp.ErrorCode)
+}
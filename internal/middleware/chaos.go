@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/chaos"
+	"exchange-rate-service/internal/models"
+)
+
+// Chaos injects the faults of cfg into /api requests, so callers' retries
+// and timeouts can be tested against the service itself. Delays respect the
+// request deadline; errors are answered with a 500 and malformed bodies with
+// truncated JSON, both without running the handler. Health, readiness and
+// metrics routes are left alone so orchestrators don't restart the instance.
+func Chaos(cfg chaos.Config) gin.HandlerFunc {
+	if !cfg.Enabled() {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	injector := chaos.NewInjector(cfg)
+
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			c.Next()
+			return
+		}
+		fault := injector.Next()
+		if fault.None() {
+			c.Next()
+			return
+		}
+
+		c.Header(chaos.FaultHeader, fault.String())
+		if err := chaos.Sleep(c.Request.Context(), fault.Delay); err != nil {
+			// The deadline passed while delaying, the handler answers it
+			c.Next()
+			return
+		}
+		switch {
+		case fault.Error:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:     "Internal Server Error",
+				Message:   "fault injected by chaos mode",
+				Code:      http.StatusInternalServerError,
+				ErrorCode: models.ErrCodeInternal,
+			})
+		case fault.Malformed:
+			c.Data(http.StatusOK, "application/json; charset=utf-8", chaos.Truncate([]byte(`{"base":"USD","rates":{"EUR":0.92}}`)))
+			c.Abort()
+		default:
+			c.Next()
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"exchange-rate-service/internal/chaos"
+)
+
+func TestChaos(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		faults    chaos.Config
+		path      string
+		status    int
+		fault     string
+		malformed bool
+	}{
+		{"disabled", chaos.Config{}, "/api/v1/rates/latest", http.StatusOK, "", false},
+		{"error", chaos.Config{ErrorRate: 1}, "/api/v1/rates/latest", http.StatusInternalServerError, "error", false},
+		{"malformed", chaos.Config{MalformedRate: 1}, "/api/v1/rates/latest", http.StatusOK, "malformed", true},
+		{"latency", chaos.Config{Latency: 10 * time.Millisecond, LatencyRate: 1}, "/api/v1/rates/latest", http.StatusOK, "latency", false},
+		{"health left alone", chaos.Config{ErrorRate: 1}, "/healthz", http.StatusOK, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(Chaos(tt.faults))
+			handler := func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"status": "ok"})
+			}
+			router.GET("/api/v1/rates/latest", handler)
+			router.GET("/healthz", handler)
+
+			start := time.Now()
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.fault, w.Header().Get(chaos.FaultHeader))
+			assert.GreaterOrEqual(t, time.Since(start), tt.faults.Latency)
+
+			var body map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &body)
+			if tt.malformed {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/brotli"
+)
+
+// Content codings responses can be compressed with
+const (
+	EncodingBrotli  = "br"
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// encoders opens a compressing writer for each supported content coding.
+// The brotli encoder has a single level.
+var encoders = map[string]func(w io.Writer, level int) (io.WriteCloser, error){
+	EncodingBrotli: func(w io.Writer, level int) (io.WriteCloser, error) {
+		return brotli.NewWriter(w), nil
+	},
+	EncodingGzip: func(w io.Writer, level int) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, level)
+	},
+	EncodingDeflate: func(w io.Writer, level int) (io.WriteCloser, error) {
+		return flate.NewWriter(w, level)
+	},
+}
+
+// IsSupportedEncoding reports whether responses can be compressed with
+// encoding
+func IsSupportedEncoding(encoding string) bool {
+	_, ok := encoders[encoding]
+	return ok
+}
+
+// CompressionConfig sets up response compression
+type CompressionConfig struct {
+	Enabled   bool
+	MinSize   int      // Bodies smaller than this many bytes are sent as they are
+	Level     int      // 1 (fastest) to 9 (smallest), or -1 for the encoder's default; br ignores it
+	Encodings []string // Codings offered, in order of preference when the client has none
+}
+
+// DefaultCompressionConfig compresses bodies of 1 KiB or more with brotli,
+// gzip or deflate at the default level
+func DefaultCompressionConfig() CompressionConfig {
+	return CompressionConfig{
+		Enabled:   true,
+		MinSize:   1024,
+		Level:     gzip.DefaultCompression,
+		Encodings: []string{EncodingBrotli, EncodingGzip, EncodingDeflate},
+	}
+}
+
+// Compress compresses responses with the content coding the client prefers
+// among those of cfg, as negotiated from Accept-Encoding. Bodies under
+// MinSize, HEAD requests, responses the handler encoded itself and formats
+// that are compressed already, such as XLSX reports, are sent as they are.
+// Compressed responses lose their Content-Length; every response carries
+// Vary: Accept-Encoding so shared caches keep the codings apart.
+func Compress(cfg CompressionConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.Enabled || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"), cfg.Encodings)
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &compressWriter{ResponseWriter: original, cfg: cfg, encoding: encoding, status: http.StatusOK}
+		c.Writer = writer
+		defer func() {
+			c.Writer = original
+			writer.close()
+		}()
+		c.Next()
+	}
+}
+
+// negotiateEncoding returns the offered coding with the highest quality in
+// accept, the first offered one on ties, or "" when the client accepts none
+// of them. A coding the client does not name may still be chosen through
+// "*".
+func negotiateEncoding(accept string, offered []string) string {
+	if accept == "" {
+		return ""
+	}
+	qualities := make(map[string]float64)
+	for _, entry := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(entry, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		quality := 1.0
+		if key, value, found := strings.Cut(strings.TrimSpace(params), "="); found && strings.TrimSpace(key) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		qualities[name] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range offered {
+		quality, named := qualities[encoding]
+		if !named {
+			quality = qualities["*"]
+		}
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// incompressibleTypes are media types whose bodies are compressed already
+var incompressibleTypes = []string{
+	"image/", "audio/", "video/",
+	"application/zip", "application/gzip", "application/pdf",
+	"application/vnd.openxmlformats-officedocument.",
+}
+
+// compressWriter holds the start of the body until it reaches MinSize, then
+// sends it and the rest through the encoder. A body that stays smaller is
+// sent as it is when the handler returns.
+type compressWriter struct {
+	gin.ResponseWriter
+	cfg      CompressionConfig
+	encoding string
+	status   int
+	buffer   bytes.Buffer
+	size     int            // Uncompressed bytes written by the handler
+	started  bool           // Headers are sent and the body is passed on
+	encoder  io.WriteCloser // Nil when the body is sent as it is
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if !w.started {
+		w.status = code
+	}
+}
+
+func (w *compressWriter) WriteHeaderNow() {}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	w.size += len(data)
+	if w.started {
+		return w.output().Write(data)
+	}
+	w.buffer.Write(data)
+	if w.buffer.Len() >= w.cfg.MinSize {
+		if err := w.start(w.compressible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Status() int {
+	return w.status
+}
+
+func (w *compressWriter) Size() int {
+	return w.size
+}
+
+func (w *compressWriter) Written() bool {
+	return w.started || w.size > 0
+}
+
+// Flush sends what the handler wrote so far; a streamed body that is still
+// under MinSize is sent uncompressed
+func (w *compressWriter) Flush() {
+	if !w.started {
+		w.start(w.buffer.Len() >= w.cfg.MinSize && w.compressible())
+	}
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// compressible reports whether the response may be compressed, judging by
+// its status and headers
+func (w *compressWriter) compressible() bool {
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+	header := w.ResponseWriter.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// start sends the headers and the buffered body, through the encoder when
+// compress is set
+func (w *compressWriter) start(compress bool) error {
+	w.started = true
+	if compress {
+		encoder, err := encoders[w.encoding](w.ResponseWriter, w.cfg.Level)
+		if err != nil {
+			return err
+		}
+		w.encoder = encoder
+		header := w.ResponseWriter.Header()
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+	if w.buffer.Len() == 0 {
+		return nil
+	}
+	_, err := w.output().Write(w.buffer.Bytes())
+	w.buffer.Reset()
+	return err
+}
+
+func (w *compressWriter) output() io.Writer {
+	if w.encoder != nil {
+		return w.encoder
+	}
+	return w.ResponseWriter
+}
+
+// close sends a body that never reached MinSize and finishes the encoding
+func (w *compressWriter) close() {
+	if !w.started {
+		if w.size == 0 && w.status == http.StatusOK {
+			// Nothing was written, leave the response to gin
+			return
+		}
+		w.start(false)
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+	}
+}
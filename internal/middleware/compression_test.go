@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/models"
+)
+
+var largeBody = strings.Repeat(`{"date":"2025-01-02","rate":83.5},`, 100)
+
+func newCompressionRouter(cfg CompressionConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	fetchedAt := time.Now().Add(-time.Minute)
+	router := gin.New()
+	router.Use(Compress(cfg))
+	router.Use(HTTPCache())
+	router.GET("/large", func(c *gin.Context) {
+		SetFreshness(c, models.Freshness{FetchedAt: fetchedAt})
+		c.Data(http.StatusOK, "application/json", []byte(largeBody))
+	})
+	router.GET("/small", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.GET("/report", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", []byte(largeBody))
+	})
+	router.GET("/empty", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return router
+}
+
+func compressionRequest(router *gin.Engine, path, acceptEncoding string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCompress(t *testing.T) {
+	router := newCompressionRouter(DefaultCompressionConfig())
+
+	// Browsers offer br last; it still wins as the first of the offered codings
+	w := compressionRequest(router, "/large", "gzip, deflate, br")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, EncodingBrotli, w.Header().Get("Content-Encoding"))
+	assert.Less(t, w.Body.Len(), len(largeBody)/10)
+
+	w = compressionRequest(router, "/large", "gzip, deflate")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, EncodingGzip, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Less(t, w.Body.Len(), len(largeBody))
+	reader, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, largeBody, string(body))
+
+	w = compressionRequest(router, "/large", "gzip;q=0.5, deflate")
+	assert.Equal(t, EncodingDeflate, w.Header().Get("Content-Encoding"))
+	body, err = io.ReadAll(flate.NewReader(w.Body))
+	require.NoError(t, err)
+	assert.Equal(t, largeBody, string(body))
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		status         int
+	}{
+		{"no Accept-Encoding", "/large", "", http.StatusOK},
+		{"unsupported coding", "/large", "zstd", http.StatusOK},
+		{"coding refused", "/large", "gzip;q=0, *;q=0", http.StatusOK},
+		{"under the minimum size", "/small", "gzip", http.StatusOK},
+		{"compressed format", "/report", "gzip", http.StatusOK},
+		{"no body", "/empty", "gzip", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := compressionRequest(router, tt.path, tt.acceptEncoding)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Empty(t, w.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		})
+	}
+	assert.Equal(t, "ok", compressionRequest(router, "/small", "gzip").Body.String())
+	assert.Equal(t, largeBody, compressionRequest(router, "/large", "zstd").Body.String())
+
+	// A revalidated response stays empty
+	etag := compressionRequest(router, "/large", "gzip").Header().Get("ETag")
+	w = compressionRequest(router, "/large", "gzip", "If-None-Match", etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Zero(t, w.Body.Len())
+
+	cfg := DefaultCompressionConfig()
+	cfg.Enabled = false
+	w = compressionRequest(newCompressionRouter(cfg), "/large", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.NotContains(t, w.Header().Values("Vary"), "Accept-Encoding")
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	offered := []string{EncodingBrotli, EncodingGzip, EncodingDeflate}
+	tests := map[string]string{
+		"gzip":                          EncodingGzip,
+		"deflate, gzip":                 EncodingGzip,
+		"GZIP;q=0.2, deflate":           EncodingDeflate,
+		"br":                            EncodingBrotli,
+		"gzip, deflate, br":             EncodingBrotli,
+		"br;q=0.8, gzip":                EncodingGzip,
+		"BR;q=0.5, gzip;q=0.4":          EncodingBrotli,
+		"zstd, *":                       EncodingBrotli,
+		"*;q=0.5, br;q=0, gzip;q=0":     EncodingDeflate,
+		"identity":                      "",
+		"br;q=0, gzip;q=0, deflate;q=0": "",
+		"gzip;q=high":                   "",
+		"":                              "",
+	}
+	for accept, want := range tests {
+		assert.Equal(t, want, negotiateEncoding(accept, offered), accept)
+	}
+
+	// Without br on offer, a client that only accepts br gets no coding
+	assert.Equal(t, "", negotiateEncoding("br", []string{EncodingGzip, EncodingDeflate}))
+	assert.Equal(t, EncodingGzip, negotiateEncoding("br, *", []string{EncodingGzip, EncodingDeflate}))
+}
+
+func TestCompress_BrotliStreams(t *testing.T) {
+	// A flushed brotli body is decodable up to the flush; here that shows as
+	// the flushed bytes leaving the recorder before the handler returns
+	gin.SetMode(gin.TestMode)
+	cfg := DefaultCompressionConfig()
+	router := gin.New()
+	router.Use(Compress(cfg))
+	sent := make(chan int, 1)
+	var w *httptest.ResponseRecorder
+	router.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Writer.WriteString(largeBody)
+		c.Writer.Flush()
+		sent <- w.Body.Len()
+		c.Writer.WriteString(largeBody)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/stream", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, EncodingBrotli, w.Header().Get("Content-Encoding"))
+	assert.Positive(t, <-sent)
+	assert.Less(t, w.Body.Len(), len(largeBody)/5)
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig sets which browser origins may call the API and how
+type CORSConfig struct {
+	// AllowedOrigins are exact origins such as https://app.example.com,
+	// patterns such as https://*.example.com matching any subdomain, or "*"
+	// for every origin
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string      // Response headers scripts may read beyond the safelisted ones
+	AllowCredentials bool          // Lets browsers send cookies and auth headers; needs explicit origins
+	MaxAge           time.Duration // How long browsers may cache a preflight answer, 0 leaves it to the browser
+}
+
+// DefaultCORSConfig allows every origin without credentials, the methods
+// and headers the API uses, and caches preflight answers for ten minutes
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", TenantHeader},
+		ExposedHeaders: []string{"Content-Disposition", "ETag", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
+		MaxAge:         10 * time.Minute,
+	}
+}
+
+// AllowsAnyOrigin reports whether AllowedOrigins holds "*"
+func (c CORSConfig) AllowsAnyOrigin() bool {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsOrigin reports whether a request from origin may read responses
+func (c CORSConfig) AllowsOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		// https://*.example.com matches https://app.example.com, not https://example.com
+		if scheme, host, ok := strings.Cut(allowed, "://*."); ok {
+			prefix, suffix := scheme+"://", "."+host
+			if len(origin) > len(prefix)+len(suffix) &&
+				strings.EqualFold(origin[:len(prefix)], prefix) &&
+				strings.EqualFold(origin[len(origin)-len(suffix):], suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CORS answers preflight requests and adds the CORS headers to responses for
+// allowed origins. A preflight from another origin is refused with 403;
+// other requests from it are served without CORS headers, so browsers keep
+// the response from the calling script. Responses name the requesting origin
+// rather than "*" unless any origin is allowed without credentials.
+func CORS(cfg CORSConfig) gin.HandlerFunc {
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge / time.Second))
+	wildcard := cfg.AllowsAnyOrigin() && !cfg.AllowCredentials
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		header := c.Writer.Header()
+		if !wildcard {
+			header.Add("Vary", "Origin")
+		}
+		if !cfg.AllowsOrigin(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if wildcard {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			header.Set("Access-Control-Allow-Methods", methods)
+			header.Set("Access-Control-Allow-Headers", headers)
+			if cfg.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		if exposed != "" {
+			header.Set("Access-Control-Expose-Headers", exposed)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func corsRouter(cfg CORSConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS(cfg))
+	router.GET("/api/v1/rates/latest", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func corsRequest(router *gin.Engine, method, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/v1/rates/latest", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS_AnyOrigin(t *testing.T) {
+	router := corsRouter(DefaultCORSConfig())
+
+	w := corsRequest(router, http.MethodGet, "https://app.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Vary"), "the answer is the same for every origin")
+	assert.Contains(t, w.Header().Get("Access-Control-Expose-Headers"), "X-RateLimit-Remaining")
+
+	w = corsRequest(router, http.MethodOptions, "https://app.example.com")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "GET, POST, PUT, DELETE, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "X-Tenant-ID")
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+
+	w = corsRequest(router, http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), "same-origin and server requests get no CORS headers")
+}
+
+func TestCORS_AllowedOrigins(t *testing.T) {
+	cfg := DefaultCORSConfig()
+	cfg.AllowedOrigins = []string{"https://app.example.com", "https://*.example.org"}
+	cfg.AllowCredentials = true
+	cfg.MaxAge = 0
+	router := corsRouter(cfg)
+
+	tests := []struct {
+		name    string
+		method  string
+		origin  string
+		status  int
+		allowed bool
+	}{
+		{"exact origin", http.MethodGet, "https://app.example.com", http.StatusOK, true},
+		{"origin case", http.MethodGet, "https://APP.example.com", http.StatusOK, true},
+		{"subdomain pattern", http.MethodGet, "https://eu.dash.example.org", http.StatusOK, true},
+		{"pattern needs a subdomain", http.MethodGet, "https://example.org", http.StatusOK, false},
+		{"other scheme", http.MethodGet, "http://app.example.com", http.StatusOK, false},
+		{"lookalike domain", http.MethodGet, "https://evil-example.org", http.StatusOK, false},
+		{"allowed preflight", http.MethodOptions, "https://app.example.com", http.StatusNoContent, true},
+		{"refused preflight", http.MethodOptions, "https://evil.example.com", http.StatusForbidden, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := corsRequest(router, tt.method, tt.origin)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, "Origin", w.Header().Get("Vary"))
+			if tt.allowed {
+				assert.Equal(t, tt.origin, w.Header().Get("Access-Control-Allow-Origin"))
+				assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+			} else {
+				assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+				assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+			}
+			assert.Empty(t, w.Header().Get("Access-Control-Max-Age"), "preflight caching is left to the browser")
+		})
+	}
+}
+
+func TestCORSConfig_MaxAgeInSeconds(t *testing.T) {
+	cfg := DefaultCORSConfig()
+	cfg.MaxAge = 2 * time.Hour
+	w := corsRequest(corsRouter(cfg), http.MethodOptions, "https://app.example.com")
+	assert.Equal(t, "7200", w.Header().Get("Access-Control-Max-Age"))
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		timeout     time.Duration
+		hasDeadline bool
+	}{
+		{"bounded", time.Second, true},
+		{"disabled", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deadline time.Time
+			var ok bool
+			router := gin.New()
+			router.Use(Deadline(tt.timeout))
+			router.GET("/", func(c *gin.Context) {
+				deadline, ok = c.Request.Context().Deadline()
+				c.Status(http.StatusOK)
+			})
+
+			start := time.Now()
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, tt.hasDeadline, ok)
+			if tt.hasDeadline {
+				assert.WithinDuration(t, start.Add(tt.timeout), deadline, 100*time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/models"
+)
+
+// RequireEntitlement rejects requests whose caller isn't entitled to
+// entitlement with 403 ENTITLEMENT_REQUIRED, naming the entitlement in the
+// body. Anonymous requests are held to the anonymous policy EnforcePolicy
+// set.
+func RequireEntitlement(entitlement string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if policy, caller := callerPolicy(c); !policy.Entitled(entitlement) {
+			abortEntitlement(c, caller, entitlement)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireConversionEntitlement is RequireEntitlement for conversions, which
+// need latest, or historical when they are at a date or timestamp
+func RequireConversionEntitlement() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy, caller := callerPolicy(c)
+		entitlement := auth.EntitlementLatest
+		if requestDated(c) {
+			entitlement = auth.EntitlementHistorical
+		}
+		if !policy.Entitled(entitlement) {
+			abortEntitlement(c, caller, entitlement)
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestDated reports whether a request names a date or timestamp, in its
+// query or JSON body. The body is put back for the handler to bind.
+func requestDated(c *gin.Context) bool {
+	if c.Query("date") != "" || c.Query("timestamp") != "" {
+		return true
+	}
+	if c.Request.Body == nil || c.ContentType() != gin.MIMEJSON {
+		return false
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	var params struct {
+		Date      string `json:"date"`
+		Timestamp string `json:"timestamp"`
+	}
+	// An unreadable body is left to the handler to reject
+	_ = json.Unmarshal(body, &params)
+	return params.Date != "" || params.Timestamp != ""
+}
+
+func abortEntitlement(c *gin.Context, caller, entitlement string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{
+		Error:       "Forbidden",
+		Message:     caller + " lacks the " + entitlement + " entitlement",
+		Code:        http.StatusForbidden,
+		ErrorCode:   models.ErrCodeEntitlementRequired,
+		Entitlement: entitlement,
+	})
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/models"
+)
+
+func TestRequireEntitlement(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := auth.NewKeyStore([]auth.APIKey{
+		{ID: "basic", Key: "basic-secret", Roles: []string{auth.RoleReader}, Policy: auth.Policy{
+			Entitlements: []string{auth.EntitlementLatest},
+		}},
+		{ID: "pro", Key: "pro-secret", Roles: []string{auth.RoleReader}, Policy: auth.Policy{
+			Entitlements: []string{auth.EntitlementLatest, auth.EntitlementHistorical},
+		}},
+		{ID: "full", Key: "full-secret", Roles: []string{auth.RoleReader}},
+	})
+
+	var body string
+	router := gin.New()
+	router.Use(Authenticate(store, nil), EnforcePolicy(auth.Policy{Entitlements: []string{auth.EntitlementLatest}}))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/v1/convert", RequireConversionEntitlement(), ok)
+	router.POST("/api/v1/convert", RequireConversionEntitlement(), func(c *gin.Context) {
+		data, _ := io.ReadAll(c.Request.Body)
+		body = string(data)
+		c.Status(http.StatusOK)
+	})
+	router.GET("/api/v1/rates/latest", RequireEntitlement(auth.EntitlementLatest), ok)
+	router.GET("/api/v1/rates/historical", RequireEntitlement(auth.EntitlementHistorical), ok)
+	router.GET("/api/v1/rates/forecast", RequireEntitlement(auth.EntitlementAnalytics), ok)
+
+	tests := []struct {
+		name        string
+		key         string
+		method      string
+		path        string
+		body        string
+		status      int
+		entitlement string
+	}{
+		{"Anonymous", "", http.MethodGet, "/api/v1/rates/latest", "", http.StatusOK, ""},
+		{"Anonymous historical", "", http.MethodGet, "/api/v1/rates/historical", "", http.StatusForbidden, auth.EntitlementHistorical},
+		{"Anonymous dated conversion", "", http.MethodGet, "/api/v1/convert?from=USD&to=INR&amount=1&date=2025-01-02", "", http.StatusForbidden, auth.EntitlementHistorical},
+		{"Latest", "basic-secret", http.MethodGet, "/api/v1/rates/latest", "", http.StatusOK, ""},
+		{"Historical without entitlement", "basic-secret", http.MethodGet, "/api/v1/rates/historical", "", http.StatusForbidden, auth.EntitlementHistorical},
+		{"Historical", "pro-secret", http.MethodGet, "/api/v1/rates/historical", "", http.StatusOK, ""},
+		{"Analytics without entitlement", "pro-secret", http.MethodGet, "/api/v1/rates/forecast", "", http.StatusForbidden, auth.EntitlementAnalytics},
+		{"No entitlements listed", "full-secret", http.MethodGet, "/api/v1/rates/forecast", "", http.StatusOK, ""},
+		{"Conversion", "basic-secret", http.MethodGet, "/api/v1/convert?from=USD&to=INR&amount=1", "", http.StatusOK, ""},
+		{"Dated conversion", "basic-secret", http.MethodGet, "/api/v1/convert?from=USD&to=INR&amount=1&date=2025-01-02", "", http.StatusForbidden, auth.EntitlementHistorical},
+		{"Conversion at a timestamp", "basic-secret", http.MethodPost, "/api/v1/convert", `{"from":"USD","to":"INR","amount":1,"timestamp":"2025-01-02T10:00:00Z"}`, http.StatusForbidden, auth.EntitlementHistorical},
+		{"Dated conversion with entitlement", "pro-secret", http.MethodPost, "/api/v1/convert", `{"from":"USD","to":"INR","amount":1,"date":"2025-01-02"}`, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, tt.status, w.Code)
+			if tt.entitlement != "" {
+				var resp models.ErrorResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, models.ErrCodeEntitlementRequired, resp.ErrorCode)
+				assert.Equal(t, tt.entitlement, resp.Entitlement)
+			}
+		})
+	}
+
+	// The handler still reads a body the entitlement was checked against
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/convert", strings.NewReader(`{"from":"EUR","to":"INR"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", "basic-secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"from":"EUR","to":"INR"}`, body)
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+// Gin context keys of what HTTPCache derives a response's headers from
+const (
+	ContextKeyFreshness = "rate_freshness" // models.Freshness of the rate behind the response
+	ContextKeyMarkup    = "rate_markup"    // Markup percent applied to the response's amounts
+)
+
+// cacheVary lists the request headers, besides Accept, a cacheable response
+// may depend on: the tenant, with its currencies and markup, is named by
+// the header or taken from the caller's key
+const cacheVary = "X-Tenant-ID, Authorization, X-API-Key"
+
+// SetFreshness records when the rate served by a handler was fetched, so
+// HTTPCache can derive validators and Cache-Control from it
+func SetFreshness(c *gin.Context, freshness models.Freshness) {
+	if !freshness.FetchedAt.IsZero() {
+		c.Set(ContextKeyFreshness, freshness)
+	}
+}
+
+// SetMarkup records the markup percent applied to the amounts of a response,
+// so its ETag changes with the markup even while the rate doesn't
+func SetMarkup(c *gin.Context, percent float64) {
+	c.Set(ContextKeyMarkup, percent)
+}
+
+// HTTPCache adds ETag, Last-Modified and Cache-Control headers to successful
+// GET responses whose handler called SetFreshness, and answers 304 Not
+// Modified when the client's If-None-Match (or If-Modified-Since) matches.
+// The ETag is derived from the request URL, content type and rate timestamp
+// rather than the body, since bodies carry per-request fields such as the
+// conversion time, plus the tenant and markup the body depends on.
+// Responses to requests carrying credentials or scoped to a tenant are
+// marked private, so shared caches don't replay them to other callers.
+func HTTPCache() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		buffered := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = buffered
+		c.Next()
+		c.Writer = original
+
+		value, exists := c.Get(ContextKeyFreshness)
+		freshness, ok := value.(models.Freshness)
+		if !exists || !ok || buffered.status != http.StatusOK {
+			buffered.flush()
+			return
+		}
+
+		var tenant string
+		if t := services.TenantFromContext(c.Request.Context()); t != nil {
+			tenant = t.ID
+		}
+		markup, _ := c.Get(ContextKeyMarkup)
+
+		header := original.Header()
+		etag := computeETag(c.Request.URL.RequestURI(), header.Get("Content-Type"), tenant, markup, freshness.FetchedAt)
+		header.Set("ETag", etag)
+		header.Set("Last-Modified", freshness.FetchedAt.UTC().Format(http.TimeFormat))
+		header.Set("Cache-Control", cacheControl(freshness.ExpiresAt, credentialed(c.Request) || tenant != ""))
+		header.Add("Vary", cacheVary)
+
+		if notModified(c.Request, etag, freshness.FetchedAt) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			original.WriteHeader(http.StatusNotModified)
+			original.WriteHeaderNow()
+			return
+		}
+
+		buffered.flush()
+	}
+}
+
+// computeETag identifies a representation: the same URL negotiated to CSV or
+// XML, or answered for another tenant or markup, must not share an ETag with
+// its JSON form
+func computeETag(uri, contentType, tenant string, markup interface{}, fetchedAt time.Time) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%v|%d", uri, contentType, tenant, markup, fetchedAt.UnixNano())))
+	return `W/"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+func cacheControl(expiresAt time.Time, private bool) string {
+	if expiresAt.IsZero() {
+		return "no-cache"
+	}
+
+	remaining := time.Until(expiresAt)
+	if remaining <= 0 {
+		return "no-cache"
+	}
+	scope := "public"
+	if private {
+		scope = "private"
+	}
+	return fmt.Sprintf("%s, max-age=%d", scope, int(math.Floor(remaining.Seconds())))
+}
+
+// credentialed reports whether a request carries an API key or bearer token,
+// or names a tenant
+func credentialed(r *http.Request) bool {
+	return r.Header.Get("X-API-Key") != "" || r.Header.Get("Authorization") != "" || r.Header.Get(TenantHeader) != ""
+}
+
+func notModified(r *http.Request, etag string, fetchedAt time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := r.Header.Get("If-Modified-Since"); since != "" {
+		if t, err := http.ParseTime(since); err == nil {
+			return !fetchedAt.Truncate(time.Second).After(t)
+		}
+	}
+	return false
+}
+
+// bufferedWriter holds the response until the middleware decides whether to
+// send it or replace it with 304 Not Modified
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return false
+}
+
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.body.Bytes())
+}
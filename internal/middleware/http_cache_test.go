@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+func newCacheRouter(freshness models.Freshness) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(HTTPCache())
+	router.GET("/rate", func(c *gin.Context) {
+		SetFreshness(c, freshness)
+		c.JSON(http.StatusOK, gin.H{"rate": 83.5, "served_at": time.Now().UnixNano()})
+	})
+	router.GET("/untracked", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	return router
+}
+
+func TestHTTPCache_ETagAndNotModified(t *testing.T) {
+	fetchedAt := time.Now().Add(-10 * time.Minute)
+	router := newCacheRouter(models.Freshness{FetchedAt: fetchedAt, ExpiresAt: fetchedAt.Add(time.Hour)})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rate?from=USD&to=INR", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Contains(t, w.Body.String(), "83.5")
+	assert.Regexp(t, `^public, max-age=(299\d|3000)$`, w.Header().Get("Cache-Control"))
+	assert.Equal(t, fetchedAt.UTC().Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+
+	req := httptest.NewRequest(http.MethodGet, "/rate?from=USD&to=INR", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// A different query is a different resource
+	req = httptest.NewRequest(http.MethodGet, "/rate?from=USD&to=EUR", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHTTPCache_IfModifiedSince(t *testing.T) {
+	fetchedAt := time.Now().Add(-10 * time.Minute)
+	router := newCacheRouter(models.Freshness{FetchedAt: fetchedAt, ExpiresAt: fetchedAt.Add(time.Hour)})
+
+	req := httptest.NewRequest(http.MethodGet, "/rate", nil)
+	req.Header.Set("If-Modified-Since", time.Now().UTC().Format(http.TimeFormat))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+}
+
+func TestHTTPCache_ExpiredRateIsNotCacheable(t *testing.T) {
+	fetchedAt := time.Now().Add(-2 * time.Hour)
+	router := newCacheRouter(models.Freshness{FetchedAt: fetchedAt, ExpiresAt: fetchedAt.Add(time.Hour)})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rate", nil))
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+}
+
+func TestHTTPCache_CredentialedResponsesArePrivate(t *testing.T) {
+	fetchedAt := time.Now().Add(-10 * time.Minute)
+	router := newCacheRouter(models.Freshness{FetchedAt: fetchedAt, ExpiresAt: fetchedAt.Add(time.Hour)})
+
+	for _, header := range []string{"X-API-Key", "Authorization"} {
+		req := httptest.NewRequest(http.MethodGet, "/rate", nil)
+		req.Header.Set(header, "secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Regexp(t, `^private, max-age=\d+$`, w.Header().Get("Cache-Control"), header)
+	}
+}
+
+func TestHTTPCache_TenantsAndMarkup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fetchedAt := time.Now().Add(-10 * time.Minute)
+	tenants := services.NewTenantRegistry([]services.Tenant{{ID: "acme"}, {ID: "globex"}})
+	markup := 0.0
+	store := auth.NewKeyStore([]auth.APIKey{{ID: "gateway", Key: "gateway-secret", Roles: []string{auth.RoleReader, auth.RoleTenantProxy}}})
+
+	router := gin.New()
+	router.Use(Authenticate(store, nil), ResolveTenant(tenants), HTTPCache())
+	router.GET("/convert", func(c *gin.Context) {
+		SetFreshness(c, models.Freshness{FetchedAt: fetchedAt, ExpiresAt: fetchedAt.Add(time.Hour)})
+		SetMarkup(c, markup)
+		c.JSON(http.StatusOK, gin.H{"markup_percent": markup})
+	})
+	get := func(tenant, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/convert?from=USD&to=INR&amount=10", nil)
+		if tenant != "" {
+			req.Header.Set("X-API-Key", "gateway-secret")
+			req.Header.Set(TenantHeader, tenant)
+		}
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	anonymous := get("", "")
+	assert.Regexp(t, `^public, `, anonymous.Header().Get("Cache-Control"))
+	assert.Equal(t, cacheVary, anonymous.Header().Get("Vary"))
+
+	acme := get("acme", "")
+	assert.Regexp(t, `^private, `, acme.Header().Get("Cache-Control"))
+	assert.NotEqual(t, anonymous.Header().Get("ETag"), acme.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, get("acme", acme.Header().Get("ETag")).Code)
+	assert.Equal(t, http.StatusOK, get("globex", acme.Header().Get("ETag")).Code, "another tenant's ETag doesn't match")
+
+	markup = 1.5
+	assert.Equal(t, http.StatusOK, get("acme", acme.Header().Get("ETag")).Code, "a changed markup changes the body")
+}
+
+func TestHTTPCache_UntrackedResponsesPassThrough(t *testing.T) {
+	router := newCacheRouter(models.Freshness{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/untracked", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+	assert.JSONEq(t, `{"ok":true}`, w.Body.String())
+}
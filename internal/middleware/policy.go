@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/models"
+)
+
+// pairlessRatePaths quote every currency against a base, so they would
+// expose pairs a key's policy excludes
+var pairlessRatePaths = map[string]bool{
+	"/api/v1/rates/table": true,
+	"/api/v1/rates/diff":  true,
+}
+
+// ContextKeyAnonymousPolicy is the gin context key holding the *auth.Policy
+// EnforcePolicy applies to a request without credentials
+const ContextKeyAnonymousPolicy = "anonymous_policy"
+
+// EnforcePolicy rejects requests outside the caller's policy: an endpoint it
+// doesn't list, or a from/to pair it doesn't allow, read from the path, query
+// or a JSON body. Requests without an API key or token are held to
+// anonymous, which RequireEntitlement then applies as well, so leaving out a
+// key never widens what a caller may do.
+func EnforcePolicy(anonymous auth.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := APIKeyFromContext(c); !ok {
+			c.Set(ContextKeyAnonymousPolicy, &anonymous)
+		}
+		policy, caller := callerPolicy(c)
+
+		path := c.FullPath()
+		if path == "" {
+			path = c.Request.URL.Path
+		}
+		if !policy.AllowsEndpoint(path) {
+			abortPolicy(c, models.ErrCodeEndpointNotAllowed, caller+" may not call "+path)
+			return
+		}
+
+		if !policy.RestrictsPairs() {
+			c.Next()
+			return
+		}
+		if pairlessRatePaths[path] {
+			abortPolicy(c, models.ErrCodeEndpointNotAllowed, caller+" is limited to some pairs and may not call "+path)
+			return
+		}
+		from, to := requestPair(c)
+		if from != "" && to != "" && !policy.AllowsPair(from, to) {
+			abortPolicy(c, models.ErrCodePairNotAllowed, caller+" may not use "+from+"/"+to)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// callerPolicy returns the policy of the request's API key, or the
+// anonymous policy EnforcePolicy set, together with who it applies to for
+// error messages. Without either every request is allowed.
+func callerPolicy(c *gin.Context) (*auth.Policy, string) {
+	if key, ok := APIKeyFromContext(c); ok {
+		return &key.Policy, "API key " + key.ID
+	}
+	if value, ok := c.Get(ContextKeyAnonymousPolicy); ok {
+		if policy, ok := value.(*auth.Policy); ok {
+			return policy, "anonymous caller"
+		}
+	}
+	return &auth.Policy{}, "anonymous caller"
+}
+
+// requestPair returns the from and to currencies of a request, from its
+// path, query or JSON body. The body is put back for the handler to bind.
+func requestPair(c *gin.Context) (string, string) {
+	from, to, _ := requestParams(c)
+	return from, to
+}
+
+// requestParams returns the from and to currencies and the amount of a
+// request, from its path, query or JSON body, with currency aliases replaced
+// by their codes so that they cannot slip past a pair policy. The body is put
+// back for the handler to bind.
+func requestParams(c *gin.Context) (from, to, amount string) {
+	from, to, amount = rawRequestParams(c)
+	return models.CanonicalCurrency(from), models.CanonicalCurrency(to), amount
+}
+
+func rawRequestParams(c *gin.Context) (from, to, amount string) {
+	if from, to := c.Param("from"), c.Param("to"); from != "" || to != "" {
+		return from, to, c.Query("amount")
+	}
+	from, to, amount = c.Query("from"), c.Query("to"), c.Query("amount")
+	if from != "" || to != "" || c.Request.Body == nil || c.ContentType() != gin.MIMEJSON {
+		return from, to, amount
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return "", "", ""
+	}
+	return bodyParams(body)
+}
+
+// bodyParams returns the from and to currencies and the amount of a JSON
+// request body
+func bodyParams(body []byte) (from, to, amount string) {
+	var params struct {
+		From   string      `json:"from"`
+		To     string      `json:"to"`
+		Amount json.Number `json:"amount"`
+	}
+	// An unreadable body is left to the handler to reject
+	_ = json.Unmarshal(body, &params)
+	return params.From, params.To, params.Amount.String()
+}
+
+func abortPolicy(c *gin.Context, code, message string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{
+		Error:     "Forbidden",
+		Message:   message,
+		Code:      http.StatusForbidden,
+		ErrorCode: code,
+	})
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/models"
+)
+
+func TestEnforcePolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := auth.NewKeyStore([]auth.APIKey{
+		{ID: "partner", Key: "partner-secret", Roles: []string{auth.RoleReader}, Policy: auth.Policy{
+			Pairs:     []string{"USD_INR", "EUR_*"},
+			Endpoints: []string{"/api/v1/convert", "/api/v1/rates/*"},
+		}},
+		{ID: "wide", Key: "wide-secret", Roles: []string{auth.RoleReader}, Policy: auth.Policy{
+			DeniedPairs: []string{"USD_RUB"},
+		}},
+	})
+
+	var body string
+	router := gin.New()
+	router.Use(Authenticate(store, nil), EnforcePolicy(auth.Policy{}))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/v1/convert", ok)
+	router.POST("/api/v1/convert", func(c *gin.Context) {
+		data, _ := io.ReadAll(c.Request.Body)
+		body = string(data)
+		c.Status(http.StatusOK)
+	})
+	router.GET("/api/v1/rates/latest", ok)
+	router.GET("/api/v1/rates/table", ok)
+	router.GET("/api/v1/currencies", ok)
+
+	tests := []struct {
+		name   string
+		key    string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"Anonymous", "", http.MethodGet, "/api/v1/rates/latest?from=USD&to=GBP", "", http.StatusOK, ""},
+		{"Allowed pair", "partner-secret", http.MethodGet, "/api/v1/convert?from=USD&to=INR", "", http.StatusOK, ""},
+		{"Allowed pair reversed", "partner-secret", http.MethodGet, "/api/v1/rates/latest?from=inr&to=usd", "", http.StatusOK, ""},
+		{"Wildcard pair", "partner-secret", http.MethodGet, "/api/v1/rates/latest?from=GBP&to=EUR", "", http.StatusOK, ""},
+		{"Pair outside the list", "partner-secret", http.MethodGet, "/api/v1/convert?from=USD&to=GBP", "", http.StatusForbidden, models.ErrCodePairNotAllowed},
+		{"Pair in a JSON body", "partner-secret", http.MethodPost, "/api/v1/convert", `{"from":"USD","to":"GBP","amount":1}`, http.StatusForbidden, models.ErrCodePairNotAllowed},
+		{"Aliased pair", "partner-secret", http.MethodGet, "/api/v1/convert?from=dollar&to=%E2%82%B9", "", http.StatusOK, ""},
+		{"Aliased pair outside the list", "partner-secret", http.MethodGet, "/api/v1/convert?from=%24&to=pound", "", http.StatusForbidden, models.ErrCodePairNotAllowed},
+		{"Aliased pair in a JSON body", "partner-secret", http.MethodPost, "/api/v1/convert", `{"from":"usd","to":"£","amount":1}`, http.StatusForbidden, models.ErrCodePairNotAllowed},
+		{"Endpoint outside the list", "partner-secret", http.MethodGet, "/api/v1/currencies", "", http.StatusForbidden, models.ErrCodeEndpointNotAllowed},
+		{"Table with a pair list", "partner-secret", http.MethodGet, "/api/v1/rates/table?base=USD", "", http.StatusForbidden, models.ErrCodeEndpointNotAllowed},
+		{"Denied pair", "wide-secret", http.MethodGet, "/api/v1/convert?from=RUB&to=USD", "", http.StatusForbidden, models.ErrCodePairNotAllowed},
+		{"Pair not denied", "wide-secret", http.MethodGet, "/api/v1/convert?from=USD&to=INR", "", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, tt.status, w.Code)
+			if tt.code != "" {
+				var resp models.ErrorResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.code, resp.ErrorCode)
+			}
+		})
+	}
+
+	// The handler still reads a body the policy was checked against
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/convert", strings.NewReader(`{"from":"EUR","to":"INR"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", "partner-secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"from":"EUR","to":"INR"}`, body)
+}
+
+func TestEnforcePolicy_Anonymous(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := auth.NewKeyStore([]auth.APIKey{{ID: "full", Key: "full-secret", Roles: []string{auth.RoleReader}}})
+	router := gin.New()
+	router.Use(Authenticate(store, nil), EnforcePolicy(auth.Policy{
+		Pairs:     []string{"USD_*"},
+		Endpoints: []string{"/api/v1/rates/*"},
+	}))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/v1/rates/latest", ok)
+	router.GET("/api/v1/convert", ok)
+
+	tests := []struct {
+		name   string
+		key    string
+		path   string
+		status int
+		code   string
+	}{
+		{"Anonymous pair in the list", "", "/api/v1/rates/latest?from=USD&to=INR", http.StatusOK, ""},
+		{"Anonymous pair outside the list", "", "/api/v1/rates/latest?from=EUR&to=INR", http.StatusForbidden, models.ErrCodePairNotAllowed},
+		{"Anonymous endpoint outside the list", "", "/api/v1/convert?from=USD&to=INR", http.StatusForbidden, models.ErrCodeEndpointNotAllowed},
+		{"Keys keep their own policy", "full-secret", "/api/v1/convert?from=EUR&to=INR", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, tt.status, w.Code)
+			if tt.code != "" {
+				var resp models.ErrorResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.code, resp.ErrorCode)
+				assert.Contains(t, resp.Message, "anonymous caller")
+			}
+		})
+	}
+}
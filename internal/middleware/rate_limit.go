@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/models"
+)
+
+// RateLimitConfig limits the requests of each client IP with a token bucket
+type RateLimitConfig struct {
+	PerMinute int // Requests a minute each IP is refilled with, 0 disables
+	Burst     int // Requests an idle IP may send back to back
+}
+
+// DefaultRateLimitConfig allows each IP 120 requests a minute in bursts of 20
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		PerMinute: 120,
+		Burst:     20,
+	}
+}
+
+// unlimitedPaths are probes the orchestrator and metrics scrapes the
+// monitoring system sends, which must not fail because another client shares
+// their IP
+var unlimitedPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// ipBucket is a token bucket refilled continuously up to the burst
+type ipBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type rateLimiter struct {
+	perSecond float64
+	burst     float64
+
+	mu        sync.Mutex
+	buckets   map[string]*ipBucket
+	lastSweep time.Time
+}
+
+// RateLimit rejects requests of a client IP beyond its rate with 429
+// RATE_LIMITED and a Retry-After header. Every answer carries the
+// X-RateLimit-Limit (the burst), X-RateLimit-Remaining and X-RateLimit-Reset
+// (the Unix time the IP's burst is whole again) headers. A zero PerMinute
+// disables limiting.
+func RateLimit(cfg RateLimitConfig) gin.HandlerFunc {
+	if cfg.PerMinute <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	burst := cfg.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	limiter := &rateLimiter{
+		perSecond: float64(cfg.PerMinute) / 60,
+		burst:     float64(burst),
+		buckets:   make(map[string]*ipBucket),
+		lastSweep: time.Now(),
+	}
+
+	return func(c *gin.Context) {
+		if unlimitedPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		allowed, remaining, reset, retry := limiter.take(c.ClientIP(), time.Now())
+		c.Header("X-RateLimit-Limit", strconv.Itoa(burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, models.ErrorResponse{
+				Error:     "Too Many Requests",
+				Message:   "rate limit of " + strconv.Itoa(cfg.PerMinute) + " requests a minute exceeded",
+				Code:      http.StatusTooManyRequests,
+				ErrorCode: models.ErrCodeRateLimited,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// take spends a token of ip's bucket. It returns whether one was left, the
+// whole tokens remaining, when the bucket is full again and, when rejected,
+// how long until the next token.
+func (l *rateLimiter) take(ip string, now time.Time) (bool, int, time.Time, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = &ipBucket{tokens: l.burst, last: now}
+		l.buckets[ip] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.perSecond)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	reset := now.Add(l.refill(l.burst - b.tokens))
+	if !allowed {
+		return false, 0, reset, l.refill(1 - b.tokens)
+	}
+	return true, int(b.tokens), reset, 0
+}
+
+// refill returns how long the bucket takes to gain tokens
+func (l *rateLimiter) refill(tokens float64) time.Duration {
+	return time.Duration(tokens / l.perSecond * float64(time.Second))
+}
+
+// sweep drops, about once a minute, the buckets of IPs idle long enough to
+// be full again, so they don't accumulate. The caller holds l.mu.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for ip, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.perSecond >= l.burst {
+			delete(l.buckets, ip)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/models"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RateLimit(RateLimitConfig{PerMinute: 60, Burst: 2}))
+	router.GET("/api/v1/rates/latest", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(path, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = ip + ":4321"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	start := time.Now()
+	w := request("/api/v1/rates/latest", "203.0.113.7")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+	reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, start.Add(time.Second).Unix(), reset, 1)
+
+	w = request("/api/v1/rates/latest", "203.0.113.7")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = request("/api/v1/rates/latest", "203.0.113.7")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), models.ErrCodeRateLimited)
+
+	w = request("/api/v1/rates/latest", "198.51.100.4")
+	assert.Equal(t, http.StatusOK, w.Code, "every IP has its own bucket")
+	w = request("/healthz", "203.0.113.7")
+	assert.Equal(t, http.StatusOK, w.Code, "probes are never limited")
+	assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+}
+
+func TestRateLimit_ForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies([]string{"10.0.0.1"}))
+	router.Use(RateLimit(RateLimitConfig{PerMinute: 60, Burst: 1}))
+	router.GET("/api/v1/rates/latest", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(remote, forwarded string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/rates/latest", nil)
+		req.RemoteAddr = remote + ":4321"
+		req.Header.Set("X-Forwarded-For", forwarded)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("203.0.113.7", "198.51.100.1"))
+	assert.Equal(t, http.StatusTooManyRequests, request("203.0.113.7", "198.51.100.2"), "untrusted clients can't pick a bucket")
+	assert.Equal(t, http.StatusOK, request("10.0.0.1", "198.51.100.3"))
+	assert.Equal(t, http.StatusOK, request("10.0.0.1", "198.51.100.4"), "a trusted proxy forwards each client's IP")
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1", "198.51.100.4"))
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RateLimit(RateLimitConfig{PerMinute: 0, Burst: 1}))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for i := 0; i < 5; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+	}
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	limiter := &rateLimiter{perSecond: 1, burst: 2, buckets: make(map[string]*ipBucket)}
+	now := time.Now()
+	limiter.lastSweep = now
+
+	allowed, remaining, _, _ := limiter.take("ip", now)
+	assert.True(t, allowed)
+	assert.Equal(t, 1, remaining)
+	limiter.take("ip", now)
+
+	allowed, _, reset, retry := limiter.take("ip", now.Add(500*time.Millisecond))
+	assert.False(t, allowed)
+	assert.Equal(t, 500*time.Millisecond, retry)
+	assert.Equal(t, now.Add(2*time.Second), reset)
+
+	allowed, remaining, _, _ = limiter.take("ip", now.Add(time.Second))
+	assert.True(t, allowed)
+	assert.Equal(t, 0, remaining)
+
+	// Idle IPs are forgotten once their bucket refilled
+	limiter.sweep(now.Add(2 * time.Minute))
+	assert.Empty(t, limiter.buckets)
+}
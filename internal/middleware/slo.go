@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/services"
+)
+
+// TrackSLO records the latency and status of every request to a route with
+// tracker, keyed by the route's pattern so /jobs/:id is one endpoint.
+// Requests matching no route are not tracked. A nil tracker tracks nothing.
+func TrackSLO(tracker *services.SLOTracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tracker == nil {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		c.Next()
+
+		if route := c.FullPath(); route != "" {
+			tracker.Observe(route, time.Since(start), c.Writer.Status())
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/services"
+)
+
+func TestTrackSLO(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tracker := services.NewSLOTracker(services.DefaultSLOConfig())
+	router := gin.New()
+	router.Use(TrackSLO(tracker))
+	router.GET("/jobs/:id", func(c *gin.Context) {
+		if c.Param("id") == "broken" {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{"/jobs/1", "/jobs/2", "/jobs/broken", "/unknown"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	report := tracker.Report(time.Now())
+	require.Len(t, report.Endpoints, 1, "requests matching no route are not tracked")
+	assert.Equal(t, "/jobs/:id", report.Endpoints[0].Endpoint)
+	assert.Equal(t, int64(3), report.Endpoints[0].Requests)
+	assert.Equal(t, int64(1), report.Endpoints[0].Errors)
+}
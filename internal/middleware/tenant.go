@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+// TenantHeader names the tenant of a request made with a key allowed to
+// act for any tenant
+const TenantHeader = "X-Tenant-ID"
+
+// ResolveTenant scopes the request context to the caller's tenant: the one
+// its API key belongs to, or else the one named by X-Tenant-ID when the key
+// holds the tenant_proxy role. Only those keys may name a tenant: anyone
+// else could take on another customer's markup, currencies and negative
+// cache. A header naming another tenant than the key's, or sent without a
+// key allowed to, is refused, as is an unknown tenant. Without configured
+// tenants the header is ignored.
+func ResolveTenant(tenants *services.TenantRegistry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tenants.Len() == 0 {
+			c.Next()
+			return
+		}
+
+		requested := strings.TrimSpace(c.GetHeader(TenantHeader))
+		key, authenticated := APIKeyFromContext(c)
+		id := ""
+		switch {
+		case authenticated && key.Tenant != "":
+			if requested != "" && requested != key.Tenant {
+				abortTenant(c, "API key "+key.ID+" does not belong to tenant "+requested)
+				return
+			}
+			id = key.Tenant
+		case requested == "":
+		case !authenticated:
+			abortAnonymous(c)
+			return
+		case !key.HasRole(auth.RoleTenantProxy):
+			abortTenant(c, "API key "+key.ID+" may not name a tenant")
+			return
+		default:
+			id = requested
+		}
+		if id == "" {
+			c.Next()
+			return
+		}
+
+		tenant, ok := tenants.Lookup(id)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "Bad Request",
+				Message:   "unknown tenant " + id,
+				Code:      http.StatusBadRequest,
+				ErrorCode: models.ErrCodeTenantUnknown,
+			})
+			return
+		}
+		c.Request = c.Request.WithContext(services.WithTenant(c.Request.Context(), tenant))
+		c.Next()
+	}
+}
+
+func abortTenant(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{
+		Error:     "Forbidden",
+		Message:   message,
+		Code:      http.StatusForbidden,
+		ErrorCode: models.ErrCodeForbidden,
+	})
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+func TestResolveTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := auth.NewKeyStore([]auth.APIKey{
+		{ID: "acme-app", Key: "acme-secret", Roles: []string{auth.RoleReader}, Tenant: "acme"},
+		{ID: "ops", Key: "ops-secret", Roles: []string{auth.RoleReader}},
+		{ID: "gateway", Key: "gateway-secret", Roles: []string{auth.RoleReader, auth.RoleTenantProxy}},
+	})
+	tenants := services.NewTenantRegistry([]services.Tenant{{ID: "acme"}, {ID: "globex"}})
+
+	router := gin.New()
+	router.Use(Authenticate(store, nil), ResolveTenant(tenants))
+	router.GET("/api/v1/currencies", func(c *gin.Context) {
+		tenant := services.TenantFromContext(c.Request.Context())
+		if tenant == nil {
+			c.String(http.StatusOK, "")
+			return
+		}
+		c.String(http.StatusOK, tenant.ID)
+	})
+
+	tests := []struct {
+		name   string
+		key    string
+		header string
+		status int
+		tenant string
+		code   string
+	}{
+		{"No tenant", "", "", http.StatusOK, "", ""},
+		{"Header without a key", "", "globex", http.StatusUnauthorized, "", models.ErrCodeUnauthorized},
+		{"Header from a tenant proxy", "gateway-secret", "globex", http.StatusOK, "globex", ""},
+		{"Tenant proxy without header", "gateway-secret", "", http.StatusOK, "", ""},
+		{"Key mapping", "acme-secret", "", http.StatusOK, "acme", ""},
+		{"Header matching the key", "acme-secret", "acme", http.StatusOK, "acme", ""},
+		{"Header of another tenant", "acme-secret", "globex", http.StatusForbidden, "", models.ErrCodeForbidden},
+		{"Key without tenant", "ops-secret", "globex", http.StatusForbidden, "", models.ErrCodeForbidden},
+		{"Key without tenant, no header", "ops-secret", "", http.StatusOK, "", ""},
+		{"Unknown tenant", "gateway-secret", "initech", http.StatusBadRequest, "", models.ErrCodeTenantUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/currencies", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			if tt.header != "" {
+				req.Header.Set(TenantHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, tt.status, w.Code)
+			if tt.code != "" {
+				var resp models.ErrorResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.code, resp.ErrorCode)
+				return
+			}
+			assert.Equal(t, tt.tenant, w.Body.String())
+		})
+	}
+}
+
+func TestResolveTenant_WithoutTenants(t *testing.T) {
+	router := gin.New()
+	router.Use(ResolveTenant(services.NewTenantRegistry(nil)))
+	router.GET("/", func(c *gin.Context) {
+		assert.Nil(t, services.TenantFromContext(c.Request.Context()))
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TenantHeader, "acme")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, "the header is ignored while multi-tenancy is off")
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+// maxUsageBodyBytes bounds the part of a JSON body kept to find the pair of
+// a request; the pair fields of larger bodies are not counted
+const maxUsageBodyBytes = 64 << 10
+
+// TrackUsage counts every request of an authenticated key with tracker: its
+// route, the currency pair it names, its status and the bytes of its body
+// and response. It must follow Authenticate, and counts requests later
+// middleware rejects too. A nil tracker tracks nothing.
+func TrackUsage(tracker *services.UsageTracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := APIKeyFromContext(c)
+		if tracker == nil || !ok {
+			c.Next()
+			return
+		}
+		// The body is kept as the handler reads it rather than read ahead,
+		// so a body over its size limit still fails there
+		var body *capturedBody
+		if c.Request.Body != nil && c.ContentType() == gin.MIMEJSON {
+			body = &capturedBody{ReadCloser: c.Request.Body}
+			c.Request.Body = body
+		}
+		c.Next()
+
+		from, to := c.Param("from"), c.Param("to")
+		if from == "" && to == "" {
+			from, to = c.Query("from"), c.Query("to")
+		}
+		if from == "" && to == "" && body != nil {
+			from, to, _ = bodyParams(body.buf.Bytes())
+		}
+		req := services.UsageRequest{
+			KeyID:    key.ID,
+			Endpoint: c.FullPath(),
+			From:     models.CanonicalCurrency(from),
+			To:       models.CanonicalCurrency(to),
+			Status:   c.Writer.Status(),
+			At:       time.Now(),
+		}
+		if c.Request.ContentLength > 0 {
+			req.BytesIn = c.Request.ContentLength
+		}
+		if size := c.Writer.Size(); size > 0 {
+			req.BytesOut = int64(size)
+		}
+		tracker.Record(req)
+	}
+}
+
+// capturedBody keeps the first maxUsageBodyBytes of a request body read
+// through it
+type capturedBody struct {
+	io.ReadCloser
+	buf bytes.Buffer
+}
+
+func (b *capturedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := maxUsageBodyBytes - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(n, room)])
+	}
+	return n, err
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/auth"
+	"exchange-rate-service/internal/services"
+)
+
+func TestTrackUsage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tracker := services.NewUsageTracker()
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if id := c.GetHeader("X-Key-ID"); id != "" {
+			c.Set(ContextKeyAPIKey, &auth.APIKey{ID: id})
+		}
+	})
+	router.Use(TrackUsage(tracker))
+	router.POST("/convert", func(c *gin.Context) {
+		var req struct {
+			From string `json:"from"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.String(http.StatusOK, "converted")
+	})
+	router.GET("/rates/latest", func(c *gin.Context) {
+		c.Status(http.StatusBadGateway)
+	})
+
+	send := func(method, path, key, body string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("X-Key-ID", key)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	send(http.MethodPost, "/convert", "partner", `{"from":"usd","to":"INR","amount":1}`)
+	send(http.MethodPost, "/convert", "partner", `{"from":"usd","to":"INR","amount":2}`)
+	send(http.MethodPost, "/convert", "partner", `{"from":`)
+	send(http.MethodGet, "/rates/latest?from=EUR&to=USD", "partner", "")
+	send(http.MethodGet, "/unknown", "partner", "")
+	send(http.MethodPost, "/convert", "", `{"from":"USD","to":"INR","amount":1}`)
+
+	usage, ok := tracker.Usage("partner")
+	require.True(t, ok)
+	assert.Equal(t, int64(5), usage.Requests)
+	assert.Equal(t, int64(2), usage.ClientErrors, "the malformed body and the unknown route")
+	assert.Equal(t, int64(1), usage.ServerErrors)
+	assert.Equal(t, int64(2*len("converted")), usage.BytesOut)
+	assert.Positive(t, usage.BytesIn)
+	require.Len(t, usage.Endpoints, 2, "requests matching no route are counted without an endpoint")
+	assert.Equal(t, "/convert", usage.Endpoints[0].Endpoint)
+	assert.Equal(t, int64(3), usage.Endpoints[0].Requests)
+	require.Len(t, usage.Pairs, 2)
+	assert.Equal(t, "USD", usage.Pairs[0].From)
+	assert.Equal(t, "INR", usage.Pairs[0].To)
+	assert.Equal(t, int64(2), usage.Pairs[0].Requests)
+
+	assert.Len(t, tracker.Report().Keys, 1, "anonymous requests are not counted")
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// MaxChainCurrencies bounds the currencies of a conversion chain
+const MaxChainCurrencies = 6
+
+// ChainConversionRequest converts an amount through an explicit list of
+// currencies, e.g. BRL, USD, INR for a remittance routed through dollars
+type ChainConversionRequest struct {
+	Path     []string   `json:"path" binding:"required"`
+	Amount   float64    `json:"amount" binding:"required,gt=0"`
+	Markups  []*float64 `json:"markups,omitempty"`  // Optional markup percent per leg; null keeps the configured markup
+	Provider string     `json:"provider,omitempty"` // Optional provider to pin every leg's rate to
+	Rounding string     `json:"rounding,omitempty"` // RoundingHalfUp by default
+}
+
+// ChainLeg is one hop of a conversion chain. Its amount is the previous
+// leg's converted amount, rounded to that currency's minor units as it would
+// be paid out.
+type ChainLeg struct {
+	From            string  `json:"from"`
+	To              string  `json:"to"`
+	Amount          float64 `json:"amount"`
+	ConvertedAmount float64 `json:"converted_amount"`
+	Rate            float64 `json:"rate"`            // Applied rate, mid-market plus markup
+	MidMarketRate   float64 `json:"mid_market_rate"` // Rate before markup
+	MarkupPercent   float64 `json:"markup_percent"`
+	Derived         string  `json:"derived,omitempty"`
+	Source
+}
+
+// ChainConversionResponse is the result of a conversion chain with the
+// breakdown of every leg
+type ChainConversionResponse struct {
+	From            string     `json:"from"`
+	To              string     `json:"to"`
+	Path            []string   `json:"path"`
+	Amount          float64    `json:"amount"`
+	ConvertedAmount float64    `json:"converted_amount"`
+	Rate            float64    `json:"rate"` // Effective rate, converted amount over amount
+	Rounding        string     `json:"rounding"`
+	Legs            []ChainLeg `json:"legs"`
+	Date            time.Time  `json:"date"`
+	ConversionID    string     `json:"conversion_id,omitempty"` // Set once the chain is recorded; GET /conversions/{id} returns it again
+}
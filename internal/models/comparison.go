@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ProviderQuote is a provider's current rate of a pair, or why there is none
+type ProviderQuote struct {
+	Rate              float64 `json:"rate,omitempty"`
+	Served            bool    `json:"served,omitempty"`             // The rate the service converts at
+	DifferencePercent float64 `json:"difference_percent,omitempty"` // How far the rate is from the served one
+	Derived           string  `json:"derived,omitempty"`
+	Error             string  `json:"error,omitempty"`
+	ErrorCode         string  `json:"error_code,omitempty"`
+	Source
+}
+
+// RateComparison lays the quotes of every configured provider for a pair
+// side by side
+type RateComparison struct {
+	From          string          `json:"from"`
+	To            string          `json:"to"`
+	Quotes        []ProviderQuote `json:"quotes"`         // In provider order, the served one first
+	SpreadPercent float64         `json:"spread_percent"` // (highest - lowest) / lowest * 100 of the quotes found
+	ComparedAt    time.Time       `json:"compared_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// CorrelationRequest asks how the daily moves of several pairs relate over
+// a window ending today
+type CorrelationRequest struct {
+	Pairs    []string // Pairs as FROM_TO, e.g. USD_INR
+	Window   int      // Length of the window in days
+	Provider string   // Provider to pin the rates to, the default one when empty
+}
+
+// CorrelationResponse holds the volatility of each pair and the correlation
+// of every two pairs' day-over-day changes. Matrix follows the order of
+// Pairs; an entry is null when the two pairs share too few trading days or
+// one of them did not move.
+type CorrelationResponse struct {
+	Window    int              `json:"window"`
+	StartDate string           `json:"start_date"`
+	EndDate   string           `json:"end_date"`
+	Pairs     []PairVolatility `json:"pairs"`
+	Matrix    [][]*float64     `json:"matrix"`
+	Date      time.Time        `json:"date"`
+	Freshness `json:"-"`
+	Source
+}
+
+// PairVolatility summarizes the day-over-day changes of one pair
+type PairVolatility struct {
+	Pair              string  `json:"pair"`
+	From              string  `json:"from"`
+	To                string  `json:"to"`
+	Samples           int     `json:"samples"`            // Day-over-day changes in the window
+	VolatilityPercent float64 `json:"volatility_percent"` // Standard deviation of those changes
+}
@@ -0,0 +1,164 @@
+package models
+
+import "sync"
+
+// Currency types
+const (
+	CurrencyTypeFiat   = "fiat"
+	CurrencyTypeCrypto = "crypto"
+	CurrencyTypeMetal  = "metal"
+)
+
+// UnitTroyOunce is the unit precious metals are quoted in: a rate of USD to
+// XAU is the troy ounces of gold one dollar buys
+const UnitTroyOunce = "troy_ounce"
+
+// CurrencyInfo describes a currency for display and formatting purposes
+type CurrencyInfo struct {
+	Code          string   `json:"code"`
+	Name          string   `json:"name"`
+	Symbol        string   `json:"symbol"`
+	DecimalPlaces int      `json:"decimal_places"`    // ISO 4217 minor units
+	Countries     []string `json:"countries"`         // ISO 3166-1 alpha-2 codes
+	Type          string   `json:"type"`              // fiat, crypto or metal
+	Unit          string   `json:"unit,omitempty"`    // What one unit is, for metals the troy ounce
+	MinAmount     float64  `json:"min_amount"`        // Smallest amount converted from the currency, 0 for any positive amount
+	MaxAmount     float64  `json:"max_amount"`        // Largest amount converted from the currency, DefaultMaxAmount when 0
+	Aliases       []string `json:"aliases,omitempty"` // Other inputs accepted for the code, such as its symbol
+}
+
+// DefaultMaxAmount is the largest amount converted from a currency whose
+// metadata sets no maximum
+const DefaultMaxAmount = 1e15
+
+// AmountLimits bounds the amounts converted from a currency. A zero Min
+// allows any positive amount and a zero Max falls back to the metadata.
+type AmountLimits struct {
+	Min float64 `json:"min_amount"`
+	Max float64 `json:"max_amount"`
+}
+
+var (
+	amountLimitsMu sync.RWMutex
+	amountLimits   = map[string]AmountLimits{}
+)
+
+// SetAmountLimits replaces the configured amount limits of each currency,
+// which take precedence over CurrencyMetadata. It is safe to call while
+// requests are being served.
+func SetAmountLimits(byCurrency map[string]AmountLimits) {
+	limits := make(map[string]AmountLimits, len(byCurrency))
+	for code, l := range byCurrency {
+		limits[code] = l
+	}
+
+	amountLimitsMu.Lock()
+	defer amountLimitsMu.Unlock()
+	amountLimits = limits
+}
+
+// AmountLimitsOf returns the effective amount limits of a currency: the
+// configured ones, else those of its metadata, with DefaultMaxAmount as the
+// maximum when neither sets one
+func AmountLimitsOf(code string) AmountLimits {
+	info := CurrencyMetadata[code]
+	limits := AmountLimits{Min: info.MinAmount, Max: info.MaxAmount}
+
+	amountLimitsMu.RLock()
+	configured, ok := amountLimits[code]
+	amountLimitsMu.RUnlock()
+	if ok {
+		if configured.Min > 0 {
+			limits.Min = configured.Min
+		}
+		if configured.Max > 0 {
+			limits.Max = configured.Max
+		}
+	}
+	if limits.Max <= 0 {
+		limits.Max = DefaultMaxAmount
+	}
+	return limits
+}
+
+// CurrencyRequest names a currency to add to the supported set
+type CurrencyRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// IsMetal reports whether code is a precious metal such as XAU
+func IsMetal(code string) bool {
+	return CurrencyMetadata[code].Type == CurrencyTypeMetal
+}
+
+// CurrencyMetadata holds the metadata of every supported currency
+var CurrencyMetadata = map[string]CurrencyInfo{
+	"USD": {
+		Code:          "USD",
+		Name:          "United States Dollar",
+		Symbol:        "$",
+		DecimalPlaces: 2,
+		Countries:     []string{"US", "EC", "SV", "PA", "PR", "TL", "ZW"},
+		Type:          CurrencyTypeFiat,
+	},
+	"INR": {
+		Code:          "INR",
+		Name:          "Indian Rupee",
+		Symbol:        "₹",
+		DecimalPlaces: 2,
+		Countries:     []string{"IN", "BT"},
+		Type:          CurrencyTypeFiat,
+	},
+	"EUR": {
+		Code:          "EUR",
+		Name:          "Euro",
+		Symbol:        "€",
+		DecimalPlaces: 2,
+		Countries: []string{
+			"AT", "BE", "HR", "CY", "EE", "FI", "FR", "DE", "GR", "IE",
+			"IT", "LV", "LT", "LU", "MT", "NL", "PT", "SK", "SI", "ES",
+		},
+		Type: CurrencyTypeFiat,
+	},
+	"JPY": {
+		Code:          "JPY",
+		Name:          "Japanese Yen",
+		Symbol:        "¥",
+		DecimalPlaces: 0,
+		Countries:     []string{"JP"},
+		Type:          CurrencyTypeFiat,
+		// A yen is worth about a hundredth of the other majors
+		MaxAmount: 1e17,
+	},
+	"GBP": {
+		Code:          "GBP",
+		Name:          "British Pound Sterling",
+		Symbol:        "£",
+		DecimalPlaces: 2,
+		Countries:     []string{"GB", "IM", "JE", "GG"},
+		Type:          CurrencyTypeFiat,
+	},
+	// Metals have no ISO 4217 minor unit; amounts are shown to a
+	// ten-thousandth of a troy ounce
+	"XAU": {
+		Code:          "XAU",
+		Name:          "Gold",
+		Symbol:        "XAU",
+		DecimalPlaces: 4,
+		Countries:     []string{},
+		Type:          CurrencyTypeMetal,
+		Unit:          UnitTroyOunce,
+		// Above the world's mined gold
+		MaxAmount: 1e10,
+	},
+	"XAG": {
+		Code:          "XAG",
+		Name:          "Silver",
+		Symbol:        "XAG",
+		DecimalPlaces: 4,
+		Countries:     []string{},
+		Type:          CurrencyTypeMetal,
+		Unit:          UnitTroyOunce,
+		MaxAmount:     1e11,
+	},
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// commonAliases are what people type for the built-in currencies besides
+// the names and symbols of CurrencyMetadata, keyed in lower case
+var commonAliases = map[string]string{
+	"us$":      "USD",
+	"dollar":   "USD",
+	"dollars":  "USD",
+	"rs":       "INR",
+	"rs.":      "INR",
+	"rupee":    "INR",
+	"rupees":   "INR",
+	"euro":     "EUR",
+	"euros":    "EUR",
+	"yen":      "JPY",
+	"円":        "JPY",
+	"pound":    "GBP",
+	"pounds":   "GBP",
+	"sterling": "GBP",
+	"gold":     "XAU",
+	"silver":   "XAG",
+}
+
+// builtinAliases adds the lower-cased name and symbol of every currency in
+// CurrencyMetadata to commonAliases
+var builtinAliases = func() map[string]string {
+	aliases := make(map[string]string, len(commonAliases)+2*len(CurrencyMetadata))
+	for alias, code := range commonAliases {
+		aliases[alias] = code
+	}
+	for code, info := range CurrencyMetadata {
+		aliases[strings.ToLower(info.Name)] = code
+		aliases[strings.ToLower(info.Symbol)] = code
+	}
+	return aliases
+}()
+
+var (
+	aliasesMu         sync.RWMutex
+	configuredAliases = map[string]string{}
+)
+
+// SetCurrencyAliases replaces the configured aliases, alias -> currency
+// code. They take precedence over the built-in names and symbols. It is safe
+// to call while requests are being served.
+func SetCurrencyAliases(aliases map[string]string) {
+	normalized := make(map[string]string, len(aliases))
+	for alias, code := range aliases {
+		normalized[strings.ToLower(strings.TrimSpace(alias))] = strings.ToUpper(strings.TrimSpace(code))
+	}
+
+	aliasesMu.Lock()
+	defer aliasesMu.Unlock()
+	configuredAliases = normalized
+}
+
+// CanonicalCurrency returns the currency code input stands for: a supported
+// code in any case, or a configured or built-in alias such as "₹", "Rs" or
+// "rupee", matched case-insensitively. Anything else is returned upper-cased,
+// for validation to reject.
+func CanonicalCurrency(input string) string {
+	trimmed := strings.TrimSpace(input)
+	code := strings.ToUpper(trimmed)
+	if IsSupportedCurrency(code) {
+		return code
+	}
+
+	alias := strings.ToLower(trimmed)
+	aliasesMu.RLock()
+	configured, ok := configuredAliases[alias]
+	aliasesMu.RUnlock()
+	if ok {
+		return configured
+	}
+	if builtin, ok := builtinAliases[alias]; ok {
+		return builtin
+	}
+	return code
+}
+
+// AliasesOf returns the aliases that resolve to code, sorted
+func AliasesOf(code string) []string {
+	aliasesMu.RLock()
+	defer aliasesMu.RUnlock()
+
+	var aliases []string
+	for alias, target := range builtinAliases {
+		if _, overridden := configuredAliases[alias]; target == code && !overridden && alias != strings.ToLower(code) {
+			aliases = append(aliases, alias)
+		}
+	}
+	for alias, target := range configuredAliases {
+		if target == code {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
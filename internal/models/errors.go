@@ -0,0 +1,175 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Error codes returned in ErrorResponse.ErrorCode. They are part of the API:
+// clients branch on them, so a published code is never renamed.
+const (
+	ErrCodeInvalidRequest      = "INVALID_REQUEST"      // Body or parameters could not be parsed
+	ErrCodeMissingParameter    = "MISSING_PARAMETER"    // A required parameter is empty
+	ErrCodeCurrencyUnsupported = "CURRENCY_UNSUPPORTED" // Currency code is not supported
+	ErrCodeAmountInvalid       = "AMOUNT_INVALID"       // Amount is not positive
+	ErrCodeAmountBelowMinimum  = "AMOUNT_BELOW_MINIMUM" // Amount is under the minimum of its currency
+	ErrCodeAmountAboveMaximum  = "AMOUNT_ABOVE_MAXIMUM" // Amount is over the maximum of its currency
+	ErrCodeDateInvalid         = "DATE_INVALID"         // Date or timestamp is malformed
+	ErrCodeDateOutOfRange      = "DATE_OUT_OF_RANGE"    // Date is in the future, too old, or the range too long
+	ErrCodeLocaleInvalid       = "LOCALE_INVALID"       // Locale is not a BCP 47 tag
+	ErrCodeValueInvalid        = "VALUE_INVALID"        // Parameter is well-formed but outside its allowed values
+	ErrCodeLimitExceeded       = "LIMIT_EXCEEDED"       // Request asks for more pairs, rows or precision than allowed
+	ErrCodePayloadTooLarge     = "PAYLOAD_TOO_LARGE"    // Request body is larger than allowed
+	ErrCodeProviderUnknown     = "PROVIDER_UNKNOWN"     // Requested provider does not exist
+	ErrCodeRateNotFound        = "RATE_NOT_FOUND"       // Provider has no rate for the pair
+	ErrCodeProviderUnavailable = "PROVIDER_UNAVAILABLE" // Provider failed or could not be reached
+	ErrCodeProviderBusy        = "PROVIDER_BUSY"        // Provider is not called for now: circuit open or throttled
+	ErrCodeTimeout             = "TIMEOUT"              // Request deadline passed before the provider answered
+	ErrCodeCanceled            = "REQUEST_CANCELED"     // Client went away before the answer was ready
+	ErrCodeUnauthorized        = "UNAUTHORIZED"
+	ErrCodeForbidden           = "FORBIDDEN"
+	ErrCodePairNotAllowed      = "PAIR_NOT_ALLOWED"     // The API key's policy excludes the currency pair
+	ErrCodeEndpointNotAllowed  = "ENDPOINT_NOT_ALLOWED" // The API key's policy excludes the endpoint
+	ErrCodeEntitlementRequired = "ENTITLEMENT_REQUIRED" // The API key lacks the entitlement the endpoint needs
+	ErrCodeTenantUnknown       = "TENANT_UNKNOWN"       // X-Tenant-ID names no configured tenant
+	ErrCodeNotFound            = "NOT_FOUND"
+	ErrCodeJobPending          = "JOB_PENDING"    // The job's result is not ready yet
+	ErrCodeQuoteExpired        = "QUOTE_EXPIRED"  // The quote's rate is no longer locked
+	ErrCodeQuoteExecuted       = "QUOTE_EXECUTED" // The quote was already executed
+	ErrCodeRateLimited         = "RATE_LIMITED"   // The client IP sent more requests than its rate limit
+	ErrCodeNotImplemented      = "NOT_IMPLEMENTED"
+	ErrCodeInternal            = "INTERNAL_ERROR"
+)
+
+// StatusClientClosedRequest is the non-standard 499 of a request the client
+// abandoned; nobody receives the response, it shows in access logs
+const StatusClientClosedRequest = 499
+
+// errorStatuses maps error codes to HTTP statuses: 400 for requests that
+// could not be read, 413 for bodies over the size limit, 422 for well-formed
+// requests with invalid values, 404 for pairs without a rate, 502 when the
+// provider failed, 503 when it is not called for now and 504 when it did not
+// answer within the request deadline
+var errorStatuses = map[string]int{
+	ErrCodeInvalidRequest:      http.StatusBadRequest,
+	ErrCodeMissingParameter:    http.StatusBadRequest,
+	ErrCodeDateInvalid:         http.StatusBadRequest,
+	ErrCodeLocaleInvalid:       http.StatusBadRequest,
+	ErrCodeCurrencyUnsupported: http.StatusUnprocessableEntity,
+	ErrCodeAmountInvalid:       http.StatusUnprocessableEntity,
+	ErrCodeAmountBelowMinimum:  http.StatusUnprocessableEntity,
+	ErrCodeAmountAboveMaximum:  http.StatusUnprocessableEntity,
+	ErrCodeDateOutOfRange:      http.StatusUnprocessableEntity,
+	ErrCodeValueInvalid:        http.StatusUnprocessableEntity,
+	ErrCodeLimitExceeded:       http.StatusUnprocessableEntity,
+	ErrCodePayloadTooLarge:     http.StatusRequestEntityTooLarge,
+	ErrCodeProviderUnknown:     http.StatusUnprocessableEntity,
+	ErrCodeRateNotFound:        http.StatusNotFound,
+	ErrCodeProviderUnavailable: http.StatusBadGateway,
+	ErrCodeProviderBusy:        http.StatusServiceUnavailable,
+	ErrCodeTimeout:             http.StatusGatewayTimeout,
+	ErrCodeCanceled:            StatusClientClosedRequest,
+	ErrCodeUnauthorized:        http.StatusUnauthorized,
+	ErrCodeForbidden:           http.StatusForbidden,
+	ErrCodePairNotAllowed:      http.StatusForbidden,
+	ErrCodeEndpointNotAllowed:  http.StatusForbidden,
+	ErrCodeEntitlementRequired: http.StatusForbidden,
+	ErrCodeTenantUnknown:       http.StatusBadRequest,
+	ErrCodeNotFound:            http.StatusNotFound,
+	ErrCodeJobPending:          http.StatusConflict,
+	ErrCodeQuoteExpired:        http.StatusGone,
+	ErrCodeQuoteExecuted:       http.StatusConflict,
+	ErrCodeRateLimited:         http.StatusTooManyRequests,
+	ErrCodeNotImplemented:      http.StatusNotImplemented,
+	ErrCodeInternal:            http.StatusInternalServerError,
+}
+
+// ErrorStatus returns the HTTP status of an error code, 400 for unknown codes
+func ErrorStatus(code string) int {
+	if status, ok := errorStatuses[code]; ok {
+		return status
+	}
+	return http.StatusBadRequest
+}
+
+// FieldError points at the request field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// CodedError is an error carrying a stable error code and, for validation
+// failures, the request field at fault. Wrapping it with %w keeps the code.
+type CodedError struct {
+	Code    string
+	Field   string // Empty when the error is not about a single field
+	Message string
+	Err     error         // Underlying cause, if any
+	Retry   time.Duration // When retrying is worth it, answered as Retry-After; 0 if unknown
+}
+
+// NewError returns a CodedError not tied to a field
+func NewError(code, format string, args ...interface{}) *CodedError {
+	return &CodedError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewFieldError returns a CodedError about one request field
+func NewFieldError(code, field, format string, args ...interface{}) *CodedError {
+	return &CodedError{Code: code, Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *CodedError) Error() string {
+	return e.Message
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the kind of failure of e's code, such as
+// apperrors.ErrUnsupportedCurrency for CURRENCY_UNSUPPORTED. More specific
+// kinds, which unwrap to the one of their code, only match when wrapped.
+func (e *CodedError) Is(target error) bool {
+	kind, ok := target.(errorCoder)
+	if !ok || kind.ErrorCode() != e.Code {
+		return false
+	}
+	return errors.Unwrap(target) == nil
+}
+
+// errorCoder is implemented by errors of a kind with an error code, as those
+// of package apperrors are
+type errorCoder interface {
+	ErrorCode() string
+}
+
+// ForField returns a copy of err attributed to field with prefix prepended
+// to its message, e.g. "invalid start date: ". Errors without a code become
+// INVALID_REQUEST.
+func ForField(err error, field, prefix string) *CodedError {
+	attributed := &CodedError{Code: ErrCodeInvalidRequest, Field: field, Message: prefix + err.Error(), Err: err}
+	var coded *CodedError
+	if errors.As(err, &coded) {
+		attributed.Code = coded.Code
+	}
+	return attributed
+}
+
+// ErrorCodeOf returns the code and field of the first CodedError in err's
+// chain, or the code of the first error there of a kind with one. Errors
+// with neither are reported as INVALID_REQUEST, matching the 400 they were
+// always answered with.
+func ErrorCodeOf(err error) (code, field string) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch e := err.(type) {
+		case *CodedError:
+			return e.Code, e.Field
+		case errorCoder:
+			return e.ErrorCode(), ""
+		}
+	}
+	return ErrCodeInvalidRequest, ""
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -15,20 +17,141 @@ type ExchangeRate struct {
 
 // ConversionRequest represents a request to convert currency
 type ConversionRequest struct {
-	From   string  `json:"from" binding:"required"`
-	To     string  `json:"to" binding:"required"`
-	Amount float64 `json:"amount" binding:"required,gt=0"`
-	Date   string  `json:"date,omitempty"` // Optional, format: YYYY-MM-DD
+	From      string  `json:"from" binding:"required"`
+	To        string  `json:"to" binding:"required"`
+	Amount    float64 `json:"amount" binding:"required,gt=0"`
+	Date      string  `json:"date,omitempty"`      // Optional, format: YYYY-MM-DD
+	Timestamp string  `json:"timestamp,omitempty"` // Optional RFC3339 instant, exclusive with Date
+	Locale    string  `json:"locale,omitempty"`    // Optional BCP 47 locale, e.g. en-IN; adds formatted amounts
+	Provider  string  `json:"provider,omitempty"`  // Optional provider to pin the rate to, e.g. frankfurter
+	Precision *int    `json:"precision,omitempty"` // Decimals of the converted amount, To's minor units by default
+	Rounding  string  `json:"rounding,omitempty"`  // RoundingHalfUp by default
+
+	// Strict refuses to substitute a rate: the previous trading day's for a
+	// date without one, the day's for a timestamp without an intraday rate,
+	// or a stale rate while the provider is down. The conversion fails
+	// instead, for accounting that must use the rate of the exact date.
+	Strict bool `json:"strict,omitempty"`
+
+	// Optional fees charged on the converted amount, in To: a percentage of
+	// it plus a fixed amount. The response itemizes them in Fees.
+	FeePercent float64 `json:"fee_percent,omitempty"`
+	FeeFixed   float64 `json:"fee_fixed,omitempty"`
 }
 
+// Rounding modes of converted amounts and rates
+const (
+	RoundingHalfUp   = "half_up"  // Halves round away from zero
+	RoundingBankers  = "bankers"  // Halves round to the even neighbour
+	RoundingTruncate = "truncate" // Extra digits are dropped
+)
+
+// MaxFeePercent bounds ConversionRequest.FeePercent
+const MaxFeePercent = 100
+
+// RatePrecision is the decimals conversion rates are rounded to
+const RatePrecision = 6
+
 // ConversionResponse represents the response for currency conversion
 type ConversionResponse struct {
-	From            string    `json:"from"`
-	To              string    `json:"to"`
-	Amount          float64   `json:"amount"`
-	ConvertedAmount float64   `json:"converted_amount"`
-	Rate            float64   `json:"rate"`
-	Date            time.Time `json:"date"`
+	ConversionID    string               `json:"conversion_id,omitempty"` // Set once the conversion is recorded; GET /conversions/{id} returns it again
+	From            string               `json:"from"`
+	To              string               `json:"to"`
+	Amount          float64              `json:"amount"`
+	ConvertedAmount float64              `json:"converted_amount"`
+	Rate            float64              `json:"rate"`            // Applied rate, mid-market plus markup
+	MidMarketRate   float64              `json:"mid_market_rate"` // Rate before markup
+	Precision       int                  `json:"precision"`       // Decimals ConvertedAmount is rounded to
+	Rounding        string               `json:"rounding"`
+	UnroundedRate   float64              `json:"unrounded_rate"`   // Applied rate at full precision, which ConvertedAmount is computed with
+	UnroundedAmount float64              `json:"unrounded_amount"` // ConvertedAmount before rounding
+	MarkupPercent   float64              `json:"markup_percent"`
+	Derived         string               `json:"derived,omitempty"`
+	Date            time.Time            `json:"date"`
+	RateDate        string               `json:"rate_date,omitempty"`      // Market day the rate was published for
+	MarketClosed    bool                 `json:"market_closed,omitempty"`  // Requested date had no market rate
+	RateTimestamp   *time.Time           `json:"rate_timestamp,omitempty"` // When the rate used for a timestamp request was published
+	Formatted       *FormattedConversion `json:"formatted,omitempty"`
+	Fees            *ConversionFees      `json:"fees,omitempty"` // Set when the request has fees
+	Signature       *Signature           `json:"signature,omitempty"`
+	Freshness       `json:"-"`
+	Source
+}
+
+// ConversionFees itemizes the fees of a conversion. Amounts are in the
+// target currency, rounded like ConvertedAmount.
+type ConversionFees struct {
+	FeePercent      float64 `json:"fee_percent" xml:"fee_percent"`
+	FeeFixed        float64 `json:"fee_fixed" xml:"fee_fixed"`
+	MidMarketAmount float64 `json:"mid_market_amount" xml:"mid_market_amount"` // Amount at the mid-market rate, before markup and fees
+	FeeAmount       float64 `json:"fee_amount" xml:"fee_amount"`               // FeePercent of ConvertedAmount plus FeeFixed
+	NetAmount       float64 `json:"net_amount" xml:"net_amount"`               // ConvertedAmount less FeeAmount, what the recipient gets
+}
+
+// FormattedConversion holds a conversion's amounts formatted for display in
+// the requested locale, e.g. "₹8,350.00" or "1.234,56 €"
+type FormattedConversion struct {
+	Locale          string `json:"locale"`
+	Amount          string `json:"amount"`
+	ConvertedAmount string `json:"converted_amount"`
+	Rate            string `json:"rate"`
+}
+
+// LatestRateResponse represents the latest rate for a currency pair
+type LatestRateResponse struct {
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	Rate      float64    `json:"rate"`
+	Derived   string     `json:"derived,omitempty"`
+	Signature *Signature `json:"signature,omitempty"`
+	Freshness `json:"-"`
+	Source
+}
+
+// RateTableResponse holds the mid-market rate of every supported currency
+// against Base, or the full matrix of every pair when Base is empty. Pairs
+// that are not cached are listed in Missing as FROM_TO.
+type RateTableResponse struct {
+	Base      string                        `json:"base,omitempty"`
+	Rates     map[string]float64            `json:"rates,omitempty"`  // quote -> rate
+	Matrix    map[string]map[string]float64 `json:"matrix,omitempty"` // from -> to -> rate
+	Missing   []string                      `json:"missing,omitempty"`
+	Freshness `json:"-"`
+	Source
+}
+
+// RateDiffRequest asks how the rates against Base moved since a date. An
+// empty Until compares with the latest rates; empty Symbols means every
+// supported currency.
+type RateDiffRequest struct {
+	Base    string
+	Since   string // YYYY-MM-DD
+	Until   string // YYYY-MM-DD
+	Symbols []string
+}
+
+// RateDiffResponse lists the rates against Base that changed between Since
+// and Until, largest moves first. Pairs without a rate on either side are
+// listed in Missing as BASE_QUOTE.
+type RateDiffResponse struct {
+	Base      string     `json:"base"`
+	Since     string     `json:"since"`
+	Until     string     `json:"until,omitempty"` // Empty when compared with the latest rates
+	Changes   []RateDiff `json:"changes"`
+	Unchanged []string   `json:"unchanged,omitempty"` // Quote currencies whose rate is the same
+	Missing   []string   `json:"missing,omitempty"`
+	Freshness `json:"-"`
+	Source
+}
+
+// RateDiff is how one rate against the base moved
+type RateDiff struct {
+	Quote         string  `json:"quote"`
+	PreviousRate  float64 `json:"previous_rate"`
+	Rate          float64 `json:"rate"`
+	Change        float64 `json:"change"`                  // Rate minus PreviousRate
+	ChangePercent float64 `json:"change_percent"`          // Change relative to PreviousRate
+	ObservedDate  string  `json:"observed_date,omitempty"` // Trading day PreviousRate is from, when Since is not one
 }
 
 // HistoricalRateRequest represents a request for historical rates
@@ -37,26 +160,218 @@ type HistoricalRateRequest struct {
 	To        string `json:"to" binding:"required"`
 	StartDate string `json:"start_date" binding:"required"` // YYYY-MM-DD
 	EndDate   string `json:"end_date" binding:"required"`   // YYYY-MM-DD
+	Provider  string `json:"provider,omitempty"`            // Optional provider to pin the rates to
+
+	Granularity string `json:"granularity,omitempty"` // GranularityDaily by default
+	MaxWaitMs   int    `json:"max_wait_ms,omitempty"` // Return what resolved within this many milliseconds, 0 waits for every date
 }
 
 // HistoricalRateResponse represents historical rate data
 type HistoricalRateResponse struct {
-	From  string                    `json:"from"`
-	To    string                    `json:"to"`
-	Rates map[string]HistoricalRate `json:"rates"` // date -> rate
+	From      string                    `json:"from"`
+	To        string                    `json:"to"`
+	Rates     map[string]HistoricalRate `json:"rates"` // date -> rate
+	Freshness `json:"-"`
+	Source
+
+	MissingDates []MissingDate `json:"missing_dates"` // Dates of the range without a rate, oldest first
 }
 
-// HistoricalRate represents a rate for a specific date
+// Reasons a date of a historical range has no rate
+const (
+	MissingNoData        = "no_data"        // The provider published no rate for the day, e.g. a weekend
+	MissingProviderError = "provider_error" // Fetching the rate failed; retrying may succeed
+	MissingOutOfRange    = "out_of_range"   // The day is outside the history the provider serves
+	MissingPending       = "pending"        // The lookup did not finish within max_wait_ms; retrying may find it cached
+)
+
+// MissingDate is a date of a historical range without a rate
+type MissingDate struct {
+	Date   string `json:"date"`
+	Reason string `json:"reason"`
+	Error  string `json:"error,omitempty"` // Why the fetch failed, unless the provider has no rate
+}
+
+// HistoricalRate represents a rate for a specific date. On a weekend or
+// holiday it is the rate of the previous trading day, named by ObservedDate.
 type HistoricalRate struct {
-	Rate float64   `json:"rate"`
-	Date time.Time `json:"date"`
+	Rate         float64   `json:"rate"`
+	Date         time.Time `json:"date"`
+	ObservedDate string    `json:"observed_date,omitempty"`
+	Derived      string    `json:"derived,omitempty"`
+}
+
+// RefetchRequest asks for the historical rates of a pair on some dates to be
+// fetched again from the provider, replacing the cached and archived ones
+type RefetchRequest struct {
+	From  string   `json:"from" binding:"required"`
+	To    string   `json:"to" binding:"required"`
+	Dates []string `json:"dates" binding:"required"` // YYYY-MM-DD
+}
+
+// Outcomes of refetching the rate of a date
+const (
+	RefetchStatusRefetched = "refetched"
+	RefetchStatusFailed    = "failed" // The previous rate is kept
+)
+
+// RefetchResponse reports who refetched a pair's rates and the outcome on
+// each date
+type RefetchResponse struct {
+	From        string          `json:"from"`
+	To          string          `json:"to"`
+	RequestedBy string          `json:"requested_by"`
+	RequestedAt time.Time       `json:"requested_at"`
+	Refetched   int             `json:"refetched"`
+	Failed      int             `json:"failed"`
+	Results     []RefetchResult `json:"results"`
+}
+
+// RefetchResult is the outcome of refetching the rate of one date
+type RefetchResult struct {
+	Date         string   `json:"date"`
+	ObservedDate string   `json:"observed_date,omitempty"` // Trading day refetched for a weekend or holiday
+	Status       string   `json:"status"`
+	PreviousRate *float64 `json:"previous_rate"` // Rate held before, null when none was
+	Rate         float64  `json:"rate,omitempty"`
+	Archived     bool     `json:"archived"` // The end-of-day snapshot of the day was corrected too
+	Error        string   `json:"error,omitempty"`
+	ErrorCode    string   `json:"error_code,omitempty"`
+}
+
+// TrendRequest asks for the moving averages of a pair over a date range.
+// Empty dates default to the DefaultTrendDays days up to today.
+type TrendRequest struct {
+	From      string
+	To        string
+	Window    int // Number of rates each moving average spans
+	StartDate string
+	EndDate   string
+	Provider  string // Provider to pin the rates to, the default one when empty
+}
+
+// TrendResponse holds a pair's daily rates with moving averages and
+// day-over-day changes, oldest first
+type TrendResponse struct {
+	From      string       `json:"from"`
+	To        string       `json:"to"`
+	Window    int          `json:"window"`
+	StartDate string       `json:"start_date"`
+	EndDate   string       `json:"end_date"`
+	Points    []TrendPoint `json:"points"`
+	Missing   []string     `json:"missing,omitempty"` // Dates without a rate, skipped by the averages
+	Freshness `json:"-"`
+	Source
+}
+
+// TrendPoint is one day of a trend. Fields that need more history than is
+// available yet are null.
+type TrendPoint struct {
+	Date          string   `json:"date"`
+	Rate          float64  `json:"rate"`
+	Change        *float64 `json:"change"`         // Rate minus the previous point's rate
+	ChangePercent *float64 `json:"change_percent"` // Change relative to the previous point's rate
+	SMA           *float64 `json:"sma"`            // Simple moving average of the last Window rates
+	EMA           *float64 `json:"ema"`            // Exponential moving average, seeded with the first SMA
+}
+
+// IntradayRateResponse lists the rates of a pair published during a day,
+// oldest first
+type IntradayRateResponse struct {
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	Date    string         `json:"date"`
+	Derived string         `json:"derived,omitempty"`
+	Rates   []IntradayRate `json:"rates"`
 }
 
+// IntradayRate is a rate as published at a point in time
+type IntradayRate struct {
+	Timestamp time.Time `json:"timestamp"`
+	Rate      float64   `json:"rate"`
+}
+
+// Freshness records when the rate behind a response was fetched and until
+// when it stays valid. It drives HTTP caching headers and is not serialized.
+type Freshness struct {
+	FetchedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Merge folds another rate into f: a response built from several rates is as
+// fresh as its newest one and valid until the first of them expires
+func (f *Freshness) Merge(other Freshness) {
+	if other.FetchedAt.After(f.FetchedAt) {
+		f.FetchedAt = other.FetchedAt
+	}
+	if !other.ExpiresAt.IsZero() && (f.ExpiresAt.IsZero() || other.ExpiresAt.Before(f.ExpiresAt)) {
+		f.ExpiresAt = other.ExpiresAt
+	}
+}
+
+// Source names the provider behind a response's rate, when it published the
+// rate, when the service fetched it and whether it was served from cache,
+// derived or fetched live. All are omitted when unknown, e.g. for
+// same-currency pairs. Stale marks an expired rate served because the
+// provider could not be reached to refresh it.
+type Source struct {
+	Provider    string     `json:"provider,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	FetchedAt   *time.Time `json:"fetched_at,omitempty"`
+	Origin      string     `json:"origin,omitempty"`
+	Stale       bool       `json:"stale,omitempty"`
+}
+
+// Where the rate behind a response came from
+const (
+	OriginCache   = "cache"   // Cached, or kept in the snapshot archive or intraday history
+	OriginDerived = "derived" // Computed from other rates, as named by Derived
+	OriginLive    = "live"    // Fetched from the provider for this request
+	OriginMixed   = "mixed"   // Built from rates of different origins
+)
+
+// Merge folds another rate's source into s: a response built from several
+// rates reports the most recently published one, the oldest fetch so its
+// staleness is never understated, and OriginMixed when origins differ. It is
+// stale when any of the rates is.
+func (s *Source) Merge(other Source) {
+	s.Stale = s.Stale || other.Stale
+	if other.FetchedAt != nil && (s.FetchedAt == nil || other.FetchedAt.Before(*s.FetchedAt)) {
+		s.FetchedAt = other.FetchedAt
+	}
+	switch {
+	case s.Origin == "":
+		s.Origin = other.Origin
+	case other.Origin != "" && other.Origin != s.Origin:
+		s.Origin = OriginMixed
+	}
+
+	if other.PublishedAt == nil {
+		if s.Provider == "" {
+			s.Provider = other.Provider
+		}
+		return
+	}
+	if s.PublishedAt == nil || other.PublishedAt.After(*s.PublishedAt) {
+		s.Provider, s.PublishedAt = other.Provider, other.PublishedAt
+	}
+}
+
+// How a rate was computed when the pair itself was not quoted
+const (
+	DerivedInverse = "inverse" // 1/rate of the reverse pair
+	DerivedCross   = "cross"   // Ratio of both currencies' rates against a pivot currency
+)
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-	Code    int    `json:"code"`
+	Error     string       `json:"error"`
+	Message   string       `json:"message"`
+	Code      int          `json:"code"`                 // HTTP status
+	ErrorCode string       `json:"error_code,omitempty"` // Stable code such as CURRENCY_UNSUPPORTED
+	Details   []FieldError `json:"details,omitempty"`    // Fields that failed validation
+	// Entitlement the API key lacks, with ENTITLEMENT_REQUIRED
+	Entitlement string `json:"entitlement,omitempty"`
 }
 
 // ExternalAPIResponse represents the response from external exchange rate API
@@ -66,13 +381,161 @@ type ExternalAPIResponse struct {
 	Date            string             `json:"date"`
 	TimeLastUpdated int64              `json:"time_last_updated"`
 	Rates           map[string]float64 `json:"rates"`
+	RebasedFrom     string             `json:"-"` // Base the provider quoted the table against, when rebased to Base
+}
+
+// ProviderStatus summarises how an upstream provider has been behaving
+type ProviderStatus struct {
+	Provider            string     `json:"provider"`
+	CircuitState        string     `json:"circuit_state"` // "closed", "open" or "half_open"
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	CircuitRetryAt      *time.Time `json:"circuit_retry_at,omitempty"` // When an open circuit lets a trial request through
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	Requests            int64      `json:"requests_last_hour"`
+	Errors              int64      `json:"errors_last_hour"`
+	ErrorRate           float64    `json:"error_rate_last_hour"` // Errors / Requests, 0..1
+	AvgLatencyMs        float64    `json:"avg_latency_ms"`       // Over the last hour's requests
+}
+
+// RateDiscrepancy records providers quoting a pair further apart than the
+// configured threshold
+type RateDiscrepancy struct {
+	From             string             `json:"from"`
+	To               string             `json:"to"`
+	Quotes           map[string]float64 `json:"quotes"`         // provider -> rate
+	SpreadPercent    float64            `json:"spread_percent"` // (highest - lowest) / lowest * 100
+	ThresholdPercent float64            `json:"threshold_percent"`
+	DetectedAt       time.Time          `json:"detected_at"`
+}
+
+// DiscrepancyStats summarises the cross-provider rate comparisons
+type DiscrepancyStats struct {
+	Enabled          bool              `json:"enabled"`
+	Providers        []string          `json:"providers"`
+	ThresholdPercent float64           `json:"threshold_percent"`
+	Checks           int64             `json:"checks"`      // Comparison rounds run
+	Comparisons      int64             `json:"comparisons"` // Pairs quoted by at least two providers
+	Alerts           int64             `json:"alerts"`
+	LastCheck        *time.Time        `json:"last_check,omitempty"`
+	Recent           []RateDiscrepancy `json:"recent"` // Latest alerts, newest first
+}
+
+// ShadowComparison is a served rate compared with the shadow provider's
+// quote of the same pair
+type ShadowComparison struct {
+	From           string    `json:"from"`
+	To             string    `json:"to"`
+	ServedProvider string    `json:"served_provider,omitempty"`
+	ServedRate     float64   `json:"served_rate"`
+	ShadowProvider string    `json:"shadow_provider"`
+	ShadowRate     float64   `json:"shadow_rate"`
+	DiffPercent    float64   `json:"diff_percent"` // |shadow - served| / served * 100
+	ComparedAt     time.Time `json:"compared_at"`
+}
+
+// ShadowStats summarises the shadow traffic sent to a provider being
+// evaluated
+type ShadowStats struct {
+	Enabled          bool               `json:"enabled"`
+	Provider         string             `json:"provider,omitempty"`
+	Percent          float64            `json:"percent"` // Share of requests mirrored
+	ThresholdPercent float64            `json:"threshold_percent"`
+	Sampled          int64              `json:"sampled"`  // Requests picked for mirroring
+	Dropped          int64              `json:"dropped"`  // Sampled while every shadow lookup slot was busy
+	Compared         int64              `json:"compared"` // Shadow quotes compared with the served rate
+	Mismatches       int64              `json:"mismatches"`
+	Errors           int64              `json:"errors"` // Shadow lookups that failed or lacked the pair
+	MaxDiffPercent   float64            `json:"max_diff_percent"`
+	Recent           []ShadowComparison `json:"recent"` // Latest mismatches, newest first
+}
+
+// DefaultCurrencies are the currencies supported unless configured otherwise
+var DefaultCurrencies = []string{
+	"USD", // United States Dollar
+	"INR", // Indian Rupee
+	"EUR", // Euro
+	"JPY", // Japanese Yen
+	"GBP", // British Pound Sterling
+}
+
+var (
+	currenciesMu        sync.RWMutex
+	supportedCurrencies = currencySet(DefaultCurrencies)
+)
+
+// IsSupportedCurrency reports whether code is currently supported
+func IsSupportedCurrency(code string) bool {
+	currenciesMu.RLock()
+	defer currenciesMu.RUnlock()
+	return supportedCurrencies[code]
+}
+
+// SupportedCurrencyCodes returns the supported currency codes, sorted
+func SupportedCurrencyCodes() []string {
+	currenciesMu.RLock()
+	defer currenciesMu.RUnlock()
+
+	codes := make([]string, 0, len(supportedCurrencies))
+	for code := range supportedCurrencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// SetSupportedCurrencies replaces the set of supported currencies. It is safe
+// to call while requests are being served.
+func SetSupportedCurrencies(codes []string) {
+	set := currencySet(codes)
+
+	currenciesMu.Lock()
+	defer currenciesMu.Unlock()
+	supportedCurrencies = set
+}
+
+// AddSupportedCurrency adds code to the supported currencies and reports
+// whether it was not supported before
+func AddSupportedCurrency(code string) bool {
+	currenciesMu.Lock()
+	defer currenciesMu.Unlock()
+
+	if supportedCurrencies[code] {
+		return false
+	}
+	set := make(map[string]bool, len(supportedCurrencies)+1)
+	for existing := range supportedCurrencies {
+		set[existing] = true
+	}
+	set[code] = true
+	supportedCurrencies = set
+	return true
+}
+
+// RemoveSupportedCurrency removes code from the supported currencies and
+// reports whether it was supported
+func RemoveSupportedCurrency(code string) bool {
+	currenciesMu.Lock()
+	defer currenciesMu.Unlock()
+
+	if !supportedCurrencies[code] {
+		return false
+	}
+	set := make(map[string]bool, len(supportedCurrencies))
+	for existing := range supportedCurrencies {
+		if existing != code {
+			set[existing] = true
+		}
+	}
+	supportedCurrencies = set
+	return true
 }
 
-// SupportedCurrencies lists all supported currencies
-var SupportedCurrencies = map[string]bool{
-	"USD": true, // United States Dollar
-	"INR": true, // Indian Rupee
-	"EUR": true, // Euro
-	"JPY": true, // Japanese Yen
-	"GBP": true, // British Pound Sterling
+func currencySet(codes []string) map[string]bool {
+	set := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	return set
 }
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Statistical models a forecast can be made with
+const (
+	ForecastModelDrift = "drift" // Random walk with the average daily log return as drift
+	ForecastModelEWMA  = "ewma"  // Exponentially smoothed level with EWMA volatility, no drift
+)
+
+// ForecastDisclaimer labels every forecast as indicative
+const ForecastDisclaimer = "Indicative projection from past rates only; it is not a quote and rates can move outside the band"
+
+// ForecastRequest asks for a pair's projected rates over the next trading
+// days
+type ForecastRequest struct {
+	From     string
+	To       string
+	Horizon  int    // Trading days projected
+	History  int    // Days of history the model is fitted on
+	Model    string // drift or ewma, drift when empty
+	Provider string // Provider to pin the rates to, the default one when empty
+}
+
+// ForecastResponse projects a pair's rate over the horizon. Lower and Upper
+// bound the ConfidencePercent band of each projected rate, assuming daily log
+// returns are normally distributed; the band widens with the square root of
+// the days ahead.
+type ForecastResponse struct {
+	From              string          `json:"from"`
+	To                string          `json:"to"`
+	Model             string          `json:"model"`
+	Horizon           int             `json:"horizon"`
+	History           int             `json:"history"`
+	StartDate         string          `json:"start_date"` // First day of the history
+	EndDate           string          `json:"end_date"`   // Last day of the history
+	Samples           int             `json:"samples"`    // Trading days with a rate in the history
+	LastRate          float64         `json:"last_rate"`
+	LastDate          string          `json:"last_date"`
+	DriftPercent      float64         `json:"drift_percent"`      // Expected daily change
+	VolatilityPercent float64         `json:"volatility_percent"` // Standard deviation of the daily change
+	ConfidencePercent float64         `json:"confidence_percent"`
+	Points            []ForecastPoint `json:"points"`
+	Indicative        bool            `json:"indicative"` // Always true: a forecast is not a quote
+	Disclaimer        string          `json:"disclaimer"`
+	Date              time.Time       `json:"date"`
+	Freshness         `json:"-"`
+	Source
+}
+
+// ForecastPoint is the projected rate of one trading day
+type ForecastPoint struct {
+	Date  string  `json:"date"`
+	Rate  float64 `json:"rate"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
@@ -0,0 +1,48 @@
+package models
+
+// Granularities of historical rates
+const (
+	GranularityDaily   = "daily"   // One rate per day, the default
+	GranularityWeekly  = "weekly"  // One bucket per ISO week, Monday to Sunday
+	GranularityMonthly = "monthly" // One bucket per calendar month
+)
+
+// RateBucket aggregates the daily rates of a week or month within the
+// requested range. A day carrying an earlier trading day's rate counts only
+// when that trading day is not in the bucket itself.
+type RateBucket struct {
+	Start   string  `json:"start" xml:"start,attr"` // First day of the bucket with a rate, YYYY-MM-DD
+	End     string  `json:"end" xml:"end,attr"`     // Last day of the bucket with a rate
+	Open    float64 `json:"open" xml:"open"`        // Rate of the first day with one
+	High    float64 `json:"high" xml:"high"`
+	Low     float64 `json:"low" xml:"low"`
+	Close   float64 `json:"close" xml:"close"` // Rate of the last day with one
+	Average float64 `json:"average" xml:"average"`
+	Days    int     `json:"days" xml:"days"` // Trading days aggregated
+}
+
+// HistoricalBucketsResponse holds the historical rates of a pair aggregated
+// by week or month
+type HistoricalBucketsResponse struct {
+	From        string       `json:"from"`
+	To          string       `json:"to"`
+	Granularity string       `json:"granularity"`
+	Buckets     []RateBucket `json:"buckets"` // Oldest first; periods without any rate are left out
+	Freshness   `json:"-"`
+	Source
+
+	MissingDates []MissingDate `json:"missing_dates"`
+}
+
+// BulkHistoricalBucketsResponse holds the historical rates of every pair of
+// a bulk request aggregated by week or month
+type BulkHistoricalBucketsResponse struct {
+	StartDate   string                  `json:"start_date"`
+	EndDate     string                  `json:"end_date"`
+	Granularity string                  `json:"granularity"`
+	Buckets     map[string][]RateBucket `json:"buckets"` // Pair -> buckets, oldest first
+	Freshness   `json:"-"`
+	Source
+
+	MissingDates map[string][]MissingDate `json:"missing_dates"`
+}
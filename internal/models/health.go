@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Health statuses, ordered from best to worst
+const (
+	HealthStatusHealthy   = "healthy"
+	HealthStatusDegraded  = "degraded"
+	HealthStatusUnhealthy = "unhealthy"
+)
+
+// HealthCheck is the result of checking one dependency
+type HealthCheck struct {
+	Status    string     `json:"status"`
+	Message   string     `json:"message,omitempty"`
+	CheckedAt time.Time  `json:"checked_at"`
+	LastOK    *time.Time `json:"last_ok,omitempty"`
+}
+
+// WorstHealthStatus returns the more severe of two health statuses
+func WorstHealthStatus(a, b string) string {
+	severity := map[string]int{
+		HealthStatusHealthy:   0,
+		HealthStatusDegraded:  1,
+		HealthStatusUnhealthy: 2,
+	}
+	if severity[b] > severity[a] {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,27 @@
+package models
+
+// BulkHistoricalRequest asks for the historical rates of several pairs over
+// one date range, e.g. for a reporting pipeline
+type BulkHistoricalRequest struct {
+	Pairs     []string `json:"pairs" binding:"required"`      // Pairs as FROM_TO, e.g. USD_INR
+	StartDate string   `json:"start_date" binding:"required"` // YYYY-MM-DD
+	EndDate   string   `json:"end_date" binding:"required"`   // YYYY-MM-DD
+	Provider  string   `json:"provider,omitempty"`            // Optional provider to pin the rates to
+
+	Granularity string `json:"granularity,omitempty"` // GranularityDaily by default
+	MaxWaitMs   int    `json:"max_wait_ms,omitempty"` // Budget of the whole request, see HistoricalRateRequest
+}
+
+// BulkHistoricalResponse holds the historical rates of every pair of a bulk
+// request, keyed like the rates of a HistoricalRateResponse
+type BulkHistoricalResponse struct {
+	StartDate string                               `json:"start_date"`
+	EndDate   string                               `json:"end_date"`
+	Rates     map[string]map[string]HistoricalRate `json:"rates"` // Pair -> date -> rate
+	Freshness `json:"-"`
+	Source
+
+	// MissingDates lists, per pair, the dates of the range without a rate,
+	// oldest first. Pairs with a rate on every date are left out.
+	MissingDates map[string][]MissingDate `json:"missing_dates"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Statuses of a conversion job
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed" // Every row was converted or failed on its own
+	JobFailed    = "failed"    // The job stopped before every row was processed
+)
+
+// ConversionJob reports the progress of an asynchronous batch conversion.
+// Rows that fail are counted in Failed and detailed in the result file; they
+// don't fail the job.
+type ConversionJob struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Caller      string     `json:"caller"`
+	Total       int        `json:"total"`
+	Processed   int        `json:"processed"` // Rows converted or failed so far
+	Failed      int        `json:"failed"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Error       string     `json:"error,omitempty"`      // Why a failed job stopped
+	ResultURL   string     `json:"result_url,omitempty"` // Set once the result file can be downloaded
+}
@@ -0,0 +1,47 @@
+package models
+
+import "sync"
+
+// Defaults of RequestLimits
+const (
+	DefaultMaxBodyBytes           = 1 << 20
+	DefaultMaxBulkHistoricalPairs = 25
+	DefaultMaxPrecision           = 10
+)
+
+// RequestLimits bounds what a single request may ask for. Requests over a
+// limit are answered LIMIT_EXCEEDED, or PAYLOAD_TOO_LARGE for the body.
+type RequestLimits struct {
+	MaxBodyBytes int64 // JSON request bodies
+	MaxPairs     int   // Pairs of a bulk historical request
+	MaxPrecision int   // Decimals of ConversionRequest.Precision
+}
+
+// DefaultRequestLimits returns the limits used unless configured otherwise
+func DefaultRequestLimits() RequestLimits {
+	return RequestLimits{
+		MaxBodyBytes: DefaultMaxBodyBytes,
+		MaxPairs:     DefaultMaxBulkHistoricalPairs,
+		MaxPrecision: DefaultMaxPrecision,
+	}
+}
+
+var (
+	requestLimitsMu sync.RWMutex
+	requestLimits   = DefaultRequestLimits()
+)
+
+// SetRequestLimits replaces the request limits. It is safe to call while
+// requests are being served.
+func SetRequestLimits(limits RequestLimits) {
+	requestLimitsMu.Lock()
+	defer requestLimitsMu.Unlock()
+	requestLimits = limits
+}
+
+// Limits returns the request limits in effect
+func Limits() RequestLimits {
+	requestLimitsMu.RLock()
+	defer requestLimitsMu.RUnlock()
+	return requestLimits
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Statuses of a rate quote
+const (
+	QuoteActive   = "active"   // The rate is locked and the quote can be executed
+	QuoteExecuted = "executed" // The quote was executed; it can't be executed again
+	QuoteExpired  = "expired"  // The quote was not executed before ExpiresAt
+)
+
+// QuoteRequest asks for the latest rate of a conversion to be locked for
+// ValidMinutes, or the service's default when 0
+type QuoteRequest struct {
+	From         string  `json:"from" binding:"required"`
+	To           string  `json:"to" binding:"required"`
+	Amount       float64 `json:"amount" binding:"required"`
+	Provider     string  `json:"provider,omitempty"`
+	ValidMinutes int     `json:"valid_minutes,omitempty"`
+}
+
+// Quote is a conversion whose rate is honored until ExpiresAt, such as the
+// price shown at a checkout. Executing it converts at the locked rate once.
+type Quote struct {
+	ID         string              `json:"id"`
+	Status     string              `json:"status"`
+	Caller     string              `json:"caller"`
+	CreatedAt  time.Time           `json:"created_at"`
+	ExpiresAt  time.Time           `json:"expires_at"`
+	ExecutedAt *time.Time          `json:"executed_at,omitempty"`
+	Conversion *ConversionResponse `json:"conversion"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Layouts of historical rate files
+const (
+	RateImportECB   = "ecb"   // A Date column and a column per currency quoted against the base, as the ECB publishes
+	RateImportPairs = "pairs" // date, from, to and rate columns, one pair per row
+)
+
+// RateImportRequest describes a historical rate file to import into the
+// end-of-day archive
+type RateImportRequest struct {
+	Base      string // Currency the ECB layout quotes against, EUR when empty
+	Provider  string // Recorded as the provider of the imported rates, "ecb" or "import" when empty
+	Overwrite bool   // Replace days already archived instead of keeping them
+}
+
+// RateImportResponse reports which days of a historical rate file were
+// archived
+type RateImportResponse struct {
+	Format            string    `json:"format"`
+	Provider          string    `json:"provider"`
+	Rows              int       `json:"rows"`
+	ImportedDays      int       `json:"imported_days"`
+	SkippedDays       int       `json:"skipped_days"` // Already archived and kept, without overwrite
+	FirstDate         string    `json:"first_date,omitempty"`
+	LastDate          string    `json:"last_date,omitempty"`
+	IgnoredCurrencies []string  `json:"ignored_currencies,omitempty"` // In the file but not supported
+	ImportedBy        string    `json:"imported_by"`
+	ImportedAt        time.Time `json:"imported_at"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Whether now is a good time to convert, for someone selling From for To:
+// a higher rate buys more of To
+const (
+	SignalFavorable   = "favorable"   // The rate is in the top quarter of the longest window
+	SignalNeutral     = "neutral"     // The rate is in the middle half of it
+	SignalUnfavorable = "unfavorable" // The rate is in the bottom quarter of it
+)
+
+// Directions of a pair's rate over a window
+const (
+	TrendRising  = "rising"
+	TrendFalling = "falling"
+	TrendFlat    = "flat" // The change is within one day's typical move
+)
+
+// RecommendationRequest asks how a pair's current rate compares with its
+// recent history
+type RecommendationRequest struct {
+	From     string
+	To       string
+	Windows  []int  // Lengths of the compared windows in days, DefaultRecommendationWindows when empty
+	Provider string // Provider to pin the rates to, the default one when empty
+}
+
+// RecommendationResponse places a pair's current rate within the range of
+// each window and sums it up as guidance
+type RecommendationResponse struct {
+	From      string                 `json:"from"`
+	To        string                 `json:"to"`
+	Rate      float64                `json:"rate"` // Current mid-market rate
+	Windows   []RecommendationWindow `json:"windows"`
+	Signal    string                 `json:"signal"`   // Based on the longest window
+	Guidance  string                 `json:"guidance"` // E.g. "The rate is in the top 10% of the last 90 days"
+	Date      time.Time              `json:"date"`
+	Freshness `json:"-"`
+	Source
+}
+
+// RecommendationWindow summarizes the trading-day rates of one window ending
+// today. Percentile is the share of those days with a lower rate than the
+// current one.
+type RecommendationWindow struct {
+	Days              int     `json:"days"`
+	StartDate         string  `json:"start_date"`
+	EndDate           string  `json:"end_date"`
+	Samples           int     `json:"samples"` // Trading days with a rate
+	Low               float64 `json:"low"`
+	High              float64 `json:"high"`
+	Mean              float64 `json:"mean"`
+	Percentile        float64 `json:"percentile"`
+	ChangePercent     float64 `json:"change_percent"`     // Current rate against the window's first
+	VolatilityPercent float64 `json:"volatility_percent"` // Standard deviation of the day-over-day changes
+	Trend             string  `json:"trend"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// ReplicationPush is the JSON body an instance POSTs to its peers with the
+// rates a fetch cycle refreshed
+type ReplicationPush struct {
+	Origin string           `json:"origin"` // Instance ID of the sender
+	SentAt time.Time        `json:"sent_at"`
+	Rates  []ReplicatedRate `json:"rates"`
+}
+
+// ReplicatedRate is a latest rate fetched by a peer
+type ReplicatedRate struct {
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Rate        float64   `json:"rate"`
+	Provider    string    `json:"provider,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// ReplicationStats reports the rates an instance pushed to and received
+// from its peers
+type ReplicationStats struct {
+	Enabled        bool         `json:"enabled"`
+	InstanceID     string       `json:"instance_id"`
+	Standby        bool         `json:"standby"`             // Upstream fetching is left to LeaderID
+	LeaderID       string       `json:"leader_id,omitempty"` // Peer whose pushes keep this instance in standby
+	Received       int          `json:"received"`            // Pushes accepted
+	Rejected       int          `json:"rejected"`            // Pushes with a bad signature, timestamp or body
+	Applied        int          `json:"applied"`             // Rates cached from accepted pushes
+	Dropped        int          `json:"dropped"`             // Cycles not pushed because the queue was full
+	LastReceivedAt *time.Time   `json:"last_received_at,omitempty"`
+	Peers          []PeerStatus `json:"peers"`
+}
+
+// PeerStatus reports the pushes to one peer
+type PeerStatus struct {
+	URL        string     `json:"url"`
+	Pushed     int        `json:"pushed"` // Pushes the peer acknowledged
+	Failed     int        `json:"failed"`
+	LastError  string     `json:"last_error,omitempty"`
+	LastPushAt *time.Time `json:"last_push_at,omitempty"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// SignatureAlgorithm is the only algorithm responses are signed with
+const SignatureAlgorithm = "Ed25519"
+
+// Signature proves a quoted rate came from this service at SignedAt.
+// Payload is the canonical form of the signed fields: sorted, URL-encoded
+// key=value pairs such as "from=USD&key_id=...&rate=83.125&signed_at=...".
+// Value is the base64 Ed25519 signature of Payload, verifiable with the
+// public key of KeyID published at /api/v1/keys.
+type Signature struct {
+	KeyID     string    `json:"key_id" xml:"key_id,attr"`
+	Algorithm string    `json:"algorithm" xml:"algorithm,attr"`
+	SignedAt  time.Time `json:"signed_at" xml:"signed_at,attr"`
+	Payload   string    `json:"payload" xml:"payload"`
+	Value     string    `json:"value" xml:"value"`
+}
+
+// PublicKey is a key responses are signed with
+type PublicKey struct {
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"` // Base64 of the raw 32-byte key
+	PEM       string `json:"pem"`        // PKIX-encoded, as read by openssl
+}
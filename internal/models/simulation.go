@@ -0,0 +1,30 @@
+package models
+
+// SimulationRequest converts an amount at the actual rate and at a
+// hypothetical one, given either as a rate or as an offset of the actual
+// applied rate
+type SimulationRequest struct {
+	ConversionRequest
+	Rate              *float64 `json:"rate,omitempty"`                // Hypothetical applied rate
+	RateOffsetPercent *float64 `json:"rate_offset_percent,omitempty"` // Shift of the actual applied rate, e.g. 2 for +2%
+}
+
+// SimulatedConversion is a conversion at a hypothetical rate, rounded and
+// charged fees like the actual one
+type SimulatedConversion struct {
+	Rate              float64         `json:"rate"`
+	RateOffsetPercent float64         `json:"rate_offset_percent"` // Rate relative to the actual applied rate
+	ConvertedAmount   float64         `json:"converted_amount"`
+	UnroundedAmount   float64         `json:"unrounded_amount"`
+	Fees              *ConversionFees `json:"fees,omitempty"`
+}
+
+// SimulationResponse holds the actual conversion and the simulated one. The
+// difference compares the net amounts when there are fees, the converted
+// amounts otherwise.
+type SimulationResponse struct {
+	Actual            *ConversionResponse `json:"actual"`
+	Simulated         SimulatedConversion `json:"simulated"`
+	Difference        float64             `json:"difference"`         // Simulated minus actual, in To
+	DifferencePercent float64             `json:"difference_percent"` // Difference relative to the actual amount
+}
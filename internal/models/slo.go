@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// SLO objectives an endpoint is measured against
+const (
+	SLOObjectiveP50       = "p50"
+	SLOObjectiveP95       = "p95"
+	SLOObjectiveP99       = "p99"
+	SLOObjectiveErrorRate = "error_rate"
+)
+
+// SLO alert states
+const (
+	SLOStatusViolated = "violated"
+	SLOStatusResolved = "resolved"
+)
+
+// SLOViolation is an objective an endpoint misses, or no longer misses when
+// Status is SLOStatusResolved
+type SLOViolation struct {
+	Endpoint  string    `json:"endpoint"`  // Route, e.g. /api/v1/rates/latest
+	Objective string    `json:"objective"` // p50, p95, p99 or error_rate
+	Target    float64   `json:"target"`    // Seconds for latencies, percent for the error rate
+	Observed  float64   `json:"observed"`
+	Status    string    `json:"status"`
+	Since     time.Time `json:"since"` // When the violation was first detected
+	At        time.Time `json:"at"`
+}
+
+// EndpointSLO is an endpoint's latency and error rate over the SLO window,
+// with the targets it is held to
+type EndpointSLO struct {
+	Endpoint         string             `json:"endpoint"`
+	Requests         int64              `json:"requests"` // In the window
+	Errors           int64              `json:"errors"`   // 5xx responses in the window
+	ErrorRatePercent float64            `json:"error_rate_percent"`
+	P50Seconds       float64            `json:"p50_seconds"`
+	P95Seconds       float64            `json:"p95_seconds"`
+	P99Seconds       float64            `json:"p99_seconds"`
+	Targets          map[string]float64 `json:"targets,omitempty"`    // Objective -> target
+	Violations       []SLOViolation     `json:"violations,omitempty"` // Objectives missed at the last evaluation
+	ViolationsTotal  map[string]int64   `json:"violations_total,omitempty"`
+}
+
+// SLOReport is every endpoint's SLO state, sorted by endpoint
+type SLOReport struct {
+	WindowSeconds float64       `json:"window_seconds"`
+	Evaluations   int64         `json:"evaluations"`
+	Alerts        int64         `json:"alerts"` // Violations and resolutions raised
+	LastEvaluated *time.Time    `json:"last_evaluated,omitempty"`
+	Endpoints     []EndpointSLO `json:"endpoints"`
+}
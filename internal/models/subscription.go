@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// WebhookEventRatesChanged is the event of every rate push
+const WebhookEventRatesChanged = "rates.changed"
+
+// SubscriptionRequest registers a callback URL for rate changes of pairs
+// given as FROM_TO
+type SubscriptionRequest struct {
+	CallbackURL string   `json:"callback_url" binding:"required"`
+	Pairs       []string `json:"pairs"`
+}
+
+// Subscription is a callback URL the changed rates of its pairs are pushed
+// to after each fetch cycle. The secret signing the pushes is returned once,
+// when the subscription is created.
+type Subscription struct {
+	ID            string     `json:"id"`
+	Caller        string     `json:"caller"`
+	CallbackURL   string     `json:"callback_url"`
+	Pairs         []string   `json:"pairs"`
+	Secret        string     `json:"secret,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Delivered     int        `json:"delivered"`    // Pushes the callback acknowledged
+	DeadLetters   int        `json:"dead_letters"` // Pushes given up on after every retry
+	LastDelivered *time.Time `json:"last_delivered,omitempty"`
+}
+
+// RateChange is a rate that changed since it was last pushed to a subscriber
+type RateChange struct {
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Rate        float64   `json:"rate"`
+	Previous    *float64  `json:"previous,omitempty"` // Rate pushed before, absent on the first push
+	Provider    string    `json:"provider,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// WebhookPayload is the JSON body POSTed to a subscriber
+type WebhookPayload struct {
+	ID             string       `json:"id"` // Same on every attempt, so subscribers can drop duplicates
+	Event          string       `json:"event"`
+	SubscriptionID string       `json:"subscription_id"`
+	Timestamp      time.Time    `json:"timestamp"`
+	Rates          []RateChange `json:"rates"`
+}
+
+// DeadLetter is a push that failed on every attempt
+type DeadLetter struct {
+	Payload   WebhookPayload `json:"payload"`
+	Attempts  int            `json:"attempts"`
+	LastError string         `json:"last_error"`
+	FailedAt  time.Time      `json:"failed_at"`
+}
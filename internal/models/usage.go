@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// KeyUsage is what an API key has requested since the service started, for
+// billing and debugging partner integrations. Only authenticated requests
+// are counted.
+type KeyUsage struct {
+	KeyID            string          `json:"key_id"`
+	Requests         int64           `json:"requests"`
+	ClientErrors     int64           `json:"client_errors"` // 4xx responses
+	ServerErrors     int64           `json:"server_errors"` // 5xx responses
+	ErrorRatePercent float64         `json:"error_rate_percent"`
+	BytesIn          int64           `json:"bytes_in"`  // Request bodies
+	BytesOut         int64           `json:"bytes_out"` // Response bodies
+	FirstRequestAt   time.Time       `json:"first_request_at"`
+	LastRequestAt    time.Time       `json:"last_request_at"`
+	Endpoints        []EndpointUsage `json:"endpoints,omitempty"` // Most requested first
+	Pairs            []PairUsage     `json:"pairs,omitempty"`     // Most requested first
+	Days             []DailyUsage    `json:"days,omitempty"`      // Oldest first, within the retention
+}
+
+// EndpointUsage counts the requests of a key to one route
+type EndpointUsage struct {
+	Endpoint string `json:"endpoint"` // Route, e.g. /api/v1/jobs/:id
+	Requests int64  `json:"requests"`
+	Errors   int64  `json:"errors"` // 4xx and 5xx responses
+}
+
+// PairUsage counts the requests of a key naming one currency pair
+type PairUsage struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Requests int64  `json:"requests"`
+}
+
+// DailyUsage counts the requests of a key on one day of the reference time
+// zone
+type DailyUsage struct {
+	Date     string `json:"date"`
+	Requests int64  `json:"requests"`
+	Errors   int64  `json:"errors"`
+	BytesOut int64  `json:"bytes_out"`
+}
+
+// UsageReport is the usage of every key that made a request, by key ID
+type UsageReport struct {
+	Since time.Time  `json:"since"` // When counting started
+	Keys  []KeyUsage `json:"keys"`
+}
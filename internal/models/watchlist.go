@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// WatchlistRequest creates or replaces a named watchlist of pairs given as
+// FROM_TO
+type WatchlistRequest struct {
+	Name  string   `json:"name" binding:"required"`
+	Pairs []string `json:"pairs"`
+}
+
+// Watchlist is a named list of pairs whose latest rates a dashboard shows
+// together
+type Watchlist struct {
+	ID        string    `json:"id"`
+	Caller    string    `json:"caller"`
+	Name      string    `json:"name"`
+	Pairs     []string  `json:"pairs"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WatchlistRatesResponse holds the latest rate of every pair of a watchlist,
+// in the watchlist's order
+type WatchlistRatesResponse struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Rates []WatchlistRate `json:"rates"`
+}
+
+// WatchlistRate is the latest rate of one pair of a watchlist. A pair whose
+// rate could not be looked up carries the error instead, so one failing
+// pair doesn't blank the whole dashboard.
+type WatchlistRate struct {
+	Pair string `json:"pair"`
+	*LatestRateResponse
+	ErrorCode string `json:"error_code,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
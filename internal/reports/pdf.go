@@ -0,0 +1,201 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Layout of PDF reports, in points on an A4 page
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 50
+	pdfLineHeight = 14
+	pdfFontSize   = 9
+	pdfTitleSize  = 16
+	pdfCharWidth  = 5 // Room per character of a column's width
+)
+
+// WritePDF writes the report as a PDF document using the standard Helvetica
+// fonts. Rows flow over as many pages as needed, each repeating the header,
+// and numbers are right-aligned in their column.
+func (r *Report) WritePDF(w io.Writer) error {
+	layout := &pdfLayout{report: r}
+	layout.newPage()
+	layout.text("F2", pdfTitleSize, pdfMargin, r.Title)
+	layout.y -= 6
+	for _, line := range r.Subtitle {
+		layout.advance()
+		layout.text("F1", pdfFontSize+1, pdfMargin, line)
+	}
+	layout.advance()
+	layout.header()
+
+	for _, row := range r.Rows {
+		layout.row(row, "F1")
+	}
+	if len(r.Summary) > 0 {
+		layout.advance()
+		layout.rule()
+		for _, row := range r.Summary {
+			layout.row(row, "F2")
+		}
+	}
+	if len(r.Notes) > 0 {
+		layout.advance()
+		for _, line := range r.Notes {
+			layout.ensureRoom()
+			layout.advance()
+			layout.text("F1", pdfFontSize-1, pdfMargin, line)
+		}
+	}
+	return layout.write(w)
+}
+
+// pdfLayout places text top-down on pages; y is the baseline of the last line
+type pdfLayout struct {
+	report *Report
+	pages  []*bytes.Buffer
+	page   *bytes.Buffer
+	y      float64
+}
+
+func (l *pdfLayout) newPage() {
+	l.page = &bytes.Buffer{}
+	l.pages = append(l.pages, l.page)
+	l.y = pdfPageHeight - pdfMargin
+}
+
+func (l *pdfLayout) advance() {
+	l.y -= pdfLineHeight
+}
+
+// ensureRoom starts a new page, with the table header, when the next line
+// would run into the footer
+func (l *pdfLayout) ensureRoom() {
+	if l.y-pdfLineHeight < pdfMargin+pdfLineHeight {
+		l.newPage()
+		l.header()
+	}
+}
+
+func (l *pdfLayout) text(font string, size, x float64, text string) {
+	fmt.Fprintf(l.page, "BT /%s %g Tf %g %g Td (%s) Tj ET\n", font, size, x, l.y, pdfEscape(text))
+}
+
+func (l *pdfLayout) rule() {
+	fmt.Fprintf(l.page, "0.5 w %d %g m %d %g l S\n", pdfMargin, l.y-4, pdfPageWidth-pdfMargin, l.y-4)
+}
+
+func (l *pdfLayout) header() {
+	l.advance()
+	x := float64(pdfMargin)
+	for _, column := range l.report.Columns {
+		l.text("F2", pdfFontSize, x, column.Name)
+		x += float64(column.Width * pdfCharWidth)
+	}
+	l.rule()
+}
+
+func (l *pdfLayout) row(cells []Cell, font string) {
+	l.ensureRoom()
+	l.advance()
+	x := float64(pdfMargin)
+	for i, column := range l.report.Columns {
+		width := float64(column.Width * pdfCharWidth)
+		if i < len(cells) {
+			text := cells[i].format(column)
+			if cells[i].IsNumber {
+				// Right-aligned, leaving a gap before the next column
+				l.text(font, pdfFontSize, x+width-8-pdfTextWidth(text, pdfFontSize), text)
+			} else if text != "" {
+				l.text(font, pdfFontSize, x, text)
+			}
+		}
+		x += width
+	}
+}
+
+// write numbers the pages and writes the document: the catalog, the page
+// tree, the two fonts, then a page object and content stream per page
+func (l *pdfLayout) write(w io.Writer) error {
+	for i, page := range l.pages {
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(l.pages))
+		fmt.Fprintf(page, "BT /F1 8 Tf %g %d Td (%s) Tj ET\n", pdfPageWidth-pdfMargin-pdfTextWidth(footer, 8), pdfMargin/2, footer)
+	}
+
+	const firstPage = 5 // Object number of the first page
+	kids := make([]string, len(l.pages))
+	for i := range l.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(l.pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	}
+	for i, page := range l.pages {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, firstPage+2*i+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pdfEscape escapes text for a PDF string literal. Characters outside ASCII
+// are replaced, since the fonts are used with their standard encoding.
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// pdfTextWidth returns the width of text in Helvetica at size. Digits, signs
+// and separators have exact widths; other characters are estimated.
+func pdfTextWidth(text string, size float64) float64 {
+	units := 0
+	for _, r := range text {
+		switch {
+		case r >= '0' && r <= '9':
+			units += 556
+		case r == '.' || r == ',' || r == ' ':
+			units += 278
+		case r == '-':
+			units += 333
+		default:
+			units += 556
+		}
+	}
+	return float64(units) * size / 1000
+}
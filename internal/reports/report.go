@@ -0,0 +1,222 @@
+// Package reports renders tabular reports, such as a pair's historical rates
+// with a summary, as CSV, XLSX or PDF files for download.
+package reports
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"exchange-rate-service/internal/models"
+)
+
+// Formats a report can be rendered in
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	FormatPDF  = "pdf"
+)
+
+// ContentTypes maps each format to its media type
+var ContentTypes = map[string]string{
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatPDF:  "application/pdf",
+}
+
+// Kinds of column values, which decide how numbers are formatted
+const (
+	KindText    = "text"
+	KindRate    = "rate"    // Six decimals
+	KindPercent = "percent" // Two decimals
+)
+
+// Column is a column of a report
+type Column struct {
+	Name  string
+	Kind  string
+	Width int // In characters
+}
+
+// Cell is a value of a report, either text or a number
+type Cell struct {
+	Text     string
+	Number   float64
+	IsNumber bool
+}
+
+// Text returns a text cell
+func Text(text string) Cell {
+	return Cell{Text: text}
+}
+
+// Number returns a numeric cell
+func Number(value float64) Cell {
+	return Cell{Number: value, IsNumber: true}
+}
+
+// Report is a titled table followed by summary rows
+type Report struct {
+	Title    string
+	Subtitle []string // Lines describing the report, printed under the title
+	Columns  []Column
+	Rows     [][]Cell
+	Summary  [][]Cell
+	Notes    []string // Lines printed after the summary
+}
+
+// Write renders the report in format
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatCSV:
+		return r.WriteCSV(w)
+	case FormatXLSX:
+		return r.WriteXLSX(w)
+	case FormatPDF:
+		return r.WritePDF(w)
+	}
+	return fmt.Errorf("unknown report format %q", format)
+}
+
+// WriteCSV writes the header, the rows, a blank line and the summary rows.
+// Numbers keep their full precision.
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	header := make([]string, len(r.Columns))
+	for i, column := range r.Columns {
+		header[i] = column.Name
+	}
+	records := [][]string{header}
+	for _, row := range r.Rows {
+		records = append(records, r.csvRecord(row))
+	}
+	if len(r.Summary) > 0 {
+		records = append(records, make([]string, len(r.Columns)))
+		for _, row := range r.Summary {
+			records = append(records, r.csvRecord(row))
+		}
+	}
+	return writer.WriteAll(records)
+}
+
+func (r *Report) csvRecord(row []Cell) []string {
+	record := make([]string, len(r.Columns))
+	for i, cell := range row {
+		if i >= len(record) {
+			break
+		}
+		if cell.IsNumber {
+			record[i] = strconv.FormatFloat(cell.Number, 'f', -1, 64)
+		} else {
+			record[i] = cell.Text
+		}
+	}
+	return record
+}
+
+// format returns the display form of a cell of column
+func (c Cell) format(column Column) string {
+	if !c.IsNumber {
+		return c.Text
+	}
+	switch column.Kind {
+	case KindRate:
+		return strconv.FormatFloat(c.Number, 'f', 6, 64)
+	case KindPercent:
+		return strconv.FormatFloat(c.Number, 'f', 2, 64)
+	}
+	return strconv.FormatFloat(c.Number, 'f', -1, 64)
+}
+
+// NewHistoricalReport lists a pair's rate on every date of a historical
+// range with its change from the previous trading day, and sums the range up
+// with its lowest, highest and average rate. Weekends and holidays carrying
+// the previous trading day's rate are listed but left out of the summary and
+// the changes, so they don't weigh on the average.
+func NewHistoricalReport(resp *models.HistoricalRateResponse, startDate, endDate string, generatedAt time.Time) *Report {
+	report := &Report{
+		Title: fmt.Sprintf("%s/%s historical exchange rates", resp.From, resp.To),
+		Subtitle: []string{
+			fmt.Sprintf("%s to %s", startDate, endDate),
+		},
+		Columns: []Column{
+			{Name: "Date", Kind: KindText, Width: 12},
+			{Name: fmt.Sprintf("Rate (%s per %s)", resp.To, resp.From), Kind: KindRate, Width: 20},
+			{Name: "Change %", Kind: KindPercent, Width: 10},
+			{Name: "Note", Kind: KindText, Width: 36},
+		},
+	}
+	if resp.Provider != "" {
+		report.Subtitle = append(report.Subtitle, "Source: "+resp.Provider)
+	}
+	report.Subtitle = append(report.Subtitle, "Generated "+generatedAt.UTC().Format(time.RFC3339))
+
+	missing := make(map[string]string, len(resp.MissingDates))
+	dates := make([]string, 0, len(resp.Rates)+len(resp.MissingDates))
+	for date := range resp.Rates {
+		dates = append(dates, date)
+	}
+	for _, date := range resp.MissingDates {
+		missing[date.Date] = date.Reason
+		if _, found := resp.Rates[date.Date]; !found {
+			dates = append(dates, date.Date)
+		}
+	}
+	sort.Strings(dates)
+
+	var samples int
+	var sum, previous float64
+	var low, high struct {
+		rate float64
+		date string
+	}
+	for _, date := range dates {
+		rate, found := resp.Rates[date]
+		if !found {
+			report.Rows = append(report.Rows, []Cell{Text(date), {}, {}, Text("No rate: " + missing[date])})
+			continue
+		}
+
+		note := ""
+		carried := rate.ObservedDate != "" && rate.ObservedDate != date
+		switch {
+		case carried:
+			note = "Rate of " + rate.ObservedDate
+		case rate.Derived != "":
+			note = "Derived: " + rate.Derived
+		}
+		row := []Cell{Text(date), Number(rate.Rate), {}, Text(note)}
+		if carried {
+			report.Rows = append(report.Rows, row)
+			continue
+		}
+
+		if samples > 0 && previous != 0 {
+			row[2] = Number((rate.Rate - previous) / previous * 100)
+		}
+		report.Rows = append(report.Rows, row)
+
+		if samples == 0 || rate.Rate < low.rate {
+			low.rate, low.date = rate.Rate, date
+		}
+		if samples == 0 || rate.Rate > high.rate {
+			high.rate, high.date = rate.Rate, date
+		}
+		samples++
+		sum += rate.Rate
+		previous = rate.Rate
+	}
+
+	if samples > 0 {
+		report.Summary = [][]Cell{
+			{Text("Minimum"), Number(low.rate), {}, Text("on " + low.date)},
+			{Text("Maximum"), Number(high.rate), {}, Text("on " + high.date)},
+			{Text("Average"), Number(sum / float64(samples)), {}, Text(fmt.Sprintf("over %d trading days", samples))},
+		}
+	}
+	report.Notes = []string{"Weekends and holidays show the previous trading day's rate and are left out of the summary."}
+	return report
+}
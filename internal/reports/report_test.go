@@ -0,0 +1,131 @@
+package reports
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/models"
+)
+
+func historicalResponse() *models.HistoricalRateResponse {
+	return &models.HistoricalRateResponse{
+		From: "USD",
+		To:   "INR",
+		Rates: map[string]models.HistoricalRate{
+			"2025-01-02": {Rate: 80},
+			"2025-01-03": {Rate: 84},
+			"2025-01-04": {Rate: 84, ObservedDate: "2025-01-03"},
+			"2025-01-06": {Rate: 82, Derived: models.DerivedInverse},
+		},
+		MissingDates: []models.MissingDate{{Date: "2025-01-05", Reason: models.MissingProviderError}},
+		Source:       models.Source{Provider: "frankfurter"},
+	}
+}
+
+func TestNewHistoricalReport(t *testing.T) {
+	report := NewHistoricalReport(historicalResponse(), "2025-01-02", "2025-01-06", time.Date(2025, 1, 7, 9, 0, 0, 0, time.UTC))
+
+	assert.Equal(t, "USD/INR historical exchange rates", report.Title)
+	assert.Contains(t, report.Subtitle, "Source: frankfurter")
+	require.Len(t, report.Rows, 5)
+
+	assert.Equal(t, []Cell{Text("2025-01-02"), Number(80), {}, Text("")}, report.Rows[0])
+	assert.Equal(t, Number(5), report.Rows[1][2], "change from the previous trading day")
+	assert.Equal(t, Text("Rate of 2025-01-03"), report.Rows[2][3])
+	assert.Equal(t, Cell{}, report.Rows[2][2], "carried rates have no change")
+	assert.Equal(t, Text("No rate: provider_error"), report.Rows[3][3])
+	assert.InDelta(t, -2.380952, report.Rows[4][2].Number, 1e-6)
+
+	require.Len(t, report.Summary, 3)
+	assert.Equal(t, []Cell{Text("Minimum"), Number(80), {}, Text("on 2025-01-02")}, report.Summary[0])
+	assert.Equal(t, []Cell{Text("Maximum"), Number(84), {}, Text("on 2025-01-03")}, report.Summary[1])
+	assert.Equal(t, []Cell{Text("Average"), Number(82), {}, Text("over 3 trading days")}, report.Summary[2], "the carried weekend rate is left out")
+}
+
+func TestReport_WriteCSV(t *testing.T) {
+	report := NewHistoricalReport(historicalResponse(), "2025-01-02", "2025-01-06", time.Now())
+
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf, FormatCSV))
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Date", "Rate (INR per USD)", "Change %", "Note"}, records[0])
+	assert.Equal(t, []string{"2025-01-03", "84", "5", ""}, records[2])
+	assert.Equal(t, []string{"", "", "", ""}, records[6])
+	assert.Equal(t, []string{"Average", "82", "", "over 3 trading days"}, records[9])
+}
+
+func TestReport_WriteXLSX(t *testing.T) {
+	report := NewHistoricalReport(historicalResponse(), "2025-01-02", "2025-01-06", time.Now())
+
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf, FormatXLSX))
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	parts := make(map[string]string)
+	for _, file := range archive.File {
+		opened, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(opened)
+		require.NoError(t, err)
+		parts[file.Name] = string(content)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		assert.Contains(t, parts, name)
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<c r="A1" s="5" t="inlineStr"><is><t>USD/INR historical exchange rates</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B7" s="2"><v>80</v></c>`, "rates are numbers with the rate format")
+	assert.Contains(t, sheet, `<c r="B15" s="4"><v>82</v></c>`, "the summary is bold")
+}
+
+func TestReport_WritePDF(t *testing.T) {
+	resp := historicalResponse()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 120; i++ {
+		date := start.AddDate(0, 0, i).Format("2006-01-02")
+		resp.Rates[date] = models.HistoricalRate{Rate: 80 + float64(i)/100}
+	}
+	report := NewHistoricalReport(resp, "2025-01-01", "2025-04-30", time.Now())
+
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf, FormatPDF))
+	pdf := buf.String()
+
+	assert.True(t, strings.HasPrefix(pdf, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(pdf, "%%EOF\n"))
+	assert.Contains(t, pdf, "(USD/INR historical exchange rates) Tj")
+	assert.Contains(t, pdf, "(Page 3 of 3) Tj", "rows flow over several pages")
+	assert.Contains(t, pdf, "/Count 3")
+
+	// Every object starts at the offset its xref entry gives
+	xref := pdf[strings.LastIndex(pdf, "\nxref\n")+1:]
+	for i, line := range strings.Split(xref, "\n")[3:9] {
+		var offset int
+		_, err := fmt.Sscanf(line, "%010d", &offset)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(pdf[offset:], fmt.Sprintf("%d 0 obj", i+1)), "object %d", i+1)
+	}
+}
+
+func TestPDFEscape(t *testing.T) {
+	assert.Equal(t, `Rate \(USD\) \\ ?`, pdfEscape("Rate (USD) \\ €"))
+}
+
+func TestXLSXColumn(t *testing.T) {
+	assert.Equal(t, "A", xlsxColumn(0))
+	assert.Equal(t, "Z", xlsxColumn(25))
+	assert.Equal(t, "AA", xlsxColumn(26))
+	assert.Equal(t, "AB", xlsxColumn(27))
+}
@@ -0,0 +1,180 @@
+package reports
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Cell styles of styles.xml, by index
+const (
+	xlsxStyleDefault = iota
+	xlsxStyleBold
+	xlsxStyleRate
+	xlsxStylePercent
+	xlsxStyleBoldRate
+	xlsxStyleTitle
+)
+
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="2"><numFmt numFmtId="164" formatCode="0.000000"/><numFmt numFmtId="165" formatCode="0.00"/></numFmts>
+<fonts count="3"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="14"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="6">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="164" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1" applyNumberFormat="1"/>
+<xf numFmtId="0" fontId="2" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+</cellXfs>
+</styleSheet>`
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Report" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+// WriteXLSX writes the report as a single-sheet Excel workbook: the title
+// and subtitle, a bold header, the rows with rates and changes as numbers,
+// and the summary in bold
+func (r *Report) WriteXLSX(w io.Writer) error {
+	archive := zip.NewWriter(w)
+	parts := []struct {
+		name    string
+		content []byte
+	}{
+		{"[Content_Types].xml", []byte(xlsxContentTypes)},
+		{"_rels/.rels", []byte(xlsxRootRels)},
+		{"xl/workbook.xml", []byte(xlsxWorkbook)},
+		{"xl/_rels/workbook.xml.rels", []byte(xlsxWorkbookRels)},
+		{"xl/styles.xml", []byte(xlsxStyles)},
+		{"xl/worksheets/sheet1.xml", r.xlsxSheet()},
+	}
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(part.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func (r *Report) xlsxSheet() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	buf.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	buf.WriteString("<cols>")
+	for i, column := range r.Columns {
+		fmt.Fprintf(&buf, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, column.Width+2)
+	}
+	buf.WriteString("</cols><sheetData>")
+
+	row := 0
+	writeRow := func(cells []Cell, style func(i int, cell Cell) int) {
+		row++
+		fmt.Fprintf(&buf, `<row r="%d">`, row)
+		for i, cell := range cells {
+			ref := xlsxColumn(i) + strconv.Itoa(row)
+			switch {
+			case cell.IsNumber:
+				fmt.Fprintf(&buf, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style(i, cell), strconv.FormatFloat(cell.Number, 'f', -1, 64))
+			case cell.Text != "":
+				fmt.Fprintf(&buf, `<c r="%s" s="%d" t="inlineStr"><is><t>`, ref, style(i, cell))
+				xml.EscapeText(&buf, []byte(cell.Text))
+				buf.WriteString("</t></is></c>")
+			}
+		}
+		buf.WriteString("</row>")
+	}
+	fixed := func(style int) func(int, Cell) int {
+		return func(int, Cell) int { return style }
+	}
+	numeric := func(bold bool) func(int, Cell) int {
+		return func(i int, cell Cell) int {
+			if !cell.IsNumber {
+				if bold {
+					return xlsxStyleBold
+				}
+				return xlsxStyleDefault
+			}
+			switch {
+			case r.Columns[i].Kind == KindPercent:
+				return xlsxStylePercent
+			case bold:
+				return xlsxStyleBoldRate
+			default:
+				return xlsxStyleRate
+			}
+		}
+	}
+
+	writeRow([]Cell{Text(r.Title)}, fixed(xlsxStyleTitle))
+	for _, line := range r.Subtitle {
+		writeRow([]Cell{Text(line)}, fixed(xlsxStyleDefault))
+	}
+	row++ // Blank line before the table
+
+	header := make([]Cell, len(r.Columns))
+	for i, column := range r.Columns {
+		header[i] = Text(column.Name)
+	}
+	writeRow(header, fixed(xlsxStyleBold))
+	for _, cells := range r.Rows {
+		writeRow(cells, numeric(false))
+	}
+	if len(r.Summary) > 0 {
+		row++
+		for _, cells := range r.Summary {
+			writeRow(cells, numeric(true))
+		}
+	}
+	if len(r.Notes) > 0 {
+		row++
+		for _, line := range r.Notes {
+			writeRow([]Cell{Text(line)}, fixed(xlsxStyleDefault))
+		}
+	}
+
+	buf.WriteString("</sheetData></worksheet>")
+	return buf.Bytes()
+}
+
+// xlsxColumn returns the letters of the zero-based column index, e.g. "A"
+// or "AB"
+func xlsxColumn(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Kinds of alert channels
+const (
+	AlertChannelWebhook = "webhook" // POSTs the alert as JSON to the target URL
+	AlertChannelSlack   = "slack"   // Posts the message to the target Slack incoming webhook URL
+	AlertChannelEmail   = "email"   // Mails the message to the target address over SMTP
+	AlertChannelSMS     = "sms"     // Texts the message to the target phone number through Twilio
+)
+
+// defaultTwilioBaseURL is where Twilio's messaging API is served
+const defaultTwilioBaseURL = "https://api.twilio.com"
+
+// AlertChannel is where an alert is sent, e.g. an email address for
+// AlertChannelEmail
+type AlertChannel struct {
+	Kind   string
+	Target string
+}
+
+func (ch AlertChannel) String() string {
+	return ch.Kind + ":" + ch.Target
+}
+
+// SMTPConfig is the mail server email alerts are sent through
+type SMTPConfig struct {
+	Addr     string // host:port
+	Username string // Optional; authenticates with PLAIN, which needs TLS unless the server is local
+	Password string
+	From     string
+}
+
+// TwilioConfig is the Twilio account SMS alerts are sent from
+type TwilioConfig struct {
+	AccountSID string
+	AuthToken  string
+	From       string // Sending phone number, e.g. +15550100
+	BaseURL    string // Empty uses Twilio's API
+}
+
+// AlertConfig sets the channels an alert is sent through and the message
+// sent. The webhook channel gets the alert itself as JSON; the others get
+// the message, rendered with an AlertMessage.
+type AlertConfig struct {
+	Channels []AlertChannel
+	Template string // text/template of the message, empty for the alert's default
+	SMTP     SMTPConfig
+	Twilio   TwilioConfig
+}
+
+// AlertMessage is what alert templates are executed with
+type AlertMessage struct {
+	Subject   string    // Summary of the alert, the subject of emails
+	Pair      string    // FROM/TO, empty for alerts not about a pair
+	Threshold float64   // Limit the alert was raised on
+	Observed  float64   // Value that crossed the threshold
+	Rates     string    // Current rates, as "provider=rate" for a discrepancy
+	Endpoint  string    // Route, for SLO alerts
+	Objective string    // p50, p95, p99 or error_rate, for SLO alerts
+	Status    string    // violated or resolved, for SLO alerts
+	At        time.Time // When the alert was raised
+}
+
+// ValidateAlertConfig checks the channels and template of cfg, and that the
+// channels needing SMTP or Twilio have them set up
+func ValidateAlertConfig(cfg AlertConfig) error {
+	if _, err := template.New("alert").Parse(cfg.Template); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	for _, ch := range cfg.Channels {
+		switch ch.Kind {
+		case AlertChannelWebhook, AlertChannelSlack:
+			parsed, err := url.Parse(ch.Target)
+			if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+				return fmt.Errorf("%s channel needs an absolute http or https URL, got %q", ch.Kind, ch.Target)
+			}
+		case AlertChannelEmail:
+			if !strings.Contains(ch.Target, "@") {
+				return fmt.Errorf("email channel needs an address, got %q", ch.Target)
+			}
+			if cfg.SMTP.Addr == "" || cfg.SMTP.From == "" {
+				return fmt.Errorf("email channel needs an SMTP server and sender")
+			}
+		case AlertChannelSMS:
+			if !strings.HasPrefix(ch.Target, "+") {
+				return fmt.Errorf("sms channel needs a phone number in E.164 form, got %q", ch.Target)
+			}
+			if cfg.Twilio.AccountSID == "" || cfg.Twilio.AuthToken == "" || cfg.Twilio.From == "" {
+				return fmt.Errorf("sms channel needs a Twilio account, token and sender")
+			}
+		default:
+			return fmt.Errorf("unknown channel %q, expected webhook, slack, email or sms", ch.Kind)
+		}
+	}
+	return nil
+}
+
+// alerter sends the alerts of one monitor through its channels, each with
+// its own timeout. Failures are logged; they never stop the monitor.
+type alerter struct {
+	name       string // Alert name used in logs, e.g. "discrepancy"
+	cfg        AlertConfig
+	template   *template.Template
+	httpClient *http.Client
+	timeout    time.Duration
+}
+
+// newAlerter sends alerts through the channels of cfg, rendering messages
+// with its template or fallback. An invalid template, which
+// ValidateAlertConfig rejects, falls back too.
+func newAlerter(name string, cfg AlertConfig, fallback string) *alerter {
+	source := cfg.Template
+	if source == "" {
+		source = fallback
+	}
+	tmpl, err := template.New(name).Parse(source)
+	if err != nil {
+		log.Printf("Invalid %s alert template, using the default: %v", name, err)
+		tmpl = template.Must(template.New(name).Parse(fallback))
+	}
+	return &alerter{
+		name:       name,
+		cfg:        cfg,
+		template:   tmpl,
+		httpClient: &http.Client{Timeout: webhookTimeout},
+		timeout:    webhookTimeout,
+	}
+}
+
+// enabled reports whether there is any channel to send alerts to
+func (a *alerter) enabled() bool {
+	return len(a.cfg.Channels) > 0
+}
+
+// send renders msg and sends it, or payload on webhook channels, through
+// every channel
+func (a *alerter) send(ctx context.Context, msg AlertMessage, payload interface{}) {
+	var text bytes.Buffer
+	if err := a.template.Execute(&text, msg); err != nil {
+		log.Printf("Failed to render %s alert: %v", a.name, err)
+		text.Reset()
+		text.WriteString(msg.Subject)
+	}
+
+	for _, ch := range a.cfg.Channels {
+		ctx, cancel := context.WithTimeout(ctx, a.timeout)
+		var err error
+		switch ch.Kind {
+		case AlertChannelWebhook:
+			err = a.postJSON(ctx, ch.Target, payload)
+		case AlertChannelSlack:
+			err = a.postJSON(ctx, ch.Target, map[string]string{"text": text.String()})
+		case AlertChannelEmail:
+			err = a.mail(ctx, ch.Target, msg.Subject, text.String())
+		case AlertChannelSMS:
+			err = a.text(ctx, ch.Target, text.String())
+		default:
+			err = fmt.Errorf("unknown channel")
+		}
+		cancel()
+		if err != nil {
+			log.Printf("Failed to send %s alert to %s: %v", a.name, ch, err)
+		}
+	}
+}
+
+func (a *alerter) postJSON(ctx context.Context, target string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return a.do(req)
+}
+
+// text sends an SMS through Twilio's Messages API
+func (a *alerter) text(ctx context.Context, to, body string) error {
+	twilio := a.cfg.Twilio
+	base := twilio.BaseURL
+	if base == "" {
+		base = defaultTwilioBaseURL
+	}
+	form := url.Values{"To": {to}, "From": {twilio.From}, "Body": {body}}
+	endpoint := strings.TrimSuffix(base, "/") + "/2010-04-01/Accounts/" + url.PathEscape(twilio.AccountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(twilio.AccountSID, twilio.AuthToken)
+	return a.do(req)
+}
+
+func (a *alerter) do(req *http.Request) error {
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%s returned status code: %d", req.URL.Host, resp.StatusCode)
+	}
+	return nil
+}
+
+// mail sends a plain text email, upgrading to TLS when the server offers it
+func (a *alerter) mail(ctx context.Context, to, subject, body string) error {
+	server := a.cfg.SMTP
+	host, _, err := net.SplitHostPort(server.Addr)
+	if err != nil {
+		return err
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if server.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", server.Username, server.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(server.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		server.From, to, subject, strings.ReplaceAll(body, "\n", "\r\n"))
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTP accepts one mail and sends its data, headers included, on mails
+func fakeSMTP(t *testing.T, mails chan<- string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ready")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					mails <- data.String()
+					reply("250 queued")
+					continue
+				}
+				data.WriteString(line)
+				continue
+			}
+			switch command := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(command, "EHLO"):
+				reply("250 localhost")
+			case command == "DATA":
+				inData = true
+				reply("354 go ahead")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestAlerter_Channels(t *testing.T) {
+	slack := make(chan map[string]string, 1)
+	slackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		slack <- body
+	}))
+	defer slackServer.Close()
+
+	sms := make(chan *http.Request, 1)
+	twilio := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sms <- r
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer twilio.Close()
+
+	mails := make(chan string, 1)
+	cfg := AlertConfig{
+		Channels: []AlertChannel{
+			{Kind: AlertChannelSlack, Target: slackServer.URL},
+			{Kind: AlertChannelSMS, Target: "+15550100"},
+			{Kind: AlertChannelEmail, Target: "ops@example.com"},
+		},
+		Template: "{{.Pair}} crossed {{.Threshold}}: {{.Rates}}",
+		SMTP:     SMTPConfig{Addr: fakeSMTP(t, mails), From: "alerts@example.com"},
+		Twilio:   TwilioConfig{AccountSID: "AC123", AuthToken: "token", From: "+15550199", BaseURL: twilio.URL},
+	}
+	require.NoError(t, ValidateAlertConfig(cfg))
+
+	newAlerter("discrepancy", cfg, defaultDiscrepancyTemplate).send(context.Background(), AlertMessage{
+		Subject:   "USD/INR quotes diverge by 2.41%",
+		Pair:      "USD/INR",
+		Threshold: 1,
+		Rates:     "erapi=83, frankfurter=85",
+		At:        time.Now(),
+	}, nil)
+
+	const message = "USD/INR crossed 1: erapi=83, frankfurter=85"
+	assert.Equal(t, map[string]string{"text": message}, <-slack)
+
+	r := <-sms
+	assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+	assert.Equal(t, "+15550100", r.PostForm.Get("To"))
+	assert.Equal(t, "+15550199", r.PostForm.Get("From"))
+	assert.Equal(t, message, r.PostForm.Get("Body"))
+	user, password, _ := r.BasicAuth()
+	assert.Equal(t, "AC123:token", user+":"+password)
+
+	mail := <-mails
+	assert.Contains(t, mail, "To: ops@example.com\r\n")
+	assert.Contains(t, mail, "Subject: USD/INR quotes diverge by 2.41%\r\n")
+	assert.Contains(t, mail, message)
+}
+
+func TestValidateAlertConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  AlertConfig
+	}{
+		{"unknown kind", AlertConfig{Channels: []AlertChannel{{Kind: "pager", Target: "ops"}}}},
+		{"slack without URL", AlertConfig{Channels: []AlertChannel{{Kind: AlertChannelSlack, Target: "ops"}}}},
+		{"email without SMTP", AlertConfig{Channels: []AlertChannel{{Kind: AlertChannelEmail, Target: "ops@example.com"}}}},
+		{"sms without Twilio", AlertConfig{Channels: []AlertChannel{{Kind: AlertChannelSMS, Target: "+15550100"}}}},
+		{"sms to a name", AlertConfig{
+			Channels: []AlertChannel{{Kind: AlertChannelSMS, Target: "ops"}},
+			Twilio:   TwilioConfig{AccountSID: "AC123", AuthToken: "token", From: "+15550199"},
+		}},
+		{"broken template", AlertConfig{Template: "{{.Pair"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, ValidateAlertConfig(tt.cfg))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+// ConvertChain converts an amount through every currency of req.Path in
+// turn, with each leg's latest rate and markup. A leg's converted amount is
+// rounded to its currency's minor units before the next leg converts it, as
+// a remittance pays out whole units at every hop. Legs allowed rejects fail
+// with PAIR_NOT_ALLOWED; a nil allowed permits every pair.
+func (s *ExchangeService) ConvertChain(ctx context.Context, req *models.ChainConversionRequest, allowed PairFilter) (*models.ChainConversionResponse, error) {
+	if err := validateChainRequest(req); err != nil {
+		return nil, err
+	}
+	tenant := TenantFromContext(ctx)
+	for i, code := range req.Path {
+		if err := tenant.checkCurrency(code); err != nil {
+			return nil, models.ForField(err, "path", fmt.Sprintf("invalid currency at path[%d]: ", i))
+		}
+	}
+	provider, err := s.resolveProvider(req.Provider)
+	if err != nil {
+		return nil, err
+	}
+	rounding := req.Rounding
+	if rounding == "" {
+		rounding = models.RoundingHalfUp
+	}
+
+	amount := req.Amount
+	legs := make([]models.ChainLeg, 0, len(req.Path)-1)
+	for i := 0; i < len(req.Path)-1; i++ {
+		from, to := req.Path[i], req.Path[i+1]
+		if allowed != nil && !allowed(from, to) {
+			return nil, models.NewFieldError(models.ErrCodePairNotAllowed, "path", "%s/%s is not allowed", from, to)
+		}
+
+		quote, err := s.getLatestRate(ctx, from, to, provider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get exchange rate of leg %s/%s: %w", from, to, err)
+		}
+
+		var appliedRate, markupPercent float64
+		if i < len(req.Markups) && req.Markups[i] != nil {
+			markupPercent = *req.Markups[i]
+			appliedRate = quote.rate * (1 + markupPercent/100)
+		} else {
+			appliedRate, markupPercent = s.getMarkup().Apply(from, to, quote.rate)
+		}
+
+		converted := utils.Round(amount*appliedRate, utils.CurrencyPrecision(to), rounding)
+		legs = append(legs, models.ChainLeg{
+			From:            from,
+			To:              to,
+			Amount:          amount,
+			ConvertedAmount: converted,
+			Rate:            utils.Round(appliedRate, models.RatePrecision, rounding),
+			MidMarketRate:   quote.rate,
+			MarkupPercent:   markupPercent,
+			Derived:         quote.derived,
+			Source:          quote.source(),
+		})
+		amount = converted
+	}
+
+	return &models.ChainConversionResponse{
+		From:            req.Path[0],
+		To:              req.Path[len(req.Path)-1],
+		Path:            req.Path,
+		Amount:          req.Amount,
+		ConvertedAmount: amount,
+		Rate:            utils.Round(amount/req.Amount, models.RatePrecision, rounding),
+		Rounding:        rounding,
+		Legs:            legs,
+		Date:            time.Now(),
+	}, nil
+}
+
+// validateChainRequest checks the path, amount, markups and rounding of a
+// conversion chain
+func validateChainRequest(req *models.ChainConversionRequest) error {
+	if len(req.Path) < 2 || len(req.Path) > models.MaxChainCurrencies {
+		return models.NewFieldError(models.ErrCodeValueInvalid, "path",
+			"path must list between 2 and %d currencies, got %d", models.MaxChainCurrencies, len(req.Path))
+	}
+	for i := range req.Path {
+		code := models.CanonicalCurrency(req.Path[i])
+		req.Path[i] = code
+		if err := utils.ValidateCurrency(code); err != nil {
+			return models.ForField(err, "path", fmt.Sprintf("invalid currency at path[%d]: ", i))
+		}
+		if i > 0 && code == req.Path[i-1] {
+			return models.NewFieldError(models.ErrCodeValueInvalid, "path", "path[%d] repeats %s; every leg must change currency", i, code)
+		}
+	}
+	if err := utils.ValidateAmount(req.Path[0], req.Amount); err != nil {
+		return err
+	}
+
+	if len(req.Markups) > len(req.Path)-1 {
+		return models.NewFieldError(models.ErrCodeValueInvalid, "markups",
+			"markups has %d entries but the path only %d legs", len(req.Markups), len(req.Path)-1)
+	}
+	for i, percent := range req.Markups {
+		if percent != nil && *percent <= -100 {
+			return models.NewFieldError(models.ErrCodeValueInvalid, "markups", "markups[%d] must be greater than -100, got %g", i, *percent)
+		}
+	}
+	return utils.ValidateRounding(req.Rounding)
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+)
+
+func TestExchangeService_ConvertChain(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "GBP", "JPY", "", 190.555)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "JPY", "INR", "", 0.55)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 80.0)
+	service := NewExchangeService(memoryCache, nil, nil)
+	service.SetMarkup(NewMarkup(1, nil))
+
+	half := 0.5
+	resp, err := service.ConvertChain(context.Background(), &models.ChainConversionRequest{
+		Path:    []string{"GBP", "JPY", "INR"},
+		Amount:  10,
+		Markups: []*float64{nil, &half},
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, resp.Legs, 2)
+
+	first := resp.Legs[0]
+	assert.Equal(t, 1.0, first.MarkupPercent, "a null markup keeps the configured one")
+	assert.Equal(t, 190.555, first.MidMarketRate)
+	assert.Equal(t, 1925.0, first.ConvertedAmount, "yen have no minor units")
+
+	second := resp.Legs[1]
+	assert.Equal(t, 1925.0, second.Amount, "every leg converts the previous leg's rounded amount")
+	assert.Equal(t, 0.5, second.MarkupPercent)
+	assert.Equal(t, 1064.04, second.ConvertedAmount)
+
+	assert.Equal(t, "GBP", resp.From)
+	assert.Equal(t, "INR", resp.To)
+	assert.Equal(t, 1064.04, resp.ConvertedAmount)
+	assert.Equal(t, 106.404, resp.Rate)
+
+	// Legs use the inverse of a cached reverse pair like single conversions
+	resp, err = service.ConvertChain(context.Background(), &models.ChainConversionRequest{
+		Path: []string{"INR", "USD"}, Amount: 800,
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.DerivedInverse, resp.Legs[0].Derived)
+}
+
+func TestExchangeService_ConvertChainValidation(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 80.0)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "INR", "EUR", "", 0.011)
+	service := NewExchangeService(memoryCache, nil, nil)
+	negative := -100.0
+
+	tests := []struct {
+		name    string
+		req     models.ChainConversionRequest
+		allowed PairFilter
+		code    string
+	}{
+		{"single currency", models.ChainConversionRequest{Path: []string{"USD"}, Amount: 1}, nil, models.ErrCodeValueInvalid},
+		{"too many currencies", models.ChainConversionRequest{Path: []string{"USD", "INR", "EUR", "JPY", "GBP", "USD", "INR"}, Amount: 1}, nil, models.ErrCodeValueInvalid},
+		{"unsupported currency", models.ChainConversionRequest{Path: []string{"USD", "XYZ"}, Amount: 1}, nil, models.ErrCodeCurrencyUnsupported},
+		{"repeated currency", models.ChainConversionRequest{Path: []string{"USD", "USD", "INR"}, Amount: 1}, nil, models.ErrCodeValueInvalid},
+		{"invalid amount", models.ChainConversionRequest{Path: []string{"USD", "INR"}, Amount: -1}, nil, models.ErrCodeAmountInvalid},
+		{"more markups than legs", models.ChainConversionRequest{Path: []string{"USD", "INR"}, Amount: 1, Markups: []*float64{nil, nil}}, nil, models.ErrCodeValueInvalid},
+		{"markup wipes out the amount", models.ChainConversionRequest{Path: []string{"USD", "INR"}, Amount: 1, Markups: []*float64{&negative}}, nil, models.ErrCodeValueInvalid},
+		{"invalid rounding", models.ChainConversionRequest{Path: []string{"USD", "INR"}, Amount: 1, Rounding: "ceiling"}, nil, models.ErrCodeValueInvalid},
+		{"leg not allowed", models.ChainConversionRequest{Path: []string{"USD", "INR", "EUR"}, Amount: 1},
+			func(from, to string) bool { return from == "USD" }, models.ErrCodePairNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.ConvertChain(context.Background(), &tt.req, tt.allowed)
+			require.Error(t, err)
+			code, _ := models.ErrorCodeOf(err)
+			assert.Equal(t, tt.code, code)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+// CompareRates returns the current rate of a pair from every configured
+// provider, leaving out those missing their credentials. The served quote is the default provider's, looked up like
+// GetLatestRate does; the other providers are fetched live, all at once. A
+// provider that fails is listed with its error rather than failing the
+// comparison.
+func (s *ExchangeService) CompareRates(ctx context.Context, from, to string) (*models.RateComparison, error) {
+	if err := validatePair(ctx, &from, &to); err != nil {
+		return nil, err
+	}
+	if s.client == nil {
+		return nil, ErrNoProviderSelection
+	}
+
+	defaultProvider := s.client.DefaultProvider()
+	providers := []string{defaultProvider}
+	for _, provider := range s.client.ConfiguredProviders() {
+		if provider != defaultProvider {
+			providers = append(providers, provider)
+		}
+	}
+
+	comparison := &models.RateComparison{
+		From:       from,
+		To:         to,
+		Quotes:     make([]models.ProviderQuote, len(providers)),
+		ComparedAt: time.Now().UTC(),
+	}
+	var wg sync.WaitGroup
+	for i, provider := range providers {
+		wg.Add(1)
+		go func(i int, provider string) {
+			defer wg.Done()
+			pinned := provider
+			if provider == defaultProvider {
+				pinned = ""
+			}
+
+			quote, err := s.getLatestRate(ctx, from, to, pinned)
+			if err != nil {
+				code, _ := models.ErrorCodeOf(err)
+				comparison.Quotes[i] = models.ProviderQuote{
+					Error:     err.Error(),
+					ErrorCode: code,
+					Source:    models.Source{Provider: provider},
+				}
+				return
+			}
+			comparison.Quotes[i] = models.ProviderQuote{
+				Rate:    quote.rate,
+				Derived: quote.derived,
+				Source:  quote.source(),
+			}
+			if comparison.Quotes[i].Provider == "" {
+				comparison.Quotes[i].Provider = provider
+			}
+		}(i, provider)
+	}
+	wg.Wait()
+
+	served := &comparison.Quotes[0]
+	served.Served = served.Error == ""
+	rates := make(map[string]float64, len(providers))
+	for i := range comparison.Quotes {
+		quote := &comparison.Quotes[i]
+		if quote.Error != "" {
+			continue
+		}
+		rates[quote.Provider] = quote.Rate
+		if served.Served && !quote.Served {
+			quote.DifferencePercent = utils.Round((quote.Rate-served.Rate)/served.Rate*100, 4, models.RoundingHalfUp)
+		}
+	}
+	if len(rates) >= 2 {
+		comparison.SpreadPercent = utils.Round(quoteSpread(rates), 4, models.RoundingHalfUp)
+	}
+	return comparison, nil
+}
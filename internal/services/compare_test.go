@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+)
+
+func TestExchangeService_CompareRates(t *testing.T) {
+	frankfurterUp := true
+	frankfurter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !frankfurterUp {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"base":"USD","date":"2025-01-02","rates":{"INR":85.0}}`))
+	}))
+	defer frankfurter.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.FrankfurterBaseURL = frankfurter.URL
+	cfg.Retry.MaxAttempts = 1
+	client := external.NewExchangeRateClientWithConfig(cfg)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	published := time.Now().Add(-time.Hour).Truncate(time.Second)
+	memoryCache.SetWithSource("USD", "INR", "", 83.0, cache.Source{Provider: external.ProviderExchangeRateAPI, PublishedAt: published})
+	service := NewExchangeService(memoryCache, NewRateFetcher(client, memoryCache), client)
+
+	comparison, err := service.CompareRates(context.Background(), "usd", "INR")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", comparison.From)
+	require.Len(t, comparison.Quotes, 2, "fixer has no key, so it is left out")
+
+	served := comparison.Quotes[0]
+	assert.Equal(t, external.ProviderExchangeRateAPI, served.Provider)
+	assert.True(t, served.Served)
+	assert.Equal(t, 83.0, served.Rate)
+	assert.Equal(t, models.OriginCache, served.Origin)
+	require.NotNil(t, served.PublishedAt)
+	assert.True(t, published.Equal(*served.PublishedAt))
+
+	live := comparison.Quotes[1]
+	assert.Equal(t, external.ProviderFrankfurter, live.Provider)
+	assert.False(t, live.Served)
+	assert.Equal(t, 85.0, live.Rate)
+	assert.Equal(t, models.OriginLive, live.Origin)
+	assert.InDelta(t, 2.4096, live.DifferencePercent, 0.0001)
+	assert.InDelta(t, 2.4096, comparison.SpreadPercent, 0.0001)
+
+	// A provider that fails is listed with its error
+	frankfurterUp = false
+	comparison, err = service.CompareRates(context.Background(), "USD", "INR")
+	require.NoError(t, err)
+	require.Len(t, comparison.Quotes, 2)
+	assert.Equal(t, 83.0, comparison.Quotes[0].Rate)
+	assert.Equal(t, external.ProviderFrankfurter, comparison.Quotes[1].Provider)
+	assert.Equal(t, models.ErrCodeProviderUnavailable, comparison.Quotes[1].ErrorCode)
+	assert.Zero(t, comparison.SpreadPercent)
+
+	_, err = service.CompareRates(context.Background(), "USD", "XX")
+	assert.Error(t, err)
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/models"
+)
+
+// JobConfig sizes the asynchronous conversion jobs
+type JobConfig struct {
+	Workers   int           // Rows converted concurrently, across all jobs
+	MaxRows   int           // Rows accepted in one file
+	Retention time.Duration // How long finished jobs and their results are kept
+}
+
+// DefaultJobConfig converts 4 rows at a time, accepts files of up to 50000
+// rows and keeps results for a day
+func DefaultJobConfig() JobConfig {
+	return JobConfig{
+		Workers:   4,
+		MaxRows:   50000,
+		Retention: 24 * time.Hour,
+	}
+}
+
+// jobColumns are the columns of an uploaded file; from, to and amount are
+// required, the others may be left out
+var jobColumns = []string{"from", "to", "amount", "date", "provider"}
+
+// PairFilter reports whether a caller may convert between two currencies
+type PairFilter func(from, to string) bool
+
+// ConversionJobs converts uploaded CSV files in the background. Every row
+// is a conversion; a fixed pool of workers converts the rows of all jobs, so
+// a large file can't starve the provider. Jobs and their results are held in
+// memory for the retention period.
+type ConversionJobs struct {
+	service *ExchangeService
+	cfg     JobConfig
+	tasks   chan jobTask
+
+	mu        sync.Mutex
+	jobs      map[string]*conversionJob
+	isRunning bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+type conversionJob struct {
+	info    models.ConversionJob
+	rows    []jobRow
+	results []jobResult
+	allowed PairFilter
+	tenant  *Tenant
+	pending sync.WaitGroup
+}
+
+// jobRow is a row of an uploaded file; line is its line number in the file
+type jobRow struct {
+	line     int
+	from     string
+	to       string
+	amount   string
+	date     string
+	provider string
+}
+
+type jobResult struct {
+	conversion *models.ConversionResponse
+	err        error
+}
+
+type jobTask struct {
+	job   *conversionJob
+	index int
+}
+
+func NewConversionJobs(service *ExchangeService, cfg JobConfig) *ConversionJobs {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &ConversionJobs{
+		service: service,
+		cfg:     cfg,
+		tasks:   make(chan jobTask, cfg.Workers),
+		jobs:    make(map[string]*conversionJob),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func (j *ConversionJobs) Start() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.isRunning {
+		return
+	}
+	j.isRunning = true
+
+	for i := 0; i < j.cfg.Workers; i++ {
+		go j.work()
+	}
+}
+
+// Stop stops the workers. Jobs still running are marked failed.
+func (j *ConversionJobs) Stop() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.isRunning {
+		return
+	}
+	j.cancel()
+	j.isRunning = false
+}
+
+// Submit parses a CSV file with a header row naming the from, to and amount
+// columns, and optionally date and provider, and queues its rows for
+// conversion on behalf of caller, within the tenant of ctx. Rows allowed
+// rejects are failed with PAIR_NOT_ALLOWED; a nil allowed permits every pair.
+func (j *ConversionJobs) Submit(ctx context.Context, caller string, file io.Reader, allowed PairFilter) (*models.ConversionJob, error) {
+	rows, err := parseJobFile(file, j.cfg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &conversionJob{
+		info: models.ConversionJob{
+			ID:        id,
+			Status:    models.JobQueued,
+			Caller:    caller,
+			Total:     len(rows),
+			CreatedAt: time.Now().UTC(),
+		},
+		rows:    rows,
+		results: make([]jobResult, len(rows)),
+		allowed: allowed,
+		tenant:  TenantFromContext(ctx),
+	}
+
+	j.mu.Lock()
+	if !j.isRunning {
+		j.mu.Unlock()
+		return nil, models.NewError(models.ErrCodeInternal, "conversion jobs are not running")
+	}
+	j.prune(time.Now())
+	j.jobs[id] = job
+	info := job.info
+	j.mu.Unlock()
+
+	log.Printf("Queued conversion job %s with %d rows for %s", id, len(rows), caller)
+	go j.feed(job)
+	return &info, nil
+}
+
+// Get returns the progress of a job
+func (j *ConversionJobs) Get(id string) (*models.ConversionJob, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.prune(time.Now())
+	job, ok := j.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	info := job.info
+	return &info, true
+}
+
+// WriteResult writes the result file of a completed job as CSV: every row
+// of the upload in order, with its conversion or the error it failed with
+func (j *ConversionJobs) WriteResult(id string, w io.Writer) error {
+	j.mu.Lock()
+	job, ok := j.jobs[id]
+	if !ok {
+		j.mu.Unlock()
+		return models.NewError(models.ErrCodeNotFound, "job %s not found", id)
+	}
+	if job.info.Status != models.JobCompleted {
+		status := job.info.Status
+		j.mu.Unlock()
+		return models.NewError(models.ErrCodeJobPending, "job %s is %s, its result is not ready", id, status)
+	}
+	j.mu.Unlock()
+
+	// A completed job's rows and results no longer change
+	out := csv.NewWriter(w)
+	out.Write([]string{"line", "from", "to", "amount", "converted_amount", "rate", "rate_date", "provider", "error_code", "error", "conversion_id"})
+	for i, row := range job.rows {
+		record := []string{strconv.Itoa(row.line), row.from, row.to, row.amount, "", "", "", "", "", "", ""}
+		if result := job.results[i]; result.err != nil {
+			record[8], _ = models.ErrorCodeOf(result.err)
+			record[9] = result.err.Error()
+		} else {
+			conversion := result.conversion
+			record[4] = strconv.FormatFloat(conversion.ConvertedAmount, 'f', -1, 64)
+			record[5] = strconv.FormatFloat(conversion.Rate, 'f', -1, 64)
+			record[6] = conversion.RateDate
+			record[7] = conversion.Provider
+			record[10] = conversion.ConversionID
+		}
+		out.Write(record)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// prune drops jobs finished longer than the retention period ago. The
+// caller holds j.mu.
+func (j *ConversionJobs) prune(now time.Time) {
+	for id, job := range j.jobs {
+		if job.info.CompletedAt != nil && now.Sub(*job.info.CompletedAt) > j.cfg.Retention {
+			delete(j.jobs, id)
+		}
+	}
+}
+
+// feed hands the rows of a job to the workers and completes the job once
+// every row is processed
+func (j *ConversionJobs) feed(job *conversionJob) {
+	j.mu.Lock()
+	started := time.Now().UTC()
+	job.info.Status = models.JobRunning
+	job.info.StartedAt = &started
+	j.mu.Unlock()
+
+	job.pending.Add(len(job.rows))
+	for i := range job.rows {
+		select {
+		case j.tasks <- jobTask{job: job, index: i}:
+		case <-j.ctx.Done():
+			j.finish(job, models.JobFailed, "the service stopped before every row was converted")
+			return
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		job.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		j.finish(job, models.JobCompleted, "")
+	case <-j.ctx.Done():
+		j.finish(job, models.JobFailed, "the service stopped before every row was converted")
+	}
+}
+
+func (j *ConversionJobs) finish(job *conversionJob, status, reason string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	completed := time.Now().UTC()
+	job.info.Status = status
+	job.info.Error = reason
+	job.info.CompletedAt = &completed
+	if status == models.JobCompleted {
+		job.info.ResultURL = "/api/v1/jobs/" + job.info.ID + "/result"
+	}
+	log.Printf("Conversion job %s %s: %d rows, %d failed", job.info.ID, status, job.info.Processed, job.info.Failed)
+}
+
+func (j *ConversionJobs) work() {
+	for {
+		select {
+		case <-j.ctx.Done():
+			return
+		case task := <-j.tasks:
+			j.convert(task)
+		}
+	}
+}
+
+func (j *ConversionJobs) convert(task jobTask) {
+	job := task.job
+	defer job.pending.Done()
+
+	row := job.rows[task.index]
+	result := jobResult{}
+	amount, err := strconv.ParseFloat(row.amount, 64)
+	from, to := models.CanonicalCurrency(row.from), models.CanonicalCurrency(row.to)
+	switch {
+	case err != nil:
+		result.err = models.NewFieldError(models.ErrCodeInvalidRequest, "amount", "amount must be a valid number, got %q", row.amount)
+	case job.allowed != nil && !job.allowed(from, to):
+		result.err = models.NewError(models.ErrCodePairNotAllowed, "%s/%s is not allowed for %s", row.from, row.to, job.info.Caller)
+	default:
+		result.conversion, result.err = j.service.ConvertCurrency(WithTenant(j.ctx, job.tenant), &models.ConversionRequest{
+			From:     from,
+			To:       to,
+			Amount:   amount,
+			Date:     row.date,
+			Provider: row.provider,
+		})
+	}
+	if result.err == nil {
+		if err := j.service.RecordConversion(job.info.Caller, result.conversion); err != nil {
+			log.Printf("Failed to audit conversion %s/%s: %v", row.from, row.to, errors.Unwrap(err))
+			result = jobResult{err: err}
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job.results[task.index] = result
+	job.info.Processed++
+	if result.err != nil {
+		job.info.Failed++
+	}
+}
+
+// parseJobFile reads the rows of an uploaded CSV file
+func parseJobFile(file io.Reader, maxRows int) ([]jobRow, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, models.NewFieldError(models.ErrCodeInvalidRequest, "file", "the file is empty")
+	}
+	if err != nil {
+		return nil, models.NewFieldError(models.ErrCodeInvalidRequest, "file", "invalid CSV: %v", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range jobColumns[:3] {
+		if _, ok := columns[name]; !ok {
+			return nil, models.NewFieldError(models.ErrCodeInvalidRequest, "file", "the header has no %s column", name)
+		}
+	}
+
+	var rows []jobRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, &models.CodedError{Code: models.ErrCodeInvalidRequest, Field: "file", Message: "invalid CSV: " + err.Error(), Err: err}
+		}
+		if len(rows) == maxRows {
+			return nil, models.NewFieldError(models.ErrCodeLimitExceeded, "file", "the file has more than %d rows", maxRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := jobRow{line: line}
+		fields := []*string{&row.from, &row.to, &row.amount, &row.date, &row.provider}
+		for i, name := range jobColumns {
+			if index, ok := columns[name]; ok && index < len(record) {
+				*fields[i] = strings.TrimSpace(record[index])
+			}
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, models.NewFieldError(models.ErrCodeInvalidRequest, "file", "the file has no rows")
+	}
+	return rows, nil
+}
+
+func newJobID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/store"
+)
+
+func newTestJobs(t *testing.T, cfg JobConfig) *ConversionJobs {
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 83.5)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "EUR", "", 0.92)
+	service := NewExchangeService(memoryCache, nil, nil)
+	auditLog, err := store.NewAuditLog("", 0)
+	require.NoError(t, err)
+	service.SetAuditLog(auditLog)
+	jobs := NewConversionJobs(service, cfg)
+	jobs.Start()
+	t.Cleanup(jobs.Stop)
+	return jobs
+}
+
+func waitForJob(t *testing.T, jobs *ConversionJobs, id string) *models.ConversionJob {
+	var job *models.ConversionJob
+	require.Eventually(t, func() bool {
+		job, _ = jobs.Get(id)
+		return job.Status == models.JobCompleted
+	}, 5*time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestConversionJobs_Convert(t *testing.T) {
+	jobs := newTestJobs(t, DefaultJobConfig())
+
+	file := "From,To,Amount\n" +
+		"USD,INR,10\n" +
+		"usd,eur,100\n" +
+		"USD,INR,ten\n" +
+		"INR,EUR,1\n" +
+		"USD,XYZ,1\n"
+	allowed := func(from, to string) bool { return from == "USD" }
+	job, err := jobs.Submit(context.Background(), "partner", strings.NewReader(file), allowed)
+	require.NoError(t, err)
+	assert.Equal(t, 5, job.Total)
+	assert.Equal(t, "partner", job.Caller)
+	assert.Len(t, job.ID, 32)
+
+	job = waitForJob(t, jobs, job.ID)
+	assert.Equal(t, 5, job.Processed)
+	assert.Equal(t, 3, job.Failed)
+	assert.Equal(t, "/api/v1/jobs/"+job.ID+"/result", job.ResultURL)
+
+	var result bytes.Buffer
+	require.NoError(t, jobs.WriteResult(job.ID, &result))
+	lines := strings.Split(strings.TrimSpace(result.String()), "\n")
+	require.Len(t, lines, 6)
+	assert.Equal(t, "line,from,to,amount,converted_amount,rate,rate_date,provider,error_code,error,conversion_id", lines[0])
+	assert.Regexp(t, `^2,USD,INR,10,835,83.5,,exchangerate-api,,,[0-9a-f]{32}$`, lines[1])
+	assert.Regexp(t, `^3,usd,eur,100,92,0.92,,exchangerate-api,,,[0-9a-f]{32}$`, lines[2])
+	assert.True(t, strings.HasPrefix(lines[3], "4,USD,INR,ten,,,,,INVALID_REQUEST,"), lines[3])
+	assert.True(t, strings.HasPrefix(lines[4], "5,INR,EUR,1,,,,,PAIR_NOT_ALLOWED,"), lines[4])
+	assert.True(t, strings.HasPrefix(lines[5], "6,USD,XYZ,1,,,,,CURRENCY_UNSUPPORTED,"), lines[5])
+
+	err = jobs.WriteResult("unknown", &result)
+	code, _ := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeNotFound, code)
+}
+
+func TestConversionJobs_InvalidFiles(t *testing.T) {
+	cfg := DefaultJobConfig()
+	cfg.MaxRows = 2
+	jobs := newTestJobs(t, cfg)
+
+	tests := []struct {
+		name string
+		file string
+		code string
+	}{
+		{"Empty", "", models.ErrCodeInvalidRequest},
+		{"Header only", "from,to,amount\n", models.ErrCodeInvalidRequest},
+		{"Missing column", "from,to\nUSD,INR\n", models.ErrCodeInvalidRequest},
+		{"Too many rows", "from,to,amount\nUSD,INR,1\nUSD,INR,2\nUSD,INR,3\n", models.ErrCodeLimitExceeded},
+		{"Malformed", "from,to,amount\n\"USD,INR,1\n", models.ErrCodeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jobs.Submit(context.Background(), "partner", strings.NewReader(tt.file), nil)
+			require.Error(t, err)
+			code, field := models.ErrorCodeOf(err)
+			assert.Equal(t, tt.code, code)
+			assert.Equal(t, "file", field)
+		})
+	}
+}
+
+func TestConversionJobs_ManyRows(t *testing.T) {
+	cfg := DefaultJobConfig()
+	cfg.Workers = 8
+	jobs := newTestJobs(t, cfg)
+
+	var file strings.Builder
+	file.WriteString("from,to,amount\n")
+	for i := 1; i <= 2000; i++ {
+		fmt.Fprintf(&file, "USD,INR,%d\n", i)
+	}
+	job, err := jobs.Submit(context.Background(), "partner", strings.NewReader(file.String()), nil)
+	require.NoError(t, err)
+
+	job = waitForJob(t, jobs, job.ID)
+	assert.Equal(t, 2000, job.Processed)
+	assert.Zero(t, job.Failed)
+
+	var result bytes.Buffer
+	require.NoError(t, jobs.WriteResult(job.ID, &result))
+	lines := strings.Split(strings.TrimSpace(result.String()), "\n")
+	assert.Regexp(t, `^2001,USD,INR,2000,167000,83.5,,exchangerate-api,,,[0-9a-f]{32}$`, lines[len(lines)-1], "rows stay in upload order")
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+// DefaultCorrelationWindow is the window, in days, correlations span when the
+// request names none
+const DefaultCorrelationWindow = 30
+
+// Bounds of a correlation request
+const (
+	maxCorrelationPairs   = 10
+	minCorrelationSamples = 3 // Shared day-over-day changes needed for a correlation
+)
+
+// GetCorrelation computes, from the historical rates of the window ending
+// today, the volatility of each pair and the Pearson correlation of every two
+// pairs' day-over-day changes. Two pairs are compared over the trading days
+// both have a change for. Historical rates are looked up like
+// GetHistoricalRates does, once per pair.
+func (s *ExchangeService) GetCorrelation(ctx context.Context, req *models.CorrelationRequest) (*models.CorrelationResponse, error) {
+	if len(req.Pairs) < 2 || len(req.Pairs) > maxCorrelationPairs {
+		return nil, models.NewFieldError(models.ErrCodeValueInvalid, "pairs", "between 2 and %d pairs can be correlated, got %d", maxCorrelationPairs, len(req.Pairs))
+	}
+	window := req.Window
+	if window == 0 {
+		window = DefaultCorrelationWindow
+	}
+	lookback, maxRange := utils.DateLimits()
+	if window <= minCorrelationSamples || window > maxRange {
+		return nil, models.NewFieldError(models.ErrCodeValueInvalid, "window", "window must be between %d and %d days, got %d", minCorrelationSamples+1, maxRange, window)
+	}
+
+	pairs, err := parsePairs(ctx, req.Pairs)
+	if err != nil {
+		return nil, err
+	}
+
+	today := utils.Today()
+	start := today.AddDate(0, 0, -(window - 1))
+	if earliest := today.AddDate(0, 0, -lookback); lookback > 0 && start.Before(earliest) {
+		start = earliest
+	}
+	dates := utils.GetDateRangeList(start, today)
+
+	resp := &models.CorrelationResponse{
+		Window:    window,
+		StartDate: dates[0],
+		EndDate:   dates[len(dates)-1],
+		Date:      time.Now(),
+	}
+	changes := make([]map[string]float64, len(pairs))
+	for i, p := range pairs {
+		historical, err := s.GetHistoricalRates(ctx, &models.HistoricalRateRequest{
+			From:      p.from,
+			To:        p.to,
+			StartDate: resp.StartDate,
+			EndDate:   resp.EndDate,
+			Provider:  req.Provider,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Freshness.Merge(historical.Freshness)
+		resp.Source.Merge(historical.Source)
+
+		points, _ := computeTrend(dates, historical.Rates, 1)
+		if len(points) == 0 {
+			return nil, models.NewError(models.ErrCodeRateNotFound, "no historical rates of %s/%s in the last %d days", p.from, p.to, window)
+		}
+		changes[i] = make(map[string]float64, len(points))
+		values := make([]float64, 0, len(points))
+		for _, point := range points {
+			if point.ChangePercent != nil {
+				changes[i][point.Date] = *point.ChangePercent
+				values = append(values, *point.ChangePercent)
+			}
+		}
+		resp.Pairs = append(resp.Pairs, models.PairVolatility{
+			Pair:              p.String(),
+			From:              p.from,
+			To:                p.to,
+			Samples:           len(values),
+			VolatilityPercent: standardDeviation(values),
+		})
+	}
+
+	resp.Matrix = make([][]*float64, len(pairs))
+	for i := range pairs {
+		resp.Matrix[i] = make([]*float64, len(pairs))
+	}
+	for i := range pairs {
+		for j := i; j < len(pairs); j++ {
+			if correlation, ok := correlate(dates, changes[i], changes[j]); ok {
+				resp.Matrix[i][j], resp.Matrix[j][i] = &correlation, &correlation
+			}
+		}
+	}
+	return resp, nil
+}
+
+// correlate returns the Pearson correlation of a and b over the dates both
+// have a value for, or false when they share fewer than
+// minCorrelationSamples dates or either is constant over them
+func correlate(dates []string, a, b map[string]float64) (float64, bool) {
+	var xs, ys []float64
+	for _, date := range dates {
+		x, inA := a[date]
+		y, inB := b[date]
+		if inA && inB {
+			xs = append(xs, x)
+			ys = append(ys, y)
+		}
+	}
+	if len(xs) < minCorrelationSamples {
+		return 0, false
+	}
+
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(len(xs))
+	meanY /= float64(len(ys))
+	var covariance, varianceX, varianceY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		covariance += dx * dy
+		varianceX += dx * dx
+		varianceY += dy * dy
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return 0, false
+	}
+	// Rounding can push a perfect correlation just past ±1
+	return math.Max(-1, math.Min(1, covariance/math.Sqrt(varianceX*varianceY))), true
+}
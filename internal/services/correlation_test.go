@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+func TestCorrelate(t *testing.T) {
+	dates := []string{"2025-01-06", "2025-01-07", "2025-01-08", "2025-01-09"}
+	a := map[string]float64{"2025-01-06": 1, "2025-01-07": -1, "2025-01-08": 2, "2025-01-09": 0.5}
+
+	tests := []struct {
+		name string
+		b    map[string]float64
+		want float64
+		ok   bool
+	}{
+		{"same moves", map[string]float64{"2025-01-06": 2, "2025-01-07": -2, "2025-01-08": 4, "2025-01-09": 1}, 1, true},
+		{"opposite moves", map[string]float64{"2025-01-06": -1, "2025-01-07": 1, "2025-01-08": -2, "2025-01-09": -0.5}, -1, true},
+		{"too few shared days", map[string]float64{"2025-01-06": 1, "2025-01-09": 2, "2025-01-10": 3}, 0, false},
+		{"no moves", map[string]float64{"2025-01-06": 0, "2025-01-07": 0, "2025-01-08": 0, "2025-01-09": 0}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := correlate(dates, a, tt.b)
+			assert.Equal(t, tt.ok, ok)
+			assert.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func TestExchangeService_GetCorrelation(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	today := utils.Today()
+	for i := 0; i < 14; i++ {
+		date := today.AddDate(0, 0, -i).Format(utils.DateFormat)
+		usd := 80 + float64(i%5)
+		memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", date, usd)
+		memoryCache.Set(external.ProviderExchangeRateAPI, "EUR", "INR", date, usd*1.1) // Same relative moves
+		memoryCache.Set(external.ProviderExchangeRateAPI, "GBP", "INR", date, 200-usd) // Opposite moves
+	}
+	service := NewExchangeService(memoryCache, nil, nil)
+
+	resp, err := service.GetCorrelation(context.Background(), &models.CorrelationRequest{Pairs: []string{"USD_INR", "eur_inr", "GBP_INR"}, Window: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 10, resp.Window)
+	assert.Equal(t, today.Format(utils.DateFormat), resp.EndDate)
+	require.Len(t, resp.Pairs, 3)
+	assert.Equal(t, "EUR_INR", resp.Pairs[1].Pair)
+	assert.GreaterOrEqual(t, resp.Pairs[0].Samples, minCorrelationSamples, "weekends carry Friday's rate and are left out")
+	assert.Equal(t, resp.Pairs[0].Samples, resp.Pairs[1].Samples)
+	assert.Greater(t, resp.Pairs[0].VolatilityPercent, 0.0)
+	assert.InDelta(t, resp.Pairs[0].VolatilityPercent, resp.Pairs[1].VolatilityPercent, 1e-9)
+
+	require.Len(t, resp.Matrix, 3)
+	for i := range resp.Matrix {
+		require.NotNil(t, resp.Matrix[i][i])
+		assert.InDelta(t, 1, *resp.Matrix[i][i], 1e-9)
+	}
+	require.NotNil(t, resp.Matrix[0][1])
+	assert.InDelta(t, 1, *resp.Matrix[0][1], 1e-9)
+	assert.Less(t, *resp.Matrix[0][2], -0.9)
+	assert.Equal(t, resp.Matrix[0][2], resp.Matrix[2][0], "the matrix is symmetric")
+
+	tests := []struct {
+		name  string
+		req   models.CorrelationRequest
+		field string
+	}{
+		{"single pair", models.CorrelationRequest{Pairs: []string{"USD_INR"}}, "pairs"},
+		{"pair without separator", models.CorrelationRequest{Pairs: []string{"USD_INR", "EURINR"}}, "pairs"},
+		{"unsupported currency", models.CorrelationRequest{Pairs: []string{"USD_INR", "XYZ_INR"}}, "pairs"},
+		{"duplicate pair", models.CorrelationRequest{Pairs: []string{"USD_INR", "usd_inr"}}, "pairs"},
+		{"window too short", models.CorrelationRequest{Pairs: []string{"USD_INR", "EUR_INR"}, Window: 3}, "window"},
+		{"window beyond the range cap", models.CorrelationRequest{Pairs: []string{"USD_INR", "EUR_INR"}, Window: utils.DefaultMaxRangeDays + 1}, "window"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.GetCorrelation(context.Background(), &tt.req)
+			require.Error(t, err)
+			_, field := models.ErrorCodeOf(err)
+			assert.Equal(t, tt.field, field)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"exchange-rate-service/internal/models"
+)
+
+// minSupportedCurrencies is how many currencies must stay supported for
+// any pair to exist
+const minSupportedCurrencies = 2
+
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// AddCurrency adds a currency to the supported set and reports whether it
+// was not supported before. The fetcher starts polling its pairs at once;
+// until their first refresh they are fetched on demand.
+func (s *ExchangeService) AddCurrency(code string) (bool, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !currencyCodePattern.MatchString(code) {
+		return false, models.NewFieldError(models.ErrCodeValueInvalid, "code", "currency code must be three letters, got %q", code)
+	}
+
+	if !models.AddSupportedCurrency(code) {
+		return false, nil
+	}
+
+	if s.rateFetcher != nil {
+		s.rateFetcher.Reschedule()
+		if s.rateFetcher.IsRunning() {
+			// Every base needs a quote of the new currency, not only its own
+			go s.rateFetcher.FetchNow(context.Background())
+		}
+	}
+	return true, nil
+}
+
+// RemoveCurrency removes a currency from the supported set, stops polling
+// its pairs and drops their cached rates, returning how many entries were
+// removed
+func (s *ExchangeService) RemoveCurrency(code string) (int, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !models.IsSupportedCurrency(code) {
+		return 0, models.NewFieldError(models.ErrCodeNotFound, "code", "currency %s is not supported", code)
+	}
+	if len(models.SupportedCurrencyCodes()) <= minSupportedCurrencies {
+		return 0, models.NewFieldError(models.ErrCodeValueInvalid, "code",
+			"at least %d currencies must stay supported", minSupportedCurrencies)
+	}
+
+	if !models.RemoveSupportedCurrency(code) {
+		return 0, models.NewFieldError(models.ErrCodeNotFound, "code", "currency %s is not supported", code)
+	}
+
+	if s.rateFetcher != nil {
+		s.rateFetcher.Reschedule()
+	}
+
+	removed := 0
+	for _, other := range models.SupportedCurrencyCodes() {
+		removed += s.cache.DeletePair(code, other)
+		removed += s.cache.DeletePair(other, code)
+	}
+	return removed, nil
+}
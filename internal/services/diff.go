@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+// GetRateDiff compares the rates against a base on a past date with the
+// latest ones, or with those of a later date, and returns how much each
+// changed. Past rates are looked up like historical rates, from the
+// end-of-day archive first; a date without trading uses the previous trading
+// day's rates.
+func (s *ExchangeService) GetRateDiff(ctx context.Context, req *models.RateDiffRequest) (*models.RateDiffResponse, error) {
+	tenant := TenantFromContext(ctx)
+	base := models.CanonicalCurrency(req.Base)
+	if err := utils.ValidateCurrency(base); err != nil {
+		return nil, models.ForField(err, "base", "")
+	}
+	if err := tenant.checkCurrency(base); err != nil {
+		return nil, models.ForField(err, "base", "")
+	}
+
+	since, err := utils.ValidateDate(req.Since)
+	if err != nil {
+		return nil, models.ForField(err, "since", "")
+	}
+	var until time.Time
+	if req.Until != "" {
+		if until, err = utils.ValidateDate(req.Until); err != nil {
+			return nil, models.ForField(err, "until", "")
+		}
+		if until.Before(since) {
+			return nil, models.NewFieldError(models.ErrCodeDateOutOfRange, "until", "until must not be before since")
+		}
+	}
+
+	quotes := tenant.FilterCurrencies(s.GetSupportedCurrencies())
+	if len(req.Symbols) > 0 {
+		quotes = make([]string, 0, len(req.Symbols))
+		for _, symbol := range req.Symbols {
+			symbol = models.CanonicalCurrency(symbol)
+			if err := utils.ValidateCurrency(symbol); err != nil {
+				return nil, models.ForField(err, "symbols", "")
+			}
+			if err := tenant.checkCurrency(symbol); err != nil {
+				return nil, models.ForField(err, "symbols", "")
+			}
+			quotes = append(quotes, symbol)
+		}
+	}
+
+	diff := &models.RateDiffResponse{
+		Base:    base,
+		Since:   since.Format(utils.DateFormat),
+		Changes: []models.RateDiff{},
+	}
+	if !until.IsZero() {
+		diff.Until = until.Format(utils.DateFormat)
+	}
+
+	for _, quote := range quotes {
+		if quote == base {
+			continue
+		}
+
+		observedDate := utils.LastTradingDay(since, base, quote).Format(utils.DateFormat)
+		previous, current, err := s.diffQuotes(ctx, base, quote, observedDate, until)
+		if err != nil {
+			if ctx.Err() != nil {
+				// The request is over; the remaining pairs were not looked up
+				return nil, err
+			}
+			diff.Missing = append(diff.Missing, base+"_"+quote)
+			continue
+		}
+		diff.Freshness.Merge(current.freshness())
+		diff.Source.Merge(current.source())
+
+		if current.rate == previous.rate {
+			diff.Unchanged = append(diff.Unchanged, quote)
+			continue
+		}
+		change := models.RateDiff{
+			Quote:        quote,
+			PreviousRate: previous.rate,
+			Rate:         current.rate,
+			Change:       current.rate - previous.rate,
+		}
+		if previous.rate != 0 {
+			change.ChangePercent = change.Change / previous.rate * 100
+		}
+		if observedDate != diff.Since {
+			change.ObservedDate = observedDate
+		}
+		diff.Changes = append(diff.Changes, change)
+	}
+
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		return math.Abs(diff.Changes[i].ChangePercent) > math.Abs(diff.Changes[j].ChangePercent)
+	})
+	return diff, nil
+}
+
+// diffQuotes returns the rate of a pair on observedDate and the one it is
+// compared with: the latest rate when until is zero, else until's
+func (s *ExchangeService) diffQuotes(ctx context.Context, from, to, observedDate string, until time.Time) (rateQuote, rateQuote, error) {
+	previous, err := s.getHistoricalRate(ctx, from, to, observedDate, "")
+	if err != nil {
+		return rateQuote{}, rateQuote{}, err
+	}
+
+	var current rateQuote
+	if until.IsZero() {
+		current, err = s.getLatestRate(ctx, from, to, "")
+	} else {
+		current, err = s.getHistoricalRate(ctx, from, to, utils.LastTradingDay(until, from, to).Format(utils.DateFormat), "")
+	}
+	return previous, current, err
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/store"
+	"exchange-rate-service/internal/utils"
+)
+
+func TestExchangeService_GetRateDiff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"result":"error","error-type":"unsupported-code"}`))
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = server.URL
+	cfg.Retry.MaxAttempts = 1
+	client := external.NewExchangeRateClientWithConfig(cfg)
+
+	since := utils.LastTradingDay(utils.Today().AddDate(0, 0, -7))
+	until := utils.LastTradingDay(since.AddDate(0, 0, 3))
+	archive, err := store.NewArchive("")
+	require.NoError(t, err)
+	for date, rates := range map[string]map[string]float64{
+		since.Format(utils.DateFormat): {"INR": 80, "EUR": 0.92, "JPY": 150},
+		until.Format(utils.DateFormat): {"INR": 82, "EUR": 0.9, "JPY": 150},
+	} {
+		require.NoError(t, archive.Save(&store.Snapshot{Date: date, CapturedAt: time.Now(), Rates: map[string]map[string]float64{"USD": rates}}))
+	}
+
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 84)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "EUR", "", 0.92)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "JPY", "USD", "", 1.0/144)
+	service := NewExchangeService(memoryCache, NewRateFetcher(client, memoryCache), client)
+	service.SetArchive(archive)
+
+	diff, err := service.GetRateDiff(context.Background(), &models.RateDiffRequest{
+		Base:    "usd",
+		Since:   since.Format(utils.DateFormat),
+		Symbols: []string{"INR", "EUR", "JPY", "GBP", "USD"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "USD", diff.Base)
+	assert.Empty(t, diff.Until, "compared with the latest rates")
+	require.Len(t, diff.Changes, 2)
+	assert.Equal(t, models.RateDiff{Quote: "INR", PreviousRate: 80, Rate: 84, Change: 4, ChangePercent: 5}, diff.Changes[0], "largest move first")
+	assert.Equal(t, "JPY", diff.Changes[1].Quote, "derived from the reverse pair")
+	assert.InDelta(t, -4, diff.Changes[1].ChangePercent, 1e-9)
+	assert.Equal(t, []string{"EUR"}, diff.Unchanged)
+	assert.Equal(t, []string{"USD_GBP"}, diff.Missing)
+
+	diff, err = service.GetRateDiff(context.Background(), &models.RateDiffRequest{
+		Base:    "USD",
+		Since:   since.Format(utils.DateFormat),
+		Until:   until.Format(utils.DateFormat),
+		Symbols: []string{"INR", "EUR", "JPY"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, until.Format(utils.DateFormat), diff.Until)
+	require.Len(t, diff.Changes, 2)
+	assert.Equal(t, "EUR", diff.Changes[1].Quote)
+	assert.Equal(t, []string{"JPY"}, diff.Unchanged)
+
+	_, err = service.GetRateDiff(context.Background(), &models.RateDiffRequest{Base: "USD", Since: until.Format(utils.DateFormat), Until: since.Format(utils.DateFormat)})
+	code, field := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeDateOutOfRange, code)
+	assert.Equal(t, "until", field)
+
+	_, err = service.GetRateDiff(context.Background(), &models.RateDiffRequest{Base: "USD", Since: "yesterday"})
+	code, field = models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeDateInvalid, code)
+	assert.Equal(t, "since", field)
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+)
+
+const (
+	// maxRecentDiscrepancies bounds the alerts kept for the stats endpoint
+	maxRecentDiscrepancies = 50
+	webhookTimeout         = 5 * time.Second
+
+	defaultDiscrepancyTemplate = `{{.Pair}} quotes diverge by {{printf "%.2f" .Observed}}% (threshold {{printf "%.2f" .Threshold}}%): {{.Rates}}`
+)
+
+// DiscrepancyConfig sets up the periodic comparison of providers' quotes
+type DiscrepancyConfig struct {
+	Providers        []string      // Providers compared; the check is off with fewer than two
+	Pairs            []string      // Pairs compared, as FROM_TO
+	ThresholdPercent float64       // Spread between the highest and lowest quote that raises an alert
+	Interval         time.Duration // Time between comparisons, 0 disables
+	WebhookURL       string        // Optional URL every alert is POSTed to as JSON
+	Alerts           AlertConfig   // Further channels alerts are sent through
+}
+
+// DefaultDiscrepancyConfig compares USD against the default currencies every
+// hour and alerts on a spread above 1%. No providers are compared until
+// configured.
+func DefaultDiscrepancyConfig() DiscrepancyConfig {
+	var pairs []string
+	for _, code := range models.DefaultCurrencies {
+		if code != "USD" {
+			pairs = append(pairs, "USD_"+code)
+		}
+	}
+	return DiscrepancyConfig{
+		Pairs:            pairs,
+		ThresholdPercent: 1,
+		Interval:         time.Hour,
+	}
+}
+
+// DiscrepancyMonitor periodically fetches the configured pairs from several
+// providers and raises an alert when their quotes diverge, guarding against
+// a bad upstream feed. Alerts are logged, counted, kept for the stats
+// endpoint and optionally sent through the configured channels.
+type DiscrepancyMonitor struct {
+	client  *external.ExchangeRateClient
+	cfg     DiscrepancyConfig
+	alerter *alerter
+
+	mu          sync.Mutex
+	checks      int64
+	comparisons int64
+	alerts      int64
+	lastCheck   time.Time
+	recent      []models.RateDiscrepancy // Newest first
+	isRunning   bool
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+func NewDiscrepancyMonitor(client *external.ExchangeRateClient, cfg DiscrepancyConfig) *DiscrepancyMonitor {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	alerts := cfg.Alerts
+	if cfg.WebhookURL != "" {
+		alerts.Channels = append([]AlertChannel{{Kind: AlertChannelWebhook, Target: cfg.WebhookURL}}, alerts.Channels...)
+	}
+
+	return &DiscrepancyMonitor{
+		client:  client,
+		cfg:     cfg,
+		alerter: newAlerter("discrepancy", alerts, defaultDiscrepancyTemplate),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Enabled reports whether there is anything to compare
+func (m *DiscrepancyMonitor) Enabled() bool {
+	return len(m.cfg.Providers) >= 2 && m.cfg.Interval > 0 && len(m.cfg.Pairs) > 0
+}
+
+func (m *DiscrepancyMonitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.isRunning || !m.Enabled() {
+		return
+	}
+	m.isRunning = true
+
+	log.Printf("Comparing %s quotes every %v (threshold %.2f%%)", strings.Join(m.cfg.Providers, ", "), m.cfg.Interval, m.cfg.ThresholdPercent)
+	go m.run()
+}
+
+func (m *DiscrepancyMonitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.isRunning {
+		return
+	}
+	m.cancel()
+	m.isRunning = false
+}
+
+func (m *DiscrepancyMonitor) run() {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			log.Println("Discrepancy monitor stopped")
+			return
+		case <-ticker.C:
+			m.Check(time.Now())
+		}
+	}
+}
+
+// Check compares the providers' current quotes of every configured pair and
+// returns the discrepancies found. Each base currency is fetched once per
+// provider; providers that fail are left out of the comparison.
+func (m *DiscrepancyMonitor) Check(now time.Time) []models.RateDiscrepancy {
+	bases := make(map[string][]string) // base -> quote currencies
+	var order []string
+	for _, pair := range m.cfg.Pairs {
+		from, to, ok := strings.Cut(pair, "_")
+		if !ok {
+			continue
+		}
+		if _, seen := bases[from]; !seen {
+			order = append(order, from)
+		}
+		bases[from] = append(bases[from], to)
+	}
+
+	var found []models.RateDiscrepancy
+	var comparisons int64
+	for _, base := range order {
+		quotes := make(map[string]map[string]float64) // quote currency -> provider -> rate
+		for _, provider := range m.cfg.Providers {
+			response, err := m.client.GetLatestRatesFrom(m.ctx, provider, base)
+			if err != nil {
+				log.Printf("Discrepancy check could not fetch %s rates from %s: %v", base, provider, err)
+				continue
+			}
+			for _, to := range bases[base] {
+				if rate, ok := response.Rates[to]; ok && rate > 0 {
+					if quotes[to] == nil {
+						quotes[to] = make(map[string]float64)
+					}
+					quotes[to][provider] = rate
+				}
+			}
+		}
+
+		for _, to := range bases[base] {
+			if len(quotes[to]) < 2 {
+				continue
+			}
+			comparisons++
+
+			spread := quoteSpread(quotes[to])
+			if spread <= m.cfg.ThresholdPercent {
+				continue
+			}
+			found = append(found, models.RateDiscrepancy{
+				From:             base,
+				To:               to,
+				Quotes:           quotes[to],
+				SpreadPercent:    spread,
+				ThresholdPercent: m.cfg.ThresholdPercent,
+				DetectedAt:       now,
+			})
+		}
+	}
+
+	m.record(now, comparisons, found)
+	for _, discrepancy := range found {
+		log.Printf("WARNING: %s/%s quotes diverge by %.2f%% (threshold %.2f%%): %s",
+			discrepancy.From, discrepancy.To, discrepancy.SpreadPercent, discrepancy.ThresholdPercent, formatQuotes(discrepancy.Quotes))
+		if m.alerter.enabled() {
+			m.alerter.send(m.ctx, AlertMessage{
+				Subject:   fmt.Sprintf("%s/%s quotes diverge by %.2f%%", discrepancy.From, discrepancy.To, discrepancy.SpreadPercent),
+				Pair:      discrepancy.From + "/" + discrepancy.To,
+				Threshold: discrepancy.ThresholdPercent,
+				Observed:  discrepancy.SpreadPercent,
+				Rates:     formatQuotes(discrepancy.Quotes),
+				At:        discrepancy.DetectedAt,
+			}, discrepancy)
+		}
+	}
+	return found
+}
+
+func (m *DiscrepancyMonitor) record(now time.Time, comparisons int64, found []models.RateDiscrepancy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.checks++
+	m.comparisons += comparisons
+	m.alerts += int64(len(found))
+	m.lastCheck = now
+	for _, discrepancy := range found {
+		m.recent = append([]models.RateDiscrepancy{discrepancy}, m.recent...)
+	}
+	if len(m.recent) > maxRecentDiscrepancies {
+		m.recent = m.recent[:maxRecentDiscrepancies]
+	}
+}
+
+// Stats returns the comparison counters and the most recent alerts
+func (m *DiscrepancyMonitor) Stats() models.DiscrepancyStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := models.DiscrepancyStats{
+		Enabled:          m.Enabled(),
+		Providers:        append([]string{}, m.cfg.Providers...),
+		ThresholdPercent: m.cfg.ThresholdPercent,
+		Checks:           m.checks,
+		Comparisons:      m.comparisons,
+		Alerts:           m.alerts,
+		Recent:           append([]models.RateDiscrepancy{}, m.recent...),
+	}
+	if !m.lastCheck.IsZero() {
+		lastCheck := m.lastCheck
+		stats.LastCheck = &lastCheck
+	}
+	return stats
+}
+
+// quoteSpread returns how far apart the highest and lowest quote are, in
+// percent of the lowest
+func quoteSpread(quotes map[string]float64) float64 {
+	lowest, highest := 0.0, 0.0
+	for _, rate := range quotes {
+		if lowest == 0 || rate < lowest {
+			lowest = rate
+		}
+		if rate > highest {
+			highest = rate
+		}
+	}
+	return (highest - lowest) / lowest * 100
+}
+
+// formatQuotes renders quotes as "provider=rate" in provider order
+func formatQuotes(quotes map[string]float64) string {
+	providers := make([]string, 0, len(quotes))
+	for provider := range quotes {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	parts := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		parts = append(parts, fmt.Sprintf("%s=%g", provider, quotes[provider]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+)
+
+func TestDiscrepancyMonitor_Check(t *testing.T) {
+	erapi := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"base":"USD","rates":{"INR":83.0,"EUR":0.92}}`))
+	}))
+	defer erapi.Close()
+	frankfurter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"base":"USD","date":"2025-01-03","rates":{"INR":85.0,"EUR":0.921}}`))
+	}))
+	defer frankfurter.Close()
+
+	alerts := make(chan models.RateDiscrepancy, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var discrepancy models.RateDiscrepancy
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&discrepancy))
+		alerts <- discrepancy
+	}))
+	defer webhook.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = erapi.URL
+	cfg.FrankfurterBaseURL = frankfurter.URL
+	monitor := NewDiscrepancyMonitor(external.NewExchangeRateClientWithConfig(cfg), DiscrepancyConfig{
+		Providers:        []string{external.ProviderExchangeRateAPI, external.ProviderFrankfurter},
+		Pairs:            []string{"USD_INR", "USD_EUR"},
+		ThresholdPercent: 1,
+		Interval:         time.Hour,
+		WebhookURL:       webhook.URL,
+	})
+	require.True(t, monitor.Enabled())
+
+	now := time.Date(2025, 1, 3, 12, 0, 0, 0, time.UTC)
+	found := monitor.Check(now)
+	require.Len(t, found, 1, "only USD/INR diverges by more than 1%")
+	assert.Equal(t, "INR", found[0].To)
+	assert.InDelta(t, 2.41, found[0].SpreadPercent, 0.01)
+	assert.Equal(t, 85.0, found[0].Quotes[external.ProviderFrankfurter])
+
+	select {
+	case discrepancy := <-alerts:
+		assert.Equal(t, "USD", discrepancy.From)
+		assert.Equal(t, "INR", discrepancy.To)
+	default:
+		t.Fatal("expected the discrepancy to be posted to the webhook")
+	}
+
+	stats := monitor.Stats()
+	assert.Equal(t, int64(1), stats.Checks)
+	assert.Equal(t, int64(2), stats.Comparisons)
+	assert.Equal(t, int64(1), stats.Alerts)
+	require.NotNil(t, stats.LastCheck)
+	assert.Equal(t, now, *stats.LastCheck)
+	assert.Len(t, stats.Recent, 1)
+}
+
+func TestDiscrepancyMonitor_SkipsFailingProviders(t *testing.T) {
+	erapi := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"base":"USD","rates":{"INR":83.0}}`))
+	}))
+	defer erapi.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = erapi.URL
+	cfg.FrankfurterBaseURL = "http://127.0.0.1:1"
+	cfg.Retry.MaxAttempts = 1
+	monitor := NewDiscrepancyMonitor(external.NewExchangeRateClientWithConfig(cfg), DiscrepancyConfig{
+		Providers:        []string{external.ProviderExchangeRateAPI, external.ProviderFrankfurter},
+		Pairs:            []string{"USD_INR"},
+		ThresholdPercent: 1,
+		Interval:         time.Hour,
+	})
+
+	assert.Empty(t, monitor.Check(time.Now()))
+	assert.Equal(t, int64(0), monitor.Stats().Comparisons, "a single quote is not compared")
+}
+
+func TestDiscrepancyMonitor_Enabled(t *testing.T) {
+	cfg := DefaultDiscrepancyConfig()
+	assert.False(t, NewDiscrepancyMonitor(nil, cfg).Enabled(), "no providers configured")
+
+	cfg.Providers = []string{external.ProviderExchangeRateAPI, external.ProviderFrankfurter}
+	assert.True(t, NewDiscrepancyMonitor(nil, cfg).Enabled())
+
+	cfg.Interval = 0
+	assert.False(t, NewDiscrepancyMonitor(nil, cfg).Enabled())
+}
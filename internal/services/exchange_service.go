@@ -1,12 +1,26 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"golang.org/x/text/language"
+
+	"exchange-rate-service/internal/apperrors"
 	"exchange-rate-service/internal/cache"
 	"exchange-rate-service/internal/external"
 	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/signing"
+	"exchange-rate-service/internal/store"
 	"exchange-rate-service/internal/utils"
 )
 
@@ -14,8 +28,31 @@ type ExchangeService struct {
 	cache       cache.CacheInterface
 	rateFetcher *RateFetcher
 	client      *external.ExchangeRateClient
+
+	mu      sync.RWMutex
+	markup  *Markup
+	archive *store.Archive
+	history *store.RateHistory
+	audit   *store.AuditLog
+	monitor *DiscrepancyMonitor
+	shadow  *ShadowTraffic
+	signer  *signing.Signer
+
+	bulkConcurrency int         // Pairs of a bulk historical request looked up at once
+	draining        atomic.Bool // Set on shutdown, failing readiness while requests drain
+
+	probeMu sync.Mutex
+	probe   models.HealthCheck
+	probeOK time.Time
 }
 
+// providerProbeInterval bounds how often health checks probe the provider
+const providerProbeInterval = 30 * time.Second
+
+// ErrNoProviderSelection is returned for requests naming a provider when the
+// service was built without a client to call it
+var ErrNoProviderSelection = apperrors.ErrNotImplemented.Variant("provider selection is not available")
+
 func NewExchangeService(cache cache.CacheInterface, rateFetcher *RateFetcher, client *external.ExchangeRateClient) *ExchangeService {
 	return &ExchangeService{
 		cache:       cache,
@@ -24,139 +61,1205 @@ func NewExchangeService(cache cache.CacheInterface, rateFetcher *RateFetcher, cl
 	}
 }
 
-func (s *ExchangeService) ConvertCurrency(req *models.ConversionRequest) (*models.ConversionResponse, error) {
+// SetMarkup replaces the spread applied to conversions
+func (s *ExchangeService) SetMarkup(markup *Markup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.markup = markup
+}
+
+func (s *ExchangeService) getMarkup() *Markup {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.markup
+}
+
+// markupFor returns the markup of the tenant of ctx, or the service's when
+// the tenant has none
+func (s *ExchangeService) markupFor(ctx context.Context) *Markup {
+	if tenant := TenantFromContext(ctx); tenant != nil && tenant.Markup != nil {
+		return tenant.Markup
+	}
+	return s.getMarkup()
+}
+
+// SetArchive sets the end-of-day snapshot archive consulted for historical
+// rates before the upstream provider
+func (s *ExchangeService) SetArchive(archive *store.Archive) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.archive = archive
+}
+
+// SetRateHistory sets the intraday rates consulted by conversions at a
+// timestamp
+func (s *ExchangeService) SetRateHistory(history *store.RateHistory) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = history
+}
+
+func (s *ExchangeService) getRateHistory() *store.RateHistory {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.history
+}
+
+// SetAuditLog sets the log every conversion is recorded in
+func (s *ExchangeService) SetAuditLog(audit *store.AuditLog) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audit = audit
+}
+
+func (s *ExchangeService) getAuditLog() *store.AuditLog {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.audit
+}
+
+// SetSigner sets the key latest rates and conversions are signed with; nil
+// leaves them unsigned
+func (s *ExchangeService) SetSigner(signer *signing.Signer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signer = signer
+}
+
+func (s *ExchangeService) getSigner() *signing.Signer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.signer
+}
+
+// GetSigningKeys returns the public keys responses are signed with, none
+// when signing is off
+func (s *ExchangeService) GetSigningKeys() []models.PublicKey {
+	signer := s.getSigner()
+	if signer == nil {
+		return []models.PublicKey{}
+	}
+	return []models.PublicKey{signer.PublicKey()}
+}
+
+// signRate signs the canonical payload of a quoted rate, adding when the
+// rate was fetched if known. It returns nil when signing is off.
+func (s *ExchangeService) signRate(fields url.Values, source models.Source) *models.Signature {
+	signer := s.getSigner()
+	if signer == nil {
+		return nil
+	}
+	if source.FetchedAt != nil {
+		fields.Set("fetched_at", signing.FormatTime(*source.FetchedAt))
+	}
+	return signer.Sign(fields, time.Now())
+}
+
+// RecordConversion writes a conversion quoted to caller to the audit log,
+// with the values its amounts were rounded from, and sets its ConversionID
+// to a receipt ID the conversion can be retrieved with. Without an audit log
+// it does nothing. A conversion that couldn't be recorded gets no ID and
+// fails with INTERNAL_ERROR, as it must not be quoted unaudited.
+func (s *ExchangeService) RecordConversion(caller string, conversion *models.ConversionResponse) error {
+	audit := s.getAuditLog()
+	if audit == nil {
+		return nil
+	}
+
+	id, err := newConversionID()
+	if err != nil {
+		return auditFailed(err)
+	}
+	conversion.ConversionID = id
+	result, err := json.Marshal(conversion)
+	if err != nil {
+		conversion.ConversionID = ""
+		return auditFailed(fmt.Errorf("failed to encode conversion: %w", err))
+	}
+
+	_, err = audit.Append(store.ConversionRecord{
+		Timestamp:       time.Now().UTC(),
+		Caller:          caller,
+		From:            conversion.From,
+		To:              conversion.To,
+		Amount:          conversion.Amount,
+		ConvertedAmount: conversion.ConvertedAmount,
+		Rate:            conversion.Rate,
+		MidMarketRate:   conversion.MidMarketRate,
+		MarkupPercent:   conversion.MarkupPercent,
+		Derived:         conversion.Derived,
+		RateDate:        conversion.RateDate,
+		RateTimestamp:   conversion.RateTimestamp,
+		Trail:           roundingTrail(conversion),
+		ConversionID:    id,
+		Result:          result,
+	})
+	if err != nil {
+		conversion.ConversionID = ""
+		return auditFailed(err)
+	}
+	return nil
+}
+
+// RecordChainConversion writes a conversion chain quoted to caller to the
+// audit log as one record from its first to its last currency, with every
+// leg, and sets its ConversionID and fails like RecordConversion does.
+func (s *ExchangeService) RecordChainConversion(caller string, chain *models.ChainConversionResponse) error {
+	audit := s.getAuditLog()
+	if audit == nil {
+		return nil
+	}
+
+	id, err := newConversionID()
+	if err != nil {
+		return auditFailed(err)
+	}
+	chain.ConversionID = id
+	result, err := json.Marshal(chain)
+	if err != nil {
+		chain.ConversionID = ""
+		return auditFailed(fmt.Errorf("failed to encode conversion chain: %w", err))
+	}
+
+	midMarketRate := 1.0
+	legs := make([]store.ChainLegRecord, len(chain.Legs))
+	for i, leg := range chain.Legs {
+		midMarketRate *= leg.MidMarketRate
+		legs[i] = store.ChainLegRecord{
+			From:            leg.From,
+			To:              leg.To,
+			Amount:          leg.Amount,
+			ConvertedAmount: leg.ConvertedAmount,
+			Rate:            leg.Rate,
+			MidMarketRate:   leg.MidMarketRate,
+			MarkupPercent:   leg.MarkupPercent,
+			Derived:         leg.Derived,
+		}
+	}
+
+	_, err = audit.Append(store.ConversionRecord{
+		Timestamp:       time.Now().UTC(),
+		Caller:          caller,
+		From:            chain.From,
+		To:              chain.To,
+		Amount:          chain.Amount,
+		ConvertedAmount: chain.ConvertedAmount,
+		Rate:            chain.Rate,
+		MidMarketRate:   midMarketRate,
+		Legs:            legs,
+		ConversionID:    id,
+		Result:          result,
+	})
+	if err != nil {
+		chain.ConversionID = ""
+		return auditFailed(err)
+	}
+	return nil
+}
+
+// auditFailed reports that a conversion could not be recorded, keeping the
+// cause for logs out of the message returned to the caller
+func auditFailed(err error) error {
+	return &models.CodedError{Code: models.ErrCodeInternal, Message: "the conversion could not be recorded", Err: err}
+}
+
+func newConversionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate conversion ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// roundingTrail returns the values a conversion's amounts were rounded from
+func roundingTrail(conversion *models.ConversionResponse) *store.RoundingTrail {
+	trail := &store.RoundingTrail{
+		RawRate:         conversion.MidMarketRate,
+		UnroundedRate:   conversion.UnroundedRate,
+		UnroundedAmount: conversion.UnroundedAmount,
+		Precision:       conversion.Precision,
+		Rounding:        conversion.Rounding,
+		RatePrecision:   models.RatePrecision,
+	}
+	if fees := conversion.Fees; fees != nil {
+		trail.FeePercent = fees.FeePercent
+		trail.FeeFixed = fees.FeeFixed
+		trail.UnroundedFee = unroundedFee(conversion.ConvertedAmount, fees.FeePercent, fees.FeeFixed)
+		trail.FeeAmount = fees.FeeAmount
+		trail.NetAmount = fees.NetAmount
+	}
+	return trail
+}
+
+// GetConversion returns the audited conversion with the given ID
+func (s *ExchangeService) GetConversion(id int64) (*store.ConversionRecord, error) {
+	audit := s.getAuditLog()
+	if audit == nil {
+		return nil, models.NewError(models.ErrCodeNotFound, "conversion auditing is not enabled")
+	}
+
+	record, ok, err := audit.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.NewError(models.ErrCodeNotFound, "conversion %d not found", id)
+	}
+	return &record, nil
+}
+
+// GetConversionReceipt returns the audited conversion the receipt ID
+// conversionID was returned for
+func (s *ExchangeService) GetConversionReceipt(conversionID string) (*store.ConversionRecord, error) {
+	audit := s.getAuditLog()
+	if audit == nil {
+		return nil, models.NewError(models.ErrCodeNotFound, "conversion auditing is not enabled")
+	}
+
+	record, ok, err := audit.GetReceipt(conversionID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.NewError(models.ErrCodeNotFound, "conversion %s not found", conversionID)
+	}
+	return &record, nil
+}
+
+// QueryConversions returns audited conversions matching filter, newest first,
+// and the total number of matches
+func (s *ExchangeService) QueryConversions(filter store.AuditFilter) ([]store.ConversionRecord, int, error) {
+	audit := s.getAuditLog()
+	if audit == nil {
+		return nil, 0, models.NewError(models.ErrCodeNotFound, "conversion auditing is not enabled")
+	}
+
+	return audit.Query(filter)
+}
+
+func (s *ExchangeService) getArchive() *store.Archive {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.archive
+}
+
+func (s *ExchangeService) ConvertCurrency(ctx context.Context, req *models.ConversionRequest) (*models.ConversionResponse, error) {
 	if err := utils.ValidateConversionRequest(req); err != nil {
 		return nil, err
 	}
+	if err := TenantFromContext(ctx).checkPair(req.From, req.To); err != nil {
+		return nil, err
+	}
 
-	var conversionDate time.Time
-	var err error
-	if req.Date != "" {
-		conversionDate, err = utils.ValidateDate(req.Date)
-		if err != nil {
+	var locale language.Tag
+	if req.Locale != "" {
+		var err error
+		if locale, err = utils.ParseLocale(req.Locale); err != nil {
 			return nil, err
 		}
-	} else {
+	}
+
+	if req.Date != "" && req.Timestamp != "" {
+		return nil, models.NewFieldError(models.ErrCodeInvalidRequest, "timestamp", "date and timestamp cannot both be set")
+	}
+
+	provider, err := s.resolveProvider(req.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	var conversionDate time.Time
+	switch {
+	case req.Timestamp != "":
+		if conversionDate, err = utils.ValidateTimestamp(req.Timestamp); err != nil {
+			err = models.ForField(err, "timestamp", "")
+		}
+	case req.Date != "":
+		conversionDate, err = utils.ValidateDate(req.Date)
+	default:
 		conversionDate = time.Now()
 	}
+	if err != nil {
+		return nil, err
+	}
 
-	var rate float64
-	if req.Date != "" {
-		rate, err = s.getHistoricalRate(req.From, req.To, req.Date)
-	} else {
-		rate, err = s.getLatestRate(req.From, req.To)
+	var quote rateQuote
+	var rateDate string
+	var rateTimestamp *time.Time
+	var marketClosed bool
+	// Intraday history only holds the default provider's rates
+	if req.Timestamp != "" && provider == "" {
+		if intraday, found := s.getIntradayRate(req.From, req.To, conversionDate); found {
+			quote = intraday
+			publishedAt := intraday.fetchedAt
+			rateTimestamp = &publishedAt
+		}
+	}
+	switch {
+	case rateTimestamp != nil:
+	case req.Date != "" || req.Timestamp != "":
+		// Without an intraday rate use the day's rate; markets publish no
+		// rates on weekends and holidays, so that is the last trading day's
+		local := conversionDate.In(utils.ReferenceLocation())
+		rateDate = utils.LastTradingDay(local, req.From, req.To).Format(utils.DateFormat)
+		marketClosed = rateDate != local.Format(utils.DateFormat)
+		if err = strictHistorical(req, local, rateDate, marketClosed); err == nil {
+			quote, err = s.getHistoricalRate(ctx, req.From, req.To, rateDate, provider)
+		}
+	default:
+		if quote, err = s.getLatestRate(ctx, req.From, req.To, provider); err == nil && provider == "" {
+			s.getShadow().Mirror(req.From, req.To, quote.rate, quote.provider)
+		}
+		if err == nil && req.Strict && quote.stale {
+			err = models.NewError(models.ErrCodeProviderUnavailable, "the provider is unreachable and strict conversions don't use the stale %s/%s rate fetched at %s", req.From, req.To, quote.fetchedAt.UTC().Format(time.RFC3339))
+		}
 	}
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get exchange rate: %w", err)
 	}
 
-	convertedAmount := req.Amount * rate
+	appliedRate, markupPercent := s.markupFor(ctx).Apply(req.From, req.To, quote.rate)
+	unroundedAmount := req.Amount * appliedRate
+
+	precision := utils.CurrencyPrecision(req.To)
+	if req.Precision != nil {
+		precision = *req.Precision
+	}
+	rounding := req.Rounding
+	if rounding == "" {
+		rounding = models.RoundingHalfUp
+	}
+	convertedAmount := utils.Round(unroundedAmount, precision, rounding)
+	rate := utils.Round(appliedRate, models.RatePrecision, rounding)
+
+	var fees *models.ConversionFees
+	if req.FeePercent != 0 || req.FeeFixed != 0 {
+		if fees, err = conversionFees(req, quote.rate, convertedAmount, precision, rounding); err != nil {
+			return nil, err
+		}
+	}
+
+	var formatted *models.FormattedConversion
+	if req.Locale != "" {
+		formatted = &models.FormattedConversion{
+			Locale:          locale.String(),
+			Amount:          utils.FormatMoney(locale, req.Amount, req.From),
+			ConvertedAmount: utils.FormatMoney(locale, convertedAmount, req.To),
+			Rate:            utils.FormatRate(locale, rate),
+		}
+	}
 
-	return &models.ConversionResponse{
+	resp := &models.ConversionResponse{
 		From:            req.From,
 		To:              req.To,
 		Amount:          req.Amount,
 		ConvertedAmount: convertedAmount,
 		Rate:            rate,
+		MidMarketRate:   quote.rate,
+		MarkupPercent:   markupPercent,
+		Precision:       precision,
+		Rounding:        rounding,
+		UnroundedRate:   appliedRate,
+		UnroundedAmount: unroundedAmount,
+		Derived:         quote.derived,
 		Date:            conversionDate,
+		RateDate:        rateDate,
+		MarketClosed:    marketClosed,
+		RateTimestamp:   rateTimestamp,
+		Formatted:       formatted,
+		Fees:            fees,
+		Freshness:       quote.freshness(),
+		Source:          quote.source(),
+	}
+	signed := url.Values{
+		"from":             {resp.From},
+		"to":               {resp.To},
+		"amount":           {signing.FormatFloat(resp.Amount)},
+		"converted_amount": {signing.FormatFloat(resp.ConvertedAmount)},
+		"rate":             {signing.FormatFloat(resp.Rate)},
+		"mid_market_rate":  {signing.FormatFloat(resp.MidMarketRate)},
+		"date":             {signing.FormatTime(resp.Date)},
+	}
+	if fees != nil {
+		signed.Set("fee_amount", signing.FormatFloat(fees.FeeAmount))
+		signed.Set("net_amount", signing.FormatFloat(fees.NetAmount))
+	}
+	resp.Signature = s.signRate(signed, resp.Source)
+	return resp, nil
+}
+
+// conversionFees itemizes the fees of a conversion. The fee is charged on
+// the converted amount, markup included, and may not exceed it.
+func conversionFees(req *models.ConversionRequest, midMarketRate, convertedAmount float64, precision int, rounding string) (*models.ConversionFees, error) {
+	feeAmount := utils.Round(unroundedFee(convertedAmount, req.FeePercent, req.FeeFixed), precision, rounding)
+	if feeAmount > convertedAmount {
+		return nil, models.NewFieldError(models.ErrCodeValueInvalid, "fee_fixed",
+			"fees of %v %s exceed the converted amount of %v %s", feeAmount, req.To, convertedAmount, req.To)
+	}
+	return &models.ConversionFees{
+		FeePercent:      req.FeePercent,
+		FeeFixed:        req.FeeFixed,
+		MidMarketAmount: utils.Round(req.Amount*midMarketRate, precision, rounding),
+		FeeAmount:       feeAmount,
+		NetAmount:       utils.Round(convertedAmount-feeAmount, precision, rounding),
 	}, nil
 }
 
-func (s *ExchangeService) GetLatestRate(from, to string) (float64, error) {
-	if err := utils.ValidateCurrencyPair(from, to); err != nil {
-		return 0, err
+// unroundedFee returns the fee of a conversion before rounding
+func unroundedFee(convertedAmount, feePercent, feeFixed float64) float64 {
+	return convertedAmount*feePercent/100 + feeFixed
+}
+
+// GetLatestRate returns the latest rate of a pair from provider, or from the
+// default provider when provider is empty
+func (s *ExchangeService) GetLatestRate(ctx context.Context, from, to, provider string) (*models.LatestRateResponse, error) {
+	if err := validatePair(ctx, &from, &to); err != nil {
+		return nil, err
+	}
+
+	provider, err := s.resolveProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := s.getLatestRate(ctx, from, to, provider)
+	if err != nil {
+		return nil, err
+	}
+	if provider == "" {
+		s.getShadow().Mirror(from, to, quote.rate, quote.provider)
 	}
 
-	return s.getLatestRate(from, to)
+	resp := &models.LatestRateResponse{
+		From:      from,
+		To:        to,
+		Rate:      quote.rate,
+		Derived:   quote.derived,
+		Freshness: quote.freshness(),
+		Source:    quote.source(),
+	}
+	resp.Signature = s.signRate(url.Values{
+		"from": {from},
+		"to":   {to},
+		"rate": {signing.FormatFloat(resp.Rate)},
+	}, resp.Source)
+	return resp, nil
+}
+
+func (s *ExchangeService) GetHistoricalRates(ctx context.Context, req *models.HistoricalRateRequest) (*models.HistoricalRateResponse, error) {
+	return s.getHistoricalRates(ctx, req, latencyDeadline(req.MaxWaitMs))
+}
+
+// getHistoricalRates looks up the dates of req in order. Once deadline, when
+// set, passes the dates not looked up yet are reported as pending and their
+// lookups carry on without the request, filling the cache for a retry.
+// strictHistorical refuses, for a strict conversion, the rate of another
+// day than the one asked for: the last trading day's on a day without
+// trading, or the day's when a timestamp has no intraday rate
+func strictHistorical(req *models.ConversionRequest, local time.Time, rateDate string, marketClosed bool) error {
+	if !req.Strict {
+		return nil
+	}
+	field := "date"
+	if req.Timestamp != "" {
+		field = "timestamp"
+	}
+	if marketClosed {
+		return models.NewFieldError(models.ErrCodeRateNotFound, field, "no %s/%s rate is published on %s, a weekend or market holiday; strict conversions don't fall back to %s", req.From, req.To, local.Format(utils.DateFormat), rateDate)
+	}
+	if req.Timestamp != "" {
+		return models.NewFieldError(models.ErrCodeRateNotFound, field, "no intraday %s/%s rate is kept for %s; strict conversions don't fall back to the rate of %s", req.From, req.To, req.Timestamp, rateDate)
+	}
+	return nil
 }
 
-func (s *ExchangeService) GetHistoricalRates(req *models.HistoricalRateRequest) (*models.HistoricalRateResponse, error) {
+func (s *ExchangeService) getHistoricalRates(ctx context.Context, req *models.HistoricalRateRequest, deadline time.Time) (*models.HistoricalRateResponse, error) {
 	if err := utils.ValidateHistoricalRequest(req); err != nil {
 		return nil, err
 	}
+	if err := TenantFromContext(ctx).checkPair(req.From, req.To); err != nil {
+		return nil, err
+	}
 
 	startDate, endDate, err := utils.ValidateDateRange(req.StartDate, req.EndDate)
 	if err != nil {
 		return nil, err
 	}
 
+	provider, err := s.resolveProvider(req.Provider)
+	if err != nil {
+		return nil, err
+	}
+
 	dates := utils.GetDateRangeList(startDate, endDate)
 	rates := make(map[string]models.HistoricalRate)
+	missing := []models.MissingDate{}
+	var freshness models.Freshness
+	var source models.Source
+
+	// Days without trading carry the previous trading day's rate, which may
+	// be before the range or shared by several days
+	type observation struct {
+		quote rateQuote
+		err   error
+	}
+	lookupCtx := ctx
+	if !deadline.IsZero() {
+		lookupCtx = context.WithoutCancel(ctx)
+	}
+	found := make(chan observation, len(dates))
+	go func() {
+		observed := make(map[string]observation)
+		for _, dateStr := range dates {
+			parsedDate, _ := time.Parse(utils.DateFormat, dateStr)
+			observedDate := utils.LastTradingDay(parsedDate, req.From, req.To).Format(utils.DateFormat)
+
+			result, done := observed[observedDate]
+			if !done {
+				result.quote, result.err = s.getHistoricalRate(lookupCtx, req.From, req.To, observedDate, provider)
+				observed[observedDate] = result
+			}
+			found <- result
+			if result.err != nil && lookupCtx.Err() != nil {
+				return
+			}
+		}
+	}()
 
-	for _, dateStr := range dates {
-		rate, err := s.getHistoricalRate(req.From, req.To, dateStr)
-		if err != nil {
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+collect:
+	for i, dateStr := range dates {
+		var result observation
+		select {
+		case result = <-found:
+		case <-expired:
+			missing = append(missing, pendingDates(dates[i:])...)
+			break collect
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if result.err != nil && ctx.Err() != nil {
+			// The request is over; the remaining days were not looked up
+			return nil, result.err
+		}
+		if result.err != nil {
+			missing = append(missing, missingDate(dateStr, result.err))
 			continue
 		}
 
 		parsedDate, _ := time.Parse(utils.DateFormat, dateStr)
-		rates[dateStr] = models.HistoricalRate{
-			Rate: rate,
-			Date: parsedDate,
+		observedDate := utils.LastTradingDay(parsedDate, req.From, req.To).Format(utils.DateFormat)
+		quote := result.quote
+		rate := models.HistoricalRate{
+			Rate:    quote.rate,
+			Date:    parsedDate,
+			Derived: quote.derived,
+		}
+		if observedDate != dateStr {
+			rate.ObservedDate = observedDate
 		}
+		rates[dateStr] = rate
+
+		freshness.Merge(quote.freshness())
+		source.Merge(quote.source())
 	}
 
 	return &models.HistoricalRateResponse{
-		From:  req.From,
-		To:    req.To,
-		Rates: rates,
+		From:         req.From,
+		To:           req.To,
+		Rates:        rates,
+		Freshness:    freshness,
+		Source:       source,
+		MissingDates: missing,
 	}, nil
 }
 
-func (s *ExchangeService) getLatestRate(from, to string) (float64, error) {
+// missingDate tells apart the reasons a day of a range has no rate, so
+// clients can distinguish a weekend gap from an outage
+func missingDate(date string, err error) models.MissingDate {
+	switch code, _ := models.ErrorCodeOf(err); code {
+	case models.ErrCodeRateNotFound:
+		return models.MissingDate{Date: date, Reason: models.MissingNoData}
+	case models.ErrCodeNotImplemented, models.ErrCodeDateOutOfRange:
+		return models.MissingDate{Date: date, Reason: models.MissingOutOfRange, Error: err.Error()}
+	}
+	return models.MissingDate{Date: date, Reason: models.MissingProviderError, Error: err.Error()}
+}
+
+// GetRateTable returns the cached rate of every supported currency against
+// base, or the full matrix of every pair when base is empty, limited to the
+// currencies of the tenant of ctx. It never calls the provider: pairs that
+// are not cached are reported as missing.
+func (s *ExchangeService) GetRateTable(ctx context.Context, base string) (*models.RateTableResponse, error) {
+	tenant := TenantFromContext(ctx)
+	if base != "" {
+		base = models.CanonicalCurrency(base)
+		if err := utils.ValidateCurrency(base); err != nil {
+			return nil, models.ForField(err, "base", "")
+		}
+		if err := tenant.checkCurrency(base); err != nil {
+			return nil, models.ForField(err, "base", "")
+		}
+	}
+
+	currencies := tenant.FilterCurrencies(s.GetSupportedCurrencies())
+	bases := currencies
+	if base != "" {
+		bases = []string{base}
+	}
+
+	table := &models.RateTableResponse{Base: base}
+	matrix := make(map[string]map[string]float64, len(bases))
+	for _, from := range bases {
+		row := make(map[string]float64, len(currencies))
+		for _, to := range currencies {
+			if from == to {
+				row[to] = 1.0
+				continue
+			}
+
+			quote, found := s.getCachedRate(from, to, "")
+			if !found {
+				table.Missing = append(table.Missing, from+"_"+to)
+				continue
+			}
+			row[to] = quote.rate
+			table.Freshness.Merge(quote.freshness())
+			table.Source.Merge(quote.source())
+		}
+		matrix[from] = row
+	}
+
+	if base != "" {
+		table.Rates = matrix[base]
+	} else {
+		table.Matrix = matrix
+	}
+	return table, nil
+}
+
+// rateQuote is a resolved rate; derived names how it was computed when the
+// pair itself was not quoted (e.g. models.DerivedInverse) and origin where it
+// was found (e.g. models.OriginCache)
+type rateQuote struct {
+	rate        float64
+	derived     string
+	origin      string
+	fetchedAt   time.Time
+	expiresAt   time.Time
+	provider    string
+	publishedAt time.Time
+	stale       bool // Expired, served because the provider is unreachable
+}
+
+func (q rateQuote) freshness() models.Freshness {
+	return models.Freshness{FetchedAt: q.fetchedAt, ExpiresAt: q.expiresAt}
+}
+
+func (q rateQuote) source() models.Source {
+	source := models.Source{Provider: q.provider, Origin: q.origin, Stale: q.stale}
+	if !q.publishedAt.IsZero() {
+		publishedAt := q.publishedAt
+		source.PublishedAt = &publishedAt
+	}
+	if !q.fetchedAt.IsZero() {
+		fetchedAt := q.fetchedAt
+		source.FetchedAt = &fetchedAt
+	}
+	if q.derived != "" {
+		source.Origin = models.OriginDerived
+	}
+	return source
+}
+
+// resolveProvider turns the provider a request asks for into the one rates
+// are fetched from: empty for the default provider, whose rates are cached,
+// or the canonical name of another one
+func (s *ExchangeService) resolveProvider(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	if s.client == nil {
+		return "", ErrNoProviderSelection
+	}
+
+	provider, err := s.client.Provider(name)
+	if err != nil {
+		return "", &models.CodedError{Code: models.ErrCodeProviderUnknown, Field: "provider", Message: err.Error(), Err: err}
+	}
+	if provider.Name() == s.client.DefaultProvider() {
+		return "", nil
+	}
+	return provider.Name(), nil
+}
+
+// cachedProvider returns the name of the provider a pair's cached rates
+// come from
+func (s *ExchangeService) cachedProvider(from, to string) string {
+	if s.rateFetcher != nil {
+		return s.rateFetcher.cacheProvider(from, to)
+	}
+	if s.client == nil {
+		// The default provider of a default client
+		return external.ProviderExchangeRateAPI
+	}
+	return s.client.DefaultProvider()
+}
+
+// getLatestRate resolves the latest rate of a pair. A pinned provider's
+// rates are not cached, so they are always fetched live.
+func (s *ExchangeService) getLatestRate(ctx context.Context, from, to, provider string) (rateQuote, error) {
 	// Same currency
 	if from == to {
-		return 1.0, nil
+		return rateQuote{rate: 1.0}, nil
 	}
 
-	if rate, found := s.cache.Get(from, to, ""); found {
-		return rate, nil
+	if provider == "" {
+		if s.rateFetcher != nil {
+			s.rateFetcher.RecordAccess(from, to)
+		}
+		if quote, found := s.getCachedRate(from, to, ""); found {
+			return quote, nil
+		}
 	}
 
-	rate, err := s.rateFetcher.FetchRateOnDemand(from, to)
+	item, err := s.rateFetcher.FetchRateOnDemand(ctx, provider, from, to)
 	if err != nil {
-		return 0, fmt.Errorf("failed to fetch rate from API: %w", err)
+		err = upstreamError("failed to fetch rate from API", err)
+		if provider == "" && providerDown(err) {
+			if quote, found := s.getStaleRate(from, to); found {
+				log.Printf("Serving stale %s/%s rate fetched at %s: %v", from, to, quote.fetchedAt.Format(time.RFC3339), err)
+				return quote, nil
+			}
+		}
+		return rateQuote{}, err
 	}
 
-	return rate, nil
+	return quoteFromItem(item, models.OriginLive), nil
 }
 
-func (s *ExchangeService) getHistoricalRate(from, to, date string) (float64, error) {
+// providerDown reports whether a failed fetch means the provider couldn't
+// be reached, rather than that the request or the pair was at fault
+func providerDown(err error) bool {
+	switch code, _ := models.ErrorCodeOf(err); code {
+	case models.ErrCodeProviderUnavailable, models.ErrCodeProviderBusy, models.ErrCodeTimeout:
+		return true
+	}
+	return false
+}
+
+func (s *ExchangeService) getHistoricalRate(ctx context.Context, from, to, date, provider string) (rateQuote, error) {
 	if from == to {
-		return 1.0, nil
+		return rateQuote{rate: 1.0}, nil
 	}
 
-	if rate, found := s.cache.Get(from, to, date); found {
-		return rate, nil
+	if provider == "" {
+		if quote, found := s.getCachedRate(from, to, date); found {
+			return quote, nil
+		}
+
+		if quote, found := s.getArchivedRate(from, to, date); found {
+			return quote, nil
+		}
 	}
 
-	rate, err := s.rateFetcher.FetchHistoricalRateOnDemand(from, to, date)
+	item, err := s.rateFetcher.FetchHistoricalRateOnDemand(ctx, provider, from, to, date)
 	if err != nil {
-		return 0, fmt.Errorf("failed to fetch historical rate from API: %w", err)
+		return rateQuote{}, upstreamError("failed to fetch historical rate from API", err)
+	}
+
+	return quoteFromItem(item, models.OriginLive), nil
+}
+
+// upstreamError classifies a failed fetch by its kind of failure. A fetch
+// cut short by the request's deadline (504) or by the client going away is
+// not the provider's fault. The provider having no rate for the pair is the
+// caller's problem (404). A request refused by the provider's open circuit
+// or the throttle was never sent and can be retried later (503); any other
+// failure is the provider's (502).
+func upstreamError(message string, err error) error {
+	coded := &models.CodedError{Code: models.ErrCodeProviderUnavailable, Message: fmt.Sprintf("%s: %v", message, err), Err: err}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		coded.Code = models.ErrCodeTimeout
+	case errors.Is(err, context.Canceled):
+		coded.Code = models.ErrCodeCanceled
+	case errors.Is(err, apperrors.ErrProviderBusy):
+		coded.Code = models.ErrCodeProviderBusy
+		coded.Retry, _ = external.RetryAfter(err)
+	default:
+		if code, ok := apperrors.CodeOf(err); ok {
+			coded.Code = code
+		}
+	}
+	return coded
+}
+
+// getCachedRate looks up a pair in the cache, deriving it from the fresh
+// reverse pair when only that one is cached
+func (s *ExchangeService) getCachedRate(from, to, date string) (rateQuote, bool) {
+	provider := s.cachedProvider(from, to)
+	if item, found := s.cache.GetItem(provider, from, to, date); found {
+		return quoteFromItem(item, models.OriginCache), true
+	}
+
+	if item, found := s.cache.GetItem(provider, to, from, date); found && item.Rate != 0 {
+		quote := quoteFromItem(item, models.OriginCache)
+		quote.rate = 1 / item.Rate
+		quote.derived = models.DerivedInverse
+		return quote, true
+	}
+
+	return rateQuote{}, false
+}
+
+// getStaleRate looks up an expired pair still within the cache's stale grace
+// window, deriving it from the reverse pair when only that one is kept
+func (s *ExchangeService) getStaleRate(from, to string) (rateQuote, bool) {
+	provider := s.cachedProvider(from, to)
+	if item, found := s.cache.GetStale(provider, from, to, ""); found {
+		quote := quoteFromItem(item, models.OriginCache)
+		quote.stale = true
+		return quote, true
+	}
+
+	if item, found := s.cache.GetStale(provider, to, from, ""); found && item.Rate != 0 {
+		quote := quoteFromItem(item, models.OriginCache)
+		quote.rate = 1 / item.Rate
+		quote.derived = models.DerivedInverse
+		quote.stale = true
+		return quote, true
+	}
+
+	return rateQuote{}, false
+}
+
+// getArchivedRate looks up a pair in the end-of-day snapshot of date,
+// deriving it from the reverse pair when only that one was captured
+func (s *ExchangeService) getArchivedRate(from, to, date string) (rateQuote, bool) {
+	archive := s.getArchive()
+	if archive == nil {
+		return rateQuote{}, false
+	}
+
+	snapshot, found := archive.Get(date)
+	if !found {
+		return rateQuote{}, false
+	}
+
+	if rate, ok := snapshot.Rate(from, to); ok {
+		return rateQuote{rate: rate, origin: models.OriginCache, fetchedAt: snapshot.CapturedAt, provider: snapshot.Provider}, true
+	}
+	if rate, ok := snapshot.Rate(to, from); ok && rate != 0 {
+		return rateQuote{rate: 1 / rate, derived: models.DerivedInverse, origin: models.OriginCache, fetchedAt: snapshot.CapturedAt, provider: snapshot.Provider}, true
+	}
+	return rateQuote{}, false
+}
+
+// maxIntradayGap is how far back from the requested instant an intraday rate
+// may be published; beyond it the day's historical rate is used instead
+const maxIntradayGap = 24 * time.Hour
+
+// getIntradayRate returns the latest rate published at or before at from the
+// intraday history, deriving it from the reverse pair when needed. The
+// quote's fetchedAt is the publication time.
+func (s *ExchangeService) getIntradayRate(from, to string, at time.Time) (rateQuote, bool) {
+	if from == to {
+		return rateQuote{rate: 1.0, fetchedAt: at}, true
+	}
+
+	history := s.getRateHistory()
+	if history == nil {
+		return rateQuote{}, false
 	}
 
-	return rate, nil
+	if observation, found := history.At(from, to, at); found && at.Sub(observation.Timestamp) <= maxIntradayGap {
+		return rateQuote{rate: observation.Rate, origin: models.OriginCache, fetchedAt: observation.Timestamp, provider: s.cachedProvider(from, to), publishedAt: observation.Timestamp}, true
+	}
+	if observation, found := history.At(to, from, at); found && observation.Rate != 0 && at.Sub(observation.Timestamp) <= maxIntradayGap {
+		return rateQuote{
+			rate:        1 / observation.Rate,
+			derived:     models.DerivedInverse,
+			origin:      models.OriginCache,
+			fetchedAt:   observation.Timestamp,
+			provider:    s.cachedProvider(from, to),
+			publishedAt: observation.Timestamp,
+		}, true
+	}
+	return rateQuote{}, false
+}
+
+// quoteFromItem wraps a cached or just fetched rate; rates fetched for a pinned
+// provider are not cached and have no expiry
+func quoteFromItem(item cache.CacheItem, origin string) rateQuote {
+	return rateQuote{
+		rate:        item.Rate,
+		origin:      origin,
+		fetchedAt:   item.StoredAt,
+		expiresAt:   item.ExpiresAt,
+		provider:    item.Provider,
+		publishedAt: item.PublishedAt,
+		derived:     item.Derived,
+	}
 }
 
 func (s *ExchangeService) GetSupportedCurrencies() []string {
-	currencies := make([]string, 0, len(models.SupportedCurrencies))
-	for currency := range models.SupportedCurrencies {
-		currencies = append(currencies, currency)
+	return models.SupportedCurrencyCodes()
+}
+
+// GetCurrencyMetadata returns display metadata for every supported currency,
+// with the amount limits and aliases in effect
+func (s *ExchangeService) GetCurrencyMetadata() []models.CurrencyInfo {
+	codes := s.GetSupportedCurrencies()
+	metadata := make([]models.CurrencyInfo, 0, len(codes))
+	for _, code := range codes {
+		info, ok := models.CurrencyMetadata[code]
+		if !ok {
+			info = models.CurrencyInfo{Code: code, Name: code, Symbol: code, DecimalPlaces: 2, Type: models.CurrencyTypeFiat}
+		}
+		limits := models.AmountLimitsOf(code)
+		info.MinAmount, info.MaxAmount = limits.Min, limits.Max
+		info.Aliases = models.AliasesOf(code)
+		metadata = append(metadata, info)
 	}
-	return currencies
+	return metadata
 }
 
 func (s *ExchangeService) GetCacheStats() map[string]interface{} {
 	return s.rateFetcher.GetCacheStats()
 }
 
+// GetProviderCacheStats returns the cache stats of the rates one provider
+// produced
+func (s *ExchangeService) GetProviderCacheStats(name string) (map[string]interface{}, error) {
+	if s.client == nil {
+		return nil, ErrNoProviderSelection
+	}
+	provider, err := s.client.Provider(name)
+	if err != nil {
+		return nil, &models.CodedError{Code: models.ErrCodeProviderUnknown, Field: "provider", Message: err.Error(), Err: err}
+	}
+	return s.rateFetcher.GetProviderCacheStats(provider.Name()), nil
+}
+
+// GetClientStats returns the upstream client's request and retry counters,
+// the fetch pool's back-pressure counters under fetch_pool and the hot pairs
+// under hot_pairs when refresh intervals are adapted
+func (s *ExchangeService) GetClientStats() map[string]interface{} {
+	stats := s.client.GetStats()
+	if s.rateFetcher != nil {
+		stats["fetch_pool"] = s.rateFetcher.PoolStats()
+		if hot := s.rateFetcher.HotPairStats(); hot != nil {
+			stats["hot_pairs"] = hot
+		}
+	}
+	return stats
+}
+
+// GetProviderStatus returns the last hour's success, error rate, latency and
+// circuit breaker state of every upstream provider
+func (s *ExchangeService) GetProviderStatus() []models.ProviderStatus {
+	return s.client.ProviderStatus()
+}
+
+// SetDiscrepancyMonitor sets the cross-provider comparison reported by
+// GetDiscrepancyStats
+func (s *ExchangeService) SetDiscrepancyMonitor(monitor *DiscrepancyMonitor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.monitor = monitor
+}
+
+// GetDiscrepancyStats returns the cross-provider comparison counters and
+// recent alerts
+func (s *ExchangeService) GetDiscrepancyStats() models.DiscrepancyStats {
+	s.mu.RLock()
+	monitor := s.monitor
+	s.mu.RUnlock()
+
+	if monitor == nil {
+		return models.DiscrepancyStats{Providers: []string{}, Recent: []models.RateDiscrepancy{}}
+	}
+	return monitor.Stats()
+}
+
+// SetShadowTraffic sets the provider a share of the latest rates served is
+// compared with in the background
+func (s *ExchangeService) SetShadowTraffic(shadow *ShadowTraffic) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shadow = shadow
+}
+
+func (s *ExchangeService) getShadow() *ShadowTraffic {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.shadow
+}
+
+// GetShadowStats returns the shadow traffic counters and recent mismatches
+func (s *ExchangeService) GetShadowStats() models.ShadowStats {
+	shadow := s.getShadow()
+	if shadow == nil {
+		return models.ShadowStats{Recent: []models.ShadowComparison{}}
+	}
+	return shadow.Stats()
+}
+
+// ClearCache drops every cached rate
+func (s *ExchangeService) ClearCache() {
+	s.cache.Clear()
+}
+
+// InvalidatePair drops the cached latest and historical rates of a pair and
+// returns how many entries were removed
+func (s *ExchangeService) InvalidatePair(from, to string) (int, error) {
+	if err := utils.ValidateCurrencyPair(from, to); err != nil {
+		return 0, err
+	}
+	return s.cache.DeletePair(from, to), nil
+}
+
+// WarmCache forces a full fetch cycle and reports how many pairs were refreshed
+func (s *ExchangeService) WarmCache(ctx context.Context) map[string]interface{} {
+	start := time.Now()
+	refreshed, failed := s.rateFetcher.FetchNow(ctx)
+
+	return map[string]interface{}{
+		"refreshed":   refreshed,
+		"failed":      failed,
+		"duration_ms": time.Since(start).Milliseconds(),
+		"cache_stats": s.GetCacheStats(),
+	}
+}
+
+func (s *ExchangeService) archivedDays() int {
+	if archive := s.getArchive(); archive != nil {
+		return archive.Len()
+	}
+	return 0
+}
+
 func (s *ExchangeService) GetServiceHealth() map[string]interface{} {
+	status, checks := s.HealthChecks()
+
 	return map[string]interface{}{
-		"status":               "healthy",
+		"status":               status,
+		"checks":               checks,
 		"rate_fetcher":         s.rateFetcher.IsRunning(),
+		"historical_data":      s.client.HasHistoricalData(),
+		"archived_days":        s.archivedDays(),
 		"supported_currencies": s.GetSupportedCurrencies(),
 		"cache_stats":          s.GetCacheStats(),
 		"timestamp":            time.Now().Format(time.RFC3339),
 	}
 }
+
+// HealthChecks checks every dependency and returns the overall status, the
+// worst of the individual ones
+func (s *ExchangeService) HealthChecks() (string, map[string]models.HealthCheck) {
+	now := time.Now()
+	checks := map[string]models.HealthCheck{
+		"rate_fetcher": s.checkRateFetcher(now),
+		"provider":     s.checkProvider(now),
+		"cache":        s.checkCache(now),
+	}
+
+	status := models.HealthStatusHealthy
+	for _, check := range checks {
+		status = models.WorstHealthStatus(status, check.Status)
+	}
+	return status, checks
+}
+
+// IsReady reports whether the service can answer rate queries: it is not
+// draining, the cache is reachable and at least one fetch cycle has
+// succeeded. The reason explains a negative answer.
+func (s *ExchangeService) IsReady() (bool, string) {
+	if s.draining.Load() {
+		return false, "shutting down"
+	}
+	if err := s.cache.Ping(); err != nil {
+		return false, fmt.Sprintf("cache unavailable: %v", err)
+	}
+	if s.rateFetcher.Status().LastSuccess.IsZero() {
+		return false, "waiting for the first successful rate fetch"
+	}
+	return true, ""
+}
+
+// Drain makes the service report not ready from now on, so load balancers
+// stop routing requests to it while the ones in flight complete
+func (s *ExchangeService) Drain() {
+	s.draining.Store(true)
+}
+
+func (s *ExchangeService) checkRateFetcher(now time.Time) models.HealthCheck {
+	status := s.rateFetcher.Status()
+	check := models.HealthCheck{Status: models.HealthStatusHealthy, CheckedAt: now}
+
+	switch {
+	case status.LastSuccess.IsZero():
+		check.Status = models.HealthStatusUnhealthy
+		check.Message = "no successful rate fetch yet"
+		if status.LastError != "" {
+			check.Message += ": " + status.LastError
+		}
+		return check
+	case now.Sub(status.LastSuccess) > 2*s.rateFetcher.FetchInterval():
+		check.Status = models.HealthStatusDegraded
+		check.Message = "rates are stale"
+		if status.LastError != "" {
+			check.Message += ": " + status.LastError
+		}
+	case !s.rateFetcher.IsRunning():
+		check.Status = models.HealthStatusDegraded
+		check.Message = "scheduled fetching is stopped"
+	}
+
+	lastOK := status.LastSuccess
+	check.LastOK = &lastOK
+	return check
+}
+
+// checkProvider probes the upstream provider, reusing the previous result
+// for providerProbeInterval so health polling does not hammer it. An
+// unreachable provider only degrades the service, which can still serve
+// cached rates.
+func (s *ExchangeService) checkProvider(now time.Time) models.HealthCheck {
+	s.probeMu.Lock()
+	defer s.probeMu.Unlock()
+
+	if !s.probe.CheckedAt.IsZero() && now.Sub(s.probe.CheckedAt) < providerProbeInterval {
+		return s.probe
+	}
+
+	check := models.HealthCheck{Status: models.HealthStatusHealthy, CheckedAt: now}
+	if err := s.client.Probe(); err != nil {
+		check.Status = models.HealthStatusDegraded
+		check.Message = err.Error()
+	} else {
+		s.probeOK = now
+	}
+	if !s.probeOK.IsZero() {
+		lastOK := s.probeOK
+		check.LastOK = &lastOK
+	}
+
+	s.probe = check
+	return check
+}
+
+func (s *ExchangeService) checkCache(now time.Time) models.HealthCheck {
+	if err := s.cache.Ping(); err != nil {
+		return models.HealthCheck{Status: models.HealthStatusUnhealthy, Message: err.Error(), CheckedAt: now}
+	}
+	return models.HealthCheck{Status: models.HealthStatusHealthy, CheckedAt: now}
+}
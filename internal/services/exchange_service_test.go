@@ -0,0 +1,663 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/signing"
+	"exchange-rate-service/internal/store"
+	"exchange-rate-service/internal/utils"
+)
+
+func TestExchangeService_InverseFallback(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 80.0)
+	service := NewExchangeService(memoryCache, nil, nil)
+
+	quote, err := service.getLatestRate(context.Background(), "USD", "INR", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 80.0, quote.rate)
+	assert.Empty(t, quote.derived)
+
+	quote, err = service.getLatestRate(context.Background(), "INR", "USD", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0125, quote.rate)
+	assert.Equal(t, models.DerivedInverse, quote.derived)
+}
+
+func TestExchangeService_InverseFallbackHistorical(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "EUR", "USD", "2025-01-02", 1.25)
+	service := NewExchangeService(memoryCache, nil, nil)
+
+	quote, found := service.getCachedRate("USD", "EUR", "2025-01-02")
+	assert.True(t, found)
+	assert.Equal(t, 0.8, quote.rate)
+	assert.Equal(t, models.DerivedInverse, quote.derived)
+
+	_, found = service.getCachedRate("USD", "EUR", "2025-01-03")
+	assert.False(t, found)
+}
+
+func TestExchangeService_ConvertWithMarkup(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 80.0)
+	service := NewExchangeService(memoryCache, nil, nil)
+	service.SetMarkup(NewMarkup(0, map[string]float64{"INR_USD": 2}))
+
+	resp, err := service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "INR", To: "USD", Amount: 800})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0125, resp.MidMarketRate)
+	assert.InDelta(t, 0.01275, resp.Rate, 1e-12)
+	assert.InDelta(t, 10.2, resp.ConvertedAmount, 1e-9)
+	assert.Equal(t, models.DerivedInverse, resp.Derived)
+}
+
+func TestExchangeService_ConvertRounding(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "JPY", "", 156.4255)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 83.12345678)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "EUR", "", 0.5)
+	service := NewExchangeService(memoryCache, nil, nil)
+	precision := func(p int) *int { return &p }
+
+	tests := []struct {
+		name      string
+		req       models.ConversionRequest
+		amount    float64
+		rate      float64
+		precision int
+	}{
+		{"Yen has no minor units", models.ConversionRequest{From: "USD", To: "JPY", Amount: 10}, 1564, 156.4255, 0},
+		{"Rupee to paise", models.ConversionRequest{From: "USD", To: "INR", Amount: 3}, 249.37, 83.123457, 2},
+		{"Explicit precision", models.ConversionRequest{From: "USD", To: "JPY", Amount: 10, Precision: precision(2)}, 1564.26, 156.4255, 2},
+		{"Half up on a half", models.ConversionRequest{From: "USD", To: "EUR", Amount: 2.25}, 1.13, 0.5, 2},
+		{"Bankers on a half", models.ConversionRequest{From: "USD", To: "EUR", Amount: 2.25, Rounding: models.RoundingBankers}, 1.12, 0.5, 2},
+		{"Truncate", models.ConversionRequest{From: "USD", To: "INR", Amount: 3, Rounding: models.RoundingTruncate}, 249.37, 83.123456, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := service.ConvertCurrency(context.Background(), &tt.req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.amount, resp.ConvertedAmount)
+			assert.Equal(t, tt.rate, resp.Rate)
+			assert.Equal(t, tt.precision, resp.Precision)
+			assert.Equal(t, tt.req.Amount*resp.UnroundedRate, resp.UnroundedAmount)
+		})
+	}
+
+	for _, invalid := range []struct {
+		req  models.ConversionRequest
+		code string
+	}{
+		{models.ConversionRequest{From: "USD", To: "INR", Amount: 1, Precision: precision(-1)}, models.ErrCodeValueInvalid},
+		{models.ConversionRequest{From: "USD", To: "INR", Amount: 1, Precision: precision(models.DefaultMaxPrecision + 1)}, models.ErrCodeLimitExceeded},
+		{models.ConversionRequest{From: "USD", To: "INR", Amount: 1, Rounding: "ceiling"}, models.ErrCodeValueInvalid},
+	} {
+		_, err := service.ConvertCurrency(context.Background(), &invalid.req)
+		code, _ := models.ErrorCodeOf(err)
+		assert.Equal(t, invalid.code, code)
+	}
+}
+
+func TestExchangeService_ConvertWithFees(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 80.0)
+	service := NewExchangeService(memoryCache, nil, nil)
+	service.SetMarkup(NewMarkup(-1, nil))
+
+	resp, err := service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 100})
+	require.NoError(t, err)
+	assert.Nil(t, resp.Fees)
+
+	resp, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{
+		From: "USD", To: "INR", Amount: 100, FeePercent: 1.5, FeeFixed: 20,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 7920.0, resp.ConvertedAmount)
+	require.NotNil(t, resp.Fees)
+	assert.Equal(t, models.ConversionFees{
+		FeePercent:      1.5,
+		FeeFixed:        20,
+		MidMarketAmount: 8000,
+		FeeAmount:       138.8,
+		NetAmount:       7781.2,
+	}, *resp.Fees)
+
+	_, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 1, FeeFixed: 100})
+	code, field := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeValueInvalid, code)
+	assert.Equal(t, "fee_fixed", field)
+}
+
+func TestExchangeService_ConvertWithLocale(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 83.5)
+	service := NewExchangeService(memoryCache, nil, nil)
+
+	resp, err := service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 100, Locale: "en_IN"})
+	assert.NoError(t, err)
+	if assert.NotNil(t, resp.Formatted) {
+		assert.Equal(t, "en-IN", resp.Formatted.Locale)
+		assert.Equal(t, "US$100.00", resp.Formatted.Amount)
+		assert.Equal(t, "₹8,350.00", resp.Formatted.ConvertedAmount)
+		assert.Equal(t, "83.5", resp.Formatted.Rate)
+	}
+
+	resp, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 100})
+	assert.NoError(t, err)
+	assert.Nil(t, resp.Formatted)
+
+	_, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 100, Locale: "xx"})
+	assert.Error(t, err)
+}
+
+func TestExchangeService_ConvertAtTimestamp(t *testing.T) {
+	published := time.Now().Add(-3 * time.Hour).Truncate(time.Second)
+	history := store.NewRateHistory(24 * time.Hour)
+	history.Record("USD", "INR", 83.0, published)
+	history.Record("USD", "INR", 83.5, published.Add(2*time.Hour))
+
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	service := NewExchangeService(memoryCache, nil, nil)
+	service.SetRateHistory(history)
+
+	at := published.Add(time.Hour).Format(time.RFC3339)
+	resp, err := service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 2, Timestamp: at})
+	assert.NoError(t, err)
+	assert.Equal(t, 83.0, resp.Rate, "the rate in force at the instant, not the later one")
+	if assert.NotNil(t, resp.RateTimestamp) {
+		assert.True(t, published.Equal(*resp.RateTimestamp))
+	}
+	assert.Empty(t, resp.RateDate)
+
+	resp, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "INR", To: "USD", Amount: 83, Timestamp: at})
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.0, resp.ConvertedAmount, 1e-9)
+	assert.Equal(t, models.DerivedInverse, resp.Derived)
+
+	// Without intraday data the day's rate is used
+	earlier := published.Add(-48 * time.Hour)
+	day := utils.LastMarketDay(earlier.In(utils.ReferenceLocation())).Format(utils.DateFormat)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", day, 82.0)
+	resp, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 1, Timestamp: earlier.Format(time.RFC3339)})
+	assert.NoError(t, err)
+	assert.Equal(t, 82.0, resp.Rate)
+	assert.Nil(t, resp.RateTimestamp)
+	assert.Equal(t, day, resp.RateDate)
+
+	// Strict conversions take the intraday rate but not the day's instead
+	resp, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 2, Timestamp: at, Strict: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 83.0, resp.Rate)
+	_, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 1, Timestamp: earlier.Format(time.RFC3339), Strict: true})
+	code, field := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeRateNotFound, code)
+	assert.Equal(t, "timestamp", field)
+
+	_, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 1, Timestamp: at, Date: day})
+	assert.Error(t, err)
+}
+
+func TestExchangeService_ConvertWithProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"base":"USD","date":"2025-01-02","rates":{"INR":85.0}}`))
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.FrankfurterBaseURL = server.URL
+	client := external.NewExchangeRateClientWithConfig(cfg)
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	published := time.Now().Add(-time.Hour).Truncate(time.Second)
+	memoryCache.SetWithSource("USD", "INR", "", 83.0, cache.Source{Provider: external.ProviderExchangeRateAPI, PublishedAt: published})
+	service := NewExchangeService(memoryCache, NewRateFetcher(client, memoryCache), client)
+
+	resp, err := service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 83.0, resp.Rate)
+	assert.Equal(t, external.ProviderExchangeRateAPI, resp.Provider)
+	require.NotNil(t, resp.PublishedAt)
+	assert.True(t, published.Equal(*resp.PublishedAt))
+
+	// Pinning the default provider by its alias still uses the cache
+	resp, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 1, Provider: "erapi"})
+	require.NoError(t, err)
+	assert.Equal(t, 83.0, resp.Rate)
+
+	resp, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 1, Provider: "frankfurter"})
+	require.NoError(t, err)
+	assert.Equal(t, 85.0, resp.Rate)
+	assert.Equal(t, external.ProviderFrankfurter, resp.Provider)
+
+	_, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 1, Provider: "nowhere"})
+	assert.ErrorIs(t, err, external.ErrUnknownProvider)
+	code, field := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeProviderUnknown, code)
+	assert.Equal(t, "provider", field)
+}
+
+func TestExchangeService_RateOrigin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"base":"USD","rates":{"EUR":0.9}}`))
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = server.URL
+	client := external.NewExchangeRateClientWithConfig(cfg)
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 80.0)
+	service := NewExchangeService(memoryCache, NewRateFetcher(client, memoryCache), client)
+
+	tests := []struct {
+		name   string
+		from   string
+		to     string
+		origin string
+	}{
+		{"cached", "USD", "INR", models.OriginCache},
+		{"inverse of a cached pair", "INR", "USD", models.OriginDerived},
+		{"fetched for the request", "USD", "EUR", models.OriginLive},
+		{"cached by the fetch before", "USD", "EUR", models.OriginCache},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now()
+			resp, err := service.GetLatestRate(context.Background(), tt.from, tt.to, "")
+			require.NoError(t, err)
+			assert.Equal(t, tt.origin, resp.Origin)
+			require.NotNil(t, resp.Source.FetchedAt)
+			if tt.origin == models.OriginLive {
+				assert.False(t, resp.Source.FetchedAt.Before(before))
+			}
+		})
+	}
+
+	aliased, err := service.GetLatestRate(context.Background(), "$", "rupee", "")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", aliased.From, "aliases are answered with the currency code")
+	assert.Equal(t, "INR", aliased.To)
+	assert.Equal(t, 80.0, aliased.Rate)
+
+	same, err := service.GetLatestRate(context.Background(), "USD", "USD", "")
+	require.NoError(t, err)
+	assert.Empty(t, same.Origin)
+	assert.Nil(t, same.Source.FetchedAt)
+}
+
+func TestExchangeService_StaleFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = server.URL
+	cfg.Retry.MaxAttempts = 1
+	client := external.NewExchangeRateClientWithConfig(cfg)
+	memoryCache := cache.NewMemoryCacheWithOptions(cache.Options{TTL: time.Hour, StaleGrace: 24 * time.Hour})
+	memoryCache.SetWithTTL(external.ProviderExchangeRateAPI, "USD", "INR", "", 80.0, -time.Second)
+	service := NewExchangeService(memoryCache, NewRateFetcher(client, memoryCache), client)
+
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		rate    float64
+		derived string
+		code    string
+	}{
+		{"expired pair", "USD", "INR", 80.0, "", ""},
+		{"inverse of an expired pair", "INR", "USD", 1 / 80.0, models.DerivedInverse, ""},
+		{"never fetched", "USD", "EUR", 0, "", models.ErrCodeProviderUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := service.GetLatestRate(context.Background(), tt.from, tt.to, "")
+			if tt.code != "" {
+				require.Error(t, err)
+				code, _ := models.ErrorCodeOf(err)
+				assert.Equal(t, tt.code, code)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.rate, resp.Rate)
+			assert.Equal(t, tt.derived, resp.Derived)
+			assert.True(t, resp.Stale)
+			require.NotNil(t, resp.Source.FetchedAt)
+		})
+	}
+
+	conversion, err := service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 800.0, conversion.ConvertedAmount)
+	assert.True(t, conversion.Stale)
+
+	_, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 10, Strict: true})
+	code, _ := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeProviderUnavailable, code, "strict conversions don't use stale rates")
+
+	noGrace := cache.NewMemoryCache(time.Hour)
+	noGrace.SetWithTTL(external.ProviderExchangeRateAPI, "USD", "INR", "", 80.0, -time.Second)
+	strict := NewExchangeService(noGrace, NewRateFetcher(client, noGrace), client)
+	_, err = strict.GetLatestRate(context.Background(), "USD", "INR", "")
+	assert.Error(t, err, "without a grace window an expired rate is not served")
+}
+
+func TestExchangeService_SignsRates(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 80.0)
+	service := NewExchangeService(memoryCache, nil, nil)
+
+	unsigned, err := service.GetLatestRate(context.Background(), "USD", "INR", "")
+	require.NoError(t, err)
+	assert.Nil(t, unsigned.Signature, "signing is off without a signer")
+	assert.Empty(t, service.GetSigningKeys())
+
+	signer, err := signing.GenerateSigner()
+	require.NoError(t, err)
+	service.SetSigner(signer)
+	keys := service.GetSigningKeys()
+	require.Len(t, keys, 1)
+	publicKey, err := base64.StdEncoding.DecodeString(keys[0].PublicKey)
+	require.NoError(t, err)
+
+	latest, err := service.GetLatestRate(context.Background(), "USD", "INR", "")
+	require.NoError(t, err)
+	require.NotNil(t, latest.Signature)
+	assert.Equal(t, keys[0].KeyID, latest.Signature.KeyID)
+	fields, err := signing.Verify(publicKey, latest.Signature)
+	require.NoError(t, err)
+	assert.Equal(t, "USD", fields.Get("from"))
+	assert.Equal(t, "80", fields.Get("rate"))
+	assert.Equal(t, signing.FormatTime(*latest.Source.FetchedAt), fields.Get("fetched_at"))
+
+	conversion, err := service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 12.5})
+	require.NoError(t, err)
+	require.NotNil(t, conversion.Signature)
+	fields, err = signing.Verify(publicKey, conversion.Signature)
+	require.NoError(t, err)
+	assert.Equal(t, "12.5", fields.Get("amount"))
+	assert.Equal(t, "1000", fields.Get("converted_amount"))
+	assert.Equal(t, signing.FormatTime(conversion.Date), fields.Get("date"))
+}
+
+func TestExchangeService_HistoricalMissingDates(t *testing.T) {
+	// Wednesday to Friday of the last full week, so no day is a weekend
+	friday := time.Now().AddDate(0, 0, -1)
+	for friday.Weekday() != time.Friday {
+		friday = friday.AddDate(0, 0, -1)
+	}
+	day := func(offset int) string {
+		return friday.AddDate(0, 0, offset+1).Format(utils.DateFormat)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, "/") {
+		case day(-3):
+			w.Write([]byte(`{"base":"USD","date":"` + day(-3) + `","rates":{"INR":85.0}}`))
+		case day(-2):
+			w.Write([]byte(`{"base":"USD","date":"` + day(-2) + `","rates":{"EUR":0.96}}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.FrankfurterBaseURL = server.URL
+	cfg.Retry.MaxAttempts = 1
+	client := external.NewExchangeRateClientWithConfig(cfg)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	service := NewExchangeService(memoryCache, NewRateFetcher(client, memoryCache), client)
+
+	resp, err := service.GetHistoricalRates(context.Background(), &models.HistoricalRateRequest{
+		From: "USD", To: "INR", StartDate: day(-3), EndDate: day(-1), Provider: "frankfurter",
+	})
+	require.NoError(t, err)
+	assert.Len(t, resp.Rates, 1)
+	require.Len(t, resp.MissingDates, 2)
+	assert.Equal(t, models.MissingDate{Date: day(-2), Reason: models.MissingNoData}, resp.MissingDates[0])
+	assert.Equal(t, day(-1), resp.MissingDates[1].Date)
+	assert.Equal(t, models.MissingProviderError, resp.MissingDates[1].Reason)
+	assert.Contains(t, resp.MissingDates[1].Error, "500")
+}
+
+func TestExchangeService_HistoricalObservedDates(t *testing.T) {
+	friday := utils.Today().AddDate(0, 0, -1)
+	for friday.Weekday() != time.Friday {
+		friday = friday.AddDate(0, 0, -1)
+	}
+	day := func(offset int) string {
+		return friday.AddDate(0, 0, offset).Format(utils.DateFormat)
+	}
+	utils.SetHolidays(map[string][]string{"INR": {day(-1)}})
+	defer utils.SetHolidays(nil)
+
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", day(-2), 83.0)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", day(0), 84.0)
+	service := NewExchangeService(memoryCache, nil, nil)
+
+	resp, err := service.GetHistoricalRates(context.Background(), &models.HistoricalRateRequest{
+		From: "USD", To: "INR", StartDate: day(-1), EndDate: day(2),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, resp.MissingDates)
+	require.Len(t, resp.Rates, 4)
+
+	holiday := resp.Rates[day(-1)]
+	assert.Equal(t, 83.0, holiday.Rate, "the holiday repeats the day before it")
+	assert.Equal(t, day(-2), holiday.ObservedDate)
+	assert.Equal(t, 84.0, resp.Rates[day(0)].Rate)
+	assert.Empty(t, resp.Rates[day(0)].ObservedDate)
+	for _, weekend := range []string{day(1), day(2)} {
+		assert.Equal(t, 84.0, resp.Rates[weekend].Rate)
+		assert.Equal(t, day(0), resp.Rates[weekend].ObservedDate)
+	}
+
+	// The holiday only closes pairs involving INR
+	assert.True(t, utils.IsTradingDay(friday.AddDate(0, 0, -1), "USD", "EUR"))
+	assert.False(t, utils.IsTradingDay(friday.AddDate(0, 0, -1), "USD", "INR"))
+}
+
+func TestExchangeService_UpstreamErrorCodes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/EUR") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"base":"USD","rates":{"INR":83.5}}`))
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = server.URL
+	cfg.Retry.MaxAttempts = 1
+	cfg.CircuitBreaker = external.CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}
+	client := external.NewExchangeRateClientWithConfig(cfg)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	service := NewExchangeService(memoryCache, NewRateFetcher(client, memoryCache), client)
+
+	tests := []struct {
+		name     string
+		from, to string
+		code     string
+	}{
+		{"Provider has no rate", "USD", "JPY", models.ErrCodeRateNotFound},
+		{"Provider failing", "EUR", "USD", models.ErrCodeProviderUnavailable},
+		{"Circuit open", "GBP", "USD", models.ErrCodeProviderBusy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: tt.from, To: tt.to, Amount: 1})
+			require.Error(t, err)
+			code, _ := models.ErrorCodeOf(err)
+			assert.Equal(t, tt.code, code)
+		})
+	}
+
+	var coded *models.CodedError
+	_, err := service.GetLatestRate(context.Background(), "JPY", "USD", "")
+	require.ErrorAs(t, err, &coded)
+	assert.Equal(t, models.ErrCodeProviderBusy, coded.Code)
+	assert.InDelta(t, time.Minute.Seconds(), coded.Retry.Seconds(), 5)
+}
+
+func TestExchangeService_RequestDeadline(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = server.URL
+	cfg.CircuitBreaker = external.CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}
+	client := external.NewExchangeRateClientWithConfig(cfg)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	service := NewExchangeService(memoryCache, NewRateFetcher(client, memoryCache), client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := service.ConvertCurrency(ctx, &models.ConversionRequest{From: "USD", To: "INR", Amount: 1})
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second, "the slow provider is abandoned at the deadline")
+	code, _ := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeTimeout, code)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = service.GetLatestRate(canceled, "USD", "INR", "")
+	code, _ = models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeCanceled, code)
+
+	// Abandoned requests say nothing about the provider: its circuit stays closed
+	for _, status := range client.ProviderStatus() {
+		assert.Equal(t, "closed", status.CircuitState, status.Provider)
+	}
+
+	// A range stops at the deadline instead of reporting every day as missing
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	today := time.Now().UTC()
+	_, err = service.GetHistoricalRates(ctx, &models.HistoricalRateRequest{
+		From:      "USD",
+		To:        "INR",
+		StartDate: today.AddDate(0, 0, -6).Format(utils.DateFormat),
+		EndDate:   today.Format(utils.DateFormat),
+		Provider:  external.ProviderFrankfurter,
+	})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestExchangeService_ConvertOnWeekend(t *testing.T) {
+	// Most recent Saturday at least a week back, so it is never in the future
+	saturday := time.Now().UTC().AddDate(0, 0, -7)
+	for saturday.Weekday() != time.Saturday {
+		saturday = saturday.AddDate(0, 0, -1)
+	}
+	friday := saturday.AddDate(0, 0, -1).Format(utils.DateFormat)
+
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", friday, 83.0)
+	service := NewExchangeService(memoryCache, nil, nil)
+
+	resp, err := service.ConvertCurrency(context.Background(), &models.ConversionRequest{
+		From: "USD", To: "INR", Amount: 2, Date: saturday.Format(utils.DateFormat),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 166.0, resp.ConvertedAmount)
+	assert.Equal(t, friday, resp.RateDate)
+	assert.True(t, resp.MarketClosed)
+
+	resp, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 1, Date: friday})
+	assert.NoError(t, err)
+	assert.Equal(t, friday, resp.RateDate)
+	assert.False(t, resp.MarketClosed)
+
+	// Strict conversions refuse the weekend but take the trading day's rate
+	_, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{
+		From: "USD", To: "INR", Amount: 2, Date: saturday.Format(utils.DateFormat), Strict: true,
+	})
+	code, field := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeRateNotFound, code)
+	assert.Equal(t, "date", field)
+	assert.Contains(t, err.Error(), friday)
+
+	resp, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 1, Date: friday, Strict: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 83.0, resp.ConvertedAmount)
+}
+
+func TestExchangeService_CurrencyMetadata(t *testing.T) {
+	service := NewExchangeService(cache.NewMemoryCache(1*time.Hour), nil, nil)
+	models.SetAmountLimits(map[string]models.AmountLimits{"INR": {Min: 1, Max: 5e12}})
+	defer models.SetAmountLimits(nil)
+
+	metadata := service.GetCurrencyMetadata()
+	assert.Len(t, metadata, len(models.SupportedCurrencyCodes()))
+	for _, info := range metadata {
+		limits := models.AmountLimitsOf(info.Code)
+		assert.Equal(t, limits.Min, info.MinAmount, info.Code)
+		assert.Equal(t, limits.Max, info.MaxAmount, info.Code)
+		assert.Equal(t, models.AliasesOf(info.Code), info.Aliases, info.Code)
+		info.MinAmount, info.MaxAmount = models.CurrencyMetadata[info.Code].MinAmount, models.CurrencyMetadata[info.Code].MaxAmount
+		info.Aliases = nil
+		assert.Equal(t, models.CurrencyMetadata[info.Code], info, "missing metadata for %s", info.Code)
+	}
+	assert.Equal(t, "EUR", metadata[0].Code)
+	assert.Equal(t, models.DefaultMaxAmount, metadata[0].MaxAmount)
+	for _, info := range metadata {
+		if info.Code == "INR" {
+			assert.Equal(t, 1.0, info.MinAmount)
+			assert.Equal(t, 5e12, info.MaxAmount)
+		}
+	}
+}
+
+func TestExchangeService_RateTable(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 80.0)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "EUR", "USD", "", 1.25)
+	service := NewExchangeService(memoryCache, nil, nil)
+
+	table, err := service.GetRateTable(context.Background(), "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"USD": 1, "INR": 80, "EUR": 0.8}, table.Rates)
+	assert.Equal(t, []string{"USD_GBP", "USD_JPY"}, table.Missing)
+	assert.False(t, table.Freshness.FetchedAt.IsZero())
+	require.NotNil(t, table.Source.FetchedAt)
+	assert.Equal(t, models.OriginMixed, table.Origin, "EUR is the inverse of the cached EUR/USD")
+
+	matrix, err := service.GetRateTable(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Nil(t, matrix.Rates)
+	assert.Len(t, matrix.Matrix, 5)
+	assert.Equal(t, 0.0125, matrix.Matrix["INR"]["USD"])
+
+	_, err = service.GetRateTable(context.Background(), "XYZ")
+	assert.Error(t, err)
+}
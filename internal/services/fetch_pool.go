@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FetchPoolConfig bounds how many base currencies a fetch cycle refreshes at
+// once and how long a cycle may run
+type FetchPoolConfig struct {
+	Concurrency int           // Bases fetched at once
+	CycleBudget time.Duration // Bases not fetched within it wait for the next cycle, 0 means no limit
+}
+
+// DefaultFetchPoolConfig returns the pool settings used when none are set
+func DefaultFetchPoolConfig() FetchPoolConfig {
+	return FetchPoolConfig{Concurrency: 4}
+}
+
+// fetchPool runs the base fetches of a cycle on a bounded number of workers,
+// so the number of concurrent upstream calls doesn't grow with the list of
+// currencies, and counts how long fetches wait for a worker
+type fetchPool struct {
+	cfg FetchPoolConfig
+
+	mu         sync.Mutex
+	inFlight   int
+	queued     int
+	peakQueued int
+	cycles     int64
+	fetched    int64
+	skipped    int64
+	waited     time.Duration
+	maxWait    time.Duration
+}
+
+func newFetchPool(cfg FetchPoolConfig) *fetchPool {
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	return &fetchPool{cfg: cfg}
+}
+
+// run calls fetch for every base on at most Concurrency goroutines and
+// returns once all have finished. fetch's context ends with ctx or when the
+// cycle budget runs out; bases still waiting for a worker by then are not
+// fetched and are returned.
+func (p *fetchPool) run(ctx context.Context, bases []string, fetch func(ctx context.Context, base string)) []string {
+	if p.cfg.CycleBudget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.cfg.CycleBudget)
+		defer cancel()
+	}
+
+	start := time.Now()
+	p.mu.Lock()
+	p.cycles++
+	p.queued += len(bases)
+	if p.queued > p.peakQueued {
+		p.peakQueued = p.queued
+	}
+	p.mu.Unlock()
+
+	queue := make(chan string, len(bases))
+	for _, base := range bases {
+		queue <- base
+	}
+	close(queue)
+
+	workers := p.cfg.Concurrency
+	if workers > len(bases) {
+		workers = len(bases)
+	}
+	var wg sync.WaitGroup
+	var skippedMu sync.Mutex
+	var skipped []string
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for base := range queue {
+				if ctx.Err() != nil {
+					p.skip()
+					skippedMu.Lock()
+					skipped = append(skipped, base)
+					skippedMu.Unlock()
+					continue
+				}
+
+				p.begin(time.Since(start))
+				fetch(ctx, base)
+				p.end()
+			}
+		}()
+	}
+	wg.Wait()
+	return skipped
+}
+
+// begin moves a base from waiting to fetching after it waited for a worker
+func (p *fetchPool) begin(wait time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queued--
+	p.inFlight++
+	p.waited += wait
+	if wait > p.maxWait {
+		p.maxWait = wait
+	}
+}
+
+func (p *fetchPool) end() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inFlight--
+	p.fetched++
+}
+
+func (p *fetchPool) skip() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queued--
+	p.skipped++
+}
+
+// Stats returns the pool's size and back-pressure counters: how many bases
+// are waiting for a worker and how long they waited
+func (p *fetchPool) Stats() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return map[string]interface{}{
+		"workers":         p.cfg.Concurrency,
+		"cycle_budget_ms": p.cfg.CycleBudget.Milliseconds(),
+		"in_flight":       p.inFlight,
+		"queued":          p.queued,
+		"peak_queued":     p.peakQueued,
+		"cycles":          p.cycles,
+		"fetched":         p.fetched,
+		"skipped":         p.skipped,
+		"total_wait_ms":   p.waited.Milliseconds(),
+		"max_wait_ms":     p.maxWait.Milliseconds(),
+	}
+}
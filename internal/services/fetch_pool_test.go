@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchPool_BoundsConcurrency(t *testing.T) {
+	pool := newFetchPool(FetchPoolConfig{Concurrency: 3})
+	bases := []string{"USD", "EUR", "GBP", "JPY", "INR", "CAD", "AUD", "CHF"}
+
+	var running, peak int32
+	var mu sync.Mutex
+	var fetched []string
+	skipped := pool.run(context.Background(), bases, func(ctx context.Context, base string) {
+		now := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if now <= old || atomic.CompareAndSwapInt32(&peak, old, now) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		mu.Lock()
+		fetched = append(fetched, base)
+		mu.Unlock()
+	})
+
+	assert.Empty(t, skipped)
+	assert.ElementsMatch(t, bases, fetched)
+	assert.LessOrEqual(t, peak, int32(3))
+
+	stats := pool.Stats()
+	assert.Equal(t, 3, stats["workers"])
+	assert.Equal(t, int64(1), stats["cycles"])
+	assert.Equal(t, int64(len(bases)), stats["fetched"])
+	assert.Equal(t, len(bases), stats["peak_queued"])
+	assert.Equal(t, 0, stats["queued"])
+	assert.Equal(t, 0, stats["in_flight"])
+	assert.Greater(t, stats["max_wait_ms"], int64(0), "later bases waited for a worker")
+}
+
+func TestFetchPool_CycleBudget(t *testing.T) {
+	pool := newFetchPool(FetchPoolConfig{Concurrency: 1, CycleBudget: 30 * time.Millisecond})
+	bases := []string{"USD", "EUR", "GBP", "JPY"}
+
+	var fetched []string
+	skipped := pool.run(context.Background(), bases, func(ctx context.Context, base string) {
+		fetched = append(fetched, base)
+		<-ctx.Done()
+	})
+
+	assert.Equal(t, []string{"USD"}, fetched, "the in-flight fetch is cancelled once the budget runs out")
+	assert.Equal(t, []string{"EUR", "GBP", "JPY"}, skipped)
+	assert.Equal(t, int64(3), pool.Stats()["skipped"])
+	assert.Equal(t, 0, pool.Stats()["queued"])
+}
+
+func TestFetchPool_MinimumConcurrency(t *testing.T) {
+	pool := newFetchPool(FetchPoolConfig{})
+
+	var count int32
+	pool.run(context.Background(), []string{"USD", "EUR"}, func(ctx context.Context, base string) {
+		atomic.AddInt32(&count, 1)
+	})
+	assert.Equal(t, int32(2), count)
+	assert.Equal(t, 1, pool.Stats()["workers"])
+}
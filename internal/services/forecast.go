@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+// Defaults of a forecast request naming no horizon or history
+const (
+	DefaultForecastHorizon = 7
+	DefaultForecastHistory = 90
+)
+
+// Bounds of a forecast request
+const (
+	maxForecastHorizon = 30
+	minForecastHistory = 14
+	minForecastSamples = 10   // Trading-day rates needed to fit a model
+	forecastConfidence = 95.0 // Confidence of the band around each projected rate
+	forecastZScore     = 1.96 // Standard normal quantile of forecastConfidence
+	forecastEWMADecay  = 0.94 // Weight of the past in the EWMA level and variance
+)
+
+// GetRateForecast projects a pair's rate over the next Horizon trading days
+// from its historical rates over the History days ending today, looked up
+// like GetHistoricalRates does. The drift model extends the average daily
+// log return; the EWMA model holds the smoothed rate level. Both put a
+// confidence band around each projected rate from the volatility of the
+// daily log returns. The result is labeled indicative.
+func (s *ExchangeService) GetRateForecast(ctx context.Context, req *models.ForecastRequest) (*models.ForecastResponse, error) {
+	if err := validatePair(ctx, &req.From, &req.To); err != nil {
+		return nil, err
+	}
+
+	model := req.Model
+	if model == "" {
+		model = models.ForecastModelDrift
+	}
+	if model != models.ForecastModelDrift && model != models.ForecastModelEWMA {
+		return nil, models.NewFieldError(models.ErrCodeValueInvalid, "model", "model must be %s or %s, got %q", models.ForecastModelDrift, models.ForecastModelEWMA, model)
+	}
+	horizon := req.Horizon
+	if horizon == 0 {
+		horizon = DefaultForecastHorizon
+	}
+	if horizon < 1 || horizon > maxForecastHorizon {
+		return nil, models.NewFieldError(models.ErrCodeValueInvalid, "horizon", "horizon must be between 1 and %d trading days, got %d", maxForecastHorizon, horizon)
+	}
+	history := req.History
+	if history == 0 {
+		history = DefaultForecastHistory
+	}
+	lookback, maxRange := utils.DateLimits()
+	if history < minForecastHistory || history > maxRange {
+		return nil, models.NewFieldError(models.ErrCodeValueInvalid, "history", "history must be between %d and %d days, got %d", minForecastHistory, maxRange, history)
+	}
+
+	today := utils.Today()
+	start := today.AddDate(0, 0, -(history - 1))
+	if earliest := today.AddDate(0, 0, -lookback); lookback > 0 && start.Before(earliest) {
+		start = earliest
+	}
+	dates := utils.GetDateRangeList(start, today)
+	historical, err := s.GetHistoricalRates(ctx, &models.HistoricalRateRequest{
+		From:      req.From,
+		To:        req.To,
+		StartDate: dates[0],
+		EndDate:   dates[len(dates)-1],
+		Provider:  req.Provider,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	points, _ := computeTrend(dates, historical.Rates, 1)
+	if len(points) < minForecastSamples {
+		return nil, models.NewError(models.ErrCodeRateNotFound, "a forecast needs %d trading days with a rate of %s/%s, found %d in the last %d days",
+			minForecastSamples, req.From, req.To, len(points), history)
+	}
+	rates := make([]float64, len(points))
+	for i, point := range points {
+		rates[i] = point.Rate
+	}
+	last := points[len(points)-1]
+
+	var level, drift, volatility float64
+	switch model {
+	case models.ForecastModelEWMA:
+		level, volatility = fitEWMA(rates)
+	default:
+		returns := logReturns(rates)
+		level, drift, volatility = last.Rate, average(returns), standardDeviation(returns)
+	}
+
+	resp := &models.ForecastResponse{
+		From:              req.From,
+		To:                req.To,
+		Model:             model,
+		Horizon:           horizon,
+		History:           history,
+		StartDate:         dates[0],
+		EndDate:           dates[len(dates)-1],
+		Samples:           len(points),
+		LastRate:          last.Rate,
+		LastDate:          last.Date,
+		DriftPercent:      (math.Exp(drift) - 1) * 100,
+		VolatilityPercent: volatility * 100,
+		ConfidencePercent: forecastConfidence,
+		Indicative:        true,
+		Disclaimer:        models.ForecastDisclaimer,
+		Date:              time.Now(),
+		Freshness:         historical.Freshness,
+		Source:            historical.Source,
+	}
+
+	// Days ahead are counted in trading days from the last rate, so a
+	// forecast made on a weekend starts from Friday's rate
+	date, err := utils.ValidateDate(last.Date)
+	if err != nil {
+		return nil, err
+	}
+	for ahead := 1; len(resp.Points) < horizon; {
+		date = date.AddDate(0, 0, 1)
+		if !utils.IsTradingDay(date, req.From, req.To) {
+			continue
+		}
+		if date.After(today) {
+			center := math.Log(level) + drift*float64(ahead)
+			spread := forecastZScore * volatility * math.Sqrt(float64(ahead))
+			resp.Points = append(resp.Points, models.ForecastPoint{
+				Date:  date.Format(utils.DateFormat),
+				Rate:  math.Exp(center),
+				Lower: math.Exp(center - spread),
+				Upper: math.Exp(center + spread),
+			})
+		}
+		ahead++
+	}
+	return resp, nil
+}
+
+// logReturns returns the log of each rate over the previous one
+func logReturns(rates []float64) []float64 {
+	returns := make([]float64, 0, len(rates))
+	for i := 1; i < len(rates); i++ {
+		if rates[i-1] > 0 && rates[i] > 0 {
+			returns = append(returns, math.Log(rates[i]/rates[i-1]))
+		}
+	}
+	return returns
+}
+
+// average returns the mean of values, 0 when there are none
+func average(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+// fitEWMA returns the exponentially smoothed level of rates and the EWMA
+// volatility of their log returns, both weighting the past by
+// forecastEWMADecay. The variance is seeded with that of all returns.
+func fitEWMA(rates []float64) (level, volatility float64) {
+	level = rates[0]
+	for _, rate := range rates[1:] {
+		level = forecastEWMADecay*level + (1-forecastEWMADecay)*rate
+	}
+
+	returns := logReturns(rates)
+	variance := math.Pow(standardDeviation(returns), 2)
+	for _, r := range returns {
+		variance = forecastEWMADecay*variance + (1-forecastEWMADecay)*r*r
+	}
+	return level, math.Sqrt(variance)
+}
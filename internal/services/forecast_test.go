@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+func TestLogReturnsAndEWMA(t *testing.T) {
+	returns := logReturns([]float64{100, 110, 0, 121})
+	require.Len(t, returns, 1, "returns next to a zero rate are skipped")
+	assert.InDelta(t, math.Log(1.1), returns[0], 1e-12)
+
+	level, volatility := fitEWMA([]float64{80, 80, 80, 80})
+	assert.InDelta(t, 80, level, 1e-9)
+	assert.Zero(t, volatility)
+
+	level, volatility = fitEWMA([]float64{80, 82, 80, 82, 84})
+	assert.Greater(t, level, 80.0)
+	assert.Less(t, level, 84.0)
+	assert.Greater(t, volatility, 0.0)
+}
+
+func TestExchangeService_GetRateForecast(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	today := utils.Today()
+	for i := 0; i < 40; i++ {
+		date := today.AddDate(0, 0, -i).Format(utils.DateFormat)
+		memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", date, 80+float64(i%4)*0.5)
+		memoryCache.Set(external.ProviderExchangeRateAPI, "EUR", "INR", date, 90)
+	}
+	service := NewExchangeService(memoryCache, nil, nil)
+
+	resp, err := service.GetRateForecast(context.Background(), &models.ForecastRequest{From: "USD", To: "INR", History: 30})
+	require.NoError(t, err)
+	assert.Equal(t, models.ForecastModelDrift, resp.Model)
+	assert.True(t, resp.Indicative)
+	assert.NotEmpty(t, resp.Disclaimer)
+	assert.Equal(t, 30, resp.History)
+	assert.GreaterOrEqual(t, resp.Samples, minForecastSamples)
+	assert.Greater(t, resp.VolatilityPercent, 0.0)
+	require.Len(t, resp.Points, DefaultForecastHorizon)
+	previous := today
+	for i, point := range resp.Points {
+		date, err := time.Parse(utils.DateFormat, point.Date)
+		require.NoError(t, err)
+		assert.True(t, date.After(previous), "points are future trading days in order")
+		assert.True(t, utils.IsMarketDay(date))
+		previous = date
+
+		assert.Less(t, point.Lower, point.Rate)
+		assert.Greater(t, point.Upper, point.Rate)
+		if i > 0 {
+			assert.Greater(t, point.Upper-point.Lower, resp.Points[i-1].Upper-resp.Points[i-1].Lower, "the band widens")
+		}
+	}
+
+	resp, err = service.GetRateForecast(context.Background(), &models.ForecastRequest{From: "EUR", To: "INR", Horizon: 3, History: 30, Model: models.ForecastModelEWMA})
+	require.NoError(t, err)
+	require.Len(t, resp.Points, 3)
+	assert.Equal(t, models.ForecastModelEWMA, resp.Model)
+	assert.Zero(t, resp.DriftPercent)
+	for _, point := range resp.Points {
+		assert.InDelta(t, 90, point.Rate, 1e-9)
+		assert.InDelta(t, 90, point.Lower, 1e-9, "a rate that never moved has no band")
+		assert.InDelta(t, 90, point.Upper, 1e-9)
+	}
+
+	tests := []struct {
+		name  string
+		req   models.ForecastRequest
+		field string
+	}{
+		{"unsupported currency", models.ForecastRequest{From: "USD", To: "XYZ"}, "to"},
+		{"unknown model", models.ForecastRequest{From: "USD", To: "INR", Model: "arima"}, "model"},
+		{"horizon too long", models.ForecastRequest{From: "USD", To: "INR", Horizon: maxForecastHorizon + 1}, "horizon"},
+		{"negative horizon", models.ForecastRequest{From: "USD", To: "INR", Horizon: -1}, "horizon"},
+		{"history too short", models.ForecastRequest{From: "USD", To: "INR", History: minForecastHistory - 1}, "history"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.GetRateForecast(context.Background(), &tt.req)
+			require.Error(t, err)
+			_, field := models.ErrorCodeOf(err)
+			assert.Equal(t, tt.field, field)
+		})
+	}
+}
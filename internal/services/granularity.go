@@ -0,0 +1,99 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+// AggregateHistoricalRates groups the daily rates of result into weekly or
+// monthly buckets, for charting long ranges without a rate per day
+func AggregateHistoricalRates(result *models.HistoricalRateResponse, granularity string) *models.HistoricalBucketsResponse {
+	return &models.HistoricalBucketsResponse{
+		From:         result.From,
+		To:           result.To,
+		Granularity:  granularity,
+		Buckets:      aggregateRates(result.Rates, granularity),
+		Freshness:    result.Freshness,
+		Source:       result.Source,
+		MissingDates: result.MissingDates,
+	}
+}
+
+// AggregateBulkHistoricalRates groups the daily rates of every pair of
+// result like AggregateHistoricalRates
+func AggregateBulkHistoricalRates(result *models.BulkHistoricalResponse, granularity string) *models.BulkHistoricalBucketsResponse {
+	buckets := make(map[string][]models.RateBucket, len(result.Rates))
+	for pair, rates := range result.Rates {
+		buckets[pair] = aggregateRates(rates, granularity)
+	}
+	return &models.BulkHistoricalBucketsResponse{
+		StartDate:    result.StartDate,
+		EndDate:      result.EndDate,
+		Granularity:  granularity,
+		Buckets:      buckets,
+		Freshness:    result.Freshness,
+		Source:       result.Source,
+		MissingDates: result.MissingDates,
+	}
+}
+
+// aggregateRates folds daily rates, keyed by date, into buckets of
+// granularity, oldest first
+func aggregateRates(rates map[string]models.HistoricalRate, granularity string) []models.RateBucket {
+	dates := make([]string, 0, len(rates))
+	for date := range rates {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	buckets := []models.RateBucket{}
+	var bucket *models.RateBucket
+	var period time.Time
+	var sum float64
+	var counted map[string]bool // Trading days counted in the bucket
+	for _, date := range dates {
+		day, err := time.Parse(utils.DateFormat, date)
+		if err != nil {
+			continue
+		}
+		if start := periodStart(day, granularity); bucket == nil || !start.Equal(period) {
+			buckets = append(buckets, models.RateBucket{Start: date})
+			bucket = &buckets[len(buckets)-1]
+			period, sum, counted = start, 0, make(map[string]bool)
+		}
+		bucket.End = date
+
+		rate := rates[date]
+		observed := date
+		if rate.ObservedDate != "" {
+			observed = rate.ObservedDate
+		}
+		if counted[observed] {
+			continue
+		}
+		counted[observed] = true
+
+		if bucket.Days == 0 {
+			bucket.Open, bucket.High, bucket.Low = rate.Rate, rate.Rate, rate.Rate
+		}
+		bucket.High = max(bucket.High, rate.Rate)
+		bucket.Low = min(bucket.Low, rate.Rate)
+		bucket.Close = rate.Rate
+		bucket.Days++
+		sum += rate.Rate
+		bucket.Average = utils.Round(sum/float64(bucket.Days), models.RatePrecision, models.RoundingHalfUp)
+	}
+	return buckets
+}
+
+// periodStart returns the first day of the week, a Monday, or of the month
+// holding day
+func periodStart(day time.Time, granularity string) time.Time {
+	if granularity == models.GranularityMonthly {
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
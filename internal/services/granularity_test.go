@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/models"
+)
+
+// Thursday 2025-01-30 to Tuesday 2025-02-04, with the weekend carrying
+// Friday's rate
+func granularityFixture() map[string]models.HistoricalRate {
+	return map[string]models.HistoricalRate{
+		"2025-01-30": {Rate: 86.0},
+		"2025-01-31": {Rate: 86.6},
+		"2025-02-01": {Rate: 86.6, ObservedDate: "2025-01-31"},
+		"2025-02-02": {Rate: 86.6, ObservedDate: "2025-01-31"},
+		"2025-02-03": {Rate: 87.1},
+		"2025-02-04": {Rate: 86.9},
+	}
+}
+
+func TestAggregateRates_Weekly(t *testing.T) {
+	buckets := aggregateRates(granularityFixture(), models.GranularityWeekly)
+
+	require.Len(t, buckets, 2)
+	assert.Equal(t, models.RateBucket{Start: "2025-01-30", End: "2025-02-02", Open: 86.0, High: 86.6, Low: 86.0, Close: 86.6, Average: 86.3, Days: 2}, buckets[0])
+	assert.Equal(t, models.RateBucket{Start: "2025-02-03", End: "2025-02-04", Open: 87.1, High: 87.1, Low: 86.9, Close: 86.9, Average: 87.0, Days: 2}, buckets[1])
+}
+
+func TestAggregateRates_Monthly(t *testing.T) {
+	buckets := aggregateRates(granularityFixture(), models.GranularityMonthly)
+
+	require.Len(t, buckets, 2)
+	assert.Equal(t, models.RateBucket{Start: "2025-01-30", End: "2025-01-31", Open: 86.0, High: 86.6, Low: 86.0, Close: 86.6, Average: 86.3, Days: 2}, buckets[0])
+	// Friday's rate carried into February opens the month, counted once
+	assert.Equal(t, models.RateBucket{Start: "2025-02-01", End: "2025-02-04", Open: 86.6, High: 87.1, Low: 86.6, Close: 86.9, Average: 86.866667, Days: 3}, buckets[1])
+}
+
+func TestAggregateBulkHistoricalRates(t *testing.T) {
+	result := AggregateBulkHistoricalRates(&models.BulkHistoricalResponse{
+		StartDate: "2025-01-30",
+		EndDate:   "2025-02-04",
+		Rates:     map[string]map[string]models.HistoricalRate{"USD_INR": granularityFixture(), "EUR_USD": {}},
+	}, models.GranularityMonthly)
+
+	assert.Equal(t, models.GranularityMonthly, result.Granularity)
+	assert.Len(t, result.Buckets["USD_INR"], 2)
+	assert.Empty(t, result.Buckets["EUR_USD"])
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+)
+
+func newHealthTestService(t *testing.T, providerStatus int) (*ExchangeService, *RateFetcher) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(providerStatus)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = server.URL
+	client := external.NewExchangeRateClientWithConfig(cfg)
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	fetcher := NewRateFetcher(client, memoryCache)
+	return NewExchangeService(memoryCache, fetcher, client), fetcher
+}
+
+func TestExchangeService_HealthBeforeFirstFetch(t *testing.T) {
+	service, _ := newHealthTestService(t, http.StatusOK)
+
+	status, checks := service.HealthChecks()
+	assert.Equal(t, models.HealthStatusUnhealthy, status)
+	assert.Equal(t, models.HealthStatusUnhealthy, checks["rate_fetcher"].Status)
+	assert.Equal(t, models.HealthStatusHealthy, checks["provider"].Status)
+	assert.Equal(t, models.HealthStatusHealthy, checks["cache"].Status)
+
+	ready, reason := service.IsReady()
+	assert.False(t, ready)
+	assert.NotEmpty(t, reason)
+}
+
+func TestExchangeService_HealthAfterFetch(t *testing.T) {
+	service, fetcher := newHealthTestService(t, http.StatusBadGateway)
+	fetcher.mu.Lock()
+	fetcher.lastSuccess = time.Now()
+	fetcher.mu.Unlock()
+
+	ready, _ := service.IsReady()
+	assert.True(t, ready)
+
+	// A failing provider degrades the service but does not make it unhealthy
+	status, checks := service.HealthChecks()
+	assert.Equal(t, models.HealthStatusDegraded, status)
+	assert.Equal(t, models.HealthStatusDegraded, checks["provider"].Status)
+	assert.Nil(t, checks["provider"].LastOK)
+
+	fetcher.mu.Lock()
+	fetcher.lastSuccess = time.Now().Add(-3 * fetcher.fetchInterval)
+	fetcher.mu.Unlock()
+	_, checks = service.HealthChecks()
+	assert.Equal(t, models.HealthStatusDegraded, checks["rate_fetcher"].Status)
+}
+
+func TestExchangeService_NotReadyWhileDraining(t *testing.T) {
+	service, fetcher := newHealthTestService(t, http.StatusOK)
+	fetcher.mu.Lock()
+	fetcher.lastSuccess = time.Now()
+	fetcher.mu.Unlock()
+
+	service.Drain()
+	ready, reason := service.IsReady()
+	assert.False(t, ready)
+	assert.Equal(t, "shutting down", reason)
+}
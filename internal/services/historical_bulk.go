@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+// DefaultBulkHistoricalConcurrency is how many pairs of a bulk historical
+// request are looked up at once when none is set
+const DefaultBulkHistoricalConcurrency = 4
+
+// SetBulkHistoricalConcurrency bounds how many pairs of a bulk historical
+// request are looked up at once, so a request for many pairs doesn't burst
+// upstream calls
+func (s *ExchangeService) SetBulkHistoricalConcurrency(concurrency int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bulkConcurrency = concurrency
+}
+
+func (s *ExchangeService) getBulkConcurrency() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.bulkConcurrency < 1 {
+		return DefaultBulkHistoricalConcurrency
+	}
+	return s.bulkConcurrency
+}
+
+// GetBulkHistoricalRates returns the historical rates of every pair of req
+// over its date range. Each pair is looked up like GetHistoricalRates does,
+// on at most the bulk concurrency of goroutines at once. The first pair that
+// fails fails the request and stops the others. Pairs allowed rejects fail
+// with PAIR_NOT_ALLOWED; a nil allowed permits every pair. With a max wait,
+// pairs share one deadline: dates of pairs in flight then are pending, and
+// pairs still waiting for their turn are not looked up, all their dates
+// pending.
+func (s *ExchangeService) GetBulkHistoricalRates(ctx context.Context, req *models.BulkHistoricalRequest, allowed PairFilter) (*models.BulkHistoricalResponse, error) {
+	maxPairs := models.Limits().MaxPairs
+	if len(req.Pairs) == 0 {
+		return nil, models.NewFieldError(models.ErrCodeValueInvalid, "pairs", "between 1 and %d pairs can be requested, got none", maxPairs)
+	}
+	if len(req.Pairs) > maxPairs {
+		return nil, models.NewFieldError(models.ErrCodeLimitExceeded, "pairs", "between 1 and %d pairs can be requested, got %d", maxPairs, len(req.Pairs))
+	}
+	pairs, err := parsePairs(ctx, req.Pairs)
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		if allowed != nil && !allowed(pair.from, pair.to) {
+			return nil, models.NewFieldError(models.ErrCodePairNotAllowed, "pairs", "%s/%s is not allowed", pair.from, pair.to)
+		}
+	}
+	startDate, endDate, err := utils.ValidateDateRange(req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.resolveProvider(req.Provider); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateMaxWait(req.MaxWaitMs); err != nil {
+		return nil, err
+	}
+	dates := utils.GetDateRangeList(startDate, endDate)
+
+	deadline := latencyDeadline(req.MaxWaitMs)
+	expired := make(chan struct{})
+	if !deadline.IsZero() {
+		timer := time.AfterFunc(time.Until(deadline), func() { close(expired) })
+		defer timer.Stop()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]*models.HistoricalRateResponse, len(pairs))
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	slots := make(chan struct{}, s.getBulkConcurrency())
+	for i, pair := range pairs {
+		wg.Add(1)
+		go func(i int, pair currencyPair) {
+			defer wg.Done()
+			pending := &models.HistoricalRateResponse{
+				From:         pair.from,
+				To:           pair.to,
+				Rates:        map[string]models.HistoricalRate{},
+				MissingDates: pendingDates(dates),
+			}
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-expired:
+				results[i] = pending
+				return
+			case <-ctx.Done():
+				return
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case <-expired:
+				results[i] = pending
+				return
+			default:
+			}
+
+			result, err := s.getHistoricalRates(ctx, &models.HistoricalRateRequest{
+				From:      pair.from,
+				To:        pair.to,
+				StartDate: req.StartDate,
+				EndDate:   req.EndDate,
+				Provider:  req.Provider,
+			}, deadline)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = fmt.Errorf("failed to get historical rates of %s: %w", pair, err)
+					cancel()
+				})
+				return
+			}
+			results[i] = result
+		}(i, pair)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	resp := &models.BulkHistoricalResponse{
+		StartDate:    startDate.Format(utils.DateFormat),
+		EndDate:      endDate.Format(utils.DateFormat),
+		Rates:        make(map[string]map[string]models.HistoricalRate, len(pairs)),
+		MissingDates: make(map[string][]models.MissingDate),
+	}
+	for i, pair := range pairs {
+		result := results[i]
+		resp.Rates[pair.String()] = result.Rates
+		if len(result.MissingDates) > 0 {
+			resp.MissingDates[pair.String()] = result.MissingDates
+		}
+		resp.Freshness.Merge(result.Freshness)
+		resp.Source.Merge(result.Source)
+	}
+	return resp, nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+func TestExchangeService_GetBulkHistoricalRates(t *testing.T) {
+	// Thursday and Friday of the last full week, so no day is a weekend
+	friday := time.Now().AddDate(0, 0, -1)
+	for friday.Weekday() != time.Friday {
+		friday = friday.AddDate(0, 0, -1)
+	}
+	thursday := friday.AddDate(0, 0, -1).Format(utils.DateFormat)
+
+	var inFlight, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		if r.URL.Query().Get("from") == "JPY" {
+			w.Write([]byte(`{"base":"JPY","rates":{"USD":0.0064}}`))
+			return
+		}
+		w.Write([]byte(`{"base":"` + r.URL.Query().Get("from") + `","rates":{"INR":85.0}}`))
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.FrankfurterBaseURL = server.URL
+	cfg.DefaultProvider = external.ProviderFrankfurter
+	cfg.Retry.MaxAttempts = 1
+	client := external.NewExchangeRateClientWithConfig(cfg)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	service := NewExchangeService(memoryCache, NewRateFetcher(client, memoryCache), client)
+	service.SetBulkHistoricalConcurrency(2)
+
+	resp, err := service.GetBulkHistoricalRates(context.Background(), &models.BulkHistoricalRequest{
+		Pairs:     []string{"USD_INR", "eur_inr", "GBP_INR", "JPY_INR"},
+		StartDate: thursday,
+		EndDate:   friday.Format(utils.DateFormat),
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, thursday, resp.StartDate)
+	require.Len(t, resp.Rates, 4)
+	assert.Len(t, resp.Rates["EUR_INR"], 2)
+	assert.Equal(t, 85.0, resp.Rates["USD_INR"][thursday].Rate)
+	assert.Empty(t, resp.Rates["JPY_INR"])
+	assert.Len(t, resp.MissingDates["JPY_INR"], 2, "a pair without rates is reported, not failed")
+	assert.NotContains(t, resp.MissingDates, "USD_INR")
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(2), "pairs are looked up on a bounded pool")
+
+	usdOnly := func(from, to string) bool { return from == "USD" }
+	tests := []struct {
+		name    string
+		req     models.BulkHistoricalRequest
+		allowed PairFilter
+		code    string
+	}{
+		{"no pairs", models.BulkHistoricalRequest{StartDate: thursday, EndDate: thursday}, nil, models.ErrCodeValueInvalid},
+		{"too many pairs", models.BulkHistoricalRequest{Pairs: make([]string, models.DefaultMaxBulkHistoricalPairs+1), StartDate: thursday, EndDate: thursday}, nil, models.ErrCodeLimitExceeded},
+		{"duplicate pair", models.BulkHistoricalRequest{Pairs: []string{"USD_INR", "usd_inr"}, StartDate: thursday, EndDate: thursday}, nil, models.ErrCodeValueInvalid},
+		{"pair not allowed", models.BulkHistoricalRequest{Pairs: []string{"USD_INR", "EUR_INR"}, StartDate: thursday, EndDate: thursday}, usdOnly, models.ErrCodePairNotAllowed},
+		{"unknown provider", models.BulkHistoricalRequest{Pairs: []string{"USD_INR"}, StartDate: thursday, EndDate: thursday, Provider: "nope"}, nil, models.ErrCodeProviderUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.GetBulkHistoricalRates(context.Background(), &tt.req, tt.allowed)
+			require.Error(t, err)
+			code, _ := models.ErrorCodeOf(err)
+			assert.Equal(t, tt.code, code)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HotPairConfig adapts the refresh interval of pairs to how often they are
+// requested. Pairs given their own interval by a PairSchedule keep it.
+type HotPairConfig struct {
+	HotInterval     time.Duration // Refresh interval of frequently requested pairs, 0 disables promotion
+	ColdInterval    time.Duration // Refresh interval of pairs not requested lately, 0 disables demotion
+	Window          time.Duration // How often intervals are adapted; past requests count half each window
+	MinRequests     int           // Decayed request count that makes a pair hot
+	MaxCallsPerHour int           // Upstream calls per hour hot bases may cost, 0 means no limit
+}
+
+// DefaultHotPairConfig adapts intervals every 10 minutes and promotes pairs
+// requested about 10 times a window, within 120 upstream calls an hour. Both
+// intervals are 0, so pairs are tracked but nothing is promoted or demoted.
+func DefaultHotPairConfig() HotPairConfig {
+	return HotPairConfig{
+		Window:          10 * time.Minute,
+		MinRequests:     20,
+		MaxCallsPerHour: 120,
+	}
+}
+
+// Enabled reports whether cfg promotes or demotes any pair
+func (cfg HotPairConfig) Enabled() bool {
+	return cfg.HotInterval > 0 || cfg.ColdInterval > 0
+}
+
+// hotPairs counts the requests of every pair and picks the pairs refreshed
+// on the hot interval. Scores decay by half every window, so a pair stops
+// being hot a few windows after its traffic dies down and goes cold once its
+// score reaches 0.
+type hotPairs struct {
+	cfg HotPairConfig
+
+	mu     sync.Mutex
+	counts map[string]int64 // "FROM_TO" -> requests this window
+	scores map[string]int64 // "FROM_TO" -> decayed requests of past windows
+	hot    map[string]bool
+	cost   int // Upstream calls per hour of the hot bases
+}
+
+func newHotPairs(cfg HotPairConfig) *hotPairs {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultHotPairConfig().Window
+	}
+	return &hotPairs{
+		cfg:    cfg,
+		counts: make(map[string]int64),
+		scores: make(map[string]int64),
+		hot:    make(map[string]bool),
+	}
+}
+
+func (h *hotPairs) record(from, to string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[from+"_"+to]++
+}
+
+// adapt halves the scores, adds the window's requests to them and picks the
+// hot pairs again, highest score first. Pairs whose score reaches 0 are
+// forgotten. One upstream call refreshes a whole base, so a pair whose base
+// is already hot is free; any other costs its base's calls per hour at the
+// hot interval and is left out once that would exceed the budget. Pairs in
+// scheduled keep their own interval and are never promoted.
+func (h *hotPairs) adapt(scheduled map[string]PairSchedule) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for key, score := range h.scores {
+		if score /= 2; score == 0 {
+			delete(h.scores, key)
+		} else {
+			h.scores[key] = score
+		}
+	}
+	for key, count := range h.counts {
+		h.scores[key] += count
+	}
+	h.counts = make(map[string]int64)
+
+	var candidates []string
+	for key, score := range h.scores {
+		if _, fixed := scheduled[key]; !fixed && score >= int64(h.cfg.MinRequests) {
+			candidates = append(candidates, key)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if h.scores[candidates[i]] != h.scores[candidates[j]] {
+			return h.scores[candidates[i]] > h.scores[candidates[j]]
+		}
+		return candidates[i] < candidates[j]
+	})
+
+	h.hot = make(map[string]bool)
+	h.cost = 0
+	if h.cfg.HotInterval > 0 {
+		perBase := int(time.Hour / h.cfg.HotInterval)
+		bases := make(map[string]bool)
+		for _, key := range candidates {
+			base := key[:strings.Index(key, "_")]
+			if !bases[base] {
+				if h.cfg.MaxCallsPerHour > 0 && h.cost+perBase > h.cfg.MaxCallsPerHour {
+					continue
+				}
+				bases[base] = true
+				h.cost += perBase
+			}
+			h.hot[key] = true
+		}
+	}
+}
+
+// interval returns the refresh interval of a pair: the hot interval for hot
+// pairs, the cold one for pairs not requested lately and fallback otherwise
+func (h *hotPairs) interval(from, to string, fallback time.Duration) time.Duration {
+	key := from + "_" + to
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch {
+	case h.hot[key] && h.cfg.HotInterval > 0:
+		return h.cfg.HotInterval
+	case h.cfg.ColdInterval > 0 && h.scores[key] == 0 && h.counts[key] == 0:
+		return h.cfg.ColdInterval
+	}
+	return fallback
+}
+
+// stats returns the hot pairs and the upstream calls per hour they cost
+func (h *hotPairs) stats() map[string]interface{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	pairs := make([]string, 0, len(h.hot))
+	for key := range h.hot {
+		pairs = append(pairs, key)
+	}
+	sort.Strings(pairs)
+	tracked := len(h.scores)
+	for key := range h.counts {
+		if _, scored := h.scores[key]; !scored {
+			tracked++
+		}
+	}
+	return map[string]interface{}{
+		"hot":            pairs,
+		"tracked":        tracked,
+		"calls_per_hour": h.cost,
+		"budget":         h.cfg.MaxCallsPerHour,
+	}
+}
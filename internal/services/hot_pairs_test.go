@@ -0,0 +1,94 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+)
+
+func recordRequests(hot *hotPairs, from, to string, n int) {
+	for i := 0; i < n; i++ {
+		hot.record(from, to)
+	}
+}
+
+func TestHotPairs_PromotesWithinBudget(t *testing.T) {
+	hot := newHotPairs(HotPairConfig{
+		HotInterval:     5 * time.Minute, // 12 calls per hour and base
+		ColdInterval:    6 * time.Hour,
+		Window:          10 * time.Minute,
+		MinRequests:     10,
+		MaxCallsPerHour: 24,
+	})
+	recordRequests(hot, "USD", "INR", 50)
+	recordRequests(hot, "USD", "JPY", 10) // Same base as USD/INR, free
+	recordRequests(hot, "EUR", "USD", 30)
+	recordRequests(hot, "GBP", "USD", 20) // Over budget
+	recordRequests(hot, "JPY", "USD", 2)  // Too few requests
+	hot.adapt(nil)
+
+	const hourly = time.Hour
+	assert.Equal(t, 5*time.Minute, hot.interval("USD", "INR", hourly))
+	assert.Equal(t, 5*time.Minute, hot.interval("USD", "JPY", hourly))
+	assert.Equal(t, 5*time.Minute, hot.interval("EUR", "USD", hourly))
+	assert.Equal(t, hourly, hot.interval("GBP", "USD", hourly))
+	assert.Equal(t, hourly, hot.interval("JPY", "USD", hourly))
+	assert.Equal(t, 6*time.Hour, hot.interval("INR", "EUR", hourly))
+
+	stats := hot.stats()
+	assert.Equal(t, []string{"EUR_USD", "USD_INR", "USD_JPY"}, stats["hot"])
+	assert.Equal(t, 24, stats["calls_per_hour"])
+	assert.Equal(t, 5, stats["tracked"])
+}
+
+func TestHotPairs_DecaysIdlePairs(t *testing.T) {
+	hot := newHotPairs(HotPairConfig{HotInterval: 5 * time.Minute, ColdInterval: 6 * time.Hour, MinRequests: 10})
+	recordRequests(hot, "USD", "INR", 40)
+	hot.adapt(nil)
+	assert.Equal(t, 5*time.Minute, hot.interval("USD", "INR", time.Hour))
+
+	// Without requests the score halves every window: 20, 10, 5, ...
+	hot.adapt(nil)
+	hot.adapt(nil)
+	assert.Equal(t, 5*time.Minute, hot.interval("USD", "INR", time.Hour))
+	hot.adapt(nil)
+	assert.Equal(t, time.Hour, hot.interval("USD", "INR", time.Hour))
+	for i := 0; i < 3; i++ {
+		hot.adapt(nil)
+	}
+	assert.Equal(t, 6*time.Hour, hot.interval("USD", "INR", time.Hour))
+}
+
+func TestRateFetcher_AdaptQueueKeepsWaitedTime(t *testing.T) {
+	fetcher := NewRateFetcher(external.NewExchangeRateClient(), cache.NewMemoryCache(time.Hour))
+	fetcher.SetSchedule(time.Hour, []PairSchedule{{From: "EUR", To: "USD", Interval: 15 * time.Minute}})
+	fetcher.SetHotPairs(HotPairConfig{HotInterval: 5 * time.Minute, MinRequests: 5})
+
+	start := time.Now()
+	queue := fetcher.buildQueue(start)
+	for i := 0; i < 5; i++ {
+		fetcher.RecordAccess("USD", "INR")
+		fetcher.RecordAccess("EUR", "USD")
+	}
+
+	// USD/INR waited 10 minutes of its hour, so it is due right away;
+	// EUR/USD keeps its own schedule
+	fetcher.adaptQueue(&queue, start.Add(10*time.Minute))
+	head := queue.peek()
+	require.NotNil(t, head)
+	assert.Equal(t, "USD_INR", head.From+"_"+head.To)
+	assert.Equal(t, 5*time.Minute, head.Interval)
+	assert.True(t, start.Add(10*time.Minute).Equal(head.next))
+	for _, pair := range queue {
+		if pair.From == "EUR" && pair.To == "USD" {
+			assert.Equal(t, 15*time.Minute, pair.Interval)
+			assert.True(t, start.Add(15*time.Minute).Equal(pair.next))
+		}
+	}
+	assert.Equal(t, []string{"USD_INR"}, fetcher.HotPairStats()["hot"])
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"io"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/store"
+)
+
+// ExchangeServiceInterface is what the HTTP handlers need from the exchange
+// service, so they can be tested against a mock such as the one in
+// services/mocks instead of a real fetcher and provider client
+type ExchangeServiceInterface interface {
+	ConvertCurrency(ctx context.Context, req *models.ConversionRequest) (*models.ConversionResponse, error)
+	ConvertChain(ctx context.Context, req *models.ChainConversionRequest, allowed PairFilter) (*models.ChainConversionResponse, error)
+	SimulateConversion(ctx context.Context, req *models.SimulationRequest) (*models.SimulationResponse, error)
+	GetLatestRate(ctx context.Context, from, to, provider string) (*models.LatestRateResponse, error)
+	CompareRates(ctx context.Context, from, to string) (*models.RateComparison, error)
+	GetRateTable(ctx context.Context, base string) (*models.RateTableResponse, error)
+	GetHistoricalRates(ctx context.Context, req *models.HistoricalRateRequest) (*models.HistoricalRateResponse, error)
+	GetBulkHistoricalRates(ctx context.Context, req *models.BulkHistoricalRequest, allowed PairFilter) (*models.BulkHistoricalResponse, error)
+	GetIntradayRates(ctx context.Context, from, to, date string) (*models.IntradayRateResponse, error)
+	GetRateTrend(ctx context.Context, req *models.TrendRequest) (*models.TrendResponse, error)
+	GetRateDiff(ctx context.Context, req *models.RateDiffRequest) (*models.RateDiffResponse, error)
+	GetRateRecommendation(ctx context.Context, req *models.RecommendationRequest) (*models.RecommendationResponse, error)
+	GetCorrelation(ctx context.Context, req *models.CorrelationRequest) (*models.CorrelationResponse, error)
+	GetRateForecast(ctx context.Context, req *models.ForecastRequest) (*models.ForecastResponse, error)
+	GetSupportedCurrencies() []string
+	GetCurrencyMetadata() []models.CurrencyInfo
+	GetSigningKeys() []models.PublicKey
+	AddCurrency(code string) (bool, error)
+	RemoveCurrency(code string) (int, error)
+
+	RecordConversion(caller string, conversion *models.ConversionResponse) error
+	RecordChainConversion(caller string, chain *models.ChainConversionResponse) error
+	QueryConversions(filter store.AuditFilter) ([]store.ConversionRecord, int, error)
+	GetConversion(id int64) (*store.ConversionRecord, error)
+	GetConversionReceipt(conversionID string) (*store.ConversionRecord, error)
+
+	ClearCache()
+	InvalidatePair(from, to string) (int, error)
+	RefetchHistoricalRates(ctx context.Context, req *models.RefetchRequest, caller string) (*models.RefetchResponse, error)
+	ImportHistoricalRates(file io.Reader, req *models.RateImportRequest, caller string) (*models.RateImportResponse, error)
+	WarmCache(ctx context.Context) map[string]interface{}
+
+	GetServiceHealth() map[string]interface{}
+	IsReady() (bool, string)
+	GetCacheStats() map[string]interface{}
+	GetProviderCacheStats(provider string) (map[string]interface{}, error)
+	GetClientStats() map[string]interface{}
+	GetProviderStatus() []models.ProviderStatus
+	GetDiscrepancyStats() models.DiscrepancyStats
+	GetShadowStats() models.ShadowStats
+}
+
+var _ ExchangeServiceInterface = (*ExchangeService)(nil)
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/store"
+	"exchange-rate-service/internal/utils"
+)
+
+// GetIntradayRates returns every rate of a pair the fetcher recorded during
+// date, a day in the reference time zone defaulting to today. Only the
+// default provider's rates are recorded, and only for the intraday
+// retention period. A pair recorded the other way round is inverted.
+func (s *ExchangeService) GetIntradayRates(ctx context.Context, from, to, date string) (*models.IntradayRateResponse, error) {
+	if err := validatePair(ctx, &from, &to); err != nil {
+		return nil, err
+	}
+	day := utils.Today()
+	if date != "" {
+		var err error
+		if day, err = utils.ValidateDate(date); err != nil {
+			return nil, models.ForField(err, "date", "")
+		}
+	}
+
+	history := s.getRateHistory()
+	if history == nil {
+		return nil, models.NewError(models.ErrCodeNotFound, "intraday history is not enabled")
+	}
+
+	resp := &models.IntradayRateResponse{
+		From:  from,
+		To:    to,
+		Date:  day.Format(utils.DateFormat),
+		Rates: []models.IntradayRate{},
+	}
+	if from == to {
+		return resp, nil
+	}
+
+	end := day.AddDate(0, 0, 1)
+	observations := history.Between(from, to, day, end)
+	inverse := false
+	if len(observations) == 0 {
+		observations, inverse = history.Between(to, from, day, end), true
+	}
+	for _, observation := range observations {
+		rate := intradayRate(observation, inverse)
+		if rate.Rate != 0 {
+			resp.Rates = append(resp.Rates, rate)
+		}
+	}
+	if inverse && len(resp.Rates) > 0 {
+		resp.Derived = models.DerivedInverse
+	}
+	return resp, nil
+}
+
+func intradayRate(observation store.Observation, inverse bool) models.IntradayRate {
+	rate := observation.Rate
+	if inverse && rate != 0 {
+		rate = 1 / rate
+	}
+	return models.IntradayRate{Timestamp: observation.Timestamp.UTC(), Rate: rate}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/store"
+	"exchange-rate-service/internal/utils"
+)
+
+func TestExchangeService_GetIntradayRates(t *testing.T) {
+	day := utils.Today().AddDate(0, 0, -1)
+	date := day.Format(utils.DateFormat)
+	history := store.NewRateHistory(72 * time.Hour)
+	history.Record("USD", "INR", 83.0, day.Add(-time.Minute))
+	history.Record("USD", "INR", 80.0, day.Add(9*time.Hour))
+	history.Record("USD", "INR", 80.5, day.Add(9*time.Hour+time.Minute))
+
+	service := NewExchangeService(cache.NewMemoryCache(time.Hour), nil, nil)
+	_, err := service.GetIntradayRates(context.Background(), "USD", "INR", date)
+	code, _ := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeNotFound, code, "without intraday history")
+
+	service.SetRateHistory(history)
+	resp, err := service.GetIntradayRates(context.Background(), "USD", "INR", date)
+	require.NoError(t, err)
+	assert.Equal(t, date, resp.Date)
+	assert.Empty(t, resp.Derived)
+	assert.Equal(t, []models.IntradayRate{
+		{Timestamp: day.Add(9 * time.Hour).UTC(), Rate: 80.0},
+		{Timestamp: day.Add(9*time.Hour + time.Minute).UTC(), Rate: 80.5},
+	}, resp.Rates, "the previous day's rate is left out")
+
+	resp, err = service.GetIntradayRates(context.Background(), "INR", "USD", date)
+	require.NoError(t, err)
+	assert.Equal(t, models.DerivedInverse, resp.Derived)
+	require.Len(t, resp.Rates, 2)
+	assert.Equal(t, 0.0125, resp.Rates[0].Rate)
+
+	resp, err = service.GetIntradayRates(context.Background(), "USD", "EUR", "")
+	require.NoError(t, err)
+	assert.Equal(t, utils.Today().Format(utils.DateFormat), resp.Date)
+	assert.NotNil(t, resp.Rates)
+	assert.Empty(t, resp.Rates)
+
+	_, err = service.GetIntradayRates(context.Background(), "USD", "INR", "yesterday")
+	code, field := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeDateInvalid, code)
+	assert.Equal(t, "date", field)
+}
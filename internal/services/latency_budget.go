@@ -0,0 +1,25 @@
+package services
+
+import (
+	"time"
+
+	"exchange-rate-service/internal/models"
+)
+
+// latencyDeadline returns when a request with a budget of maxWaitMs
+// milliseconds, started now, must answer, or the zero time without a budget
+func latencyDeadline(maxWaitMs int) time.Time {
+	if maxWaitMs <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(maxWaitMs) * time.Millisecond)
+}
+
+// pendingDates reports dates whose lookup did not finish within the budget
+func pendingDates(dates []string) []models.MissingDate {
+	missing := make([]models.MissingDate, len(dates))
+	for i, date := range dates {
+		missing[i] = models.MissingDate{Date: date, Reason: models.MissingPending}
+	}
+	return missing
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+// newSlowHistoricalService serves USD rates of the day fast right away and
+// holds back the other days until release is closed
+func newSlowHistoricalService(t *testing.T, fast string, release <-chan struct{}, calls *int32) *ExchangeService {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		date := strings.TrimPrefix(r.URL.Path, "/")
+		if date != fast {
+			<-release
+		}
+		w.Write([]byte(`{"base":"USD","date":"` + date + `","rates":{"INR":85.0,"EUR":0.96}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := external.DefaultConfig()
+	cfg.FrankfurterBaseURL = server.URL
+	cfg.DefaultProvider = external.ProviderFrankfurter
+	cfg.Retry.MaxAttempts = 1
+	client := external.NewExchangeRateClientWithConfig(cfg)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	return NewExchangeService(memoryCache, NewRateFetcher(client, memoryCache), client)
+}
+
+func lastFullWeekDay(offset int) string {
+	friday := time.Now().AddDate(0, 0, -1)
+	for friday.Weekday() != time.Friday {
+		friday = friday.AddDate(0, 0, -1)
+	}
+	return friday.AddDate(0, 0, offset).Format(utils.DateFormat)
+}
+
+func TestExchangeService_HistoricalMaxWait(t *testing.T) {
+	wednesday, thursday, friday := lastFullWeekDay(-2), lastFullWeekDay(-1), lastFullWeekDay(0)
+	release := make(chan struct{})
+	var calls int32
+	service := newSlowHistoricalService(t, wednesday, release, &calls)
+
+	req := &models.HistoricalRateRequest{
+		From: "USD", To: "INR", StartDate: wednesday, EndDate: friday, MaxWaitMs: 100,
+	}
+	start := time.Now()
+	resp, err := service.GetHistoricalRates(context.Background(), req)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second, "the slow dates are not waited for")
+	assert.Equal(t, 85.0, resp.Rates[wednesday].Rate)
+	assert.Len(t, resp.Rates, 1)
+	assert.Equal(t, []models.MissingDate{
+		{Date: thursday, Reason: models.MissingPending},
+		{Date: friday, Reason: models.MissingPending},
+	}, resp.MissingDates)
+
+	// The pending lookups carry on and fill the cache for a retry
+	close(release)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 3 }, 2*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		resp, err = service.GetHistoricalRates(context.Background(), req)
+		return err == nil && len(resp.Rates) == 3
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Empty(t, resp.MissingDates)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	_, err = service.GetHistoricalRates(context.Background(), &models.HistoricalRateRequest{
+		From: "USD", To: "INR", StartDate: wednesday, EndDate: friday, MaxWaitMs: -1,
+	})
+	code, _ := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeValueInvalid, code)
+}
+
+func TestExchangeService_BulkHistoricalMaxWait(t *testing.T) {
+	wednesday := lastFullWeekDay(-2)
+	release := make(chan struct{})
+	defer close(release)
+	var calls int32
+	service := newSlowHistoricalService(t, "", release, &calls)
+	service.SetBulkHistoricalConcurrency(1)
+
+	resp, err := service.GetBulkHistoricalRates(context.Background(), &models.BulkHistoricalRequest{
+		Pairs: []string{"USD_INR", "USD_EUR"}, StartDate: wednesday, EndDate: wednesday, MaxWaitMs: 100,
+	}, nil)
+	require.NoError(t, err)
+	pending := []models.MissingDate{{Date: wednesday, Reason: models.MissingPending}}
+	assert.Equal(t, pending, resp.MissingDates["USD_INR"])
+	assert.Equal(t, pending, resp.MissingDates["USD_EUR"])
+	assert.Empty(t, resp.Rates["USD_INR"])
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "the pair waiting for its turn is not looked up")
+}
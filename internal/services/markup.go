@@ -0,0 +1,42 @@
+package services
+
+// Markup holds the spread applied on top of mid-market rates. Percentages are
+// expressed as plain numbers, so 0.5 means +0.5%. A Markup is immutable once
+// created.
+type Markup struct {
+	globalPercent float64
+	pairPercent   map[string]float64
+}
+
+func NewMarkup(globalPercent float64, pairPercent map[string]float64) *Markup {
+	pairs := make(map[string]float64, len(pairPercent))
+	for pair, percent := range pairPercent {
+		pairs[pair] = percent
+	}
+
+	return &Markup{
+		globalPercent: globalPercent,
+		pairPercent:   pairs,
+	}
+}
+
+// PercentFor returns the markup for a pair, falling back to the global markup
+func (m *Markup) PercentFor(from, to string) float64 {
+	if m == nil {
+		return 0
+	}
+
+	if percent, ok := m.pairPercent[from+"_"+to]; ok {
+		return percent
+	}
+	return m.globalPercent
+}
+
+// Apply returns the rate with the pair's markup applied and the percent used
+func (m *Markup) Apply(from, to string, rate float64) (float64, float64) {
+	percent := m.PercentFor(from, to)
+	if from == to || percent == 0 {
+		return rate, 0
+	}
+	return rate * (1 + percent/100), percent
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkup_PercentFor(t *testing.T) {
+	markup := NewMarkup(0.5, map[string]float64{"USD_INR": 1.0})
+
+	assert.Equal(t, 1.0, markup.PercentFor("USD", "INR"))
+	assert.Equal(t, 0.5, markup.PercentFor("INR", "USD"))
+	assert.Equal(t, 0.5, markup.PercentFor("EUR", "GBP"))
+}
+
+func TestMarkup_Apply(t *testing.T) {
+	markup := NewMarkup(0.5, map[string]float64{"USD_INR": 1.0})
+
+	rate, percent := markup.Apply("USD", "INR", 80.0)
+	assert.InDelta(t, 80.8, rate, 1e-9)
+	assert.Equal(t, 1.0, percent)
+
+	rate, percent = markup.Apply("EUR", "USD", 1.1)
+	assert.InDelta(t, 1.1055, rate, 1e-9)
+	assert.Equal(t, 0.5, percent)
+
+	rate, percent = markup.Apply("USD", "USD", 1.0)
+	assert.Equal(t, 1.0, rate)
+	assert.Equal(t, 0.0, percent)
+}
+
+func TestMarkup_Nil(t *testing.T) {
+	var markup *Markup
+
+	rate, percent := markup.Apply("USD", "INR", 80.0)
+	assert.Equal(t, 80.0, rate)
+	assert.Equal(t, 0.0, percent)
+}
@@ -0,0 +1,245 @@
+// Code generated by gen from services.ExchangeServiceInterface; DO NOT EDIT.
+
+package mocks
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+	"exchange-rate-service/internal/store"
+)
+
+var _ services.ExchangeServiceInterface = (*ExchangeService)(nil)
+
+// ExchangeService is a mock of services.ExchangeServiceInterface. It records
+// the name of every method called, in order.
+type ExchangeService struct {
+	ConvertCurrencyFunc        func(ctx context.Context, req *models.ConversionRequest) (*models.ConversionResponse, error)
+	ConvertChainFunc           func(ctx context.Context, req *models.ChainConversionRequest, allowed services.PairFilter) (*models.ChainConversionResponse, error)
+	SimulateConversionFunc     func(ctx context.Context, req *models.SimulationRequest) (*models.SimulationResponse, error)
+	GetLatestRateFunc          func(ctx context.Context, from, to, provider string) (*models.LatestRateResponse, error)
+	CompareRatesFunc           func(ctx context.Context, from, to string) (*models.RateComparison, error)
+	GetRateTableFunc           func(ctx context.Context, base string) (*models.RateTableResponse, error)
+	GetHistoricalRatesFunc     func(ctx context.Context, req *models.HistoricalRateRequest) (*models.HistoricalRateResponse, error)
+	GetBulkHistoricalRatesFunc func(ctx context.Context, req *models.BulkHistoricalRequest, allowed services.PairFilter) (*models.BulkHistoricalResponse, error)
+	GetIntradayRatesFunc       func(ctx context.Context, from, to, date string) (*models.IntradayRateResponse, error)
+	GetRateTrendFunc           func(ctx context.Context, req *models.TrendRequest) (*models.TrendResponse, error)
+	GetRateDiffFunc            func(ctx context.Context, req *models.RateDiffRequest) (*models.RateDiffResponse, error)
+	GetRateRecommendationFunc  func(ctx context.Context, req *models.RecommendationRequest) (*models.RecommendationResponse, error)
+	GetCorrelationFunc         func(ctx context.Context, req *models.CorrelationRequest) (*models.CorrelationResponse, error)
+	GetRateForecastFunc        func(ctx context.Context, req *models.ForecastRequest) (*models.ForecastResponse, error)
+	GetSupportedCurrenciesFunc func() []string
+	GetCurrencyMetadataFunc    func() []models.CurrencyInfo
+	GetSigningKeysFunc         func() []models.PublicKey
+	AddCurrencyFunc            func(code string) (bool, error)
+	RemoveCurrencyFunc         func(code string) (int, error)
+	RecordConversionFunc       func(caller string, conversion *models.ConversionResponse) error
+	RecordChainConversionFunc  func(caller string, chain *models.ChainConversionResponse) error
+	QueryConversionsFunc       func(filter store.AuditFilter) ([]store.ConversionRecord, int, error)
+	GetConversionFunc          func(id int64) (*store.ConversionRecord, error)
+	GetConversionReceiptFunc   func(conversionID string) (*store.ConversionRecord, error)
+	ClearCacheFunc             func()
+	InvalidatePairFunc         func(from, to string) (int, error)
+	RefetchHistoricalRatesFunc func(ctx context.Context, req *models.RefetchRequest, caller string) (*models.RefetchResponse, error)
+	ImportHistoricalRatesFunc  func(file io.Reader, req *models.RateImportRequest, caller string) (*models.RateImportResponse, error)
+	WarmCacheFunc              func(ctx context.Context) map[string]interface{}
+	GetServiceHealthFunc       func() map[string]interface{}
+	IsReadyFunc                func() (bool, string)
+	GetCacheStatsFunc          func() map[string]interface{}
+	GetProviderCacheStatsFunc  func(provider string) (map[string]interface{}, error)
+	GetClientStatsFunc         func() map[string]interface{}
+	GetProviderStatusFunc      func() []models.ProviderStatus
+	GetDiscrepancyStatsFunc    func() models.DiscrepancyStats
+	GetShadowStatsFunc         func() models.ShadowStats
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (m *ExchangeService) ConvertCurrency(ctx context.Context, req *models.ConversionRequest) (*models.ConversionResponse, error) {
+	m.record("ConvertCurrency", m.ConvertCurrencyFunc != nil)
+	return m.ConvertCurrencyFunc(ctx, req)
+}
+
+func (m *ExchangeService) ConvertChain(ctx context.Context, req *models.ChainConversionRequest, allowed services.PairFilter) (*models.ChainConversionResponse, error) {
+	m.record("ConvertChain", m.ConvertChainFunc != nil)
+	return m.ConvertChainFunc(ctx, req, allowed)
+}
+
+func (m *ExchangeService) SimulateConversion(ctx context.Context, req *models.SimulationRequest) (*models.SimulationResponse, error) {
+	m.record("SimulateConversion", m.SimulateConversionFunc != nil)
+	return m.SimulateConversionFunc(ctx, req)
+}
+
+func (m *ExchangeService) GetLatestRate(ctx context.Context, from, to, provider string) (*models.LatestRateResponse, error) {
+	m.record("GetLatestRate", m.GetLatestRateFunc != nil)
+	return m.GetLatestRateFunc(ctx, from, to, provider)
+}
+
+func (m *ExchangeService) CompareRates(ctx context.Context, from, to string) (*models.RateComparison, error) {
+	m.record("CompareRates", m.CompareRatesFunc != nil)
+	return m.CompareRatesFunc(ctx, from, to)
+}
+
+func (m *ExchangeService) GetRateTable(ctx context.Context, base string) (*models.RateTableResponse, error) {
+	m.record("GetRateTable", m.GetRateTableFunc != nil)
+	return m.GetRateTableFunc(ctx, base)
+}
+
+func (m *ExchangeService) GetHistoricalRates(ctx context.Context, req *models.HistoricalRateRequest) (*models.HistoricalRateResponse, error) {
+	m.record("GetHistoricalRates", m.GetHistoricalRatesFunc != nil)
+	return m.GetHistoricalRatesFunc(ctx, req)
+}
+
+func (m *ExchangeService) GetBulkHistoricalRates(ctx context.Context, req *models.BulkHistoricalRequest, allowed services.PairFilter) (*models.BulkHistoricalResponse, error) {
+	m.record("GetBulkHistoricalRates", m.GetBulkHistoricalRatesFunc != nil)
+	return m.GetBulkHistoricalRatesFunc(ctx, req, allowed)
+}
+
+func (m *ExchangeService) GetIntradayRates(ctx context.Context, from, to, date string) (*models.IntradayRateResponse, error) {
+	m.record("GetIntradayRates", m.GetIntradayRatesFunc != nil)
+	return m.GetIntradayRatesFunc(ctx, from, to, date)
+}
+
+func (m *ExchangeService) GetRateTrend(ctx context.Context, req *models.TrendRequest) (*models.TrendResponse, error) {
+	m.record("GetRateTrend", m.GetRateTrendFunc != nil)
+	return m.GetRateTrendFunc(ctx, req)
+}
+
+func (m *ExchangeService) GetRateDiff(ctx context.Context, req *models.RateDiffRequest) (*models.RateDiffResponse, error) {
+	m.record("GetRateDiff", m.GetRateDiffFunc != nil)
+	return m.GetRateDiffFunc(ctx, req)
+}
+
+func (m *ExchangeService) GetRateRecommendation(ctx context.Context, req *models.RecommendationRequest) (*models.RecommendationResponse, error) {
+	m.record("GetRateRecommendation", m.GetRateRecommendationFunc != nil)
+	return m.GetRateRecommendationFunc(ctx, req)
+}
+
+func (m *ExchangeService) GetCorrelation(ctx context.Context, req *models.CorrelationRequest) (*models.CorrelationResponse, error) {
+	m.record("GetCorrelation", m.GetCorrelationFunc != nil)
+	return m.GetCorrelationFunc(ctx, req)
+}
+
+func (m *ExchangeService) GetRateForecast(ctx context.Context, req *models.ForecastRequest) (*models.ForecastResponse, error) {
+	m.record("GetRateForecast", m.GetRateForecastFunc != nil)
+	return m.GetRateForecastFunc(ctx, req)
+}
+
+func (m *ExchangeService) GetSupportedCurrencies() []string {
+	m.record("GetSupportedCurrencies", m.GetSupportedCurrenciesFunc != nil)
+	return m.GetSupportedCurrenciesFunc()
+}
+
+func (m *ExchangeService) GetCurrencyMetadata() []models.CurrencyInfo {
+	m.record("GetCurrencyMetadata", m.GetCurrencyMetadataFunc != nil)
+	return m.GetCurrencyMetadataFunc()
+}
+
+func (m *ExchangeService) GetSigningKeys() []models.PublicKey {
+	m.record("GetSigningKeys", m.GetSigningKeysFunc != nil)
+	return m.GetSigningKeysFunc()
+}
+
+func (m *ExchangeService) AddCurrency(code string) (bool, error) {
+	m.record("AddCurrency", m.AddCurrencyFunc != nil)
+	return m.AddCurrencyFunc(code)
+}
+
+func (m *ExchangeService) RemoveCurrency(code string) (int, error) {
+	m.record("RemoveCurrency", m.RemoveCurrencyFunc != nil)
+	return m.RemoveCurrencyFunc(code)
+}
+
+func (m *ExchangeService) RecordConversion(caller string, conversion *models.ConversionResponse) error {
+	m.record("RecordConversion", m.RecordConversionFunc != nil)
+	return m.RecordConversionFunc(caller, conversion)
+}
+
+func (m *ExchangeService) RecordChainConversion(caller string, chain *models.ChainConversionResponse) error {
+	m.record("RecordChainConversion", m.RecordChainConversionFunc != nil)
+	return m.RecordChainConversionFunc(caller, chain)
+}
+
+func (m *ExchangeService) QueryConversions(filter store.AuditFilter) ([]store.ConversionRecord, int, error) {
+	m.record("QueryConversions", m.QueryConversionsFunc != nil)
+	return m.QueryConversionsFunc(filter)
+}
+
+func (m *ExchangeService) GetConversion(id int64) (*store.ConversionRecord, error) {
+	m.record("GetConversion", m.GetConversionFunc != nil)
+	return m.GetConversionFunc(id)
+}
+
+func (m *ExchangeService) GetConversionReceipt(conversionID string) (*store.ConversionRecord, error) {
+	m.record("GetConversionReceipt", m.GetConversionReceiptFunc != nil)
+	return m.GetConversionReceiptFunc(conversionID)
+}
+
+func (m *ExchangeService) ClearCache() {
+	m.record("ClearCache", m.ClearCacheFunc != nil)
+	m.ClearCacheFunc()
+}
+
+func (m *ExchangeService) InvalidatePair(from, to string) (int, error) {
+	m.record("InvalidatePair", m.InvalidatePairFunc != nil)
+	return m.InvalidatePairFunc(from, to)
+}
+
+func (m *ExchangeService) RefetchHistoricalRates(ctx context.Context, req *models.RefetchRequest, caller string) (*models.RefetchResponse, error) {
+	m.record("RefetchHistoricalRates", m.RefetchHistoricalRatesFunc != nil)
+	return m.RefetchHistoricalRatesFunc(ctx, req, caller)
+}
+
+func (m *ExchangeService) ImportHistoricalRates(file io.Reader, req *models.RateImportRequest, caller string) (*models.RateImportResponse, error) {
+	m.record("ImportHistoricalRates", m.ImportHistoricalRatesFunc != nil)
+	return m.ImportHistoricalRatesFunc(file, req, caller)
+}
+
+func (m *ExchangeService) WarmCache(ctx context.Context) map[string]interface{} {
+	m.record("WarmCache", m.WarmCacheFunc != nil)
+	return m.WarmCacheFunc(ctx)
+}
+
+func (m *ExchangeService) GetServiceHealth() map[string]interface{} {
+	m.record("GetServiceHealth", m.GetServiceHealthFunc != nil)
+	return m.GetServiceHealthFunc()
+}
+
+func (m *ExchangeService) IsReady() (bool, string) {
+	m.record("IsReady", m.IsReadyFunc != nil)
+	return m.IsReadyFunc()
+}
+
+func (m *ExchangeService) GetCacheStats() map[string]interface{} {
+	m.record("GetCacheStats", m.GetCacheStatsFunc != nil)
+	return m.GetCacheStatsFunc()
+}
+
+func (m *ExchangeService) GetProviderCacheStats(provider string) (map[string]interface{}, error) {
+	m.record("GetProviderCacheStats", m.GetProviderCacheStatsFunc != nil)
+	return m.GetProviderCacheStatsFunc(provider)
+}
+
+func (m *ExchangeService) GetClientStats() map[string]interface{} {
+	m.record("GetClientStats", m.GetClientStatsFunc != nil)
+	return m.GetClientStatsFunc()
+}
+
+func (m *ExchangeService) GetProviderStatus() []models.ProviderStatus {
+	m.record("GetProviderStatus", m.GetProviderStatusFunc != nil)
+	return m.GetProviderStatusFunc()
+}
+
+func (m *ExchangeService) GetDiscrepancyStats() models.DiscrepancyStats {
+	m.record("GetDiscrepancyStats", m.GetDiscrepancyStatsFunc != nil)
+	return m.GetDiscrepancyStatsFunc()
+}
+
+func (m *ExchangeService) GetShadowStats() models.ShadowStats {
+	m.record("GetShadowStats", m.GetShadowStatsFunc != nil)
+	return m.GetShadowStatsFunc()
+}
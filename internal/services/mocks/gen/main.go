@@ -0,0 +1,194 @@
+// Command gen writes the mock of services.ExchangeServiceInterface in
+// mocks/exchange_service.go from the interface's declaration, so the mock
+// follows every change of the interface. Run it with go generate in
+// internal/services/mocks.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	interfaceFile = "../interface.go"
+	interfaceName = "ExchangeServiceInterface"
+	mockFile      = "exchange_service.go"
+	servicesPath  = "exchange-rate-service/internal/services"
+)
+
+func main() {
+	src, err := os.ReadFile(interfaceFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	out, err := generate(src)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(mockFile, out, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// method is one method of the interface, with its types qualified for the
+// mocks package
+type method struct {
+	name      string
+	signature string // Parameters and results, as after the method's name
+	funcType  string // The signature as a func type
+	args      string // The parameters' names, to call the function with
+	returns   bool
+}
+
+// generate returns the mock of the interface declared in src
+func generate(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, interfaceFile, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	iface := findInterface(file)
+	if iface == nil {
+		return nil, fmt.Errorf("%s not found in %s", interfaceName, interfaceFile)
+	}
+
+	imports := map[string]string{"services": servicesPath}
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		imports[path[strings.LastIndex(path, "/")+1:]] = path
+	}
+	used := map[string]bool{"services": true}
+
+	var methods []method
+	for _, field := range iface.Methods.List {
+		fn, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) != 1 {
+			return nil, fmt.Errorf("%s embeds an interface; only methods are supported", interfaceName)
+		}
+		qualify(fn, used)
+		m := method{name: field.Names[0].Name, returns: fn.Results != nil && len(fn.Results.List) > 0}
+		var names []string
+		for i, param := range fn.Params.List {
+			if len(param.Names) == 0 {
+				return nil, fmt.Errorf("parameter %d of %s has no name", i, m.name)
+			}
+			for _, name := range param.Names {
+				names = append(names, name.Name)
+			}
+		}
+		m.args = strings.Join(names, ", ")
+		m.funcType = nodeString(fset, fn)
+		m.signature = strings.TrimPrefix(m.funcType, "func")
+		methods = append(methods, m)
+	}
+
+	var buf bytes.Buffer
+	writeMock(&buf, methods, imports, used)
+	return format.Source(buf.Bytes())
+}
+
+func findInterface(file *ast.File) *ast.InterfaceType {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			if ts := spec.(*ast.TypeSpec); ts.Name.Name == interfaceName {
+				iface, _ := ts.Type.(*ast.InterfaceType)
+				return iface
+			}
+		}
+	}
+	return nil
+}
+
+// qualify prefixes the types the services package declares with its name
+// and notes which imports the types use
+func qualify(fn *ast.FuncType, used map[string]bool) {
+	ast.Inspect(fn, func(n ast.Node) bool {
+		field, ok := n.(*ast.Field)
+		if !ok {
+			return true
+		}
+		// Only a field's type is qualified, not its names
+		ast.Inspect(field.Type, func(t ast.Node) bool {
+			switch t := t.(type) {
+			case *ast.SelectorExpr:
+				if pkg, ok := t.X.(*ast.Ident); ok {
+					used[pkg.Name] = true
+				}
+				return false
+			case *ast.Ident:
+				if ast.IsExported(t.Name) {
+					t.Name = "services." + t.Name
+				}
+			}
+			return true
+		})
+		return false
+	})
+}
+
+func nodeString(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, fset, node)
+	return buf.String()
+}
+
+func writeMock(buf *bytes.Buffer, methods []method, imports map[string]string, used map[string]bool) {
+	module, _, _ := strings.Cut(servicesPath, "/")
+	var std, local []string
+	for name := range used {
+		path := imports[name]
+		// Standard library paths have no dot in their first element
+		if first, _, _ := strings.Cut(path, "/"); strings.Contains(first, ".") || first == module {
+			local = append(local, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	std = append(std, "sync")
+	sort.Strings(std)
+	sort.Strings(local)
+
+	fmt.Fprintf(buf, "// Code generated by gen from services.%s; DO NOT EDIT.\n\n", interfaceName)
+	buf.WriteString("package mocks\n\nimport (\n")
+	for _, path := range std {
+		fmt.Fprintf(buf, "\t%q\n", path)
+	}
+	buf.WriteString("\n")
+	for _, path := range local {
+		fmt.Fprintf(buf, "\t%q\n", path)
+	}
+	buf.WriteString(")\n\n")
+
+	fmt.Fprintf(buf, "var _ services.%s = (*ExchangeService)(nil)\n\n", interfaceName)
+	fmt.Fprintf(buf, "// ExchangeService is a mock of services.%s. It records\n// the name of every method called, in order.\n", interfaceName)
+	buf.WriteString("type ExchangeService struct {\n")
+	for _, m := range methods {
+		fmt.Fprintf(buf, "\t%sFunc %s\n", m.name, m.funcType)
+	}
+	buf.WriteString("\n\tmu    sync.Mutex\n\tcalls []string\n}\n")
+
+	for _, m := range methods {
+		fmt.Fprintf(buf, "\nfunc (m *ExchangeService) %s%s {\n", m.name, m.signature)
+		fmt.Fprintf(buf, "\tm.record(%q, m.%sFunc != nil)\n", m.name, m.name)
+		if m.returns {
+			buf.WriteString("\treturn ")
+		} else {
+			buf.WriteString("\t")
+		}
+		fmt.Fprintf(buf, "m.%sFunc(%s)\n}\n", m.name, m.args)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockIsUpToDate(t *testing.T) {
+	src, err := os.ReadFile("../" + interfaceFile)
+	require.NoError(t, err)
+	want, err := generate(src)
+	require.NoError(t, err)
+
+	got, err := os.ReadFile("../" + mockFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got), "the mock is out of date; run go generate in internal/services/mocks")
+}
@@ -0,0 +1,49 @@
+// Package mocks provides a mock of services.ExchangeServiceInterface for
+// testing the handlers of this module without a provider. Every method
+// calls the function of the same name with a Func suffix and panics when it
+// is not set, so a test only stubs what it expects to be called:
+//
+//	service := &mocks.ExchangeService{
+//		GetLatestRateFunc: func(ctx context.Context, from, to, provider string) (*models.LatestRateResponse, error) {
+//			return &models.LatestRateResponse{From: from, To: to, Rate: 83.5}, nil
+//		},
+//	}
+//	handler := handlers.NewExchangeHandler(service)
+//
+// The mock is generated from the interface; run go generate here after
+// changing it.
+package mocks
+
+//go:generate go run ./gen
+
+// Calls returns the names of the methods called so far, in order
+func (m *ExchangeService) Calls() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.calls...)
+}
+
+// CallCount returns how many times method was called
+func (m *ExchangeService) CallCount(method string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, call := range m.calls {
+		if call == method {
+			count++
+		}
+	}
+	return count
+}
+
+// record notes a call and panics when its function is not set
+func (m *ExchangeService) record(method string, set bool) {
+	m.mu.Lock()
+	m.calls = append(m.calls, method)
+	m.mu.Unlock()
+
+	if !set {
+		panic("mocks.ExchangeService: " + method + " called but " + method + "Func is not set")
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"container/heap"
+	"time"
+)
+
+// PairSchedule configures how often one currency pair is refreshed. Pairs
+// without a schedule are refreshed on the fetcher's default interval.
+type PairSchedule struct {
+	From     string
+	To       string
+	Interval time.Duration
+	Priority int // Among pairs due at the same time, higher goes first
+}
+
+// scheduledPair is a pair waiting in the fetch queue
+type scheduledPair struct {
+	PairSchedule
+	next  time.Time
+	index int
+}
+
+// pairQueue is a min-heap of pairs ordered by next due time, then priority
+type pairQueue []*scheduledPair
+
+func (q pairQueue) Len() int { return len(q) }
+
+func (q pairQueue) Less(i, j int) bool {
+	if !q[i].next.Equal(q[j].next) {
+		return q[i].next.Before(q[j].next)
+	}
+	return q[i].Priority > q[j].Priority
+}
+
+func (q pairQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *pairQueue) Push(x interface{}) {
+	pair := x.(*scheduledPair)
+	pair.index = len(*q)
+	*q = append(*q, pair)
+}
+
+func (q *pairQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	pair := old[n-1]
+	old[n-1] = nil
+	pair.index = -1
+	*q = old[:n-1]
+	return pair
+}
+
+// peek returns the pair due first without removing it
+func (q pairQueue) peek() *scheduledPair {
+	if len(q) == 0 {
+		return nil
+	}
+	return q[0]
+}
+
+// reschedule moves pair to its next due time
+func (q *pairQueue) reschedule(pair *scheduledPair, next time.Time) {
+	pair.next = next
+	heap.Fix(q, pair.index)
+}
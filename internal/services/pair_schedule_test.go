@@ -0,0 +1,67 @@
+package services
+
+import (
+	"container/heap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+)
+
+func TestPairQueue_OrdersByDueTimeThenPriority(t *testing.T) {
+	now := time.Now()
+	queue := pairQueue{}
+	heap.Push(&queue, &scheduledPair{PairSchedule: PairSchedule{From: "EUR", To: "GBP"}, next: now.Add(time.Hour)})
+	heap.Push(&queue, &scheduledPair{PairSchedule: PairSchedule{From: "USD", To: "JPY", Priority: 1}, next: now})
+	heap.Push(&queue, &scheduledPair{PairSchedule: PairSchedule{From: "USD", To: "INR", Priority: 10}, next: now})
+
+	var order []string
+	for queue.Len() > 0 {
+		pair := heap.Pop(&queue).(*scheduledPair)
+		order = append(order, pair.From+"_"+pair.To)
+	}
+	assert.Equal(t, []string{"USD_INR", "USD_JPY", "EUR_GBP"}, order)
+}
+
+func TestRateFetcher_RefreshDueHonoursPairIntervals(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		mu.Lock()
+		calls[base]++
+		mu.Unlock()
+		w.Write([]byte(`{"base":"` + base + `","rates":{"USD":1.1,"INR":83.5,"EUR":0.9,"GBP":0.8,"JPY":150}}`))
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = server.URL
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	fetcher := NewRateFetcher(external.NewExchangeRateClientWithConfig(cfg), memoryCache)
+	fetcher.SetSchedule(time.Hour, []PairSchedule{{From: "USD", To: "INR", Interval: 5 * time.Minute, Priority: 1}})
+
+	start := time.Now()
+	queue := fetcher.buildQueue(start)
+	require.Equal(t, 20, queue.Len())
+
+	// Only USD/INR is due after five minutes; the USD fetch refreshes every USD pair
+	fetcher.refreshDue(&queue, start.Add(5*time.Minute))
+	assert.Equal(t, map[string]int{"USD": 1}, calls)
+	_, found := memoryCache.Get(external.ProviderExchangeRateAPI, "USD", "JPY", "")
+	assert.True(t, found)
+	assert.True(t, start.Add(10*time.Minute).Equal(queue.peek().next))
+
+	// After an hour every base is due and fetched exactly once
+	fetcher.refreshDue(&queue, start.Add(time.Hour))
+	assert.Equal(t, map[string]int{"USD": 2, "INR": 1, "EUR": 1, "GBP": 1, "JPY": 1}, calls)
+	assert.False(t, fetcher.Status().LastSuccess.IsZero())
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/models"
+)
+
+// QuoteConfig bounds how long quotes lock their rate
+type QuoteConfig struct {
+	DefaultTTL time.Duration // Lock of a quote asking for no particular validity
+	MaxTTL     time.Duration // Longest lock a quote may ask for
+	Retention  time.Duration // How long expired and executed quotes are kept
+}
+
+// DefaultQuoteConfig locks rates for 5 minutes unless asked for up to 30,
+// and keeps quotes an hour after they expire or are executed
+func DefaultQuoteConfig() QuoteConfig {
+	return QuoteConfig{
+		DefaultTTL: 5 * time.Minute,
+		MaxTTL:     30 * time.Minute,
+		Retention:  time.Hour,
+	}
+}
+
+// Quotes locks the latest rate of conversions for a few minutes, so a
+// checkout can honor the price it displayed. Quotes are held in memory.
+type Quotes struct {
+	service *ExchangeService
+	cfg     QuoteConfig
+
+	mu     sync.Mutex
+	quotes map[string]*models.Quote
+}
+
+func NewQuotes(service *ExchangeService, cfg QuoteConfig) *Quotes {
+	return &Quotes{
+		service: service,
+		cfg:     cfg,
+		quotes:  make(map[string]*models.Quote),
+	}
+}
+
+// Create converts at the latest rate, within the tenant of ctx, and locks
+// the conversion for caller. Pairs allowed rejects fail with
+// PAIR_NOT_ALLOWED; a nil allowed permits every pair.
+func (q *Quotes) Create(ctx context.Context, caller string, req *models.QuoteRequest, allowed PairFilter) (*models.Quote, error) {
+	ttl := q.cfg.DefaultTTL
+	if req.ValidMinutes != 0 {
+		ttl = time.Duration(req.ValidMinutes) * time.Minute
+		if req.ValidMinutes < 0 || ttl > q.cfg.MaxTTL {
+			return nil, models.NewFieldError(models.ErrCodeValueInvalid, "valid_minutes", "valid_minutes must be between 1 and %d", int(q.cfg.MaxTTL/time.Minute))
+		}
+	}
+	if allowed != nil && !allowed(req.From, req.To) {
+		return nil, models.NewFieldError(models.ErrCodePairNotAllowed, "to", "%s/%s is not allowed", req.From, req.To)
+	}
+
+	conversion, err := q.service.ConvertCurrency(ctx, &models.ConversionRequest{
+		From:     req.From,
+		To:       req.To,
+		Amount:   req.Amount,
+		Provider: req.Provider,
+	})
+	if err != nil {
+		return nil, err
+	}
+	id, err := newQuoteID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	quote := &models.Quote{
+		ID:         id,
+		Status:     models.QuoteActive,
+		Caller:     caller,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(ttl),
+		Conversion: conversion,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.prune(now)
+	q.quotes[id] = quote
+	copied := *quote
+	return &copied, nil
+}
+
+// Get returns a quote, expired once its lock has passed
+func (q *Quotes) Get(id string) (*models.Quote, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	q.prune(now)
+	quote, ok := q.quotes[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *quote
+	if copied.Status == models.QuoteActive && !now.Before(quote.ExpiresAt) {
+		copied.Status = models.QuoteExpired
+	}
+	return &copied, true
+}
+
+// Execute converts at the locked rate of an active quote and records the
+// conversion to caller's audit trail. A quote executes once: later attempts
+// fail with QUOTE_EXECUTED, and attempts after its lock with QUOTE_EXPIRED.
+// A conversion that couldn't be recorded fails and leaves the quote active.
+func (q *Quotes) Execute(id, caller string) (*models.Quote, error) {
+	q.mu.Lock()
+	quote, ok := q.quotes[id]
+	if !ok {
+		q.mu.Unlock()
+		return nil, models.NewError(models.ErrCodeNotFound, "quote %s not found", id)
+	}
+	now := time.Now().UTC()
+	switch {
+	case quote.Status == models.QuoteExecuted:
+		q.mu.Unlock()
+		return nil, models.NewError(models.ErrCodeQuoteExecuted, "quote %s was already executed", id)
+	case !now.Before(quote.ExpiresAt):
+		q.mu.Unlock()
+		return nil, models.NewError(models.ErrCodeQuoteExpired, "quote %s expired at %s", id, quote.ExpiresAt.Format(time.RFC3339))
+	}
+	quote.Status = models.QuoteExecuted
+	quote.ExecutedAt = &now
+	conversion := *quote.Conversion
+	q.mu.Unlock()
+
+	// Recording sets the conversion's receipt ID, which the quote keeps
+	err := q.service.RecordConversion(caller, &conversion)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err != nil {
+		log.Printf("Failed to audit quote %s: %v", id, errors.Unwrap(err))
+		quote.Status = models.QuoteActive
+		quote.ExecutedAt = nil
+		return nil, err
+	}
+	quote.Conversion = &conversion
+	copied := *quote
+	return &copied, nil
+}
+
+// prune drops quotes that expired or were executed longer than the
+// retention period ago. The caller holds q.mu.
+func (q *Quotes) prune(now time.Time) {
+	for id, quote := range q.quotes {
+		done := quote.ExpiresAt
+		if quote.ExecutedAt != nil {
+			done = *quote.ExecutedAt
+		}
+		if now.Sub(done) > q.cfg.Retention {
+			delete(q.quotes, id)
+		}
+	}
+}
+
+func newQuoteID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate quote ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/store"
+)
+
+func TestQuotes_LockAndExecute(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 83.5)
+	service := NewExchangeService(memoryCache, nil, nil)
+	auditLog, err := store.NewAuditLog("", 0)
+	require.NoError(t, err)
+	service.SetAuditLog(auditLog)
+	quotes := NewQuotes(service, DefaultQuoteConfig())
+
+	quote, err := quotes.Create(context.Background(), "shop", &models.QuoteRequest{From: "USD", To: "INR", Amount: 10, ValidMinutes: 10}, nil)
+	require.NoError(t, err)
+	assert.Len(t, quote.ID, 32)
+	assert.Equal(t, models.QuoteActive, quote.Status)
+	assert.Equal(t, 10*time.Minute, quote.ExpiresAt.Sub(quote.CreatedAt))
+	assert.Equal(t, 835.0, quote.Conversion.ConvertedAmount)
+
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 84.0)
+	executed, err := quotes.Execute(quote.ID, "shop")
+	require.NoError(t, err)
+	assert.Equal(t, models.QuoteExecuted, executed.Status)
+	require.NotNil(t, executed.ExecutedAt)
+	assert.Equal(t, 83.5, executed.Conversion.Rate, "the locked rate is honored after the rate moved")
+	assert.Empty(t, quote.Conversion.ConversionID, "only executed quotes are conversions")
+	receipt, err := service.GetConversionReceipt(executed.Conversion.ConversionID)
+	require.NoError(t, err)
+	assert.Equal(t, "shop", receipt.Caller)
+	got, ok := quotes.Get(quote.ID)
+	require.True(t, ok)
+	assert.Equal(t, executed.Conversion.ConversionID, got.Conversion.ConversionID)
+
+	_, err = quotes.Execute(quote.ID, "shop")
+	code, _ := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeQuoteExecuted, code)
+
+	expiring, err := quotes.Create(context.Background(), "shop", &models.QuoteRequest{From: "USD", To: "INR", Amount: 1}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultQuoteConfig().DefaultTTL, expiring.ExpiresAt.Sub(expiring.CreatedAt))
+	quotes.mu.Lock()
+	quotes.quotes[expiring.ID].ExpiresAt = time.Now().Add(-time.Second)
+	quotes.mu.Unlock()
+	got, ok = quotes.Get(expiring.ID)
+	require.True(t, ok)
+	assert.Equal(t, models.QuoteExpired, got.Status)
+	_, err = quotes.Execute(expiring.ID, "shop")
+	code, _ = models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeQuoteExpired, code)
+
+	usdOnly := func(from, to string) bool { return from == "USD" && to == "EUR" }
+	tests := []struct {
+		name    string
+		req     models.QuoteRequest
+		allowed PairFilter
+		code    string
+	}{
+		{"validity too long", models.QuoteRequest{From: "USD", To: "INR", Amount: 1, ValidMinutes: 31}, nil, models.ErrCodeValueInvalid},
+		{"negative validity", models.QuoteRequest{From: "USD", To: "INR", Amount: 1, ValidMinutes: -1}, nil, models.ErrCodeValueInvalid},
+		{"pair not allowed", models.QuoteRequest{From: "USD", To: "INR", Amount: 1}, usdOnly, models.ErrCodePairNotAllowed},
+		{"invalid amount", models.QuoteRequest{From: "USD", To: "INR", Amount: -1}, nil, models.ErrCodeAmountInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := quotes.Create(context.Background(), "shop", &tt.req, tt.allowed)
+			require.Error(t, err)
+			code, _ := models.ErrorCodeOf(err)
+			assert.Equal(t, tt.code, code)
+		})
+	}
+}
+
+func TestQuotes_UnauditedExecutionFails(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("needs /dev/full to fail audit writes")
+	}
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 83.5)
+	service := NewExchangeService(memoryCache, nil, nil)
+	auditLog, err := store.NewAuditLog("/dev/full", 0)
+	require.NoError(t, err)
+	defer auditLog.Close()
+	service.SetAuditLog(auditLog)
+	quotes := NewQuotes(service, DefaultQuoteConfig())
+
+	quote, err := quotes.Create(context.Background(), "shop", &models.QuoteRequest{From: "USD", To: "INR", Amount: 10}, nil)
+	require.NoError(t, err)
+	_, err = quotes.Execute(quote.ID, "shop")
+	code, _ := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeInternal, code)
+
+	got, ok := quotes.Get(quote.ID)
+	require.True(t, ok)
+	assert.Equal(t, models.QuoteActive, got.Status, "a quote stays executable when its conversion couldn't be recorded")
+	assert.Nil(t, got.ExecutedAt)
+}
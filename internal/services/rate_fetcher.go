@@ -1,7 +1,10 @@
 package services
 
 import (
+	"container/heap"
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -9,37 +12,288 @@ import (
 	"exchange-rate-service/internal/cache"
 	"exchange-rate-service/internal/external"
 	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/store"
 )
 
 type RateFetcher struct {
 	client        *external.ExchangeRateClient
 	cache         cache.CacheInterface
-	currencies    []string
 	fetchInterval time.Duration
+	schedules     map[string]PairSchedule // "FROM_TO" -> schedule
+	rescheduled   chan struct{}           // Signals the scheduling loop to rebuild its queue
+	history       *store.RateHistory      // Intraday rates, nil when not kept
+	negativeTTL   time.Duration           // How long "rate not found" answers are cached, 0 disables
+	metals        string                  // Provider precious metal pairs are fetched from, "" for the default
+	onCycle       func([]FetchedRate)     // Called with the rates each cycle refreshed, nil when unset
+	pivot         string                  // Currency every base of the default provider is derived from, "" to fetch each base
+	perBase       map[string]bool         // Providers always fetched per base
+	pool          *fetchPool              // Bounds the bases a cycle fetches at once
+	hot           *hotPairs               // Adapts pair intervals to requests, nil when not adapted
+	standby       func() bool             // Reports whether scheduled cycles leave the upstream to a peer, nil when never
 	mu            sync.RWMutex
 	isRunning     bool
+	lastAttempt   time.Time
+	lastSuccess   time.Time
+	lastError     string
 	ctx           context.Context
 	cancel        context.CancelFunc
 }
 
-func NewRateFetcher(client *external.ExchangeRateClient, cache cache.CacheInterface) *RateFetcher {
-	currencies := make([]string, 0, len(models.SupportedCurrencies))
-	for currency := range models.SupportedCurrencies {
-		currencies = append(currencies, currency)
-	}
+// FetchStatus describes the outcome of the most recent fetch cycles
+type FetchStatus struct {
+	LastAttempt time.Time
+	LastSuccess time.Time // Zero until a cycle refreshed at least one pair
+	LastError   string
+}
+
+// FetchedRate is a latest rate refreshed by a fetch cycle
+type FetchedRate struct {
+	From        string
+	To          string
+	Rate        float64
+	Provider    string
+	PublishedAt time.Time
+}
 
+func NewRateFetcher(client *external.ExchangeRateClient, cache cache.CacheInterface) *RateFetcher {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &RateFetcher{
 		client:        client,
 		cache:         cache,
-		currencies:    currencies,
-		fetchInterval: 1 * time.Hour, 
+		fetchInterval: 1 * time.Hour,
+		rescheduled:   make(chan struct{}, 1),
+		schedules:     make(map[string]PairSchedule),
+		pool:          newFetchPool(DefaultFetchPoolConfig()),
 		ctx:           ctx,
 		cancel:        cancel,
 	}
 }
 
+// SetHistory makes the fetcher record every latest rate it fetches, stamped
+// with the provider's publication time
+func (rf *RateFetcher) SetHistory(history *store.RateHistory) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	rf.history = history
+}
+
+func (rf *RateFetcher) getHistory() *store.RateHistory {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	return rf.history
+}
+
+// SetCycleListener makes the fetcher call listener with the rates each fetch
+// cycle refreshed, once they are cached. The listener runs on the fetching
+// goroutine and must not block.
+func (rf *RateFetcher) SetCycleListener(listener func([]FetchedRate)) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	rf.onCycle = listener
+}
+
+func (rf *RateFetcher) getCycleListener() func([]FetchedRate) {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	return rf.onCycle
+}
+
+// SetStandby makes scheduled fetch cycles skip the upstream while standby
+// reports true, e.g. while a peer replicates its rates. Pairs are still
+// rescheduled, so fetching resumes on their next turn once standby ends.
+func (rf *RateFetcher) SetStandby(standby func() bool) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	rf.standby = standby
+}
+
+func (rf *RateFetcher) inStandby() bool {
+	rf.mu.RLock()
+	standby := rf.standby
+	rf.mu.RUnlock()
+	return standby != nil && standby()
+}
+
+// SetPivot makes fetch cycles download a single table, the default
+// provider's rates against pivot, and derive every other base from it as
+// cross rates: A/B = (pivot/B) / (pivot/A). A cycle then costs one upstream
+// call instead of one per base. Providers in perBase keep fetching every
+// base, for those quoting asymmetric spreads where the cross rate differs
+// from the quoted one. An empty pivot fetches every base.
+func (rf *RateFetcher) SetPivot(pivot string, perBase []string) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	rf.pivot = pivot
+	rf.perBase = make(map[string]bool, len(perBase))
+	for _, provider := range perBase {
+		rf.perBase[provider] = true
+	}
+}
+
+// getPivot returns the currency the default provider's rates are derived
+// from, or "" when every base is fetched
+func (rf *RateFetcher) getPivot() string {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	if rf.pivot == "" || rf.perBase[rf.client.DefaultProvider()] {
+		return ""
+	}
+	return rf.pivot
+}
+
+// SetFetchPool sets how many base currencies a cycle fetches at once and
+// how long a cycle may run. It applies from the next cycle on.
+func (rf *RateFetcher) SetFetchPool(cfg FetchPoolConfig) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	rf.pool = newFetchPool(cfg)
+}
+
+func (rf *RateFetcher) getPool() *fetchPool {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	return rf.pool
+}
+
+// PoolStats returns the fetch pool's size and back-pressure counters
+func (rf *RateFetcher) PoolStats() map[string]interface{} {
+	return rf.getPool().Stats()
+}
+
+// SetHotPairs makes the fetcher adapt the refresh interval of pairs without
+// a schedule to how often they are requested, see HotPairConfig. A disabled
+// cfg refreshes every such pair on the default interval. When the fetcher is
+// running, every pair is rescheduled from now on.
+func (rf *RateFetcher) SetHotPairs(cfg HotPairConfig) {
+	rf.mu.Lock()
+	rf.hot = nil
+	if cfg.Enabled() {
+		rf.hot = newHotPairs(cfg)
+	}
+	rf.mu.Unlock()
+
+	rf.Reschedule()
+}
+
+func (rf *RateFetcher) getHotPairs() *hotPairs {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	return rf.hot
+}
+
+// RecordAccess counts a request for the latest rate of a pair, for picking
+// the pairs refreshed on the hot interval
+func (rf *RateFetcher) RecordAccess(from, to string) {
+	if hot := rf.getHotPairs(); hot != nil {
+		hot.record(from, to)
+	}
+}
+
+// HotPairStats returns the pairs refreshed on the hot interval and the
+// upstream calls per hour they cost, nil when intervals are not adapted
+func (rf *RateFetcher) HotPairStats() map[string]interface{} {
+	if hot := rf.getHotPairs(); hot != nil {
+		return hot.stats()
+	}
+	return nil
+}
+
+// SetNegativeTTL sets how long a pair the provider has no rate for is
+// remembered, so repeated requests for it don't go upstream. 0 disables
+// negative caching.
+func (rf *RateFetcher) SetNegativeTTL(ttl time.Duration) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	rf.negativeTTL = ttl
+}
+
+func (rf *RateFetcher) getNegativeTTL() time.Duration {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	return rf.negativeTTL
+}
+
+// SetMetalsProvider routes pairs involving a precious metal such as XAU to
+// provider, for default providers that only quote fiat currencies. Their
+// rates are cached like the default provider's, since it is their only
+// source. An empty provider fetches metals from the default provider.
+func (rf *RateFetcher) SetMetalsProvider(provider string) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	rf.metals = provider
+}
+
+// route returns the provider a pair is fetched from: provider when a
+// request pins one, the metals provider for pairs involving a metal, and ""
+// (the default provider) otherwise
+func (rf *RateFetcher) route(provider, from, to string) string {
+	if provider != "" || !(models.IsMetal(from) || models.IsMetal(to)) {
+		return provider
+	}
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	if rf.metals == rf.client.DefaultProvider() {
+		return ""
+	}
+	return rf.metals
+}
+
+// cacheProvider returns the provider whose cached rates of a pair are
+// served: the metals provider for metal pairs, the default one otherwise
+func (rf *RateFetcher) cacheProvider(from, to string) string {
+	if rf.client == nil {
+		// The default provider of a default client
+		return external.ProviderExchangeRateAPI
+	}
+	if provider := rf.route("", from, to); provider != "" {
+		return provider
+	}
+	return rf.client.DefaultProvider()
+}
+
+// rememberNotFound caches a "rate not found" answer of the provider as a
+// negative entry of the tenant of ctx. Other failures may be transient and
+// are not cached.
+func (rf *RateFetcher) rememberNotFound(ctx context.Context, from, to, date string, err error) {
+	if ttl := rf.getNegativeTTL(); ttl > 0 && errors.Is(err, external.ErrRateNotFound) {
+		rf.cache.SetNegative(rf.cacheProvider(from, to), negativeScope(ctx, from), to, date, ttl)
+	}
+}
+
+// storeLatest caches a fetched latest rate and records it in the history
+func (rf *RateFetcher) storeLatest(result rateResult) {
+	rf.cache.SetWithSource(result.from, result.to, "", result.rate, result.source())
+	if history := rf.getHistory(); history != nil && result.from != result.to {
+		history.Record(result.from, result.to, result.rate, result.at)
+	}
+}
+
+// SetSchedule sets the default refresh interval and per-pair overrides. When
+// the fetcher is running, every pair is rescheduled from now on.
+func (rf *RateFetcher) SetSchedule(defaultInterval time.Duration, pairs []PairSchedule) {
+	rf.mu.Lock()
+	if defaultInterval > 0 {
+		rf.fetchInterval = defaultInterval
+	}
+	rf.schedules = make(map[string]PairSchedule, len(pairs))
+	for _, pair := range pairs {
+		rf.schedules[pair.From+"_"+pair.To] = pair
+	}
+	rf.mu.Unlock()
+
+	rf.Reschedule()
+}
+
+// Reschedule makes the scheduling loop rebuild its queue, picking up changes
+// to the schedule or to the supported currencies
+func (rf *RateFetcher) Reschedule() {
+	select {
+	case rf.rescheduled <- struct{}{}:
+	default: // a rebuild is already pending
+	}
+}
+
 func (rf *RateFetcher) Start() {
 	rf.mu.Lock()
 	if rf.isRunning {
@@ -51,7 +305,7 @@ func (rf *RateFetcher) Start() {
 
 	log.Println("Starting rate fetcher service...")
 
-	go rf.fetchAllRates()
+	go rf.fetchStartup(rf.ctx)
 
 	go rf.periodicFetch()
 }
@@ -75,69 +329,496 @@ func (rf *RateFetcher) IsRunning() bool {
 	return rf.isRunning
 }
 
+// Status returns when rates were last fetched and the last fetch error
+func (rf *RateFetcher) Status() FetchStatus {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	return FetchStatus{
+		LastAttempt: rf.lastAttempt,
+		LastSuccess: rf.lastSuccess,
+		LastError:   rf.lastError,
+	}
+}
+
+// FetchInterval returns the default time between refreshes of a pair
+func (rf *RateFetcher) FetchInterval() time.Duration {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	return rf.fetchInterval
+}
+
 func (rf *RateFetcher) periodicFetch() {
-	ticker := time.NewTicker(rf.fetchInterval)
-	defer ticker.Stop()
+	queue := rf.buildQueue(time.Now())
+	adapter := rf.newAdapter()
 
 	for {
+		var timer *time.Timer
+		var due <-chan time.Time
+		if head := queue.peek(); head != nil {
+			timer = time.NewTimer(time.Until(head.next))
+			due = timer.C
+		}
+
+		var adapt <-chan time.Time
+		if adapter != nil {
+			adapt = adapter.C
+		}
+
 		select {
 		case <-rf.ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			if adapter != nil {
+				adapter.Stop()
+			}
 			log.Println("Rate fetcher stopped")
 			return
-		case <-ticker.C:
-			rf.fetchAllRates()
+		case <-due:
+			rf.refreshDue(&queue, time.Now())
+		case <-adapt:
+			if timer != nil {
+				timer.Stop()
+			}
+			rf.adaptQueue(&queue, time.Now())
+		case <-rf.rescheduled:
+			if timer != nil {
+				timer.Stop()
+			}
+			if adapter != nil {
+				adapter.Stop()
+			}
+			queue = rf.buildQueue(time.Now())
+			adapter = rf.newAdapter()
 		}
 	}
 }
 
-func (rf *RateFetcher) fetchAllRates() {
-	log.Println("Fetching latest exchange rates...")
+// newAdapter returns a ticker for every window of the hot pair tracker, nil
+// when intervals are not adapted
+func (rf *RateFetcher) newAdapter() *time.Ticker {
+	if hot := rf.getHotPairs(); hot != nil {
+		return time.NewTicker(hot.cfg.Window)
+	}
+	return nil
+}
+
+// adaptQueue picks the hot pairs of the window that ended and moves every
+// pair whose interval changed by the difference, keeping the time it has
+// waited since its last refresh. A pair that has waited longer than its new
+// interval is due now.
+func (rf *RateFetcher) adaptQueue(queue *pairQueue, now time.Time) {
+	hot := rf.getHotPairs()
+	if hot == nil {
+		return
+	}
+	rf.mu.RLock()
+	hot.adapt(rf.schedules)
+	rf.mu.RUnlock()
+
+	type change struct {
+		pair     *scheduledPair
+		interval time.Duration
+	}
+	var changes []change
+	rf.mu.RLock()
+	for _, pair := range *queue {
+		if interval := rf.pairInterval(pair.From, pair.To); interval != pair.Interval {
+			changes = append(changes, change{pair, interval})
+		}
+	}
+	rf.mu.RUnlock()
+
+	for _, c := range changes {
+		next := c.pair.next.Add(c.interval - c.pair.Interval)
+		if next.Before(now) {
+			next = now
+		}
+		c.pair.Interval = c.interval
+		queue.reschedule(c.pair, next)
+	}
+}
+
+// pairInterval returns the refresh interval of a pair: its schedule's, or
+// the default one adapted to its requests. The caller holds rf.mu.
+func (rf *RateFetcher) pairInterval(from, to string) time.Duration {
+	if schedule, ok := rf.schedules[from+"_"+to]; ok && schedule.Interval > 0 {
+		return schedule.Interval
+	}
+	if rf.hot != nil {
+		return rf.hot.interval(from, to, rf.fetchInterval)
+	}
+	return rf.fetchInterval
+}
+
+// buildQueue schedules every pair for its first refresh after now
+func (rf *RateFetcher) buildQueue(now time.Time) pairQueue {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+
+	currencies := models.SupportedCurrencyCodes()
+	queue := make(pairQueue, 0, len(currencies)*len(currencies))
+	for _, from := range currencies {
+		for _, to := range currencies {
+			if from == to {
+				continue
+			}
+
+			schedule := rf.schedules[from+"_"+to]
+			schedule = PairSchedule{From: from, To: to, Interval: rf.pairInterval(from, to), Priority: schedule.Priority}
+			heap.Push(&queue, &scheduledPair{PairSchedule: schedule, next: now.Add(schedule.Interval)})
+		}
+	}
+	return queue
+}
+
+// refreshDue refreshes every pair due by now. One upstream call returns all
+// rates of a base currency, so each due pair refreshes its whole base and
+// every pair of that base is rescheduled. The due bases are fetched through
+// the fetch pool; those the cycle budget leaves out wait for their next turn.
+// In standby nothing is fetched.
+func (rf *RateFetcher) refreshDue(queue *pairQueue, now time.Time) {
+	var bases []string
+	for head := queue.peek(); head != nil && !head.next.After(now); head = queue.peek() {
+		base := head.From
+		bases = append(bases, base)
+
+		var refreshed []*scheduledPair
+		for _, pair := range *queue {
+			if pair.From == base {
+				refreshed = append(refreshed, pair)
+			}
+		}
+		for _, pair := range refreshed {
+			queue.reschedule(pair, now.Add(pair.Interval))
+		}
+	}
+	if len(bases) == 0 || rf.inStandby() {
+		return
+	}
+
+	pivot := rf.fetchPivot(rf.ctx)
+	skipped := rf.getPool().run(rf.ctx, bases, func(ctx context.Context, base string) {
+		rf.refreshBase(ctx, base, pivot)
+	})
+	if len(skipped) > 0 {
+		log.Printf("Fetch cycle budget ran out before refreshing %v", skipped)
+	}
+}
+
+// refreshBase fetches and caches every rate of one base currency, deriving
+// the default provider's from pivot unless it is nil
+func (rf *RateFetcher) refreshBase(ctx context.Context, base string, pivot *pivotTable) {
 	start := time.Now()
+	currencies := models.SupportedCurrencyCodes()
+	results := make(chan rateResult, len(currencies))
+	rf.fetchRatesForBase(ctx, base, currencies, pivot, results)
+	close(results)
+
+	var fetched []FetchedRate
+	var lastErr error
+	for result := range results {
+		if result.err != nil {
+			lastErr = result.err
+			continue
+		}
+		rf.storeLatest(result)
+		fetched = append(fetched, result.fetched())
+	}
 
-	var wg sync.WaitGroup
-	rateChan := make(chan rateResult, len(rf.currencies)*len(rf.currencies))
+	if lastErr != nil {
+		log.Printf("Error refreshing rates for %s: %v", base, lastErr)
+	}
+	rf.recordFetch(start, len(fetched), lastErr)
+	rf.completeCycle(fetched)
+}
+
+// fetchStartup runs the first fetch cycle, skipping base currencies whose
+// rates are all cached already, e.g. restored from a cache snapshot. Their
+// rates count as fetched successfully when they were stored.
+func (rf *RateFetcher) fetchStartup(ctx context.Context) {
+	currencies := models.SupportedCurrencyCodes()
+	var missing []string
+	var oldest time.Time
+	for _, base := range currencies {
+		storedAt, cached := rf.cachedSince(base, currencies)
+		if !cached {
+			missing = append(missing, base)
+			continue
+		}
+		if oldest.IsZero() || storedAt.Before(oldest) {
+			oldest = storedAt
+		}
+	}
+
+	if len(missing) == len(currencies) {
+		rf.fetchAllRates(ctx)
+		return
+	}
+
+	log.Printf("Rates of %d of %d base currencies are cached, fetching the rest", len(currencies)-len(missing), len(currencies))
+	if !oldest.IsZero() {
+		rf.recordFetch(oldest, 1, nil)
+	}
+	if len(missing) == 0 {
+		return
+	}
+	pivot := rf.fetchPivot(ctx)
+	skipped := rf.getPool().run(ctx, missing, func(ctx context.Context, base string) {
+		rf.refreshBase(ctx, base, pivot)
+	})
+	if len(skipped) > 0 {
+		log.Printf("Fetch cycle budget ran out before refreshing %v", skipped)
+	}
+}
 
-	for _, baseCurrency := range rf.currencies {
-		wg.Add(1)
-		go func(base string) {
-			defer wg.Done()
-			rf.fetchRatesForBase(base, rateChan)
-		}(baseCurrency)
+// cachedSince reports whether every latest rate of base is cached and when
+// the oldest of them was stored
+func (rf *RateFetcher) cachedSince(base string, currencies []string) (time.Time, bool) {
+	var oldest time.Time
+	for _, to := range currencies {
+		if to == base {
+			continue
+		}
+		item, found := rf.cache.GetItem(rf.cacheProvider(base, to), base, to, "")
+		if !found {
+			return time.Time{}, false
+		}
+		if oldest.IsZero() || item.StoredAt.Before(oldest) {
+			oldest = item.StoredAt
+		}
 	}
+	return oldest, true
+}
+
+// FetchNow runs a full fetch cycle synchronously and returns how many pairs
+// were refreshed and how many failed
+func (rf *RateFetcher) FetchNow(ctx context.Context) (int, int) {
+	return rf.fetchAllRates(ctx)
+}
 
+func (rf *RateFetcher) fetchAllRates(ctx context.Context) (int, int) {
+	log.Println("Fetching latest exchange rates...")
+	start := time.Now()
+
+	currencies := models.SupportedCurrencyCodes()
+	rateChan := make(chan rateResult, len(currencies)*len(currencies))
+	pivot := rf.fetchPivot(ctx)
+
+	var skipped []string
 	go func() {
-		wg.Wait()
+		skipped = rf.getPool().run(ctx, currencies, func(ctx context.Context, base string) {
+			rf.fetchRatesForBase(ctx, base, currencies, pivot, rateChan)
+		})
 		close(rateChan)
 	}()
 
 	successCount := 0
 	errorCount := 0
+	var lastErr error
+	var fetched []FetchedRate
 
 	for result := range rateChan {
 		if result.err != nil {
 			log.Printf("Error fetching rate %s/%s: %v", result.from, result.to, result.err)
 			errorCount++
+			lastErr = result.err
 		} else {
-			rf.cache.Set(result.from, result.to, "", result.rate)
+			rf.storeLatest(result)
+			fetched = append(fetched, result.fetched())
 			successCount++
 		}
 	}
 
+	if len(skipped) > 0 {
+		// Every pair of a base left out by the cycle budget went unrefreshed
+		errorCount += len(skipped) * (len(currencies) - 1)
+		lastErr = fmt.Errorf("fetch cycle budget ran out before refreshing %v", skipped)
+		log.Println(lastErr)
+	}
+
 	duration := time.Since(start)
 	log.Printf("Rate fetch completed in %v. Success: %d, Errors: %d", duration, successCount, errorCount)
+
+	rf.recordFetch(start, successCount, lastErr)
+	rf.completeCycle(fetched)
+
+	return successCount, errorCount
+}
+
+// completeCycle hands the rates a cycle refreshed to the cycle listener
+func (rf *RateFetcher) completeCycle(fetched []FetchedRate) {
+	if listener := rf.getCycleListener(); listener != nil && len(fetched) > 0 {
+		listener(fetched)
+	}
+}
+
+// recordFetch updates the fetch status reported by Status
+func (rf *RateFetcher) recordFetch(start time.Time, successCount int, lastErr error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	rf.lastAttempt = start
+	if successCount > 0 {
+		rf.lastSuccess = start
+	}
+	rf.lastError = ""
+	if lastErr != nil {
+		rf.lastError = lastErr.Error()
+	}
+}
+
+// publishedAt returns when the provider published a response's rates, or now
+// when it doesn't say
+func publishedAt(apiResponse *models.ExternalAPIResponse) time.Time {
+	if apiResponse.TimeLastUpdated > 0 {
+		return time.Unix(apiResponse.TimeLastUpdated, 0)
+	}
+	return time.Now()
+}
+
+// derivedFrom returns how the rates of a response were computed: as cross
+// rates when the provider's table was rebased from another base
+func derivedFrom(apiResponse *models.ExternalAPIResponse) string {
+	if apiResponse.RebasedFrom != "" {
+		return models.DerivedCross
+	}
+	return ""
 }
 
 type rateResult struct {
-	from string
-	to   string
-	rate float64
-	err  error
+	from     string
+	to       string
+	rate     float64
+	provider string
+	at       time.Time // When the provider published the rate
+	derived  string    // How the rate was computed when not quoted
+	err      error
+}
+
+func (r rateResult) source() cache.Source {
+	return cache.Source{Provider: r.provider, PublishedAt: r.at, Derived: r.derived}
 }
 
-func (rf *RateFetcher) fetchRatesForBase(baseCurrency string, resultChan chan<- rateResult) {
-	apiResponse, err := rf.client.GetLatestRates(baseCurrency)
+func (r rateResult) fetched() FetchedRate {
+	return FetchedRate{From: r.from, To: r.to, Rate: r.rate, Provider: r.provider, PublishedAt: r.at}
+}
+
+// item wraps a rate that was fetched but not cached
+func (r rateResult) item() cache.CacheItem {
+	return cache.CacheItem{Rate: r.rate, StoredAt: time.Now(), Source: r.source()}
+}
+
+// fetchRatesForBase fetches every rate of a base currency. Quote currencies
+// are grouped by the provider they are routed to, so a fiat base costs a
+// second upstream call only when metals are quoted by another provider.
+// With a pivot table, the default provider's rates are derived from it
+// instead of fetched.
+func (rf *RateFetcher) fetchRatesForBase(ctx context.Context, baseCurrency string, currencies []string, pivot *pivotTable, resultChan chan<- rateResult) {
+	var order []string
+	routes := make(map[string][]string) // provider -> quote currencies
+	for _, toCurrency := range currencies {
+		provider := rf.route("", baseCurrency, toCurrency)
+		if _, seen := routes[provider]; !seen {
+			order = append(order, provider)
+		}
+		routes[provider] = append(routes[provider], toCurrency)
+	}
+
+	for _, provider := range order {
+		if provider == "" && pivot != nil {
+			pivot.derive(baseCurrency, routes[provider], resultChan)
+			continue
+		}
+		rf.fetchQuotes(ctx, provider, baseCurrency, routes[provider], resultChan)
+	}
+}
+
+// pivotTable is the default provider's table of the pivot currency, or the
+// error fetching it
+type pivotTable struct {
+	pivot    string
+	response *models.ExternalAPIResponse
+	err      error
+}
+
+// fetchPivot fetches the pivot table of a cycle, nil when every base is
+// fetched
+func (rf *RateFetcher) fetchPivot(ctx context.Context) *pivotTable {
+	pivot := rf.getPivot()
+	if pivot == "" {
+		return nil
+	}
+	response, err := rf.client.GetLatestRatesFrom(ctx, "", pivot)
+	return &pivotTable{pivot: pivot, response: response, err: err}
+}
+
+// against returns the pivot's rate against code
+func (t *pivotTable) against(code string) (float64, bool) {
+	if code == t.pivot {
+		return 1, true
+	}
+	rate, ok := t.response.Rates[code]
+	return rate, ok && rate != 0
+}
+
+// derive sends the rates of base against currencies, computed from the
+// pivot table like fetchQuotes sends fetched ones. Rates of the pivot are
+// sent as quoted, all others as cross rates.
+func (t *pivotTable) derive(baseCurrency string, currencies []string, resultChan chan<- rateResult) {
+	if t.err != nil {
+		for _, toCurrency := range currencies {
+			if toCurrency != baseCurrency {
+				resultChan <- rateResult{
+					from: baseCurrency,
+					to:   toCurrency,
+					err:  t.err,
+				}
+			}
+		}
+		return
+	}
+
+	baseRate, ok := t.against(baseCurrency)
+	if !ok {
+		return
+	}
+	derived := models.DerivedCross
+	if baseCurrency == t.pivot {
+		derived = ""
+	}
+
+	at := publishedAt(t.response)
+	for _, toCurrency := range currencies {
+		if toCurrency == baseCurrency {
+			resultChan <- rateResult{
+				from:     baseCurrency,
+				to:       baseCurrency,
+				rate:     1.0,
+				provider: t.response.Provider,
+				at:       at,
+			}
+			continue
+		}
+		if rate, ok := t.against(toCurrency); ok {
+			resultChan <- rateResult{
+				from:     baseCurrency,
+				to:       toCurrency,
+				rate:     rate / baseRate,
+				provider: t.response.Provider,
+				at:       at,
+				derived:  derived,
+			}
+		}
+	}
+}
+
+// fetchQuotes fetches the rates of base against currencies from provider.
+// The identity rate of base is sent by whichever call is asked for it.
+func (rf *RateFetcher) fetchQuotes(ctx context.Context, provider, baseCurrency string, currencies []string, resultChan chan<- rateResult) {
+	apiResponse, err := rf.client.GetLatestRatesFrom(ctx, provider, baseCurrency)
 	if err != nil {
-		for _, toCurrency := range rf.currencies {
+		for _, toCurrency := range currencies {
 			if toCurrency != baseCurrency {
 				resultChan <- rateResult{
 					from: baseCurrency,
@@ -149,50 +830,138 @@ func (rf *RateFetcher) fetchRatesForBase(baseCurrency string, resultChan chan<-
 		return
 	}
 
-	for toCurrency, rate := range apiResponse.Rates {
-		if models.SupportedCurrencies[toCurrency] && toCurrency != baseCurrency {
+	at := publishedAt(apiResponse)
+	for _, toCurrency := range currencies {
+		if toCurrency == baseCurrency {
+			resultChan <- rateResult{
+				from:     baseCurrency,
+				to:       baseCurrency,
+				rate:     1.0,
+				provider: apiResponse.Provider,
+				at:       at,
+			}
+			continue
+		}
+		if rate, ok := apiResponse.Rates[toCurrency]; ok {
 			resultChan <- rateResult{
-				from: baseCurrency,
-				to:   toCurrency,
-				rate: rate,
+				from:     baseCurrency,
+				to:       toCurrency,
+				rate:     rate,
+				provider: apiResponse.Provider,
+				at:       at,
+				derived:  derivedFrom(apiResponse),
 			}
 		}
 	}
+}
 
-	resultChan <- rateResult{
-		from: baseCurrency,
-		to:   baseCurrency,
-		rate: 1.0,
+// FetchRateOnDemand fetches the latest rate of a pair from provider, or from
+// the default provider when provider is empty. Only rates of the default
+// provider are cached, so pinning a request never mixes sources in the cache.
+// Metal pairs are fetched from the metals provider unless pinned.
+func (rf *RateFetcher) FetchRateOnDemand(ctx context.Context, provider, from, to string) (cache.CacheItem, error) {
+	if provider == "" {
+		if _, found := rf.cache.GetNegative(rf.cacheProvider(from, to), negativeScope(ctx, from), to, ""); found {
+			return cache.CacheItem{}, fmt.Errorf("%w for currency pair %s/%s", external.ErrRateNotFound, from, to)
+		}
 	}
-}
 
-func (rf *RateFetcher) FetchRateOnDemand(from, to string) (float64, error) {
 	log.Printf("Fetching on-demand rate for %s/%s", from, to)
 
-	rate, err := rf.client.GetRateForPair(from, to)
+	apiResponse, err := rf.client.GetLatestRatesFrom(ctx, rf.route(provider, from, to), from)
 	if err != nil {
-		return 0, err
+		if provider == "" {
+			rf.rememberNotFound(ctx, from, to, "", err)
+		}
+		return cache.CacheItem{}, err
 	}
 
-	rf.cache.Set(from, to, "", rate)
+	rate, exists := apiResponse.Rates[to]
+	if !exists {
+		err := fmt.Errorf("%w for currency pair %s/%s", external.ErrRateNotFound, from, to)
+		if provider == "" {
+			rf.rememberNotFound(ctx, from, to, "", err)
+		}
+		return cache.CacheItem{}, err
+	}
 
-	return rate, nil
+	result := rateResult{from: from, to: to, rate: rate, provider: apiResponse.Provider, at: publishedAt(apiResponse), derived: derivedFrom(apiResponse)}
+	if provider == "" {
+		rf.storeLatest(result)
+		if item, found := rf.cache.GetItem(result.provider, from, to, ""); found {
+			return item, nil
+		}
+	}
+	return result.item(), nil
 }
 
-func (rf *RateFetcher) FetchHistoricalRateOnDemand(from, to, date string) (float64, error) {
+// FetchHistoricalRateOnDemand fetches the rate of a pair on date from
+// provider, or from the default provider when provider is empty. Like latest
+// rates, only the default provider's and the metals provider's are cached.
+func (rf *RateFetcher) FetchHistoricalRateOnDemand(ctx context.Context, provider, from, to, date string) (cache.CacheItem, error) {
+	if provider == "" {
+		if _, found := rf.cache.GetNegative(rf.cacheProvider(from, to), negativeScope(ctx, from), to, date); found {
+			return cache.CacheItem{}, fmt.Errorf("historical %w for currency pair %s/%s on %s", external.ErrRateNotFound, from, to, date)
+		}
+	}
+
 	log.Printf("Fetching historical rate for %s/%s on %s", from, to, date)
 
-	rate, err := rf.client.GetHistoricalRateForPair(from, to, date)
+	apiResponse, err := rf.client.GetHistoricalRatesFrom(ctx, rf.route(provider, from, to), from, date)
+	if err == nil {
+		if _, exists := apiResponse.Rates[to]; !exists {
+			err = fmt.Errorf("historical %w for currency pair %s/%s on %s", external.ErrRateNotFound, from, to, date)
+		}
+	}
 	if err != nil {
-		return 0, err
+		if provider == "" {
+			rf.rememberNotFound(ctx, from, to, date, err)
+		}
+		return cache.CacheItem{}, err
 	}
 
-	// Cache the fetched historical rate
-	rf.cache.Set(from, to, date, rate)
+	result := rateResult{from: from, to: to, rate: apiResponse.Rates[to], provider: apiResponse.Provider, at: publishedAt(apiResponse), derived: derivedFrom(apiResponse)}
+	if provider == "" {
+		rf.cache.SetWithSource(from, to, date, result.rate, result.source())
+		if item, found := rf.cache.GetItem(result.provider, from, to, date); found {
+			return item, nil
+		}
+	}
+	return result.item(), nil
+}
 
-	return rate, nil
+// RefetchHistoricalRate fetches the rate of a pair on date from the default
+// provider again, skipping negative entries. On success it replaces the
+// cached entries of the pair and of the reverse pair on date, in every
+// namespace; on failure they are left as they were.
+func (rf *RateFetcher) RefetchHistoricalRate(ctx context.Context, from, to, date string) (cache.CacheItem, error) {
+	log.Printf("Refetching historical rate for %s/%s on %s", from, to, date)
+
+	apiResponse, err := rf.client.GetHistoricalRatesFrom(ctx, rf.route("", from, to), from, date)
+	if err == nil {
+		if _, exists := apiResponse.Rates[to]; !exists {
+			err = fmt.Errorf("historical %w for currency pair %s/%s on %s", external.ErrRateNotFound, from, to, date)
+		}
+	}
+	if err != nil {
+		return cache.CacheItem{}, err
+	}
+
+	rf.cache.DeleteDate(from, to, date)
+	rf.cache.DeleteDate(to, from, date)
+	result := rateResult{from: from, to: to, rate: apiResponse.Rates[to], provider: apiResponse.Provider, at: publishedAt(apiResponse)}
+	rf.cache.SetWithSource(from, to, date, result.rate, result.source())
+	if item, found := rf.cache.GetItem(result.provider, from, to, date); found {
+		return item, nil
+	}
+	return result.item(), nil
 }
 
 func (rf *RateFetcher) GetCacheStats() map[string]interface{} {
 	return rf.cache.GetStats()
 }
+
+// GetProviderCacheStats returns the cache stats of one provider's rates
+func (rf *RateFetcher) GetProviderCacheStats(provider string) map[string]interface{} {
+	return rf.cache.GetProviderStats(provider)
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+)
+
+func TestRateFetcher_CachesRateNotFound(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"base":"USD","rates":{"INR":83.5}}`))
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = server.URL
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	fetcher := NewRateFetcher(external.NewExchangeRateClientWithConfig(cfg), memoryCache)
+	fetcher.SetNegativeTTL(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		_, err := fetcher.FetchRateOnDemand(context.Background(), "", "USD", "XYZ")
+		assert.ErrorIs(t, err, external.ErrRateNotFound)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "repeated misses are answered from the negative cache")
+
+	// Other pairs of the base still go upstream
+	item, err := fetcher.FetchRateOnDemand(context.Background(), "", "USD", "INR")
+	assert.NoError(t, err)
+	assert.Equal(t, 83.5, item.Rate)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRateFetcher_DoesNotCacheTransientFailures(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = server.URL
+	cfg.Retry.MaxAttempts = 1
+	fetcher := NewRateFetcher(external.NewExchangeRateClientWithConfig(cfg), cache.NewMemoryCache(time.Hour))
+	fetcher.SetNegativeTTL(time.Minute)
+
+	for i := 0; i < 2; i++ {
+		_, err := fetcher.FetchRateOnDemand(context.Background(), "", "USD", "INR")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, external.ErrRateNotFound)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRateFetcher_PinnedProviderIsNotCached(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"base":"USD","date":"2025-01-02","rates":{"INR":85.2}}`))
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.FrankfurterBaseURL = server.URL
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	fetcher := NewRateFetcher(external.NewExchangeRateClientWithConfig(cfg), memoryCache)
+
+	item, err := fetcher.FetchRateOnDemand(context.Background(), external.ProviderFrankfurter, "USD", "INR")
+	require.NoError(t, err)
+	assert.Equal(t, 85.2, item.Rate)
+	assert.Equal(t, external.ProviderFrankfurter, item.Provider)
+	assert.Equal(t, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), item.PublishedAt.UTC())
+
+	_, found := memoryCache.Get(external.ProviderExchangeRateAPI, "USD", "INR", "")
+	assert.False(t, found, "only the default provider's rates are cached")
+}
+
+func TestRateFetcher_RoutesMetalsToMetalsProvider(t *testing.T) {
+	var erapiBases, fixerBases []string
+	var mu sync.Mutex
+	erapi := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		erapiBases = append(erapiBases, r.URL.Path)
+		mu.Unlock()
+		w.Write([]byte(`{"base":"USD","rates":{"USD":1,"INR":83.5}}`))
+	}))
+	defer erapi.Close()
+	fixer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fixerBases = append(fixerBases, r.URL.Query().Get("base"))
+		mu.Unlock()
+		if r.URL.Query().Get("base") == "XAU" {
+			w.Write([]byte(`{"success":true,"timestamp":1735819200,"base":"XAU","rates":{"USD":2650.0,"INR":221275.0}}`))
+			return
+		}
+		w.Write([]byte(`{"success":true,"timestamp":1735819200,"base":"USD","rates":{"XAU":0.000377}}`))
+	}))
+	defer fixer.Close()
+
+	creds := external.NewCredentials()
+	creds.SetKey(external.ProviderFixer, "fixer-test-key")
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = erapi.URL
+	cfg.FixerBaseURL = fixer.URL
+	cfg.Credentials = creds
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	fetcher := NewRateFetcher(external.NewExchangeRateClientWithConfig(cfg), memoryCache)
+	fetcher.SetMetalsProvider(external.ProviderFixer)
+
+	item, err := fetcher.FetchRateOnDemand(context.Background(), "", "USD", "XAU")
+	require.NoError(t, err)
+	assert.Equal(t, 0.000377, item.Rate)
+	assert.Equal(t, external.ProviderFixer, item.Provider)
+	_, cached := memoryCache.GetItem(external.ProviderFixer, "USD", "XAU", "")
+	assert.True(t, cached, "metal rates have a single source and are cached")
+
+	models.SetSupportedCurrencies([]string{"USD", "INR", "XAU"})
+	defer models.SetSupportedCurrencies(models.DefaultCurrencies)
+
+	var cycle []FetchedRate
+	fetcher.SetCycleListener(func(rates []FetchedRate) { cycle = rates })
+
+	mu.Lock()
+	erapiBases, fixerBases = nil, nil
+	mu.Unlock()
+	success, failed := fetcher.FetchNow(context.Background())
+	assert.Equal(t, 0, failed)
+	assert.Equal(t, 9, success, "six pairs plus one identity rate per base")
+	assert.Len(t, cycle, 9, "the listener gets every rate of the cycle")
+
+	usdInr, _ := memoryCache.GetItem(external.ProviderExchangeRateAPI, "USD", "INR", "")
+	assert.Equal(t, external.ProviderExchangeRateAPI, usdInr.Provider)
+	xauUsd, _ := memoryCache.GetItem(external.ProviderFixer, "XAU", "USD", "")
+	assert.Equal(t, 2650.0, xauUsd.Rate)
+	assert.Equal(t, external.ProviderFixer, xauUsd.Provider)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []string{"USD", "INR", "XAU"}, fixerBases, "fiat bases fetch their metal quotes from fixer")
+	assert.Len(t, erapiBases, 2, "the metal base never goes to the default provider")
+}
+
+func TestRateFetcher_StartupSkipsCachedBases(t *testing.T) {
+	original := models.SupportedCurrencyCodes()
+	models.SetSupportedCurrencies([]string{"USD", "EUR"})
+	defer models.SetSupportedCurrencies(original)
+
+	var mu sync.Mutex
+	var bases []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		bases = append(bases, r.URL.Path)
+		mu.Unlock()
+		w.Write([]byte(`{"base":"EUR","rates":{"USD":1.08}}`))
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = server.URL
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	storedAt := time.Now()
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "EUR", "", 0.92) // As restored from a cache snapshot
+	fetcher := NewRateFetcher(external.NewExchangeRateClientWithConfig(cfg), memoryCache)
+
+	fetcher.fetchStartup(context.Background())
+
+	assert.Equal(t, []string{"/latest/EUR"}, bases, "only the base without cached rates is fetched")
+	rate, found := memoryCache.Get(external.ProviderExchangeRateAPI, "EUR", "USD", "")
+	assert.True(t, found)
+	assert.Equal(t, 1.08, rate)
+
+	// With every base cached nothing is fetched, and the cached rates count
+	// as a successful fetch so the service is ready
+	restarted := NewRateFetcher(external.NewExchangeRateClientWithConfig(cfg), memoryCache)
+	restarted.fetchStartup(context.Background())
+	assert.Len(t, bases, 1)
+	lastSuccess := restarted.Status().LastSuccess
+	assert.False(t, lastSuccess.IsZero())
+	assert.False(t, lastSuccess.Before(storedAt))
+}
+
+func TestRateFetcher_DerivesBasesFromPivot(t *testing.T) {
+	models.SetSupportedCurrencies([]string{"USD", "EUR", "INR"})
+	defer models.SetSupportedCurrencies(models.DefaultCurrencies)
+
+	var mu sync.Mutex
+	var bases []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		bases = append(bases, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/latest/USD" {
+			w.Write([]byte(`{"base":"USD","rates":{"USD":1,"EUR":0.8,"INR":80}}`))
+			return
+		}
+		w.Write([]byte(`{"base":"EUR","rates":{"EUR":1,"USD":1.24,"INR":99}}`))
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = server.URL
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	fetcher := NewRateFetcher(external.NewExchangeRateClientWithConfig(cfg), memoryCache)
+	fetcher.SetPivot("USD", nil)
+
+	success, failed := fetcher.FetchNow(context.Background())
+	assert.Equal(t, 0, failed)
+	assert.Equal(t, 9, success)
+	assert.Equal(t, []string{"/latest/USD"}, bases, "one upstream call per cycle")
+
+	usdInr, _ := memoryCache.GetItem(external.ProviderExchangeRateAPI, "USD", "INR", "")
+	assert.Equal(t, 80.0, usdInr.Rate)
+	assert.Empty(t, usdInr.Derived, "the pivot's own rates are quoted")
+	eurInr, _ := memoryCache.GetItem(external.ProviderExchangeRateAPI, "EUR", "INR", "")
+	assert.InDelta(t, 100.0, eurInr.Rate, 1e-9)
+	assert.Equal(t, models.DerivedCross, eurInr.Derived)
+	inrUsd, _ := memoryCache.GetItem(external.ProviderExchangeRateAPI, "INR", "USD", "")
+	assert.InDelta(t, 0.0125, inrUsd.Rate, 1e-12)
+
+	// Providers quoting asymmetric spreads keep fetching every base
+	mu.Lock()
+	bases = nil
+	mu.Unlock()
+	fetcher.SetPivot("USD", []string{external.ProviderExchangeRateAPI})
+	_, failed = fetcher.FetchNow(context.Background())
+	assert.Equal(t, 0, failed)
+	assert.Len(t, bases, 3)
+	eurInr, _ = memoryCache.GetItem(external.ProviderExchangeRateAPI, "EUR", "INR", "")
+	assert.Equal(t, 99.0, eurInr.Rate)
+	assert.Empty(t, eurInr.Derived)
+}
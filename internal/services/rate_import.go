@@ -0,0 +1,284 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/store"
+	"exchange-rate-service/internal/utils"
+)
+
+// importedDay holds the rates of one day read from a historical rate file
+type importedDay map[string]map[string]float64 // base -> quote -> rate
+
+func (d importedDay) set(from, to string, rate float64) {
+	if d[from] == nil {
+		d[from] = make(map[string]float64)
+	}
+	d[from][to] = rate
+}
+
+// ImportHistoricalRates archives the end-of-day rates of a CSV file, such as
+// the ECB's eurofxref-hist.csv or the zip it is published in, so historical
+// queries are answered for days no provider call could reach. The ECB layout
+// is converted to every pair of supported currencies it quotes; currencies
+// that are not supported are ignored. The whole file is read before anything
+// is archived, so a malformed row imports nothing. Days already archived are
+// kept unless req.Overwrite is set.
+func (s *ExchangeService) ImportHistoricalRates(file io.Reader, req *models.RateImportRequest, caller string) (*models.RateImportResponse, error) {
+	archive := s.getArchive()
+	if archive == nil {
+		return nil, models.NewError(models.ErrCodeNotImplemented, "no rate archive is configured")
+	}
+
+	base := strings.ToUpper(strings.TrimSpace(req.Base))
+	if base == "" {
+		base = "EUR"
+	}
+	if len(base) != 3 {
+		return nil, models.NewFieldError(models.ErrCodeValueInvalid, "base", "base must be a currency code, got %q", req.Base)
+	}
+
+	file, err := unzipRateFile(file)
+	if err != nil {
+		return nil, err
+	}
+	format, days, rows, ignored, err := parseRateFile(file, base)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.RateImportResponse{
+		Format:            format,
+		Provider:          req.Provider,
+		Rows:              rows,
+		IgnoredCurrencies: ignored,
+		ImportedBy:        caller,
+		ImportedAt:        time.Now(),
+	}
+	if resp.Provider == "" {
+		resp.Provider = "import"
+		if format == models.RateImportECB && base == "EUR" {
+			resp.Provider = "ecb"
+		}
+	}
+
+	dates := make([]string, 0, len(days))
+	for date := range days {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	for _, date := range dates {
+		if _, archived := archive.Get(date); archived && !req.Overwrite {
+			resp.SkippedDays++
+			continue
+		}
+		snapshot := &store.Snapshot{
+			Date:       date,
+			CapturedAt: resp.ImportedAt,
+			Provider:   resp.Provider,
+			Rates:      days[date],
+		}
+		if err := archive.Save(snapshot); err != nil {
+			return nil, err
+		}
+		if resp.FirstDate == "" {
+			resp.FirstDate = date
+		}
+		resp.LastDate = date
+		resp.ImportedDays++
+	}
+
+	log.Printf("Historical rates imported by %s: %d days archived, %d already archived days kept", caller, resp.ImportedDays, resp.SkippedDays)
+	return resp, nil
+}
+
+// unzipRateFile returns the CSV file in a zip archive, or file itself when
+// it is not one
+func unzipRateFile(file io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return bytes.NewReader(data), nil
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, models.NewFieldError(models.ErrCodeInvalidRequest, "file", "invalid zip archive: %v", err)
+	}
+	for _, entry := range archive.File {
+		if !strings.HasSuffix(strings.ToLower(entry.Name), ".csv") {
+			continue
+		}
+		opened, err := entry.Open()
+		if err != nil {
+			return nil, models.NewFieldError(models.ErrCodeInvalidRequest, "file", "invalid zip archive: %v", err)
+		}
+		defer opened.Close()
+		content, err := io.ReadAll(opened)
+		if err != nil {
+			return nil, models.NewFieldError(models.ErrCodeInvalidRequest, "file", "invalid zip archive: %v", err)
+		}
+		return bytes.NewReader(content), nil
+	}
+	return nil, models.NewFieldError(models.ErrCodeInvalidRequest, "file", "the zip archive holds no CSV file")
+}
+
+// parseRateFile reads the days of a historical rate file, telling its
+// layout by the header, and returns the layout, the days, the number of rows
+// and the unsupported currencies that were ignored
+func parseRateFile(file io.Reader, base string) (string, map[string]importedDay, int, []string, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return "", nil, 0, nil, models.NewFieldError(models.ErrCodeInvalidRequest, "file", "the file is empty")
+	}
+	if err != nil {
+		return "", nil, 0, nil, models.NewFieldError(models.ErrCodeInvalidRequest, "file", "invalid CSV: %v", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	format := models.RateImportPairs
+	for _, name := range []string{"date", "from", "to", "rate"} {
+		if _, ok := columns[name]; !ok {
+			format = models.RateImportECB
+		}
+	}
+	if format == models.RateImportECB && columns["date"] != 0 {
+		return "", nil, 0, nil, models.NewFieldError(models.ErrCodeInvalidRequest, "file", "the header must start with a date column followed by currencies, or have date, from, to and rate columns")
+	}
+
+	days := make(map[string]importedDay)
+	ignored := make(map[string]bool)
+	today := utils.Today()
+	rows := 0
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", nil, 0, nil, &models.CodedError{Code: models.ErrCodeInvalidRequest, Field: "file", Message: "invalid CSV: " + err.Error(), Err: err}
+		}
+		line, _ := reader.FieldPos(0)
+		rows++
+
+		field := func(index int) string {
+			if index < len(record) {
+				return strings.TrimSpace(record[index])
+			}
+			return ""
+		}
+		date := field(columns["date"])
+		parsed, err := time.ParseInLocation(utils.DateFormat, date, utils.ReferenceLocation())
+		if err != nil {
+			return "", nil, 0, nil, models.NewFieldError(models.ErrCodeDateInvalid, "file", "line %d: invalid date %q, expected YYYY-MM-DD", line, date)
+		}
+		if parsed.After(today) {
+			return "", nil, 0, nil, models.NewFieldError(models.ErrCodeDateOutOfRange, "file", "line %d: date %s is in the future", line, date)
+		}
+		if days[date] == nil {
+			days[date] = make(importedDay)
+		}
+
+		if format == models.RateImportPairs {
+			from := strings.ToUpper(field(columns["from"]))
+			to := strings.ToUpper(field(columns["to"]))
+			rate, err := parseImportedRate(field(columns["rate"]))
+			if err != nil || rate == 0 {
+				return "", nil, 0, nil, models.NewFieldError(models.ErrCodeValueInvalid, "file", "line %d: rate must be a positive number, got %q", line, field(columns["rate"]))
+			}
+			supported := true
+			for _, currency := range []string{from, to} {
+				if !models.IsSupportedCurrency(currency) {
+					ignored[currency] = true
+					supported = false
+				}
+			}
+			if supported && from != to {
+				days[date].set(from, to, rate)
+			}
+			continue
+		}
+
+		// Rates of the ECB layout are units of each currency per unit of
+		// base, so every pair is a ratio of two of them
+		quoted := map[string]float64{base: 1}
+		for i, name := range header[1:] {
+			currency := strings.ToUpper(strings.TrimSpace(name))
+			if currency == "" {
+				// The ECB ends every line with a comma
+				continue
+			}
+			if !models.IsSupportedCurrency(currency) {
+				ignored[currency] = true
+				continue
+			}
+			rate, err := parseImportedRate(field(i + 1))
+			if err != nil {
+				return "", nil, 0, nil, models.NewFieldError(models.ErrCodeValueInvalid, "file", "line %d: %s rate must be a positive number, got %q", line, currency, field(i+1))
+			}
+			if rate > 0 {
+				quoted[currency] = rate
+			}
+		}
+		if !models.IsSupportedCurrency(base) {
+			delete(quoted, base)
+		}
+		for from, fromRate := range quoted {
+			for to, toRate := range quoted {
+				if from != to {
+					days[date].set(from, to, toRate/fromRate)
+				}
+			}
+		}
+	}
+	if rows == 0 {
+		return "", nil, 0, nil, models.NewFieldError(models.ErrCodeInvalidRequest, "file", "the file has no rows")
+	}
+
+	for date, rates := range days {
+		if len(rates) == 0 {
+			delete(days, date)
+		}
+	}
+	ignoredCurrencies := make([]string, 0, len(ignored))
+	for currency := range ignored {
+		ignoredCurrencies = append(ignoredCurrencies, currency)
+	}
+	sort.Strings(ignoredCurrencies)
+	return format, days, rows, ignoredCurrencies, nil
+}
+
+// parseImportedRate parses a rate of a historical rate file. Missing rates,
+// empty or N/A as the ECB writes them, are 0.
+func parseImportedRate(value string) (float64, error) {
+	if value == "" || strings.EqualFold(value, "N/A") {
+		return 0, nil
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate <= 0 {
+		return 0, strconv.ErrRange
+	}
+	return rate, nil
+}
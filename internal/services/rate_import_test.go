@@ -0,0 +1,142 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/store"
+)
+
+// ecbHistory is the start of the ECB's eurofxref-hist.csv, newest day first
+// and every line ending with a comma
+const ecbHistory = `Date,USD,JPY,BGN,CYP,INR,GBP,
+2024-01-03,1.0919,155.52,1.9558,N/A,90.9595,0.86325,
+2024-01-02,1.0956,155.19,1.9558,N/A,91.1745,0.86655,
+1999-01-04,1.1789,133.73,N/A,0.58231,N/A,0.7111,
+`
+
+func TestExchangeService_ImportHistoricalRates_ECB(t *testing.T) {
+	archive, err := store.NewArchive("")
+	require.NoError(t, err)
+	require.NoError(t, archive.Save(&store.Snapshot{Date: "2024-01-03", Provider: "frankfurter", Rates: map[string]map[string]float64{"USD": {"INR": 83.3}}}))
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	service := NewExchangeService(memoryCache, NewRateFetcher(nil, memoryCache), nil)
+	service.SetArchive(archive)
+
+	resp, err := service.ImportHistoricalRates(strings.NewReader(ecbHistory), &models.RateImportRequest{}, "ops")
+	require.NoError(t, err)
+	assert.Equal(t, models.RateImportECB, resp.Format)
+	assert.Equal(t, "ecb", resp.Provider)
+	assert.Equal(t, 3, resp.Rows)
+	assert.Equal(t, 2, resp.ImportedDays)
+	assert.Equal(t, 1, resp.SkippedDays)
+	assert.Equal(t, "1999-01-04", resp.FirstDate)
+	assert.Equal(t, "2024-01-02", resp.LastDate)
+	assert.Equal(t, []string{"BGN", "CYP"}, resp.IgnoredCurrencies)
+
+	snapshot, ok := archive.Get("2024-01-02")
+	require.True(t, ok)
+	assert.Equal(t, "ecb", snapshot.Provider)
+	rate, ok := snapshot.Rate("EUR", "USD")
+	require.True(t, ok)
+	assert.Equal(t, 1.0956, rate)
+	rate, ok = snapshot.Rate("USD", "INR")
+	require.True(t, ok)
+	assert.InDelta(t, 91.1745/1.0956, rate, 1e-12)
+	rate, ok = snapshot.Rate("GBP", "EUR")
+	require.True(t, ok)
+	assert.InDelta(t, 1/0.86655, rate, 1e-12)
+
+	// INR was not quoted yet, so it is left out rather than archived as 0
+	snapshot, ok = archive.Get("1999-01-04")
+	require.True(t, ok)
+	_, ok = snapshot.Rate("USD", "INR")
+	assert.False(t, ok)
+
+	// The captured day is kept, and historical queries are answered from
+	// the imported ones
+	snapshot, _ = archive.Get("2024-01-03")
+	assert.Equal(t, "frankfurter", snapshot.Provider)
+	quote, found := service.getArchivedRate("INR", "JPY", "2024-01-02")
+	require.True(t, found)
+	assert.InDelta(t, 155.19/91.1745, quote.rate, 1e-12)
+
+	resp, err = service.ImportHistoricalRates(strings.NewReader(ecbHistory), &models.RateImportRequest{Overwrite: true}, "ops")
+	require.NoError(t, err)
+	assert.Equal(t, 3, resp.ImportedDays)
+	snapshot, _ = archive.Get("2024-01-03")
+	assert.Equal(t, "ecb", snapshot.Provider)
+}
+
+func TestExchangeService_ImportHistoricalRates_PairsAndZip(t *testing.T) {
+	archive, err := store.NewArchive("")
+	require.NoError(t, err)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	service := NewExchangeService(memoryCache, NewRateFetcher(nil, memoryCache), nil)
+	service.SetArchive(archive)
+
+	var zipped bytes.Buffer
+	writer := zip.NewWriter(&zipped)
+	entry, err := writer.Create("rates.csv")
+	require.NoError(t, err)
+	entry.Write([]byte("date,from,to,rate\n2010-06-01,USD,INR,46.5\n2010-06-01,USD,XAU,0.0008\n2010-06-02,GBP,USD,1.45\n"))
+	require.NoError(t, writer.Close())
+
+	resp, err := service.ImportHistoricalRates(&zipped, &models.RateImportRequest{Provider: "ledger"}, "ops")
+	require.NoError(t, err)
+	assert.Equal(t, models.RateImportPairs, resp.Format)
+	assert.Equal(t, "ledger", resp.Provider)
+	assert.Equal(t, 2, resp.ImportedDays)
+	assert.Equal(t, []string{"XAU"}, resp.IgnoredCurrencies)
+
+	quote, found := service.getArchivedRate("INR", "USD", "2010-06-01")
+	require.True(t, found)
+	assert.InDelta(t, 1/46.5, quote.rate, 1e-12)
+	assert.Equal(t, models.DerivedInverse, quote.derived)
+	assert.Equal(t, "ledger", quote.provider)
+}
+
+func TestExchangeService_ImportHistoricalRates_Invalid(t *testing.T) {
+	archive, err := store.NewArchive("")
+	require.NoError(t, err)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	service := NewExchangeService(memoryCache, NewRateFetcher(nil, memoryCache), nil)
+
+	_, err = service.ImportHistoricalRates(strings.NewReader(ecbHistory), &models.RateImportRequest{}, "ops")
+	code, _ := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeNotImplemented, code)
+	service.SetArchive(archive)
+
+	tests := []struct {
+		name string
+		file string
+		code string
+	}{
+		{"empty", "", models.ErrCodeInvalidRequest},
+		{"no rows", "Date,USD,\n", models.ErrCodeInvalidRequest},
+		{"unknown layout", "day,from,to,rate\n", models.ErrCodeInvalidRequest},
+		{"invalid date", "Date,USD,\n03/01/2024,1.09,\n", models.ErrCodeDateInvalid},
+		{"future date", "Date,USD,\n2999-01-01,1.09,\n", models.ErrCodeDateOutOfRange},
+		{"invalid rate", "Date,USD,\n2024-01-02,abc,\n", models.ErrCodeValueInvalid},
+		{"negative pair rate", "date,from,to,rate\n2024-01-02,USD,INR,-1\n", models.ErrCodeValueInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.ImportHistoricalRates(strings.NewReader(tt.file), &models.RateImportRequest{}, "ops")
+			require.Error(t, err)
+			code, field := models.ErrorCodeOf(err)
+			assert.Equal(t, tt.code, code, err.Error())
+			assert.Equal(t, "file", field)
+		})
+	}
+	// A malformed row imports nothing
+	assert.Equal(t, 0, archive.Len())
+}
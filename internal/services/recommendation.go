@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+// DefaultRecommendationWindows are the windows, in days, a recommendation
+// compares the current rate with when the request names none
+var DefaultRecommendationWindows = []int{30, 90}
+
+// maxRecommendationWindows bounds the windows of a single recommendation
+const maxRecommendationWindows = 5
+
+// GetRateRecommendation places a pair's latest rate within the range of its
+// historical rates over each window ending today: its percentile, the
+// window's low, high and mean, and the trend and volatility of the rate. The
+// longest window decides the signal and guidance. Historical rates are looked
+// up like GetHistoricalRates does, once for the longest window, and days
+// without one are left out.
+func (s *ExchangeService) GetRateRecommendation(ctx context.Context, req *models.RecommendationRequest) (*models.RecommendationResponse, error) {
+	if err := validatePair(ctx, &req.From, &req.To); err != nil {
+		return nil, err
+	}
+
+	windows := req.Windows
+	if len(windows) == 0 {
+		windows = DefaultRecommendationWindows
+	}
+	if len(windows) > maxRecommendationWindows {
+		return nil, models.NewFieldError(models.ErrCodeValueInvalid, "windows", "at most %d windows can be compared, got %d", maxRecommendationWindows, len(windows))
+	}
+	lookback, maxRange := utils.DateLimits()
+	longest := 0
+	for _, days := range windows {
+		if days < 2 || days > maxRange {
+			return nil, models.NewFieldError(models.ErrCodeValueInvalid, "windows", "windows must be between 2 and %d days, got %d", maxRange, days)
+		}
+		if days > longest {
+			longest = days
+		}
+	}
+
+	latest, err := s.GetLatestRate(ctx, req.From, req.To, req.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	today := utils.Today()
+	windowStart := func(days int) time.Time {
+		start := today.AddDate(0, 0, -(days - 1))
+		if earliest := today.AddDate(0, 0, -lookback); lookback > 0 && start.Before(earliest) {
+			start = earliest
+		}
+		return start
+	}
+	historical, err := s.GetHistoricalRates(ctx, &models.HistoricalRateRequest{
+		From:      req.From,
+		To:        req.To,
+		StartDate: windowStart(longest).Format(utils.DateFormat),
+		EndDate:   today.Format(utils.DateFormat),
+		Provider:  req.Provider,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.RecommendationResponse{
+		From:      req.From,
+		To:        req.To,
+		Rate:      latest.Rate,
+		Date:      time.Now(),
+		Freshness: latest.Freshness,
+		Source:    latest.Source,
+	}
+	resp.Freshness.Merge(historical.Freshness)
+	resp.Source.Merge(historical.Source)
+
+	var decisive *models.RecommendationWindow
+	for _, days := range windows {
+		window := summarizeWindow(days, utils.GetDateRangeList(windowStart(days), today), historical.Rates, latest.Rate)
+		resp.Windows = append(resp.Windows, window)
+		if days == longest {
+			decisive = &resp.Windows[len(resp.Windows)-1]
+		}
+	}
+	if decisive.Samples == 0 {
+		return nil, models.NewError(models.ErrCodeRateNotFound, "no historical rates of %s/%s in the last %d days", req.From, req.To, longest)
+	}
+	resp.Signal, resp.Guidance = guidance(decisive.Percentile, longest)
+	return resp, nil
+}
+
+// summarizeWindow compares current with the trading-day rates of dates
+func summarizeWindow(days int, dates []string, rates map[string]models.HistoricalRate, current float64) models.RecommendationWindow {
+	window := models.RecommendationWindow{Days: days, StartDate: dates[0], EndDate: dates[len(dates)-1]}
+	points, _ := computeTrend(dates, rates, 1)
+	window.Samples = len(points)
+	if len(points) == 0 {
+		return window
+	}
+
+	window.Low, window.High = points[0].Rate, points[0].Rate
+	var sum float64
+	below := 0
+	for _, point := range points {
+		window.Low = math.Min(window.Low, point.Rate)
+		window.High = math.Max(window.High, point.Rate)
+		sum += point.Rate
+		if point.Rate < current {
+			below++
+		}
+	}
+	window.Mean = sum / float64(len(points))
+	window.Percentile = float64(below) / float64(len(points)) * 100
+	if first := points[0].Rate; first != 0 {
+		window.ChangePercent = (current - first) / first * 100
+	}
+
+	// Volatility is the standard deviation of the day-over-day changes
+	var changes []float64
+	for _, point := range points {
+		if point.ChangePercent != nil {
+			changes = append(changes, *point.ChangePercent)
+		}
+	}
+	window.VolatilityPercent = standardDeviation(changes)
+
+	// A change within one day's typical move is noise rather than a trend
+	switch {
+	case math.Abs(window.ChangePercent) <= window.VolatilityPercent:
+		window.Trend = models.TrendFlat
+	case window.ChangePercent > 0:
+		window.Trend = models.TrendRising
+	default:
+		window.Trend = models.TrendFalling
+	}
+	return window
+}
+
+// standardDeviation returns the population standard deviation of values, 0
+// when there are none
+func standardDeviation(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var mean, variance float64
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(len(values))
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	return math.Sqrt(variance / float64(len(values)))
+}
+
+// guidance sums up the percentile of the current rate within the last days
+func guidance(percentile float64, days int) (signal, text string) {
+	switch {
+	case percentile >= 90:
+		return models.SignalFavorable, fmt.Sprintf("The rate is in the top 10%% of the last %d days", days)
+	case percentile >= 75:
+		return models.SignalFavorable, fmt.Sprintf("The rate is in the top 25%% of the last %d days", days)
+	case percentile <= 10:
+		return models.SignalUnfavorable, fmt.Sprintf("The rate is in the bottom 10%% of the last %d days", days)
+	case percentile <= 25:
+		return models.SignalUnfavorable, fmt.Sprintf("The rate is in the bottom 25%% of the last %d days", days)
+	default:
+		return models.SignalNeutral, fmt.Sprintf("The rate is near the middle of its %d-day range", days)
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+func TestSummarizeWindow(t *testing.T) {
+	dates := []string{"2025-01-06", "2025-01-07", "2025-01-08", "2025-01-09", "2025-01-10"}
+	rates := map[string]models.HistoricalRate{
+		"2025-01-06": {Rate: 80},
+		"2025-01-07": {Rate: 82},
+		"2025-01-08": {Rate: 81},
+		"2025-01-10": {Rate: 83},
+	}
+
+	window := summarizeWindow(5, dates, rates, 82.5)
+	assert.Equal(t, "2025-01-06", window.StartDate)
+	assert.Equal(t, "2025-01-10", window.EndDate)
+	assert.Equal(t, 4, window.Samples, "the missing day is left out")
+	assert.Equal(t, 80.0, window.Low)
+	assert.Equal(t, 83.0, window.High)
+	assert.Equal(t, 81.5, window.Mean)
+	assert.Equal(t, 75.0, window.Percentile, "3 of 4 days had a lower rate")
+	assert.InDelta(t, 3.125, window.ChangePercent, 1e-9)
+	assert.Greater(t, window.VolatilityPercent, 0.0)
+	assert.Equal(t, models.TrendRising, window.Trend)
+
+	flat := summarizeWindow(5, dates, rates, 80.5)
+	assert.Equal(t, models.TrendFlat, flat.Trend, "a move within the daily volatility is no trend")
+
+	empty := summarizeWindow(5, dates, map[string]models.HistoricalRate{}, 80)
+	assert.Zero(t, empty.Samples)
+}
+
+func TestGuidance(t *testing.T) {
+	tests := []struct {
+		percentile float64
+		signal     string
+		guidance   string
+	}{
+		{95, models.SignalFavorable, "The rate is in the top 10% of the last 90 days"},
+		{80, models.SignalFavorable, "The rate is in the top 25% of the last 90 days"},
+		{50, models.SignalNeutral, "The rate is near the middle of its 90-day range"},
+		{20, models.SignalUnfavorable, "The rate is in the bottom 25% of the last 90 days"},
+		{0, models.SignalUnfavorable, "The rate is in the bottom 10% of the last 90 days"},
+	}
+
+	for _, tt := range tests {
+		signal, guidance := guidance(tt.percentile, 90)
+		assert.Equal(t, tt.signal, signal)
+		assert.Equal(t, tt.guidance, guidance)
+	}
+}
+
+func TestExchangeService_GetRateRecommendation(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 85.0)
+	today := utils.Today()
+	// A few days before the windows too, as a weekend at the start carries
+	// the previous Friday's rate
+	for i := 0; i < 24; i++ {
+		day := today.AddDate(0, 0, -i)
+		memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", day.Format(utils.DateFormat), 80+float64(i%5))
+	}
+	service := NewExchangeService(memoryCache, nil, nil)
+
+	resp, err := service.GetRateRecommendation(context.Background(), &models.RecommendationRequest{From: "USD", To: "INR", Windows: []int{10, 20}})
+	require.NoError(t, err)
+	assert.Equal(t, 85.0, resp.Rate)
+	require.Len(t, resp.Windows, 2)
+	assert.Equal(t, 10, resp.Windows[0].Days)
+	assert.Equal(t, 84.0, resp.Windows[1].High)
+	assert.Equal(t, 100.0, resp.Windows[1].Percentile)
+	assert.Equal(t, models.SignalFavorable, resp.Signal)
+	assert.Equal(t, "The rate is in the top 10% of the last 20 days", resp.Guidance)
+
+	tests := []struct {
+		name    string
+		windows []int
+	}{
+		{"window too short", []int{1}},
+		{"window beyond the range cap", []int{utils.DefaultMaxRangeDays + 1}},
+		{"too many windows", []int{7, 14, 30, 60, 90, 30}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.GetRateRecommendation(context.Background(), &models.RecommendationRequest{From: "USD", To: "INR", Windows: tt.windows})
+			code, field := models.ErrorCodeOf(err)
+			assert.Equal(t, models.ErrCodeValueInvalid, code)
+			assert.Equal(t, "windows", field)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+// maxRefetchDates bounds the dates of a single refetch, each an upstream call
+const maxRefetchDates = 31
+
+// RefetchHistoricalRates fetches the rates of a pair on dates from the
+// default provider again, for when a provider glitch left a bad rate behind.
+// A weekend or holiday refetches the trading day it carries the rate of. On
+// success the new rate replaces the cached one, the reverse pair's and the
+// negative entries of the day, and the end-of-day snapshot is corrected,
+// recording the previous rate and caller. On failure the previous rate stays
+// in place, so a refetch can never leave a day without a rate.
+func (s *ExchangeService) RefetchHistoricalRates(ctx context.Context, req *models.RefetchRequest, caller string) (*models.RefetchResponse, error) {
+	if err := utils.ValidateCurrencyPair(req.From, req.To); err != nil {
+		return nil, err
+	}
+	if req.From == req.To {
+		return nil, models.NewFieldError(models.ErrCodeValueInvalid, "to", "from and to must differ, got %s twice", req.From)
+	}
+	if len(req.Dates) == 0 || len(req.Dates) > maxRefetchDates {
+		return nil, models.NewFieldError(models.ErrCodeValueInvalid, "dates", "between 1 and %d dates can be refetched, got %d", maxRefetchDates, len(req.Dates))
+	}
+	observedDates := make([]string, len(req.Dates))
+	for i, date := range req.Dates {
+		if date == "" {
+			return nil, models.NewFieldError(models.ErrCodeDateInvalid, "dates", "dates must not be empty")
+		}
+		parsed, err := utils.ValidateDate(date)
+		if err != nil {
+			return nil, models.ForField(err, "dates", "")
+		}
+		observedDates[i] = utils.LastTradingDay(parsed, req.From, req.To).Format(utils.DateFormat)
+	}
+
+	resp := &models.RefetchResponse{
+		From:        req.From,
+		To:          req.To,
+		RequestedBy: caller,
+		RequestedAt: time.Now(),
+	}
+	// Dates carrying the same trading day's rate share one refetch
+	refetched := make(map[string]models.RefetchResult)
+	for i, date := range req.Dates {
+		observedDate := observedDates[i]
+		result, done := refetched[observedDate]
+		if !done {
+			result = s.refetchHistoricalRate(ctx, req.From, req.To, observedDate, caller, resp.RequestedAt)
+			if ctx.Err() != nil {
+				// The request is over; the remaining dates were not refetched
+				return nil, upstreamError("refetch interrupted", ctx.Err())
+			}
+			refetched[observedDate] = result
+		}
+
+		result.Date = date
+		if observedDate != date {
+			result.ObservedDate = observedDate
+		}
+		if result.Status == models.RefetchStatusRefetched {
+			resp.Refetched++
+		} else {
+			resp.Failed++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp, nil
+}
+
+func (s *ExchangeService) refetchHistoricalRate(ctx context.Context, from, to, date, caller string, at time.Time) models.RefetchResult {
+	var result models.RefetchResult
+	previous, found := s.getCachedRate(from, to, date)
+	if !found {
+		previous, found = s.getArchivedRate(from, to, date)
+	}
+	if found {
+		result.PreviousRate = &previous.rate
+	}
+
+	item, err := s.rateFetcher.RefetchHistoricalRate(ctx, from, to, date)
+	if err != nil {
+		err = upstreamError("failed to refetch historical rate from API", err)
+		log.Printf("Refetch of %s/%s on %s requested by %s failed, keeping the previous rate: %v", from, to, date, caller, err)
+		result.Status = models.RefetchStatusFailed
+		result.Error = err.Error()
+		result.ErrorCode, _ = models.ErrorCodeOf(err)
+		return result
+	}
+	result.Status = models.RefetchStatusRefetched
+	result.Rate = item.Rate
+
+	if archive := s.getArchive(); archive != nil {
+		corrected, err := archive.Correct(date, from, to, item.Rate, caller, at)
+		if err != nil {
+			log.Printf("Failed to correct the archived %s/%s rate of %s: %v", from, to, date, err)
+		}
+		result.Archived = corrected && err == nil
+	}
+	if result.PreviousRate != nil {
+		log.Printf("Historical rate of %s/%s on %s refetched by %s: %g replaced by %g", from, to, date, caller, *result.PreviousRate, item.Rate)
+	} else {
+		log.Printf("Historical rate of %s/%s on %s refetched by %s: %g", from, to, date, caller, item.Rate)
+	}
+	return result
+}
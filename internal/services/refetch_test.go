@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/store"
+	"exchange-rate-service/internal/utils"
+)
+
+func TestExchangeService_RefetchHistoricalRates(t *testing.T) {
+	friday := utils.Today().AddDate(0, 0, -1)
+	for friday.Weekday() != time.Friday {
+		friday = friday.AddDate(0, 0, -1)
+	}
+	day := func(offset int) string {
+		return friday.AddDate(0, 0, offset).Format(utils.DateFormat)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimPrefix(r.URL.Path, "/") == day(0) {
+			w.Write([]byte(`{"base":"USD","date":"` + day(0) + `","rates":{"INR":85.5}}`))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.FrankfurterBaseURL = server.URL
+	cfg.DefaultProvider = external.ProviderFrankfurter
+	cfg.Retry.MaxAttempts = 1
+	client := external.NewExchangeRateClientWithConfig(cfg)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderFrankfurter, "USD", "INR", day(0), 8.55) // A provider glitch
+	memoryCache.Set(external.ProviderFrankfurter, "INR", "USD", day(0), 0.117)
+	memoryCache.Set(external.ProviderFrankfurter, "USD", "INR", day(-1), 84.0)
+	archive, err := store.NewArchive("")
+	require.NoError(t, err)
+	require.NoError(t, archive.Save(&store.Snapshot{Date: day(0), Rates: map[string]map[string]float64{"USD": {"INR": 8.55}}}))
+	service := NewExchangeService(memoryCache, NewRateFetcher(client, memoryCache), client)
+	service.SetArchive(archive)
+
+	resp, err := service.RefetchHistoricalRates(context.Background(), &models.RefetchRequest{
+		From: "USD", To: "INR", Dates: []string{day(0), day(1), day(-1)},
+	}, "ops")
+	require.NoError(t, err)
+	assert.Equal(t, "ops", resp.RequestedBy)
+	assert.Equal(t, 2, resp.Refetched)
+	assert.Equal(t, 1, resp.Failed)
+	require.Len(t, resp.Results, 3)
+
+	refetched := resp.Results[0]
+	assert.Equal(t, models.RefetchStatusRefetched, refetched.Status)
+	require.NotNil(t, refetched.PreviousRate)
+	assert.Equal(t, 8.55, *refetched.PreviousRate)
+	assert.Equal(t, 85.5, refetched.Rate)
+	assert.True(t, refetched.Archived)
+
+	saturday := resp.Results[1]
+	assert.Equal(t, day(1), saturday.Date)
+	assert.Equal(t, day(0), saturday.ObservedDate, "a weekend refetches the Friday it carries")
+	assert.Equal(t, 85.5, saturday.Rate)
+
+	failed := resp.Results[2]
+	assert.Equal(t, models.RefetchStatusFailed, failed.Status)
+	assert.Equal(t, models.ErrCodeProviderUnavailable, failed.ErrorCode)
+	assert.Contains(t, failed.Error, "500")
+
+	rate, _ := memoryCache.Get(external.ProviderFrankfurter, "USD", "INR", day(0))
+	assert.Equal(t, 85.5, rate)
+	_, found := memoryCache.Get(external.ProviderFrankfurter, "INR", "USD", day(0))
+	assert.False(t, found, "the reverse pair is dropped rather than left inconsistent")
+	rate, _ = memoryCache.Get(external.ProviderFrankfurter, "USD", "INR", day(-1))
+	assert.Equal(t, 84.0, rate, "a failed refetch keeps the previous rate")
+
+	snapshot, _ := archive.Get(day(0))
+	rate, _ = snapshot.Rate("USD", "INR")
+	assert.Equal(t, 85.5, rate)
+	require.Len(t, snapshot.Corrections, 1)
+	assert.Equal(t, "ops", snapshot.Corrections[0].By)
+	assert.Equal(t, 8.55, snapshot.Corrections[0].PreviousRate)
+
+	tests := []struct {
+		name  string
+		req   models.RefetchRequest
+		field string
+	}{
+		{"no dates", models.RefetchRequest{From: "USD", To: "INR"}, "dates"},
+		{"malformed date", models.RefetchRequest{From: "USD", To: "INR", Dates: []string{"yesterday"}}, "dates"},
+		{"future date", models.RefetchRequest{From: "USD", To: "INR", Dates: []string{utils.Today().AddDate(0, 0, 2).Format(utils.DateFormat)}}, "dates"},
+		{"same currencies", models.RefetchRequest{From: "USD", To: "USD", Dates: []string{day(0)}}, "to"},
+		{"unsupported currency", models.RefetchRequest{From: "USD", To: "XYZ", Dates: []string{day(0)}}, "to"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.RefetchHistoricalRates(context.Background(), &tt.req, "ops")
+			require.Error(t, err)
+			_, field := models.ErrorCodeOf(err)
+			assert.Equal(t, tt.field, field)
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/models"
+)
+
+// ReplicationPath is the route peers push fetched rates to
+const ReplicationPath = "/api/v1/replication/rates"
+
+const (
+	// maxReplicatedRates bounds the rates of one push, so a full cycle stays
+	// under the request body limit of the peers
+	maxReplicatedRates = 1000
+	// replicationQueueSize bounds the cycles waiting to be pushed
+	replicationQueueSize = 64
+	// replicationMaxSkew is how far a push's timestamp may be from the
+	// receiver's clock, so a captured push can't be replayed later
+	replicationMaxSkew = 5 * time.Minute
+)
+
+// ReplicationConfig sets how instances share fetched rates
+type ReplicationConfig struct {
+	InstanceID string        // Identifies this instance to its peers; the lowest live ID fetches
+	Peers      []string      // Base URLs of the instances fetched rates are pushed to
+	Secret     string        // Shared key pushes are signed with, replication is off without it
+	StandbyFor time.Duration // How long a push keeps a follower from fetching, 0 for twice the fetch interval
+	Timeout    time.Duration // Deadline of each push
+}
+
+// DefaultReplicationConfig leaves replication off
+func DefaultReplicationConfig() ReplicationConfig {
+	return ReplicationConfig{
+		Timeout: 5 * time.Second,
+	}
+}
+
+// Enabled reports whether rates are pushed to and accepted from peers
+func (c ReplicationConfig) Enabled() bool {
+	return c.Secret != ""
+}
+
+// Replicator keeps a group of instances warm from a single upstream fetcher.
+// Every instance pushes the rates its fetch cycles refresh to its peers,
+// signed with the shared secret, and caches the rates pushed to it. An
+// instance receiving pushes from a peer with a lower instance ID goes into
+// standby: its scheduled cycles skip the upstream until the pushes stop for
+// StandbyFor, so only the lowest live instance fetches and a follower takes
+// over when it goes away.
+type Replicator struct {
+	cfg        ReplicationConfig
+	fetcher    *RateFetcher
+	httpClient *http.Client
+	pending    chan []FetchedRate
+
+	mu             sync.Mutex
+	onReceive      func([]FetchedRate) // Called with the rates of each accepted push, nil when unset
+	peers          []*models.PeerStatus
+	leader         string
+	leaderSeenAt   time.Time
+	received       int
+	rejected       int
+	applied        int
+	dropped        int
+	lastReceivedAt time.Time
+	isRunning      bool
+	ctx            context.Context
+	cancel         context.CancelFunc
+}
+
+func NewReplicator(cfg ReplicationConfig, fetcher *RateFetcher) *Replicator {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	peers := make([]*models.PeerStatus, 0, len(cfg.Peers))
+	for _, peer := range cfg.Peers {
+		peers = append(peers, &models.PeerStatus{URL: strings.TrimRight(peer, "/")})
+	}
+	return &Replicator{
+		cfg:        cfg,
+		fetcher:    fetcher,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		pending:    make(chan []FetchedRate, replicationQueueSize),
+		peers:      peers,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Enabled reports whether rates are pushed to and accepted from peers
+func (r *Replicator) Enabled() bool {
+	return r.cfg.Enabled()
+}
+
+// SetReceiveListener makes the replicator call listener with the rates of
+// each push it accepts, once they are cached. The listener must not block.
+func (r *Replicator) SetReceiveListener(listener func([]FetchedRate)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onReceive = listener
+}
+
+func (r *Replicator) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.isRunning || !r.Enabled() {
+		return
+	}
+	r.isRunning = true
+	go r.run()
+	log.Printf("Replicating fetched rates to %d peers as %s", len(r.peers), r.cfg.InstanceID)
+}
+
+// Stop abandons the pushes in flight and those still queued
+func (r *Replicator) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.isRunning {
+		return
+	}
+	r.cancel()
+	r.isRunning = false
+}
+
+// Publish queues the rates of a fetch cycle for pushing to every peer. It
+// doesn't wait for the pushes; a cycle finding the queue full is dropped.
+func (r *Replicator) Publish(rates []FetchedRate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.isRunning || len(r.peers) == 0 || len(rates) == 0 {
+		return
+	}
+	select {
+	case r.pending <- rates:
+	default:
+		r.dropped++
+	}
+}
+
+func (r *Replicator) run() {
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case rates := <-r.pending:
+			for start := 0; start < len(rates); start += maxReplicatedRates {
+				r.push(rates[start:min(start+maxReplicatedRates, len(rates))])
+			}
+		}
+	}
+}
+
+// push sends one batch of rates to every peer. Failed pushes are not
+// retried: the next cycle brings fresher rates, and a peer that misses them
+// for long enough fetches them itself.
+func (r *Replicator) push(rates []FetchedRate) {
+	payload := models.ReplicationPush{
+		Origin: r.cfg.InstanceID,
+		SentAt: time.Now().UTC(),
+		Rates:  make([]models.ReplicatedRate, 0, len(rates)),
+	}
+	for _, rate := range rates {
+		payload.Rates = append(payload.Rates, models.ReplicatedRate{
+			From:        rate.From,
+			To:          rate.To,
+			Rate:        rate.Rate,
+			Provider:    rate.Provider,
+			PublishedAt: rate.PublishedAt.UTC(),
+		})
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to encode replication push: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, peer := range r.peers {
+		wg.Add(1)
+		go func(peer *models.PeerStatus) {
+			defer wg.Done()
+			err := r.post(peer.URL, body)
+
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if err != nil {
+				peer.Failed++
+				peer.LastError = err.Error()
+				return
+			}
+			pushed := time.Now().UTC()
+			peer.Pushed++
+			peer.LastError = ""
+			peer.LastPushAt = &pushed
+		}(peer)
+	}
+	wg.Wait()
+}
+
+func (r *Replicator) post(peer string, body []byte) error {
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodPost, peer+ReplicationPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhook(r.cfg.Secret, timestamp, body))
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("peer returned status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Receive verifies a push from a peer and caches its rates, returning how
+// many it cached. Pushes with a signature not made with the shared secret or
+// a timestamp outside the replay window are refused with UNAUTHORIZED.
+func (r *Replicator) Receive(body []byte, timestamp, signature string) (int, error) {
+	if !r.Enabled() {
+		return 0, models.NewError(models.ErrCodeNotImplemented, "replication is not enabled")
+	}
+	if err := r.verify(body, timestamp, signature); err != nil {
+		r.reject()
+		return 0, err
+	}
+	var push models.ReplicationPush
+	if err := json.Unmarshal(body, &push); err != nil {
+		r.reject()
+		return 0, models.NewError(models.ErrCodeInvalidRequest, "invalid replication push: %v", err)
+	}
+	if push.Origin == r.cfg.InstanceID {
+		r.reject()
+		return 0, models.NewError(models.ErrCodeInvalidRequest, "push from instance %s to itself, check REPLICATION_PEERS", push.Origin)
+	}
+
+	// Only supported currencies are cached, so a peer running with more
+	// currencies can't grow the cache with pairs nothing here asks for
+	var applied []FetchedRate
+	for _, rate := range push.Rates {
+		if rate.From == rate.To || rate.Rate <= 0 || !models.IsSupportedCurrency(rate.From) || !models.IsSupportedCurrency(rate.To) {
+			continue
+		}
+		r.fetcher.storeLatest(rateResult{from: rate.From, to: rate.To, rate: rate.Rate, provider: rate.Provider, at: rate.PublishedAt})
+		applied = append(applied, FetchedRate{From: rate.From, To: rate.To, Rate: rate.Rate, Provider: rate.Provider, PublishedAt: rate.PublishedAt})
+	}
+	now := time.Now()
+	if len(applied) > 0 {
+		r.fetcher.recordFetch(now, len(applied), nil)
+	}
+
+	r.mu.Lock()
+	r.received++
+	r.applied += len(applied)
+	r.lastReceivedAt = now
+	// The lowest instance ID pushing leads; a push from a higher one is
+	// cached but leaves this instance fetching
+	if push.Origin < r.cfg.InstanceID && (!r.leading(now) || push.Origin <= r.leader) {
+		r.leader = push.Origin
+		r.leaderSeenAt = now
+	}
+	listener := r.onReceive
+	r.mu.Unlock()
+
+	if listener != nil && len(applied) > 0 {
+		listener(applied)
+	}
+	return len(applied), nil
+}
+
+func (r *Replicator) verify(body []byte, timestamp, signature string) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return models.NewError(models.ErrCodeUnauthorized, "missing or malformed %s header", WebhookTimestampHeader)
+	}
+	if skew := time.Since(time.Unix(seconds, 0)); skew > replicationMaxSkew || skew < -replicationMaxSkew {
+		return models.NewError(models.ErrCodeUnauthorized, "push timestamp is %v off, more than %v", skew.Round(time.Second), replicationMaxSkew)
+	}
+	expected := "sha256=" + SignWebhook(r.cfg.Secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return models.NewError(models.ErrCodeUnauthorized, "push signature does not match the replication secret")
+	}
+	return nil
+}
+
+func (r *Replicator) reject() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rejected++
+}
+
+// Standby reports whether a peer with a lower instance ID pushed rates
+// recently enough that this instance's scheduled cycles skip the upstream
+func (r *Replicator) Standby() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.leading(time.Now())
+}
+
+// leading reports whether the leader's last push is within the standby
+// window. The caller holds r.mu.
+func (r *Replicator) leading(now time.Time) bool {
+	if r.leader == "" {
+		return false
+	}
+	standbyFor := r.cfg.StandbyFor
+	if standbyFor <= 0 {
+		standbyFor = 2 * r.fetcher.FetchInterval()
+	}
+	return now.Sub(r.leaderSeenAt) < standbyFor
+}
+
+// Stats reports the pushes sent to every peer and those received
+func (r *Replicator) Stats() models.ReplicationStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := models.ReplicationStats{
+		Enabled:    r.Enabled(),
+		InstanceID: r.cfg.InstanceID,
+		Standby:    r.leading(time.Now()),
+		Received:   r.received,
+		Rejected:   r.rejected,
+		Applied:    r.applied,
+		Dropped:    r.dropped,
+		Peers:      make([]models.PeerStatus, 0, len(r.peers)),
+	}
+	if stats.Standby {
+		stats.LeaderID = r.leader
+	}
+	if !r.lastReceivedAt.IsZero() {
+		received := r.lastReceivedAt.UTC()
+		stats.LastReceivedAt = &received
+	}
+	for _, peer := range r.peers {
+		stats.Peers = append(stats.Peers, *peer)
+	}
+	return stats
+}
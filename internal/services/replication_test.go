@@ -0,0 +1,185 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+)
+
+const testReplicationSecret = "replication-secret"
+
+func newTestReplicator(t *testing.T, id string, peers ...string) (*Replicator, *cache.MemoryCache) {
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	fetcher := NewRateFetcher(external.NewExchangeRateClientWithConfig(external.DefaultConfig()), memoryCache)
+	replicator := NewReplicator(ReplicationConfig{
+		InstanceID: id,
+		Peers:      peers,
+		Secret:     testReplicationSecret,
+		StandbyFor: time.Minute,
+		Timeout:    time.Second,
+	}, fetcher)
+	replicator.Start()
+	t.Cleanup(replicator.Stop)
+	return replicator, memoryCache
+}
+
+// replicationPeer serves the replication route of a replicator
+func replicationPeer(t *testing.T, replicator *Replicator) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, ReplicationPath, r.URL.Path)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if _, err := replicator.Receive(body, r.Header.Get(WebhookTimestampHeader), r.Header.Get(WebhookSignatureHeader)); err != nil {
+			code, _ := models.ErrorCodeOf(err)
+			w.WriteHeader(models.ErrorStatus(code))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func signedPush(t *testing.T, secret string, push models.ReplicationPush, at time.Time) ([]byte, string, string) {
+	body, err := json.Marshal(push)
+	require.NoError(t, err)
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return body, timestamp, "sha256=" + SignWebhook(secret, timestamp, body)
+}
+
+func TestReplicator_PushesFetchedRatesToPeers(t *testing.T) {
+	follower, followerCache := newTestReplicator(t, "b")
+	var received []FetchedRate
+	follower.SetReceiveListener(func(rates []FetchedRate) {
+		received = append(received, rates...)
+	})
+	leader, _ := newTestReplicator(t, "a", replicationPeer(t, follower).URL+"/")
+
+	published := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	leader.Publish([]FetchedRate{
+		{From: "USD", To: "INR", Rate: 83.1, Provider: external.ProviderExchangeRateAPI, PublishedAt: published},
+		{From: "USD", To: "XXX", Rate: 2, Provider: external.ProviderExchangeRateAPI, PublishedAt: published},
+	})
+
+	require.Eventually(t, func() bool {
+		return leader.Stats().Peers[0].Pushed == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	item, found := followerCache.GetItem(external.ProviderExchangeRateAPI, "USD", "INR", "")
+	require.True(t, found)
+	assert.Equal(t, 83.1, item.Rate)
+	assert.True(t, published.Equal(item.PublishedAt))
+	_, found = followerCache.Get(external.ProviderExchangeRateAPI, "USD", "XXX", "")
+	assert.False(t, found, "unsupported currencies are not cached")
+	assert.Len(t, received, 1)
+	assert.False(t, follower.fetcher.Status().LastSuccess.IsZero())
+
+	stats := follower.Stats()
+	assert.True(t, stats.Standby)
+	assert.Equal(t, "a", stats.LeaderID)
+	assert.Equal(t, 1, stats.Received)
+	assert.Equal(t, 1, stats.Applied)
+	assert.True(t, follower.Standby())
+	assert.False(t, leader.Standby())
+}
+
+func TestReplicator_HigherInstanceDoesNotLead(t *testing.T) {
+	replicator, _ := newTestReplicator(t, "b")
+
+	body, timestamp, signature := signedPush(t, testReplicationSecret, models.ReplicationPush{
+		Origin: "c",
+		Rates:  []models.ReplicatedRate{{From: "EUR", To: "USD", Rate: 1.09}},
+	}, time.Now())
+	applied, err := replicator.Receive(body, timestamp, signature)
+	require.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	assert.False(t, replicator.Standby(), "the lowest instance keeps fetching")
+}
+
+func TestReplicator_RejectsUnverifiedPushes(t *testing.T) {
+	replicator, memoryCache := newTestReplicator(t, "b")
+	push := models.ReplicationPush{Origin: "a", Rates: []models.ReplicatedRate{{From: "EUR", To: "USD", Rate: 1.09}}}
+
+	tests := []struct {
+		name string
+		push func() ([]byte, string, string)
+	}{
+		{"wrong secret", func() ([]byte, string, string) {
+			return signedPush(t, "other-secret", push, time.Now())
+		}},
+		{"stale timestamp", func() ([]byte, string, string) {
+			return signedPush(t, testReplicationSecret, push, time.Now().Add(-time.Hour))
+		}},
+		{"missing timestamp", func() ([]byte, string, string) {
+			body, _, signature := signedPush(t, testReplicationSecret, push, time.Now())
+			return body, "", signature
+		}},
+		{"tampered body", func() ([]byte, string, string) {
+			body, timestamp, signature := signedPush(t, testReplicationSecret, push, time.Now())
+			body[len(body)-3] = '9'
+			return body, timestamp, signature
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := replicator.Receive(tt.push())
+			code, _ := models.ErrorCodeOf(err)
+			assert.Equal(t, models.ErrCodeUnauthorized, code)
+		})
+	}
+
+	_, found := memoryCache.Get("", "EUR", "USD", "")
+	assert.False(t, found)
+	stats := replicator.Stats()
+	assert.Equal(t, len(tests), stats.Rejected)
+	assert.Zero(t, stats.Received)
+	assert.False(t, stats.Standby)
+}
+
+func TestReplicator_DisabledWithoutSecret(t *testing.T) {
+	fetcher := NewRateFetcher(external.NewExchangeRateClientWithConfig(external.DefaultConfig()), cache.NewMemoryCache(time.Hour))
+	replicator := NewReplicator(DefaultReplicationConfig(), fetcher)
+	replicator.Start()
+	defer replicator.Stop()
+
+	_, err := replicator.Receive([]byte(`{}`), "", "")
+	code, _ := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeNotImplemented, code)
+	assert.False(t, replicator.Stats().Enabled)
+}
+
+func TestRateFetcher_StandbySkipsScheduledFetches(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"base":"USD","rates":{"USD":1,"INR":83.5,"EUR":0.9,"GBP":0.8,"JPY":150}}`))
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = server.URL
+	fetcher := NewRateFetcher(external.NewExchangeRateClientWithConfig(cfg), cache.NewMemoryCache(time.Hour))
+	fetcher.SetSchedule(time.Hour, nil)
+	standby := true
+	fetcher.SetStandby(func() bool { return standby })
+
+	start := time.Now()
+	queue := fetcher.buildQueue(start)
+	fetcher.refreshDue(&queue, start.Add(time.Hour))
+	assert.Zero(t, calls.Load())
+	assert.True(t, start.Add(2*time.Hour).Equal(queue.peek().next), "pairs are rescheduled in standby")
+
+	standby = false
+	fetcher.refreshDue(&queue, start.Add(2*time.Hour))
+	assert.NotZero(t, calls.Load())
+}
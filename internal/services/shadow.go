@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+)
+
+// maxRecentShadowMismatches bounds the mismatches kept for the stats endpoint
+const maxRecentShadowMismatches = 50
+
+// ShadowConfig sets up shadow traffic to a provider being evaluated
+type ShadowConfig struct {
+	Provider         string        // Provider queried in the shadow, off when empty
+	Percent          float64       // Share of latest rate requests mirrored, 0 to 100
+	ThresholdPercent float64       // Difference from the served rate logged as a mismatch
+	Concurrency      int           // Shadow lookups in flight at once; requests beyond are not mirrored
+	Timeout          time.Duration // Deadline of each shadow lookup
+}
+
+// DefaultShadowConfig mirrors 10% of requests, four at a time, once a
+// provider is configured
+func DefaultShadowConfig() ShadowConfig {
+	return ShadowConfig{
+		Percent:          10,
+		ThresholdPercent: 0.5,
+		Concurrency:      4,
+		Timeout:          5 * time.Second,
+	}
+}
+
+// ShadowTraffic queries a secondary provider for a share of the latest rates
+// served, in the background, and compares its quotes with the served ones.
+// The shadow's answers are only logged and counted, never served, so a new
+// provider can be validated on live traffic before switching to it.
+type ShadowTraffic struct {
+	client *external.ExchangeRateClient
+	cfg    ShadowConfig
+	slots  chan struct{}
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu             sync.Mutex
+	credit         float64 // Accumulated Percent; a request is mirrored each time it reaches 100
+	sampled        int64
+	dropped        int64
+	compared       int64
+	mismatches     int64
+	errors         int64
+	maxDiffPercent float64
+	recent         []models.ShadowComparison // Newest first
+}
+
+func NewShadowTraffic(client *external.ExchangeRateClient, cfg ShadowConfig) *ShadowTraffic {
+	ctx, cancel := context.WithCancel(context.Background())
+	concurrency := cfg.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &ShadowTraffic{
+		client: client,
+		cfg:    cfg,
+		slots:  make(chan struct{}, concurrency),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Enabled reports whether any traffic is mirrored
+func (s *ShadowTraffic) Enabled() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enabled()
+}
+
+// enabled is Enabled for a caller holding s.mu
+func (s *ShadowTraffic) enabled() bool {
+	return s.cfg.Provider != "" && s.cfg.Percent > 0
+}
+
+// SetProvider changes the provider compared with the served rates; an empty
+// provider stops mirroring. Lookups in flight finish with the previous one.
+func (s *ShadowTraffic) SetProvider(provider string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg.Provider = provider
+}
+
+// Mirror compares the rate served for a pair with the shadow provider's, in
+// the background, when the request falls in the sampled share. Sampling is
+// spread evenly: at 10% every tenth request is mirrored.
+func (s *ShadowTraffic) Mirror(from, to string, served float64, servedProvider string) {
+	if s == nil || from == to {
+		return
+	}
+	provider, sampled := s.sample()
+	if !sampled {
+		return
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		s.count(func() { s.dropped++ })
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() { <-s.slots }()
+		s.compare(provider, from, to, served, servedProvider, time.Now())
+	}()
+}
+
+// sample reports whether a request is mirrored, and to which provider
+func (s *ShadowTraffic) sample() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.enabled() {
+		return "", false
+	}
+	s.credit += s.cfg.Percent
+	if s.credit < 100 {
+		return "", false
+	}
+	s.credit -= 100
+	s.sampled++
+	return s.cfg.Provider, true
+}
+
+func (s *ShadowTraffic) count(update func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update()
+}
+
+func (s *ShadowTraffic) compare(provider, from, to string, served float64, servedProvider string, now time.Time) {
+	ctx, cancel := context.WithTimeout(s.ctx, s.cfg.Timeout)
+	defer cancel()
+
+	response, err := s.client.GetLatestRatesFrom(ctx, provider, from)
+	var shadow float64
+	if err == nil {
+		if shadow = response.Rates[to]; shadow <= 0 {
+			err = external.ErrRateNotFound
+		}
+	}
+	if err != nil {
+		log.Printf("Shadow provider %s could not quote %s/%s: %v", provider, from, to, err)
+		s.count(func() { s.errors++ })
+		return
+	}
+
+	diff := math.Abs(shadow-served) / served * 100
+	comparison := models.ShadowComparison{
+		From:           from,
+		To:             to,
+		ServedProvider: servedProvider,
+		ServedRate:     served,
+		ShadowProvider: provider,
+		ShadowRate:     shadow,
+		DiffPercent:    diff,
+		ComparedAt:     now,
+	}
+	mismatch := diff > s.cfg.ThresholdPercent
+	if mismatch {
+		log.Printf("Shadow provider %s quotes %s/%s at %g, %.3f%% from the served %g (threshold %.2f%%)",
+			provider, from, to, shadow, diff, served, s.cfg.ThresholdPercent)
+	}
+
+	s.count(func() {
+		s.compared++
+		if diff > s.maxDiffPercent {
+			s.maxDiffPercent = diff
+		}
+		if !mismatch {
+			return
+		}
+		s.mismatches++
+		s.recent = append([]models.ShadowComparison{comparison}, s.recent...)
+		if len(s.recent) > maxRecentShadowMismatches {
+			s.recent = s.recent[:maxRecentShadowMismatches]
+		}
+	})
+}
+
+// Stop cancels the shadow lookups in flight and waits for them to end
+func (s *ShadowTraffic) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Stats returns the comparison counters and the most recent mismatches
+func (s *ShadowTraffic) Stats() models.ShadowStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return models.ShadowStats{
+		Enabled:          s.enabled(),
+		Provider:         s.cfg.Provider,
+		Percent:          s.cfg.Percent,
+		ThresholdPercent: s.cfg.ThresholdPercent,
+		Sampled:          s.sampled,
+		Dropped:          s.dropped,
+		Compared:         s.compared,
+		Mismatches:       s.mismatches,
+		Errors:           s.errors,
+		MaxDiffPercent:   s.maxDiffPercent,
+		Recent:           append([]models.ShadowComparison{}, s.recent...),
+	}
+}
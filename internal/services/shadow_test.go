@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+)
+
+func newShadowClient(t *testing.T, handler http.HandlerFunc) *external.ExchangeRateClient {
+	frankfurter := httptest.NewServer(handler)
+	t.Cleanup(frankfurter.Close)
+
+	cfg := external.DefaultConfig()
+	cfg.FrankfurterBaseURL = frankfurter.URL
+	return external.NewExchangeRateClientWithConfig(cfg)
+}
+
+func TestShadowTraffic_Mirror(t *testing.T) {
+	var lookups int32
+	client := newShadowClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lookups, 1)
+		w.Write([]byte(`{"base":"USD","date":"2025-01-03","rates":{"INR":85.0,"EUR":0.92}}`))
+	})
+	shadow := NewShadowTraffic(client, ShadowConfig{
+		Provider:         external.ProviderFrankfurter,
+		Percent:          50,
+		ThresholdPercent: 1,
+		Concurrency:      4,
+		Timeout:          time.Second,
+	})
+
+	shadow.Mirror("USD", "INR", 83.0, external.ProviderExchangeRateAPI) // Not sampled
+	shadow.Mirror("USD", "INR", 83.0, external.ProviderExchangeRateAPI)
+	shadow.Mirror("USD", "EUR", 0.921, external.ProviderExchangeRateAPI) // Not sampled
+	shadow.Mirror("USD", "EUR", 0.921, external.ProviderExchangeRateAPI)
+	shadow.Mirror("USD", "GBP", 0.79, external.ProviderExchangeRateAPI) // Not sampled
+	shadow.Mirror("USD", "GBP", 0.79, external.ProviderExchangeRateAPI)
+	shadow.wg.Wait()
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&lookups), "half of the requests are mirrored")
+	stats := shadow.Stats()
+	assert.True(t, stats.Enabled)
+	assert.Equal(t, int64(3), stats.Sampled)
+	assert.Equal(t, int64(2), stats.Compared)
+	assert.Equal(t, int64(1), stats.Mismatches)
+	assert.Equal(t, int64(1), stats.Errors, "the shadow provider has no GBP rate")
+	assert.InDelta(t, 2.41, stats.MaxDiffPercent, 0.01)
+	require.Len(t, stats.Recent, 1)
+	assert.Equal(t, "INR", stats.Recent[0].To)
+	assert.Equal(t, 85.0, stats.Recent[0].ShadowRate)
+	assert.Equal(t, external.ProviderExchangeRateAPI, stats.Recent[0].ServedProvider)
+}
+
+func TestShadowTraffic_SetProvider(t *testing.T) {
+	var lookups int32
+	client := newShadowClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lookups, 1)
+		w.Write([]byte(`{"base":"USD","date":"2025-01-03","rates":{"INR":83.0}}`))
+	})
+	shadow := NewShadowTraffic(client, ShadowConfig{Percent: 100, Concurrency: 1, Timeout: time.Second})
+	assert.False(t, shadow.Enabled())
+
+	shadow.SetProvider(external.ProviderFrankfurter)
+	require.True(t, shadow.Enabled(), "a reload can turn the shadow traffic on")
+	shadow.Mirror("USD", "INR", 83.0, external.ProviderExchangeRateAPI)
+	shadow.wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&lookups))
+
+	shadow.SetProvider("")
+	shadow.Mirror("USD", "INR", 83.0, external.ProviderExchangeRateAPI)
+	shadow.wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&lookups), "and off again")
+	assert.Equal(t, int64(1), shadow.Stats().Sampled)
+}
+
+func TestShadowTraffic_DropsWhenBusy(t *testing.T) {
+	release := make(chan struct{})
+	client := newShadowClient(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{"base":"USD","date":"2025-01-03","rates":{"INR":83.0}}`))
+	})
+	shadow := NewShadowTraffic(client, ShadowConfig{Provider: external.ProviderFrankfurter, Percent: 100, Concurrency: 1, Timeout: time.Second})
+
+	shadow.Mirror("USD", "INR", 83.0, "")
+	shadow.Mirror("USD", "INR", 83.0, "")
+	close(release)
+	shadow.wg.Wait()
+
+	stats := shadow.Stats()
+	assert.Equal(t, int64(2), stats.Sampled)
+	assert.Equal(t, int64(1), stats.Dropped, "the only slot was taken")
+	assert.Equal(t, int64(1), stats.Compared)
+	assert.Zero(t, stats.Mismatches)
+}
+
+func TestExchangeService_MirrorsDefaultProviderRates(t *testing.T) {
+	var lookups int32
+	client := newShadowClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lookups, 1)
+		w.Write([]byte(`{"base":"USD","date":"2025-01-03","rates":{"INR":83.0}}`))
+	})
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 83.0)
+	service := NewExchangeService(memoryCache, nil, client)
+	assert.Empty(t, service.GetShadowStats().Provider)
+
+	shadow := NewShadowTraffic(client, ShadowConfig{Provider: external.ProviderFrankfurter, Percent: 100, Concurrency: 4, Timeout: time.Second})
+	service.SetShadowTraffic(shadow)
+
+	_, err := service.GetLatestRate(context.Background(), "USD", "INR", "")
+	require.NoError(t, err)
+	_, err = service.ConvertCurrency(context.Background(), &models.ConversionRequest{From: "USD", To: "INR", Amount: 10})
+	require.NoError(t, err)
+	shadow.wg.Wait()
+
+	stats := service.GetShadowStats()
+	assert.Equal(t, int64(2), stats.Compared)
+	assert.Zero(t, stats.MaxDiffPercent)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&lookups))
+}
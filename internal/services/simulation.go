@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+// SimulateConversion converts the amount of req like ConvertCurrency does,
+// then again at the hypothetical rate of req, keeping the precision,
+// rounding and fees, for margin and scenario analysis. Simulations are
+// neither signed nor audited.
+func (s *ExchangeService) SimulateConversion(ctx context.Context, req *models.SimulationRequest) (*models.SimulationResponse, error) {
+	switch {
+	case req.Rate == nil && req.RateOffsetPercent == nil:
+		return nil, models.NewFieldError(models.ErrCodeMissingParameter, "rate", "rate or rate_offset_percent is required")
+	case req.Rate != nil && req.RateOffsetPercent != nil:
+		return nil, models.NewFieldError(models.ErrCodeInvalidRequest, "rate_offset_percent", "rate and rate_offset_percent cannot both be set")
+	case req.Rate != nil && *req.Rate <= 0:
+		return nil, models.NewFieldError(models.ErrCodeValueInvalid, "rate", "rate must be positive")
+	case req.RateOffsetPercent != nil && *req.RateOffsetPercent <= -100:
+		return nil, models.NewFieldError(models.ErrCodeValueInvalid, "rate_offset_percent", "rate_offset_percent must be above -100")
+	}
+
+	actual, err := s.ConvertCurrency(ctx, &req.ConversionRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := actual.UnroundedRate
+	if req.Rate != nil {
+		rate = *req.Rate
+	} else {
+		rate *= 1 + *req.RateOffsetPercent/100
+	}
+	unroundedAmount := req.Amount * rate
+	simulated := models.SimulatedConversion{
+		Rate:              utils.Round(rate, models.RatePrecision, actual.Rounding),
+		RateOffsetPercent: utils.Round((rate/actual.UnroundedRate-1)*100, 4, models.RoundingHalfUp),
+		ConvertedAmount:   utils.Round(unroundedAmount, actual.Precision, actual.Rounding),
+		UnroundedAmount:   unroundedAmount,
+	}
+
+	actualAmount, simulatedAmount := actual.ConvertedAmount, simulated.ConvertedAmount
+	if actual.Fees != nil {
+		if simulated.Fees, err = conversionFees(&req.ConversionRequest, actual.MidMarketRate, simulated.ConvertedAmount, actual.Precision, actual.Rounding); err != nil {
+			return nil, err
+		}
+		actualAmount, simulatedAmount = actual.Fees.NetAmount, simulated.Fees.NetAmount
+	}
+
+	resp := &models.SimulationResponse{
+		Actual:     actual,
+		Simulated:  simulated,
+		Difference: utils.Round(simulatedAmount-actualAmount, actual.Precision, models.RoundingHalfUp),
+	}
+	if actualAmount != 0 {
+		resp.DifferencePercent = utils.Round((simulatedAmount-actualAmount)/actualAmount*100, 4, models.RoundingHalfUp)
+	}
+	return resp, nil
+}
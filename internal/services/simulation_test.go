@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+)
+
+func TestExchangeService_SimulateConversion(t *testing.T) {
+	client := external.NewExchangeRateClient()
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 83.0)
+	service := NewExchangeService(memoryCache, NewRateFetcher(client, memoryCache), client)
+
+	offset := 2.0
+	resp, err := service.SimulateConversion(context.Background(), &models.SimulationRequest{
+		ConversionRequest: models.ConversionRequest{From: "USD", To: "INR", Amount: 100},
+		RateOffsetPercent: &offset,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 8300.0, resp.Actual.ConvertedAmount)
+	assert.Equal(t, 84.66, resp.Simulated.Rate)
+	assert.Equal(t, 2.0, resp.Simulated.RateOffsetPercent)
+	assert.Equal(t, 8466.0, resp.Simulated.ConvertedAmount)
+	assert.Equal(t, 166.0, resp.Difference)
+	assert.Equal(t, 2.0, resp.DifferencePercent)
+	assert.Nil(t, resp.Simulated.Fees)
+
+	// A given rate, with the fees of the actual conversion
+	rate := 80.0
+	resp, err = service.SimulateConversion(context.Background(), &models.SimulationRequest{
+		ConversionRequest: models.ConversionRequest{From: "USD", To: "INR", Amount: 100, FeePercent: 1},
+		Rate:              &rate,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Actual.Fees)
+	assert.Equal(t, 8217.0, resp.Actual.Fees.NetAmount)
+	require.NotNil(t, resp.Simulated.Fees)
+	assert.Equal(t, 80.0, resp.Simulated.Fees.FeeAmount)
+	assert.Equal(t, 7920.0, resp.Simulated.Fees.NetAmount)
+	assert.Equal(t, -297.0, resp.Difference)
+	assert.InDelta(t, -3.6145, resp.Simulated.RateOffsetPercent, 0.0001)
+
+	tests := []struct {
+		name  string
+		req   models.SimulationRequest
+		code  string
+		field string
+	}{
+		{"no what-if rate", models.SimulationRequest{}, models.ErrCodeMissingParameter, "rate"},
+		{"rate and offset", models.SimulationRequest{Rate: &rate, RateOffsetPercent: &offset}, models.ErrCodeInvalidRequest, "rate_offset_percent"},
+		{"zero rate", models.SimulationRequest{Rate: new(float64)}, models.ErrCodeValueInvalid, "rate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.ConversionRequest = models.ConversionRequest{From: "USD", To: "INR", Amount: 100}
+			_, err := service.SimulateConversion(context.Background(), &tt.req)
+			code, field := models.ErrorCodeOf(err)
+			assert.Equal(t, tt.code, code)
+			assert.Equal(t, tt.field, field)
+		})
+	}
+}
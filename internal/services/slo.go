@@ -0,0 +1,359 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/models"
+)
+
+const (
+	// SLOAnyEndpoint keys the targets of endpoints without their own
+	SLOAnyEndpoint = "*"
+
+	// maxSLOSamples bounds the requests kept per endpoint; busier endpoints
+	// are judged on their most recent requests within the window
+	maxSLOSamples = 4096
+
+	defaultSLOTemplate = `SLO {{.Status}}: {{.Endpoint}} {{.Objective}} is {{printf "%.4g" .Observed}}, target {{printf "%.4g" .Threshold}}`
+)
+
+// SLOTarget is what an endpoint is held to. Zero objectives are not checked.
+type SLOTarget struct {
+	P50              time.Duration
+	P95              time.Duration
+	P99              time.Duration
+	ErrorRatePercent float64 // Share of 5xx responses
+}
+
+// objectives returns the set objectives, latencies in seconds
+func (t SLOTarget) objectives() map[string]float64 {
+	objectives := make(map[string]float64, 4)
+	for objective, latency := range map[string]time.Duration{
+		models.SLOObjectiveP50: t.P50,
+		models.SLOObjectiveP95: t.P95,
+		models.SLOObjectiveP99: t.P99,
+	} {
+		if latency > 0 {
+			objectives[objective] = latency.Seconds()
+		}
+	}
+	if t.ErrorRatePercent > 0 {
+		objectives[models.SLOObjectiveErrorRate] = t.ErrorRatePercent
+	}
+	return objectives
+}
+
+// SLOConfig sets the targets endpoints are evaluated against
+type SLOConfig struct {
+	Targets     map[string]SLOTarget // Route -> target; SLOAnyEndpoint applies to the other routes
+	Window      time.Duration        // How far back requests are measured
+	Interval    time.Duration        // Time between evaluations
+	MinRequests int                  // Fewer requests in the window are not judged
+	WebhookURL  string               // Optional URL violations and resolutions are POSTed to as JSON
+	Alerts      AlertConfig          // Further channels violations and resolutions are sent through
+}
+
+// DefaultSLOConfig measures the last 5 minutes every 30 seconds, judging
+// endpoints with at least 20 requests. No targets are set until configured.
+func DefaultSLOConfig() SLOConfig {
+	return SLOConfig{
+		Window:      5 * time.Minute,
+		Interval:    30 * time.Second,
+		MinRequests: 20,
+	}
+}
+
+type sloSample struct {
+	at      time.Time
+	latency time.Duration
+	failed  bool
+}
+
+// endpointSLO holds an endpoint's recent requests, in a ring, and the
+// objectives it currently misses
+type endpointSLO struct {
+	samples    []sloSample
+	next       int
+	violations map[string]models.SLOViolation
+	total      map[string]int64
+}
+
+// sloSummary is an endpoint's requests within the window
+type sloSummary struct {
+	requests, errors int64
+	errorRate        float64
+	p50, p95, p99    float64
+}
+
+func (s sloSummary) observed(objective string) float64 {
+	switch objective {
+	case models.SLOObjectiveP50:
+		return s.p50
+	case models.SLOObjectiveP95:
+		return s.p95
+	case models.SLOObjectiveP99:
+		return s.p99
+	}
+	return s.errorRate
+}
+
+// SLOTracker measures the latency percentiles and error rate of every
+// endpoint and periodically checks them against their targets. An
+// objective missed is logged, counted, reported and optionally sent through
+// the configured channels, and so is its recovery.
+type SLOTracker struct {
+	cfg     SLOConfig
+	alerter *alerter
+
+	mu            sync.Mutex
+	endpoints     map[string]*endpointSLO
+	evaluations   int64
+	alerts        int64
+	lastEvaluated time.Time
+	isRunning     bool
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+func NewSLOTracker(cfg SLOConfig) *SLOTracker {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	alerts := cfg.Alerts
+	if cfg.WebhookURL != "" {
+		alerts.Channels = append([]AlertChannel{{Kind: AlertChannelWebhook, Target: cfg.WebhookURL}}, alerts.Channels...)
+	}
+
+	return &SLOTracker{
+		cfg:       cfg,
+		alerter:   newAlerter("SLO", alerts, defaultSLOTemplate),
+		endpoints: make(map[string]*endpointSLO),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Enabled reports whether any endpoint has a target to be evaluated against
+func (t *SLOTracker) Enabled() bool {
+	return len(t.cfg.Targets) > 0 && t.cfg.Interval > 0
+}
+
+// Observe records a request to endpoint. 5xx responses count as errors; a
+// client's mistake does not burn the error budget.
+func (t *SLOTracker) Observe(endpoint string, latency time.Duration, status int) {
+	if t == nil {
+		return
+	}
+	sample := sloSample{at: time.Now(), latency: latency, failed: status >= http.StatusInternalServerError}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.endpoints[endpoint]
+	if !ok {
+		e = &endpointSLO{violations: make(map[string]models.SLOViolation), total: make(map[string]int64)}
+		t.endpoints[endpoint] = e
+	}
+	if len(e.samples) < maxSLOSamples {
+		e.samples = append(e.samples, sample)
+		return
+	}
+	e.samples[e.next] = sample
+	e.next = (e.next + 1) % maxSLOSamples
+}
+
+func (t *SLOTracker) Start() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.isRunning || !t.Enabled() {
+		return
+	}
+	t.isRunning = true
+
+	log.Printf("Evaluating SLOs of %d endpoints every %v over %v", len(t.cfg.Targets), t.cfg.Interval, t.cfg.Window)
+	go t.run()
+}
+
+func (t *SLOTracker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.isRunning {
+		return
+	}
+	t.cancel()
+	t.isRunning = false
+}
+
+func (t *SLOTracker) run() {
+	ticker := time.NewTicker(t.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case now := <-ticker.C:
+			t.Evaluate(now)
+		}
+	}
+}
+
+// target returns the target of endpoint, its own or the catch-all one
+func (t *SLOTracker) target(endpoint string) (SLOTarget, bool) {
+	if target, ok := t.cfg.Targets[endpoint]; ok {
+		return target, true
+	}
+	target, ok := t.cfg.Targets[SLOAnyEndpoint]
+	return target, ok
+}
+
+// Evaluate checks every endpoint with a target against it and returns the
+// objectives that started or stopped being missed since the last
+// evaluation. Endpoints with fewer than MinRequests requests in the window
+// keep their state.
+func (t *SLOTracker) Evaluate(now time.Time) []models.SLOViolation {
+	t.mu.Lock()
+	var changes []models.SLOViolation
+	for endpoint, e := range t.endpoints {
+		target, ok := t.target(endpoint)
+		if !ok {
+			continue
+		}
+		summary := e.summarize(now, t.cfg.Window)
+		if summary.requests < int64(t.cfg.MinRequests) {
+			continue
+		}
+
+		for objective, limit := range target.objectives() {
+			observed := summary.observed(objective)
+			active, violated := e.violations[objective]
+			switch {
+			case observed > limit && !violated:
+				active = models.SLOViolation{Endpoint: endpoint, Objective: objective, Target: limit,
+					Observed: observed, Status: models.SLOStatusViolated, Since: now, At: now}
+				e.violations[objective] = active
+				e.total[objective]++
+				changes = append(changes, active)
+			case observed > limit:
+				active.Observed, active.At = observed, now
+				e.violations[objective] = active
+			case violated:
+				delete(e.violations, objective)
+				active.Observed, active.Status, active.At = observed, models.SLOStatusResolved, now
+				changes = append(changes, active)
+			}
+		}
+	}
+	t.evaluations++
+	t.alerts += int64(len(changes))
+	t.lastEvaluated = now
+	t.mu.Unlock()
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Endpoint != changes[j].Endpoint {
+			return changes[i].Endpoint < changes[j].Endpoint
+		}
+		return changes[i].Objective < changes[j].Objective
+	})
+	for _, change := range changes {
+		if change.Status == models.SLOStatusViolated {
+			log.Printf("SLO violated: %s %s is %.4g, target %.4g", change.Endpoint, change.Objective, change.Observed, change.Target)
+		} else {
+			log.Printf("SLO recovered: %s %s is %.4g, target %.4g", change.Endpoint, change.Objective, change.Observed, change.Target)
+		}
+		if t.alerter.enabled() {
+			t.alerter.send(t.ctx, AlertMessage{
+				Subject:   fmt.Sprintf("SLO %s: %s %s", change.Status, change.Endpoint, change.Objective),
+				Threshold: change.Target,
+				Observed:  change.Observed,
+				Endpoint:  change.Endpoint,
+				Objective: change.Objective,
+				Status:    change.Status,
+				At:        change.At,
+			}, change)
+		}
+	}
+	return changes
+}
+
+// summarize measures the requests of the last window before now. The caller
+// must hold the tracker's lock.
+func (e *endpointSLO) summarize(now time.Time, window time.Duration) sloSummary {
+	since := now.Add(-window)
+	latencies := make([]time.Duration, 0, len(e.samples))
+	var summary sloSummary
+	for _, sample := range e.samples {
+		if sample.at.Before(since) || sample.at.After(now) {
+			continue
+		}
+		latencies = append(latencies, sample.latency)
+		if sample.failed {
+			summary.errors++
+		}
+	}
+	summary.requests = int64(len(latencies))
+	if summary.requests == 0 {
+		return summary
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) float64 {
+		return latencies[int(p*float64(len(latencies)-1))].Seconds()
+	}
+	summary.p50, summary.p95, summary.p99 = percentile(0.5), percentile(0.95), percentile(0.99)
+	summary.errorRate = float64(summary.errors) / float64(summary.requests) * 100
+	return summary
+}
+
+// Report returns every endpoint's latency and error rate over the window
+// ending at now, with its targets and the objectives it missed at the last
+// evaluation
+func (t *SLOTracker) Report(now time.Time) models.SLOReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := models.SLOReport{
+		WindowSeconds: t.cfg.Window.Seconds(),
+		Evaluations:   t.evaluations,
+		Alerts:        t.alerts,
+		Endpoints:     make([]models.EndpointSLO, 0, len(t.endpoints)),
+	}
+	if !t.lastEvaluated.IsZero() {
+		lastEvaluated := t.lastEvaluated
+		report.LastEvaluated = &lastEvaluated
+	}
+
+	for endpoint, e := range t.endpoints {
+		summary := e.summarize(now, t.cfg.Window)
+		slo := models.EndpointSLO{
+			Endpoint:         endpoint,
+			Requests:         summary.requests,
+			Errors:           summary.errors,
+			ErrorRatePercent: summary.errorRate,
+			P50Seconds:       summary.p50,
+			P95Seconds:       summary.p95,
+			P99Seconds:       summary.p99,
+		}
+		if target, ok := t.target(endpoint); ok {
+			slo.Targets = target.objectives()
+			slo.ViolationsTotal = make(map[string]int64, len(e.total))
+			for objective, total := range e.total {
+				slo.ViolationsTotal[objective] = total
+			}
+		}
+		for _, violation := range e.violations {
+			slo.Violations = append(slo.Violations, violation)
+		}
+		sort.Slice(slo.Violations, func(i, j int) bool { return slo.Violations[i].Objective < slo.Violations[j].Objective })
+		report.Endpoints = append(report.Endpoints, slo)
+	}
+	sort.Slice(report.Endpoints, func(i, j int) bool { return report.Endpoints[i].Endpoint < report.Endpoints[j].Endpoint })
+	return report
+}
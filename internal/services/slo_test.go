@@ -0,0 +1,93 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/models"
+)
+
+func TestSLOTracker_Evaluate(t *testing.T) {
+	var alerts []models.SLOViolation
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var violation models.SLOViolation
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&violation))
+		alerts = append(alerts, violation)
+	}))
+	defer webhook.Close()
+
+	cfg := DefaultSLOConfig()
+	cfg.MinRequests = 10
+	cfg.WebhookURL = webhook.URL
+	cfg.Targets = map[string]SLOTarget{
+		"/api/v1/rates/latest": {P95: 100 * time.Millisecond, ErrorRatePercent: 5},
+		SLOAnyEndpoint:         {P99: time.Second},
+	}
+	tracker := NewSLOTracker(cfg)
+
+	for i := 0; i < 20; i++ {
+		latency, status := 10*time.Millisecond, http.StatusOK
+		if i%4 == 0 {
+			latency, status = 300*time.Millisecond, http.StatusBadGateway
+		}
+		tracker.Observe("/api/v1/rates/latest", latency, status)
+		tracker.Observe("/api/v1/currencies", time.Millisecond, http.StatusBadRequest)
+	}
+	tracker.Observe("/api/v1/keys", 5*time.Second, http.StatusOK)
+
+	changes := tracker.Evaluate(time.Now())
+	require.Len(t, changes, 2, "too few requests to /keys to judge it")
+	assert.Equal(t, models.SLOObjectiveErrorRate, changes[0].Objective)
+	assert.InDelta(t, 25, changes[0].Observed, 0.001)
+	assert.Equal(t, models.SLOObjectiveP95, changes[1].Objective)
+	assert.InDelta(t, 0.3, changes[1].Observed, 0.001)
+	assert.Equal(t, models.SLOStatusViolated, changes[1].Status)
+	assert.Len(t, alerts, 2)
+
+	assert.Empty(t, tracker.Evaluate(time.Now()), "ongoing violations are alerted once")
+
+	// Once the slow requests leave the window, the endpoint recovers
+	latest := tracker.endpoints["/api/v1/rates/latest"]
+	for i := range latest.samples {
+		latest.samples[i].at = latest.samples[i].at.Add(-cfg.Window)
+	}
+	assert.Empty(t, tracker.Evaluate(time.Now()), "an endpoint with too few requests keeps its state")
+	report := tracker.Report(time.Now())
+	require.Len(t, report.Endpoints, 3)
+	assert.Equal(t, "/api/v1/rates/latest", report.Endpoints[2].Endpoint)
+	assert.Len(t, report.Endpoints[2].Violations, 2)
+	assert.Equal(t, int64(1), report.Endpoints[2].ViolationsTotal[models.SLOObjectiveP95])
+
+	for i := 0; i < 20; i++ {
+		tracker.Observe("/api/v1/rates/latest", 10*time.Millisecond, http.StatusOK)
+	}
+	changes = tracker.Evaluate(time.Now())
+	require.Len(t, changes, 2)
+	for _, change := range changes {
+		assert.Equal(t, models.SLOStatusResolved, change.Status)
+	}
+	assert.Len(t, alerts, 4)
+
+	report = tracker.Report(time.Now())
+	assert.Empty(t, report.Endpoints[2].Violations)
+	assert.Equal(t, map[string]float64{models.SLOObjectiveP99: 1}, report.Endpoints[1].Targets, "the catch-all target applies")
+	assert.Equal(t, int64(4), report.Evaluations)
+	assert.Equal(t, int64(4), report.Alerts)
+}
+
+func TestSLOTracker_Enabled(t *testing.T) {
+	cfg := DefaultSLOConfig()
+	assert.False(t, NewSLOTracker(cfg).Enabled(), "no targets configured")
+
+	cfg.Targets = map[string]SLOTarget{SLOAnyEndpoint: {P99: time.Second}}
+	assert.True(t, NewSLOTracker(cfg).Enabled())
+
+	var tracker *SLOTracker
+	tracker.Observe("/", time.Millisecond, http.StatusOK) // A nil tracker ignores requests
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/store"
+	"exchange-rate-service/internal/utils"
+)
+
+// SnapshotScheduler captures the latest rate of every supported pair once a
+// day and archives it as that day's historical rate, so historical queries
+// can be answered without a provider history endpoint
+type SnapshotScheduler struct {
+	fetcher *RateFetcher
+	archive *store.Archive
+	hour    int
+	minute  int
+
+	mu        sync.Mutex
+	isRunning bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// NewSnapshotScheduler creates a scheduler that runs daily at hour:minute in
+// the reference time zone
+func NewSnapshotScheduler(fetcher *RateFetcher, archive *store.Archive, hour, minute int) *SnapshotScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &SnapshotScheduler{
+		fetcher: fetcher,
+		archive: archive,
+		hour:    hour,
+		minute:  minute,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func (s *SnapshotScheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isRunning {
+		return
+	}
+	s.isRunning = true
+
+	log.Printf("Scheduling daily rate snapshots at %02d:%02d %s", s.hour, s.minute, utils.ReferenceLocation())
+	go s.run()
+}
+
+func (s *SnapshotScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.isRunning {
+		return
+	}
+	s.cancel()
+	s.isRunning = false
+}
+
+func (s *SnapshotScheduler) run() {
+	for {
+		next := s.nextRun(time.Now())
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			log.Println("Snapshot scheduler stopped")
+			return
+		case <-timer.C:
+			s.fire(next)
+		}
+	}
+}
+
+// fire refreshes the rates and archives them as the snapshot of the day of
+// scheduled, the time the run was due. A slow refresh at 23:59 must not
+// file the snapshot under the next day.
+func (s *SnapshotScheduler) fire(scheduled time.Time) {
+	// Refresh first so the snapshot reflects the end of the day
+	s.fetcher.FetchNow(s.ctx)
+	if _, err := s.Capture(scheduled); err != nil {
+		log.Printf("Failed to capture rate snapshot: %v", err)
+	}
+}
+
+// nextRun returns the first scheduled time strictly after now
+func (s *SnapshotScheduler) nextRun(now time.Time) time.Time {
+	local := now.In(utils.ReferenceLocation())
+	next := time.Date(local.Year(), local.Month(), local.Day(), s.hour, s.minute, 0, 0, local.Location())
+	if !next.After(local) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, s.hour, s.minute, 0, 0, local.Location())
+	}
+	return next
+}
+
+// Capture archives the currently cached latest rates as the snapshot of the
+// day at falls on in the reference time zone
+func (s *SnapshotScheduler) Capture(at time.Time) (*store.Snapshot, error) {
+	snapshot := &store.Snapshot{
+		Date:       at.In(utils.ReferenceLocation()).Format(utils.DateFormat),
+		CapturedAt: at,
+		Rates:      make(map[string]map[string]float64),
+	}
+
+	captured := 0
+	currencies := models.SupportedCurrencyCodes()
+	for _, from := range currencies {
+		for _, to := range currencies {
+			if from == to {
+				continue
+			}
+
+			item, found := s.fetcher.cache.GetItem(s.fetcher.cacheProvider(from, to), from, to, "")
+			if !found {
+				continue
+			}
+			if snapshot.Rates[from] == nil {
+				snapshot.Rates[from] = make(map[string]float64)
+			}
+			snapshot.Rates[from][to] = item.Rate
+			if item.Provider != "" {
+				snapshot.Provider = item.Provider
+			}
+			captured++
+		}
+	}
+
+	if captured == 0 {
+		return nil, fmt.Errorf("no cached rates to snapshot for %s", snapshot.Date)
+	}
+	if err := s.archive.Save(snapshot); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Archived %d rates for %s", captured, snapshot.Date)
+	return snapshot, nil
+}
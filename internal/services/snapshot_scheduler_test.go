@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/store"
+)
+
+func TestSnapshotScheduler_NextRun(t *testing.T) {
+	scheduler := NewSnapshotScheduler(nil, nil, 23, 59)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"Earlier the same day", time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC), time.Date(2025, 1, 2, 23, 59, 0, 0, time.UTC)},
+		{"Exactly at the run time", time.Date(2025, 1, 2, 23, 59, 0, 0, time.UTC), time.Date(2025, 1, 3, 23, 59, 0, 0, time.UTC)},
+		{"After the run time", time.Date(2025, 12, 31, 23, 59, 30, 0, time.UTC), time.Date(2026, 1, 1, 23, 59, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, tt.want.Equal(scheduler.nextRun(tt.now)), "got %v", scheduler.nextRun(tt.now))
+		})
+	}
+}
+
+func TestSnapshotScheduler_CaptureServesHistoricalRates(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 85.0)
+	archive, err := store.NewArchive("")
+	require.NoError(t, err)
+
+	scheduler := NewSnapshotScheduler(NewRateFetcher(nil, memoryCache), archive, 23, 59)
+	snapshot, err := scheduler.Capture(time.Date(2025, 1, 2, 23, 59, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "2025-01-02", snapshot.Date)
+
+	service := NewExchangeService(cache.NewMemoryCache(1*time.Hour), nil, nil)
+	service.SetArchive(archive)
+
+	quote, err := service.getHistoricalRate(context.Background(), "USD", "INR", "2025-01-02", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 85.0, quote.rate)
+
+	quote, err = service.getHistoricalRate(context.Background(), "INR", "USD", "2025-01-02", "")
+	assert.NoError(t, err)
+	assert.InDelta(t, 1/85.0, quote.rate, 1e-12)
+	assert.Equal(t, models.DerivedInverse, quote.derived)
+}
+
+func TestSnapshotScheduler_CaptureWithEmptyCache(t *testing.T) {
+	archive, err := store.NewArchive("")
+	require.NoError(t, err)
+
+	scheduler := NewSnapshotScheduler(NewRateFetcher(nil, cache.NewMemoryCache(1*time.Hour)), archive, 23, 59)
+	_, err = scheduler.Capture(time.Now())
+	assert.Error(t, err)
+	assert.Equal(t, 0, archive.Len())
+}
+
+func TestSnapshotScheduler_FireFilesUnderScheduledDay(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer provider.Close()
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = provider.URL
+	cfg.Retry.MaxAttempts = 1
+	cfg.Throttle = external.ThrottleConfig{}
+	client := external.NewExchangeRateClientWithConfig(cfg)
+
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 85.0)
+	archive, err := store.NewArchive("")
+	require.NoError(t, err)
+
+	// The refresh finishes long after 23:59 on the 2nd
+	scheduler := NewSnapshotScheduler(NewRateFetcher(client, memoryCache), archive, 23, 59)
+	scheduler.fire(time.Date(2025, 1, 2, 23, 59, 0, 0, time.UTC))
+
+	_, ok := archive.Get("2025-01-02")
+	assert.True(t, ok, "the snapshot is filed under the day it was scheduled for")
+	assert.Equal(t, 1, archive.Len())
+}
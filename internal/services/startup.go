@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// What to do when a dependency is unhealthy at startup
+const (
+	StartupFailFast = "fail-fast" // Exit before binding the port
+	StartupDegrade  = "degrade"   // Start anyway and retry the check in the background
+)
+
+// StartupConfig sets how dependencies are checked before the server starts
+type StartupConfig struct {
+	Policy           string        // StartupFailFast or StartupDegrade
+	Timeout          time.Duration // Longest a single check may take
+	RetryInterval    time.Duration // First wait before re-checking a failed dependency in the background
+	MaxRetryInterval time.Duration // The wait doubles after each failure up to this
+}
+
+// DefaultStartupConfig starts degraded, re-checking failed dependencies
+// every 5 seconds at first and every minute at most
+func DefaultStartupConfig() StartupConfig {
+	return StartupConfig{
+		Policy:           StartupDegrade,
+		Timeout:          5 * time.Second,
+		RetryInterval:    5 * time.Second,
+		MaxRetryInterval: time.Minute,
+	}
+}
+
+// StartupCheck verifies that one dependency, such as a provider, is usable
+type StartupCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// StartupResult is the outcome of one check
+type StartupResult struct {
+	Name    string
+	Healthy bool
+	Error   string
+	Latency time.Duration
+}
+
+// StartupReport holds the outcome of every check, in the order they were
+// given
+type StartupReport struct {
+	Results []StartupResult
+}
+
+// Healthy reports whether every check passed
+func (r StartupReport) Healthy() bool {
+	for _, result := range r.Results {
+		if !result.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns the names of the checks that did not pass
+func (r StartupReport) Failed() []string {
+	var failed []string
+	for _, result := range r.Results {
+		if !result.Healthy {
+			failed = append(failed, result.Name)
+		}
+	}
+	return failed
+}
+
+// StartupChecker runs the dependency checks at startup and, under the
+// degrade policy, keeps re-checking the failed ones until they pass or it is
+// stopped
+type StartupChecker struct {
+	cfg    StartupConfig
+	checks []StartupCheck
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewStartupChecker(cfg StartupConfig, checks ...StartupCheck) *StartupChecker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &StartupChecker{cfg: cfg, checks: checks, ctx: ctx, cancel: cancel}
+}
+
+// Run checks every dependency concurrently and logs the report. Under the
+// fail-fast policy it returns an error naming the failed checks; under the
+// degrade policy it returns nil and re-checks them in the background.
+func (s *StartupChecker) Run() (StartupReport, error) {
+	report := StartupReport{Results: make([]StartupResult, len(s.checks))}
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+		go func(i int, check StartupCheck) {
+			defer wg.Done()
+			report.Results[i] = s.run(check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Results {
+		if result.Healthy {
+			log.Printf("Startup check %s: healthy (%v)", result.Name, result.Latency.Round(time.Millisecond))
+		} else {
+			log.Printf("Startup check %s: unhealthy: %s", result.Name, result.Error)
+		}
+	}
+	if report.Healthy() {
+		log.Printf("Startup checks passed: all %d dependencies healthy", len(report.Results))
+		return report, nil
+	}
+
+	failed := report.Failed()
+	if s.cfg.Policy == StartupFailFast {
+		return report, fmt.Errorf("unhealthy dependencies: %s", strings.Join(failed, ", "))
+	}
+	log.Printf("Starting degraded: %s unhealthy, re-checking in the background", strings.Join(failed, ", "))
+	for i, result := range report.Results {
+		if !result.Healthy {
+			s.wg.Add(1)
+			go s.retry(s.checks[i])
+		}
+	}
+	return report, nil
+}
+
+// Stop ends the background re-checks
+func (s *StartupChecker) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *StartupChecker) run(check StartupCheck) StartupResult {
+	ctx, cancel := context.WithTimeout(s.ctx, s.cfg.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	result := StartupResult{Name: check.Name, Healthy: err == nil, Latency: time.Since(start)}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// retry re-runs check with exponential backoff until it passes
+func (s *StartupChecker) retry(check StartupCheck) {
+	defer s.wg.Done()
+
+	wait := s.cfg.RetryInterval
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		result := s.run(check)
+		if result.Healthy {
+			log.Printf("Startup check %s: recovered after %d retries", check.Name, attempt)
+			return
+		}
+		if s.ctx.Err() != nil {
+			return
+		}
+		log.Printf("Startup check %s: still unhealthy: %s", check.Name, result.Error)
+		if wait *= 2; wait > s.cfg.MaxRetryInterval {
+			wait = s.cfg.MaxRetryInterval
+		}
+	}
+}
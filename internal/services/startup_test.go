@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartupChecker(t *testing.T) {
+	cfg := StartupConfig{Policy: StartupFailFast, Timeout: 50 * time.Millisecond, RetryInterval: time.Millisecond, MaxRetryInterval: 4 * time.Millisecond}
+	healthy := StartupCheck{Name: "cache", Check: func(ctx context.Context) error { return nil }}
+	slow := StartupCheck{Name: "jwks", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	var calls atomic.Int32
+	flaky := StartupCheck{Name: "provider", Check: func(ctx context.Context) error {
+		if calls.Add(1) < 3 {
+			return errors.New("provider unreachable")
+		}
+		return nil
+	}}
+
+	report, err := NewStartupChecker(cfg, healthy).Run()
+	require.NoError(t, err)
+	assert.True(t, report.Healthy())
+
+	report, err = NewStartupChecker(cfg, healthy, flaky, slow).Run()
+	assert.ErrorContains(t, err, "unhealthy dependencies: provider, jwks")
+	require.Len(t, report.Results, 3)
+	assert.True(t, report.Results[0].Healthy)
+	assert.Equal(t, "provider unreachable", report.Results[1].Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Results[2].Error, "checks are bounded by the timeout")
+	assert.Equal(t, []string{"provider", "jwks"}, report.Failed())
+
+	cfg.Policy = StartupDegrade
+	checker := NewStartupChecker(cfg, healthy, flaky)
+	report, err = checker.Run()
+	require.NoError(t, err, "a degraded start is not an error")
+	assert.False(t, report.Healthy())
+	assert.Eventually(t, func() bool { return calls.Load() >= 3 }, time.Second, time.Millisecond, "failed checks are retried in the background")
+	checker.Stop()
+
+	checker = NewStartupChecker(cfg, slow)
+	_, err = checker.Run()
+	require.NoError(t, err)
+	done := make(chan struct{})
+	go func() {
+		checker.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not end the background retries")
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+// Tenant scopes the settings of one customer: the currencies its callers may
+// use and the markup of their conversions. Its callers' negative cache
+// entries are kept apart from other tenants', so a pair one tenant found
+// missing is still looked up for the others.
+type Tenant struct {
+	ID         string
+	Currencies []string // Supported currencies the tenant may use, all of them when empty
+	Markup     *Markup  // Replaces the service's markup when set
+}
+
+// SupportsCurrency reports whether the tenant may use code. A nil tenant
+// may use every currency.
+func (t *Tenant) SupportsCurrency(code string) bool {
+	if t == nil || len(t.Currencies) == 0 {
+		return true
+	}
+	for _, currency := range t.Currencies {
+		if currency == code {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterCurrencies returns the codes the tenant may use
+func (t *Tenant) FilterCurrencies(codes []string) []string {
+	filtered := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if t.SupportsCurrency(code) {
+			filtered = append(filtered, code)
+		}
+	}
+	return filtered
+}
+
+// checkPair rejects a pair using a currency outside the tenant's
+func (t *Tenant) checkPair(from, to string) error {
+	if err := t.checkCurrency(from); err != nil {
+		return models.ForField(err, "from", "invalid 'from' currency: ")
+	}
+	if err := t.checkCurrency(to); err != nil {
+		return models.ForField(err, "to", "invalid 'to' currency: ")
+	}
+	return nil
+}
+
+func (t *Tenant) checkCurrency(code string) error {
+	if t.SupportsCurrency(code) {
+		return nil
+	}
+	return models.NewError(models.ErrCodeCurrencyUnsupported, "currency %s is not enabled for tenant %s", code, t.ID)
+}
+
+// TenantRegistry holds the configured tenants by ID
+type TenantRegistry struct {
+	mu      sync.RWMutex
+	tenants map[string]*Tenant
+}
+
+func NewTenantRegistry(tenants []Tenant) *TenantRegistry {
+	registry := &TenantRegistry{}
+	registry.Replace(tenants)
+	return registry
+}
+
+// Replace swaps the set of tenants
+func (r *TenantRegistry) Replace(tenants []Tenant) {
+	byID := make(map[string]*Tenant, len(tenants))
+	for i := range tenants {
+		tenant := tenants[i]
+		byID[tenant.ID] = &tenant
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tenants = byID
+}
+
+// Lookup returns the tenant with id
+func (r *TenantRegistry) Lookup(id string) (*Tenant, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tenant, ok := r.tenants[id]
+	return tenant, ok
+}
+
+// IDs returns the IDs of the configured tenants, sorted
+func (r *TenantRegistry) IDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]string, 0, len(r.tenants))
+	for id := range r.tenants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Len returns the number of configured tenants
+func (r *TenantRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.tenants)
+}
+
+type tenantContextKey struct{}
+
+// WithTenant returns a copy of ctx scoped to tenant
+func WithTenant(ctx context.Context, tenant *Tenant) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant ctx is scoped to, nil when none
+func TenantFromContext(ctx context.Context) *Tenant {
+	tenant, _ := ctx.Value(tenantContextKey{}).(*Tenant)
+	return tenant
+}
+
+// validatePair replaces currency aliases in a pair with their codes and
+// checks that both currencies are supported and enabled for the tenant of ctx
+func validatePair(ctx context.Context, from, to *string) error {
+	*from, *to = models.CanonicalCurrency(*from), models.CanonicalCurrency(*to)
+	if err := utils.ValidateCurrencyPair(*from, *to); err != nil {
+		return err
+	}
+	return TenantFromContext(ctx).checkPair(*from, *to)
+}
+
+// currencyPair is a pair named in a request as FROM_TO
+type currencyPair struct{ from, to string }
+
+func (p currencyPair) String() string {
+	return p.from + "_" + p.to
+}
+
+// parsePairs parses and validates the FROM_TO pairs of the pairs field of a
+// request, rejecting pairs listed twice
+func parsePairs(ctx context.Context, names []string) ([]currencyPair, error) {
+	pairs := make([]currencyPair, len(names))
+	seen := make(map[currencyPair]bool, len(names))
+	for i, name := range names {
+		from, to, found := strings.Cut(strings.ToUpper(strings.TrimSpace(name)), "_")
+		if !found {
+			return nil, models.NewFieldError(models.ErrCodeValueInvalid, "pairs", "pairs must be written as FROM_TO, got %q", name)
+		}
+		if err := validatePair(ctx, &from, &to); err != nil {
+			return nil, models.ForField(err, "pairs", "invalid pair "+name+": ")
+		}
+		pair := currencyPair{from, to}
+		if seen[pair] {
+			return nil, models.NewFieldError(models.ErrCodeValueInvalid, "pairs", "pair %s is listed twice", pair)
+		}
+		seen[pair] = true
+		pairs[i] = pair
+	}
+	return pairs, nil
+}
+
+// negativeScope returns the currency negative cache entries of a pair are
+// stored under: from itself, or from namespaced by the tenant of ctx
+func negativeScope(ctx context.Context, from string) string {
+	if tenant := TenantFromContext(ctx); tenant != nil {
+		return cache.Namespace(tenant.ID, from)
+	}
+	return from
+}
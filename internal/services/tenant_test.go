@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+)
+
+func TestExchangeService_TenantScopes(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 80.0)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "EUR", "", 0.9)
+	service := NewExchangeService(memoryCache, nil, nil)
+	service.SetMarkup(NewMarkup(1, nil))
+
+	acme := &Tenant{ID: "acme", Currencies: []string{"USD", "INR"}, Markup: NewMarkup(0, map[string]float64{"USD_INR": 2})}
+	globex := &Tenant{ID: "globex"}
+	ctx := WithTenant(context.Background(), acme)
+
+	resp, err := service.ConvertCurrency(ctx, &models.ConversionRequest{From: "USD", To: "INR", Amount: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, resp.MarkupPercent, "the tenant's markup replaces the service's")
+
+	resp, err = service.ConvertCurrency(WithTenant(context.Background(), globex), &models.ConversionRequest{From: "USD", To: "INR", Amount: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, resp.MarkupPercent, "a tenant without a markup uses the service's")
+
+	_, err = service.ConvertCurrency(ctx, &models.ConversionRequest{From: "USD", To: "EUR", Amount: 10})
+	code, field := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeCurrencyUnsupported, code)
+	assert.Equal(t, "to", field)
+
+	_, err = service.GetLatestRate(ctx, "EUR", "USD", "")
+	code, _ = models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeCurrencyUnsupported, code)
+
+	_, err = service.GetLatestRate(context.Background(), "USD", "EUR", "")
+	assert.NoError(t, err, "other callers still use every supported currency")
+
+	table, err := service.GetRateTable(ctx, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"USD": 1, "INR": 80}, table.Rates)
+}
+
+func TestRateFetcher_NegativeEntriesPerTenant(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"base":"USD","rates":{"INR":83.5}}`))
+	}))
+	defer server.Close()
+
+	cfg := external.DefaultConfig()
+	cfg.BaseURL = server.URL
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	fetcher := NewRateFetcher(external.NewExchangeRateClientWithConfig(cfg), memoryCache)
+	fetcher.SetNegativeTTL(time.Minute)
+
+	acme := WithTenant(context.Background(), &Tenant{ID: "acme"})
+	globex := WithTenant(context.Background(), &Tenant{ID: "globex"})
+
+	for i := 0; i < 2; i++ {
+		_, err := fetcher.FetchRateOnDemand(acme, "", "USD", "XYZ")
+		assert.ErrorIs(t, err, external.ErrRateNotFound)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	_, err := fetcher.FetchRateOnDemand(globex, "", "USD", "XYZ")
+	assert.ErrorIs(t, err, external.ErrRateNotFound)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls), "another tenant's negative entry doesn't apply")
+
+	assert.Equal(t, 2, memoryCache.DeletePair("USD", "XYZ"), "invalidating a pair clears it for every tenant")
+}
+
+func TestTenant_FilterCurrencies(t *testing.T) {
+	var none *Tenant
+	assert.Equal(t, []string{"EUR", "USD"}, none.FilterCurrencies([]string{"EUR", "USD"}))
+
+	tenant := &Tenant{ID: "acme", Currencies: []string{"USD"}}
+	assert.Equal(t, []string{"USD"}, tenant.FilterCurrencies([]string{"EUR", "USD"}))
+	assert.False(t, tenant.SupportsCurrency("EUR"))
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+const (
+	DefaultTrendWindow = 7
+	DefaultTrendDays   = 30 // Span of a trend request without a start date
+)
+
+// GetRateTrend returns a pair's daily rates over the requested range with
+// simple and exponential moving averages and day-over-day changes. Rates are
+// looked up like historical rates; days without one are reported as missing
+// and the averages span the last Window available rates.
+func (s *ExchangeService) GetRateTrend(ctx context.Context, req *models.TrendRequest) (*models.TrendResponse, error) {
+	lookback, maxRange := utils.DateLimits()
+	if req.Window < 1 || req.Window > maxRange {
+		return nil, models.NewFieldError(models.ErrCodeValueInvalid, "window", "window must be between 1 and %d", maxRange)
+	}
+
+	endDate := req.EndDate
+	if endDate == "" {
+		endDate = utils.Today().Format(utils.DateFormat)
+	}
+	startDate := req.StartDate
+	if startDate == "" {
+		end, err := utils.ValidateDate(endDate)
+		if err != nil {
+			return nil, err
+		}
+		start := end.AddDate(0, 0, -(DefaultTrendDays - 1))
+		if earliest := utils.Today().AddDate(0, 0, -lookback); lookback > 0 && start.Before(earliest) {
+			start = earliest
+		}
+		startDate = start.Format(utils.DateFormat)
+	}
+
+	historical, err := s.GetHistoricalRates(ctx, &models.HistoricalRateRequest{
+		From:      req.From,
+		To:        req.To,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Provider:  req.Provider,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := utils.ValidateDateRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	points, missing := computeTrend(utils.GetDateRangeList(start, end), historical.Rates, req.Window)
+
+	return &models.TrendResponse{
+		From:      req.From,
+		To:        req.To,
+		Window:    req.Window,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Points:    points,
+		Missing:   missing,
+		Freshness: historical.Freshness,
+		Source:    historical.Source,
+	}, nil
+}
+
+// computeTrend builds the trend points of the trading days that have a rate,
+// in order, and returns the dates that don't
+func computeTrend(dates []string, rates map[string]models.HistoricalRate, window int) ([]models.TrendPoint, []string) {
+	points := make([]models.TrendPoint, 0, len(dates))
+	var missing []string
+
+	alpha := 2 / float64(window+1)
+	var sum, ema float64
+	for _, date := range dates {
+		rate, found := rates[date]
+		if !found {
+			missing = append(missing, date)
+			continue
+		}
+		if rate.ObservedDate != "" {
+			// A weekend or holiday repeats the previous trading day's rate
+			continue
+		}
+
+		point := models.TrendPoint{Date: date, Rate: rate.Rate}
+		n := len(points)
+
+		if n > 0 {
+			previous := points[n-1].Rate
+			change := rate.Rate - previous
+			point.Change = &change
+			if previous != 0 {
+				changePercent := change / previous * 100
+				point.ChangePercent = &changePercent
+			}
+		}
+
+		sum += rate.Rate
+		if n >= window {
+			sum -= points[n-window].Rate
+		}
+		if n+1 >= window {
+			sma := sum / float64(window)
+			point.SMA = &sma
+
+			if n+1 == window {
+				ema = sma
+			} else {
+				ema = alpha*rate.Rate + (1-alpha)*ema
+			}
+			emaValue := ema
+			point.EMA = &emaValue
+		}
+
+		points = append(points, point)
+	}
+	return points, missing
+}
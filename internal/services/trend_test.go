@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+func TestComputeTrend(t *testing.T) {
+	dates := []string{"2025-01-01", "2025-01-02", "2025-01-03", "2025-01-04", "2025-01-05"}
+	rates := map[string]models.HistoricalRate{
+		"2025-01-01": {Rate: 10},
+		"2025-01-02": {Rate: 12},
+		"2025-01-04": {Rate: 11},
+		"2025-01-05": {Rate: 14},
+	}
+
+	points, missing := computeTrend(dates, rates, 2)
+	assert.Equal(t, []string{"2025-01-03"}, missing)
+	require.Len(t, points, 4)
+
+	assert.Nil(t, points[0].Change)
+	assert.Nil(t, points[0].SMA, "not enough history yet")
+	assert.Nil(t, points[0].EMA)
+
+	assert.Equal(t, 2.0, *points[1].Change)
+	assert.InDelta(t, 20.0, *points[1].ChangePercent, 1e-9)
+	assert.Equal(t, 11.0, *points[1].SMA)
+	assert.Equal(t, 11.0, *points[1].EMA, "EMA is seeded with the first SMA")
+
+	// The missing day is skipped, so the 4th is compared with the 2nd
+	assert.Equal(t, "2025-01-04", points[2].Date)
+	assert.Equal(t, -1.0, *points[2].Change)
+	assert.Equal(t, 11.5, *points[2].SMA)
+	assert.InDelta(t, 11.0, *points[2].EMA, 1e-9) // 2/3*11 + 1/3*11
+
+	assert.Equal(t, 12.5, *points[3].SMA)
+	assert.InDelta(t, 13.0, *points[3].EMA, 1e-9) // 2/3*14 + 1/3*11
+}
+
+func TestExchangeService_GetRateTrend(t *testing.T) {
+	end := utils.LastTradingDay(utils.Today().AddDate(0, 0, -1))
+	memoryCache := cache.NewMemoryCache(1 * time.Hour)
+	start := end
+	for i := 0; i < 5; i++ {
+		start = utils.LastTradingDay(start)
+		memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", start.Format(utils.DateFormat), 80+float64(i))
+		if i < 4 {
+			start = start.AddDate(0, 0, -1)
+		}
+	}
+	service := NewExchangeService(memoryCache, nil, nil)
+
+	trend, err := service.GetRateTrend(context.Background(), &models.TrendRequest{
+		From:      "USD",
+		To:        "INR",
+		Window:    3,
+		StartDate: start.Format(utils.DateFormat),
+		EndDate:   end.Format(utils.DateFormat),
+	})
+	require.NoError(t, err)
+	require.Len(t, trend.Points, 5)
+	assert.Equal(t, 84.0, trend.Points[0].Rate, "oldest first")
+	assert.Equal(t, 81.0, *trend.Points[4].SMA)
+	assert.Empty(t, trend.Missing, "weekends repeat the previous trading day")
+
+	_, err = service.GetRateTrend(context.Background(), &models.TrendRequest{From: "USD", To: "INR", Window: 0})
+	assert.Error(t, err)
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+// usageRetentionDays bounds the daily counts kept per key
+const usageRetentionDays = 31
+
+// UsageRequest is one authenticated request, as counted by a UsageTracker
+type UsageRequest struct {
+	KeyID    string
+	Endpoint string // Route pattern, empty for requests matching none
+	From, To string // Currency pair the request names, if any
+	Status   int
+	BytesIn  int64
+	BytesOut int64
+	At       time.Time
+}
+
+// keyUsage holds the counters of one key
+type keyUsage struct {
+	usage     models.KeyUsage
+	endpoints map[string]*models.EndpointUsage
+	pairs     map[string]*models.PairUsage // "FROM_TO" -> requests
+	days      map[string]*models.DailyUsage
+}
+
+// UsageTracker counts the requests, errors, pairs and bytes of every API key
+// in memory, so partners can be billed and their integrations debugged.
+// Counts start over when the service restarts.
+type UsageTracker struct {
+	since time.Time
+
+	mu   sync.Mutex
+	keys map[string]*keyUsage
+}
+
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{
+		since: time.Now(),
+		keys:  make(map[string]*keyUsage),
+	}
+}
+
+// Since returns when counting started
+func (t *UsageTracker) Since() time.Time {
+	return t.since
+}
+
+// Record counts a request. Only pairs of supported currencies are counted,
+// so arbitrary codes in rejected requests cannot grow the tracker.
+func (t *UsageTracker) Record(req UsageRequest) {
+	if t == nil || req.KeyID == "" {
+		return
+	}
+	failed := req.Status >= http.StatusBadRequest
+	date := req.At.In(utils.ReferenceLocation()).Format(utils.DateFormat)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k, ok := t.keys[req.KeyID]
+	if !ok {
+		k = &keyUsage{
+			usage:     models.KeyUsage{KeyID: req.KeyID, FirstRequestAt: req.At},
+			endpoints: make(map[string]*models.EndpointUsage),
+			pairs:     make(map[string]*models.PairUsage),
+			days:      make(map[string]*models.DailyUsage),
+		}
+		t.keys[req.KeyID] = k
+	}
+
+	k.usage.Requests++
+	switch {
+	case req.Status >= http.StatusInternalServerError:
+		k.usage.ServerErrors++
+	case failed:
+		k.usage.ClientErrors++
+	}
+	k.usage.BytesIn += req.BytesIn
+	k.usage.BytesOut += req.BytesOut
+	if req.At.After(k.usage.LastRequestAt) {
+		k.usage.LastRequestAt = req.At
+	}
+
+	if req.Endpoint != "" {
+		endpoint, ok := k.endpoints[req.Endpoint]
+		if !ok {
+			endpoint = &models.EndpointUsage{Endpoint: req.Endpoint}
+			k.endpoints[req.Endpoint] = endpoint
+		}
+		endpoint.Requests++
+		if failed {
+			endpoint.Errors++
+		}
+	}
+
+	if req.From != req.To && models.IsSupportedCurrency(req.From) && models.IsSupportedCurrency(req.To) {
+		pair, ok := k.pairs[req.From+"_"+req.To]
+		if !ok {
+			pair = &models.PairUsage{From: req.From, To: req.To}
+			k.pairs[req.From+"_"+req.To] = pair
+		}
+		pair.Requests++
+	}
+
+	day, ok := k.days[date]
+	if !ok {
+		day = &models.DailyUsage{Date: date}
+		k.days[date] = day
+		k.prune(req.At)
+	}
+	day.Requests++
+	if failed {
+		day.Errors++
+	}
+	day.BytesOut += req.BytesOut
+}
+
+// prune drops the daily counts older than the retention
+func (k *keyUsage) prune(now time.Time) {
+	oldest := now.In(utils.ReferenceLocation()).AddDate(0, 0, -usageRetentionDays+1).Format(utils.DateFormat)
+	for date := range k.days {
+		if date < oldest {
+			delete(k.days, date)
+		}
+	}
+}
+
+// Usage returns the usage of a key, false when it made no request
+func (t *UsageTracker) Usage(keyID string) (models.KeyUsage, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k, ok := t.keys[keyID]
+	if !ok {
+		return models.KeyUsage{}, false
+	}
+	return k.snapshot(), true
+}
+
+// Report returns the usage of every key that made a request, most requests
+// first
+func (t *UsageTracker) Report() models.UsageReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := models.UsageReport{Since: t.since, Keys: make([]models.KeyUsage, 0, len(t.keys))}
+	for _, k := range t.keys {
+		report.Keys = append(report.Keys, k.snapshot())
+	}
+	sort.Slice(report.Keys, func(i, j int) bool {
+		if report.Keys[i].Requests != report.Keys[j].Requests {
+			return report.Keys[i].Requests > report.Keys[j].Requests
+		}
+		return report.Keys[i].KeyID < report.Keys[j].KeyID
+	})
+	return report
+}
+
+// snapshot copies the counters of a key, sorted for the response
+func (k *keyUsage) snapshot() models.KeyUsage {
+	usage := k.usage
+	if usage.Requests > 0 {
+		usage.ErrorRatePercent = float64(usage.ClientErrors+usage.ServerErrors) / float64(usage.Requests) * 100
+	}
+
+	usage.Endpoints = make([]models.EndpointUsage, 0, len(k.endpoints))
+	for _, endpoint := range k.endpoints {
+		usage.Endpoints = append(usage.Endpoints, *endpoint)
+	}
+	sort.Slice(usage.Endpoints, func(i, j int) bool {
+		if usage.Endpoints[i].Requests != usage.Endpoints[j].Requests {
+			return usage.Endpoints[i].Requests > usage.Endpoints[j].Requests
+		}
+		return usage.Endpoints[i].Endpoint < usage.Endpoints[j].Endpoint
+	})
+
+	usage.Pairs = make([]models.PairUsage, 0, len(k.pairs))
+	for _, pair := range k.pairs {
+		usage.Pairs = append(usage.Pairs, *pair)
+	}
+	sort.Slice(usage.Pairs, func(i, j int) bool {
+		if usage.Pairs[i].Requests != usage.Pairs[j].Requests {
+			return usage.Pairs[i].Requests > usage.Pairs[j].Requests
+		}
+		return usage.Pairs[i].From+usage.Pairs[i].To < usage.Pairs[j].From+usage.Pairs[j].To
+	})
+
+	usage.Days = make([]models.DailyUsage, 0, len(k.days))
+	for _, day := range k.days {
+		usage.Days = append(usage.Days, *day)
+	}
+	sort.Slice(usage.Days, func(i, j int) bool {
+		return usage.Days[i].Date < usage.Days[j].Date
+	})
+	return usage
+}
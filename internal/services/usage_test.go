@@ -0,0 +1,62 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/utils"
+)
+
+func TestUsageTracker(t *testing.T) {
+	tracker := NewUsageTracker()
+	now := time.Now()
+	day := func(offset int) time.Time {
+		return now.AddDate(0, 0, offset)
+	}
+
+	tracker.Record(UsageRequest{KeyID: "partner", Endpoint: "/api/v1/convert", From: "USD", To: "INR", Status: http.StatusOK, BytesIn: 40, BytesOut: 200, At: day(-40)})
+	tracker.Record(UsageRequest{KeyID: "partner", Endpoint: "/api/v1/convert", From: "USD", To: "INR", Status: http.StatusOK, BytesIn: 40, BytesOut: 200, At: day(-1)})
+	tracker.Record(UsageRequest{KeyID: "partner", Endpoint: "/api/v1/convert", From: "USD", To: "XYZ", Status: http.StatusUnprocessableEntity, BytesOut: 100, At: day(0)})
+	tracker.Record(UsageRequest{KeyID: "partner", Endpoint: "/api/v1/rates/latest", From: "EUR", To: "USD", Status: http.StatusBadGateway, At: day(0)})
+	tracker.Record(UsageRequest{KeyID: "dashboard", Endpoint: "/api/v1/rates/table", Status: http.StatusOK, At: day(0)})
+	tracker.Record(UsageRequest{Endpoint: "/api/v1/rates/table", Status: http.StatusOK, At: day(0)})
+
+	usage, ok := tracker.Usage("partner")
+	require.True(t, ok)
+	assert.Equal(t, int64(4), usage.Requests)
+	assert.Equal(t, int64(1), usage.ClientErrors)
+	assert.Equal(t, int64(1), usage.ServerErrors)
+	assert.Equal(t, 50.0, usage.ErrorRatePercent)
+	assert.Equal(t, int64(80), usage.BytesIn)
+	assert.Equal(t, int64(500), usage.BytesOut)
+	assert.Equal(t, day(-40), usage.FirstRequestAt)
+	assert.Equal(t, day(0), usage.LastRequestAt)
+
+	require.Len(t, usage.Endpoints, 2)
+	assert.Equal(t, "/api/v1/convert", usage.Endpoints[0].Endpoint)
+	assert.Equal(t, int64(3), usage.Endpoints[0].Requests)
+	assert.Equal(t, int64(1), usage.Endpoints[0].Errors)
+
+	// The unsupported currency is not counted as a pair
+	require.Len(t, usage.Pairs, 2)
+	assert.Equal(t, "INR", usage.Pairs[0].To)
+	assert.Equal(t, int64(2), usage.Pairs[0].Requests)
+
+	// Days past the retention are dropped
+	require.Len(t, usage.Days, 2)
+	assert.Equal(t, day(-1).In(utils.ReferenceLocation()).Format(utils.DateFormat), usage.Days[0].Date)
+	assert.Equal(t, int64(2), usage.Days[1].Requests)
+	assert.Equal(t, int64(2), usage.Days[1].Errors)
+
+	_, ok = tracker.Usage("nobody")
+	assert.False(t, ok)
+
+	report := tracker.Report()
+	require.Len(t, report.Keys, 2, "requests without a key are not counted")
+	assert.Equal(t, "partner", report.Keys[0].KeyID)
+	assert.Equal(t, "dashboard", report.Keys[1].KeyID)
+}
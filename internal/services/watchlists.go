@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"exchange-rate-service/internal/models"
+)
+
+// maxWatchlistName is the longest name a watchlist may have, in characters
+const maxWatchlistName = 100
+
+// WatchlistConfig bounds the watchlists callers keep
+type WatchlistConfig struct {
+	MaxPairs     int // Pairs of one watchlist
+	MaxPerCaller int // Watchlists of one caller
+}
+
+// DefaultWatchlistConfig lets each caller keep 20 watchlists of up to 50
+// pairs
+func DefaultWatchlistConfig() WatchlistConfig {
+	return WatchlistConfig{
+		MaxPairs:     50,
+		MaxPerCaller: 20,
+	}
+}
+
+// Watchlists keeps named lists of pairs per caller, and looks up the latest
+// rates of a whole list in one call for dashboard home screens. Watchlists
+// are held in memory.
+type Watchlists struct {
+	service *ExchangeService
+	cfg     WatchlistConfig
+
+	mu         sync.Mutex
+	watchlists map[string]*models.Watchlist
+}
+
+func NewWatchlists(service *ExchangeService, cfg WatchlistConfig) *Watchlists {
+	return &Watchlists{
+		service:    service,
+		cfg:        cfg,
+		watchlists: make(map[string]*models.Watchlist),
+	}
+}
+
+// Create adds a watchlist for caller. Pairs allowed rejects fail with
+// PAIR_NOT_ALLOWED; a nil allowed permits every pair. Callers over their
+// number of watchlists fail with LIMIT_EXCEEDED.
+func (w *Watchlists) Create(ctx context.Context, caller string, req *models.WatchlistRequest, allowed PairFilter) (*models.Watchlist, error) {
+	name, pairs, err := w.validate(ctx, req, allowed)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newWatchlistID()
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.count(caller) >= w.cfg.MaxPerCaller {
+		return nil, models.NewError(models.ErrCodeLimitExceeded, "at most %d watchlists can be kept, delete one first", w.cfg.MaxPerCaller)
+	}
+
+	now := time.Now().UTC()
+	watchlist := &models.Watchlist{
+		ID:        id,
+		Caller:    caller,
+		Name:      name,
+		Pairs:     pairs,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	w.watchlists[id] = watchlist
+	copied := *watchlist
+	return &copied, nil
+}
+
+// Update replaces the name and pairs of a watchlist, checked like Create
+func (w *Watchlists) Update(ctx context.Context, id string, req *models.WatchlistRequest, allowed PairFilter) (*models.Watchlist, error) {
+	name, pairs, err := w.validate(ctx, req, allowed)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	watchlist, ok := w.watchlists[id]
+	if !ok {
+		return nil, models.NewError(models.ErrCodeNotFound, "watchlist %s not found", id)
+	}
+	watchlist.Name = name
+	watchlist.Pairs = pairs
+	watchlist.UpdatedAt = time.Now().UTC()
+	copied := *watchlist
+	return &copied, nil
+}
+
+// Delete removes a watchlist
+func (w *Watchlists) Delete(id string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.watchlists[id]; !ok {
+		return false
+	}
+	delete(w.watchlists, id)
+	return true
+}
+
+// Get returns a watchlist
+func (w *Watchlists) Get(id string) (*models.Watchlist, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	watchlist, ok := w.watchlists[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *watchlist
+	return &copied, true
+}
+
+// List returns the watchlists of caller, or every watchlist when caller is
+// empty, oldest first
+func (w *Watchlists) List(caller string) []models.Watchlist {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	list := make([]models.Watchlist, 0, len(w.watchlists))
+	for _, watchlist := range w.watchlists {
+		if caller == "" || watchlist.Caller == caller {
+			list = append(list, *watchlist)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Rates looks up the latest rate of every pair of a watchlist, within the
+// tenant of ctx, on at most the bulk concurrency of goroutines at once.
+// Unlike bulk historical requests, a pair that fails doesn't fail the
+// others: its entry carries the error code and message instead of a rate.
+// Pairs allowed rejects, e.g. after the caller's policy was narrowed, fail
+// with PAIR_NOT_ALLOWED the same way.
+func (w *Watchlists) Rates(ctx context.Context, id string, allowed PairFilter) (*models.WatchlistRatesResponse, error) {
+	watchlist, ok := w.Get(id)
+	if !ok {
+		return nil, models.NewError(models.ErrCodeNotFound, "watchlist %s not found", id)
+	}
+
+	rates := make([]models.WatchlistRate, len(watchlist.Pairs))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, w.service.getBulkConcurrency())
+	for i, pair := range watchlist.Pairs {
+		rates[i].Pair = pair
+		from, to, _ := strings.Cut(pair, "_")
+		if allowed != nil && !allowed(from, to) {
+			setWatchlistError(&rates[i], models.NewError(models.ErrCodePairNotAllowed, "%s/%s is not allowed", from, to))
+			continue
+		}
+
+		wg.Add(1)
+		go func(rate *models.WatchlistRate, from, to string) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				setWatchlistError(rate, upstreamError("request ended before the rate was looked up", ctx.Err()))
+				return
+			}
+			result, err := w.service.GetLatestRate(ctx, from, to, "")
+			if err != nil {
+				setWatchlistError(rate, err)
+				return
+			}
+			rate.LatestRateResponse = result
+		}(&rates[i], from, to)
+	}
+	wg.Wait()
+
+	return &models.WatchlistRatesResponse{
+		ID:    watchlist.ID,
+		Name:  watchlist.Name,
+		Rates: rates,
+	}, nil
+}
+
+// validate returns the trimmed name and the pairs of req as FROM_TO
+func (w *Watchlists) validate(ctx context.Context, req *models.WatchlistRequest, allowed PairFilter) (string, []string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return "", nil, models.NewFieldError(models.ErrCodeMissingParameter, "name", "name is required")
+	}
+	if len([]rune(name)) > maxWatchlistName {
+		return "", nil, models.NewFieldError(models.ErrCodeValueInvalid, "name", "name must be at most %d characters", maxWatchlistName)
+	}
+	if len(req.Pairs) == 0 {
+		return "", nil, models.NewFieldError(models.ErrCodeMissingParameter, "pairs", "at least one pair is required")
+	}
+	if len(req.Pairs) > w.cfg.MaxPairs {
+		return "", nil, models.NewFieldError(models.ErrCodeLimitExceeded, "pairs", "between 1 and %d pairs can be watched, got %d", w.cfg.MaxPairs, len(req.Pairs))
+	}
+	pairs, err := parsePairs(ctx, req.Pairs)
+	if err != nil {
+		return "", nil, err
+	}
+	names := make([]string, len(pairs))
+	for i, pair := range pairs {
+		if allowed != nil && !allowed(pair.from, pair.to) {
+			return "", nil, models.NewFieldError(models.ErrCodePairNotAllowed, "pairs", "%s/%s is not allowed", pair.from, pair.to)
+		}
+		names[i] = pair.String()
+	}
+	return name, names, nil
+}
+
+// setWatchlistError reports err in place of the rate of a watchlist pair
+func setWatchlistError(rate *models.WatchlistRate, err error) {
+	rate.ErrorCode, _ = models.ErrorCodeOf(err)
+	rate.Error = err.Error()
+}
+
+// count returns how many watchlists caller keeps. The caller holds w.mu.
+func (w *Watchlists) count(caller string) int {
+	n := 0
+	for _, watchlist := range w.watchlists {
+		if watchlist.Caller == caller {
+			n++
+		}
+	}
+	return n
+}
+
+func newWatchlistID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate watchlist ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+)
+
+func newTestWatchlists(t *testing.T, cfg WatchlistConfig) *Watchlists {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(provider.Close)
+
+	clientCfg := external.DefaultConfig()
+	clientCfg.BaseURL = provider.URL
+	clientCfg.Retry.MaxAttempts = 1
+	clientCfg.Throttle = external.ThrottleConfig{}
+	client := external.NewExchangeRateClientWithConfig(clientCfg)
+	memoryCache := cache.NewMemoryCache(time.Hour)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "USD", "INR", "", 83.5)
+	memoryCache.Set(external.ProviderExchangeRateAPI, "EUR", "USD", "", 1.08)
+	return NewWatchlists(NewExchangeService(memoryCache, NewRateFetcher(client, memoryCache), client), cfg)
+}
+
+func TestWatchlists_CRUD(t *testing.T) {
+	watchlists := newTestWatchlists(t, DefaultWatchlistConfig())
+	ctx := context.Background()
+
+	watchlist, err := watchlists.Create(ctx, "dashboard", &models.WatchlistRequest{Name: " Home ", Pairs: []string{"usd_inr", "EUR_USD"}}, nil)
+	require.NoError(t, err)
+	assert.Len(t, watchlist.ID, 32)
+	assert.Equal(t, "Home", watchlist.Name)
+	assert.Equal(t, []string{"USD_INR", "EUR_USD"}, watchlist.Pairs)
+	_, err = watchlists.Create(ctx, "other", &models.WatchlistRequest{Name: "Other", Pairs: []string{"USD_INR"}}, nil)
+	require.NoError(t, err)
+
+	assert.Len(t, watchlists.List("dashboard"), 1)
+	assert.Len(t, watchlists.List(""), 2)
+
+	updated, err := watchlists.Update(ctx, watchlist.ID, &models.WatchlistRequest{Name: "Travel", Pairs: []string{"EUR_USD"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Travel", updated.Name)
+	assert.Equal(t, []string{"EUR_USD"}, updated.Pairs)
+	assert.Equal(t, watchlist.CreatedAt, updated.CreatedAt)
+	got, ok := watchlists.Get(watchlist.ID)
+	require.True(t, ok)
+	assert.Equal(t, updated, got)
+
+	assert.True(t, watchlists.Delete(watchlist.ID))
+	assert.False(t, watchlists.Delete(watchlist.ID))
+	_, ok = watchlists.Get(watchlist.ID)
+	assert.False(t, ok)
+	_, err = watchlists.Update(ctx, watchlist.ID, &models.WatchlistRequest{Name: "Gone", Pairs: []string{"EUR_USD"}}, nil)
+	code, _ := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeNotFound, code)
+}
+
+func TestWatchlists_Validation(t *testing.T) {
+	watchlists := newTestWatchlists(t, WatchlistConfig{MaxPairs: 2, MaxPerCaller: 1})
+	ctx := context.Background()
+	usdOnly := func(from, to string) bool { return from == "USD" }
+
+	tests := []struct {
+		name    string
+		req     models.WatchlistRequest
+		allowed PairFilter
+		code    string
+		field   string
+	}{
+		{"Blank name", models.WatchlistRequest{Name: "  ", Pairs: []string{"USD_INR"}}, nil, models.ErrCodeMissingParameter, "name"},
+		{"No pairs", models.WatchlistRequest{Name: "Home"}, nil, models.ErrCodeMissingParameter, "pairs"},
+		{"Too many pairs", models.WatchlistRequest{Name: "Home", Pairs: []string{"USD_INR", "USD_EUR", "USD_GBP"}}, nil, models.ErrCodeLimitExceeded, "pairs"},
+		{"Malformed pair", models.WatchlistRequest{Name: "Home", Pairs: []string{"USDINR"}}, nil, models.ErrCodeValueInvalid, "pairs"},
+		{"Unsupported currency", models.WatchlistRequest{Name: "Home", Pairs: []string{"USD_XXX"}}, nil, models.ErrCodeCurrencyUnsupported, "pairs"},
+		{"Duplicate pair", models.WatchlistRequest{Name: "Home", Pairs: []string{"USD_INR", "usd_inr"}}, nil, models.ErrCodeValueInvalid, "pairs"},
+		{"Pair not allowed", models.WatchlistRequest{Name: "Home", Pairs: []string{"EUR_USD"}}, usdOnly, models.ErrCodePairNotAllowed, "pairs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := watchlists.Create(ctx, "dashboard", &tt.req, tt.allowed)
+			code, field := models.ErrorCodeOf(err)
+			assert.Equal(t, tt.code, code, err)
+			assert.Equal(t, tt.field, field)
+		})
+	}
+
+	_, err := watchlists.Create(ctx, "dashboard", &models.WatchlistRequest{Name: "Home", Pairs: []string{"USD_INR"}}, nil)
+	require.NoError(t, err)
+	_, err = watchlists.Create(ctx, "dashboard", &models.WatchlistRequest{Name: "Second", Pairs: []string{"USD_INR"}}, nil)
+	code, _ := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeLimitExceeded, code, "callers keep at most MaxPerCaller watchlists")
+	_, err = watchlists.Create(ctx, "other", &models.WatchlistRequest{Name: "Home", Pairs: []string{"USD_INR"}}, nil)
+	assert.NoError(t, err, "the limit is per caller")
+}
+
+func TestWatchlists_Rates(t *testing.T) {
+	watchlists := newTestWatchlists(t, DefaultWatchlistConfig())
+	ctx := context.Background()
+
+	watchlist, err := watchlists.Create(ctx, "dashboard", &models.WatchlistRequest{Name: "Home", Pairs: []string{"USD_INR", "GBP_JPY", "EUR_USD"}}, nil)
+	require.NoError(t, err)
+
+	result, err := watchlists.Rates(ctx, watchlist.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Home", result.Name)
+	require.Len(t, result.Rates, 3)
+
+	assert.Equal(t, "USD_INR", result.Rates[0].Pair)
+	require.NotNil(t, result.Rates[0].LatestRateResponse)
+	assert.Equal(t, 83.5, result.Rates[0].Rate)
+	assert.Empty(t, result.Rates[0].ErrorCode)
+
+	assert.Equal(t, "GBP_JPY", result.Rates[1].Pair)
+	assert.Nil(t, result.Rates[1].LatestRateResponse, "a pair the provider fails for fails alone")
+	assert.Equal(t, models.ErrCodeProviderUnavailable, result.Rates[1].ErrorCode)
+	assert.NotEmpty(t, result.Rates[1].Error)
+
+	assert.Equal(t, "EUR_USD", result.Rates[2].Pair)
+	require.NotNil(t, result.Rates[2].LatestRateResponse)
+	assert.Equal(t, 1.08, result.Rates[2].Rate)
+
+	result, err = watchlists.Rates(ctx, watchlist.ID, func(from, to string) bool { return from == "USD" })
+	require.NoError(t, err)
+	assert.NotNil(t, result.Rates[0].LatestRateResponse)
+	assert.Equal(t, models.ErrCodePairNotAllowed, result.Rates[2].ErrorCode, "pairs the policy no longer allows are withheld")
+
+	_, err = watchlists.Rates(ctx, "unknown", nil)
+	code, _ := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeNotFound, code)
+}
@@ -0,0 +1,508 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/utils"
+)
+
+// Headers of a webhook push. The signature is the hex HMAC-SHA256, keyed
+// with the subscription's secret, of the timestamp header, a dot and the
+// body, so a captured push can't be replayed with a new timestamp.
+const (
+	WebhookSignatureHeader = "X-Signature-256"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookIDHeader        = "X-Webhook-ID"
+)
+
+// WebhookConfig sets how rate pushes to subscribers are delivered
+type WebhookConfig struct {
+	MaxAttempts    int           // Attempts of a push before it is dead-lettered
+	RetryBackoff   time.Duration // Wait before the first retry, doubled for each one after
+	Timeout        time.Duration // Deadline of each attempt
+	MaxDeadLetters int           // Failed pushes kept per subscription, oldest dropped first
+	MaxPerCaller   int           // Subscriptions of one caller
+
+	// AllowPrivate lets callbacks resolve to loopback, private and
+	// link-local addresses, for local development. Left off, such callbacks
+	// are refused when subscribing and pushes to them are never dialed.
+	AllowPrivate bool
+}
+
+// DefaultWebhookConfig tries a push 5 times over about 15 seconds, keeps
+// the last 100 failed pushes of each subscription and lets each caller keep
+// 20 subscriptions
+func DefaultWebhookConfig() WebhookConfig {
+	return WebhookConfig{
+		MaxAttempts:    5,
+		RetryBackoff:   time.Second,
+		Timeout:        webhookTimeout,
+		MaxDeadLetters: 100,
+		MaxPerCaller:   20,
+	}
+}
+
+// WebhookDispatcher pushes changed rates to subscribed callback URLs. After
+// each fetch cycle, every subscription is sent the rates of its pairs that
+// changed since its last push, signed with its secret. Failed pushes are
+// retried with exponential backoff and, once every attempt failed, kept as
+// dead letters that can be redelivered. Subscriptions are held in memory.
+type WebhookDispatcher struct {
+	cfg        WebhookConfig
+	httpClient *http.Client
+	lookup     func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+	mu            sync.Mutex
+	subscriptions map[string]*subscription
+	isRunning     bool
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+type subscription struct {
+	info        models.Subscription
+	pairs       map[string]bool    // "FROM_TO"
+	pushed      map[string]float64 // "FROM_TO" -> rate last pushed
+	deadLetters []models.DeadLetter
+}
+
+func NewWebhookDispatcher(cfg WebhookConfig) *WebhookDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &WebhookDispatcher{
+		cfg:           cfg,
+		httpClient:    newCallbackClient(cfg),
+		lookup:        net.DefaultResolver.LookupIPAddr,
+		subscriptions: make(map[string]*subscription),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+func (d *WebhookDispatcher) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.isRunning = true
+}
+
+// Stop abandons pushes in flight and stops publishing
+func (d *WebhookDispatcher) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.isRunning {
+		return
+	}
+	d.cancel()
+	d.isRunning = false
+}
+
+// Subscribe registers a callback URL for the pairs of req on behalf of
+// caller. Pairs allowed rejects are refused with PAIR_NOT_ALLOWED; a nil
+// allowed permits every pair. Callbacks resolving to loopback, private or
+// link-local addresses are refused unless AllowPrivate is set, and callers
+// over their number of subscriptions fail with LIMIT_EXCEEDED. The returned
+// subscription carries the secret its pushes are signed with.
+func (d *WebhookDispatcher) Subscribe(caller string, req *models.SubscriptionRequest, allowed PairFilter) (*models.Subscription, error) {
+	if err := d.validateCallbackURL(req.CallbackURL); err != nil {
+		return nil, err
+	}
+	if len(req.Pairs) == 0 {
+		return nil, models.NewFieldError(models.ErrCodeMissingParameter, "pairs", "at least one pair is required")
+	}
+
+	pairs := make(map[string]bool, len(req.Pairs))
+	var names []string
+	for _, pair := range req.Pairs {
+		pair = strings.ToUpper(strings.TrimSpace(pair))
+		from, to, ok := strings.Cut(pair, "_")
+		if !ok {
+			return nil, models.NewFieldError(models.ErrCodeInvalidRequest, "pairs", "expected pair in FROM_TO form, got %q", pair)
+		}
+		if err := utils.ValidateCurrencyPair(from, to); err != nil {
+			return nil, models.ForField(err, "pairs", "")
+		}
+		if from == to {
+			return nil, models.NewFieldError(models.ErrCodeValueInvalid, "pairs", "%s pairs a currency with itself", pair)
+		}
+		if allowed != nil && !allowed(from, to) {
+			return nil, models.NewError(models.ErrCodePairNotAllowed, "%s/%s is not allowed for %s", from, to, caller)
+		}
+		if !pairs[pair] {
+			pairs[pair] = true
+			names = append(names, pair)
+		}
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &subscription{
+		info: models.Subscription{
+			ID:          id,
+			Caller:      caller,
+			CallbackURL: req.CallbackURL,
+			Pairs:       names,
+			Secret:      "whsec_" + secret,
+			CreatedAt:   time.Now().UTC(),
+		},
+		pairs:  pairs,
+		pushed: make(map[string]float64),
+	}
+
+	d.mu.Lock()
+	if d.count(caller) >= d.cfg.MaxPerCaller {
+		d.mu.Unlock()
+		return nil, models.NewError(models.ErrCodeLimitExceeded, "at most %d subscriptions can be kept, delete one first", d.cfg.MaxPerCaller)
+	}
+	d.subscriptions[id] = sub
+	d.mu.Unlock()
+
+	log.Printf("Subscribed %s to %s for %s", req.CallbackURL, strings.Join(names, ", "), caller)
+	info := sub.info
+	return &info, nil
+}
+
+// Unsubscribe removes a subscription; pushes in flight are still completed
+func (d *WebhookDispatcher) Unsubscribe(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.subscriptions[id]; !ok {
+		return false
+	}
+	delete(d.subscriptions, id)
+	return true
+}
+
+// Get returns a subscription, without its secret
+func (d *WebhookDispatcher) Get(id string) (*models.Subscription, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	sub, ok := d.subscriptions[id]
+	if !ok {
+		return nil, false
+	}
+	info := sub.public()
+	return &info, true
+}
+
+// List returns the subscriptions of caller, or every subscription when
+// caller is empty, oldest first and without their secrets
+func (d *WebhookDispatcher) List(caller string) []models.Subscription {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	list := make([]models.Subscription, 0, len(d.subscriptions))
+	for _, sub := range d.subscriptions {
+		if caller == "" || sub.info.Caller == caller {
+			list = append(list, sub.public())
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// DeadLetters returns the failed pushes of a subscription, oldest first
+func (d *WebhookDispatcher) DeadLetters(id string) ([]models.DeadLetter, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	sub, ok := d.subscriptions[id]
+	if !ok {
+		return nil, false
+	}
+	return append([]models.DeadLetter{}, sub.deadLetters...), true
+}
+
+// Redeliver takes the dead letters of a subscription and pushes them again,
+// and returns how many it queued. Pushes that fail again return to the dead
+// letters.
+func (d *WebhookDispatcher) Redeliver(id string) (int, bool) {
+	d.mu.Lock()
+	sub, ok := d.subscriptions[id]
+	if !ok {
+		d.mu.Unlock()
+		return 0, false
+	}
+	if !d.isRunning {
+		d.mu.Unlock()
+		return 0, true
+	}
+	letters := sub.deadLetters
+	sub.deadLetters = nil
+	d.mu.Unlock()
+
+	for _, letter := range letters {
+		go d.deliver(sub, letter.Payload)
+	}
+	return len(letters), true
+}
+
+// Publish pushes the rates of a fetch cycle that changed to the
+// subscriptions of their pairs. It doesn't wait for the pushes.
+func (d *WebhookDispatcher) Publish(rates []FetchedRate) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.isRunning {
+		return
+	}
+	now := time.Now().UTC()
+	for _, sub := range d.subscriptions {
+		var changes []models.RateChange
+		for _, rate := range rates {
+			pair := rate.From + "_" + rate.To
+			if !sub.pairs[pair] {
+				continue
+			}
+			change := models.RateChange{
+				From:        rate.From,
+				To:          rate.To,
+				Rate:        rate.Rate,
+				Provider:    rate.Provider,
+				PublishedAt: rate.PublishedAt.UTC(),
+			}
+			if previous, pushed := sub.pushed[pair]; pushed {
+				if previous == rate.Rate {
+					continue
+				}
+				change.Previous = &previous
+			}
+			sub.pushed[pair] = rate.Rate
+			changes = append(changes, change)
+		}
+		if len(changes) == 0 {
+			continue
+		}
+
+		id, err := randomHex(16)
+		if err != nil {
+			log.Printf("Failed to push rates to subscription %s: %v", sub.info.ID, err)
+			continue
+		}
+		go d.deliver(sub, models.WebhookPayload{
+			ID:             id,
+			Event:          models.WebhookEventRatesChanged,
+			SubscriptionID: sub.info.ID,
+			Timestamp:      now,
+			Rates:          changes,
+		})
+	}
+}
+
+// deliver POSTs a payload until the subscriber acknowledges it with a 2xx
+// status, and dead-letters it once every attempt failed
+func (d *WebhookDispatcher) deliver(sub *subscription, payload models.WebhookPayload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to encode push to subscription %s: %v", sub.info.ID, err)
+		return
+	}
+
+	backoff := d.cfg.RetryBackoff
+	var lastErr error
+	attempts := 0
+	for attempts < d.cfg.MaxAttempts {
+		if attempts > 0 {
+			select {
+			case <-d.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		attempts++
+
+		if lastErr = d.post(sub, payload.ID, body); lastErr == nil {
+			d.mu.Lock()
+			delivered := time.Now().UTC()
+			sub.info.Delivered++
+			sub.info.LastDelivered = &delivered
+			d.mu.Unlock()
+			return
+		}
+		if d.ctx.Err() != nil {
+			return
+		}
+	}
+
+	log.Printf("Giving up on push %s to %s after %d attempts: %v", payload.ID, sub.info.CallbackURL, attempts, lastErr)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sub.info.DeadLetters++
+	sub.deadLetters = append(sub.deadLetters, models.DeadLetter{
+		Payload:   payload,
+		Attempts:  attempts,
+		LastError: lastErr.Error(),
+		FailedAt:  time.Now().UTC(),
+	})
+	if excess := len(sub.deadLetters) - d.cfg.MaxDeadLetters; excess > 0 {
+		sub.deadLetters = append([]models.DeadLetter{}, sub.deadLetters[excess:]...)
+	}
+}
+
+func (d *WebhookDispatcher) post(sub *subscription, id string, body []byte) error {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, sub.info.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, id)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhook(sub.info.Secret, timestamp, body))
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("subscriber returned status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhook returns the hex HMAC-SHA256 signature of a push, as sent in
+// the X-Signature-256 header after "sha256="
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// public returns the subscription without its secret
+func (s *subscription) public() models.Subscription {
+	info := s.info
+	info.Secret = ""
+	info.Pairs = append([]string{}, s.info.Pairs...)
+	return info
+}
+
+// count returns how many subscriptions caller keeps. The caller holds d.mu.
+func (d *WebhookDispatcher) count(caller string) int {
+	n := 0
+	for _, sub := range d.subscriptions {
+		if sub.info.Caller == caller {
+			n++
+		}
+	}
+	return n
+}
+
+// validateCallbackURL checks callback is an absolute http or https URL and,
+// unless AllowPrivate is set, that every address its host resolves to may be
+// pushed to. Pushes check the address again when dialing, as DNS answers
+// can change after subscribing.
+func (d *WebhookDispatcher) validateCallbackURL(callback string) error {
+	parsed, err := url.Parse(callback)
+	if err != nil || parsed.Hostname() == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return models.NewFieldError(models.ErrCodeValueInvalid, "callback_url", "callback_url must be an absolute http or https URL, got %q", callback)
+	}
+	if d.cfg.AllowPrivate {
+		return nil
+	}
+
+	host := parsed.Hostname()
+	var addrs []net.IPAddr
+	if ip := net.ParseIP(host); ip != nil {
+		addrs = []net.IPAddr{{IP: ip}}
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), d.cfg.Timeout)
+		defer cancel()
+		if addrs, err = d.lookup(ctx, host); err != nil || len(addrs) == 0 {
+			return models.NewFieldError(models.ErrCodeValueInvalid, "callback_url", "callback_url host %s could not be resolved", host)
+		}
+	}
+	for _, addr := range addrs {
+		if blockedCallbackIP(addr.IP) {
+			return models.NewFieldError(models.ErrCodeValueInvalid, "callback_url", "callback_url host %s resolves to %s, which can't be pushed to", host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, private in
+// practice but not to net.IP.IsPrivate
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// blockedCallbackIP reports whether ip is an address pushes must not reach:
+// loopback, private, link-local (cloud metadata services among them),
+// multicast or unspecified
+func blockedCallbackIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// newCallbackClient returns the client pushes are sent with. Unless
+// AllowPrivate is set, it refuses to connect to blocked addresses, checked
+// on the address actually dialed so a callback host re-resolving to one
+// after subscribing isn't reached either. Proxies from the environment are
+// not used, as they would be dialed instead of the callback.
+func newCallbackClient(cfg WebhookConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || blockedCallbackIP(ip) {
+				return fmt.Errorf("callback address %s can't be pushed to", host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: cfg.Timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// A redirect could point anywhere, so it is not followed
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
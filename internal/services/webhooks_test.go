@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/models"
+)
+
+// webhookReceiver records the pushes it accepts after verifying their
+// signature, and answers the first failures with 503
+type webhookReceiver struct {
+	t        *testing.T
+	secret   string
+	mu       sync.Mutex
+	failures int
+	attempts int
+	payloads []models.WebhookPayload
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	require.NoError(r.t, err)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	timestamp := req.Header.Get(WebhookTimestampHeader)
+	assert.Equal(r.t, "sha256="+SignWebhook(r.secret, timestamp, body), req.Header.Get(WebhookSignatureHeader))
+	var payload models.WebhookPayload
+	require.NoError(r.t, json.Unmarshal(body, &payload))
+	assert.Equal(r.t, payload.ID, req.Header.Get(WebhookIDHeader))
+	r.payloads = append(r.payloads, payload)
+}
+
+func (r *webhookReceiver) received() []models.WebhookPayload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.WebhookPayload{}, r.payloads...)
+}
+
+func newTestWebhooks(t *testing.T) *WebhookDispatcher {
+	cfg := DefaultWebhookConfig()
+	cfg.RetryBackoff = time.Millisecond
+	cfg.MaxAttempts = 3
+	cfg.AllowPrivate = true // Receivers listen on loopback
+	webhooks := NewWebhookDispatcher(cfg)
+	webhooks.Start()
+	t.Cleanup(webhooks.Stop)
+	return webhooks
+}
+
+func TestWebhookDispatcher_PushesChangedRates(t *testing.T) {
+	webhooks := newTestWebhooks(t)
+	receiver := &webhookReceiver{t: t, failures: 1}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	sub, err := webhooks.Subscribe("partner", &models.SubscriptionRequest{
+		CallbackURL: server.URL,
+		Pairs:       []string{"usd_inr", "USD_EUR", "USD_INR"},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"USD_INR", "USD_EUR"}, sub.Pairs)
+	require.NotEmpty(t, sub.Secret)
+	receiver.secret = sub.Secret
+
+	published := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	webhooks.Publish([]FetchedRate{
+		{From: "USD", To: "INR", Rate: 83.5, Provider: "frankfurter", PublishedAt: published},
+		{From: "USD", To: "EUR", Rate: 0.92, PublishedAt: published},
+		{From: "USD", To: "GBP", Rate: 0.79, PublishedAt: published},
+	})
+	require.Eventually(t, func() bool { return len(receiver.received()) == 1 }, 5*time.Second, 5*time.Millisecond)
+
+	first := receiver.received()[0]
+	assert.Equal(t, models.WebhookEventRatesChanged, first.Event)
+	assert.Equal(t, sub.ID, first.SubscriptionID)
+	require.Len(t, first.Rates, 2)
+	assert.Equal(t, "INR", first.Rates[0].To)
+	assert.Equal(t, "frankfurter", first.Rates[0].Provider)
+	assert.Nil(t, first.Rates[0].Previous)
+
+	// Only the rate that changed is pushed, with the one pushed before
+	webhooks.Publish([]FetchedRate{
+		{From: "USD", To: "INR", Rate: 83.7, PublishedAt: published.Add(time.Hour)},
+		{From: "USD", To: "EUR", Rate: 0.92, PublishedAt: published.Add(time.Hour)},
+	})
+	require.Eventually(t, func() bool { return len(receiver.received()) == 2 }, 5*time.Second, 5*time.Millisecond)
+
+	second := receiver.received()[1]
+	require.Len(t, second.Rates, 1)
+	assert.Equal(t, 83.7, second.Rates[0].Rate)
+	require.NotNil(t, second.Rates[0].Previous)
+	assert.Equal(t, 83.5, *second.Rates[0].Previous)
+
+	// Nothing changed, nothing is pushed
+	webhooks.Publish([]FetchedRate{{From: "USD", To: "INR", Rate: 83.7}})
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, receiver.received(), 2)
+
+	info, ok := webhooks.Get(sub.ID)
+	require.True(t, ok)
+	assert.Equal(t, 2, info.Delivered)
+	assert.Empty(t, info.Secret)
+}
+
+func TestWebhookDispatcher_DeadLetters(t *testing.T) {
+	webhooks := newTestWebhooks(t)
+	receiver := &webhookReceiver{t: t, failures: 3}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	sub, err := webhooks.Subscribe("partner", &models.SubscriptionRequest{CallbackURL: server.URL, Pairs: []string{"USD_INR"}}, nil)
+	require.NoError(t, err)
+	receiver.secret = sub.Secret
+
+	webhooks.Publish([]FetchedRate{{From: "USD", To: "INR", Rate: 83.5}})
+	var letters []models.DeadLetter
+	require.Eventually(t, func() bool {
+		letters, _ = webhooks.DeadLetters(sub.ID)
+		return len(letters) == 1
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, 3, letters[0].Attempts)
+	assert.Contains(t, letters[0].LastError, "503")
+	assert.Empty(t, receiver.received())
+
+	// The receiver recovered; redelivery pushes the same payload
+	queued, ok := webhooks.Redeliver(sub.ID)
+	require.True(t, ok)
+	assert.Equal(t, 1, queued)
+	require.Eventually(t, func() bool { return len(receiver.received()) == 1 }, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, letters[0].Payload.ID, receiver.received()[0].ID)
+
+	letters, _ = webhooks.DeadLetters(sub.ID)
+	assert.Empty(t, letters)
+	info, _ := webhooks.Get(sub.ID)
+	assert.Equal(t, 1, info.Delivered)
+	assert.Equal(t, 1, info.DeadLetters)
+}
+
+func TestWebhookDispatcher_Subscribe(t *testing.T) {
+	webhooks := newTestWebhooks(t)
+	onlyUSD := func(from, to string) bool { return from == "USD" }
+
+	tests := []struct {
+		name     string
+		callback string
+		pairs    []string
+		code     string
+	}{
+		{"relative URL", "/hooks", []string{"USD_INR"}, models.ErrCodeValueInvalid},
+		{"unsupported scheme", "ftp://example.com/hooks", []string{"USD_INR"}, models.ErrCodeValueInvalid},
+		{"no pairs", "https://example.com/hooks", nil, models.ErrCodeMissingParameter},
+		{"malformed pair", "https://example.com/hooks", []string{"USDINR"}, models.ErrCodeInvalidRequest},
+		{"unsupported currency", "https://example.com/hooks", []string{"USD_XYZ"}, models.ErrCodeCurrencyUnsupported},
+		{"same currency", "https://example.com/hooks", []string{"USD_USD"}, models.ErrCodeValueInvalid},
+		{"pair not allowed", "https://example.com/hooks", []string{"INR_USD"}, models.ErrCodePairNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := webhooks.Subscribe("partner", &models.SubscriptionRequest{CallbackURL: tt.callback, Pairs: tt.pairs}, onlyUSD)
+			require.Error(t, err)
+			code, _ := models.ErrorCodeOf(err)
+			assert.Equal(t, tt.code, code)
+		})
+	}
+
+	first, err := webhooks.Subscribe("partner", &models.SubscriptionRequest{CallbackURL: "https://example.com/a", Pairs: []string{"USD_INR"}}, onlyUSD)
+	require.NoError(t, err)
+	_, err = webhooks.Subscribe("other", &models.SubscriptionRequest{CallbackURL: "https://example.com/b", Pairs: []string{"EUR_INR"}}, nil)
+	require.NoError(t, err)
+
+	assert.Len(t, webhooks.List("partner"), 1)
+	assert.Len(t, webhooks.List(""), 2)
+	assert.True(t, webhooks.Unsubscribe(first.ID))
+	assert.False(t, webhooks.Unsubscribe(first.ID))
+	assert.Empty(t, webhooks.List("partner"))
+}
+
+func TestWebhookDispatcher_RefusesPrivateCallbacks(t *testing.T) {
+	webhooks := NewWebhookDispatcher(DefaultWebhookConfig())
+	webhooks.lookup = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "hooks.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}}, nil
+		case "rebound.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}, {IP: net.ParseIP("10.0.0.5")}}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	for _, callback := range []string{
+		"http://127.0.0.1:8080/hooks",
+		"http://[::1]/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"http://192.168.1.10/hooks",
+		"http://100.64.0.1/hooks",
+		"http://0.0.0.0/hooks",
+		"https://rebound.example.com/hooks",
+		"https://unknown.example.com/hooks",
+	} {
+		_, err := webhooks.Subscribe("partner", &models.SubscriptionRequest{CallbackURL: callback, Pairs: []string{"USD_INR"}}, nil)
+		code, field := models.ErrorCodeOf(err)
+		assert.Equal(t, models.ErrCodeValueInvalid, code, callback)
+		assert.Equal(t, "callback_url", field)
+	}
+
+	_, err := webhooks.Subscribe("partner", &models.SubscriptionRequest{CallbackURL: "https://hooks.example.com/rates", Pairs: []string{"USD_INR"}}, nil)
+	assert.NoError(t, err)
+
+	// A host re-resolving to a blocked address after subscribing isn't dialed
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a blocked address was dialed")
+	}))
+	defer server.Close()
+	_, err = webhooks.httpClient.Post(server.URL, "application/json", nil)
+	assert.ErrorContains(t, err, "can't be pushed to")
+}
+
+func TestWebhookDispatcher_MaxPerCaller(t *testing.T) {
+	cfg := DefaultWebhookConfig()
+	cfg.MaxPerCaller = 1
+	webhooks := NewWebhookDispatcher(cfg)
+
+	req := &models.SubscriptionRequest{CallbackURL: "https://93.184.215.14/hooks", Pairs: []string{"USD_INR"}}
+	first, err := webhooks.Subscribe("partner", req, nil)
+	require.NoError(t, err)
+	_, err = webhooks.Subscribe("partner", req, nil)
+	code, _ := models.ErrorCodeOf(err)
+	assert.Equal(t, models.ErrCodeLimitExceeded, code)
+	_, err = webhooks.Subscribe("other", req, nil)
+	assert.NoError(t, err, "the limit is per caller")
+
+	webhooks.Unsubscribe(first.ID)
+	_, err = webhooks.Subscribe("partner", req, nil)
+	assert.NoError(t, err, "unsubscribing frees a place")
+}
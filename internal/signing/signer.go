@@ -0,0 +1,145 @@
+// Package signing signs quoted rates with an Ed25519 key, so downstream
+// systems can prove a rate came from this service at a given time.
+//
+// A signed response carries a models.Signature whose Payload is the
+// canonical form of the signed fields: URL-encoded key=value pairs sorted by
+// key, with floats in their shortest exact decimal form and times in RFC 3339
+// with nanoseconds. Verifiers check the signature over Payload, then compare
+// its fields with the ones they rely on.
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"exchange-rate-service/internal/models"
+)
+
+// ErrInvalidSignature is returned by Verify for a signature that doesn't
+// match its payload and key
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Signer signs canonical rate payloads with one private key
+type Signer struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// NewSigner returns a signer using key. Its key ID is derived from the public
+// key, so it stays the same across restarts with the same key.
+func NewSigner(key ed25519.PrivateKey) *Signer {
+	return &Signer{key: key, keyID: KeyID(key.Public().(ed25519.PublicKey))}
+}
+
+// GenerateSigner returns a signer with a new random key
+func GenerateSigner() (*Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	return NewSigner(key), nil
+}
+
+// LoadSigner reads a PEM-encoded PKCS #8 Ed25519 private key, as written by
+// `openssl genpkey -algorithm ed25519`
+func LoadSigner(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("signing key %s is not a PEM private key", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key %s is a %T, expected Ed25519", path, parsed)
+	}
+	return NewSigner(key), nil
+}
+
+// KeyID identifies a public key: the first 8 bytes of its SHA-256, in hex
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Sign signs fields at signedAt. The key ID and signing time are added to
+// the signed fields, so neither can be swapped without breaking the
+// signature.
+func (s *Signer) Sign(fields url.Values, signedAt time.Time) *models.Signature {
+	signed := url.Values{}
+	for key, values := range fields {
+		signed[key] = values
+	}
+	signedAt = signedAt.UTC()
+	signed.Set("key_id", s.keyID)
+	signed.Set("signed_at", FormatTime(signedAt))
+	payload := signed.Encode()
+
+	return &models.Signature{
+		KeyID:     s.keyID,
+		Algorithm: models.SignatureAlgorithm,
+		SignedAt:  signedAt,
+		Payload:   payload,
+		Value:     base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, []byte(payload))),
+	}
+}
+
+// PublicKey returns the public half of the signer's key
+func (s *Signer) PublicKey() models.PublicKey {
+	public := s.key.Public().(ed25519.PublicKey)
+	der, _ := x509.MarshalPKIXPublicKey(public)
+	return models.PublicKey{
+		KeyID:     s.keyID,
+		Algorithm: models.SignatureAlgorithm,
+		PublicKey: base64.StdEncoding.EncodeToString(public),
+		PEM:       string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	}
+}
+
+// Verify checks sig against key and returns its signed fields
+func Verify(key ed25519.PublicKey, sig *models.Signature) (url.Values, error) {
+	if sig.Algorithm != models.SignatureAlgorithm {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidSignature, sig.Algorithm)
+	}
+	value, err := base64.StdEncoding.DecodeString(sig.Value)
+	if err != nil || !ed25519.Verify(key, []byte(sig.Payload), value) {
+		return nil, ErrInvalidSignature
+	}
+
+	fields, err := url.ParseQuery(sig.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed payload", ErrInvalidSignature)
+	}
+	if fields.Get("key_id") != sig.KeyID || fields.Get("signed_at") != FormatTime(sig.SignedAt) {
+		return nil, fmt.Errorf("%w: key ID or signing time differ from the payload", ErrInvalidSignature)
+	}
+	return fields, nil
+}
+
+// FormatFloat is the canonical form of a signed number
+func FormatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// FormatTime is the canonical form of a signed time
+func FormatTime(value time.Time) string {
+	return value.UTC().Format(time.RFC3339Nano)
+}
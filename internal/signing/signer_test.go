@@ -0,0 +1,86 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"exchange-rate-service/internal/models"
+)
+
+func TestSigner_SignAndVerify(t *testing.T) {
+	signer, err := GenerateSigner()
+	require.NoError(t, err)
+	public := signer.key.Public().(ed25519.PublicKey)
+	signedAt := time.Date(2025, 1, 16, 9, 30, 0, 123, time.UTC)
+
+	sig := signer.Sign(url.Values{"from": {"USD"}, "to": {"INR"}, "rate": {FormatFloat(83.125)}}, signedAt)
+	assert.Equal(t, models.SignatureAlgorithm, sig.Algorithm)
+	assert.Equal(t, KeyID(public), sig.KeyID)
+	assert.Equal(t, "from=USD&key_id="+sig.KeyID+"&rate=83.125&signed_at=2025-01-16T09%3A30%3A00.000000123Z&to=INR", sig.Payload)
+
+	fields, err := Verify(public, sig)
+	require.NoError(t, err)
+	assert.Equal(t, "83.125", fields.Get("rate"))
+
+	tests := []struct {
+		name   string
+		tamper func(sig *models.Signature)
+	}{
+		{"changed rate", func(sig *models.Signature) { sig.Payload = sig.Payload[:len(sig.Payload)-3] + "EUR" }},
+		{"moved signing time", func(sig *models.Signature) { sig.SignedAt = sig.SignedAt.Add(time.Hour) }},
+		{"other key ID", func(sig *models.Signature) { sig.KeyID = "0000000000000000" }},
+		{"other algorithm", func(sig *models.Signature) { sig.Algorithm = "HS256" }},
+		{"garbled value", func(sig *models.Signature) { sig.Value = "not base64!" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := *sig
+			tt.tamper(&tampered)
+			_, err := Verify(public, &tampered)
+			assert.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+
+	other, err := GenerateSigner()
+	require.NoError(t, err)
+	_, err = Verify(other.key.Public().(ed25519.PublicKey), sig)
+	assert.ErrorIs(t, err, ErrInvalidSignature, "another key doesn't verify the signature")
+}
+
+func TestLoadSigner(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "signing.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	signer, err := LoadSigner(path)
+	require.NoError(t, err)
+	published := signer.PublicKey()
+	assert.Equal(t, NewSigner(key).keyID, published.KeyID, "the key ID is stable for a key")
+
+	block, _ := pem.Decode([]byte(published.PEM))
+	require.NotNil(t, block)
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, key.Public(), parsed)
+
+	notKey := filepath.Join(dir, "not-a-key.pem")
+	require.NoError(t, os.WriteFile(notKey, []byte("hello"), 0o600))
+	_, err = LoadSigner(notKey)
+	assert.Error(t, err)
+	_, err = LoadSigner(filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+}
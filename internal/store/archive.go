@@ -0,0 +1,200 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Snapshot is the set of rates captured for one calendar day
+type Snapshot struct {
+	Date       string                        `json:"date"`
+	CapturedAt time.Time                     `json:"captured_at"`
+	Provider   string                        `json:"provider,omitempty"` // Provider that published the captured rates
+	Rates      map[string]map[string]float64 `json:"rates"`              // base -> quote -> rate
+
+	Corrections []Correction `json:"corrections,omitempty"` // Rates replaced by operators since the capture
+}
+
+// Correction records an operator replacing a captured rate
+type Correction struct {
+	From         string    `json:"from"`
+	To           string    `json:"to"`
+	PreviousRate float64   `json:"previous_rate"`
+	Rate         float64   `json:"rate"`
+	By           string    `json:"by"`
+	At           time.Time `json:"at"`
+}
+
+// Rate returns the captured rate of a pair
+func (s *Snapshot) Rate(from, to string) (float64, bool) {
+	rate, ok := s.Rates[from][to]
+	return rate, ok
+}
+
+// Archive keeps one end-of-day snapshot per date. When dir is set every
+// snapshot is also written to dir/<date>.json, and existing files are loaded
+// on start so the archive survives restarts.
+type Archive struct {
+	mu        sync.RWMutex
+	dir       string
+	snapshots map[string]*Snapshot
+}
+
+// NewArchive creates an archive backed by dir, or an in-memory one when dir
+// is empty
+func NewArchive(dir string) (*Archive, error) {
+	archive := &Archive{
+		dir:       dir,
+		snapshots: make(map[string]*Snapshot),
+	}
+	if dir == "" {
+		return archive, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	if err := archive.load(); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// Save stores a snapshot, replacing any earlier snapshot of the same date
+func (a *Archive) Save(snapshot *Snapshot) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.save(snapshot)
+}
+
+// Correct replaces the captured rate of a pair on date, and the reverse
+// pair's with its inverse, recording the previous rate and who replaced it.
+// It returns false when the snapshot of date holds neither pair.
+func (a *Archive) Correct(date, from, to string, rate float64, by string, at time.Time) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	snapshot, ok := a.snapshots[date]
+	if !ok {
+		return false, nil
+	}
+	previous, direct := snapshot.Rate(from, to)
+	inverse, reverse := snapshot.Rate(to, from)
+	if !direct && !reverse {
+		return false, nil
+	}
+	if !direct && inverse != 0 {
+		previous = 1 / inverse
+	}
+
+	// Readers may hold the current snapshot, so a corrected copy replaces it
+	corrected := *snapshot
+	corrected.Rates = make(map[string]map[string]float64, len(snapshot.Rates))
+	for base, quotes := range snapshot.Rates {
+		corrected.Rates[base] = quotes
+	}
+	replace := func(base, quote string, rate float64) {
+		quotes := make(map[string]float64, len(corrected.Rates[base]))
+		for currency, value := range corrected.Rates[base] {
+			quotes[currency] = value
+		}
+		quotes[quote] = rate
+		corrected.Rates[base] = quotes
+	}
+	if direct {
+		replace(from, to, rate)
+	}
+	if reverse {
+		replace(to, from, 1/rate)
+	}
+	corrected.Corrections = append(append([]Correction{}, snapshot.Corrections...), Correction{
+		From:         from,
+		To:           to,
+		PreviousRate: previous,
+		Rate:         rate,
+		By:           by,
+		At:           at,
+	})
+	return true, a.save(&corrected)
+}
+
+func (a *Archive) save(snapshot *Snapshot) error {
+	if a.dir != "" {
+		data, err := json.MarshalIndent(snapshot, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode snapshot %s: %w", snapshot.Date, err)
+		}
+
+		// Write then rename so a crash never leaves a truncated snapshot behind
+		path := filepath.Join(a.dir, snapshot.Date+".json")
+		if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+			return fmt.Errorf("failed to write snapshot %s: %w", snapshot.Date, err)
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return fmt.Errorf("failed to write snapshot %s: %w", snapshot.Date, err)
+		}
+	}
+
+	a.snapshots[snapshot.Date] = snapshot
+	return nil
+}
+
+// Get returns the snapshot of date
+func (a *Archive) Get(date string) (*Snapshot, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	snapshot, ok := a.snapshots[date]
+	return snapshot, ok
+}
+
+// Dates returns the archived dates in ascending order
+func (a *Archive) Dates() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	dates := make([]string, 0, len(a.snapshots))
+	for date := range a.snapshots {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// Len returns the number of archived days
+func (a *Archive) Len() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.snapshots)
+}
+
+func (a *Archive) load() error {
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read archive directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(a.dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot %s: %w", entry.Name(), err)
+		}
+
+		var snapshot Snapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return fmt.Errorf("failed to decode snapshot %s: %w", entry.Name(), err)
+		}
+		a.snapshots[snapshot.Date] = &snapshot
+	}
+	return nil
+}
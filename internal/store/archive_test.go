@@ -0,0 +1,93 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchive_InMemory(t *testing.T) {
+	archive, err := NewArchive("")
+	require.NoError(t, err)
+
+	require.NoError(t, archive.Save(&Snapshot{
+		Date:  "2025-01-02",
+		Rates: map[string]map[string]float64{"USD": {"INR": 85.5}},
+	}))
+
+	snapshot, found := archive.Get("2025-01-02")
+	require.True(t, found)
+	rate, ok := snapshot.Rate("USD", "INR")
+	assert.True(t, ok)
+	assert.Equal(t, 85.5, rate)
+
+	_, ok = snapshot.Rate("INR", "USD")
+	assert.False(t, ok)
+
+	_, found = archive.Get("2025-01-03")
+	assert.False(t, found)
+}
+
+func TestArchive_PersistsToDirectory(t *testing.T) {
+	dir := t.TempDir()
+	capturedAt := time.Date(2025, 1, 2, 23, 59, 0, 0, time.UTC)
+
+	archive, err := NewArchive(dir)
+	require.NoError(t, err)
+	require.NoError(t, archive.Save(&Snapshot{
+		Date:       "2025-01-02",
+		CapturedAt: capturedAt,
+		Rates:      map[string]map[string]float64{"EUR": {"USD": 1.03}},
+	}))
+	require.NoError(t, archive.Save(&Snapshot{Date: "2025-01-01"}))
+
+	reopened, err := NewArchive(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2025-01-01", "2025-01-02"}, reopened.Dates())
+
+	snapshot, found := reopened.Get("2025-01-02")
+	require.True(t, found)
+	assert.True(t, capturedAt.Equal(snapshot.CapturedAt))
+	rate, _ := snapshot.Rate("EUR", "USD")
+	assert.Equal(t, 1.03, rate)
+}
+
+func TestArchive_Correct(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := NewArchive(dir)
+	require.NoError(t, err)
+	require.NoError(t, archive.Save(&Snapshot{
+		Date:  "2025-01-02",
+		Rates: map[string]map[string]float64{"USD": {"INR": 8.55, "EUR": 0.97}, "INR": {"USD": 0.117}},
+	}))
+	original, _ := archive.Get("2025-01-02")
+	at := time.Date(2025, 1, 3, 9, 0, 0, 0, time.UTC)
+
+	corrected, err := archive.Correct("2025-01-02", "USD", "INR", 85.5, "ops", at)
+	require.NoError(t, err)
+	assert.True(t, corrected)
+
+	rate, _ := original.Rate("USD", "INR")
+	assert.Equal(t, 8.55, rate, "snapshots already handed out are left alone")
+
+	reopened, err := NewArchive(dir)
+	require.NoError(t, err)
+	snapshot, _ := reopened.Get("2025-01-02")
+	rate, _ = snapshot.Rate("USD", "INR")
+	assert.Equal(t, 85.5, rate)
+	rate, _ = snapshot.Rate("INR", "USD")
+	assert.InDelta(t, 1/85.5, rate, 1e-12, "the reverse pair is corrected too")
+	rate, _ = snapshot.Rate("USD", "EUR")
+	assert.Equal(t, 0.97, rate)
+	require.Len(t, snapshot.Corrections, 1)
+	assert.Equal(t, Correction{From: "USD", To: "INR", PreviousRate: 8.55, Rate: 85.5, By: "ops", At: at}, snapshot.Corrections[0])
+
+	corrected, err = archive.Correct("2025-01-02", "GBP", "JPY", 190, "ops", at)
+	require.NoError(t, err)
+	assert.False(t, corrected, "a pair the snapshot does not hold")
+	corrected, err = archive.Correct("2025-01-05", "USD", "INR", 85.5, "ops", at)
+	require.NoError(t, err)
+	assert.False(t, corrected, "a day without a snapshot")
+}
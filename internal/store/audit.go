@@ -0,0 +1,399 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ConversionRecord is one audited conversion: what rate was quoted to whom
+type ConversionRecord struct {
+	ID              int64      `json:"id"`
+	Timestamp       time.Time  `json:"timestamp"`
+	Caller          string     `json:"caller"` // API key ID, or client IP for anonymous callers
+	From            string     `json:"from"`
+	To              string     `json:"to"`
+	Amount          float64    `json:"amount"`
+	ConvertedAmount float64    `json:"converted_amount"`
+	Rate            float64    `json:"rate"`
+	MidMarketRate   float64    `json:"mid_market_rate"`
+	MarkupPercent   float64    `json:"markup_percent"`
+	Derived         string     `json:"derived,omitempty"`
+	RateDate        string     `json:"rate_date,omitempty"`
+	RateTimestamp   *time.Time `json:"rate_timestamp,omitempty"`
+
+	Trail *RoundingTrail `json:"rounding_trail,omitempty"` // Unset on records written before trails were kept
+
+	// Legs are the hops of a conversion chain, which is recorded once from
+	// its first to its last currency. Unset on single conversions.
+	Legs []ChainLegRecord `json:"legs,omitempty"`
+
+	// ConversionID is the receipt ID returned to the caller, and Result the
+	// response exactly as it was returned. Both are unset on records
+	// written before receipts were kept.
+	ConversionID string          `json:"conversion_id,omitempty"`
+	Result       json.RawMessage `json:"result,omitempty"`
+}
+
+// RoundingTrail holds the values a conversion's amounts were rounded from,
+// so disputes over a cent can be settled by redoing the rounding:
+// ConvertedAmount is UnroundedAmount rounded to Precision decimals in
+// Rounding mode, and FeeAmount is UnroundedFee rounded the same way.
+type RoundingTrail struct {
+	RawRate         float64 `json:"raw_rate"`         // Rate the provider quoted, before markup
+	UnroundedRate   float64 `json:"unrounded_rate"`   // RawRate with markup at full precision
+	UnroundedAmount float64 `json:"unrounded_amount"` // Amount times UnroundedRate
+	Precision       int     `json:"precision"`
+	Rounding        string  `json:"rounding"`
+	RatePrecision   int     `json:"rate_precision"` // Decimals Rate was rounded to from UnroundedRate
+
+	FeePercent   float64 `json:"fee_percent,omitempty"`
+	FeeFixed     float64 `json:"fee_fixed,omitempty"`
+	UnroundedFee float64 `json:"unrounded_fee,omitempty"` // FeePercent of ConvertedAmount plus FeeFixed
+	FeeAmount    float64 `json:"fee_amount,omitempty"`
+	NetAmount    float64 `json:"net_amount,omitempty"` // ConvertedAmount less FeeAmount, rounded
+}
+
+// ChainLegRecord is one audited hop of a conversion chain
+type ChainLegRecord struct {
+	From            string  `json:"from"`
+	To              string  `json:"to"`
+	Amount          float64 `json:"amount"`
+	ConvertedAmount float64 `json:"converted_amount"`
+	Rate            float64 `json:"rate"`
+	MidMarketRate   float64 `json:"mid_market_rate"`
+	MarkupPercent   float64 `json:"markup_percent"`
+	Derived         string  `json:"derived,omitempty"`
+}
+
+// AuditFilter selects audit records. Zero values match everything; Since is
+// inclusive and Until exclusive.
+type AuditFilter struct {
+	Caller string
+	From   string
+	To     string
+	Since  time.Time
+	Until  time.Time
+	Offset int
+	Limit  int
+}
+
+func (f AuditFilter) matches(record *ConversionRecord) bool {
+	return (f.Caller == "" || record.Caller == f.Caller) &&
+		(f.From == "" || record.From == f.From) &&
+		(f.To == "" || record.To == f.To) &&
+		(f.Since.IsZero() || !record.Timestamp.Before(f.Since)) &&
+		(f.Until.IsZero() || record.Timestamp.Before(f.Until))
+}
+
+// AuditLog is an append-only log of conversions. Only the newest records
+// are held in memory, at most maxRecords of them. When backed by a file
+// every record is appended to it as one JSON line, and lookups and queries
+// read older records from the file; without one they are dropped.
+type AuditLog struct {
+	mu         sync.RWMutex
+	path       string
+	file       *os.File
+	size       int64 // Bytes of the file holding complete records
+	maxRecords int
+	records    []ConversionRecord // Newest records, sorted by ID
+	receipts   map[string]int64   // Conversion ID -> record ID, of records in memory
+	nextID     int64
+}
+
+// NewAuditLog opens the audit log at path, or an in-memory one when path is
+// empty, holding at most maxRecords records in memory. A maxRecords below 1
+// holds every record. A last line left unfinished by a crash is truncated,
+// as its conversion was never returned to the caller.
+func NewAuditLog(path string, maxRecords int) (*AuditLog, error) {
+	auditLog := &AuditLog{path: path, maxRecords: maxRecords, nextID: 1, receipts: make(map[string]int64)}
+	if path == "" {
+		return auditLog, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	size, err := truncatePartialLine(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	err = scanAuditFile(file, size, func(record *ConversionRecord) bool {
+		auditLog.index(*record)
+		if record.ID >= auditLog.nextID {
+			auditLog.nextID = record.ID + 1
+		}
+		return true
+	})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	auditLog.file = file
+	auditLog.size = size
+	return auditLog, nil
+}
+
+// Append assigns the record an ID and stores it. The record is only kept
+// once it was durably written.
+func (l *AuditLog) Append(record ConversionRecord) (ConversionRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record.ID = l.nextID
+	if l.file != nil {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return record, fmt.Errorf("failed to encode audit record: %w", err)
+		}
+		if _, err := l.file.Write(append(line, '\n')); err != nil {
+			// Drop what was written of the line, so the next record
+			// doesn't follow a partial one
+			l.file.Truncate(l.size)
+			return record, fmt.Errorf("failed to write audit record: %w", err)
+		}
+		l.size += int64(len(line)) + 1
+	}
+
+	l.index(record)
+	l.nextID++
+	return record, nil
+}
+
+// index adds record to the records in memory, dropping the oldest once
+// there are more than maxRecords. The caller holds l.mu or owns l.
+func (l *AuditLog) index(record ConversionRecord) {
+	if record.ConversionID != "" {
+		l.receipts[record.ConversionID] = record.ID
+	}
+	l.records = append(l.records, record)
+	if l.maxRecords > 0 && len(l.records) > l.maxRecords {
+		dropped := len(l.records) - l.maxRecords
+		for _, old := range l.records[:dropped] {
+			if old.ConversionID != "" {
+				delete(l.receipts, old.ConversionID)
+			}
+		}
+		// Dropped records are released when append next grows the array
+		l.records = l.records[dropped:]
+	}
+}
+
+// Get returns the record with the given ID
+func (l *AuditLog) Get(id int64) (ConversionRecord, bool, error) {
+	l.mu.RLock()
+	// IDs are assigned in order, so the records are sorted by ID
+	i := sort.Search(len(l.records), func(i int) bool { return l.records[i].ID >= id })
+	if i < len(l.records) && l.records[i].ID == id {
+		record := l.records[i]
+		l.mu.RUnlock()
+		return record, true, nil
+	}
+	older := i == 0 && id > 0
+	l.mu.RUnlock()
+
+	if !older {
+		return ConversionRecord{}, false, nil
+	}
+	return l.find(func(record *ConversionRecord) bool { return record.ID == id })
+}
+
+// GetReceipt returns the record of the conversion the receipt ID
+// conversionID was returned for
+func (l *AuditLog) GetReceipt(conversionID string) (ConversionRecord, bool, error) {
+	if conversionID == "" {
+		return ConversionRecord{}, false, nil
+	}
+
+	l.mu.RLock()
+	if id, ok := l.receipts[conversionID]; ok {
+		i := sort.Search(len(l.records), func(i int) bool { return l.records[i].ID >= id })
+		record := l.records[i]
+		l.mu.RUnlock()
+		return record, true, nil
+	}
+	l.mu.RUnlock()
+
+	return l.find(func(record *ConversionRecord) bool { return record.ConversionID == conversionID })
+}
+
+// find returns the first record of the file match accepts, if the log has
+// one
+func (l *AuditLog) find(match func(record *ConversionRecord) bool) (ConversionRecord, bool, error) {
+	if l.path == "" {
+		return ConversionRecord{}, false, nil
+	}
+
+	var found ConversionRecord
+	ok := false
+	err := l.scan(func(record *ConversionRecord) bool {
+		if match(record) {
+			found, ok = *record, true
+		}
+		return !ok
+	})
+	return found, ok, err
+}
+
+// Query returns the page of matching records selected by the filter's Offset
+// and Limit, newest first, together with the total number of matches. A
+// file-backed log is paged from the file, so older records than those held
+// in memory are found too.
+func (l *AuditLog) Query(filter AuditFilter) ([]ConversionRecord, int, error) {
+	l.mu.RLock()
+	if l.path == "" {
+		defer l.mu.RUnlock()
+		page, total := queryRecords(l.records, filter)
+		return page, total, nil
+	}
+	l.mu.RUnlock()
+
+	// The file is read oldest first: count the matches, then collect those
+	// that fall on the page when counted from the newest
+	total := 0
+	err := l.scan(func(record *ConversionRecord) bool {
+		if filter.matches(record) {
+			total++
+		}
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page := []ConversionRecord{}
+	last := total - filter.Offset // Matches before the page's newest, counted from the oldest
+	first := 0
+	if filter.Limit > 0 {
+		first = last - filter.Limit
+	}
+	if last <= 0 {
+		return page, total, nil
+	}
+	n := 0
+	err = l.scan(func(record *ConversionRecord) bool {
+		if !filter.matches(record) {
+			return true
+		}
+		if n >= first && n < last {
+			page = append(page, *record)
+		}
+		n++
+		return n < last
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, j := 0, len(page)-1; i < j; i, j = i+1, j-1 {
+		page[i], page[j] = page[j], page[i]
+	}
+	return page, total, nil
+}
+
+// queryRecords pages the matching records, newest first
+func queryRecords(records []ConversionRecord, filter AuditFilter) ([]ConversionRecord, int) {
+	page := []ConversionRecord{}
+	total := 0
+	for i := len(records) - 1; i >= 0; i-- {
+		record := &records[i]
+		if !filter.matches(record) {
+			continue
+		}
+		if total >= filter.Offset && (filter.Limit <= 0 || len(page) < filter.Limit) {
+			page = append(page, *record)
+		}
+		total++
+	}
+	return page, total
+}
+
+// scan passes the records of the file to fn, oldest first, until fn returns
+// false. It reads the records written so far through a handle of its own,
+// without holding l.mu, so appends carry on meanwhile.
+func (l *AuditLog) scan(fn func(record *ConversionRecord) bool) error {
+	l.mu.RLock()
+	size := l.size
+	l.mu.RUnlock()
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer file.Close()
+	return scanAuditFile(file, size, fn)
+}
+
+// scanAuditFile decodes the first size bytes of file as JSON lines, passing
+// every record to fn until it returns false
+func scanAuditFile(file *os.File, size int64, fn func(record *ConversionRecord) bool) error {
+	scanner := bufio.NewScanner(io.LimitReader(file, size))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		var record ConversionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("failed to decode audit record %d: %w", n, err)
+		}
+		if !fn(&record) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return nil
+}
+
+// truncatePartialLine cuts file after its last newline, dropping a record
+// whose write was cut short, and returns the size left
+func truncatePartialLine(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	size := info.Size()
+	complete, end := int64(0), size
+	buf := make([]byte, 4096)
+	for end > 0 {
+		start := max(end-int64(len(buf)), 0)
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil {
+			return 0, fmt.Errorf("failed to read audit log: %w", err)
+		}
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			complete = start + int64(i) + 1
+			break
+		}
+		end = start
+	}
+	if complete == size {
+		return size, nil
+	}
+	if err := file.Truncate(complete); err != nil {
+		return 0, fmt.Errorf("failed to truncate unfinished audit record: %w", err)
+	}
+	return complete, nil
+}
+
+// Close closes the backing file
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,232 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog_QueryFiltersAndPages(t *testing.T) {
+	for name, path := range map[string]string{"Memory": "", "File": "audit.jsonl"} {
+		t.Run(name, func(t *testing.T) {
+			if path != "" {
+				path = filepath.Join(t.TempDir(), path)
+			}
+			log, err := NewAuditLog(path, 0)
+			require.NoError(t, err)
+			defer log.Close()
+
+			start := time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)
+			for i, caller := range []string{"alice", "bob", "alice", "alice"} {
+				_, err := log.Append(ConversionRecord{
+					Timestamp: start.Add(time.Duration(i) * time.Hour),
+					Caller:    caller,
+					From:      "USD",
+					To:        "INR",
+					Amount:    float64(i + 1),
+				})
+				require.NoError(t, err)
+			}
+
+			records, total, err := log.Query(AuditFilter{Caller: "alice", Limit: 2})
+			require.NoError(t, err)
+			assert.Equal(t, 3, total)
+			require.Len(t, records, 2)
+			assert.Equal(t, int64(4), records[0].ID, "newest first")
+			assert.Equal(t, int64(3), records[1].ID)
+
+			records, total, err = log.Query(AuditFilter{Caller: "alice", Offset: 2, Limit: 2})
+			require.NoError(t, err)
+			assert.Equal(t, 3, total)
+			require.Len(t, records, 1)
+			assert.Equal(t, int64(1), records[0].ID)
+
+			records, total, err = log.Query(AuditFilter{Since: start.Add(time.Hour), Until: start.Add(3 * time.Hour)})
+			require.NoError(t, err)
+			assert.Equal(t, 2, total)
+			assert.Equal(t, "alice", records[0].Caller)
+			assert.Equal(t, "bob", records[1].Caller)
+
+			records, total, err = log.Query(AuditFilter{Caller: "alice", Offset: 5})
+			require.NoError(t, err)
+			assert.Equal(t, 3, total)
+			assert.Empty(t, records)
+
+			records, total, err = log.Query(AuditFilter{From: "EUR"})
+			require.NoError(t, err)
+			assert.Equal(t, 0, total)
+			assert.NotNil(t, records)
+		})
+	}
+}
+
+func TestAuditLog_MaxRecords(t *testing.T) {
+	log, err := NewAuditLog("", 2)
+	require.NoError(t, err)
+	for _, id := range []string{"first", "second", "third"} {
+		_, err := log.Append(ConversionRecord{Caller: "alice", ConversionID: id})
+		require.NoError(t, err)
+	}
+
+	records, total, err := log.Query(AuditFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total, "only the newest records are held")
+	assert.Equal(t, int64(3), records[0].ID)
+	_, ok, err := log.Get(1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, ok, err = log.GetReceipt("first")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, ok, err = log.GetReceipt("third")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	backed, err := NewAuditLog(path, 2)
+	require.NoError(t, err)
+	for _, id := range []string{"first", "second", "third"} {
+		_, err := backed.Append(ConversionRecord{Caller: "alice", ConversionID: id})
+		require.NoError(t, err)
+	}
+	require.NoError(t, backed.Close())
+
+	reopened, err := NewAuditLog(path, 2)
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Len(t, reopened.records, 2, "replaying the file holds only the newest records")
+
+	records, total, err = reopened.Query(AuditFilter{Offset: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 3, total, "queries page through the file")
+	require.Len(t, records, 1)
+	assert.Equal(t, int64(1), records[0].ID)
+	record, ok, err := reopened.Get(1)
+	require.NoError(t, err)
+	require.True(t, ok, "older records are read from the file")
+	assert.Equal(t, "first", record.ConversionID)
+	record, ok, err = reopened.GetReceipt("first")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, int64(1), record.ID)
+
+	record, err = reopened.Append(ConversionRecord{Caller: "bob"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), record.ID)
+}
+
+func TestAuditLog_ReplaysFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	log, err := NewAuditLog(path, 0)
+	require.NoError(t, err)
+	_, err = log.Append(ConversionRecord{Caller: "alice", From: "USD", To: "INR", Rate: 83.5})
+	require.NoError(t, err)
+	require.NoError(t, log.Close())
+
+	reopened, err := NewAuditLog(path, 0)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	record, err := reopened.Append(ConversionRecord{Caller: "bob"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), record.ID)
+
+	records, total, err := reopened.Query(AuditFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, 83.5, records[1].Rate)
+}
+
+func TestAuditLog_TruncatesUnfinishedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	log, err := NewAuditLog(path, 0)
+	require.NoError(t, err)
+	_, err = log.Append(ConversionRecord{Caller: "alice", From: "USD", To: "INR", Rate: 83.5})
+	require.NoError(t, err)
+	require.NoError(t, log.Close())
+
+	// A crash mid-write leaves the start of a record without its newline
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"id":2,"timestamp":"2025-01-`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	reopened, err := NewAuditLog(path, 0)
+	require.NoError(t, err, "an unfinished last record doesn't keep the log from opening")
+	record, err := reopened.Append(ConversionRecord{Caller: "bob"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), record.ID)
+	require.NoError(t, reopened.Close())
+
+	reopened, err = NewAuditLog(path, 0)
+	require.NoError(t, err, "the next record starts on a line of its own")
+	defer reopened.Close()
+	records, total, err := reopened.Query(AuditFilter{})
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+	assert.Equal(t, "bob", records[0].Caller)
+	assert.Equal(t, "alice", records[1].Caller)
+}
+
+func TestAuditLog_GetKeepsRoundingTrail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	log, err := NewAuditLog(path, 0)
+	require.NoError(t, err)
+	trail := &RoundingTrail{RawRate: 83.123456789, UnroundedRate: 83.123456789, UnroundedAmount: 831.23456789, Precision: 2, Rounding: "bankers", RatePrecision: 6}
+	_, err = log.Append(ConversionRecord{Caller: "alice", ConvertedAmount: 831.23, Trail: trail})
+	require.NoError(t, err)
+	_, err = log.Append(ConversionRecord{Caller: "bob"})
+	require.NoError(t, err)
+	require.NoError(t, log.Close())
+
+	reopened, err := NewAuditLog(path, 0)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	record, ok, err := reopened.Get(1)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, trail, record.Trail)
+	record, ok, err = reopened.Get(2)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Nil(t, record.Trail)
+	_, ok, err = reopened.Get(3)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestAuditLog_GetReceipt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	log, err := NewAuditLog(path, 0)
+	require.NoError(t, err)
+	_, err = log.Append(ConversionRecord{Caller: "alice", From: "USD", To: "INR"})
+	require.NoError(t, err)
+	_, err = log.Append(ConversionRecord{Caller: "bob", ConversionID: "receipt", Result: []byte(`{"converted_amount":835}`)})
+	require.NoError(t, err)
+	require.NoError(t, log.Close())
+
+	reopened, err := NewAuditLog(path, 0)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	record, ok, err := reopened.GetReceipt("receipt")
+	require.NoError(t, err)
+	require.True(t, ok, "receipts are indexed when the file is replayed")
+	assert.Equal(t, int64(2), record.ID)
+	assert.Equal(t, "bob", record.Caller)
+	assert.JSONEq(t, `{"converted_amount":835}`, string(record.Result))
+
+	_, ok, err = reopened.GetReceipt("")
+	require.NoError(t, err)
+	assert.False(t, ok, "records written before receipts have none")
+}
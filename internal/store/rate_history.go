@@ -0,0 +1,111 @@
+package store
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Observation is a rate as published at a point in time
+type Observation struct {
+	Rate      float64
+	Timestamp time.Time
+}
+
+// RateHistory keeps the intraday rates observed for each pair for a
+// retention period, so conversions can use the rate in force at an instant.
+// It is in-memory only; end-of-day rates are kept by the Archive.
+type RateHistory struct {
+	retention time.Duration
+
+	mu    sync.RWMutex
+	pairs map[string][]Observation // "FROM_TO" -> observations, oldest first
+}
+
+// NewRateHistory creates a history that forgets observations older than
+// retention
+func NewRateHistory(retention time.Duration) *RateHistory {
+	return &RateHistory{
+		retention: retention,
+		pairs:     make(map[string][]Observation),
+	}
+}
+
+// Record stores the rate of a pair published at timestamp. Observations
+// older than the latest one of the pair are ignored, and one with the same
+// timestamp replaces it.
+func (h *RateHistory) Record(from, to string, rate float64, timestamp time.Time) {
+	key := from + "_" + to
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	observations := h.pairs[key]
+	if n := len(observations); n > 0 {
+		last := observations[n-1].Timestamp
+		if timestamp.Before(last) {
+			return
+		}
+		if timestamp.Equal(last) {
+			observations[n-1].Rate = rate
+			return
+		}
+	}
+	observations = append(observations, Observation{Rate: rate, Timestamp: timestamp})
+
+	// Drop what fell out of the retention period
+	cutoff := timestamp.Add(-h.retention)
+	expired := sort.Search(len(observations), func(i int) bool {
+		return !observations[i].Timestamp.Before(cutoff)
+	})
+	if expired > 0 {
+		observations = append([]Observation(nil), observations[expired:]...)
+	}
+	h.pairs[key] = observations
+}
+
+// At returns the latest observation of a pair at or before at
+func (h *RateHistory) At(from, to string, at time.Time) (Observation, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	observations := h.pairs[from+"_"+to]
+	i := sort.Search(len(observations), func(i int) bool {
+		return observations[i].Timestamp.After(at)
+	})
+	if i == 0 {
+		return Observation{}, false
+	}
+	return observations[i-1], true
+}
+
+// Between returns the observations of a pair published from start up to
+// but excluding end, oldest first
+func (h *RateHistory) Between(from, to string, start, end time.Time) []Observation {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	observations := h.pairs[from+"_"+to]
+	first := sort.Search(len(observations), func(i int) bool {
+		return !observations[i].Timestamp.Before(start)
+	})
+	last := sort.Search(len(observations), func(i int) bool {
+		return !observations[i].Timestamp.Before(end)
+	})
+	if first >= last {
+		return nil
+	}
+	return append([]Observation(nil), observations[first:last]...)
+}
+
+// Len returns the number of observations held across all pairs
+func (h *RateHistory) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	total := 0
+	for _, observations := range h.pairs {
+		total += len(observations)
+	}
+	return total
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateHistory_At(t *testing.T) {
+	start := time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)
+	history := NewRateHistory(24 * time.Hour)
+	history.Record("USD", "INR", 83.0, start)
+	history.Record("USD", "INR", 83.5, start.Add(time.Hour))
+	history.Record("USD", "INR", 83.25, start.Add(30*time.Minute).Add(time.Hour))
+
+	tests := []struct {
+		name  string
+		at    time.Time
+		found bool
+		rate  float64
+	}{
+		{"Before the first rate", start.Add(-time.Second), false, 0},
+		{"Exactly at a rate", start, true, 83.0},
+		{"Between rates", start.Add(59 * time.Minute), true, 83.0},
+		{"After the last rate", start.Add(5 * time.Hour), true, 83.25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observation, found := history.At("USD", "INR", tt.at)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.rate, observation.Rate)
+		})
+	}
+
+	_, found := history.At("INR", "USD", start.Add(time.Hour))
+	assert.False(t, found)
+}
+
+func TestRateHistory_RecordOrderingAndRetention(t *testing.T) {
+	start := time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)
+	history := NewRateHistory(2 * time.Hour)
+
+	history.Record("USD", "INR", 83.0, start)
+	history.Record("USD", "INR", 83.1, start) // Same publication time replaces
+	history.Record("USD", "INR", 82.0, start.Add(-time.Hour))
+	assert.Equal(t, 1, history.Len(), "older observations are ignored")
+
+	observation, _ := history.At("USD", "INR", start)
+	assert.Equal(t, 83.1, observation.Rate)
+
+	history.Record("USD", "INR", 84.0, start.Add(3*time.Hour))
+	assert.Equal(t, 1, history.Len(), "observations past the retention are dropped")
+	_, found := history.At("USD", "INR", start.Add(time.Hour))
+	assert.False(t, found)
+}
+
+func TestRateHistory_Between(t *testing.T) {
+	day := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	history := NewRateHistory(72 * time.Hour)
+	history.Record("USD", "INR", 83.0, day.Add(-time.Minute))
+	history.Record("USD", "INR", 83.1, day)
+	history.Record("USD", "INR", 83.2, day.Add(9*time.Hour+time.Minute))
+	history.Record("USD", "INR", 83.3, day.Add(24*time.Hour))
+
+	observations := history.Between("USD", "INR", day, day.Add(24*time.Hour))
+	assert.Equal(t, []Observation{
+		{Rate: 83.1, Timestamp: day},
+		{Rate: 83.2, Timestamp: day.Add(9*time.Hour + time.Minute)},
+	}, observations, "the start is included and the end excluded")
+
+	assert.Empty(t, history.Between("USD", "INR", day.Add(2*24*time.Hour), day.Add(3*24*time.Hour)))
+	assert.Empty(t, history.Between("INR", "USD", day, day.Add(24*time.Hour)))
+}
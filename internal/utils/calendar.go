@@ -0,0 +1,49 @@
+package utils
+
+import "time"
+
+var holidays = map[string]map[string]bool{} // Currency -> YYYY-MM-DD -> closed
+
+// SetHolidays sets the market holidays of each currency, as YYYY-MM-DD
+// dates. Rates involving a currency are not published on its holidays.
+func SetHolidays(byCurrency map[string][]string) {
+	calendar := make(map[string]map[string]bool, len(byCurrency))
+	for currency, dates := range byCurrency {
+		days := make(map[string]bool, len(dates))
+		for _, date := range dates {
+			days[date] = true
+		}
+		calendar[currency] = days
+	}
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	holidays = calendar
+}
+
+// IsTradingDay reports whether rates between currencies are published on
+// date: it is a market day and a holiday of none of them
+func IsTradingDay(date time.Time, currencies ...string) bool {
+	if !IsMarketDay(date) {
+		return false
+	}
+
+	day := date.Format(DateFormat)
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	for _, currency := range currencies {
+		if holidays[currency][day] {
+			return false
+		}
+	}
+	return true
+}
+
+// LastTradingDay returns date itself when it is a trading day of currencies,
+// otherwise the closest earlier one
+func LastTradingDay(date time.Time, currencies ...string) time.Time {
+	for !IsTradingDay(date, currencies...) {
+		date = date.AddDate(0, 0, -1)
+	}
+	return date
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+
+	"exchange-rate-service/internal/models"
+)
+
+// maxRateFractionDigits is the precision formatted rates are shown with
+const maxRateFractionDigits = 6
+
+// currencyPatterns are the standard currency formats of CLDR 42 of the
+// locales that don't write "¤#,##0.00", which English and every locale
+// missing here use. A locale without a pattern of its own takes its
+// parent's, so es-MX writes es-419's and de-DE de's. ¤ stands for the
+// symbol, and the pattern after ";" is the one of negative amounts; without
+// one, a minus sign precedes the positive pattern. Only the placement of the
+// symbol and the sign is taken from them: digits are grouped by x/text.
+var currencyPatterns = map[string]string{
+	"az": "#,##0.00\u00a0¤", "be": "#,##0.00\u00a0¤", "bg": "#,##0.00\u00a0¤",
+	"bs": "#,##0.00\u00a0¤", "ca": "#,##0.00\u00a0¤", "cs": "#,##0.00\u00a0¤",
+	"da": "#,##0.00\u00a0¤", "de": "#,##0.00\u00a0¤", "el": "#,##0.00\u00a0¤",
+	"es": "#,##0.00\u00a0¤", "et": "#,##0.00\u00a0¤", "eu": "#,##0.00\u00a0¤",
+	"fi": "#,##0.00\u00a0¤", "fr": "#,##0.00\u00a0¤", "gl": "#,##0.00\u00a0¤",
+	"hr": "#,##0.00\u00a0¤", "hu": "#,##0.00\u00a0¤", "hy": "#,##0.00\u00a0¤",
+	"is": "#,##0.00\u00a0¤", "it": "#,##0.00\u00a0¤", "ka": "#,##0.00\u00a0¤",
+	"kk": "#,##0.00\u00a0¤", "lt": "#,##0.00\u00a0¤", "lv": "#,##0.00\u00a0¤",
+	"mk": "#,##0.00\u00a0¤", "nb": "#,##0.00\u00a0¤", "no": "#,##0.00\u00a0¤",
+	"pl": "#,##0.00\u00a0¤", "ro": "#,##0.00\u00a0¤", "ru": "#,##0.00\u00a0¤",
+	"sk": "#,##0.00\u00a0¤", "sl": "#,##0.00\u00a0¤", "sq": "#,##0.00\u00a0¤",
+	"sr": "#,##0.00\u00a0¤", "sv": "#,##0.00\u00a0¤", "uk": "#,##0.00\u00a0¤",
+	"vi": "#,##0.00\u00a0¤",
+
+	"de-AT": "¤\u00a0#,##0.00", "de-CH": "¤\u00a0#,##0.00;¤-#,##0.00", "de-LI": "¤\u00a0#,##0.00",
+	"en-AT": "¤\u00a0#,##0.00", "en-CH": "¤\u00a0#,##0.00;¤-#,##0.00", "en-NL": "¤\u00a0#,##0.00;¤\u00a0-#,##0.00",
+	"en-BE": "#,##0.00\u00a0¤", "en-DE": "#,##0.00\u00a0¤", "en-DK": "#,##0.00\u00a0¤",
+	"en-FI": "#,##0.00\u00a0¤", "en-SE": "#,##0.00\u00a0¤", "en-SI": "#,##0.00\u00a0¤",
+	"es-419": "¤#,##0.00",
+	"it-CH":  "¤\u00a0#,##0.00;¤-#,##0.00",
+	"nl":     "¤\u00a0#,##0.00;¤\u00a0-#,##0.00",
+	"pt":     "¤\u00a0#,##0.00", "pt-PT": "#,##0.00\u00a0¤",
+}
+
+// defaultCurrencyPattern is the pattern of English, for locales without one
+const defaultCurrencyPattern = "¤#,##0.00"
+
+// ParseLocale parses a BCP 47 locale such as "en-IN"; underscores ("de_DE")
+// are accepted as well
+func ParseLocale(locale string) (language.Tag, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return language.Und, &models.CodedError{
+			Code:    models.ErrCodeLocaleInvalid,
+			Field:   "locale",
+			Message: fmt.Sprintf("invalid locale %q: %v", locale, err),
+			Err:     err,
+		}
+	}
+	return tag, nil
+}
+
+// FormatMoney formats amount in the given currency the way tag's locale
+// writes it, rounded to the currency's minor unit (no decimals for JPY).
+// Codes unknown to CLDR are shown as-is with two decimals.
+func FormatMoney(tag language.Tag, amount float64, code string) string {
+	printer := message.NewPrinter(tag)
+
+	symbol := code
+	scale := 2
+	if unit, err := currency.ParseISO(code); err == nil {
+		symbol = printer.Sprint(currency.Symbol(unit))
+		scale, _ = currency.Standard.Rounding(unit)
+	}
+	// CLDR rounds metals like money, which hides small weights of gold
+	if models.IsMetal(code) {
+		scale = models.CurrencyMetadata[code].DecimalPlaces
+	}
+
+	digits := printer.Sprint(number.Decimal(math.Abs(amount), number.Scale(scale)))
+	sign := ""
+	if amount < 0 && digits != printer.Sprint(number.Decimal(0, number.Scale(scale))) {
+		sign = "-"
+	}
+
+	prefix, suffix := currencyAffixes(tag, sign != "")
+	return strings.ReplaceAll(prefix, "¤", symbol) + digits + strings.ReplaceAll(suffix, "¤", symbol)
+}
+
+// currencyAffixes returns what tag's currency pattern writes before and
+// after the digits of a positive or negative amount
+func currencyAffixes(tag language.Tag, negative bool) (prefix, suffix string) {
+	// Extensions such as -u-nu- don't change the pattern
+	base, script, region := tag.Raw()
+	tag, _ = language.Compose(base, script, region)
+
+	pattern := defaultCurrencyPattern
+	for ; ; tag = tag.Parent() {
+		if p, ok := currencyPatterns[tag.String()]; ok {
+			pattern = p
+			break
+		}
+		if tag.IsRoot() {
+			break
+		}
+	}
+
+	positive, negativePattern, explicit := strings.Cut(pattern, ";")
+	if negative && explicit {
+		return splitPattern(negativePattern)
+	}
+	prefix, suffix = splitPattern(positive)
+	if negative {
+		prefix = "-" + prefix
+	}
+	return prefix, suffix
+}
+
+// splitPattern returns what a number pattern has before and after its digits
+func splitPattern(pattern string) (prefix, suffix string) {
+	start, end := strings.IndexAny(pattern, "#0"), strings.LastIndexAny(pattern, "#0")+1
+	return pattern[:start], pattern[end:]
+}
+
+// FormatRate formats an exchange rate with tag's digit grouping and decimal
+// separator
+func FormatRate(tag language.Tag, rate float64) string {
+	return message.NewPrinter(tag).Sprint(number.Decimal(rate, number.MaxFractionDigits(maxRateFractionDigits)))
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		locale   string
+		amount   float64
+		currency string
+		expected string
+	}{
+		{"en-IN", 8350, "INR", "₹8,350.00"},
+		{"en-IN", 1234567.891, "INR", "₹12,34,567.89"},
+		{"de-DE", 1234.56, "EUR", "1.234,56\u00a0€"},
+		{"de_DE", 1234.56, "EUR", "1.234,56\u00a0€"},
+		{"fr", 1234.56, "EUR", "1\u00a0234,56\u00a0€"},
+		{"en-US", 1234.7, "JPY", "¥1,235"},
+		{"ja", 100, "JPY", "￥100"},
+		{"en-US", -12.5, "USD", "-$12.50"},
+		{"en-US", -0.001, "USD", "$0.00"},
+		{"en-US", 10, "ABC", "ABC10.00"},
+		{"en-US", 0.41237, "XAU", "XAU0.4124"},
+		{"es-MX", 1234.56, "MXN", "$1,234.56"},
+		{"es-ES", 1234.56, "EUR", "1.234,56\u00a0€"},
+		{"de-CH", 1234.56, "CHF", "CHF\u00a01’234.56"},
+		{"de-CH", -1234.56, "CHF", "CHF-1’234.56"},
+		{"de-AT", 1234.56, "EUR", "€\u00a01\u00a0234,56"},
+		{"nl", 1234.56, "EUR", "€\u00a01.234,56"},
+		{"nl-NL", -1234.56, "EUR", "€\u00a0-1.234,56"},
+		{"pt-BR", 1234.56, "BRL", "R$\u00a01.234,56"},
+		{"pt-PT", 1234.56, "EUR", "1\u00a0234,56\u00a0€"},
+		{"de-DE-u-nu-latn", 1234.56, "EUR", "1.234,56\u00a0€"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale+" "+tt.currency, func(t *testing.T) {
+			tag, err := ParseLocale(tt.locale)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, FormatMoney(tag, tt.amount, tt.currency))
+		})
+	}
+}
+
+func TestFormatRate(t *testing.T) {
+	tag, err := ParseLocale("de")
+	require.NoError(t, err)
+	assert.Equal(t, "83,5", FormatRate(tag, 83.5))
+	assert.Equal(t, "0,012048", FormatRate(tag, 0.0120481927))
+}
+
+func TestParseLocale_Invalid(t *testing.T) {
+	for _, locale := range []string{"xx", "en-", "not a locale"} {
+		_, err := ParseLocale(locale)
+		assert.Error(t, err, locale)
+	}
+}
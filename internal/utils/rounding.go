@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"exchange-rate-service/internal/models"
+)
+
+// CurrencyPrecision returns the minor units of a currency, 2 when unknown
+func CurrencyPrecision(code string) int {
+	if info, ok := models.CurrencyMetadata[code]; ok {
+		return info.DecimalPlaces
+	}
+	return 2
+}
+
+// ValidateRounding checks a rounding mode, empty meaning the default
+func ValidateRounding(mode string) error {
+	switch mode {
+	case "", models.RoundingHalfUp, models.RoundingBankers, models.RoundingTruncate:
+		return nil
+	}
+	return models.NewFieldError(models.ErrCodeValueInvalid, "rounding",
+		"rounding must be one of half_up, bankers or truncate")
+}
+
+// Round rounds value to precision decimals in the given mode. It works on
+// the shortest decimal form of value, so 2.675 rounds half up to 2.68 even
+// though its binary form is slightly below.
+func Round(value float64, precision int, mode string) float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return value
+	}
+
+	text := strconv.FormatFloat(math.Abs(value), 'f', -1, 64)
+	whole, fraction, _ := strings.Cut(text, ".")
+	if len(fraction) <= precision {
+		return value
+	}
+
+	kept, dropped := fraction[:precision], fraction[precision:]
+	digits, _ := new(big.Int).SetString(whole+kept, 10)
+	if roundsUp(dropped, digits.Bit(0) == 1, mode) {
+		digits.Add(digits, big.NewInt(1))
+	}
+
+	rounded := digits.String()
+	if precision > 0 {
+		if len(rounded) <= precision {
+			rounded = strings.Repeat("0", precision-len(rounded)+1) + rounded
+		}
+		rounded = rounded[:len(rounded)-precision] + "." + rounded[len(rounded)-precision:]
+	}
+	result, _ := strconv.ParseFloat(rounded, 64)
+	return math.Copysign(result, value)
+}
+
+// roundsUp reports whether dropping the digits moves the magnitude up; odd
+// tells bankers rounding whether the last kept digit is odd
+func roundsUp(dropped string, odd bool, mode string) bool {
+	switch mode {
+	case models.RoundingTruncate:
+		return false
+	case models.RoundingBankers:
+		if dropped[0] != '5' {
+			return dropped[0] > '5'
+		}
+		if strings.TrimRight(dropped[1:], "0") != "" {
+			return true
+		}
+		return odd
+	default:
+		return dropped[0] >= '5'
+	}
+}
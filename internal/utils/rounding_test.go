@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"exchange-rate-service/internal/models"
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     float64
+		precision int
+		mode      string
+		want      float64
+	}{
+		{"Half up", 2.675, 2, models.RoundingHalfUp, 2.68},
+		{"Half up below half", 2.674, 2, models.RoundingHalfUp, 2.67},
+		{"Half up negative", -2.675, 2, models.RoundingHalfUp, -2.68},
+		{"Default mode", 1.005, 2, "", 1.01},
+		{"Bankers to even down", 2.665, 2, models.RoundingBankers, 2.66},
+		{"Bankers to even up", 2.675, 2, models.RoundingBankers, 2.68},
+		{"Bankers past half", 2.6651, 2, models.RoundingBankers, 2.67},
+		{"Truncate", 2.679, 2, models.RoundingTruncate, 2.67},
+		{"Truncate negative", -2.679, 2, models.RoundingTruncate, -2.67},
+		{"Zero decimals", 8349.5, 0, models.RoundingHalfUp, 8350},
+		{"Carry into whole", 9.999, 2, models.RoundingHalfUp, 10},
+		{"Below one", 0.0049, 2, models.RoundingHalfUp, 0},
+		{"Small carry", 0.005, 2, models.RoundingHalfUp, 0.01},
+		{"Already short", 1.5, 4, models.RoundingHalfUp, 1.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Round(tt.value, tt.precision, tt.mode))
+		})
+	}
+}
+
+func TestCurrencyPrecision(t *testing.T) {
+	assert.Equal(t, 0, CurrencyPrecision("JPY"))
+	assert.Equal(t, 2, CurrencyPrecision("INR"))
+	assert.Equal(t, 4, CurrencyPrecision("XAU"))
+	assert.Equal(t, 2, CurrencyPrecision("ZZZ"))
+}
@@ -2,20 +2,69 @@ package utils
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"exchange-rate-service/internal/models"
 )
 
 const (
-	DateFormat      = "2006-01-02"
-	MaxLookbackDays = 90
+	DateFormat          = "2006-01-02"
+	DefaultLookbackDays = 90
+	DefaultMaxRangeDays = 90
 )
 
+var (
+	settingsMu        sync.RWMutex
+	referenceLocation = time.UTC
+	lookbackDays      = DefaultLookbackDays
+	maxRangeDays      = DefaultMaxRangeDays
+)
+
+// SetDateLimits sets how far back dates may go and how many days a single
+// historical range may span. Zero lookback means unbounded; the range cap
+// always applies to protect the upstream provider.
+func SetDateLimits(lookback, maxRange int) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	lookbackDays = lookback
+	maxRangeDays = maxRange
+}
+
+// DateLimits returns the lookback window and range cap, in days
+func DateLimits() (lookback, maxRange int) {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return lookbackDays, maxRangeDays
+}
+
+// SetReferenceLocation sets the time zone that defines "today" when
+// validating dates. It defaults to UTC.
+func SetReferenceLocation(loc *time.Location) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	referenceLocation = loc
+}
+
+// ReferenceLocation returns the time zone dates are interpreted in
+func ReferenceLocation() *time.Location {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return referenceLocation
+}
+
+// Today returns midnight of the current day in the reference time zone
+func Today() time.Time {
+	now := time.Now().In(ReferenceLocation())
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
 // ValidateCurrency checks if a currency is supported
 func ValidateCurrency(currency string) error {
-	if !models.SupportedCurrencies[currency] {
-		return fmt.Errorf("unsupported currency: %s. Supported currencies: USD, INR, EUR, JPY, GBP", currency)
+	if !models.IsSupportedCurrency(currency) {
+		return models.NewError(models.ErrCodeCurrencyUnsupported, "unsupported currency: %s. Supported currencies: %s", currency,
+			strings.Join(models.SupportedCurrencyCodes(), ", "))
 	}
 	return nil
 }
@@ -23,10 +72,10 @@ func ValidateCurrency(currency string) error {
 // ValidateCurrencyPair checks if both currencies in a pair are supported
 func ValidateCurrencyPair(from, to string) error {
 	if err := ValidateCurrency(from); err != nil {
-		return fmt.Errorf("invalid 'from' currency: %w", err)
+		return models.ForField(err, "from", "invalid 'from' currency: ")
 	}
 	if err := ValidateCurrency(to); err != nil {
-		return fmt.Errorf("invalid 'to' currency: %w", err)
+		return models.ForField(err, "to", "invalid 'to' currency: ")
 	}
 	return nil
 }
@@ -37,63 +86,87 @@ func ValidateDate(dateStr string) (time.Time, error) {
 		return time.Now(), nil
 	}
 
-	// Parse the date
-	parsedDate, err := time.Parse(DateFormat, dateStr)
+	// Parse the date as a calendar day in the reference time zone
+	parsedDate, err := time.ParseInLocation(DateFormat, dateStr, ReferenceLocation())
 	if err != nil {
-		return time.Time{}, fmt.Errorf("invalid date format. Expected YYYY-MM-DD, got: %s", dateStr)
+		return time.Time{}, models.NewError(models.ErrCodeDateInvalid, "invalid date format. Expected YYYY-MM-DD, got: %s", dateStr)
 	}
 
 	// Check if date is in the future
-	now := time.Now()
-	if parsedDate.After(now) {
-		return time.Time{}, fmt.Errorf("date cannot be in the future: %s", dateStr)
+	today := Today()
+	if parsedDate.After(today) {
+		return time.Time{}, models.NewError(models.ErrCodeDateOutOfRange, "date cannot be in the future: %s", dateStr)
 	}
 
 	// Check if date is beyond the maximum lookback period
-	maxLookbackDate := now.AddDate(0, 0, -MaxLookbackDays)
-	if parsedDate.Before(maxLookbackDate) {
-		return time.Time{}, fmt.Errorf("date is beyond the maximum lookback period of %d days. Earliest allowed date: %s",
-			MaxLookbackDays, maxLookbackDate.Format(DateFormat))
+	lookback, _ := DateLimits()
+	if lookback > 0 {
+		maxLookbackDate := today.AddDate(0, 0, -lookback)
+		if parsedDate.Before(maxLookbackDate) {
+			return time.Time{}, models.NewError(models.ErrCodeDateOutOfRange, "date is beyond the maximum lookback period of %d days. Earliest allowed date: %s",
+				lookback, maxLookbackDate.Format(DateFormat))
+		}
 	}
 
 	return parsedDate, nil
 }
 
+// ValidateTimestamp parses an RFC3339 timestamp and applies the same limits
+// as ValidateDate to the day it falls on in the reference time zone
+func ValidateTimestamp(timestamp string) (time.Time, error) {
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return time.Time{}, models.NewError(models.ErrCodeDateInvalid, "invalid timestamp format. Expected RFC3339, got: %s", timestamp)
+	}
+	if parsed.After(time.Now()) {
+		return time.Time{}, models.NewError(models.ErrCodeDateOutOfRange, "timestamp cannot be in the future: %s", timestamp)
+	}
+	if _, err := ValidateDate(parsed.In(ReferenceLocation()).Format(DateFormat)); err != nil {
+		return time.Time{}, err
+	}
+	return parsed, nil
+}
+
 // ValidateDateRange validates a date range for historical data requests
 func ValidateDateRange(startDateStr, endDateStr string) (time.Time, time.Time, error) {
 	// Validate start date
 	startDate, err := ValidateDate(startDateStr)
 	if err != nil {
-		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date: %w", err)
+		return time.Time{}, time.Time{}, models.ForField(err, "start_date", "invalid start date: ")
 	}
 
 	// Validate end date
 	endDate, err := ValidateDate(endDateStr)
 	if err != nil {
-		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date: %w", err)
+		return time.Time{}, time.Time{}, models.ForField(err, "end_date", "invalid end date: ")
 	}
 
 	// Check if start date is after end date
 	if startDate.After(endDate) {
-		return time.Time{}, time.Time{}, fmt.Errorf("start date (%s) cannot be after end date (%s)",
+		return time.Time{}, time.Time{}, models.NewFieldError(models.ErrCodeDateOutOfRange, "start_date", "start date (%s) cannot be after end date (%s)",
 			startDateStr, endDateStr)
 	}
 
-	// Check if the date range is reasonable (not more than 90 days)
-	if endDate.Sub(startDate) > time.Duration(MaxLookbackDays)*24*time.Hour {
-		return time.Time{}, time.Time{}, fmt.Errorf("date range cannot exceed %d days", MaxLookbackDays)
+	// Check if the date range is within the per-request cap
+	if _, maxRange := DateLimits(); endDate.After(startDate.AddDate(0, 0, maxRange)) {
+		return time.Time{}, time.Time{}, models.NewFieldError(models.ErrCodeDateOutOfRange, "end_date", "date range cannot exceed %d days", maxRange)
 	}
 
 	return startDate, endDate, nil
 }
 
-// ValidateAmount checks if the amount is valid for conversion
-func ValidateAmount(amount float64) error {
-	if amount <= 0 {
-		return fmt.Errorf("amount must be greater than 0, got: %f", amount)
+// ValidateAmount checks if an amount of currency is valid for conversion: it
+// must be positive and within the currency's amount limits
+func ValidateAmount(currency string, amount float64) error {
+	if !(amount > 0) {
+		return models.NewFieldError(models.ErrCodeAmountInvalid, "amount", "amount must be greater than 0, got: %f", amount)
+	}
+	limits := models.AmountLimitsOf(currency)
+	if amount < limits.Min {
+		return models.NewFieldError(models.ErrCodeAmountBelowMinimum, "amount", "amount must be at least %g %s, got: %g", limits.Min, currency, amount)
 	}
-	if amount > 1e15 { // Reasonable upper limit
-		return fmt.Errorf("amount too large: %f", amount)
+	if amount > limits.Max {
+		return models.NewFieldError(models.ErrCodeAmountAboveMaximum, "amount", "amount must be at most %g %s, got: %g", limits.Max, currency, amount)
 	}
 	return nil
 }
@@ -120,6 +193,22 @@ func IsValidDateString(dateStr string) bool {
 	return err == nil
 }
 
+// IsMarketDay reports whether FX markets publish reference rates on date.
+// Markets are closed on weekends.
+func IsMarketDay(date time.Time) bool {
+	weekday := date.Weekday()
+	return weekday != time.Saturday && weekday != time.Sunday
+}
+
+// LastMarketDay returns date itself when it is a market day, otherwise the
+// closest earlier market day
+func LastMarketDay(date time.Time) time.Time {
+	for !IsMarketDay(date) {
+		date = date.AddDate(0, 0, -1)
+	}
+	return date
+}
+
 // GetDateRangeList returns a slice of date strings between start and end dates
 func GetDateRangeList(startDate, endDate time.Time) []string {
 	var dates []string
@@ -133,15 +222,17 @@ func GetDateRangeList(startDate, endDate time.Time) []string {
 	return dates
 }
 
-// ValidateConversionRequest validates a complete conversion request
+// ValidateConversionRequest validates a complete conversion request,
+// replacing currency aliases such as "₹" with their codes
 func ValidateConversionRequest(req *models.ConversionRequest) error {
 	// Validate currency pair
+	req.From, req.To = models.CanonicalCurrency(req.From), models.CanonicalCurrency(req.To)
 	if err := ValidateCurrencyPair(req.From, req.To); err != nil {
 		return err
 	}
 
 	// Validate amount
-	if err := ValidateAmount(req.Amount); err != nil {
+	if err := ValidateAmount(req.From, req.Amount); err != nil {
 		return err
 	}
 
@@ -149,21 +240,64 @@ func ValidateConversionRequest(req *models.ConversionRequest) error {
 	if req.Date != "" {
 		_, err := ValidateDate(req.Date)
 		if err != nil {
-			return err
+			return models.ForField(err, "date", "")
 		}
 	}
 
-	return nil
+	if req.Precision != nil {
+		maxPrecision := models.Limits().MaxPrecision
+		if *req.Precision < 0 {
+			return models.NewFieldError(models.ErrCodeValueInvalid, "precision",
+				fmt.Sprintf("precision must be between 0 and %d", maxPrecision))
+		}
+		if *req.Precision > maxPrecision {
+			return models.NewFieldError(models.ErrCodeLimitExceeded, "precision",
+				fmt.Sprintf("precision must be between 0 and %d", maxPrecision))
+		}
+	}
+	if req.FeePercent < 0 || req.FeePercent >= models.MaxFeePercent {
+		return models.NewFieldError(models.ErrCodeValueInvalid, "fee_percent",
+			fmt.Sprintf("fee_percent must be at least 0 and below %d", models.MaxFeePercent))
+	}
+	if req.FeeFixed < 0 {
+		return models.NewFieldError(models.ErrCodeValueInvalid, "fee_fixed", "fee_fixed must not be negative")
+	}
+	return ValidateRounding(req.Rounding)
 }
 
-// ValidateHistoricalRequest validates a historical rate request
+// ValidateHistoricalRequest validates a historical rate request, replacing
+// currency aliases with their codes
 func ValidateHistoricalRequest(req *models.HistoricalRateRequest) error {
 	// Validate currency pair
+	req.From, req.To = models.CanonicalCurrency(req.From), models.CanonicalCurrency(req.To)
 	if err := ValidateCurrencyPair(req.From, req.To); err != nil {
 		return err
 	}
 
 	// Validate date range
-	_, _, err := ValidateDateRange(req.StartDate, req.EndDate)
-	return err
+	if _, _, err := ValidateDateRange(req.StartDate, req.EndDate); err != nil {
+		return err
+	}
+	return ValidateMaxWait(req.MaxWaitMs)
+}
+
+// ValidateMaxWait checks the latency budget of a request in milliseconds, 0
+// meaning none
+func ValidateMaxWait(maxWaitMs int) error {
+	if maxWaitMs < 0 {
+		return models.NewFieldError(models.ErrCodeValueInvalid, "max_wait_ms",
+			"max_wait_ms must not be negative")
+	}
+	return nil
+}
+
+// ValidateGranularity checks a granularity of historical rates, empty
+// meaning daily
+func ValidateGranularity(granularity string) error {
+	switch granularity {
+	case "", models.GranularityDaily, models.GranularityWeekly, models.GranularityMonthly:
+		return nil
+	}
+	return models.NewFieldError(models.ErrCodeValueInvalid, "granularity",
+		"granularity must be one of daily, weekly or monthly")
 }
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"exchange-rate-service/internal/models"
 )
@@ -96,6 +97,33 @@ func TestValidateDate(t *testing.T) {
 	}
 }
 
+func TestValidateTimestamp(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		timestamp string
+		wantErr   bool
+	}{
+		{"Valid UTC", now.Add(-time.Hour).UTC().Format(time.RFC3339), false},
+		{"Valid with offset", now.Add(-time.Hour).In(time.FixedZone("IST", 5*3600+1800)).Format(time.RFC3339), false},
+		{"Date only", now.Format(DateFormat), true},
+		{"Future", now.Add(time.Hour).Format(time.RFC3339), true},
+		{"Beyond lookback", now.AddDate(0, 0, -100).Format(time.RFC3339), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateTimestamp(tt.timestamp)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestValidateDateRange(t *testing.T) {
 	now := time.Now()
 	validStart := now.AddDate(0, 0, -30).Format(DateFormat)
@@ -131,27 +159,39 @@ func TestValidateDateRange(t *testing.T) {
 }
 
 func TestValidateAmount(t *testing.T) {
+	models.SetAmountLimits(map[string]models.AmountLimits{"USD": {Min: 0.01}, "GBP": {Max: 1e9}})
+	defer models.SetAmountLimits(nil)
+
 	tests := []struct {
-		name    string
-		amount  float64
-		wantErr bool
+		name     string
+		currency string
+		amount   float64
+		wantCode string
 	}{
-		{"Valid amount", 100.0, false},
-		{"Valid small amount", 0.01, false},
-		{"Valid large amount", 1000000.0, false},
-		{"Zero amount", 0.0, true},
-		{"Negative amount", -100.0, true},
-		{"Too large amount", 1e16, true},
+		{"Valid amount", "USD", 100.0, ""},
+		{"Valid small amount", "USD", 0.01, ""},
+		{"Valid large amount", "USD", 1000000.0, ""},
+		{"Zero amount", "USD", 0.0, models.ErrCodeAmountInvalid},
+		{"Negative amount", "USD", -100.0, models.ErrCodeAmountInvalid},
+		{"Below configured minimum", "USD", 0.001, models.ErrCodeAmountBelowMinimum},
+		{"Too large amount", "USD", 1e16, models.ErrCodeAmountAboveMaximum},
+		{"Large yen amount", "JPY", 1e16, ""},
+		{"Too large yen amount", "JPY", 1e18, models.ErrCodeAmountAboveMaximum},
+		{"Above configured maximum", "GBP", 2e9, models.ErrCodeAmountAboveMaximum},
+		{"Too much gold", "XAU", 1e11, models.ErrCodeAmountAboveMaximum},
+		{"Currency without metadata", "CHF", 1e15, ""},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := ValidateAmount(tt.amount)
-			if tt.wantErr {
-				assert.Error(t, err)
-			} else {
+			err := ValidateAmount(tt.currency, tt.amount)
+			if tt.wantCode == "" {
 				assert.NoError(t, err)
+				return
 			}
+			code, field := models.ErrorCodeOf(err)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, "amount", field)
 		})
 	}
 }
@@ -194,6 +234,26 @@ func TestValidateConversionRequest(t *testing.T) {
 			&models.ConversionRequest{From: "USD", To: "INR", Amount: 100.0, Date: "invalid-date"},
 			true,
 		},
+		{
+			"Valid fees",
+			&models.ConversionRequest{From: "USD", To: "INR", Amount: 100.0, FeePercent: 1.5, FeeFixed: 50},
+			false,
+		},
+		{
+			"Negative fee percent",
+			&models.ConversionRequest{From: "USD", To: "INR", Amount: 100.0, FeePercent: -1},
+			true,
+		},
+		{
+			"Fee percent of the whole amount",
+			&models.ConversionRequest{From: "USD", To: "INR", Amount: 100.0, FeePercent: models.MaxFeePercent},
+			true,
+		},
+		{
+			"Negative fixed fee",
+			&models.ConversionRequest{From: "USD", To: "INR", Amount: 100.0, FeeFixed: -0.5},
+			true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -208,6 +268,46 @@ func TestValidateConversionRequest(t *testing.T) {
 	}
 }
 
+func TestCurrencyAliases(t *testing.T) {
+	models.SetCurrencyAliases(map[string]string{"Quid": "gbp", "rs": "USD"})
+	defer models.SetCurrencyAliases(nil)
+
+	tests := map[string]string{
+		"usd":     "USD",
+		" Inr ":   "INR",
+		"₹":       "INR",
+		"Rs":      "USD", // configured aliases win over built-in ones
+		"rupee":   "INR",
+		"US$":     "USD",
+		"€":       "EUR",
+		"euro":    "EUR",
+		"QUID":    "GBP",
+		"gold":    "XAU",
+		"dinar":   "DINAR",
+		"unknown": "UNKNOWN",
+	}
+	for input, want := range tests {
+		assert.Equal(t, want, models.CanonicalCurrency(input), input)
+	}
+	assert.Contains(t, models.AliasesOf("INR"), "₹")
+	assert.NotContains(t, models.AliasesOf("INR"), "rs")
+	assert.Contains(t, models.AliasesOf("USD"), "rs")
+
+	req := &models.ConversionRequest{From: "₹", To: "dollar", Amount: 100}
+	require.NoError(t, ValidateConversionRequest(req))
+	assert.Equal(t, "INR", req.From)
+	assert.Equal(t, "USD", req.To)
+
+	historical := &models.HistoricalRateRequest{From: "eur", To: "£", StartDate: time.Now().AddDate(0, 0, -7).Format(DateFormat), EndDate: time.Now().AddDate(0, 0, -1).Format(DateFormat)}
+	require.NoError(t, ValidateHistoricalRequest(historical))
+	assert.Equal(t, "EUR", historical.From)
+	assert.Equal(t, "GBP", historical.To)
+
+	code, field := models.ErrorCodeOf(ValidateConversionRequest(&models.ConversionRequest{From: "dinar", To: "USD", Amount: 1}))
+	assert.Equal(t, models.ErrCodeCurrencyUnsupported, code)
+	assert.Equal(t, "from", field)
+}
+
 func TestGetDateRangeList(t *testing.T) {
 	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
 	end := time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC)
@@ -248,3 +348,91 @@ func TestIsValidDateString(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateDateUsesReferenceLocation(t *testing.T) {
+	// Kiritimati is UTC+14, so its "today" is tomorrow in UTC for most of the day
+	kiritimati := time.FixedZone("UTC+14", 14*60*60)
+	SetReferenceLocation(kiritimati)
+	defer SetReferenceLocation(time.UTC)
+
+	today := time.Now().In(kiritimati).Format(DateFormat)
+	parsed, err := ValidateDate(today)
+	assert.NoError(t, err)
+	assert.Equal(t, kiritimati, parsed.Location())
+	assert.Equal(t, today, Today().Format(DateFormat))
+
+	tomorrow := time.Now().In(kiritimati).AddDate(0, 0, 1).Format(DateFormat)
+	_, err = ValidateDate(tomorrow)
+	assert.Error(t, err)
+}
+
+func TestLastMarketDay(t *testing.T) {
+	tests := []struct {
+		name string
+		date string
+		want string
+	}{
+		{"Friday", "2024-01-05", "2024-01-05"},
+		{"Saturday", "2024-01-06", "2024-01-05"},
+		{"Sunday", "2024-01-07", "2024-01-05"},
+		{"Monday", "2024-01-08", "2024-01-08"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, _ := time.Parse(DateFormat, tt.date)
+			assert.Equal(t, tt.want, LastMarketDay(date).Format(DateFormat))
+			assert.Equal(t, tt.date == tt.want, IsMarketDay(date))
+		})
+	}
+}
+
+func TestDateLimits(t *testing.T) {
+	defer SetDateLimits(DefaultLookbackDays, DefaultMaxRangeDays)
+	today := Today()
+	yearAgo := today.AddDate(-1, 0, 0).Format(DateFormat)
+
+	SetDateLimits(DefaultLookbackDays, DefaultMaxRangeDays)
+	_, err := ValidateDate(yearAgo)
+	assert.Error(t, err)
+
+	// Unbounded lookback still caps the range length
+	SetDateLimits(0, 10)
+	_, err = ValidateDate(yearAgo)
+	assert.NoError(t, err)
+
+	start := today.AddDate(-1, 0, 0)
+	_, _, err = ValidateDateRange(start.Format(DateFormat), start.AddDate(0, 0, 10).Format(DateFormat))
+	assert.NoError(t, err)
+	_, _, err = ValidateDateRange(start.Format(DateFormat), start.AddDate(0, 0, 11).Format(DateFormat))
+	assert.EqualError(t, err, "date range cannot exceed 10 days")
+}
+
+func TestValidationErrorCodes(t *testing.T) {
+	today := time.Now().Format(DateFormat)
+	future := time.Now().AddDate(0, 0, 7).Format(DateFormat)
+	tests := []struct {
+		name  string
+		err   error
+		code  string
+		field string
+	}{
+		{"Unsupported currency", ValidateCurrency("XYZ"), models.ErrCodeCurrencyUnsupported, ""},
+		{"Unsupported to currency", ValidateCurrencyPair("USD", "XYZ"), models.ErrCodeCurrencyUnsupported, "to"},
+		{"Zero amount", ValidateAmount("USD", 0), models.ErrCodeAmountInvalid, "amount"},
+		{"Malformed start date", errOf(ValidateDateRange("2025/01/01", today)), models.ErrCodeDateInvalid, "start_date"},
+		{"Future end date", errOf(ValidateDateRange(today, future)), models.ErrCodeDateOutOfRange, "end_date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, field := models.ErrorCodeOf(tt.err)
+			assert.Equal(t, tt.code, code)
+			assert.Equal(t, tt.field, field)
+		})
+	}
+}
+
+func errOf(_, _ time.Time, err error) error {
+	return err
+}
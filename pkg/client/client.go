@@ -0,0 +1,323 @@
+// Package client is a typed Go client for the Exchange Rate Service HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTimeout      = 10 * time.Second
+	DefaultMaxRetries   = 2
+	DefaultRetryBackoff = 200 * time.Millisecond
+)
+
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	maxRetries   int
+	retryBackoff time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient replaces the default http.Client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how many times a failed request is retried and the initial
+// backoff between attempts. The backoff doubles after every attempt.
+// Conversions are never retried.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryBackoff = backoff
+	}
+}
+
+// New creates a client for the service running at baseURL, e.g. "http://localhost:8080"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: DefaultTimeout,
+		},
+		maxRetries:   DefaultMaxRetries,
+		retryBackoff: DefaultRetryBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Convert converts an amount between two currencies, optionally on a past date.
+// The service records every conversion, so a failed one is not retried: the
+// first attempt may have been recorded even though its response was lost.
+func (c *Client) Convert(ctx context.Context, req ConversionRequest) (*ConversionResponse, error) {
+	var resp ConversionResponse
+	if err := c.send(ctx, http.MethodPost, "/api/v1/convert", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Conversion returns a past conversion exactly as it was quoted, by the
+// ConversionID of its response
+func (c *Client) Conversion(ctx context.Context, conversionID string) (*ConversionResponse, error) {
+	var resp ConversionResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/conversions/"+url.PathEscape(conversionID), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// LatestRate returns the latest rate for a currency pair
+func (c *Client) LatestRate(ctx context.Context, from, to string) (*LatestRate, error) {
+	return c.LatestRateFrom(ctx, "", from, to)
+}
+
+// LatestRateFrom returns the latest rate for a currency pair from the named
+// provider, e.g. "frankfurter", or the service's default when it is empty
+func (c *Client) LatestRateFrom(ctx context.Context, provider, from, to string) (*LatestRate, error) {
+	query := url.Values{}
+	query.Set("from", from)
+	query.Set("to", to)
+	if provider != "" {
+		query.Set("provider", provider)
+	}
+
+	var resp LatestRate
+	if err := c.do(ctx, http.MethodGet, "/api/v1/rates/latest", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RateTable returns the cached rate of every supported currency against base
+func (c *Client) RateTable(ctx context.Context, base string) (*RateTable, error) {
+	query := url.Values{}
+	query.Set("base", base)
+
+	var resp RateTable
+	if err := c.do(ctx, http.MethodGet, "/api/v1/rates/table", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RateMatrix returns the cached rate of every supported currency pair
+func (c *Client) RateMatrix(ctx context.Context) (*RateTable, error) {
+	query := url.Values{}
+	query.Set("matrix", "true")
+
+	var resp RateTable
+	if err := c.do(ctx, http.MethodGet, "/api/v1/rates/table", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// HistoricalRates returns the rates of a currency pair for a date range
+func (c *Client) HistoricalRates(ctx context.Context, req HistoricalRatesRequest) (*HistoricalRatesResponse, error) {
+	var resp HistoricalRatesResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/rates/historical", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// BulkHistoricalRates returns the rates of several currency pairs for one
+// date range
+func (c *Client) BulkHistoricalRates(ctx context.Context, req BulkHistoricalRatesRequest) (*BulkHistoricalRatesResponse, error) {
+	var resp BulkHistoricalRatesResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/rates/historical/bulk", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RateTrend returns the moving averages of a pair over window days. Empty
+// dates default to the service's trailing 30 days; window 0 uses its default.
+func (c *Client) RateTrend(ctx context.Context, from, to string, window int, startDate, endDate string) (*RateTrend, error) {
+	query := url.Values{}
+	query.Set("from", from)
+	query.Set("to", to)
+	if window > 0 {
+		query.Set("window", strconv.Itoa(window))
+	}
+	if startDate != "" {
+		query.Set("start_date", startDate)
+	}
+	if endDate != "" {
+		query.Set("end_date", endDate)
+	}
+
+	var resp RateTrend
+	if err := c.do(ctx, http.MethodGet, "/api/v1/rates/trend", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// IntradayRates returns the rates of a pair recorded during date, today
+// when empty
+func (c *Client) IntradayRates(ctx context.Context, from, to, date string) (*IntradayRates, error) {
+	query := url.Values{}
+	query.Set("from", from)
+	query.Set("to", to)
+	if date != "" {
+		query.Set("date", date)
+	}
+
+	var resp IntradayRates
+	if err := c.do(ctx, http.MethodGet, "/api/v1/rates/intraday", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Currencies returns the currency codes supported by the service
+func (c *Client) Currencies(ctx context.Context) ([]string, error) {
+	var resp currenciesResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/currencies", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Currencies, nil
+}
+
+// CurrencyMetadata returns name, symbol and formatting details of every
+// supported currency
+func (c *Client) CurrencyMetadata(ctx context.Context) ([]CurrencyInfo, error) {
+	var resp currenciesResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/currencies", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Metadata, nil
+}
+
+// Health returns the service health report. An unhealthy service answers
+// 503, which surfaces as ErrServerUnavailable.
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	var resp Health
+	if err := c.do(ctx, http.MethodGet, "/api/v1/health", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// do performs a request that only reads, retrying transient failures. The
+// historical rate lookups are POSTed for their long bodies but read too.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	return c.request(ctx, method, path, query, body, out, c.maxRetries)
+}
+
+// send performs a request that changes the service's state, once
+func (c *Client) send(ctx context.Context, method, path string, body, out interface{}) error {
+	return c.request(ctx, method, path, nil, body, out, 0)
+}
+
+func (c *Client) request(ctx context.Context, method, path string, query url.Values, body, out interface{}, maxRetries int) error {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	backoff := c.retryBackoff
+	var lastErr error
+
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		retry, err := c.attempt(ctx, method, endpoint, payload, out)
+		if err == nil {
+			return nil
+		}
+		if !retry {
+			return err
+		}
+		lastErr = err
+	}
+
+	return fmt.Errorf("%w: %v", ErrServerUnavailable, lastErr)
+}
+
+// attempt performs a single request. The returned bool reports whether the
+// failure is transient and the request may be retried.
+func (c *Client) attempt(ctx context.Context, method, endpoint string, payload []byte, out interface{}) (bool, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return false, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		return true, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return false, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return false, nil
+	}
+
+	apiErr := &APIError{StatusCode: resp.StatusCode, Type: http.StatusText(resp.StatusCode)}
+	var errResp errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err == nil && errResp.Error != "" {
+		apiErr.Type = errResp.Error
+		apiErr.Message = errResp.Message
+		apiErr.Code = errResp.ErrorCode
+		apiErr.Details = errResp.Details
+		apiErr.Entitlement = errResp.Entitlement
+	}
+
+	return isRetryableStatus(resp.StatusCode), apiErr
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || (status >= 500 && status != http.StatusNotImplemented)
+}
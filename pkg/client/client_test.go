@@ -0,0 +1,283 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Convert(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/convert", r.URL.Path)
+
+		var req ConversionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "USD", req.From)
+		assert.Equal(t, "INR", req.To)
+
+		json.NewEncoder(w).Encode(ConversionResponse{
+			From:            req.From,
+			To:              req.To,
+			Amount:          req.Amount,
+			ConvertedAmount: req.Amount * 83.5,
+			Rate:            83.5,
+		})
+	}))
+	defer server.Close()
+
+	c := New(server.URL)
+	resp, err := c.Convert(context.Background(), ConversionRequest{From: "USD", To: "INR", Amount: 100})
+	require.NoError(t, err)
+	assert.Equal(t, 8350.0, resp.ConvertedAmount)
+	assert.Equal(t, 83.5, resp.Rate)
+}
+
+func TestClient_Conversion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/api/v1/conversions/9f2c4e7a", r.URL.Path)
+
+		json.NewEncoder(w).Encode(ConversionResponse{ConversionID: "9f2c4e7a", From: "USD", To: "INR", ConvertedAmount: 8350})
+	}))
+	defer server.Close()
+
+	resp, err := New(server.URL).Conversion(context.Background(), "9f2c4e7a")
+	require.NoError(t, err)
+	assert.Equal(t, "9f2c4e7a", resp.ConversionID)
+	assert.Equal(t, 8350.0, resp.ConvertedAmount)
+}
+
+func TestClient_LatestRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/rates/latest", r.URL.Path)
+		assert.Equal(t, "EUR", r.URL.Query().Get("from"))
+		assert.Equal(t, "GBP", r.URL.Query().Get("to"))
+
+		json.NewEncoder(w).Encode(LatestRate{From: "EUR", To: "GBP", Rate: 0.85})
+	}))
+	defer server.Close()
+
+	rate, err := New(server.URL).LatestRate(context.Background(), "EUR", "GBP")
+	require.NoError(t, err)
+	assert.Equal(t, 0.85, rate.Rate)
+}
+
+func TestClient_LatestRateFrom(t *testing.T) {
+	published := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "frankfurter", r.URL.Query().Get("provider"))
+
+		json.NewEncoder(w).Encode(LatestRate{
+			From:   "EUR",
+			To:     "GBP",
+			Rate:   0.84,
+			Source: Source{Provider: "frankfurter", PublishedAt: &published},
+		})
+	}))
+	defer server.Close()
+
+	rate, err := New(server.URL).LatestRateFrom(context.Background(), "frankfurter", "EUR", "GBP")
+	require.NoError(t, err)
+	assert.Equal(t, "frankfurter", rate.Provider)
+	require.NotNil(t, rate.PublishedAt)
+	assert.True(t, published.Equal(*rate.PublishedAt))
+}
+
+func TestClient_RateTable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/rates/table", r.URL.Path)
+		if r.URL.Query().Get("matrix") == "true" {
+			json.NewEncoder(w).Encode(RateTable{Matrix: map[string]map[string]float64{"USD": {"INR": 83.5}}})
+			return
+		}
+		assert.Equal(t, "USD", r.URL.Query().Get("base"))
+		json.NewEncoder(w).Encode(RateTable{Base: "USD", Rates: map[string]float64{"INR": 83.5}, Missing: []string{"USD_JPY"}})
+	}))
+	defer server.Close()
+
+	client := New(server.URL)
+	table, err := client.RateTable(context.Background(), "USD")
+	require.NoError(t, err)
+	assert.Equal(t, 83.5, table.Rates["INR"])
+	assert.Equal(t, []string{"USD_JPY"}, table.Missing)
+
+	matrix, err := client.RateMatrix(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 83.5, matrix.Matrix["USD"]["INR"])
+}
+
+func TestClient_RateTrend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/rates/trend", r.URL.Path)
+		assert.Equal(t, "14", r.URL.Query().Get("window"))
+		assert.False(t, r.URL.Query().Has("start_date"))
+		w.Write([]byte(`{"from":"USD","to":"INR","window":14,"points":[{"date":"2025-01-02","rate":83.5,"change":null,"sma":null}]}`))
+	}))
+	defer server.Close()
+
+	trend, err := New(server.URL).RateTrend(context.Background(), "USD", "INR", 14, "", "")
+	require.NoError(t, err)
+	require.Len(t, trend.Points, 1)
+	assert.Equal(t, 83.5, trend.Points[0].Rate)
+	assert.Nil(t, trend.Points[0].SMA)
+}
+
+func TestClient_IntradayRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/rates/intraday", r.URL.Path)
+		assert.Equal(t, "2025-01-02", r.URL.Query().Get("date"))
+		w.Write([]byte(`{"from":"USD","to":"INR","date":"2025-01-02","rates":[{"timestamp":"2025-01-02T09:00:00Z","rate":83.5},{"timestamp":"2025-01-02T09:01:00Z","rate":83.52}]}`))
+	}))
+	defer server.Close()
+
+	intraday, err := New(server.URL).IntradayRates(context.Background(), "USD", "INR", "2025-01-02")
+	require.NoError(t, err)
+	require.Len(t, intraday.Rates, 2)
+	assert.Equal(t, 83.52, intraday.Rates[1].Rate)
+	assert.Equal(t, time.Date(2025, 1, 2, 9, 1, 0, 0, time.UTC), intraday.Rates[1].Timestamp)
+}
+
+func TestClient_BulkHistoricalRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/rates/historical/bulk", r.URL.Path)
+
+		var req BulkHistoricalRatesRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, []string{"USD_INR", "EUR_INR"}, req.Pairs)
+		w.Write([]byte(`{"start_date":"2025-01-02","end_date":"2025-01-03",` +
+			`"rates":{"USD_INR":{"2025-01-02":{"rate":85.6,"date":"2025-01-02T00:00:00Z"}},"EUR_INR":{}},` +
+			`"missing_dates":{"EUR_INR":[{"date":"2025-01-02","reason":"no_data"}]}}`))
+	}))
+	defer server.Close()
+
+	bulk, err := New(server.URL).BulkHistoricalRates(context.Background(), BulkHistoricalRatesRequest{
+		Pairs: []string{"USD_INR", "EUR_INR"}, StartDate: "2025-01-02", EndDate: "2025-01-03",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 85.6, bulk.Rates["USD_INR"]["2025-01-02"].Rate)
+	require.Len(t, bulk.MissingDates["EUR_INR"], 1)
+	assert.Equal(t, "no_data", bulk.MissingDates["EUR_INR"][0].Reason)
+}
+
+func TestClient_APIError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(errorResponse{
+			Error:     "Failed to get exchange rate",
+			Message:   "invalid 'from' currency: unsupported currency: XYZ",
+			Code:      http.StatusUnprocessableEntity,
+			ErrorCode: "CURRENCY_UNSUPPORTED",
+			Details:   []FieldError{{Field: "from", Code: "CURRENCY_UNSUPPORTED", Message: "unsupported currency: XYZ"}},
+		})
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL).LatestRate(context.Background(), "XYZ", "USD")
+	require.Error(t, err)
+	assert.True(t, IsBadRequest(err))
+	assert.Equal(t, "CURRENCY_UNSUPPORTED", ErrorCode(err))
+
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "invalid 'from' currency: unsupported currency: XYZ", apiErr.Message)
+	require.Len(t, apiErr.Details, 1)
+	assert.Equal(t, "from", apiErr.Details[0].Field)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "client errors must not be retried")
+}
+
+func TestClient_RetriesServerErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(currenciesResponse{Currencies: []string{"USD", "INR"}})
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithRetries(2, time.Millisecond))
+	currencies, err := c.Currencies(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"USD", "INR"}, currencies)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestClient_ConvertIsNotRetried(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithRetries(2, time.Millisecond))
+	_, err := c.Convert(context.Background(), ConversionRequest{From: "USD", To: "INR", Amount: 100})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrServerUnavailable))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "a conversion may have been recorded, so it must not be repeated")
+
+	// Lookups sent as POST are still retried
+	atomic.StoreInt32(&calls, 0)
+	_, err = c.BulkHistoricalRates(context.Background(), BulkHistoricalRatesRequest{})
+	require.Error(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestClient_RetriesExhausted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithRetries(1, time.Millisecond))
+	_, err := c.Health(context.Background())
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrServerUnavailable))
+}
+
+func TestClient_ContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := New(server.URL, WithRetries(5, time.Second))
+	_, err := c.Currencies(ctx)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestClient_EntitlementError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(errorResponse{
+			Error:       "Forbidden",
+			Message:     "API key starter lacks the historical entitlement",
+			Code:        http.StatusForbidden,
+			ErrorCode:   "ENTITLEMENT_REQUIRED",
+			Entitlement: "historical",
+		})
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL).LatestRate(context.Background(), "USD", "INR")
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "ENTITLEMENT_REQUIRED", apiErr.Code)
+	assert.Equal(t, "historical", apiErr.Entitlement)
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrServerUnavailable is returned when the service could not be reached or
+// kept failing after all retry attempts were used
+var ErrServerUnavailable = errors.New("exchange rate service unavailable")
+
+// APIError is returned when the service answers with a non-2xx status code
+type APIError struct {
+	StatusCode int
+	Type       string // the "error" field of the response body
+	Message    string
+	Code       string       // Stable error code such as CURRENCY_UNSUPPORTED
+	Details    []FieldError // Fields that failed validation
+	// Entitlement the API key lacks, with ENTITLEMENT_REQUIRED
+	Entitlement string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("exchange rate service returned %d: %s", e.StatusCode, e.Type)
+	}
+	return fmt.Sprintf("exchange rate service returned %d: %s: %s", e.StatusCode, e.Type, e.Message)
+}
+
+// IsBadRequest reports whether err is an APIError caused by an invalid
+// request, either malformed (400) or with invalid values (422)
+func IsBadRequest(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) &&
+		(apiErr.StatusCode == http.StatusBadRequest || apiErr.StatusCode == http.StatusUnprocessableEntity)
+}
+
+// ErrorCode returns the service's error code of err, such as
+// "CURRENCY_UNSUPPORTED", or "" when err is not an APIError with one
+func ErrorCode(err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+// IsNotFound reports whether err is an APIError with a 404 status code
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
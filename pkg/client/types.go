@@ -0,0 +1,238 @@
+package client
+
+import "time"
+
+// ConversionRequest mirrors the body accepted by POST /api/v1/convert
+type ConversionRequest struct {
+	From      string  `json:"from"`
+	To        string  `json:"to"`
+	Amount    float64 `json:"amount"`
+	Date      string  `json:"date,omitempty"`      // Optional, format: YYYY-MM-DD
+	Timestamp string  `json:"timestamp,omitempty"` // Optional RFC3339 instant, exclusive with Date
+	Locale    string  `json:"locale,omitempty"`    // Optional BCP 47 locale; fills ConversionResponse.Formatted
+	Provider  string  `json:"provider,omitempty"`  // Optional provider to pin the rate to, e.g. "frankfurter"
+	Precision *int    `json:"precision,omitempty"` // Optional decimals of the converted amount, the target currency's minor units by default
+	Rounding  string  `json:"rounding,omitempty"`  // Optional "half_up" (default), "bankers" or "truncate"
+	Strict    bool    `json:"strict,omitempty"`    // Optional, fail rather than use another day's or a stale rate
+
+	FeePercent float64 `json:"fee_percent,omitempty"` // Optional percent of the converted amount charged as a fee
+	FeeFixed   float64 `json:"fee_fixed,omitempty"`   // Optional fixed fee in the target currency
+}
+
+// ConversionResponse is the result of a currency conversion
+type ConversionResponse struct {
+	ConversionID    string               `json:"conversion_id,omitempty"` // Receipt ID to retrieve the conversion with, see Client.Conversion
+	From            string               `json:"from"`
+	To              string               `json:"to"`
+	Amount          float64              `json:"amount"`
+	ConvertedAmount float64              `json:"converted_amount"`
+	Rate            float64              `json:"rate"` // Applied rate, mid-market plus markup
+	MidMarketRate   float64              `json:"mid_market_rate"`
+	MarkupPercent   float64              `json:"markup_percent"`
+	Precision       int                  `json:"precision"` // Decimals ConvertedAmount is rounded to
+	Rounding        string               `json:"rounding"`
+	UnroundedRate   float64              `json:"unrounded_rate"`    // Applied rate at full precision
+	UnroundedAmount float64              `json:"unrounded_amount"`  // ConvertedAmount before rounding
+	Derived         string               `json:"derived,omitempty"` // "inverse" when computed from the reverse pair
+	Date            time.Time            `json:"date"`
+	RateDate        string               `json:"rate_date,omitempty"`      // Market day the rate was published for
+	MarketClosed    bool                 `json:"market_closed,omitempty"`  // True when RateDate differs from the requested date
+	RateTimestamp   *time.Time           `json:"rate_timestamp,omitempty"` // When the rate used for a Timestamp request was published
+	Formatted       *FormattedConversion `json:"formatted,omitempty"`
+	Fees            *ConversionFees      `json:"fees,omitempty"` // Set when the request has fees
+	Source
+}
+
+// Source names the provider a response's rate came from and when it
+// published the rate. Fields are empty when unknown.
+type Source struct {
+	Provider    string     `json:"provider,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+}
+
+// ConversionFees itemizes the fees of a conversion, in the target currency
+type ConversionFees struct {
+	FeePercent      float64 `json:"fee_percent"`
+	FeeFixed        float64 `json:"fee_fixed"`
+	MidMarketAmount float64 `json:"mid_market_amount"` // Amount at the mid-market rate, before markup and fees
+	FeeAmount       float64 `json:"fee_amount"`
+	NetAmount       float64 `json:"net_amount"` // ConvertedAmount less FeeAmount
+}
+
+// FormattedConversion holds display strings for the locale requested with
+// ConversionRequest.Locale
+type FormattedConversion struct {
+	Locale          string `json:"locale"`
+	Amount          string `json:"amount"`
+	ConvertedAmount string `json:"converted_amount"`
+	Rate            string `json:"rate"`
+}
+
+// LatestRate is the latest known rate for a currency pair
+type LatestRate struct {
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Rate    float64 `json:"rate"`
+	Derived string  `json:"derived,omitempty"`
+	Source
+}
+
+// RateTable holds mid-market rates against Base, or the full Matrix when
+// requested with RateMatrix. Missing lists uncached pairs as FROM_TO.
+type RateTable struct {
+	Base    string                        `json:"base,omitempty"`
+	Rates   map[string]float64            `json:"rates,omitempty"`
+	Matrix  map[string]map[string]float64 `json:"matrix,omitempty"`
+	Missing []string                      `json:"missing,omitempty"`
+	Source
+}
+
+// HistoricalRatesRequest mirrors the body accepted by POST /api/v1/rates/historical
+type HistoricalRatesRequest struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	StartDate string `json:"start_date"` // YYYY-MM-DD
+	EndDate   string `json:"end_date"`   // YYYY-MM-DD
+	Provider  string `json:"provider,omitempty"`
+}
+
+// HistoricalRatesResponse holds the rates found for a date range
+type HistoricalRatesResponse struct {
+	From  string                    `json:"from"`
+	To    string                    `json:"to"`
+	Rates map[string]HistoricalRate `json:"rates"` // date -> rate
+	Source
+
+	MissingDates []MissingDate `json:"missing_dates"` // Dates without a rate, oldest first
+}
+
+// BulkHistoricalRatesRequest mirrors the body accepted by
+// POST /api/v1/rates/historical/bulk
+type BulkHistoricalRatesRequest struct {
+	Pairs     []string `json:"pairs"`      // FROM_TO, e.g. USD_INR
+	StartDate string   `json:"start_date"` // YYYY-MM-DD
+	EndDate   string   `json:"end_date"`   // YYYY-MM-DD
+	Provider  string   `json:"provider,omitempty"`
+}
+
+// BulkHistoricalRatesResponse holds the rates found for every pair of a bulk
+// request
+type BulkHistoricalRatesResponse struct {
+	StartDate string                               `json:"start_date"`
+	EndDate   string                               `json:"end_date"`
+	Rates     map[string]map[string]HistoricalRate `json:"rates"` // pair -> date -> rate
+	Source
+
+	MissingDates map[string][]MissingDate `json:"missing_dates"` // pair -> dates without a rate, oldest first
+}
+
+// MissingDate is a date of a range without a rate. Reason is "no_data" when
+// the provider published none (e.g. a weekend), "provider_error" when the
+// fetch failed and "out_of_range" when the provider serves no history for it.
+type MissingDate struct {
+	Date   string `json:"date"`
+	Reason string `json:"reason"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HistoricalRate is a rate for a specific date
+type HistoricalRate struct {
+	Rate         float64   `json:"rate"`
+	Date         time.Time `json:"date"`
+	ObservedDate string    `json:"observed_date,omitempty"` // Trading day the rate is from, when Date was not one
+	Derived      string    `json:"derived,omitempty"`
+}
+
+// RateTrend holds a pair's daily rates with moving averages, oldest first
+type RateTrend struct {
+	From      string       `json:"from"`
+	To        string       `json:"to"`
+	Window    int          `json:"window"`
+	StartDate string       `json:"start_date"`
+	EndDate   string       `json:"end_date"`
+	Points    []TrendPoint `json:"points"`
+	Missing   []string     `json:"missing,omitempty"` // Dates without a rate
+	Source
+}
+
+// TrendPoint is one day of a RateTrend. Nil fields need more history than
+// the range provides.
+type TrendPoint struct {
+	Date          string   `json:"date"`
+	Rate          float64  `json:"rate"`
+	Change        *float64 `json:"change"`
+	ChangePercent *float64 `json:"change_percent"`
+	SMA           *float64 `json:"sma"`
+	EMA           *float64 `json:"ema"`
+}
+
+// IntradayRates lists the rates of a pair the service recorded during a
+// day, oldest first
+type IntradayRates struct {
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	Date    string         `json:"date"`
+	Derived string         `json:"derived,omitempty"` // "inverse" when computed from the reverse pair
+	Rates   []IntradayRate `json:"rates"`
+}
+
+// IntradayRate is a rate as published at a point in time
+type IntradayRate struct {
+	Timestamp time.Time `json:"timestamp"`
+	Rate      float64   `json:"rate"`
+}
+
+// Health is the payload returned by the health endpoint
+type Health struct {
+	Status              string                 `json:"status"` // "healthy" or "degraded"
+	Checks              map[string]HealthCheck `json:"checks"`
+	RateFetcher         bool                   `json:"rate_fetcher"`
+	HistoricalData      bool                   `json:"historical_data"`
+	SupportedCurrencies []string               `json:"supported_currencies"`
+	CacheStats          map[string]interface{} `json:"cache_stats"`
+	Timestamp           string                 `json:"timestamp"`
+}
+
+// HealthCheck is the status of one dependency of the service
+type HealthCheck struct {
+	Status    string     `json:"status"`
+	Message   string     `json:"message,omitempty"`
+	CheckedAt time.Time  `json:"checked_at"`
+	LastOK    *time.Time `json:"last_ok,omitempty"`
+}
+
+// CurrencyInfo describes a supported currency
+type CurrencyInfo struct {
+	Code          string   `json:"code"`
+	Name          string   `json:"name"`
+	Symbol        string   `json:"symbol"`
+	DecimalPlaces int      `json:"decimal_places"`
+	Countries     []string `json:"countries"`
+	Type          string   `json:"type"`              // fiat, crypto or metal
+	Unit          string   `json:"unit,omitempty"`    // troy_ounce for metals
+	MinAmount     float64  `json:"min_amount"`        // Smallest amount converted from the currency
+	MaxAmount     float64  `json:"max_amount"`        // Largest amount converted from the currency
+	Aliases       []string `json:"aliases,omitempty"` // Other inputs accepted for the code, such as its symbol
+}
+
+type currenciesResponse struct {
+	Currencies []string       `json:"currencies"`
+	Metadata   []CurrencyInfo `json:"metadata"`
+}
+
+// FieldError names a request field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type errorResponse struct {
+	Error     string       `json:"error"`
+	Message   string       `json:"message"`
+	Code      int          `json:"code"`
+	ErrorCode string       `json:"error_code,omitempty"`
+	Details   []FieldError `json:"details,omitempty"`
+
+	Entitlement string `json:"entitlement,omitempty"`
+}
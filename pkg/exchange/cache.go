@@ -0,0 +1,154 @@
+package exchange
+
+import (
+	"time"
+
+	"exchange-rate-service/internal/cache"
+)
+
+// Cache stores rates between requests, plugged in with WithCache to share
+// them between processes, e.g. in Redis. Keys are a pair and a date, empty
+// for the latest rate. An entry of another provider than the one a rate is
+// looked up for is treated as missing, so processes using different
+// providers never serve each other's rates. Implementations must be safe for
+// concurrent use and should drop entries once ExpiresAt has passed.
+type Cache interface {
+	Get(from, to, date string) (CachedRate, bool)
+	Set(from, to, date string, rate CachedRate)
+	Delete(from, to, date string)
+}
+
+// CachedRate is a cache entry
+type CachedRate struct {
+	Rate        float64
+	Provider    string
+	PublishedAt time.Time
+	StoredAt    time.Time
+	ExpiresAt   time.Time
+
+	// NotFound records that the provider has no rate for the pair, so
+	// repeated lookups don't go upstream
+	NotFound bool
+}
+
+// cacheAdapter serves the services' cache from a Cache
+type cacheAdapter struct {
+	cache Cache
+	ttl   time.Duration
+}
+
+func (a *cacheAdapter) Get(provider, from, to, date string) (float64, bool) {
+	item, found := a.GetItem(provider, from, to, date)
+	return item.Rate, found
+}
+
+func (a *cacheAdapter) GetItem(provider, from, to, date string) (cache.CacheItem, bool) {
+	entry, found := a.lookup(provider, from, to, date)
+	if !found || entry.NotFound {
+		return cache.CacheItem{}, false
+	}
+	return item(entry), true
+}
+
+// GetStale finds nothing: a Cache may drop entries once they expire, so
+// rates are never served past their expiry
+func (a *cacheAdapter) GetStale(provider, from, to, date string) (cache.CacheItem, bool) {
+	return cache.CacheItem{}, false
+}
+
+func (a *cacheAdapter) GetNegative(provider, from, to, date string) (cache.CacheItem, bool) {
+	entry, found := a.lookup(provider, from, to, date)
+	if !found || !entry.NotFound {
+		return cache.CacheItem{}, false
+	}
+	return item(entry), true
+}
+
+func (a *cacheAdapter) Set(provider, from, to, date string, rate float64) {
+	a.SetWithTTL(provider, from, to, date, rate, a.ttl)
+}
+
+func (a *cacheAdapter) SetWithSource(from, to, date string, rate float64, source cache.Source) {
+	a.store(from, to, date, CachedRate{Rate: rate, Provider: source.Provider, PublishedAt: source.PublishedAt}, a.ttl)
+}
+
+func (a *cacheAdapter) SetWithTTL(provider, from, to, date string, rate float64, ttl time.Duration) {
+	a.store(from, to, date, CachedRate{Rate: rate, Provider: provider}, ttl)
+}
+
+func (a *cacheAdapter) SetNegative(provider, from, to, date string, ttl time.Duration) {
+	a.store(from, to, date, CachedRate{Provider: provider, NotFound: true}, ttl)
+}
+
+// Delete deletes the pair's entry on date, whichever provider it is of
+func (a *cacheAdapter) Delete(provider, from, to, date string) {
+	a.cache.Delete(from, to, date)
+}
+
+// DeletePair deletes the pair's latest rate; dated entries can't be listed
+// through Cache and expire on their own
+func (a *cacheAdapter) DeletePair(from, to string) int {
+	if _, found := a.cache.Get(from, to, ""); !found {
+		return 0
+	}
+	a.cache.Delete(from, to, "")
+	return 1
+}
+
+// DeleteDate deletes the pair's entry on date; entries of other namespaces
+// can't be listed through Cache and expire on their own
+func (a *cacheAdapter) DeleteDate(from, to, date string) int {
+	if _, found := a.cache.Get(from, to, date); !found {
+		return 0
+	}
+	a.cache.Delete(from, to, date)
+	return 1
+}
+
+// Clear is not reachable through Exchange; entries expire on their own
+func (a *cacheAdapter) Clear() {}
+
+// Size is unknown for a Cache
+func (a *cacheAdapter) Size() int {
+	return 0
+}
+
+func (a *cacheAdapter) GetStats() map[string]interface{} {
+	return map[string]interface{}{"ttl": a.ttl.String()}
+}
+
+// GetProviderStats has nothing to report: entries can't be listed through
+// Cache
+func (a *cacheAdapter) GetProviderStats(provider string) map[string]interface{} {
+	return map[string]interface{}{"provider": provider, "ttl": a.ttl.String()}
+}
+
+func (a *cacheAdapter) Ping() error {
+	return nil
+}
+
+// lookup returns the entry of a key if it is of provider and hasn't expired,
+// whether or not the Cache drops expired entries itself
+func (a *cacheAdapter) lookup(provider, from, to, date string) (CachedRate, bool) {
+	entry, found := a.cache.Get(from, to, date)
+	if !found || entry.Provider != provider || (!entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt)) {
+		return CachedRate{}, false
+	}
+	return entry, true
+}
+
+func (a *cacheAdapter) store(from, to, date string, entry CachedRate, ttl time.Duration) {
+	entry.StoredAt = time.Now()
+	entry.ExpiresAt = entry.StoredAt.Add(ttl)
+	a.cache.Set(from, to, date, entry)
+}
+
+func item(entry CachedRate) cache.CacheItem {
+	return cache.CacheItem{
+		Rate:      entry.Rate,
+		StoredAt:  entry.StoredAt,
+		ExpiresAt: entry.ExpiresAt,
+		Source:    cache.Source{Provider: entry.Provider, PublishedAt: entry.PublishedAt},
+		Negative:  entry.NotFound,
+	}
+}
@@ -0,0 +1,196 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SourceKindCommand is the builtin kind of source whose rates are printed by
+// a command, such as a script reading an internal treasury feed
+const SourceKindCommand = "command"
+
+// Defaults of a command source's timeout, cache_ttl and max_runs settings
+const (
+	DefaultCommandTimeout  = 10 * time.Second
+	DefaultCommandCacheTTL = time.Minute
+	DefaultCommandMaxRuns  = 4
+)
+
+// commandSource runs its command with the arguments "latest BASE" or
+// "historical BASE YYYY-MM-DD" appended. The command prints the rates as JSON
+// and exits 0:
+//
+//	{"base": "USD", "rates": {"INR": 85.6}, "published_at": "2025-01-02T16:00:00Z"}
+//
+// published_at is optional. A nonzero exit fails the request with the
+// command's standard error. Requests pinned to the source skip the service's
+// cache, so the source keeps each run's rates for cacheTTL itself, and runs
+// at most cap(runs) commands at once.
+type commandSource struct {
+	name       string
+	args       []string
+	timeout    time.Duration
+	historical bool
+	cacheTTL   time.Duration
+	runs       chan struct{} // Holds a token per command running
+
+	mu      sync.Mutex
+	results map[string]commandResult // By the arguments appended
+}
+
+type commandResult struct {
+	rates   *Rates
+	expires time.Time
+}
+
+// newCommandSource builds a command source from its settings: command, the
+// command line split on spaces; timeout; historical, false when the command
+// only knows the latest rates; cache_ttl, 0 to run the command for every
+// request; and max_runs
+func newCommandSource(name string, settings map[string]string) (RateSource, error) {
+	source := &commandSource{
+		name:       name,
+		timeout:    DefaultCommandTimeout,
+		historical: true,
+		cacheTTL:   DefaultCommandCacheTTL,
+		results:    make(map[string]commandResult),
+	}
+	maxRuns := DefaultCommandMaxRuns
+	for key, value := range settings {
+		var err error
+		switch key {
+		case "command":
+			source.args = strings.Fields(value)
+		case "timeout":
+			source.timeout, err = time.ParseDuration(value)
+			if err == nil && source.timeout <= 0 {
+				err = fmt.Errorf("must be positive")
+			}
+		case "historical":
+			source.historical, err = strconv.ParseBool(value)
+		case "cache_ttl":
+			source.cacheTTL, err = time.ParseDuration(value)
+			if err == nil && source.cacheTTL < 0 {
+				err = fmt.Errorf("must not be negative")
+			}
+		case "max_runs":
+			maxRuns, err = strconv.Atoi(value)
+			if err == nil && maxRuns <= 0 {
+				err = fmt.Errorf("must be positive")
+			}
+		default:
+			return nil, fmt.Errorf("unknown setting %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", key, value, err)
+		}
+	}
+	if len(source.args) == 0 {
+		return nil, fmt.Errorf("command is required")
+	}
+	source.runs = make(chan struct{}, maxRuns)
+	return source, nil
+}
+
+func (s *commandSource) Name() string {
+	return s.name
+}
+
+func (s *commandSource) LatestRates(ctx context.Context, base string) (*Rates, error) {
+	return s.run(ctx, "latest", base)
+}
+
+func (s *commandSource) HistoricalRates(ctx context.Context, base string, date time.Time) (*Rates, error) {
+	if !s.historical {
+		return nil, fmt.Errorf("source %s: %w", s.name, ErrNoHistoricalData)
+	}
+	return s.run(ctx, "historical", base, date.Format(dateFormat))
+}
+
+type commandOutput struct {
+	Base        string             `json:"base"`
+	Rates       map[string]float64 `json:"rates"`
+	PublishedAt time.Time          `json:"published_at"`
+}
+
+// run returns the rates the command printed for args within cacheTTL, or
+// runs it once a run slot is free
+func (s *commandSource) run(ctx context.Context, args ...string) (*Rates, error) {
+	key := strings.Join(args, " ")
+	if rates, ok := s.cached(key); ok {
+		return rates, nil
+	}
+	select {
+	case s.runs <- struct{}{}:
+		defer func() { <-s.runs }()
+	case <-ctx.Done():
+		return nil, fmt.Errorf("source %s: no free slot to run the command: %w", s.name, ctx.Err())
+	}
+	// Another request may have run it while this one waited
+	if rates, ok := s.cached(key); ok {
+		return rates, nil
+	}
+
+	rates, err := s.exec(ctx, args...)
+	if err != nil || s.cacheTTL == 0 {
+		return rates, err
+	}
+	now := time.Now()
+	s.mu.Lock()
+	for k, result := range s.results {
+		if now.After(result.expires) {
+			delete(s.results, k)
+		}
+	}
+	s.results[key] = commandResult{rates: rates, expires: now.Add(s.cacheTTL)}
+	s.mu.Unlock()
+	return rates.clone(), nil
+}
+
+// cached returns a copy of the unexpired rates of a run with the arguments key
+func (s *commandSource) cached(key string) (*Rates, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, ok := s.results[key]
+	if !ok || time.Now().After(result.expires) {
+		return nil, false
+	}
+	return result.rates.clone(), true
+}
+
+func (s *commandSource) exec(ctx context.Context, args ...string) (*Rates, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.args[0], append(s.args[1:], args...)...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("source %s: command did not finish: %w", s.name, ctx.Err())
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("source %s: command failed: %w: %s", s.name, err, message)
+		}
+		return nil, fmt.Errorf("source %s: command failed: %w", s.name, err)
+	}
+
+	var output commandOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("source %s: command printed invalid rates: %w", s.name, err)
+	}
+	if output.Rates == nil {
+		return nil, fmt.Errorf("source %s: command printed no rates", s.name)
+	}
+	if output.Base == "" {
+		output.Base = args[1]
+	}
+	return &Rates{Base: output.Base, Rates: output.Rates, PublishedAt: output.PublishedAt}, nil
+}
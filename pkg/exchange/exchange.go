@@ -0,0 +1,354 @@
+// Package exchange embeds the service's conversion logic in other Go
+// programs, without running the HTTP server:
+//
+//	ex, err := exchange.New(exchange.WithProvider("frankfurter"))
+//	conversion, err := ex.Convert(ctx, exchange.ConversionRequest{From: "USD", To: "INR", Amount: 100})
+//
+// Rates come from the builtin providers or from a RateSource of your own, and
+// are cached in memory or in a Cache of your own.
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"exchange-rate-service/internal/apperrors"
+	"exchange-rate-service/internal/cache"
+	"exchange-rate-service/internal/external"
+	"exchange-rate-service/internal/models"
+	"exchange-rate-service/internal/services"
+)
+
+// Builtin provider names accepted by WithProvider and ConversionRequest.Provider
+const (
+	ProviderExchangeRateAPI = external.ProviderExchangeRateAPI
+	ProviderFrankfurter     = external.ProviderFrankfurter
+	ProviderFixer           = external.ProviderFixer
+)
+
+// DefaultCacheTTL is how long rates are cached unless WithCacheTTL says otherwise
+const DefaultCacheTTL = time.Hour
+
+// ErrRateNotFound is returned, wrapped, when a provider has no rate for a
+// pair. RateSource implementations return it for currencies they don't quote.
+var ErrRateNotFound = external.ErrRateNotFound
+
+// ErrNoHistoricalData is returned, wrapped, when a provider serves no
+// historical rates. RateSource implementations return it from HistoricalRates
+// when they only know the latest rates.
+var ErrNoHistoricalData = external.ErrNoHistoricalData
+
+// Kinds of failure returned, wrapped, by Exchange, for errors.Is checks such
+// as errors.Is(err, exchange.ErrUnsupportedCurrency)
+var (
+	ErrUnsupportedCurrency = apperrors.ErrUnsupportedCurrency
+	ErrInvalidAmount       = apperrors.ErrInvalidAmount
+	ErrInvalidDate         = apperrors.ErrInvalidDate
+	ErrUnknownProvider     = apperrors.ErrUnknownProvider
+	ErrRateUnavailable     = apperrors.ErrRateUnavailable
+	ErrProviderBusy        = apperrors.ErrProviderBusy
+	ErrProviderTimeout     = apperrors.ErrProviderTimeout
+)
+
+// Converter converts amounts and looks up rates. *Exchange implements it;
+// programs can depend on the interface to substitute a fake in tests.
+type Converter interface {
+	// Convert converts an amount at the latest rate, or at a past day's rate
+	// when the request has a Date
+	Convert(ctx context.Context, req ConversionRequest) (*Conversion, error)
+	// Rate returns the latest rate of a pair
+	Rate(ctx context.Context, from, to string) (*Rate, error)
+	// History returns the daily rates of a pair from start to end inclusive
+	History(ctx context.Context, from, to string, start, end time.Time) (*History, error)
+}
+
+// ConversionRequest is an amount to convert
+type ConversionRequest struct {
+	From      string
+	To        string
+	Amount    float64
+	Date      time.Time // Zero for the latest rate
+	Provider  string    // Empty for the default provider
+	Precision *int      // Decimals of ConvertedAmount, nil for To's minor units
+	Rounding  string    // "half_up" (default), "bankers" or "truncate"
+	Strict    bool      // Fail rather than use the last market day's rate for a Date without one, or a stale rate
+
+	// Optional fees charged on the converted amount: a percent of it plus a
+	// fixed amount in To
+	FeePercent float64
+	FeeFixed   float64
+}
+
+// Conversion is the result of a conversion
+type Conversion struct {
+	From            string
+	To              string
+	Amount          float64
+	ConvertedAmount float64 // Rounded as requested
+	Rate            float64 // Applied rate, mid-market plus markup, to six decimals
+	MidMarketRate   float64
+	UnroundedRate   float64 // Applied rate at full precision
+	UnroundedAmount float64 // ConvertedAmount before rounding
+	FeeAmount       float64 // Fees charged, zero without fees
+	NetAmount       float64 // ConvertedAmount less FeeAmount
+	RateDate        string  // Market day of the rate, set when converting at a Date
+	Provider        string
+	PublishedAt     time.Time // Zero when unknown
+}
+
+// Rate is the mid-market rate of a pair
+type Rate struct {
+	From        string
+	To          string
+	Rate        float64
+	Provider    string
+	PublishedAt time.Time // Zero when unknown
+}
+
+// History holds a pair's daily rates, oldest first, and the days of the
+// range without a rate
+type History struct {
+	From    string
+	To      string
+	Rates   []DailyRate
+	Missing []MissingDate
+}
+
+// DailyRate is a pair's rate on one day. On a weekend or holiday it is the
+// rate of the previous trading day, ObservedDate.
+type DailyRate struct {
+	Date         string // YYYY-MM-DD
+	Rate         float64
+	ObservedDate string // Empty when the rate is the day's own
+}
+
+// MissingDate is a day without a rate. Reason is "no_data" when the provider
+// published none, "provider_error" when the fetch failed and "out_of_range"
+// when the provider serves no history for the day.
+type MissingDate struct {
+	Date   string
+	Reason string
+}
+
+// Exchange is an embedded exchange rate service
+type Exchange struct {
+	service *services.ExchangeService
+}
+
+var _ Converter = (*Exchange)(nil)
+
+// Option configures an Exchange
+type Option func(*options)
+
+type options struct {
+	provider      string
+	sources       []RateSource
+	keys          map[string]string
+	cache         Cache
+	cacheTTL      time.Duration
+	markupPercent float64
+	timeout       time.Duration
+}
+
+// WithProvider sets the provider used when a request names none, a builtin
+// one or the name of a RateSource. It defaults to the first RateSource, or
+// exchangerate-api without one.
+func WithProvider(name string) Option {
+	return func(o *options) {
+		o.provider = name
+	}
+}
+
+// WithSource adds a RateSource requests can be pinned to by its name
+func WithSource(source RateSource) Option {
+	return func(o *options) {
+		o.sources = append(o.sources, source)
+	}
+}
+
+// WithAPIKey sets the API key of a builtin provider
+func WithAPIKey(provider, key string) Option {
+	return func(o *options) {
+		o.keys[provider] = key
+	}
+}
+
+// WithCache stores rates in cache instead of in memory
+func WithCache(cache Cache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+// WithCacheTTL sets how long rates are cached, DefaultCacheTTL by default
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.cacheTTL = ttl
+	}
+}
+
+// WithMarkup adds percent to every converted rate, e.g. 1.5 for 1.5%
+func WithMarkup(percent float64) Option {
+	return func(o *options) {
+		o.markupPercent = percent
+	}
+}
+
+// WithTimeout sets the timeout of each request to a builtin provider
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// New returns an Exchange. It fails when the default provider is unknown.
+func New(opts ...Option) (*Exchange, error) {
+	o := options{keys: make(map[string]string), cacheTTL: DefaultCacheTTL}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cfg := external.DefaultConfig()
+	cfg.Timeout = o.timeout
+	cfg.Credentials = external.NewCredentials()
+	for provider, key := range o.keys {
+		cfg.Credentials.SetKey(external.CanonicalProvider(provider), key)
+	}
+	for _, source := range o.sources {
+		cfg.Providers = append(cfg.Providers, &sourceProvider{source: source})
+	}
+	if len(o.sources) > 0 {
+		cfg.DefaultProvider = o.sources[0].Name()
+	}
+	if o.provider != "" {
+		cfg.DefaultProvider = o.provider
+	}
+	client := external.NewExchangeRateClientWithConfig(cfg)
+	if client.DefaultProvider() != external.CanonicalProvider(cfg.DefaultProvider) {
+		return nil, fmt.Errorf("%w %q", external.ErrUnknownProvider, cfg.DefaultProvider)
+	}
+
+	var rateCache cache.CacheInterface
+	if o.cache != nil {
+		rateCache = &cacheAdapter{cache: o.cache, ttl: o.cacheTTL}
+	} else {
+		rateCache = cache.NewMemoryCache(o.cacheTTL)
+	}
+
+	rateFetcher := services.NewRateFetcher(client, rateCache)
+	service := services.NewExchangeService(rateCache, rateFetcher, client)
+	service.SetMarkup(services.NewMarkup(o.markupPercent, nil))
+	return &Exchange{service: service}, nil
+}
+
+// Convert converts an amount at the latest rate, or at the rate of the last
+// market day on or before req.Date
+func (e *Exchange) Convert(ctx context.Context, req ConversionRequest) (*Conversion, error) {
+	request := &models.ConversionRequest{
+		From:       req.From,
+		To:         req.To,
+		Amount:     req.Amount,
+		Provider:   req.Provider,
+		Precision:  req.Precision,
+		Rounding:   req.Rounding,
+		Strict:     req.Strict,
+		FeePercent: req.FeePercent,
+		FeeFixed:   req.FeeFixed,
+	}
+	if !req.Date.IsZero() {
+		request.Date = req.Date.Format(dateFormat)
+	}
+	resp, err := e.service.ConvertCurrency(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	conversion := &Conversion{
+		From:            resp.From,
+		To:              resp.To,
+		Amount:          resp.Amount,
+		ConvertedAmount: resp.ConvertedAmount,
+		Rate:            resp.Rate,
+		MidMarketRate:   resp.MidMarketRate,
+		UnroundedRate:   resp.UnroundedRate,
+		UnroundedAmount: resp.UnroundedAmount,
+		NetAmount:       resp.ConvertedAmount,
+		RateDate:        resp.RateDate,
+		Provider:        resp.Provider,
+		PublishedAt:     publishedAt(resp.Source),
+	}
+	if resp.Fees != nil {
+		conversion.FeeAmount = resp.Fees.FeeAmount
+		conversion.NetAmount = resp.Fees.NetAmount
+	}
+	return conversion, nil
+}
+
+// Rate returns the latest mid-market rate of a pair from the default provider
+func (e *Exchange) Rate(ctx context.Context, from, to string) (*Rate, error) {
+	return e.RateFrom(ctx, "", from, to)
+}
+
+// RateFrom returns the latest mid-market rate of a pair from the named
+// provider, or the default one when provider is empty
+func (e *Exchange) RateFrom(ctx context.Context, provider, from, to string) (*Rate, error) {
+	resp, err := e.service.GetLatestRate(ctx, from, to, provider)
+	if err != nil {
+		return nil, err
+	}
+	return &Rate{
+		From:        resp.From,
+		To:          resp.To,
+		Rate:        resp.Rate,
+		Provider:    resp.Provider,
+		PublishedAt: publishedAt(resp.Source),
+	}, nil
+}
+
+// History returns the daily rates of a pair from start to end inclusive
+func (e *Exchange) History(ctx context.Context, from, to string, start, end time.Time) (*History, error) {
+	resp, err := e.service.GetHistoricalRates(ctx, &models.HistoricalRateRequest{
+		From:      from,
+		To:        to,
+		StartDate: start.Format(dateFormat),
+		EndDate:   end.Format(dateFormat),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	history := &History{From: resp.From, To: resp.To, Rates: make([]DailyRate, 0, len(resp.Rates))}
+	for date, rate := range resp.Rates {
+		history.Rates = append(history.Rates, DailyRate{Date: date, Rate: rate.Rate, ObservedDate: rate.ObservedDate})
+	}
+	sort.Slice(history.Rates, func(i, j int) bool {
+		return history.Rates[i].Date < history.Rates[j].Date
+	})
+	for _, missing := range resp.MissingDates {
+		history.Missing = append(history.Missing, MissingDate{Date: missing.Date, Reason: missing.Reason})
+	}
+	return history, nil
+}
+
+// Currencies returns the supported currency codes
+func (e *Exchange) Currencies() []string {
+	return e.service.GetSupportedCurrencies()
+}
+
+// ErrorCode returns the error code of err, the same as the HTTP API's
+// error_code such as "CURRENCY_UNSUPPORTED", or "" when err has none
+func ErrorCode(err error) string {
+	code, _ := apperrors.CodeOf(err)
+	return code
+}
+
+const dateFormat = "2006-01-02"
+
+func publishedAt(source models.Source) time.Time {
+	if source.PublishedAt == nil {
+		return time.Time{}
+	}
+	return *source.PublishedAt
+}
@@ -0,0 +1,150 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource quotes USD/INR at 83.5, with no rates on weekends
+type fakeSource struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (s *fakeSource) Name() string {
+	return "ledger"
+}
+
+func (s *fakeSource) LatestRates(ctx context.Context, base string) (*Rates, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+	if base != "USD" {
+		return nil, fmt.Errorf("no rates against %s", base)
+	}
+	return &Rates{Base: base, Rates: map[string]float64{"INR": 83.5, "EUR": 0.92}}, nil
+}
+
+func (s *fakeSource) HistoricalRates(_ context.Context, base string, date time.Time) (*Rates, error) {
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return &Rates{Base: base, Rates: map[string]float64{}}, nil
+	}
+	return &Rates{Base: base, Rates: map[string]float64{"INR": 80 + float64(date.Day())/10}}, nil
+}
+
+// mapCache is a Cache over a map
+type mapCache struct {
+	mu      sync.Mutex
+	entries map[string]CachedRate
+}
+
+func (c *mapCache) Get(from, to, date string) (CachedRate, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[from+to+date]
+	return entry, ok
+}
+
+func (c *mapCache) Set(from, to, date string, rate CachedRate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[from+to+date] = rate
+}
+
+func (c *mapCache) Delete(from, to, date string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, from+to+date)
+}
+
+func TestExchange_ConvertWithSource(t *testing.T) {
+	source := &fakeSource{}
+	store := &mapCache{entries: make(map[string]CachedRate)}
+	ex, err := New(WithSource(source), WithCache(store), WithMarkup(1))
+	require.NoError(t, err)
+
+	conversion, err := ex.Convert(context.Background(), ConversionRequest{From: "USD", To: "INR", Amount: 100})
+	require.NoError(t, err)
+	assert.Equal(t, 83.5, conversion.MidMarketRate)
+	assert.InDelta(t, 84.335, conversion.Rate, 1e-9)
+	assert.InDelta(t, 8433.5, conversion.ConvertedAmount, 1e-9)
+	assert.Equal(t, "ledger", conversion.Provider)
+	assert.Equal(t, conversion.ConvertedAmount, conversion.NetAmount, "without fees the whole amount is paid out")
+
+	conversion, err = ex.Convert(context.Background(), ConversionRequest{From: "USD", To: "INR", Amount: 100, FeePercent: 2, FeeFixed: 10})
+	require.NoError(t, err)
+	assert.InDelta(t, 178.67, conversion.FeeAmount, 1e-9)
+	assert.InDelta(t, 8254.83, conversion.NetAmount, 1e-9)
+
+	cached, found := store.Get("USD", "INR", "")
+	require.True(t, found)
+	assert.Equal(t, 83.5, cached.Rate)
+	assert.Equal(t, "ledger", cached.Provider)
+
+	// The second lookup is served from the cache
+	rate, err := ex.Rate(context.Background(), "USD", "INR")
+	require.NoError(t, err)
+	assert.Equal(t, 83.5, rate.Rate)
+	assert.Equal(t, 1, source.calls)
+}
+
+func TestExchange_History(t *testing.T) {
+	ex, err := New(WithSource(&fakeSource{}))
+	require.NoError(t, err)
+
+	end := time.Now().AddDate(0, 0, -1)
+	start := end.AddDate(0, 0, -6)
+	history, err := ex.History(context.Background(), "USD", "INR", start, end)
+	require.NoError(t, err)
+
+	assert.Len(t, history.Rates, 7)
+	assert.Empty(t, history.Missing)
+	for i := 1; i < len(history.Rates); i++ {
+		assert.Less(t, history.Rates[i-1].Date, history.Rates[i].Date)
+	}
+	for _, rate := range history.Rates {
+		day, err := time.Parse(dateFormat, rate.Date)
+		require.NoError(t, err)
+		friday := day
+		for friday.Weekday() == time.Saturday || friday.Weekday() == time.Sunday {
+			friday = friday.AddDate(0, 0, -1)
+		}
+		if friday.Equal(day) {
+			assert.Empty(t, rate.ObservedDate)
+		} else {
+			assert.Equal(t, friday.Format(dateFormat), rate.ObservedDate, "weekends repeat Friday")
+		}
+	}
+}
+
+func TestExchange_Errors(t *testing.T) {
+	_, err := New(WithProvider("nowhere"))
+	assert.Error(t, err)
+
+	ex, err := New(WithSource(&fakeSource{}))
+	require.NoError(t, err)
+
+	_, err = ex.Convert(context.Background(), ConversionRequest{From: "USD", To: "XXX", Amount: 1})
+	require.Error(t, err)
+	assert.Equal(t, "CURRENCY_UNSUPPORTED", ErrorCode(err))
+	assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+
+	_, err = ex.Convert(context.Background(), ConversionRequest{From: "USD", To: "INR", Amount: 1, Provider: "nowhere"})
+	require.Error(t, err)
+	assert.Equal(t, "PROVIDER_UNKNOWN", ErrorCode(err))
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ex.Rate(ctx, "USD", "INR")
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,74 @@
+package exchange
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"exchange-rate-service/internal/external"
+)
+
+// SourceFactory builds a RateSource called name from the settings it is
+// declared with, e.g. the command of a command source
+type SourceFactory func(name string, settings map[string]string) (RateSource, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]SourceFactory{SourceKindCommand: newCommandSource}
+)
+
+// RegisterSource makes a kind of RateSource available to NewSource and to the
+// server's RATE_SOURCES, typically from the init function of a Go plugin or
+// of a package linked into a custom build. Like database/sql.Register, it
+// panics when kind is registered twice or factory is nil.
+func RegisterSource(kind string, factory SourceFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("exchange: RegisterSource factory is nil")
+	}
+	if _, dup := registry[kind]; dup {
+		panic("exchange: RegisterSource called twice for kind " + kind)
+	}
+	registry[kind] = factory
+}
+
+// SourceKinds returns the registered kinds of source, sorted
+func SourceKinds() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	kinds := make([]string, 0, len(registry))
+	for kind := range registry {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// NewSource builds a source of a registered kind called name. The name is
+// what requests pin the source with, so it may not be a builtin provider's.
+func NewSource(kind, name string, settings map[string]string) (RateSource, error) {
+	if name == "" || name != external.CanonicalProvider(name) {
+		return nil, fmt.Errorf("invalid source name %q: must be lower case", name)
+	}
+	if external.IsBuiltinProvider(name) {
+		return nil, fmt.Errorf("invalid source name %q: it is a builtin provider", name)
+	}
+
+	registryMu.RLock()
+	factory, ok := registry[kind]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown kind of source %q, expected one of %s", kind, strings.Join(SourceKinds(), ", "))
+	}
+
+	source, err := factory(name, settings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s source %s: %w", kind, name, err)
+	}
+	if source.Name() != name {
+		return nil, fmt.Errorf("%s source %s calls itself %q", kind, name, source.Name())
+	}
+	return source, nil
+}
@@ -0,0 +1,144 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterSource(t *testing.T) {
+	RegisterSource("test-ledger", func(name string, settings map[string]string) (RateSource, error) {
+		if settings["feed"] == "" {
+			return nil, errors.New("feed is required")
+		}
+		return &fakeSource{}, nil
+	})
+	assert.Contains(t, SourceKinds(), "test-ledger")
+	assert.Contains(t, SourceKinds(), SourceKindCommand)
+	assert.Panics(t, func() { RegisterSource("test-ledger", newCommandSource) }, "a kind is registered once")
+
+	source, err := NewSource("test-ledger", "ledger", map[string]string{"feed": "main"})
+	require.NoError(t, err)
+	assert.Equal(t, "ledger", source.Name())
+
+	tests := []struct {
+		name     string
+		kind     string
+		source   string
+		settings map[string]string
+		err      string
+	}{
+		{"unknown kind", "nope", "ledger", nil, "unknown kind"},
+		{"builtin name", SourceKindCommand, "frankfurter", map[string]string{"command": "true"}, "builtin provider"},
+		{"upper case name", SourceKindCommand, "Treasury", map[string]string{"command": "true"}, "lower case"},
+		{"factory error", "test-ledger", "ledger", nil, "feed is required"},
+		{"name differs", "test-ledger", "treasury", map[string]string{"feed": "main"}, "calls itself"},
+		{"command missing", SourceKindCommand, "treasury", nil, "command is required"},
+		{"unknown setting", SourceKindCommand, "treasury", map[string]string{"command": "true", "retries": "3"}, "unknown setting"},
+		{"invalid timeout", SourceKindCommand, "treasury", map[string]string{"command": "true", "timeout": "-1s"}, "invalid timeout"},
+		{"invalid cache TTL", SourceKindCommand, "treasury", map[string]string{"command": "true", "cache_ttl": "-1m"}, "invalid cache_ttl"},
+		{"invalid max runs", SourceKindCommand, "treasury", map[string]string{"command": "true", "max_runs": "0"}, "invalid max_runs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSource(tt.kind, tt.source, tt.settings)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestCommandSource(t *testing.T) {
+	source, err := NewSource(SourceKindCommand, "treasury", map[string]string{"command": "sh testdata/rates.sh", "timeout": "5s"})
+	require.NoError(t, err)
+
+	latest, err := source.LatestRates(context.Background(), "USD")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"INR": 85.5}, latest.Rates)
+	assert.Equal(t, time.Date(2025, 1, 2, 16, 0, 0, 0, time.UTC), latest.PublishedAt.UTC())
+
+	historical, err := source.HistoricalRates(context.Background(), "USD", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 85.0, historical.Rates["INR"])
+
+	_, err = source.LatestRates(context.Background(), "EUR")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no rates against EUR", "the command's standard error is reported")
+
+	ex, err := New(WithSource(source))
+	require.NoError(t, err)
+	rate, err := ex.Rate(context.Background(), "USD", "INR")
+	require.NoError(t, err)
+	assert.Equal(t, 85.5, rate.Rate)
+
+	latestOnly, err := NewSource(SourceKindCommand, "treasury", map[string]string{"command": "sh testdata/rates.sh", "historical": "false"})
+	require.NoError(t, err)
+	_, err = latestOnly.HistoricalRates(context.Background(), "USD", time.Now())
+	assert.ErrorIs(t, err, ErrNoHistoricalData)
+}
+
+// runs returns the runs count.sh noted in file
+func runs(t *testing.T, file string) []string {
+	t.Helper()
+	content, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	return strings.Fields(strings.ReplaceAll(string(content), " ", "_"))
+}
+
+func TestCommandSource_CachesRuns(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "runs")
+	source, err := NewSource(SourceKindCommand, "treasury", map[string]string{"command": "sh testdata/count.sh " + file + " 0"})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		rates, err := source.LatestRates(context.Background(), "USD")
+		require.NoError(t, err)
+		assert.Equal(t, 85.5, rates.Rates["INR"])
+		rates.Rates["INR"] = 0 // Callers get copies of the cached rates
+	}
+	_, err = source.LatestRates(context.Background(), "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"latest_USD", "latest_EUR"}, runs(t, file), "one run per base")
+
+	uncached, err := NewSource(SourceKindCommand, "treasury", map[string]string{"command": "sh testdata/count.sh " + file + " 0", "cache_ttl": "0s"})
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = uncached.LatestRates(context.Background(), "USD")
+		require.NoError(t, err)
+	}
+	assert.Len(t, runs(t, file), 4)
+}
+
+func TestCommandSource_CapsRuns(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "runs")
+	source, err := NewSource(SourceKindCommand, "treasury", map[string]string{"command": "sh testdata/count.sh " + file + " 0.5", "max_runs": "1"})
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := source.LatestRates(context.Background(), "USD")
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return len(runs(t, file)) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// The only slot is taken, so a request that can't wait for it fails
+	// without running the command
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = source.LatestRates(ctx, "EUR")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "no free slot")
+
+	require.NoError(t, <-done)
+	assert.Equal(t, []string{"latest_USD"}, runs(t, file))
+}
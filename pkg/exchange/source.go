@@ -0,0 +1,91 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"exchange-rate-service/internal/models"
+)
+
+// RateSource is an upstream source of exchange rates, plugged in with
+// WithSource. Unlike the builtin providers, its calls are not throttled,
+// retried or guarded by a circuit breaker; the source does so itself if it
+// needs to. Calls should give up once ctx is done.
+type RateSource interface {
+	// Name identifies the source in requests and results, e.g. "ledger"
+	Name() string
+	// LatestRates returns the current rates of every currency it quotes
+	// against base
+	LatestRates(ctx context.Context, base string) (*Rates, error)
+	// HistoricalRates returns the rates against base published for date, or
+	// an error wrapping ErrNoHistoricalData when it has no history
+	HistoricalRates(ctx context.Context, base string, date time.Time) (*Rates, error)
+}
+
+// Rates are a source's rates of every currency it quotes against Base
+type Rates struct {
+	Base        string
+	Rates       map[string]float64 // Quote currency -> units of it per unit of Base
+	PublishedAt time.Time          // Zero when unknown
+}
+
+// clone copies r, so callers sharing cached rates can't change each other's
+func (r *Rates) clone() *Rates {
+	rates := make(map[string]float64, len(r.Rates))
+	for currency, rate := range r.Rates {
+		rates[currency] = rate
+	}
+	return &Rates{Base: r.Base, Rates: rates, PublishedAt: r.PublishedAt}
+}
+
+// sourceProvider adapts a RateSource to the client's providers
+type sourceProvider struct {
+	source RateSource
+}
+
+func (p *sourceProvider) Name() string {
+	return p.source.Name()
+}
+
+func (p *sourceProvider) LatestRates(ctx context.Context, base string) (*models.ExternalAPIResponse, error) {
+	rates, err := p.source.LatestRates(ctx, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest rates: %w", err)
+	}
+	return p.response(rates, ""), nil
+}
+
+func (p *sourceProvider) HistoricalRates(ctx context.Context, base, date string) (*models.ExternalAPIResponse, error) {
+	day, err := time.Parse(dateFormat, date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid historical date %q: %w", date, err)
+	}
+	rates, err := p.source.HistoricalRates(ctx, base, day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch historical rates: %w", err)
+	}
+	return p.response(rates, date), nil
+}
+
+// HasHistoricalData is true: a source without history says so per request
+func (p *sourceProvider) HasHistoricalData() bool {
+	return true
+}
+
+func (p *sourceProvider) ProbeURL() string {
+	return ""
+}
+
+func (p *sourceProvider) response(rates *Rates, date string) *models.ExternalAPIResponse {
+	response := &models.ExternalAPIResponse{
+		Provider: p.source.Name(),
+		Base:     rates.Base,
+		Date:     date,
+		Rates:    rates.Rates,
+	}
+	if !rates.PublishedAt.IsZero() {
+		response.TimeLastUpdated = rates.PublishedAt.Unix()
+	}
+	return response
+}
@@ -0,0 +1,6 @@
+#!/bin/sh
+# A command source noting its runs in FILE and taking SECONDS to answer:
+# count.sh FILE SECONDS latest|historical BASE [DATE]
+echo "$3 $4" >> "$1"
+sleep "$2"
+echo '{"base":"'"$4"'","rates":{"INR":85.5}}'
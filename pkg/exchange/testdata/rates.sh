@@ -0,0 +1,11 @@
+#!/bin/sh
+# A command source quoting USD/INR: rates.sh latest|historical BASE [DATE]
+if [ "$2" != "USD" ]; then
+	echo "no rates against $2" >&2
+	exit 3
+fi
+case "$1" in
+latest) echo '{"base":"USD","rates":{"INR":85.5},"published_at":"2025-01-02T16:00:00Z"}' ;;
+historical) echo '{"base":"USD","rates":{"INR":85.0}}' ;;
+*) exit 2 ;;
+esac